@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/handlers"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"os"
+)
+
+// Manifest is the JSON document client generators read the error codes from
+type Manifest struct {
+	Codes []handlers.ErrorCodeInfo `json:"codes"` // Every error code with its default HTTP status
+}
+
+// main writes the manifest of the API's error codes to stdout, e.g.
+// go run ./cmd/gencodes > error-codes.json
+func main() {
+	logging.Default = logging.New(os.Stderr, logging.LevelFromEnv())
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(Manifest{Codes: handlers.AllErrorCodes()}); err != nil {
+		logging.Default.Error("failed to write the error code manifest", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+}
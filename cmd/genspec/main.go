@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/handlers"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"os"
+)
+
+// main writes the OpenAPI document of the API to stdout, e.g.
+// go run ./cmd/genspec > openapi.json
+// It fails if a route lacks the metadata the document needs.
+func main() {
+	logging.Default = logging.New(os.Stderr, logging.LevelFromEnv())
+
+	undocumented := false
+	for _, route := range handlers.Routes() {
+		if methods := route.Undocumented(); len(methods) > 0 {
+			logging.Default.Error("route is missing a summary or status", logging.Fields{
+				"route":   route.Template,
+				"methods": methods,
+			})
+			undocumented = true
+		}
+	}
+	if undocumented {
+		os.Exit(1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(handlers.OpenAPI()); err != nil {
+		logging.Default.Error("failed to write the OpenAPI document", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/app"
+	"github.com/Vansh3140/golang-serverless/pkg/config"
+	"github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"net/http"
+	"os"
+)
+
+// defaultAddr is the address the local server listens on when LOCAL_ADDR is unset
+const defaultAddr = ":8080"
+
+// main starts a local HTTP server serving the API through the same router and handlers
+// as the Lambda function. Set DYNAMODB_ENDPOINT to use DynamoDB Local or LocalStack.
+func main() {
+	// Configure structured logging, with verbosity from LOG_LEVEL
+	logging.Default = logging.New(os.Stdout, logging.LevelFromEnv())
+
+	// Replace the secretsmanager: and ssm: references of the environment with their values,
+	// refusing to start with a secret missing
+	if err := config.Load(); err != nil {
+		logging.Default.Error("failed to resolve secret references", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+
+	// Read and validate the configuration, failing fast on missing variables
+	cfg, err := app.LoadConfig()
+	if err != nil {
+		logging.Default.Error("invalid configuration", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+
+	// Create a new AWS session, pointed at a local DynamoDB if DYNAMODB_ENDPOINT is set
+	awsSession, err := app.NewSession(cfg)
+	if err != nil {
+		logging.Default.Error("failed to create AWS session", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+	dynaClient := dynamodb.New(awsSession)
+
+	// Publish user lifecycle events to EVENT_BUS_NAME or EVENT_TOPIC_ARN, if set
+	events.Default = events.NewPublisher(awsSession)
+
+	// Encrypt names under PII_KMS_KEY_ARN, if set, and decrypt names encrypted before
+	user.Keys = kms.New(awsSession)
+
+	// Load the disposable email domains blocked when BLOCK_DISPOSABLE_EMAILS is set
+	if err := validators.LoadDisposableDomains(); err != nil {
+		logging.Default.Warn("using the built-in disposable domains", logging.Fields{"error": err})
+	}
+
+	// Create the table if AUTO_CREATE_TABLE is set and it doesn't exist yet
+	if err := app.EnsureTable(cfg, dynaClient); err != nil {
+		logging.Default.Error("failed to ensure table", logging.Fields{"table": cfg.TableName, "error": err})
+		os.Exit(1)
+	}
+
+	addr := os.Getenv("LOCAL_ADDR")
+	if addr == "" {
+		addr = defaultAddr
+	}
+
+	// Keep users in memory instead of DynamoDB if MEMORY_STORE is set
+	application := app.New(cfg, dynaClient)
+	if os.Getenv("MEMORY_STORE") == "true" {
+		application.Repository = user.NewMemoryRepository()
+	}
+
+	logging.Default.Info("listening", logging.Fields{"addr": addr, "table": cfg.TableName})
+	if err := http.ListenAndServe(addr, application); err != nil {
+		logging.Default.Error("server stopped", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+}
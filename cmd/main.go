@@ -1,64 +1,77 @@
 package main
 
 import (
-	"github.com/Vansh3140/golang-serverless/pkg/handlers"
-	"github.com/aws/aws-lambda-go/events"
+	"github.com/Vansh3140/golang-serverless/pkg/app"
+	"github.com/Vansh3140/golang-serverless/pkg/config"
+	"github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/tracing"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/kms"
 	"os"
 )
 
-// Global DynamoDB client interface
-var (
-	dynaClient dynamodbiface.DynamoDBAPI
-)
-
-// main function initializes the AWS session, DynamoDB client, and starts the Lambda function handler.
+// main function loads the configuration, initializes the AWS session and DynamoDB client,
+// and starts the Lambda function handler.
 func main() {
-	// Get AWS region from the environment variable
-	region := os.Getenv("AWS_REGION")
+	// Configure structured logging, with verbosity from LOG_LEVEL
+	logging.Default = logging.New(os.Stdout, logging.LevelFromEnv())
+
+	// Replace the secretsmanager: and ssm: references of the environment with their values,
+	// refusing to start with a secret missing
+	if err := config.Load(); err != nil {
+		logging.Default.Error("failed to resolve secret references", logging.Fields{"error": err})
+		os.Exit(1)
+	}
 
-	// Create a new AWS session
-	awsSession, err := session.NewSession(&aws.Config{
-		Region: aws.String(region)}, // AWS region for the session
-	)
+	// Read and validate the configuration, failing fast on missing variables
+	cfg, err := app.LoadConfig()
 	if err != nil {
-		// Exit if the session cannot be created
-		return
+		logging.Default.Error("invalid configuration", logging.Fields{"error": err})
+		os.Exit(1)
 	}
 
-	// Initialize the DynamoDB client using the session
-	dynaClient = dynamodb.New(awsSession)
+	// Create a new AWS session, pointed at a local DynamoDB if DYNAMODB_ENDPOINT is set
+	awsSession, err := app.NewSession(cfg)
+	if err != nil {
+		logging.Default.Error("failed to create AWS session", logging.Fields{"error": err})
+		os.Exit(1)
+	}
 
-	// Start the Lambda function and set the handler
-	lambda.Start(handler)
-}
+	// Initialize the DynamoDB client using the session, traced by X-Ray if ENABLE_XRAY is set
+	dynamoClient := dynamodb.New(awsSession)
+	tracing.Configure(dynamoClient.Client)
 
-// tableName stores the DynamoDB table name from the environment variable
-var tableName = os.Getenv("TABLE_NAME")
+	// Serve item reads and writes from DAX if DAX_ENDPOINT is set
+	var dynaClient dynamodbiface.DynamoDBAPI = dynamoClient
+	if cfg.DAXEndpoint != "" {
+		if dynaClient, err = app.NewDAXClient(cfg, dynamoClient); err != nil {
+			logging.Default.Error("failed to create DAX client", logging.Fields{"endpoint": cfg.DAXEndpoint, "error": err})
+			os.Exit(1)
+		}
+	}
+
+	// Publish user lifecycle events to EVENT_BUS_NAME or EVENT_TOPIC_ARN, if set
+	events.Default = events.NewPublisher(awsSession)
+
+	// Encrypt names under PII_KMS_KEY_ARN, if set, and decrypt names encrypted before
+	user.Keys = kms.New(awsSession)
 
-// handler processes incoming API Gateway requests and routes them to appropriate handler functions.
-// It supports CRUD operations for user management.
-func handler(req events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
-	// Route the request based on HTTP method
-	switch req.HTTPMethod {
-	case "GET":
-		// Handle GET requests to fetch user data
-		return handlers.GetUser(req, tableName, dynaClient)
-	case "POST":
-		// Handle POST requests to create a new user
-		return handlers.CreateUser(req, tableName, dynaClient)
-	case "PUT":
-		// Handle PUT requests to update existing user data
-		return handlers.UpdateUser(req, tableName, dynaClient)
-	case "DELETE":
-		// Handle DELETE requests to remove a user
-		return handlers.DeleteUser(req, tableName, dynaClient)
-	default:
-		// Handle unsupported HTTP methods
-		return handlers.UnhandledMethod()
+	// Load the disposable email domains blocked when BLOCK_DISPOSABLE_EMAILS is set
+	if err := validators.LoadDisposableDomains(); err != nil {
+		logging.Default.Warn("using the built-in disposable domains", logging.Fields{"error": err})
 	}
+
+	// Create the table if AUTO_CREATE_TABLE is set and it doesn't exist yet
+	if err := app.EnsureTable(cfg, dynaClient); err != nil {
+		logging.Default.Error("failed to ensure table", logging.Fields{"table": cfg.TableName, "error": err})
+		os.Exit(1)
+	}
+
+	// Start the Lambda function and set the handler, accepting REST and HTTP API payloads
+	lambda.Start(app.New(cfg, dynaClient).RawHandler)
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/config"
+	"github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/outbox"
+	"github.com/Vansh3140/golang-serverless/pkg/relay"
+	"github.com/Vansh3140/golang-serverless/pkg/tracing"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"os"
+)
+
+// main starts a Lambda function, run on a schedule, publishing the events recorded in the
+// outbox (OUTBOX_TABLE_NAME) to the sink configured by EVENT_BUS_NAME or EVENT_TOPIC_ARN.
+func main() {
+	// Configure structured logging, with verbosity from LOG_LEVEL
+	logging.Default = logging.New(os.Stdout, logging.LevelFromEnv())
+
+	// Replace the secretsmanager: and ssm: references of the environment with their values,
+	// refusing to start with a secret missing
+	if err := config.Load(); err != nil {
+		logging.Default.Error("failed to resolve secret references", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+
+	// There is nothing to relay without an outbox
+	if !outbox.Enabled() {
+		logging.Default.Error("invalid configuration", logging.Fields{"error": "missing required environment variable: OUTBOX_TABLE_NAME"})
+		os.Exit(1)
+	}
+
+	// Create a new AWS session for DynamoDB and the event sink
+	awsSession, err := session.NewSession(&aws.Config{
+		Region: aws.String(os.Getenv("AWS_REGION")),
+	})
+	if err != nil {
+		logging.Default.Error("failed to create AWS session", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+
+	// Initialize the DynamoDB client using the session, traced by X-Ray if ENABLE_XRAY is set
+	dynaClient := dynamodb.New(awsSession)
+	tracing.Configure(dynaClient.Client)
+
+	// Publish the relayed events to EVENT_BUS_NAME or EVENT_TOPIC_ARN
+	events.Default = events.NewPublisher(awsSession)
+
+	// Start the Lambda function and set the handler
+	handler := &relay.Handler{DynaClient: dynaClient}
+	lambda.Start(handler.Handle)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/app"
+	"github.com/Vansh3140/golang-serverless/pkg/config"
+	"github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/queue"
+	"github.com/Vansh3140/golang-serverless/pkg/tracing"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"os"
+)
+
+// main starts a Lambda function creating users from the messages of an SQS queue.
+func main() {
+	// Configure structured logging, with verbosity from LOG_LEVEL
+	logging.Default = logging.New(os.Stdout, logging.LevelFromEnv())
+
+	// Replace the secretsmanager: and ssm: references of the environment with their values,
+	// refusing to start with a secret missing
+	if err := config.Load(); err != nil {
+		logging.Default.Error("failed to resolve secret references", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+
+	// Read and validate the configuration, failing fast on missing variables
+	cfg, err := app.LoadConfig()
+	if err != nil {
+		logging.Default.Error("invalid configuration", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+
+	// Create a new AWS session, pointed at a local DynamoDB if DYNAMODB_ENDPOINT is set
+	awsSession, err := app.NewSession(cfg)
+	if err != nil {
+		logging.Default.Error("failed to create AWS session", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+
+	// Initialize the DynamoDB client using the session, traced by X-Ray if ENABLE_XRAY is set
+	dynaClient := dynamodb.New(awsSession)
+	tracing.Configure(dynaClient.Client)
+
+	// Publish user lifecycle events to EVENT_BUS_NAME or EVENT_TOPIC_ARN, if set
+	events.Default = events.NewPublisher(awsSession)
+
+	// Encrypt names under PII_KMS_KEY_ARN, if set, and decrypt names encrypted before
+	user.Keys = kms.New(awsSession)
+
+	// Load the disposable email domains blocked when BLOCK_DISPOSABLE_EMAILS is set
+	if err := validators.LoadDisposableDomains(); err != nil {
+		logging.Default.Warn("using the built-in disposable domains", logging.Fields{"error": err})
+	}
+
+	// Start the Lambda function and set the handler
+	handler := &queue.Handler{
+		Repository: user.NewDynamoRepository(cfg.TableName, dynaClient),
+		DynaClient: dynaClient,
+	}
+	lambda.Start(handler.Handle)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/stream"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"os"
+)
+
+// main starts a Lambda function consuming the users table's DynamoDB stream and
+// forwarding every change to the sink configured by STREAM_TOPIC_ARN.
+func main() {
+	// Configure structured logging, with verbosity from LOG_LEVEL
+	logging.Default = logging.New(os.Stdout, logging.LevelFromEnv())
+
+	// Create a new AWS session for the SNS sink
+	awsSession, err := session.NewSession(&aws.Config{
+		Region: aws.String(os.Getenv("AWS_REGION")),
+	})
+	if err != nil {
+		logging.Default.Error("failed to create AWS session", logging.Fields{"error": err})
+		os.Exit(1)
+	}
+
+	// Decrypt the names encrypted under PII_KMS_KEY_ARN
+	user.Keys = kms.New(awsSession)
+
+	// Start the Lambda function and set the handler
+	handler := &stream.Handler{Sink: stream.NewSink(awsSession)}
+	lambda.Start(handler.Handle)
+}
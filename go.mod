@@ -0,0 +1,13 @@
+module github.com/Vansh3140/golang-serverless
+
+go 1.18
+
+require (
+	github.com/aws/aws-dax-go v1.2.12
+	github.com/aws/aws-lambda-go v1.34.1
+	github.com/aws/aws-sdk-go v1.44.100
+	github.com/aws/aws-xray-sdk-go v1.7.1
+	golang.org/x/crypto v0.14.0
+	golang.org/x/net v0.17.0
+	golang.org/x/text v0.13.0
+)
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyRequestFromALB converts an ALB target group request into the REST API (v1) proxy
+// event.
+//
+// ALB passes query parameters still percent-encoded, and with multi-value headers enabled
+// on the target group only fills the multi-value fields. Repeated query parameters keep
+// their first value, so e.g. ?email=a&email=b targets a. ALB has no request ID, so the
+// X-Amzn-Trace-Id header stands in for it.
+//
+// Parameters:
+// - in: The ALB request.
+//
+// Returns:
+// - The v1 proxy event.
+func ProxyRequestFromALB(in events.ALBTargetGroupRequest) events.APIGatewayProxyRequest {
+	req := events.APIGatewayProxyRequest{
+		Path:                            in.Path,
+		HTTPMethod:                      in.HTTPMethod,
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string{},
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string{},
+		Body:                            in.Body,
+		IsBase64Encoded:                 in.IsBase64Encoded,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Path:       in.Path,
+			HTTPMethod: in.HTTPMethod,
+		},
+	}
+
+	headers := in.MultiValueHeaders
+	if len(headers) == 0 {
+		headers = singleToMulti(in.Headers)
+	}
+	for name, values := range headers {
+		if len(values) == 0 {
+			continue
+		}
+		req.Headers[name] = values[0]
+		req.MultiValueHeaders[name] = values
+	}
+
+	query := in.MultiValueQueryStringParameters
+	if len(query) == 0 {
+		query = singleToMulti(in.QueryStringParameters)
+	}
+	for rawName, rawValues := range query {
+		if len(rawValues) == 0 {
+			continue
+		}
+		name := queryUnescape(rawName)
+		for _, value := range rawValues {
+			req.MultiValueQueryStringParameters[name] = append(req.MultiValueQueryStringParameters[name], queryUnescape(value))
+		}
+		req.QueryStringParameters[name] = req.MultiValueQueryStringParameters[name][0]
+	}
+
+	req.RequestContext.RequestID = req.Headers["x-amzn-trace-id"]
+	if req.RequestContext.RequestID == "" {
+		req.RequestContext.RequestID = localRequestID()
+	}
+	req.RequestContext.Identity = events.APIGatewayRequestIdentity{
+		SourceIP:  strings.TrimSpace(strings.Split(req.Headers["x-forwarded-for"], ",")[0]),
+		UserAgent: req.Headers["user-agent"],
+	}
+	return req
+}
+
+// ALBResponse converts a REST API (v1) proxy response into an ALB target group response.
+// ALB requires a status description, and reads only the multi-value headers when they are
+// enabled on the target group and only the single-value ones otherwise.
+//
+// Parameters:
+// - resp: The v1 response.
+// - multiValue: Whether the target group has multi-value headers enabled, i.e. the request had them.
+//
+// Returns:
+// - The ALB response.
+func ALBResponse(resp *events.APIGatewayProxyResponse, multiValue bool) events.ALBTargetGroupResponse {
+	out := events.ALBTargetGroupResponse{
+		StatusCode:        resp.StatusCode,
+		StatusDescription: fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		Body:              resp.Body,
+		IsBase64Encoded:   resp.IsBase64Encoded,
+	}
+	if multiValue {
+		out.MultiValueHeaders = map[string][]string{}
+		for name, value := range resp.Headers {
+			out.MultiValueHeaders[name] = []string{value}
+		}
+		for name, values := range resp.MultiValueHeaders {
+			out.MultiValueHeaders[name] = values
+		}
+		return out
+	}
+
+	out.Headers = map[string]string{}
+	for name, value := range resp.Headers {
+		out.Headers[name] = value
+	}
+	for name, values := range resp.MultiValueHeaders {
+		out.Headers[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// singleToMulti wraps every value of a single-value map in a slice.
+func singleToMulti(in map[string]string) map[string][]string {
+	out := make(map[string][]string, len(in))
+	for name, value := range in {
+		out[name] = []string{value}
+	}
+	return out
+}
+
+// queryUnescape decodes a query string component ALB left encoded, keeping it as is if
+// it isn't validly encoded.
+func queryUnescape(raw string) string {
+	if decoded, err := url.QueryUnescape(raw); err == nil {
+		return decoded
+	}
+	return raw
+}
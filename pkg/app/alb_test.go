@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadFixture reads an invocation payload from testdata.
+func loadFixture(t *testing.T, name string) json.RawMessage {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	return payload
+}
+
+func TestALBRoundTrip(t *testing.T) {
+	tests := []struct {
+		name            string
+		fixture         string
+		wantStatus      int
+		wantDescription string
+		wantMultiValue  bool
+		wantEmail       string
+	}{
+		{name: "get", fixture: "alb_get.json", wantStatus: 200, wantDescription: "200 OK", wantMultiValue: true,
+			wantEmail: "ada@example.com"},
+		{name: "post", fixture: "alb_post.json", wantStatus: 201, wantDescription: "201 Created",
+			wantEmail: "bob@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := user.NewMemoryRepository()
+			for _, email := range []string{"ada@example.com", "grace@example.com"} {
+				if err := repo.Create(&user.User{Email: email, FirstName: "Ada", LastName: "Lovelace", Version: 1}); err != nil {
+					t.Fatalf("seeding: %v", err)
+				}
+			}
+			a := New(&Config{TableName: "users"}, mocks.NewFakeDynamo())
+			a.Repository = repo
+
+			out, err := a.RawHandler(context.Background(), loadFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("RawHandler() error = %v", err)
+			}
+			resp, ok := out.(events.ALBTargetGroupResponse)
+			if !ok {
+				t.Fatalf("RawHandler() = %T, want an ALBTargetGroupResponse", out)
+			}
+			if resp.StatusCode != tt.wantStatus || resp.StatusDescription != tt.wantDescription {
+				t.Fatalf("response = %d %q, want %d %q: %s", resp.StatusCode, resp.StatusDescription,
+					tt.wantStatus, tt.wantDescription, resp.Body)
+			}
+
+			// ALB reads only the headers of the kind the request had
+			contentType := resp.Headers["Content-Type"]
+			if tt.wantMultiValue {
+				if len(resp.Headers) > 0 || len(resp.MultiValueHeaders["Content-Type"]) == 0 {
+					t.Fatalf("headers = %v, multi-value %v; want only multi-value ones", resp.Headers, resp.MultiValueHeaders)
+				}
+				contentType = resp.MultiValueHeaders["Content-Type"][0]
+			} else if len(resp.MultiValueHeaders) > 0 {
+				t.Fatalf("multi-value headers = %v, want none", resp.MultiValueHeaders)
+			}
+			if !strings.HasPrefix(contentType, "application/json") {
+				t.Errorf("Content-Type = %q, want JSON", contentType)
+			}
+
+			var got user.User
+			if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+				t.Fatalf("decoding body %s: %v", resp.Body, err)
+			}
+			if got.Email != tt.wantEmail {
+				t.Errorf("email = %q, want %q", got.Email, tt.wantEmail)
+			}
+		})
+	}
+}
+
+// TestRepeatedQueryParameters checks ALB and v2 requests agree on which of repeated query
+// parameters the handlers see.
+func TestRepeatedQueryParameters(t *testing.T) {
+	alb := ProxyRequestFromALB(events.ALBTargetGroupRequest{
+		MultiValueQueryStringParameters: map[string][]string{"email": {"ada%40example.com", "grace%40example.com"}},
+	})
+	v2 := ProxyRequestFromV2(events.APIGatewayV2HTTPRequest{
+		RawQueryString:        "email=ada%40example.com&email=grace%40example.com",
+		QueryStringParameters: map[string]string{"email": "ada@example.com,grace@example.com"},
+	})
+
+	for name, req := range map[string]events.APIGatewayProxyRequest{"alb": alb, "v2": v2} {
+		if email := req.QueryStringParameters["email"]; email != "ada@example.com" {
+			t.Errorf("%s email = %q, want the first value", name, email)
+		}
+		if emails := req.MultiValueQueryStringParameters["email"]; len(emails) != 2 {
+			t.Errorf("%s emails = %v, want both values", name, emails)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/config"
+	"github.com/Vansh3140/golang-serverless/pkg/handlers"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/metrics"
+	"github.com/Vansh3140/golang-serverless/pkg/tracing"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/Vansh3140/golang-serverless/pkg/webhook"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ConsumedCapacityHeader reports the DynamoDB capacity a request consumed, when
+// DEBUG_CAPACITY is set
+const ConsumedCapacityHeader = "X-Consumed-Capacity"
+
+// App carries the configuration and clients shared by every invocation
+type App struct {
+	Config     *Config                   // Settings loaded at startup
+	DynaClient dynamodbiface.DynamoDBAPI // DynamoDB client used by the handlers
+	Repository user.Repository           // Overrides the DynamoDB user repository, e.g. in memory for local runs
+
+	warmed sync.Once // Pre-warms the DynamoDB connection on the first warm-up ping
+}
+
+// New creates an App.
+//
+// Parameters:
+// - cfg: The validated configuration.
+// - dynaClient: DynamoDB client interface.
+//
+// Returns:
+// - The App, whose Handler can be passed to lambda.Start.
+func New(cfg *Config, dynaClient dynamodbiface.DynamoDBAPI) *App {
+	return &App{Config: cfg, DynaClient: dynaClient}
+}
+
+// Handler processes incoming API Gateway requests and routes them to appropriate handler functions.
+// It supports CRUD operations for user management.
+// Every invocation emits one structured log line summarizing the request and is traced
+// as an X-Ray subsegment when tracing is enabled.
+func (a *App) Handler(ctx context.Context, req events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	start := time.Now()
+
+	// Fetch the secrets referenced by the environment again once SECRETS_REFRESH_SECONDS passed
+	config.Refresh()
+
+	// Decide the API version once, routing every version through the same handlers
+	req = handlers.ResolveVersion(req)
+
+	// Serve the request under the upstream X-Request-Id, if any, so every log line, audit
+	// entry and event of the request carries the ID the client gets back
+	req = handlers.ResolveRequestID(req)
+
+	ctx, seg := tracing.Begin(ctx, "handler")
+	seg.Annotate("method", req.HTTPMethod)
+	seg.Annotate("route", handlers.RouteName(req))
+
+	// Time every DynamoDB call made while serving this request, under the request's trace
+	calls := &logging.CallRecorder{}
+	client := logging.NewTimedClient(tracing.WithContext(ctx, a.DynaClient), calls)
+	resp, err := a.safeRoute(ctx, req, client)
+	if resp != nil {
+		seg.Annotate("status", resp.StatusCode)
+	}
+
+	// Call WEBHOOK_URL with the changes the request made, if any, within the time left
+	webhook.Flush(ctx, req.RequestContext.RequestID)
+	seg.Close(err)
+
+	// Return the request ID on every response, and in the body of errors
+	handlers.AddRequestID(req, resp)
+
+	// Translate error messages to the language the client prefers
+	handlers.LocalizeResponse(req, resp)
+
+	// Report the consumed capacity to whoever is sizing the table
+	addCapacityHeader(resp, calls)
+
+	// Wrap the body in the v2 envelope for v2 requests
+	handlers.EnvelopeResponse(req, resp)
+
+	// Serialize the body as XML for clients that prefer it
+	handlers.NegotiateResponse(req, resp)
+
+	// Compress large bodies for clients that accept gzip
+	handlers.CompressResponse(req, resp)
+
+	// Add CORS headers to every response for allowed origins
+	handlers.AddCORSHeaders(req, resp)
+
+	logRequest(req, resp, err, start, calls)
+	emitMetrics(req, resp, start, calls)
+	return resp, err
+}
+
+// logRequest writes the summary line of a request.
+func logRequest(req events.APIGatewayProxyRequest, resp *events.APIGatewayProxyResponse, err error,
+	start time.Time, calls *logging.CallRecorder) {
+	fields := logging.Fields{
+		"requestId":  req.RequestContext.RequestID,
+		"method":     req.HTTPMethod,
+		"route":      handlers.RouteName(req),
+		"version":    handlers.RequestVersion(req),
+		"durationMs": float64(time.Since(start).Microseconds()) / 1000,
+		"dynamodb":   calls.Calls(),
+	}
+	if resp != nil {
+		fields["status"] = resp.StatusCode
+	}
+	if err != nil {
+		fields["error"] = err
+	}
+	logging.Default.Info("request", fields)
+}
+
+// addCapacityHeader sets the X-Consumed-Capacity header of a response to the read and write
+// capacity units its DynamoDB calls consumed, e.g. "read=1.5, write=2", when
+// DEBUG_CAPACITY=true.
+func addCapacityHeader(resp *events.APIGatewayProxyResponse, calls *logging.CallRecorder) {
+	if resp == nil || os.Getenv("DEBUG_CAPACITY") != "true" {
+		return
+	}
+	if resp.Headers == nil {
+		resp.Headers = map[string]string{}
+	}
+	read, write := calls.Capacity()
+	resp.Headers[ConsumedCapacityHeader] = "read=" + strconv.FormatFloat(read, 'f', -1, 64) +
+		", write=" + strconv.FormatFloat(write, 'f', -1, 64)
+}
+
+// emitMetrics reports a request to CloudWatch, when metrics are enabled. Invocations that
+// returned no response count as 500s.
+func emitMetrics(req events.APIGatewayProxyRequest, resp *events.APIGatewayProxyResponse, start time.Time,
+	calls *logging.CallRecorder) {
+	status := http.StatusInternalServerError
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	var dynamo time.Duration
+	for _, call := range calls.Calls() {
+		dynamo += time.Duration(call.DurationMs * float64(time.Millisecond))
+	}
+	read, write := calls.Capacity()
+	metrics.EmitRequest(metrics.Request{
+		Method:         req.HTTPMethod,
+		Route:          handlers.RouteName(req),
+		Status:         status,
+		Duration:       time.Since(start),
+		DynamoDuration: dynamo,
+		ReadUnits:      read,
+		WriteUnits:     write,
+	})
+}
+
+// safeRoute calls route, converting a panic into a 500 response so the invocation
+// doesn't crash (which API Gateway would surface as an opaque 502).
+// The panic value and stack trace are logged with the request ID.
+func (a *App) safeRoute(ctx context.Context, req events.APIGatewayProxyRequest, dynaClient dynamodbiface.DynamoDBAPI) (
+	resp *events.APIGatewayProxyResponse, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logging.Default.Error("panic while handling request", logging.Fields{
+				"requestId": req.RequestContext.RequestID,
+				"method":    req.HTTPMethod,
+				"route":     handlers.RouteName(req),
+				"panic":     fmt.Sprint(recovered),
+				"stack":     string(debug.Stack()),
+			})
+			resp, err = handlers.APIError(http.StatusInternalServerError, handlers.CodeInternal, handlers.ErrorInternal)
+		}
+	}()
+	return a.route(ctx, req, dynaClient)
+}
+
+// repository returns the user repository serving a request.
+// Unless overridden, users are stored in the configured table through the request's client,
+// and outbox entries are recorded under the request's ID.
+func (a *App) repository(ctx context.Context, req events.APIGatewayProxyRequest, dynaClient dynamodbiface.DynamoDBAPI) user.Repository {
+	if a.Repository != nil {
+		return a.Repository
+	}
+	repo := user.NewDynamoRepository(a.Config.TableName, dynaClient)
+	repo.Context = ctx
+	repo.RequestID = req.RequestContext.RequestID
+	return repo
+}
+
+// route dispatches the request to the handler matching its path and HTTP method.
+func (a *App) route(ctx context.Context, req events.APIGatewayProxyRequest, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	// The health check stays unauthenticated for monitoring
+	if handlers.IsHealthCheck(req) {
+		resp, err := handlers.Health(ctx, req, a.Config.TableName, dynaClient)
+		if req.HTTPMethod == http.MethodHead {
+			handlers.StripBody(resp)
+		}
+		return resp, err
+	}
+
+	// Freeze writes, or every request, while READ_ONLY or MAINTENANCE_MODE is set
+	if unavailable := handlers.CheckServiceMode(req); unavailable != nil {
+		return unavailable, nil
+	}
+
+	// Logging in is how callers get a token, so it can't require one
+	if handlers.IsLoginRequest(req) {
+		if limited := handlers.RateLimit(req, dynaClient); limited != nil {
+			return limited, nil
+		}
+		return handlers.Login(req, a.repository(ctx, req, dynaClient))
+	}
+
+	// Reject unauthenticated or under-scoped callers before doing any work
+	req, denied := handlers.RequireAuth(req, dynaClient)
+	if denied != nil {
+		return denied, nil
+	}
+
+	// Count the request against its client's limit, now that the caller is known
+	if limited := handlers.RateLimit(req, dynaClient); limited != nil {
+		return limited, nil
+	}
+
+	// Dispatch from the route table, rejecting unknown paths and methods before doing any work
+	route, matched := handlers.MatchRoute(req)
+	if route == nil {
+		return handlers.RouteNotFound(req)
+	}
+	if !route.Allows(req.HTTPMethod) {
+		return handlers.UnhandledMethod(req)
+	}
+	return dispatch(route, matched, a.repository(ctx, matched, dynaClient), dynaClient)
+}
+
+// dispatch calls the handler of a route for the request's method. HEAD is served as GET
+// without the body where the route has no HEAD operation of its own, and OPTIONS as a
+// preflight.
+func dispatch(route *handlers.Route, req events.APIGatewayProxyRequest, repo user.Repository,
+	dynaClient dynamodbiface.DynamoDBAPI) (*events.APIGatewayProxyResponse, error) {
+	operation, ok := route.Methods[req.HTTPMethod]
+	if !ok && req.HTTPMethod == http.MethodHead {
+		operation, ok = route.Methods[http.MethodGet]
+	}
+	switch {
+	case ok && operation.Handler != nil:
+		resp, err := operation.Handler(req, repo, dynaClient)
+		if req.HTTPMethod == http.MethodHead {
+			handlers.StripBody(resp)
+		}
+		return resp, err
+	case req.HTTPMethod == http.MethodOptions:
+		return handlers.Preflight(req)
+	default:
+		return handlers.UnhandledMethod(req)
+	}
+}
@@ -0,0 +1,350 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/handlers"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestApp returns an App storing users in memory, seeded with the given emails, and
+// the FakeDynamo standing in for the tables it reaches directly.
+func newTestApp(t *testing.T, emails ...string) (*App, *mocks.FakeDynamo) {
+	t.Helper()
+	repo := user.NewMemoryRepository()
+	for _, email := range emails {
+		seeded := &user.User{Email: email, FirstName: "Ada", LastName: "Lovelace", Version: 1,
+			CreatedAt: "2021-01-01T00:00:00Z", UpdatedAt: "2021-01-01T00:00:00Z"}
+		if err := repo.Create(seeded); err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+	fake := mocks.NewFakeDynamo()
+	a := New(&Config{TableName: "users"}, fake)
+	a.Repository = repo
+	return a, fake
+}
+
+// serve passes a request through the App's handler.
+func serve(t *testing.T, a *App, req events.APIGatewayProxyRequest) *events.APIGatewayProxyResponse {
+	t.Helper()
+	if req.Headers == nil {
+		req.Headers = map[string]string{}
+	}
+	resp, err := a.Handler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	return resp
+}
+
+func TestPathRouting(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "get encoded at", method: http.MethodGet, path: "/users/ada%40example.com", wantStatus: http.StatusOK},
+		{name: "get raw at", method: http.MethodGet, path: "/users/ada@example.com", wantStatus: http.StatusOK},
+		{name: "get plus", method: http.MethodGet, path: "/users/ada+news@example.com", wantStatus: http.StatusOK},
+		{name: "get encoded plus", method: http.MethodGet, path: "/users/ada%2Bnews%40example.com", wantStatus: http.StatusOK},
+		{name: "get mixed case", method: http.MethodGet, path: "/users/Ada%40Example.com", wantStatus: http.StatusOK},
+		{name: "get missing", method: http.MethodGet, path: "/users/bob%40example.com", wantStatus: http.StatusNotFound},
+		{name: "put", method: http.MethodPut, path: "/users/ada%40example.com",
+			body: `{"firstname": "Ada", "lastname": "King"}`, wantStatus: http.StatusOK},
+		{name: "delete", method: http.MethodDelete, path: "/users/ada%2Bnews%40example.com", wantStatus: http.StatusOK},
+		{name: "malformed encoding", method: http.MethodGet, path: "/users/ada%4", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestApp(t, "ada@example.com", "ada+news@example.com")
+			req := events.APIGatewayProxyRequest{HTTPMethod: tt.method, Path: tt.path, Body: tt.body}
+			if tt.body != "" {
+				req.Headers = map[string]string{"Content-Type": "application/json"}
+			}
+
+			resp := serve(t, a, req)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.path, resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+		})
+	}
+}
+
+func TestMixedCaseEmails(t *testing.T) {
+	a, _ := newTestApp(t)
+	created := serve(t, a, events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/users",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    `{"email": "Ada.Lovelace@Example.COM", "firstname": "Ada", "lastname": "Lovelace"}`})
+	if created.StatusCode != http.StatusCreated {
+		t.Fatalf("POST = %d, want 201: %s", created.StatusCode, created.Body)
+	}
+
+	tests := []struct {
+		name string
+		req  events.APIGatewayProxyRequest
+	}{
+		{name: "path", req: events.APIGatewayProxyRequest{Path: "/users/ada.lovelace%40example.com"}},
+		{name: "query", req: events.APIGatewayProxyRequest{Path: "/users",
+			QueryStringParameters: map[string]string{"email": "ada.lovelace@example.com"}}},
+		{name: "mixed case path", req: events.APIGatewayProxyRequest{Path: "/users/ADA.lovelace%40example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.HTTPMethod = http.MethodGet
+			resp := serve(t, a, tt.req)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET = %d, want 200: %s", resp.StatusCode, resp.Body)
+			}
+			var got user.User
+			if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+				t.Fatalf("decoding the response: %v", err)
+			}
+			if got.Email != "ada.lovelace@example.com" || got.CreatedAt == "" {
+				t.Errorf("GET = %+v, want the created record", got)
+			}
+		})
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	var logs bytes.Buffer
+	defer func(logger *logging.Logger) { logging.Default = logger }(logging.Default)
+	logging.Default = logging.New(&logs, logging.LevelInfo)
+
+	fake := mocks.NewFakeDynamo()
+	fake.OnGetItem(func(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+		panic("injected panic")
+	})
+	a := New(&Config{TableName: "users"}, fake)
+
+	resp := serve(t, a, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users/ada%40example.com",
+		RequestContext: events.APIGatewayProxyRequestContext{RequestID: "req-528"}})
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500: %s", resp.StatusCode, resp.Body)
+	}
+	var body handlers.ErrorBody
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+		t.Fatalf("decoding the response: %v", err)
+	}
+	if aws.StringValue(body.Code) != string(handlers.CodeInternal) || aws.StringValue(body.ErrorMsg) != handlers.ErrorInternal ||
+		aws.StringValue(body.RequestID) != "req-528" {
+		t.Errorf("body = %s, want the standard error envelope of request req-528", resp.Body)
+	}
+
+	var logged map[string]interface{}
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, "panic while handling request") {
+			if err := json.Unmarshal([]byte(line), &logged); err != nil {
+				t.Fatalf("decoding the log line: %v", err)
+			}
+		}
+	}
+	if logged["requestId"] != "req-528" || logged["panic"] != "injected panic" || logged["stack"] == "" {
+		t.Errorf("panic log line = %v, want the request ID, panic and stack", logged)
+	}
+}
+
+func TestInternationalEmails(t *testing.T) {
+	tests := []struct {
+		name      string
+		email     string
+		wantEmail string
+		getPath   string
+	}{
+		{name: "unicode local part and domain", email: "用户@例え.jp", wantEmail: "用户@例え.jp",
+			getPath: "/users/%E7%94%A8%E6%88%B7%40%E4%BE%8B%E3%81%88.jp"},
+		{name: "upper-case domain", email: "josé@BÜCHER.example", wantEmail: "josé@bücher.example",
+			getPath: "/users/jos%C3%A9%40b%C3%BCcher.example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOW_IDN_EMAIL", "true")
+			a, _ := newTestApp(t)
+			created := serve(t, a, events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/users",
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    `{"email": "` + tt.email + `", "firstname": "Ada", "lastname": "Lovelace"}`})
+			if created.StatusCode != http.StatusCreated {
+				t.Fatalf("POST = %d, want 201: %s", created.StatusCode, created.Body)
+			}
+
+			resp := serve(t, a, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: tt.getPath})
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET %s = %d, want 200: %s", tt.getPath, resp.StatusCode, resp.Body)
+			}
+			var got user.User
+			if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+				t.Fatalf("decoding the response: %v", err)
+			}
+			if got.Email != tt.wantEmail {
+				t.Errorf("GET email = %q, want %q", got.Email, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestRequestIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		upstream   string
+		wantStatus int
+		wantID     string
+	}{
+		{name: "success", method: http.MethodGet, path: "/users/ada%40example.com", wantStatus: http.StatusOK,
+			wantID: "req-586"},
+		{name: "error", method: http.MethodGet, path: "/users/grace%40example.com", wantStatus: http.StatusNotFound,
+			wantID: "req-586"},
+		{name: "method not allowed", method: "TRACE", path: "/users", wantStatus: http.StatusMethodNotAllowed,
+			wantID: "req-586"},
+		{name: "upstream ID preferred", method: http.MethodGet, path: "/users/grace%40example.com", upstream: "edge-1",
+			wantStatus: http.StatusNotFound, wantID: "edge-1"},
+		{name: "invalid upstream ID ignored", method: http.MethodGet, path: "/users/grace%40example.com",
+			upstream: "edge 1", wantStatus: http.StatusNotFound, wantID: "req-586"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestApp(t, "ada@example.com")
+			req := events.APIGatewayProxyRequest{HTTPMethod: tt.method, Path: tt.path, Headers: map[string]string{},
+				RequestContext: events.APIGatewayProxyRequestContext{RequestID: "req-586"}}
+			if tt.upstream != "" {
+				req.Headers[handlers.RequestIDHeader] = tt.upstream
+			}
+
+			resp := serve(t, a, req)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("%s %s = %d, want %d: %s", tt.method, tt.path, resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			if got := resp.Headers[handlers.RequestIDHeader]; got != tt.wantID {
+				t.Errorf("%s = %q, want %q", handlers.RequestIDHeader, got, tt.wantID)
+			}
+			if resp.StatusCode < http.StatusBadRequest {
+				return
+			}
+			var body handlers.ErrorBody
+			if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+				t.Fatalf("body %s isn't an error: %v", resp.Body, err)
+			}
+			if aws.StringValue(body.RequestID) != tt.wantID {
+				t.Errorf("requestId = %q, want %q", aws.StringValue(body.RequestID), tt.wantID)
+			}
+		})
+	}
+}
+
+func TestConsumedCapacityHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		debug      string
+		wantHeader string
+	}{
+		{name: "debug", debug: "true", wantHeader: "read=0.5, write=0"},
+		{name: "not debug"},
+		{name: "other value", debug: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEBUG_CAPACITY", tt.debug)
+			fake := mocks.NewFakeDynamo()
+			fake.OnGetItem(func(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+				return &dynamodb.GetItemOutput{ConsumedCapacity: &dynamodb.ConsumedCapacity{
+					TableName: in.TableName, CapacityUnits: aws.Float64(0.5)}}, nil
+			})
+			a := New(&Config{TableName: "users"}, fake)
+
+			resp := serve(t, a, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users/ada%40example.com"})
+			got, ok := resp.Headers[ConsumedCapacityHeader]
+			if got != tt.wantHeader || ok != (tt.wantHeader != "") {
+				t.Errorf("%s = %q (set %v), want %q", ConsumedCapacityHeader, got, ok, tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestStorageModes(t *testing.T) {
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "create ada", method: http.MethodPost, path: "/users",
+			body: `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`, wantStatus: http.StatusCreated},
+		{name: "create grace", method: http.MethodPost, path: "/users",
+			body: `{"email": "grace@example.com", "firstname": "Grace", "lastname": "Hopper"}`, wantStatus: http.StatusCreated},
+		{name: "get", method: http.MethodGet, path: "/users/ada%40example.com", wantStatus: http.StatusOK},
+		{name: "list", method: http.MethodGet, path: "/users", wantStatus: http.StatusOK},
+		{name: "update", method: http.MethodPut, path: "/users/ada%40example.com",
+			body: `{"firstname": "Ada", "lastname": "King"}`, wantStatus: http.StatusOK},
+		{name: "get updated", method: http.MethodGet, path: "/users/ada%40example.com", wantStatus: http.StatusOK},
+		{name: "delete", method: http.MethodDelete, path: "/users/ada%40example.com", wantStatus: http.StatusOK},
+		{name: "get deleted", method: http.MethodGet, path: "/users/ada%40example.com", wantStatus: http.StatusNotFound},
+		{name: "list after the delete", method: http.MethodGet, path: "/users", wantStatus: http.StatusOK},
+	}
+
+	// Each mode runs the steps; the bodies must not tell the modes apart
+	bodies := map[string][]interface{}{}
+	for _, mode := range []string{"false", "true"} {
+		t.Run("SINGLE_TABLE="+mode, func(t *testing.T) {
+			t.Setenv("SINGLE_TABLE", mode)
+			fake := newItemTable()
+			a := New(&Config{TableName: "users"}, fake)
+
+			for _, step := range steps {
+				resp := serve(t, a, events.APIGatewayProxyRequest{HTTPMethod: step.method, Path: step.path, Body: step.body,
+					Headers: map[string]string{"Content-Type": "application/json"}})
+				if resp.StatusCode != step.wantStatus {
+					t.Fatalf("%s: status = %d, want %d: %s", step.name, resp.StatusCode, step.wantStatus, resp.Body)
+				}
+				var document interface{}
+				if err := json.Unmarshal([]byte(resp.Body), &document); err != nil {
+					t.Fatalf("%s: body %s isn't JSON: %v", step.name, resp.Body, err)
+				}
+				bodies[mode] = append(bodies[mode], withoutVolatileFields(document))
+			}
+
+			// Single-table items are keyed by PK and SK, and listed from the entity index
+			puts := fake.Inputs("PutItem")
+			if len(puts) == 0 {
+				t.Fatalf("no PutItem calls")
+			}
+			item := puts[0].(*dynamodb.PutItemInput).Item
+			scans, queries := len(fake.Inputs("Scan")), fake.Inputs("Query")
+			if mode == "true" {
+				if aws.StringValue(item[user.PartitionKeyAttribute].S) != "USER#ada@example.com" ||
+					aws.StringValue(item[user.SortKeyAttribute].S) != user.DefaultUserSortKey ||
+					aws.StringValue(item[user.EntityTypeAttribute].S) != user.DefaultUserEntityType {
+					t.Errorf("stored item = %v, want PK, SK and entityType set", item)
+				}
+				if scans != 0 || len(queries) == 0 ||
+					aws.StringValue(queries[0].(*dynamodb.QueryInput).IndexName) != user.DefaultEntityIndex {
+					t.Errorf("%d scans and queries %v, want the lists to query %s", scans, queries, user.DefaultEntityIndex)
+				}
+				return
+			}
+			if aws.StringValue(item[user.DefaultKeyAttribute].S) != "ada@example.com" || item[user.PartitionKeyAttribute] != nil {
+				t.Errorf("stored item = %v, want it keyed by email", item)
+			}
+			if scans == 0 || len(queries) != 0 {
+				t.Errorf("%d scans and %d queries, want the lists to scan", scans, len(queries))
+			}
+		})
+	}
+	if !reflect.DeepEqual(bodies["false"], bodies["true"]) {
+		t.Errorf("bodies differ between the modes:\n%v\n%v", bodies["false"], bodies["true"])
+	}
+}
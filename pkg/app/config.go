@@ -0,0 +1,44 @@
+package app
+
+import (
+	"fmt"
+	"os"
+)
+
+// ErrorMissingEnv is returned when a required environment variable is not set
+var ErrorMissingEnv = "missing required environment variable"
+
+// Config holds the settings the function needs at startup
+type Config struct {
+	Region    string // AWS region of the DynamoDB table (AWS_REGION)
+	TableName string // DynamoDB table storing the users (TABLE_NAME)
+
+	DynamoDBEndpoint string // Optional endpoint override for DynamoDB Local or LocalStack (DYNAMODB_ENDPOINT)
+	AutoCreateTable  bool   // Create the table at startup if it is missing (AUTO_CREATE_TABLE=true)
+	DAXEndpoint      string // Optional DAX cluster endpoint serving item reads and writes (DAX_ENDPOINT)
+}
+
+// LoadConfig reads the configuration from the environment.
+//
+// Returns:
+// - The configuration.
+// - An error naming the first required variable that is unset.
+func LoadConfig() (*Config, error) {
+	cfg := &Config{
+		Region:    os.Getenv("AWS_REGION"),
+		TableName: os.Getenv("TABLE_NAME"),
+
+		DynamoDBEndpoint: os.Getenv("DYNAMODB_ENDPOINT"),
+		AutoCreateTable:  os.Getenv("AUTO_CREATE_TABLE") == "true",
+		DAXEndpoint:      os.Getenv("DAX_ENDPOINT"),
+	}
+
+	// Fail fast instead of letting every DynamoDB call fail with an empty table name
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("%s: AWS_REGION", ErrorMissingEnv)
+	}
+	if cfg.TableName == "" {
+		return nil, fmt.Errorf("%s: TABLE_NAME", ErrorMissingEnv)
+	}
+	return cfg, nil
+}
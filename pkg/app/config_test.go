@@ -0,0 +1,55 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantMissing string
+		want        Config
+	}{
+		{name: "required", env: map[string]string{"AWS_REGION": "eu-west-1", "TABLE_NAME": "users"},
+			want: Config{Region: "eu-west-1", TableName: "users"}},
+		{name: "optional", env: map[string]string{"AWS_REGION": "eu-west-1", "TABLE_NAME": "users",
+			"DYNAMODB_ENDPOINT": "http://localhost:8000", "AUTO_CREATE_TABLE": "true", "DAX_ENDPOINT": "dax://cluster"},
+			want: Config{Region: "eu-west-1", TableName: "users", DynamoDBEndpoint: "http://localhost:8000",
+				AutoCreateTable: true, DAXEndpoint: "dax://cluster"}},
+		{name: "auto-create not true", env: map[string]string{"AWS_REGION": "eu-west-1", "TABLE_NAME": "users",
+			"AUTO_CREATE_TABLE": "yes"}, want: Config{Region: "eu-west-1", TableName: "users"}},
+		{name: "no region", env: map[string]string{"TABLE_NAME": "users"}, wantMissing: "AWS_REGION"},
+		{name: "no table", env: map[string]string{"AWS_REGION": "eu-west-1"}, wantMissing: "TABLE_NAME"},
+		{name: "nothing", wantMissing: "AWS_REGION"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"AWS_REGION", "TABLE_NAME", "DYNAMODB_ENDPOINT", "AUTO_CREATE_TABLE", "DAX_ENDPOINT"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			cfg, err := LoadConfig()
+			if tt.wantMissing != "" {
+				if err == nil || !strings.Contains(err.Error(), ErrorMissingEnv) || !strings.HasSuffix(err.Error(), tt.wantMissing) {
+					t.Fatalf("LoadConfig() error = %v, want one naming %s", err, tt.wantMissing)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if *cfg != tt.want {
+				t.Errorf("LoadConfig() = %+v, want %+v", *cfg, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCarriesTheClient(t *testing.T) {
+	a, fake := newTestApp(t)
+	if a.DynaClient != fake || a.Config.TableName != "users" {
+		t.Errorf("New() = %+v, want the given config and client", a)
+	}
+}
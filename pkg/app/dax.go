@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"github.com/aws/aws-dax-go/dax"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+)
+
+// daxClient sends item reads and writes through a DAX cluster and everything else, such as
+// the DescribeTable of the health check, to DynamoDB. Strongly consistent reads go to
+// DynamoDB too, since DAX only serves eventually consistent ones.
+type daxClient struct {
+	dynamodbiface.DynamoDBAPI // DynamoDB, for the operations DAX doesn't serve
+	dax                       dynamodbiface.DynamoDBAPI
+}
+
+// NewDAXClient connects to the DAX cluster at cfg.DAXEndpoint, whose cache then serves the
+// item reads and writes of the function. DAX writes through to the table, so the cache
+// stays in sync with the writes made through it.
+//
+// Parameters:
+// - cfg: The validated configuration, with DAXEndpoint set.
+// - dynaClient: The DynamoDB client, used for the operations DAX doesn't serve.
+//
+// Returns:
+// - A client combining DAX and DynamoDB.
+// - An error if the DAX client can't be created.
+func NewDAXClient(cfg *Config, dynaClient dynamodbiface.DynamoDBAPI) (dynamodbiface.DynamoDBAPI, error) {
+	daxConfig := dax.DefaultConfig()
+	daxConfig.HostPorts = strings.Split(cfg.DAXEndpoint, ",")
+	daxConfig.Region = cfg.Region
+	cluster, err := dax.New(daxConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewCachedClient(cluster, dynaClient), nil
+}
+
+// NewCachedClient combines a DAX client with a DynamoDB client, as NewDAXClient does. Tests
+// can pass any implementation of the DynamoDB API standing in for DAX.
+//
+// Parameters:
+// - cache: The DAX client.
+// - dynaClient: The DynamoDB client.
+//
+// Returns:
+// - The combined client.
+func NewCachedClient(cache dynamodbiface.DynamoDBAPI, dynaClient dynamodbiface.DynamoDBAPI) dynamodbiface.DynamoDBAPI {
+	return &daxClient{DynamoDBAPI: dynaClient, dax: cache}
+}
+
+// reader returns the client serving a read, which is DynamoDB if the read is strongly consistent.
+func (c *daxClient) reader(consistent *bool) dynamodbiface.DynamoDBAPI {
+	if aws.BoolValue(consistent) {
+		return c.DynamoDBAPI
+	}
+	return c.dax
+}
+
+// GetItem runs dynamodb.GetItem through DAX, unless it is strongly consistent.
+func (c *daxClient) GetItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return c.GetItemWithContext(context.Background(), in)
+}
+
+// GetItemWithContext runs dynamodb.GetItemWithContext through DAX, unless it is strongly consistent.
+func (c *daxClient) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput,
+	opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	return c.reader(in.ConsistentRead).GetItemWithContext(ctx, in, opts...)
+}
+
+// Scan runs dynamodb.Scan through DAX, unless it is strongly consistent.
+func (c *daxClient) Scan(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	return c.ScanWithContext(context.Background(), in)
+}
+
+// ScanWithContext runs dynamodb.ScanWithContext through DAX, unless it is strongly consistent.
+func (c *daxClient) ScanWithContext(ctx aws.Context, in *dynamodb.ScanInput,
+	opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	return c.reader(in.ConsistentRead).ScanWithContext(ctx, in, opts...)
+}
+
+// Query runs dynamodb.Query through DAX, unless it is strongly consistent.
+func (c *daxClient) Query(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	return c.QueryWithContext(context.Background(), in)
+}
+
+// QueryWithContext runs dynamodb.QueryWithContext through DAX, unless it is strongly consistent.
+func (c *daxClient) QueryWithContext(ctx aws.Context, in *dynamodb.QueryInput,
+	opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	return c.reader(in.ConsistentRead).QueryWithContext(ctx, in, opts...)
+}
+
+// BatchGetItem runs dynamodb.BatchGetItem through DAX, unless it reads a table strongly consistently.
+func (c *daxClient) BatchGetItem(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	return c.BatchGetItemWithContext(context.Background(), in)
+}
+
+// BatchGetItemWithContext runs dynamodb.BatchGetItemWithContext through DAX, unless it reads
+// a table strongly consistently.
+func (c *daxClient) BatchGetItemWithContext(ctx aws.Context, in *dynamodb.BatchGetItemInput,
+	opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	for _, keys := range in.RequestItems {
+		if keys != nil && aws.BoolValue(keys.ConsistentRead) {
+			return c.DynamoDBAPI.BatchGetItemWithContext(ctx, in, opts...)
+		}
+	}
+	return c.dax.BatchGetItemWithContext(ctx, in, opts...)
+}
+
+// PutItem runs dynamodb.PutItem through DAX.
+func (c *daxClient) PutItem(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return c.PutItemWithContext(context.Background(), in)
+}
+
+// PutItemWithContext runs dynamodb.PutItemWithContext through DAX.
+func (c *daxClient) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput,
+	opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	return c.dax.PutItemWithContext(ctx, in, opts...)
+}
+
+// UpdateItem runs dynamodb.UpdateItem through DAX.
+func (c *daxClient) UpdateItem(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	return c.UpdateItemWithContext(context.Background(), in)
+}
+
+// UpdateItemWithContext runs dynamodb.UpdateItemWithContext through DAX.
+func (c *daxClient) UpdateItemWithContext(ctx aws.Context, in *dynamodb.UpdateItemInput,
+	opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	return c.dax.UpdateItemWithContext(ctx, in, opts...)
+}
+
+// DeleteItem runs dynamodb.DeleteItem through DAX.
+func (c *daxClient) DeleteItem(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	return c.DeleteItemWithContext(context.Background(), in)
+}
+
+// DeleteItemWithContext runs dynamodb.DeleteItemWithContext through DAX.
+func (c *daxClient) DeleteItemWithContext(ctx aws.Context, in *dynamodb.DeleteItemInput,
+	opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	return c.dax.DeleteItemWithContext(ctx, in, opts...)
+}
+
+// BatchWriteItem runs dynamodb.BatchWriteItem through DAX.
+func (c *daxClient) BatchWriteItem(in *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	return c.BatchWriteItemWithContext(context.Background(), in)
+}
+
+// BatchWriteItemWithContext runs dynamodb.BatchWriteItemWithContext through DAX.
+func (c *daxClient) BatchWriteItemWithContext(ctx aws.Context, in *dynamodb.BatchWriteItemInput,
+	opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	return c.dax.BatchWriteItemWithContext(ctx, in, opts...)
+}
+
+// TransactWriteItems runs dynamodb.TransactWriteItems through DAX.
+func (c *daxClient) TransactWriteItems(in *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return c.TransactWriteItemsWithContext(context.Background(), in)
+}
+
+// TransactWriteItemsWithContext runs dynamodb.TransactWriteItemsWithContext through DAX.
+func (c *daxClient) TransactWriteItemsWithContext(ctx aws.Context, in *dynamodb.TransactWriteItemsInput,
+	opts ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	return c.dax.TransactWriteItemsWithContext(ctx, in, opts...)
+}
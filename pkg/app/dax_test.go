@@ -0,0 +1,110 @@
+package app
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"testing"
+)
+
+func TestCachedClientRouting(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		call      func(client dynamodbiface.DynamoDBAPI)
+		wantDAX   bool
+	}{
+		{name: "get", operation: "GetItem", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.GetItem(&dynamodb.GetItemInput{TableName: aws.String("users")})
+		}, wantDAX: true},
+		{name: "consistent get", operation: "GetItem", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.GetItem(&dynamodb.GetItemInput{TableName: aws.String("users"), ConsistentRead: aws.Bool(true)})
+		}},
+		{name: "scan", operation: "Scan", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.Scan(&dynamodb.ScanInput{TableName: aws.String("users"), ConsistentRead: aws.Bool(false)})
+		}, wantDAX: true},
+		{name: "consistent scan", operation: "Scan", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.Scan(&dynamodb.ScanInput{TableName: aws.String("users"), ConsistentRead: aws.Bool(true)})
+		}},
+		{name: "query", operation: "Query", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.Query(&dynamodb.QueryInput{TableName: aws.String("users")})
+		}, wantDAX: true},
+		{name: "consistent query", operation: "Query", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.Query(&dynamodb.QueryInput{TableName: aws.String("users"), ConsistentRead: aws.Bool(true)})
+		}},
+		{name: "batch get", operation: "BatchGetItem", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: map[string]*dynamodb.KeysAndAttributes{
+				"users": {}, "orgs": {ConsistentRead: aws.Bool(false)}}})
+		}, wantDAX: true},
+		{name: "batch get, one table consistent", operation: "BatchGetItem", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: map[string]*dynamodb.KeysAndAttributes{
+				"users": {}, "orgs": {ConsistentRead: aws.Bool(true)}}})
+		}},
+		{name: "put", operation: "PutItem", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.PutItem(&dynamodb.PutItemInput{TableName: aws.String("users")})
+		}, wantDAX: true},
+		{name: "update", operation: "UpdateItem", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.UpdateItem(&dynamodb.UpdateItemInput{TableName: aws.String("users")})
+		}, wantDAX: true},
+		{name: "delete", operation: "DeleteItem", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.DeleteItem(&dynamodb.DeleteItemInput{TableName: aws.String("users")})
+		}, wantDAX: true},
+		{name: "batch write", operation: "BatchWriteItem", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.BatchWriteItem(&dynamodb.BatchWriteItemInput{})
+		}, wantDAX: true},
+		{name: "transaction", operation: "TransactWriteItems", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{})
+		}, wantDAX: true},
+		{name: "describe table", operation: "DescribeTable", call: func(client dynamodbiface.DynamoDBAPI) {
+			client.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("users")})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dax, dynamo := mocks.NewFakeDynamo(), mocks.NewFakeDynamo()
+			tt.call(NewCachedClient(dax, dynamo))
+
+			wantDAX, wantDynamo := 0, 1
+			if tt.wantDAX {
+				wantDAX, wantDynamo = 1, 0
+			}
+			if got := len(dax.Inputs(tt.operation)); got != wantDAX {
+				t.Errorf("%s calls to DAX = %d, want %d", tt.operation, got, wantDAX)
+			}
+			if got := len(dynamo.Inputs(tt.operation)); got != wantDynamo {
+				t.Errorf("%s calls to DynamoDB = %d, want %d", tt.operation, got, wantDynamo)
+			}
+		})
+	}
+}
+
+func TestConsistentReadsBypassDAX(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   map[string]string
+		wantDAX bool
+	}{
+		{name: "default", wantDAX: true},
+		{name: "consistent", query: map[string]string{"consistent": "true"}},
+		{name: "not consistent", query: map[string]string{"consistent": "false"}, wantDAX: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dax, dynamo := mocks.NewFakeDynamo(), mocks.NewFakeDynamo()
+			a := New(&Config{TableName: "users"}, NewCachedClient(dax, dynamo))
+
+			resp := serve(t, a, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users/ada%40example.com",
+				QueryStringParameters: tt.query})
+			if resp.StatusCode != http.StatusNotFound {
+				t.Fatalf("GET = %d, want 404 from the empty table: %s", resp.StatusCode, resp.Body)
+			}
+			fromDAX, fromDynamo := len(dax.Inputs("GetItem")), len(dynamo.Inputs("GetItem"))
+			if (fromDAX > 0) != tt.wantDAX || (fromDynamo > 0) == tt.wantDAX {
+				t.Errorf("GetItem calls to DAX, DynamoDB = %d, %d; want DAX: %v", fromDAX, fromDynamo, tt.wantDAX)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/Vansh3140/golang-serverless/pkg/handlers"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/aws/aws-lambda-go/events"
+	"io/ioutil"
+	"net/http"
+	"unicode/utf8"
+)
+
+// ServeHTTP lets the App run behind a net/http server for local development.
+// The request is converted to the API Gateway proxy event the Lambda receives and goes
+// through the same Handler, so routing and handlers behave exactly as when deployed.
+//
+// Parameters:
+// - w: Writer receiving the converted response.
+// - r: The incoming HTTP request.
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := ProxyRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := a.Handler(r.Context(), req)
+	if err != nil || resp == nil {
+		// API Gateway answers a failed invocation with a 502
+		logging.Default.Error("handler returned no response", logging.Fields{"requestId": req.RequestContext.RequestID, "error": err})
+		http.Error(w, handlers.ErrorInternal, http.StatusBadGateway)
+		return
+	}
+	writeProxyResponse(w, resp)
+}
+
+// ProxyRequest converts an HTTP request into an API Gateway REST API proxy event.
+//
+// Parameters:
+// - r: The HTTP request to convert.
+//
+// Returns:
+// - The proxy event carrying the method, path, query string, headers and body.
+// - An error if the body can't be read.
+func ProxyRequest(r *http.Request) (events.APIGatewayProxyRequest, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, err
+	}
+
+	req := events.APIGatewayProxyRequest{
+		Path:                            r.URL.Path,
+		HTTPMethod:                      r.Method,
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string{},
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string{},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID:  localRequestID(),
+			Path:       r.URL.Path,
+			HTTPMethod: r.Method,
+			Identity:   events.APIGatewayRequestIdentity{SourceIP: r.RemoteAddr, UserAgent: r.UserAgent()},
+		},
+	}
+	for name, values := range r.Header {
+		req.Headers[name] = values[len(values)-1]
+		req.MultiValueHeaders[name] = values
+	}
+	if r.Host != "" {
+		req.Headers["Host"] = r.Host
+	}
+	for name, values := range r.URL.Query() {
+		req.QueryStringParameters[name] = values[len(values)-1]
+		req.MultiValueQueryStringParameters[name] = values
+	}
+
+	// API Gateway base64-encodes bodies that aren't text
+	if utf8.Valid(body) {
+		req.Body = string(body)
+	} else {
+		req.Body = base64.StdEncoding.EncodeToString(body)
+		req.IsBase64Encoded = true
+	}
+	return req, nil
+}
+
+// writeProxyResponse writes an API Gateway proxy response to an HTTP response writer.
+func writeProxyResponse(w http.ResponseWriter, resp *events.APIGatewayProxyResponse) {
+	for name, value := range resp.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range resp.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			http.Error(w, handlers.ErrorInternal, http.StatusBadGateway)
+			return
+		}
+		body = decoded
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(body)
+}
+
+// localRequestID returns a random ID standing in for the API Gateway request ID.
+func localRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
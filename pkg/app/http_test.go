@@ -0,0 +1,133 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newItemTable returns a FakeDynamo keeping the users it is sent in a map, like a table
+// would, without checking conditions. Scans and queries return the users in email order,
+// queries only those of the entity type they ask for, and neither evaluates filters. Items
+// are copied in and out, since readers decode them in place.
+func newItemTable() *mocks.FakeDynamo {
+	items := map[string]map[string]*dynamodb.AttributeValue{}
+	fake := mocks.NewFakeDynamo()
+	fake.OnGetItem(func(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+		return &dynamodb.GetItemOutput{Item: copyItem(items[user.KeyEmail(in.Key)])}, nil
+	})
+	fake.OnPutItem(func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+		items[user.KeyEmail(in.Item)] = copyItem(in.Item)
+		return &dynamodb.PutItemOutput{}, nil
+	})
+	fake.OnDeleteItem(func(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+		delete(items, user.KeyEmail(in.Key))
+		return &dynamodb.DeleteItemOutput{}, nil
+	})
+	sorted := func(keep func(item map[string]*dynamodb.AttributeValue) bool) []map[string]*dynamodb.AttributeValue {
+		emails := make([]string, 0, len(items))
+		for email := range items {
+			emails = append(emails, email)
+		}
+		sort.Strings(emails)
+		var kept []map[string]*dynamodb.AttributeValue
+		for _, email := range emails {
+			if keep(items[email]) {
+				kept = append(kept, copyItem(items[email]))
+			}
+		}
+		return kept
+	}
+	fake.OnScan(func(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+		found := sorted(func(map[string]*dynamodb.AttributeValue) bool { return true })
+		return &dynamodb.ScanOutput{Items: found, Count: aws.Int64(int64(len(found)))}, nil
+	})
+	fake.OnQuery(func(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+		entityType := in.ExpressionAttributeValues[":entityType"]
+		found := sorted(func(item map[string]*dynamodb.AttributeValue) bool {
+			return entityType != nil && item[user.EntityTypeAttribute] != nil &&
+				aws.StringValue(item[user.EntityTypeAttribute].S) == aws.StringValue(entityType.S)
+		})
+		return &dynamodb.QueryOutput{Items: found, Count: aws.Int64(int64(len(found)))}, nil
+	})
+	return fake
+}
+
+// copyItem returns a copy of an item, or nil for no item.
+func copyItem(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	copied := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, value := range item {
+		copied[name] = value
+	}
+	return copied
+}
+
+// TestLocalServer runs a create, read, update and delete cycle against the local server,
+// backed by a fake DynamoDB client.
+func TestLocalServer(t *testing.T) {
+	server := httptest.NewServer(New(&Config{TableName: "users"}, newItemTable()))
+	defer server.Close()
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantName   string
+	}{
+		{name: "create", method: http.MethodPost, path: "/users",
+			body:       `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`,
+			wantStatus: http.StatusCreated, wantName: "Lovelace"},
+		{name: "read", method: http.MethodGet, path: "/users/ada%40example.com", wantStatus: http.StatusOK, wantName: "Lovelace"},
+		{name: "update", method: http.MethodPut, path: "/users/ada%40example.com",
+			body: `{"firstname": "Ada", "lastname": "King"}`, wantStatus: http.StatusOK, wantName: "King"},
+		{name: "read updated", method: http.MethodGet, path: "/users?email=ada%40example.com", wantStatus: http.StatusOK,
+			wantName: "King"},
+		{name: "delete", method: http.MethodDelete, path: "/users/ada%40example.com", wantStatus: http.StatusOK},
+		{name: "read deleted", method: http.MethodGet, path: "/users/ada%40example.com", wantStatus: http.StatusNotFound},
+		{name: "unknown path", method: http.MethodGet, path: "/teams", wantStatus: http.StatusNotFound},
+	}
+	for _, step := range steps {
+		req, err := http.NewRequest(step.method, server.URL+step.path, strings.NewReader(step.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if step.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, resp.StatusCode, step.wantStatus, body)
+		}
+		if resp.Header.Get("X-Request-Id") == "" {
+			t.Errorf("%s: no X-Request-Id header", step.name)
+		}
+		if step.wantName != "" {
+			var got user.User
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("%s: decoding %s: %v", step.name, body, err)
+			}
+			if got.LastName != step.wantName {
+				t.Errorf("%s: lastname = %q, want %q", step.name, got.LastName, step.wantName)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+//go:build integration
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestIntegrationCRUD creates a table on DynamoDB Local or LocalStack and runs the create,
+// read, update and delete cycle through the real handlers. Run it with
+//
+//	DYNAMODB_ENDPOINT=http://localhost:8000 go test -tags integration ./pkg/app
+func TestIntegrationCRUD(t *testing.T) {
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DYNAMODB_ENDPOINT is not set")
+	}
+	cfg := &Config{
+		Region:           "us-east-1",
+		TableName:        "users-it-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		DynamoDBEndpoint: endpoint,
+		AutoCreateTable:  true,
+	}
+	awsSession, err := NewSession(cfg)
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	dynaClient := dynamodb.New(awsSession)
+	if err := EnsureTable(cfg, dynaClient); err != nil {
+		t.Fatalf("EnsureTable() error = %v", err)
+	}
+	defer func() {
+		_, _ = dynaClient.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(cfg.TableName)})
+	}()
+	a := New(cfg, dynaClient)
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantName   string
+	}{
+		{name: "create", method: http.MethodPost, path: "/users",
+			body:       `{"email": "Ada@Example.com", "firstname": "Ada", "lastname": "Lovelace"}`,
+			wantStatus: http.StatusCreated, wantName: "Lovelace"},
+		{name: "duplicate", method: http.MethodPost, path: "/users",
+			body:       `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`,
+			wantStatus: http.StatusConflict},
+		{name: "read", method: http.MethodGet, path: "/users/ada%40example.com", wantStatus: http.StatusOK, wantName: "Lovelace"},
+		{name: "update", method: http.MethodPut, path: "/users/ada%40example.com",
+			body: `{"firstname": "Ada", "lastname": "King"}`, wantStatus: http.StatusOK, wantName: "King"},
+		{name: "read updated", method: http.MethodGet, path: "/users/ada%40example.com", wantStatus: http.StatusOK, wantName: "King"},
+		{name: "delete", method: http.MethodDelete, path: "/users/ada%40example.com", wantStatus: http.StatusOK},
+		{name: "read deleted", method: http.MethodGet, path: "/users/ada%40example.com", wantStatus: http.StatusNotFound},
+		{name: "delete again", method: http.MethodDelete, path: "/users/ada%40example.com", wantStatus: http.StatusNotFound},
+	}
+	for _, step := range steps {
+		req := events.APIGatewayProxyRequest{HTTPMethod: step.method, Path: step.path, Body: step.body,
+			Headers: map[string]string{"Content-Type": "application/json"}}
+		resp, err := a.Handler(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: Handler() error = %v", step.name, err)
+		}
+		if resp.StatusCode != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, resp.StatusCode, step.wantStatus, resp.Body)
+		}
+		if step.wantName != "" {
+			var got user.User
+			if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+				t.Fatalf("%s: decoding %s: %v", step.name, resp.Body, err)
+			}
+			if got.LastName != step.wantName || got.Email != "ada@example.com" {
+				t.Errorf("%s: user = %s %s, want ada@example.com %s", step.name, got.Email, got.LastName, step.wantName)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/handlers"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"testing"
+)
+
+func TestLocalizedErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		v2           bool
+		language     string
+		wantLanguage string
+		wantMessage  string
+	}{
+		{name: "spanish v1", path: "/users/nobody%40example.com", language: "es-MX, en;q=0.5", wantLanguage: "es",
+			wantMessage: "El usuario no existe."},
+		{name: "hindi v2", path: "/v2/users/nobody%40example.com", v2: true, language: "hi-IN", wantLanguage: "hi",
+			wantMessage: "उपयोगकर्ता मौजूद नहीं है।"},
+		{name: "unsupported", path: "/users/nobody%40example.com", language: "fr", wantLanguage: "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestApp(t, "ada@example.com")
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: tt.path,
+				Headers:        map[string]string{"Accept-Language": tt.language},
+				RequestContext: events.APIGatewayProxyRequestContext{RequestID: "req-1"}}
+
+			resp := serve(t, a, req)
+			if resp.StatusCode != http.StatusNotFound {
+				t.Fatalf("status = %d, want 404: %s", resp.StatusCode, resp.Body)
+			}
+			if resp.Headers["Content-Language"] != tt.wantLanguage {
+				t.Errorf("Content-Language = %q, want %q", resp.Headers["Content-Language"], tt.wantLanguage)
+			}
+
+			got := &handlers.EnvelopeError{}
+			if tt.v2 {
+				var envelope handlers.Envelope
+				if err := json.Unmarshal([]byte(resp.Body), &envelope); err != nil || envelope.Error == nil {
+					t.Fatalf("body %s isn't an enveloped error: %v", resp.Body, err)
+				}
+				got = envelope.Error
+			} else {
+				var flat handlers.ErrorBody
+				if err := json.Unmarshal([]byte(resp.Body), &flat); err != nil || flat.Code == nil || flat.ErrorMsg == nil ||
+					flat.RequestID == nil {
+					t.Fatalf("body %s isn't an error: %v", resp.Body, err)
+				}
+				got.Code, got.Message, got.RequestID = handlers.ErrorCode(*flat.Code), *flat.ErrorMsg, *flat.RequestID
+			}
+			wantCode := handlers.CodeNotFound
+			if tt.v2 {
+				wantCode = handlers.CodeUserNotFound
+			}
+			if got.Code != wantCode {
+				t.Errorf("code = %s, want %s untouched", got.Code, wantCode)
+			}
+			if tt.wantMessage != "" && got.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", got.Message, tt.wantMessage)
+			}
+			if got.RequestID != "req-1" {
+				t.Errorf("requestId = %q, want it kept", got.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/handlers"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"net/http"
+	"testing"
+)
+
+func TestServiceModes(t *testing.T) {
+	requests := map[string]events.APIGatewayProxyRequest{
+		"GET":    {HTTPMethod: http.MethodGet, Path: "/users/ada%40example.com"},
+		"HEAD":   {HTTPMethod: http.MethodHead, Path: "/users/ada%40example.com"},
+		"POST":   {HTTPMethod: http.MethodPost, Path: "/users", Body: `{"email": "bob@example.com", "firstname": "Bob", "lastname": "Barker"}`},
+		"PUT":    {HTTPMethod: http.MethodPut, Path: "/users/ada%40example.com", Body: `{"firstname": "Ada", "lastname": "King"}`},
+		"PATCH":  {HTTPMethod: http.MethodPatch, Path: "/users/ada%40example.com", Body: `{"lastname": "King"}`},
+		"DELETE": {HTTPMethod: http.MethodDelete, Path: "/users/ada%40example.com"},
+		"login":  {HTTPMethod: http.MethodPost, Path: handlers.LoginPath, Body: `{"email": "ada@example.com", "password": "x"}`},
+		"health": {HTTPMethod: http.MethodGet, Path: handlers.HealthPath},
+	}
+	tests := []struct {
+		name           string
+		env            map[string]string
+		request        string
+		wantMessage    string // The message of the 503, or "" if the request goes through
+		wantRetryAfter string
+	}{
+		{name: "normal POST", request: "POST"},
+		{name: "normal DELETE", request: "DELETE"},
+		{name: "read-only GET", env: map[string]string{"READ_ONLY": "true"}, request: "GET"},
+		{name: "read-only HEAD", env: map[string]string{"READ_ONLY": "true"}, request: "HEAD"},
+		{name: "read-only POST", env: map[string]string{"READ_ONLY": "true", "READ_ONLY_RETRY_AFTER": "120"},
+			request: "POST", wantMessage: handlers.ErrorReadOnly, wantRetryAfter: "120"},
+		{name: "read-only PUT", env: map[string]string{"READ_ONLY": "true"}, request: "PUT",
+			wantMessage: handlers.ErrorReadOnly},
+		{name: "read-only PATCH", env: map[string]string{"READ_ONLY": "true"}, request: "PATCH",
+			wantMessage: handlers.ErrorReadOnly},
+		{name: "read-only DELETE", env: map[string]string{"READ_ONLY": "true"}, request: "DELETE",
+			wantMessage: handlers.ErrorReadOnly},
+		{name: "read-only invalid retry", env: map[string]string{"READ_ONLY": "true", "READ_ONLY_RETRY_AFTER": "soon"},
+			request: "DELETE", wantMessage: handlers.ErrorReadOnly},
+		{name: "read-only login", env: map[string]string{"READ_ONLY": "true"}, request: "login",
+			wantMessage: handlers.ErrorReadOnly},
+		{name: "read-only health", env: map[string]string{"READ_ONLY": "true"}, request: "health"},
+		{name: "maintenance GET", env: map[string]string{"MAINTENANCE_MODE": "true"}, request: "GET",
+			wantMessage: handlers.ErrorMaintenance},
+		{name: "maintenance POST", env: map[string]string{"MAINTENANCE_MODE": "true", "READ_ONLY": "true"},
+			request: "POST", wantMessage: handlers.ErrorMaintenance},
+		{name: "maintenance login", env: map[string]string{"MAINTENANCE_MODE": "true"}, request: "login",
+			wantMessage: handlers.ErrorMaintenance},
+		{name: "maintenance health", env: map[string]string{"MAINTENANCE_MODE": "true"}, request: "health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			a, fake := newTestApp(t, "ada@example.com")
+			fake.OnDescribeTable(func(in *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+				return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+					TableStatus: aws.String(dynamodb.TableStatusActive)}}, nil
+			})
+			req := requests[tt.request]
+			req.Headers = map[string]string{"Content-Type": "application/json"}
+
+			resp := serve(t, a, req)
+			if tt.wantMessage == "" {
+				if resp.StatusCode == http.StatusServiceUnavailable {
+					t.Errorf("%s %s = 503, want it served: %s", req.HTTPMethod, req.Path, resp.Body)
+				}
+				return
+			}
+			var body handlers.ErrorBody
+			if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+				t.Fatalf("body %s isn't an error: %v", resp.Body, err)
+			}
+			if resp.StatusCode != http.StatusServiceUnavailable || aws.StringValue(body.ErrorMsg) != tt.wantMessage ||
+				aws.StringValue(body.Code) != string(handlers.CodeServiceUnavailable) {
+				t.Errorf("%s %s = %d %s, want 503 %q", req.HTTPMethod, req.Path, resp.StatusCode, resp.Body, tt.wantMessage)
+			}
+			if resp.Headers["Retry-After"] != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", resp.Headers["Retry-After"], tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestServiceModeIsReadPerInvocation(t *testing.T) {
+	a, _ := newTestApp(t, "ada@example.com")
+	steps := []struct {
+		readOnly   string
+		wantStatus int
+	}{
+		{readOnly: "true", wantStatus: http.StatusServiceUnavailable},
+		{readOnly: "false", wantStatus: http.StatusOK},
+	}
+	for _, step := range steps {
+		t.Setenv("READ_ONLY", step.readOnly)
+		resp := serve(t, a, events.APIGatewayProxyRequest{HTTPMethod: http.MethodDelete, Path: "/users/ada%40example.com"})
+		if resp.StatusCode != step.wantStatus {
+			t.Errorf("DELETE with READ_ONLY=%s = %d, want %d: %s", step.readOnly, resp.StatusCode, step.wantStatus, resp.Body)
+		}
+	}
+}
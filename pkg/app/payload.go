@@ -0,0 +1,221 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// payloadVersion2 is the version field of HTTP API (v2) payloads
+const payloadVersion2 = "2.0"
+
+// payloadProbe holds the fields telling the payload formats apart
+type payloadProbe struct {
+	Version        string `json:"version"`
+	Source         string `json:"source"`
+	DetailType     string `json:"detail-type"`
+	Warmup         bool   `json:"warmup"`
+	RequestContext struct {
+		HTTP struct {
+			Method string `json:"method"`
+		} `json:"http"`
+		ELB struct {
+			TargetGroupArn string `json:"targetGroupArn"`
+		} `json:"elb"`
+	} `json:"requestContext"`
+}
+
+// RawHandler accepts REST API (v1) and HTTP API (v2) proxy payloads, which Function URLs
+// use too, and ALB target group requests. Warm-up pings are answered without routing.
+// Other payloads are converted to the v1 event the handlers take and their responses back
+// to their own format, so every route behaves the same whatever invokes the function.
+//
+// Parameters:
+// - ctx: The invocation context.
+// - payload: The raw invocation payload.
+//
+// Returns:
+//   - An APIGatewayProxyResponse for v1 payloads, an APIGatewayV2HTTPResponse for v2 payloads
+//     an ALBTargetGroupResponse for ALB requests or a WarmupResponse for warm-up pings.
+//   - An error if the payload can't be decoded or the handler failed.
+func (a *App) RawHandler(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var probe payloadProbe
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return nil, err
+	}
+
+	if isWarmup(probe) {
+		return a.warmUp(ctx), nil
+	}
+
+	if probe.Version == payloadVersion2 && probe.RequestContext.HTTP.Method != "" {
+		var req events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, err
+		}
+		resp, err := a.Handler(ctx, ProxyRequestFromV2(req))
+		if resp == nil {
+			return nil, err
+		}
+		return V2Response(resp), err
+	}
+
+	if probe.RequestContext.ELB.TargetGroupArn != "" {
+		var req events.ALBTargetGroupRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, err
+		}
+		resp, err := a.Handler(ctx, ProxyRequestFromALB(req))
+		if resp == nil {
+			return nil, err
+		}
+		return ALBResponse(resp, len(req.MultiValueHeaders) > 0), err
+	}
+
+	var req events.APIGatewayProxyRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return nil, err
+	}
+	resp, err := a.Handler(ctx, req)
+	if resp == nil {
+		return nil, err
+	}
+	return resp, err
+}
+
+// ProxyRequestFromV2 converts an HTTP API (v2) payload into the REST API (v1) proxy event.
+//
+// Parameters:
+// - in: The v2 request.
+//
+// Returns:
+//   - The v1 proxy event, with the stage stripped from the path and JWT or Lambda authorizer
+//     context moved to where a REST API authorizer puts it.
+func ProxyRequestFromV2(in events.APIGatewayV2HTTPRequest) events.APIGatewayProxyRequest {
+	path := in.RawPath
+	if stage := in.RequestContext.Stage; stage != "" && stage != "$default" {
+		// Named stages are part of the v2 path, but not of the v1 one
+		path = strings.TrimPrefix(path, "/"+stage)
+	}
+
+	// The route key is the method and resource, e.g. "GET /users/{email}"
+	resource := ""
+	if i := strings.Index(in.RouteKey, " "); i >= 0 {
+		resource = in.RouteKey[i+1:]
+	}
+
+	req := events.APIGatewayProxyRequest{
+		Resource:                        resource,
+		Path:                            path,
+		HTTPMethod:                      in.RequestContext.HTTP.Method,
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string{},
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string{},
+		PathParameters:                  in.PathParameters,
+		StageVariables:                  in.StageVariables,
+		Body:                            in.Body,
+		IsBase64Encoded:                 in.IsBase64Encoded,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:        in.RequestContext.AccountID,
+			Stage:            in.RequestContext.Stage,
+			DomainName:       in.RequestContext.DomainName,
+			DomainPrefix:     in.RequestContext.DomainPrefix,
+			RequestID:        in.RequestContext.RequestID,
+			Protocol:         in.RequestContext.HTTP.Protocol,
+			Identity:         events.APIGatewayRequestIdentity{SourceIP: in.RequestContext.HTTP.SourceIP, UserAgent: in.RequestContext.HTTP.UserAgent},
+			ResourcePath:     resource,
+			Path:             in.RequestContext.HTTP.Path,
+			Authorizer:       v1Authorizer(in.RequestContext.Authorizer),
+			HTTPMethod:       in.RequestContext.HTTP.Method,
+			RequestTime:      in.RequestContext.Time,
+			RequestTimeEpoch: in.RequestContext.TimeEpoch,
+			APIID:            in.RequestContext.APIID,
+		},
+	}
+
+	for name, value := range in.Headers {
+		req.Headers[name] = value
+		req.MultiValueHeaders[name] = []string{value}
+	}
+	// v2 moves cookies out of the headers
+	if len(in.Cookies) > 0 {
+		cookie := strings.Join(in.Cookies, "; ")
+		req.Headers["cookie"] = cookie
+		req.MultiValueHeaders["cookie"] = []string{cookie}
+	}
+
+	// v2 joins repeated parameters with commas, so read them from the raw query string. The
+	// first value wins, as for ALB requests
+	query, err := url.ParseQuery(in.RawQueryString)
+	if err != nil {
+		for name, value := range in.QueryStringParameters {
+			query[name] = []string{value}
+		}
+	}
+	for name, values := range query {
+		req.QueryStringParameters[name] = values[0]
+		req.MultiValueQueryStringParameters[name] = values
+	}
+	return req
+}
+
+// v1Authorizer converts a v2 authorizer context into the v1 one: JWT claims go under
+// "claims" like a Cognito authorizer's, with the scopes as a space-separated "scope"
+// claim, and Lambda authorizer context is flattened into the map.
+func v1Authorizer(in *events.APIGatewayV2HTTPRequestContextAuthorizerDescription) map[string]interface{} {
+	if in == nil {
+		return nil
+	}
+	authorizer := map[string]interface{}{}
+	for key, value := range in.Lambda {
+		authorizer[key] = value
+	}
+	if in.JWT != nil {
+		claims := make(map[string]interface{}, len(in.JWT.Claims)+1)
+		for key, value := range in.JWT.Claims {
+			claims[key] = value
+		}
+		if _, ok := claims["scope"]; !ok && len(in.JWT.Scopes) > 0 {
+			claims["scope"] = strings.Join(in.JWT.Scopes, " ")
+		}
+		authorizer["claims"] = claims
+	}
+	return authorizer
+}
+
+// V2Response converts a REST API (v1) proxy response into the HTTP API (v2) format,
+// which has no multi-value headers: Set-Cookie values become cookies and other repeated
+// headers are joined with commas.
+//
+// Parameters:
+// - resp: The v1 response.
+//
+// Returns:
+// - The v2 response.
+func V2Response(resp *events.APIGatewayProxyResponse) events.APIGatewayV2HTTPResponse {
+	out := events.APIGatewayV2HTTPResponse{
+		StatusCode:      resp.StatusCode,
+		Headers:         map[string]string{},
+		Body:            resp.Body,
+		IsBase64Encoded: resp.IsBase64Encoded,
+	}
+	for name, value := range resp.Headers {
+		out.Headers[name] = value
+	}
+	for name, values := range resp.MultiValueHeaders {
+		if http.CanonicalHeaderKey(name) == "Set-Cookie" {
+			out.Cookies = append(out.Cookies, values...)
+			continue
+		}
+		out.Headers[name] = strings.Join(values, ", ")
+	}
+	if cookie, ok := out.Headers["Set-Cookie"]; ok {
+		out.Cookies = append(out.Cookies, cookie)
+		delete(out.Headers, "Set-Cookie")
+	}
+	return out
+}
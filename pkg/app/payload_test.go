@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"reflect"
+	"testing"
+)
+
+// TestPayloadVersions runs the same requests as REST API (v1) and HTTP API (v2) payloads
+// and checks both get the same answer, in the response type of their payload.
+func TestPayloadVersions(t *testing.T) {
+	tests := []struct {
+		name       string
+		fixture    string // Fixture name after its "v1_" or "v2_" prefix
+		wantStatus int
+	}{
+		{name: "get", fixture: "get_user.json", wantStatus: 200},
+		{name: "list", fixture: "list_users.json", wantStatus: 200},
+		{name: "create", fixture: "create_user.json", wantStatus: 201},
+		{name: "update", fixture: "update_user.json", wantStatus: 200},
+		{name: "delete", fixture: "delete_user.json", wantStatus: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []interface{}
+			for _, version := range []string{"v1", "v2"} {
+				repo := user.NewMemoryRepository()
+				for _, u := range []user.User{
+					{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 1,
+						CreatedAt: "2024-01-01T00:00:00Z", UpdatedAt: "2024-01-01T00:00:00Z"},
+					{Email: "grace@example.com", FirstName: "Grace", LastName: "Hopper", Version: 1,
+						CreatedAt: "2024-01-01T00:00:00Z", UpdatedAt: "2024-01-01T00:00:00Z"},
+				} {
+					if err := repo.Create(&u); err != nil {
+						t.Fatalf("seeding: %v", err)
+					}
+				}
+				a := New(&Config{TableName: "users"}, mocks.NewFakeDynamo())
+				a.Repository = repo
+
+				out, err := a.RawHandler(context.Background(), loadFixture(t, version+"_"+tt.fixture))
+				if err != nil {
+					t.Fatalf("%s: RawHandler() error = %v", version, err)
+				}
+				var status int
+				var contentType, body string
+				switch resp := out.(type) {
+				case *events.APIGatewayProxyResponse:
+					if version != "v1" {
+						t.Fatalf("%s: RawHandler() = %T, want an APIGatewayV2HTTPResponse", version, out)
+					}
+					status, contentType, body = resp.StatusCode, resp.Headers["Content-Type"], resp.Body
+				case events.APIGatewayV2HTTPResponse:
+					if version != "v2" {
+						t.Fatalf("%s: RawHandler() = %T, want an APIGatewayProxyResponse", version, out)
+					}
+					status, contentType, body = resp.StatusCode, resp.Headers["Content-Type"], resp.Body
+				default:
+					t.Fatalf("%s: RawHandler() = %T, want a proxy response", version, out)
+				}
+				if status != tt.wantStatus {
+					t.Fatalf("%s: status = %d, want %d: %s", version, status, tt.wantStatus, body)
+				}
+				if contentType != "application/json" {
+					t.Errorf("%s: Content-Type = %q, want application/json", version, contentType)
+				}
+
+				var document interface{}
+				if err := json.Unmarshal([]byte(body), &document); err != nil {
+					t.Fatalf("%s: body %s isn't JSON: %v", version, body, err)
+				}
+				bodies = append(bodies, withoutVolatileFields(document))
+			}
+			if !reflect.DeepEqual(bodies[0], bodies[1]) {
+				t.Errorf("v1 body %v differs from v2 body %v", bodies[0], bodies[1])
+			}
+		})
+	}
+}
+
+// withoutVolatileFields drops the fields of a user that differ on every write, such as
+// the timestamps and verification token, from a decoded body.
+func withoutVolatileFields(document interface{}) interface{} {
+	switch document := document.(type) {
+	case map[string]interface{}:
+		for _, field := range []string{"createdAt", "updatedAt", "verifyToken", "verifyTokenExpiresAt"} {
+			delete(document, field)
+		}
+	case []interface{}:
+		for _, element := range document {
+			withoutVolatileFields(element)
+		}
+	}
+	return document
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// localCredentials are the dummy credentials sent to a local DynamoDB, which accepts any
+const localCredentials = "local"
+
+// NewSession creates the AWS session for the configured region, whose clients share an
+// HTTP client tuned for Lambda (see NewHTTPClient).
+// When DynamoDBEndpoint is set (DynamoDB Local, LocalStack), the session targets it with
+// static dummy credentials and path-style addressing.
+//
+// Parameters:
+// - cfg: The validated configuration.
+//
+// Returns:
+// - The AWS session.
+// - An error if the session cannot be created.
+func NewSession(cfg *Config) (*session.Session, error) {
+	awsConfig := &aws.Config{
+		Region:     aws.String(cfg.Region), // AWS region for the session
+		HTTPClient: NewHTTPClient(),        // Keeps connections alive across invocations
+	}
+	if cfg.DynamoDBEndpoint != "" {
+		awsConfig.Endpoint = aws.String(cfg.DynamoDBEndpoint)
+		awsConfig.Credentials = credentials.NewStaticCredentials(localCredentials, localCredentials, "")
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+	return session.NewSession(awsConfig)
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"time"
+)
+
+// ensureTableTimeout bounds table creation at startup
+const ensureTableTimeout = 90 * time.Second
+
+// EnsureTable creates the users table at startup when AUTO_CREATE_TABLE is enabled.
+// Deployments that manage the table through infrastructure as code leave it disabled.
+//
+// Parameters:
+// - cfg: The validated configuration.
+// - dynaClient: DynamoDB client interface.
+//
+// Returns:
+// - An error if the table is missing and couldn't be created.
+func EnsureTable(cfg *Config, dynaClient dynamodbiface.DynamoDBAPI) error {
+	if !cfg.AutoCreateTable {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ensureTableTimeout)
+	defer cancel()
+
+	created, err := user.EnsureTable(ctx, cfg.TableName, dynaClient)
+	if err != nil {
+		return err
+	}
+	if created {
+		logging.Default.Info("created table", logging.Fields{"table": cfg.TableName})
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Settings of the HTTP client the AWS clients share. One container serves one request at
+// a time but may make many calls, some in parallel (batch chunks, scan segments), so a
+// handful of kept-alive connections to each endpoint saves a TLS handshake per call.
+const (
+	maxIdleConns          = 64
+	maxIdleConnsPerHost   = 16
+	idleConnTimeout       = 60 * time.Second
+	dialTimeout           = 2 * time.Second
+	tcpKeepAlive          = 30 * time.Second
+	tlsHandshakeTimeout   = 3 * time.Second
+	responseHeaderTimeout = 10 * time.Second
+)
+
+// resettingTransport drops the idle connections after a failed round trip. A connection
+// kept while Lambda froze the container may since have been closed by the other side; the
+// SDK retries the failed call, which then dials a fresh connection instead of trying the
+// next stale one.
+type resettingTransport struct {
+	*http.Transport
+}
+
+// RoundTrip sends the request, dropping the idle connections if it fails for any reason
+// but its own cancellation.
+func (t resettingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil && req.Context().Err() == nil {
+		t.Transport.CloseIdleConnections()
+	}
+	return resp, err
+}
+
+// NewHTTPClient creates the HTTP client used by the AWS clients, tuned for Lambda: keep-alive
+// connections are reused across invocations of a container, dialing and handshakes fail fast,
+// and connections left dead by a freeze are replaced on the first failed call.
+//
+// Returns:
+// - The HTTP client.
+func NewHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: dialTimeout, KeepAlive: tcpKeepAlive}).DialContext
+	transport.MaxIdleConns = maxIdleConns
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	transport.IdleConnTimeout = idleConnTimeout
+	transport.TLSHandshakeTimeout = tlsHandshakeTimeout
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+	return &http.Client{Transport: resettingTransport{transport}}
+}
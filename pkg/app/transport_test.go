@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// BenchmarkHandlerReusesConnections invokes the handler repeatedly with a DynamoDB client
+// made the way main makes it, against a local server standing in for DynamoDB, and checks
+// every invocation reused the first connection. Against the real endpoint, each new
+// connection would cost a TLS handshake.
+func BenchmarkHandlerReusesConnections(b *testing.B) {
+	var connections int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// An empty GetItem output: the user doesn't exist
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		io.WriteString(w, `{}`)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	cfg := &Config{TableName: "users", Region: "us-east-1", DynamoDBEndpoint: server.URL}
+	awsSession, err := NewSession(cfg)
+	if err != nil {
+		b.Fatalf("NewSession() error = %v", err)
+	}
+	a := New(cfg, dynamodb.New(awsSession))
+	req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users/ada%40example.com",
+		Headers: map[string]string{}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := a.Handler(context.Background(), req)
+		if err != nil || resp.StatusCode != http.StatusNotFound {
+			b.Fatalf("Handler() = %+v, %v; want a 404 from the stand-in table", resp, err)
+		}
+	}
+	b.StopTimer()
+
+	if n := atomic.LoadInt64(&connections); n != 1 {
+		b.Errorf("%d connections for %d invocations, want 1", n, b.N)
+	}
+}
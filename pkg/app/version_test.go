@@ -0,0 +1,110 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/handlers"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"testing"
+)
+
+// Bodies v1 clients rely on, byte for byte
+const (
+	v1Ada   = `{"email":"ada@example.com","firstname":"Ada","lastname":"Lovelace","createdAt":"2021-01-01T00:00:00Z","updatedAt":"2021-01-01T00:00:00Z","version":1,"verified":false}`
+	v1Alan  = `{"email":"alan@example.com","firstname":"Ada","lastname":"Lovelace","createdAt":"2021-01-01T00:00:00Z","updatedAt":"2021-01-01T00:00:00Z","version":1,"verified":false}`
+	v1Grace = `{"email":"grace@example.com","firstname":"Ada","lastname":"Lovelace","createdAt":"2021-01-01T00:00:00Z","updatedAt":"2021-01-01T00:00:00Z","version":1,"verified":false}`
+)
+
+func TestV1Responses(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		query      map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "get", path: "/users/ada%40example.com", wantStatus: http.StatusOK, wantBody: v1Ada},
+		{name: "get prefixed", path: "/v1/users/ada%40example.com", wantStatus: http.StatusOK, wantBody: v1Ada},
+		{name: "list", path: "/users", wantStatus: http.StatusOK, wantBody: "[" + v1Ada + "," + v1Alan + "," + v1Grace + "]"},
+		{name: "list ignores limit", path: "/v1/users", query: map[string]string{"limit": "2"}, wantStatus: http.StatusOK,
+			wantBody: "[" + v1Ada + "," + v1Alan + "," + v1Grace + "]"},
+		{name: "missing", path: "/users/bob%40example.com", wantStatus: http.StatusNotFound,
+			wantBody: `{"error":"user doesn't exist","code":"NOT_FOUND"}`},
+		{name: "missing prefixed", path: "/v1/users/bob%40example.com", wantStatus: http.StatusNotFound,
+			wantBody: `{"error":"user doesn't exist","code":"NOT_FOUND"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestApp(t, "ada@example.com", "alan@example.com", "grace@example.com")
+			resp := serve(t, a, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: tt.path,
+				QueryStringParameters: tt.query})
+			if resp.StatusCode != tt.wantStatus || resp.Body != tt.wantBody {
+				t.Errorf("GET %s = %d %s, want %d %s", tt.path, resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if cursor, ok := resp.Headers[handlers.NextCursorHeader]; ok {
+				t.Errorf("%s = %q, want no cursor in v1", handlers.NextCursorHeader, cursor)
+			}
+		})
+	}
+}
+
+func TestV2Responses(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		headers    map[string]string
+		query      map[string]string
+		wantStatus int
+		wantData   string
+		wantCursor string
+		wantCode   string
+	}{
+		{name: "get", path: "/v2/users/ada%40example.com", wantStatus: http.StatusOK, wantData: v1Ada},
+		{name: "get by media type", path: "/users/ada%40example.com",
+			headers: map[string]string{"Accept": handlers.MediaTypeV2}, wantStatus: http.StatusOK, wantData: v1Ada},
+		{name: "first page", path: "/v2/users", query: map[string]string{"limit": "2"}, wantStatus: http.StatusOK,
+			wantData: "[" + v1Ada + "," + v1Alan + "]", wantCursor: "Mg"},
+		{name: "last page", path: "/v2/users", query: map[string]string{"limit": "2", "cursor": "Mg"},
+			wantStatus: http.StatusOK, wantData: "[" + v1Grace + "]"},
+		{name: "invalid limit", path: "/v2/users", query: map[string]string{"limit": "0"},
+			wantStatus: http.StatusBadRequest, wantCode: string(handlers.CodeInvalidRequest)},
+		{name: "missing", path: "/v2/users/bob%40example.com", wantStatus: http.StatusNotFound,
+			wantCode: string(handlers.CodeUserNotFound)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestApp(t, "ada@example.com", "alan@example.com", "grace@example.com")
+			resp := serve(t, a, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: tt.path,
+				Headers: tt.headers, QueryStringParameters: tt.query})
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("GET %s = %d, want %d: %s", tt.path, resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+
+			var envelope struct {
+				Data  json.RawMessage `json:"data"`
+				Meta  *struct{ NextCursor string }
+				Error *struct{ Code string }
+			}
+			if err := json.Unmarshal([]byte(resp.Body), &envelope); err != nil {
+				t.Fatalf("body %s isn't an envelope: %v", resp.Body, err)
+			}
+			if tt.wantCode != "" {
+				if envelope.Error == nil || envelope.Error.Code != tt.wantCode {
+					t.Errorf("body = %s, want an error with code %s", resp.Body, tt.wantCode)
+				}
+				return
+			}
+			if string(envelope.Data) != tt.wantData {
+				t.Errorf("data = %s, want %s", envelope.Data, tt.wantData)
+			}
+			cursor := ""
+			if envelope.Meta != nil {
+				cursor = envelope.Meta.NextCursor
+			}
+			if cursor != tt.wantCursor || resp.Headers[handlers.NextCursorHeader] != tt.wantCursor {
+				t.Errorf("next cursor = %q in meta and %q in %s, want %q", cursor,
+					resp.Headers[handlers.NextCursorHeader], handlers.NextCursorHeader, tt.wantCursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"time"
+)
+
+// Scheduled EventBridge (CloudWatch Events) rules invoke the function with these fields
+const (
+	scheduledEventSource     = "aws.events"
+	scheduledEventDetailType = "Scheduled Event"
+)
+
+// warmupTimeout bounds the DescribeTable call pre-warming the DynamoDB connection
+const warmupTimeout = 2 * time.Second
+
+// WarmupResponse is the result of a warm-up invocation
+type WarmupResponse struct {
+	Warm bool `json:"warm"` // Always true
+}
+
+// isWarmup reports whether a payload is a warm-up ping: a scheduled EventBridge event or
+// {"warmup": true}. API Gateway, Function URL and ALB payloads never carry these top-level
+// fields; a request body of {"warmup": true} is nested in their "body" string.
+func isWarmup(probe payloadProbe) bool {
+	return probe.Warmup || (probe.Source == scheduledEventSource && probe.DetailType == scheduledEventDetailType)
+}
+
+// warmUp answers a warm-up ping without routing it, so it costs nothing and stays out of
+// the request logs and metrics. The first ping of a container also describes the table,
+// opening the connection to DynamoDB before a real request needs it.
+//
+// Parameters:
+// - ctx: The invocation context.
+//
+// Returns:
+// - The WarmupResponse.
+func (a *App) warmUp(ctx context.Context) WarmupResponse {
+	logging.Default.Debug("warm-up ping", nil)
+	a.warmed.Do(func() {
+		ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+		defer cancel()
+		_, err := a.DynaClient.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(a.Config.TableName),
+		})
+		if err != nil {
+			logging.Default.Warn("failed to pre-warm the dynamodb connection", logging.Fields{
+				"table": a.Config.TableName,
+				"error": err,
+			})
+		}
+	})
+	return WarmupResponse{Warm: true}
+}
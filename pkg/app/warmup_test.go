@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"testing"
+)
+
+func TestWarmup(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    string
+		wantWarmup bool
+	}{
+		{name: "scheduled event", payload: `{"version": "0", "id": "89d1a02d", "detail-type": "Scheduled Event",
+			"source": "aws.events", "account": "123456789012", "time": "2024-01-01T00:00:00Z", "region": "us-east-1",
+			"resources": ["arn:aws:events:us-east-1:123456789012:rule/warm"], "detail": {}}`, wantWarmup: true},
+		{name: "warmup body", payload: `{"warmup": true}`, wantWarmup: true},
+		{name: "warmup false", payload: `{"warmup": false, "httpMethod": "GET", "path": "/users"}`},
+		{name: "other event from aws.events", payload: `{"detail-type": "EC2 Instance State-change Notification",
+			"source": "aws.events", "httpMethod": "GET", "path": "/users"}`},
+		{name: "v1 request with a warmup body", payload: `{"httpMethod": "POST", "path": "/users",
+			"headers": {"Content-Type": "application/json"}, "body": "{\"warmup\": true}"}`},
+		{name: "v1 request with a scheduled event body", payload: `{"httpMethod": "POST", "path": "/users",
+			"headers": {"Content-Type": "application/json"},
+			"body": "{\"source\": \"aws.events\", \"detail-type\": \"Scheduled Event\"}"}`},
+		{name: "v2 request with a warmup body", payload: `{"version": "2.0", "routeKey": "POST /users",
+			"rawPath": "/users", "headers": {"content-type": "application/json"},
+			"requestContext": {"http": {"method": "POST", "path": "/users"}}, "body": "{\"warmup\": true}"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, fake := newTestApp(t, "ada@example.com")
+
+			for ping := 0; ping < 2; ping++ {
+				out, err := a.RawHandler(context.Background(), json.RawMessage(tt.payload))
+				if err != nil {
+					t.Fatalf("RawHandler() error = %v", err)
+				}
+				warmup, isWarmup := out.(WarmupResponse)
+				if isWarmup != tt.wantWarmup {
+					t.Fatalf("RawHandler() = %#v, want a warm-up response: %v", out, tt.wantWarmup)
+				}
+				if isWarmup && !warmup.Warm {
+					t.Errorf("RawHandler() = %#v, want warm", warmup)
+				}
+				if _, routed := out.(*events.APIGatewayProxyResponse); !tt.wantWarmup && !routed {
+					if _, routed = out.(events.APIGatewayV2HTTPResponse); !routed {
+						t.Errorf("RawHandler() = %T, want the request routed", out)
+					}
+				}
+			}
+
+			// Only the first ping of a container describes the table
+			wantDescribes := 0
+			if tt.wantWarmup {
+				wantDescribes = 1
+			}
+			if describes := len(fake.Inputs("DescribeTable")); describes != wantDescribes {
+				t.Errorf("DescribeTable called %d times, want %d", describes, wantDescribes)
+			}
+		})
+	}
+}
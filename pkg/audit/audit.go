@@ -0,0 +1,244 @@
+package audit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Limits on the number of entries a page holds
+const (
+	DefaultPageSize = 25
+	MaxPageSize     = 100
+)
+
+// writeBatchSize is the most items a single BatchWriteItem call accepts
+const writeBatchSize = 25
+
+// writeMaxAttempts bounds the retries of unprocessed audit items
+const writeMaxAttempts = 3
+
+// idTimeFormat is a fixed-width UTC timestamp, so entry IDs sort chronologically
+const idTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// Errors returned by the audit log
+var (
+	ErrInvalidCursor = errors.New("invalid audit cursor")
+	ErrUnprocessed   = errors.New("some audit entries were not written")
+)
+
+// Change is the old and new value of a field modified by an update
+type Change struct {
+	From interface{} `json:"from"` // Value before the update
+	To   interface{} `json:"to"`   // Value after the update
+}
+
+// Entry records one mutation of a user.
+// The audit table uses email as partition key and id as sort key.
+type Entry struct {
+	Email     string            `json:"email"`             // Email of the affected user
+	ID        string            `json:"id"`                // Timestamp followed by the request ID, unique and sortable
+	Action    string            `json:"action"`            // What happened to the user, e.g. "created"
+	Actor     string            `json:"actor"`             // Who made the change, or "anonymous"
+	RequestID string            `json:"requestId"`         // ID of the request that made the change
+	Timestamp string            `json:"timestamp"`         // RFC3339 time of the change
+	Changes   map[string]Change `json:"changes,omitempty"` // Modified fields, for updates
+}
+
+// Page is a page of audit entries, newest first
+type Page struct {
+	Entries    []Entry `json:"entries"`              // The entries of the page
+	NextCursor string  `json:"nextCursor,omitempty"` // Cursor of the next page, if there is one
+}
+
+// TableName returns the audit table (AUDIT_TABLE_NAME), or an empty string when auditing is off.
+func TableName() string {
+	return os.Getenv("AUDIT_TABLE_NAME")
+}
+
+// Enabled reports whether mutations are audited.
+func Enabled() bool {
+	return TableName() != ""
+}
+
+// NewEntry builds the entry of a mutation made now.
+//
+// Parameters:
+// - action: What happened to the user, e.g. "created".
+// - email: The email of the affected user.
+// - actor: Who made the change.
+// - requestID: The ID of the request that made the change.
+//
+// Returns:
+// - The entry, without changes.
+func NewEntry(action string, email string, actor string, requestID string) Entry {
+	now := time.Now().UTC()
+	return Entry{
+		Email:     email,
+		ID:        now.Format(idTimeFormat) + "#" + requestID,
+		Action:    action,
+		Actor:     actor,
+		RequestID: requestID,
+		Timestamp: now.Format(time.RFC3339),
+	}
+}
+
+// Diff returns the fields whose JSON values differ between two versions of a record.
+//
+// Parameters:
+// - before: The record before the change.
+// - after: The record after the change.
+//
+// Returns:
+// - The changed fields keyed by their JSON names.
+func Diff(before interface{}, after interface{}) map[string]Change {
+	from, to := jsonFields(before), jsonFields(after)
+	changes := map[string]Change{}
+	for field, value := range to {
+		if !reflect.DeepEqual(from[field], value) {
+			changes[field] = Change{From: from[field], To: value}
+		}
+	}
+	for field, value := range from {
+		if _, ok := to[field]; !ok {
+			changes[field] = Change{From: value, To: nil}
+		}
+	}
+	return changes
+}
+
+// jsonFields returns a value as a map of its JSON fields.
+func jsonFields(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	encoded, err := json.Marshal(v)
+	if err == nil {
+		_ = json.Unmarshal(encoded, &fields)
+	}
+	return fields
+}
+
+// Record writes entries to the audit table with BatchWriteItem, in chunks of 25,
+// retrying unprocessed items a few times.
+//
+// Parameters:
+// - entries: The entries to write.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - An error if some entries could not be written.
+func Record(entries []Entry, dynaClient dynamodbiface.DynamoDBAPI) error {
+	table := TableName()
+	for start := 0; start < len(entries); start += writeBatchSize {
+		end := start + writeBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		var requests []*dynamodb.WriteRequest
+		for _, entry := range entries[start:end] {
+			item, err := dynamodbattribute.MarshalMap(entry)
+			if err != nil {
+				return err
+			}
+			requests = append(requests, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+		}
+
+		pending := map[string][]*dynamodb.WriteRequest{table: requests}
+		for attempt := 0; len(pending[table]) > 0; attempt++ {
+			if attempt == writeMaxAttempts {
+				return ErrUnprocessed
+			}
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt) * 50 * time.Millisecond)
+			}
+			result, err := dynaClient.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return err
+			}
+			pending = result.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// List reads a page of a user's audit entries, newest first.
+//
+// Parameters:
+// - email: The email of the user.
+// - limit: The most entries to return.
+// - cursor: The NextCursor of the previous page, or an empty string for the first page.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - The page of entries.
+// - ErrInvalidCursor if the cursor is malformed, or an error if the query fails.
+func List(email string, limit int, cursor string, dynaClient dynamodbiface.DynamoDBAPI) (*Page, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(TableName()),
+		KeyConditionExpression: aws.String("#email = :email"),
+		ExpressionAttributeNames: map[string]*string{
+			"#email": aws.String("email"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":email": {S: aws.String(email)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int64(int64(limit)),
+	}
+	if cursor != "" {
+		id, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(id) == 0 {
+			return nil, ErrInvalidCursor
+		}
+		input.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{
+			"email": {S: aws.String(email)},
+			"id":    {S: aws.String(string(id))},
+		}
+	}
+
+	result, err := dynaClient.Query(input)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Entries: []Entry{}}
+	if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &page.Entries); err != nil {
+		return nil, err
+	}
+	if last, ok := result.LastEvaluatedKey["id"]; ok {
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(aws.StringValue(last.S)))
+	}
+	return page, nil
+}
+
+// ListAll reads every audit entry of a user, newest first.
+//
+// Parameters:
+// - email: The email of the user.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - The entries.
+// - An error if a query fails.
+func ListAll(email string, dynaClient dynamodbiface.DynamoDBAPI) ([]Entry, error) {
+	var entries []Entry
+	cursor := ""
+	for {
+		page, err := List(email, MaxPageSize, cursor, dynaClient)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, page.Entries...)
+		if page.NextCursor == "" {
+			return entries, nil
+		}
+		cursor = page.NextCursor
+	}
+}
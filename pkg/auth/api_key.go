@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"sync"
+	"time"
+)
+
+// Errors returned by ValidateAPIKey
+var (
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	ErrExpiredAPIKey = errors.New("API key has expired")
+)
+
+// APIKey is a service credential stored in the API keys table.
+// The table uses keyHash (the hex SHA-256 of the key) as its partition key,
+// so plaintext keys are never stored.
+type APIKey struct {
+	KeyHash   string   `json:"keyHash"`   // Hex-encoded SHA-256 of the key
+	Name      string   `json:"name"`      // Human-readable name of the client
+	Scopes    []string `json:"scopes"`    // Granted scopes (read, write)
+	ExpiresAt int64    `json:"expiresAt"` // Expiry as epoch seconds, or 0 for no expiry
+}
+
+// expired reports whether the key is past its expiry.
+func (k *APIKey) expired(now time.Time) bool {
+	return k.ExpiresAt != 0 && now.Unix() >= k.ExpiresAt
+}
+
+// keyCache remembers validated keys for the container lifetime to avoid a DynamoDB
+// read on every request. Only positive results are cached, so revoked keys still fail
+// on fresh containers and newly issued keys work immediately.
+var keyCache = struct {
+	sync.RWMutex
+	keys map[string]*APIKey
+}{keys: map[string]*APIKey{}}
+
+// APIKeysEnabled reports whether API key authentication is configured via API_KEYS_TABLE.
+func APIKeysEnabled() bool {
+	return os.Getenv("API_KEYS_TABLE") != ""
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 of a key, as stored in the keys table.
+//
+// Parameters:
+// - key: The plaintext API key.
+//
+// Returns:
+// - The hash used as the table's partition key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateAPIKey checks a presented API key against the keys table named by API_KEYS_TABLE.
+//
+// Parameters:
+// - ctx: Context bounding the DynamoDB lookup.
+// - key: The plaintext key from the X-Api-Key header.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - The stored key record, including its scopes.
+// - ErrInvalidAPIKey if the key is unknown, ErrExpiredAPIKey if it has expired, or a wrapped DynamoDB error.
+func ValidateAPIKey(ctx context.Context, key string, dynaClient dynamodbiface.DynamoDBAPI) (*APIKey, error) {
+	if key == "" {
+		return nil, ErrInvalidAPIKey
+	}
+	hash := HashAPIKey(key)
+
+	keyCache.RLock()
+	cached, ok := keyCache.keys[hash]
+	keyCache.RUnlock()
+	if ok {
+		if cached.expired(time.Now()) {
+			return nil, ErrExpiredAPIKey
+		}
+		return cached, nil
+	}
+
+	input := &dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"keyHash": {
+				S: aws.String(hash),
+			},
+		},
+		TableName: aws.String(os.Getenv("API_KEYS_TABLE")),
+	}
+	result, err := dynaClient.GetItemWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API key: %w", err)
+	}
+	if len(result.Item) == 0 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey := new(APIKey)
+	if err := dynamodbattribute.UnmarshalMap(result.Item, apiKey); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal API key: %w", err)
+	}
+	if apiKey.expired(time.Now()) {
+		return nil, ErrExpiredAPIKey
+	}
+
+	keyCache.Lock()
+	keyCache.keys[hash] = apiKey
+	keyCache.Unlock()
+	return apiKey, nil
+}
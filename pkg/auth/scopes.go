@@ -0,0 +1,26 @@
+package auth
+
+// Scopes a caller must hold for each kind of operation
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// HasScope reports whether the granted scopes cover the required one.
+// The write scope also covers reads, and the admin scope covers everything.
+//
+// Parameters:
+// - granted: The scopes held by the caller.
+// - required: The scope the operation needs.
+//
+// Returns:
+// - True if the operation is allowed.
+func HasScope(granted []string, required string) bool {
+	for _, scope := range granted {
+		if scope == required || scope == ScopeAdmin || (required == ScopeRead && scope == ScopeWrite) {
+			return true
+		}
+	}
+	return false
+}
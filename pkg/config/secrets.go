@@ -0,0 +1,224 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prefixes of the environment values that reference a secret instead of holding it
+const (
+	SecretsManagerPrefix = "secretsmanager:" // Followed by the ARN or name of a secret
+	SSMPrefix            = "ssm:"            // Followed by the name of a parameter, e.g. "/users/jwt-key"
+)
+
+// SecretsClient reads secrets from Secrets Manager. *secretsmanager.SecretsManager implements it.
+type SecretsClient interface {
+	GetSecretValue(input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// ParameterClient reads parameters from SSM Parameter Store. *ssm.SSM implements it.
+type ParameterClient interface {
+	GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error)
+}
+
+// Resolver replaces the environment variables referencing a secret or parameter with its
+// value, so the rest of the application reads them like any other variable
+type Resolver struct {
+	Secrets    SecretsClient   // Client reading secretsmanager: references
+	Parameters ParameterClient // Client reading ssm: references
+	TTL        time.Duration   // How long resolved values are kept before Refresh fetches them again; 0 keeps them for the container lifetime
+
+	mu         sync.Mutex
+	references map[string]string // Reference each resolved variable held, by variable name
+	resolvedAt time.Time
+}
+
+// Default is the resolver used by Load and Refresh. Load creates it on first use; tests can
+// set it to a resolver with fake clients beforehand.
+var Default *Resolver
+
+// NewResolver creates a resolver reading from the session's Secrets Manager and Parameter
+// Store, refreshing values every SECRETS_REFRESH_SECONDS if set.
+//
+// Parameters:
+// - sess: The AWS session.
+//
+// Returns:
+// - The resolver.
+func NewResolver(sess *session.Session) *Resolver {
+	resolver := &Resolver{Secrets: secretsmanager.New(sess), Parameters: ssm.New(sess)}
+	if seconds, err := strconv.Atoi(os.Getenv("SECRETS_REFRESH_SECONDS")); err == nil && seconds > 0 {
+		resolver.TTL = time.Duration(seconds) * time.Second
+	}
+	return resolver
+}
+
+// Load resolves the references of the environment with Default, and is meant to be called
+// once at cold start, before the configuration is read. The AWS clients are only created
+// when some variable holds a reference.
+//
+// Returns:
+//   - An error naming the first variable that can't be resolved; the function must not start
+//     with it unresolved.
+func Load() error {
+	if Default == nil {
+		if len(references()) == 0 {
+			return nil
+		}
+		sess, err := session.NewSession(&aws.Config{Region: aws.String(os.Getenv("AWS_REGION"))})
+		if err != nil {
+			return fmt.Errorf("failed to create AWS session: %w", err)
+		}
+		Default = NewResolver(sess)
+	}
+	return Default.Resolve()
+}
+
+// Refresh fetches the values resolved by Default again once they are older than its TTL.
+// It is called at the start of every request and does nothing without references or a TTL.
+func Refresh() {
+	if Default != nil {
+		Default.Refresh()
+	}
+}
+
+// Resolve replaces every environment variable whose value is a reference with the value of
+// the secret or parameter it references. Each distinct reference is fetched once.
+//
+// Returns:
+// - An error naming the first variable that can't be resolved.
+func (r *Resolver) Resolve() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := references()
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := map[string]string{}
+	for _, name := range names {
+		reference := found[name]
+		value, ok := values[reference]
+		if !ok {
+			var err error
+			if value, err = r.fetch(reference); err != nil {
+				return fmt.Errorf("failed to resolve %s from %s: %w", name, reference, err)
+			}
+			values[reference] = value
+		}
+		os.Setenv(name, value)
+	}
+
+	if r.references == nil {
+		r.references = map[string]string{}
+	}
+	for name, reference := range found {
+		r.references[name] = reference
+	}
+	r.resolvedAt = time.Now()
+	if len(names) > 0 {
+		logging.Default.Info("resolved secret references", logging.Fields{"variables": names})
+	}
+	return nil
+}
+
+// Refresh fetches the resolved values again once they are older than the TTL. A value that
+// can't be fetched keeps its previous value and is retried on the next refresh.
+func (r *Resolver) Refresh() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.TTL <= 0 || len(r.references) == 0 || time.Since(r.resolvedAt) < r.TTL {
+		return
+	}
+
+	failed := false
+	values := map[string]string{}
+	for name, reference := range r.references {
+		value, ok := values[reference]
+		if !ok {
+			var err error
+			if value, err = r.fetch(reference); err != nil {
+				logging.Default.Warn("failed to refresh secret, keeping its previous value",
+					logging.Fields{"variable": name, "reference": reference, "error": err})
+				failed = true
+				continue
+			}
+			values[reference] = value
+		}
+		os.Setenv(name, value)
+	}
+	if !failed {
+		r.resolvedAt = time.Now()
+	}
+}
+
+// fetch reads the value a reference points to, decrypting SecureString parameters.
+func (r *Resolver) fetch(reference string) (string, error) {
+	switch {
+	case strings.HasPrefix(reference, SecretsManagerPrefix):
+		if r.Secrets == nil {
+			return "", errors.New("no Secrets Manager client")
+		}
+		result, err := r.Secrets.GetSecretValue(&secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(strings.TrimPrefix(reference, SecretsManagerPrefix)),
+		})
+		if err != nil {
+			return "", err
+		}
+		if result.SecretString != nil {
+			return *result.SecretString, nil
+		}
+		return string(result.SecretBinary), nil
+	case strings.HasPrefix(reference, SSMPrefix):
+		if r.Parameters == nil {
+			return "", errors.New("no Parameter Store client")
+		}
+		result, err := r.Parameters.GetParameter(&ssm.GetParameterInput{
+			Name:           aws.String(strings.TrimPrefix(reference, SSMPrefix)),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return "", err
+		}
+		if result.Parameter == nil || result.Parameter.Value == nil {
+			return "", errors.New("parameter has no value")
+		}
+		return *result.Parameter.Value, nil
+	}
+	return "", fmt.Errorf("unknown reference %q", reference)
+}
+
+// references returns the environment variables whose value is a reference, keyed by name.
+func references() map[string]string {
+	found := map[string]string{}
+	for _, variable := range os.Environ() {
+		name, value := variable, ""
+		if i := strings.Index(variable, "="); i >= 0 {
+			name, value = variable[:i], variable[i+1:]
+		}
+		if isReference(value) {
+			found[name] = value
+		}
+	}
+	return found
+}
+
+// isReference reports whether an environment value references a secret or parameter.
+func isReference(value string) bool {
+	return (strings.HasPrefix(value, SecretsManagerPrefix) && len(value) > len(SecretsManagerPrefix)) ||
+		(strings.HasPrefix(value, SSMPrefix) && len(value) > len(SSMPrefix))
+}
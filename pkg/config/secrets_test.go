@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSecrets serves secrets by ID, counting the reads
+type fakeSecrets struct {
+	values map[string]string
+	binary map[string][]byte
+	reads  int
+}
+
+func (f *fakeSecrets) GetSecretValue(input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+	f.reads++
+	if value, ok := f.values[aws.StringValue(input.SecretId)]; ok {
+		return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(value)}, nil
+	}
+	if value, ok := f.binary[aws.StringValue(input.SecretId)]; ok {
+		return &secretsmanager.GetSecretValueOutput{SecretBinary: value}, nil
+	}
+	return nil, errors.New("secret not found")
+}
+
+// fakeParameters serves parameters by name, only decrypted, counting the reads
+type fakeParameters struct {
+	values map[string]string
+	reads  int
+}
+
+func (f *fakeParameters) GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	f.reads++
+	if !aws.BoolValue(input.WithDecryption) {
+		return nil, errors.New("parameter is encrypted")
+	}
+	value, ok := f.values[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, errors.New("parameter not found")
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(value)}}, nil
+}
+
+func TestResolve(t *testing.T) {
+	secretARN := "arn:aws:secretsmanager:us-east-1:123456789012:secret:jwt"
+	tests := []struct {
+		name        string
+		env         map[string]string
+		noClients   bool
+		want        map[string]string
+		wantErr     string // Substring of the error, or "" if the references resolve
+		wantFetches int
+	}{
+		{name: "secrets manager", env: map[string]string{"JWT_SECRET": "secretsmanager:" + secretARN},
+			want: map[string]string{"JWT_SECRET": "jwt-value"}, wantFetches: 1},
+		{name: "binary secret", env: map[string]string{"API_KEY": "secretsmanager:binary"},
+			want: map[string]string{"API_KEY": "binary-value"}, wantFetches: 1},
+		{name: "parameter store", env: map[string]string{"API_KEY": "ssm:/users/api-key"},
+			want: map[string]string{"API_KEY": "api-key-value"}, wantFetches: 1},
+		{name: "plain values untouched", env: map[string]string{"TABLE_NAME": "users", "PREFIX_ONLY": "ssm:",
+			"NOT_A_PREFIX": "my-ssm:/users/api-key"},
+			want: map[string]string{"TABLE_NAME": "users", "PREFIX_ONLY": "ssm:", "NOT_A_PREFIX": "my-ssm:/users/api-key"}},
+		{name: "shared reference fetched once",
+			env:  map[string]string{"JWT_SECRET": "ssm:/users/api-key", "JWT_SECRET_OLD": "ssm:/users/api-key"},
+			want: map[string]string{"JWT_SECRET": "api-key-value", "JWT_SECRET_OLD": "api-key-value"}, wantFetches: 1},
+		{name: "missing secret", env: map[string]string{"JWT_SECRET": "secretsmanager:missing"},
+			wantErr: "JWT_SECRET from secretsmanager:missing"},
+		{name: "missing parameter", env: map[string]string{"API_KEY": "ssm:/users/missing"},
+			wantErr: "API_KEY from ssm:/users/missing"},
+		{name: "no clients", env: map[string]string{"API_KEY": "ssm:/users/api-key"}, noClients: true,
+			wantErr: "no Parameter Store client"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			secrets := &fakeSecrets{values: map[string]string{secretARN: "jwt-value"},
+				binary: map[string][]byte{"binary": []byte("binary-value")}}
+			parameters := &fakeParameters{values: map[string]string{"/users/api-key": "api-key-value"}}
+			resolver := &Resolver{Secrets: secrets, Parameters: parameters}
+			if tt.noClients {
+				resolver = &Resolver{}
+			}
+
+			err := resolver.Resolve()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Resolve() error = %v, want one naming %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			for name, want := range tt.want {
+				if got := os.Getenv(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if fetches := secrets.reads + parameters.reads; fetches != tt.wantFetches {
+				t.Errorf("fetched %d times, want %d", fetches, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		rotated     string // Value of the parameter after the first resolution; "" if it was deleted
+		wantValue   string
+		wantFetches int
+	}{
+		{name: "container lifetime", rotated: "rotated", wantValue: "api-key-value", wantFetches: 1},
+		{name: "expired", ttl: time.Millisecond, rotated: "rotated", wantValue: "rotated", wantFetches: 2},
+		{name: "not expired", ttl: time.Hour, rotated: "rotated", wantValue: "api-key-value", wantFetches: 1},
+		{name: "failed refresh keeps the value", ttl: time.Millisecond, wantValue: "api-key-value", wantFetches: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("API_KEY", "ssm:/users/api-key")
+			parameters := &fakeParameters{values: map[string]string{"/users/api-key": "api-key-value"}}
+			resolver := &Resolver{Parameters: parameters, TTL: tt.ttl}
+			if err := resolver.Resolve(); err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+
+			delete(parameters.values, "/users/api-key")
+			if tt.rotated != "" {
+				parameters.values["/users/api-key"] = tt.rotated
+			}
+			time.Sleep(2 * time.Millisecond)
+			resolver.Refresh()
+
+			if got := os.Getenv("API_KEY"); got != tt.wantValue {
+				t.Errorf("API_KEY = %q, want %q", got, tt.wantValue)
+			}
+			if parameters.reads != tt.wantFetches {
+				t.Errorf("fetched %d times, want %d", parameters.reads, tt.wantFetches)
+			}
+		})
+	}
+}
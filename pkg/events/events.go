@@ -0,0 +1,200 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"os"
+	"sync"
+	"time"
+)
+
+// Types of user lifecycle events
+const (
+	UserCreated = "user.created"
+	UserUpdated = "user.updated"
+	UserDeleted = "user.deleted"
+
+	// UserVerificationRequested carries a new email verification token, for a mailer to send
+	UserVerificationRequested = "user.verificationRequested"
+
+	// WebhookFailed carries an event the WEBHOOK_URL callback failed to deliver even after retries
+	WebhookFailed = "webhook.failed"
+)
+
+// Source is the EventBridge source of the events published by this service
+const Source = "golang-serverless.users"
+
+// publishConcurrency bounds the events published at the same time
+const publishConcurrency = 8
+
+// Event is a change to a user, published for downstream services
+type Event struct {
+	ID        string     `json:"id,omitempty"`       // Deterministic ID of events relayed from the outbox, for deduplication
+	Type      string     `json:"type"`               // UserCreated, UserUpdated, UserDeleted, UserVerificationRequested or WebhookFailed
+	Email     string     `json:"email"`              // Email of the affected user
+	User      *user.User `json:"user,omitempty"`     // The new record, for creates and updates
+	Token     string     `json:"token,omitempty"`    // The verification token, for verification requests
+	Delivery  *Delivery  `json:"delivery,omitempty"` // The failed callback, for WebhookFailed
+	RequestID string     `json:"requestId"`          // ID of the request that made the change
+	Time      string     `json:"time"`               // RFC3339 time of the change
+}
+
+// Delivery describes a webhook callback that failed
+type Delivery struct {
+	Type     string `json:"type"`             // Type of the undelivered event
+	Status   int    `json:"status,omitempty"` // HTTP status of the last attempt, if it got a response
+	Attempts int    `json:"attempts"`         // Number of attempts made
+	Error    string `json:"error"`            // Why the last attempt failed
+}
+
+// NewEvent builds an event for a change made now.
+//
+// Parameters:
+// - eventType: UserCreated, UserUpdated or UserDeleted.
+// - email: The email of the affected user.
+// - u: The new record, or nil for deletes.
+// - requestID: The ID of the request that made the change.
+//
+// Returns:
+// - The event.
+func NewEvent(eventType string, email string, u *user.User, requestID string) Event {
+	return Event{Type: eventType, Email: email, User: u, RequestID: requestID, Time: time.Now().UTC().Format(time.RFC3339)}
+}
+
+// Publisher delivers events to downstream services
+type Publisher interface {
+	// Publish delivers one event.
+	Publish(event Event) error
+}
+
+// nopPublisher drops every event
+type nopPublisher struct{}
+
+// Publish drops the event.
+func (nopPublisher) Publish(Event) error {
+	return nil
+}
+
+// Default is the publisher used across the application, replaced in main() at cold start.
+// It drops events until configured.
+var Default Publisher = nopPublisher{}
+
+// EventBridgePublisher puts events on an EventBridge bus
+type EventBridgePublisher struct {
+	Client  eventbridgeiface.EventBridgeAPI // The EventBridge client interface
+	BusName string                          // The name or ARN of the event bus
+}
+
+// Publish puts the event on the bus, with the event type as detail type.
+func (p *EventBridgePublisher) Publish(event Event) error {
+	detail, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	result, err := p.Client.PutEvents(&eventbridge.PutEventsInput{
+		Entries: []*eventbridge.PutEventsRequestEntry{{
+			EventBusName: aws.String(p.BusName),
+			Source:       aws.String(Source),
+			DetailType:   aws.String(event.Type),
+			Detail:       aws.String(string(detail)),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	if aws.Int64Value(result.FailedEntryCount) > 0 && len(result.Entries) > 0 {
+		return fmt.Errorf("event rejected: %s: %s",
+			aws.StringValue(result.Entries[0].ErrorCode), aws.StringValue(result.Entries[0].ErrorMessage))
+	}
+	return nil
+}
+
+// SNSPublisher publishes events to an SNS topic
+type SNSPublisher struct {
+	Client   snsiface.SNSAPI // The SNS client interface
+	TopicArn string          // The ARN of the topic
+}
+
+// Publish sends the event as the message, with its type as the "type" message attribute
+// so subscriptions can filter on it.
+func (p *SNSPublisher) Publish(event Event) error {
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = p.Client.Publish(&sns.PublishInput{
+		TopicArn: aws.String(p.TopicArn),
+		Message:  aws.String(string(message)),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"type": {DataType: aws.String("String"), StringValue: aws.String(event.Type)},
+		},
+	})
+	return err
+}
+
+// NewPublisher creates the publisher configured by the environment: an EventBridge bus
+// named by EVENT_BUS_NAME, an SNS topic given by EVENT_TOPIC_ARN, or a publisher dropping
+// every event when neither is set.
+//
+// Parameters:
+// - sess: The AWS session the clients are created from.
+//
+// Returns:
+// - The publisher.
+func NewPublisher(sess *session.Session) Publisher {
+	if bus := os.Getenv("EVENT_BUS_NAME"); bus != "" {
+		return &EventBridgePublisher{Client: eventbridge.New(sess), BusName: bus}
+	}
+	if topic := os.Getenv("EVENT_TOPIC_ARN"); topic != "" {
+		return &SNSPublisher{Client: sns.New(sess), TopicArn: topic}
+	}
+	return nopPublisher{}
+}
+
+// Failure is an event that could not be published
+type Failure struct {
+	Event Event // The event
+	Err   error // The error of the last attempt
+}
+
+// PublishAll publishes events concurrently, retrying each failed event once.
+//
+// Parameters:
+// - p: The publisher.
+// - events: The events to publish.
+//
+// Returns:
+// - The events that failed twice, in no particular order.
+func PublishAll(p Publisher, events []Event) []Failure {
+	var (
+		mu       sync.Mutex
+		failures []Failure
+		wg       sync.WaitGroup
+	)
+	slots := make(chan struct{}, publishConcurrency)
+	for _, event := range events {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(event Event) {
+			defer func() { <-slots; wg.Done() }()
+			err := p.Publish(event)
+			if err != nil {
+				err = p.Publish(event)
+			}
+			if err != nil {
+				mu.Lock()
+				failures = append(failures, Failure{Event: event, Err: err})
+				mu.Unlock()
+			}
+		}(event)
+	}
+	wg.Wait()
+	return failures
+}
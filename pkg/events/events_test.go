@@ -0,0 +1,208 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// fakeBus is an EventBridge client recording the events put on it
+type fakeBus struct {
+	eventbridgeiface.EventBridgeAPI
+	inputs []*eventbridge.PutEventsInput
+	output *eventbridge.PutEventsOutput
+	err    error
+}
+
+func (f *fakeBus) PutEvents(in *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+	f.inputs = append(f.inputs, in)
+	if f.output == nil {
+		return &eventbridge.PutEventsOutput{}, f.err
+	}
+	return f.output, f.err
+}
+
+func (f *fakeBus) PutEventsWithContext(_ aws.Context, in *eventbridge.PutEventsInput, _ ...request.Option) (
+	*eventbridge.PutEventsOutput, error) {
+	return f.PutEvents(in)
+}
+
+// fakeTopic is an SNS client recording the messages published to it
+type fakeTopic struct {
+	snsiface.SNSAPI
+	inputs []*sns.PublishInput
+	err    error
+}
+
+func (f *fakeTopic) Publish(in *sns.PublishInput) (*sns.PublishOutput, error) {
+	f.inputs = append(f.inputs, in)
+	return &sns.PublishOutput{}, f.err
+}
+
+func (f *fakeTopic) PublishWithContext(_ aws.Context, in *sns.PublishInput, _ ...request.Option) (*sns.PublishOutput, error) {
+	return f.Publish(in)
+}
+
+func TestEventShape(t *testing.T) {
+	ada := &user.User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 1}
+	tests := []struct {
+		name     string
+		event    Event
+		wantKeys []string
+	}{
+		{name: "created", event: NewEvent(UserCreated, ada.Email, ada, "req-1"),
+			wantKeys: []string{"email", "requestId", "time", "type", "user"}},
+		{name: "updated", event: NewEvent(UserUpdated, ada.Email, ada, "req-1"),
+			wantKeys: []string{"email", "requestId", "time", "type", "user"}},
+		{name: "deleted", event: NewEvent(UserDeleted, ada.Email, nil, "req-1"),
+			wantKeys: []string{"email", "requestId", "time", "type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := json.Marshal(tt.event)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var document map[string]interface{}
+			if err := json.Unmarshal(encoded, &document); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			var keys []string
+			for key := range document {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("event %s has keys %v, want %v", encoded, keys, tt.wantKeys)
+			}
+			if document["type"] != tt.event.Type || document["email"] != "ada@example.com" || document["requestId"] != "req-1" {
+				t.Errorf("event %s doesn't describe the change", encoded)
+			}
+		})
+	}
+}
+
+func TestEventBridgePublisher(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  *eventbridge.PutEventsOutput
+		err     error
+		wantErr bool
+	}{
+		{name: "accepted"},
+		{name: "rejected", output: &eventbridge.PutEventsOutput{FailedEntryCount: aws.Int64(1),
+			Entries: []*eventbridge.PutEventsResultEntry{{ErrorCode: aws.String("InternalFailure"),
+				ErrorMessage: aws.String("try again")}}}, wantErr: true},
+		{name: "failed", err: errors.New("connection reset"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &fakeBus{output: tt.output, err: tt.err}
+			p := &EventBridgePublisher{Client: bus, BusName: "users-bus"}
+
+			err := p.Publish(NewEvent(UserCreated, "ada@example.com", nil, "req-1"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Publish() error = %v, want error %v", err, tt.wantErr)
+			}
+			entry := bus.inputs[0].Entries[0]
+			if aws.StringValue(entry.EventBusName) != "users-bus" || aws.StringValue(entry.Source) != Source ||
+				aws.StringValue(entry.DetailType) != UserCreated {
+				t.Errorf("entry = %v, want a %s event from %s on users-bus", entry, UserCreated, Source)
+			}
+			var detail Event
+			if err := json.Unmarshal([]byte(aws.StringValue(entry.Detail)), &detail); err != nil ||
+				detail.Email != "ada@example.com" {
+				t.Errorf("detail = %s, want the event: %v", aws.StringValue(entry.Detail), err)
+			}
+		})
+	}
+}
+
+func TestSNSPublisher(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "published"},
+		{name: "failed", err: errors.New("throttled"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topic := &fakeTopic{err: tt.err}
+			p := &SNSPublisher{Client: topic, TopicArn: "arn:aws:sns:eu-west-1:123456789012:users"}
+
+			err := p.Publish(NewEvent(UserDeleted, "ada@example.com", nil, "req-1"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Publish() error = %v, want error %v", err, tt.wantErr)
+			}
+			in := topic.inputs[0]
+			if aws.StringValue(in.TopicArn) != p.TopicArn ||
+				aws.StringValue(in.MessageAttributes["type"].StringValue) != UserDeleted {
+				t.Errorf("input = %v, want a %s message on the topic", in, UserDeleted)
+			}
+		})
+	}
+}
+
+// flakyPublisher fails the first attempts at publishing each event
+type flakyPublisher struct {
+	mu       sync.Mutex
+	failures int            // Attempts failing per event
+	attempts map[string]int // Attempts made per email
+}
+
+func (p *flakyPublisher) Publish(event Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts[event.Email]++
+	if p.attempts[event.Email] <= p.failures {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func TestPublishAll(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		wantFailed   int
+		wantAttempts int
+	}{
+		{name: "published", wantAttempts: 1},
+		{name: "retried once", failures: 1, wantAttempts: 2},
+		{name: "failed twice", failures: 2, wantFailed: 20, wantAttempts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &flakyPublisher{failures: tt.failures, attempts: map[string]int{}}
+			var published []Event
+			for i := 0; i < 20; i++ {
+				published = append(published, NewEvent(UserUpdated, string(rune('a'+i))+"@example.com", nil, "req-1"))
+			}
+
+			failures := PublishAll(p, published)
+			if len(failures) != tt.wantFailed {
+				t.Errorf("%d events failed, want %d", len(failures), tt.wantFailed)
+			}
+			for email, attempts := range p.attempts {
+				if attempts != tt.wantAttempts {
+					t.Errorf("%s published %d times, want %d", email, attempts, tt.wantAttempts)
+				}
+			}
+			if len(p.attempts) != len(published) {
+				t.Errorf("%d events published, want %d", len(p.attempts), len(published))
+			}
+		})
+	}
+}
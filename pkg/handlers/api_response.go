@@ -2,29 +2,61 @@ package handlers
 
 import (
 	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"net/http"
 )
 
-// apiResponse generates a standardized API Gateway Proxy Response.
+// internalErrorBody is the fixed body sent when a response cannot be marshaled
+var internalErrorBody = `{"error":"` + ErrorInternal + `","code":"` + string(CodeInternal) + `"}`
+
+// APIResponse generates a standardized API Gateway Proxy Response.
 // It accepts a status code and a response body, formats them into an APIGatewayProxyResponse,
 // and sets the "Content-Type" header to "application/json".
+// If the body cannot be marshaled, the failure is logged and a 500 response with a fixed
+// error body is returned instead.
 //
 // Parameters:
 // - status: HTTP status code (e.g., 200, 400, 500).
 // - body: Response body, which can be any type (usually a struct or map).
+// - headers: Optional extra headers, e.g. a Location.
 //
 // Returns:
 // - A pointer to an APIGatewayProxyResponse containing the status code, headers, and JSON-encoded body.
-// - An error (always nil in this function as errors are not handled here).
-func apiResponse(status int, body interface{}) (*events.APIGatewayProxyResponse, error) {
+// - An error (always nil, so the response is always delivered to API Gateway).
+func APIResponse(status int, body interface{}, headers ...map[string]string) (*events.APIGatewayProxyResponse, error) {
 	// Initialize response with JSON content-type header
 	resp := events.APIGatewayProxyResponse{Headers: map[string]string{"Content-Type": "application/json"}}
 	resp.StatusCode = status
+	for _, extra := range headers {
+		for name, value := range extra {
+			resp.Headers[name] = value
+		}
+	}
 
-	// Marshal the response body into a JSON string
-	stringBody, _ := json.Marshal(body)
+	// Marshal the response body into a JSON string, falling back to a 500 on failure
+	stringBody, err := json.Marshal(body)
+	if err != nil {
+		logging.Default.Error("failed to marshal response body", logging.Fields{"status": status, "error": err})
+		resp.StatusCode = http.StatusInternalServerError
+		stringBody = []byte(internalErrorBody)
+	}
 	resp.Body = string(stringBody)
 
-	// Return the response and nil for error (no error-handling logic here)
 	return &resp, nil
 }
+
+// APIError generates an error response with the standard ErrorBody.
+//
+// Parameters:
+// - status: HTTP status code (e.g., 400, 404, 500).
+// - code: Machine-readable error code (one of the Code constants).
+// - msg: Human-readable error message.
+//
+// Returns:
+// - A pointer to an APIGatewayProxyResponse containing the JSON-encoded ErrorBody.
+// - An error (always nil).
+func APIError(status int, code ErrorCode, msg string) (*events.APIGatewayProxyResponse, error) {
+	return APIResponse(status, ErrorBody{ErrorMsg: aws.String(msg), Code: aws.String(string(code))})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestAPIResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       interface{}
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "struct", status: http.StatusOK, body: struct {
+			Email string `json:"email"`
+		}{"ada@example.com"}, wantStatus: http.StatusOK, wantBody: `{"email":"ada@example.com"}`},
+		{name: "extra headers", status: http.StatusCreated, body: map[string]int{"version": 1},
+			headers: map[string]string{"Location": "/users/ada%40example.com"}, wantStatus: http.StatusCreated,
+			wantBody: `{"version":1}`},
+		{name: "channel", status: http.StatusOK, body: make(chan int), wantStatus: http.StatusInternalServerError,
+			wantBody: internalErrorBody},
+		{name: "NaN", status: http.StatusOK, body: math.NaN(), wantStatus: http.StatusInternalServerError,
+			wantBody: internalErrorBody},
+		{name: "function in a map", status: http.StatusCreated, body: map[string]interface{}{"f": func() {}},
+			wantStatus: http.StatusInternalServerError, wantBody: internalErrorBody},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := APIResponse(tt.status, tt.body, tt.headers)
+			if err != nil {
+				t.Fatalf("APIResponse() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus || resp.Body != tt.wantBody {
+				t.Errorf("APIResponse() = %d %s, want %d %s", resp.StatusCode, resp.Body, tt.wantStatus, tt.wantBody)
+			}
+			if resp.Headers["Content-Type"] != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", resp.Headers["Content-Type"])
+			}
+			for name, value := range tt.headers {
+				if resp.Headers[name] != value {
+					t.Errorf("%s = %q, want %q", name, resp.Headers[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestInternalErrorBody(t *testing.T) {
+	var body ErrorBody
+	if err := json.Unmarshal([]byte(internalErrorBody), &body); err != nil {
+		t.Fatalf("internalErrorBody isn't JSON: %v", err)
+	}
+	if body.Code == nil || *body.Code != string(CodeInternal) || body.ErrorMsg == nil {
+		t.Errorf("internalErrorBody = %s, want the standard error envelope with %s", internalErrorBody, CodeInternal)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/audit"
+	userevents "github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/Vansh3140/golang-serverless/pkg/webhook"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"strconv"
+)
+
+// Error messages for audit log requests
+var (
+	ErrorAuditDisabled = "audit log is not enabled"
+	ErrorInvalidLimit  = "limit must be a number between 1 and " + strconv.Itoa(audit.MaxPageSize)
+)
+
+// mutation is a successful change to a user
+type mutation struct {
+	action string     // What happened to the user, e.g. "created"
+	email  string     // Email of the affected user
+	before *user.User // The user before an update, if known
+	after  *user.User // The user after a create, update or restore, if known
+
+	// Whether the write recorded the event in the outbox, which publishes it instead
+	outboxed bool
+}
+
+// recordMutations logs who changed which users, writes the changes to the audit log when
+// AUDIT_TABLE_NAME is set and publishes them as lifecycle events. A failed audit write or
+// publish is logged but never fails the request, since the changes have already been made.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest that made the changes.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+// - mutations: The changes made.
+func recordMutations(req events.APIGatewayProxyRequest, dynaClient dynamodbiface.DynamoDBAPI, mutations ...mutation) {
+	publishEvents(req, mutations)
+
+	entries := make([]audit.Entry, 0, len(mutations))
+	for _, m := range mutations {
+		logMutation(req, m.action, m.email)
+
+		entry := audit.NewEntry(m.action, m.email, actor(req), req.RequestContext.RequestID)
+		if m.before != nil && m.after != nil {
+			entry.Changes = audit.Diff(m.before, m.after)
+		}
+		entries = append(entries, entry)
+	}
+
+	if !audit.Enabled() || len(entries) == 0 {
+		return
+	}
+	if err := audit.Record(entries, dynaClient); err != nil {
+		logging.Default.Error("failed to write audit log", logging.Fields{
+			"requestId": req.RequestContext.RequestID,
+			"entries":   len(entries),
+			"error":     err,
+		})
+	}
+}
+
+// eventTypes maps mutation actions to the lifecycle event they publish. A restore brings
+// the user back with its record unchanged apart from the flag, so it counts as an update,
+// as do status, email and password changes, verifications and merges.
+var eventTypes = map[string]string{
+	"created":         userevents.UserCreated,
+	"updated":         userevents.UserUpdated,
+	"restored":        userevents.UserUpdated,
+	"activated":       userevents.UserUpdated,
+	"deactivated":     userevents.UserUpdated,
+	"emailChanged":    userevents.UserUpdated,
+	"passwordChanged": userevents.UserUpdated,
+	"verified":        userevents.UserUpdated,
+	"merged":          userevents.UserUpdated,
+	"deleted":         userevents.UserDeleted,
+}
+
+// publishEvents publishes a lifecycle event per mutation with the default publisher,
+// logging the events that failed even after a retry, and holds them for the webhook, which
+// is called once the response is ready. Mutations recorded in the outbox are left to it.
+func publishEvents(req events.APIGatewayProxyRequest, mutations []mutation) {
+	published := make([]userevents.Event, 0, len(mutations))
+	for _, m := range mutations {
+		if eventType, ok := eventTypes[m.action]; ok && !m.outboxed {
+			published = append(published, userevents.NewEvent(eventType, m.email, m.after, req.RequestContext.RequestID))
+		}
+	}
+	webhook.Enqueue(req.RequestContext.RequestID, published)
+	for _, failure := range userevents.PublishAll(userevents.Default, published) {
+		logging.Default.Error("failed to publish event", logging.Fields{
+			"requestId": req.RequestContext.RequestID,
+			"type":      failure.Event.Type,
+			"email":     logging.Email(failure.Event.Email),
+			"error":     failure.Err,
+		})
+	}
+}
+
+// AuditLog handles GET /users/{email}/audit, returning the user's audit entries newest
+// first. Pages hold "limit" entries (25 by default, at most 100); pass the returned
+// nextCursor as "cursor" to read the next one. Only the user themselves or an
+// administrator may read it when authentication is configured.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the user.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+// - APIGatewayProxyResponse with a page of entries, or 404 if auditing is off.
+func AuditLog(req events.APIGatewayProxyRequest, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if !audit.Enabled() {
+		return APIError(http.StatusNotFound, CodeNotFound, ErrorAuditDisabled)
+	}
+
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	limit := audit.DefaultPageSize
+	if raw, ok := req.QueryStringParameters["limit"]; ok {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > audit.MaxPageSize {
+			return APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorInvalidLimit)
+		}
+	}
+
+	page, err := audit.List(user.NormalizeEmail(email), limit, req.QueryStringParameters["cursor"], dynaClient)
+	if errors.Is(err, audit.ErrInvalidCursor) {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	return APIResponse(http.StatusOK, page)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	userevents "github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// capturePublisher records the events it is asked to publish, failing them all if err is set
+type capturePublisher struct {
+	mu     sync.Mutex
+	events []userevents.Event
+	err    error
+}
+
+func (p *capturePublisher) Publish(event userevents.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return p.err
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	const ada = `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`
+	tests := []struct {
+		name       string
+		handler    userHandler
+		method     string
+		email      string
+		body       string
+		publishErr error
+		wantStatus int
+		wantType   string
+		wantUser   bool
+		wantTries  int
+	}{
+		{name: "create", handler: CreateUser, method: http.MethodPost,
+			body:       `{"email": "alan@example.com", "firstname": "Alan", "lastname": "Turing"}`,
+			wantStatus: http.StatusCreated, wantType: userevents.UserCreated, wantUser: true, wantTries: 1},
+		{name: "update", handler: UpdateUser, method: http.MethodPut, email: "ada@example.com", body: ada,
+			wantStatus: http.StatusOK, wantType: userevents.UserUpdated, wantUser: true, wantTries: 1},
+		{name: "delete", handler: DeleteUser, method: http.MethodDelete, email: "ada@example.com",
+			wantStatus: http.StatusOK, wantType: userevents.UserDeleted, wantTries: 1},
+		{name: "failed create", handler: CreateUser, method: http.MethodPost, body: ada,
+			wantStatus: http.StatusConflict},
+		{name: "publish failure", handler: DeleteUser, method: http.MethodDelete, email: "ada@example.com",
+			publishErr: errors.New("unavailable"), wantStatus: http.StatusOK, wantType: userevents.UserDeleted,
+			wantTries: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &capturePublisher{err: tt.publishErr}
+			defer func(previous userevents.Publisher) { userevents.Default = previous }(userevents.Default)
+			userevents.Default = publisher
+
+			repo := user.NewMemoryRepository()
+			if err := repo.Create(&user.User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace"}); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+			req := events.APIGatewayProxyRequest{HTTPMethod: tt.method, Body: tt.body,
+				Headers:        map[string]string{"Content-Type": "application/json"},
+				RequestContext: events.APIGatewayProxyRequestContext{RequestID: "req-1"}}
+			if tt.email != "" {
+				req.PathParameters = map[string]string{"email": tt.email}
+			}
+
+			resp, err := tt.handler(req, repo, nil)
+			if err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			// Creates also ask for the verification email, which isn't a lifecycle event
+			var lifecycle []userevents.Event
+			for _, event := range publisher.events {
+				if event.Type != userevents.UserVerificationRequested {
+					lifecycle = append(lifecycle, event)
+				}
+			}
+			if len(lifecycle) != tt.wantTries {
+				t.Fatalf("published %d events, want %d", len(lifecycle), tt.wantTries)
+			}
+			for _, event := range lifecycle {
+				if event.Type != tt.wantType || event.RequestID != "req-1" || event.Time == "" {
+					t.Errorf("event = %+v, want a %s event of req-1", event, tt.wantType)
+				}
+				if (event.User != nil) != tt.wantUser || (event.User != nil && event.User.Email != event.Email) {
+					t.Errorf("event user = %+v, want one: %v", event.User, tt.wantUser)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/auth"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Error messages for authentication failures
+var (
+	ErrorMissingToken      = "missing bearer token"
+	ErrorInvalidToken      = "invalid bearer token"
+	ErrorExpiredToken      = "bearer token has expired"
+	ErrorInsufficientScope = "token scope does not allow this operation"
+	ErrorMissingAPIKey     = "missing API key"
+)
+
+// Claims holds the validated claims of a bearer token
+type Claims struct {
+	Subject   string   `json:"sub"`            // Identifier of the caller
+	Email     string   `json:"email"`          // Email of the caller, if the issuer includes it
+	Scope     string   `json:"scope"`          // Space-separated list of granted scopes
+	Groups    []string `json:"cognito:groups"` // Cognito groups of the caller, if the issuer includes them
+	Issuer    string   `json:"iss"`            // Token issuer
+	IssuedAt  int64    `json:"iat"`            // Issue time as epoch seconds
+	ExpiresAt int64    `json:"exp"`            // Expiry as epoch seconds
+	NotBefore int64    `json:"nbf"`            // Start of validity as epoch seconds
+}
+
+// jwtHeader is the decoded JOSE header of a token
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// jwksCache caches the RSA keys fetched from JWT_JWKS_URL for the container lifetime
+var jwksCache = struct {
+	sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}{}
+
+// jwksClient fetches signing keys with a short timeout so a slow issuer can't stall requests
+var jwksClient = &http.Client{Timeout: 3 * time.Second}
+
+// jwtEnabled reports whether bearer-token authentication is configured.
+func jwtEnabled() bool {
+	return hmacSecret() != "" || os.Getenv("JWT_JWKS_URL") != "" || os.Getenv("JWT_PRIVATE_KEY") != ""
+}
+
+// hmacSecret returns the HS256 secret: JWT_SIGNING_KEY, or JWT_SECRET as an alias.
+func hmacSecret() string {
+	if secret := os.Getenv("JWT_SIGNING_KEY"); secret != "" {
+		return secret
+	}
+	return os.Getenv("JWT_SECRET")
+}
+
+// requiredScope returns the scope needed for an HTTP method, or an empty string if the
+// method needs no authentication.
+func requiredScope(method string) string {
+	switch method {
+	case http.MethodOptions:
+		return ""
+	case http.MethodGet, http.MethodHead:
+		if os.Getenv("AUTH_OPTIONAL_READS") == "true" {
+			return ""
+		}
+		return auth.ScopeRead
+	default:
+		return auth.ScopeWrite
+	}
+}
+
+// RequireAuth authenticates a request with an API key or a bearer token.
+//
+// An "X-Api-Key" header is checked against the API_KEYS_TABLE keys table when configured.
+// Otherwise the "Authorization: Bearer <jwt>" header is verified with the HMAC secret in
+// JWT_SIGNING_KEY (HS256) or the keys published at JWT_JWKS_URL (RS256), which includes the
+// tokens issued by POST /login; when JWT_ISSUER is
+// set, the "iss" claim must match it. Authentication is skipped entirely when neither
+// mechanism is configured, and for reads when AUTH_OPTIONAL_READS is "true". On success the
+// claims are attached to the request's authorizer context, in the same shape a Cognito
+// authorizer uses, so handlers can log the caller.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to authenticate.
+// - dynaClient: DynamoDB client interface used to look up API keys.
+//
+// Returns:
+// - The request with the caller's claims attached.
+// - A 401 or 403 response if the request is not allowed, or nil if it may proceed.
+func RequireAuth(req events.APIGatewayProxyRequest, dynaClient dynamodbiface.DynamoDBAPI) (
+	events.APIGatewayProxyRequest, *events.APIGatewayProxyResponse) {
+	scope := requiredScope(req.HTTPMethod)
+	if scope == "" || (!jwtEnabled() && !auth.APIKeysEnabled()) {
+		return req, nil
+	}
+
+	if key := headerValue(req, "X-Api-Key"); key != "" && auth.APIKeysEnabled() {
+		return requireAPIKey(req, key, scope, dynaClient)
+	}
+	if !jwtEnabled() {
+		return req, unauthorized(ErrorMissingAPIKey)
+	}
+
+	header := headerValue(req, "Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return req, unauthorized(ErrorMissingToken)
+	}
+
+	claims, err := parseToken(strings.TrimSpace(header[len("Bearer "):]))
+	if err != nil {
+		return req, unauthorized(err.Error())
+	}
+	if !auth.HasScope(strings.Fields(claims.Scope), scope) {
+		resp, _ := APIError(http.StatusForbidden, CodeForbidden, ErrorInsufficientScope)
+		return req, resp
+	}
+
+	return withClaims(req, claims), nil
+}
+
+// requireAPIKey authenticates a request with an API key.
+func requireAPIKey(req events.APIGatewayProxyRequest, key string, scope string,
+	dynaClient dynamodbiface.DynamoDBAPI) (events.APIGatewayProxyRequest, *events.APIGatewayProxyResponse) {
+	apiKey, err := auth.ValidateAPIKey(context.Background(), key, dynaClient)
+	switch {
+	case errors.Is(err, auth.ErrInvalidAPIKey), errors.Is(err, auth.ErrExpiredAPIKey):
+		return req, unauthorized(err.Error())
+	case err != nil:
+		logging.Default.Error("failed to validate API key", logging.Fields{
+			"requestId": req.RequestContext.RequestID,
+			"error":     err,
+		})
+		resp, _ := APIError(http.StatusBadGateway, CodeStorage, ErrorInternal)
+		return req, resp
+	}
+
+	if !auth.HasScope(apiKey.Scopes, scope) {
+		resp, _ := APIError(http.StatusForbidden, CodeForbidden, ErrorInsufficientScope)
+		return req, resp
+	}
+	return withClaims(req, &Claims{Subject: "apikey:" + apiKey.Name, Scope: strings.Join(apiKey.Scopes, " ")}), nil
+}
+
+// unauthorized builds a 401 response with the WWW-Authenticate challenge.
+func unauthorized(msg string) *events.APIGatewayProxyResponse {
+	resp, _ := APIError(http.StatusUnauthorized, CodeUnauthorized, msg)
+	resp.Headers["WWW-Authenticate"] = "Bearer"
+	return resp
+}
+
+// withClaims attaches validated claims to the request's authorizer context. When they
+// name the caller an upstream authorizer already identified, they are merged into its
+// claims, so its other claims, such as cognito:groups, are kept; the caller's identity and
+// scope are replaced. Claims of an upstream caller with another subject or email are
+// dropped, so a token never inherits someone else's groups.
+func withClaims(req events.APIGatewayProxyRequest, claims *Claims) events.APIGatewayProxyRequest {
+	authorizer := map[string]interface{}{}
+	for key, value := range req.RequestContext.Authorizer {
+		authorizer[key] = value
+	}
+	merged := map[string]interface{}{}
+	if existing, ok := authorizer["claims"].(map[string]interface{}); ok && sameCaller(existing, claims) {
+		for key, value := range existing {
+			merged[key] = value
+		}
+	}
+	merged["sub"] = claims.Subject
+	merged["email"] = claims.Email
+	merged["scope"] = claims.Scope
+	if len(claims.Groups) > 0 {
+		groups := make([]interface{}, len(claims.Groups))
+		for i, group := range claims.Groups {
+			groups[i] = group
+		}
+		merged["cognito:groups"] = groups
+	}
+	authorizer["claims"] = merged
+	req.RequestContext.Authorizer = authorizer
+	return req
+}
+
+// sameCaller reports whether upstream claims identify the caller of verified claims: they
+// carry a subject or an email, and each one they carry matches.
+func sameCaller(upstream map[string]interface{}, claims *Claims) bool {
+	sub, _ := upstream["sub"].(string)
+	email, _ := upstream["email"].(string)
+	if sub == "" && email == "" {
+		return false
+	}
+	if sub != "" && sub != claims.Subject {
+		return false
+	}
+	return email == "" || validators.NormalizeEmail(email) == validators.NormalizeEmail(claims.Email)
+}
+
+// parseToken verifies a compact JWT and returns its claims.
+//
+// Parameters:
+// - token: The encoded token.
+//
+// Returns:
+// - The validated claims.
+// - An error if the signature, expiry, or issuer is invalid.
+func parseToken(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New(ErrorInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New(ErrorInvalidToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New(ErrorInvalidToken)
+	}
+	if err := verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, errors.New(ErrorInvalidToken)
+	}
+
+	claims := new(Claims)
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, errors.New(ErrorInvalidToken)
+	}
+
+	now := time.Now().Unix()
+	if claims.ExpiresAt == 0 || now >= claims.ExpiresAt {
+		return nil, errors.New(ErrorExpiredToken)
+	}
+	if claims.NotBefore != 0 && now < claims.NotBefore {
+		return nil, errors.New(ErrorInvalidToken)
+	}
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" && claims.Issuer != issuer {
+		return nil, errors.New(ErrorInvalidToken)
+	}
+	return claims, nil
+}
+
+// decodeSegment base64url-decodes a token segment and unmarshals its JSON.
+func decodeSegment(segment string, out interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// verifySignature checks the token signature with the key configured for its algorithm.
+// Only HS256 and RS256 are accepted, which rules out "none" and algorithm confusion.
+func verifySignature(header jwtHeader, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch header.Algorithm {
+	case "HS256":
+		secret := hmacSecret()
+		if secret == "" {
+			return errors.New("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	case "RS256":
+		// Tokens issued by POST /login are signed with JWT_PRIVATE_KEY
+		if own, keyID, err := loginKey(); err == nil && own != nil && header.KeyID == keyID {
+			return rsa.VerifyPKCS1v15(&own.PublicKey, crypto.SHA256, digest[:], signature)
+		}
+		key, err := jwksKey(header.KeyID)
+		if err != nil {
+			return err
+		}
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	default:
+		return fmt.Errorf("unsupported algorithm %q", header.Algorithm)
+	}
+}
+
+// jwksKey returns the RSA key with the given ID from JWT_JWKS_URL.
+// Keys are cached; an unknown key ID triggers a refetch at most once a minute
+// so rotated keys are picked up without hammering the issuer.
+func jwksKey(keyID string) (*rsa.PublicKey, error) {
+	url := os.Getenv("JWT_JWKS_URL")
+	if url == "" {
+		return nil, errors.New("RS256 tokens are not accepted")
+	}
+
+	jwksCache.Lock()
+	defer jwksCache.Unlock()
+
+	if key, ok := jwksCache.keys[keyID]; ok {
+		return key, nil
+	}
+	if time.Since(jwksCache.fetchedAt) < time.Minute {
+		return nil, fmt.Errorf("unknown key id %q", keyID)
+	}
+
+	keys, err := fetchJWKS(url)
+	jwksCache.fetchedAt = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	jwksCache.keys = keys
+
+	if key, ok := keys[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", keyID)
+}
+
+// fetchJWKS downloads a JSON Web Key Set and returns its RSA keys by key ID.
+func fetchJWKS(url string) (map[string]*rsa.PublicKey, error) {
+	resp, err := jwksClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS returned %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[jwk.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// actor names the caller of a request for logging: the token subject or email,
+// or "anonymous" when the request carries no claims.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the authorizer context.
+//
+// Returns:
+// - A string identifying the caller.
+func actor(req events.APIGatewayProxyRequest) string {
+	claims, _ := req.RequestContext.Authorizer["claims"].(map[string]interface{})
+	for _, key := range []string{"sub", "email"} {
+		if value, _ := claims[key].(string); value != "" {
+			return value
+		}
+	}
+	return "anonymous"
+}
+
+// logMutation records who changed which user.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest that performed the change.
+// - action: What happened to the user (e.g. "created").
+// - email: The email of the affected user.
+func logMutation(req events.APIGatewayProxyRequest, action string, email string) {
+	logging.Default.Info("user "+action, logging.Fields{
+		"requestId": req.RequestContext.RequestID,
+		"email":     logging.Email(email),
+		"actor":     actor(req),
+	})
+}
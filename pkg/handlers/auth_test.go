@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"reflect"
+	"testing"
+)
+
+func TestWithClaims(t *testing.T) {
+	tests := []struct {
+		name       string
+		upstream   map[string]interface{}
+		claims     Claims
+		wantGroups []string
+		wantAdmin  bool
+		wantKept   bool // Whether the other upstream claims are kept
+	}{
+		{name: "no upstream claims", claims: Claims{Subject: "ada", Email: "ada@example.com", Scope: "read"}},
+		{name: "keeps upstream groups", upstream: map[string]interface{}{"sub": "ada", "cognito:groups": "admins",
+			"cognito:username": "ada"},
+			claims: Claims{Subject: "ada", Email: "ada@example.com", Scope: "read"}, wantGroups: []string{"admins"},
+			wantAdmin: true, wantKept: true},
+		{name: "same email in another case", upstream: map[string]interface{}{"email": "Ada@Example.com",
+			"cognito:groups": "admins", "cognito:username": "ada"},
+			claims: Claims{Subject: "ada", Email: "ada@example.com", Scope: "read"}, wantGroups: []string{"admins"},
+			wantAdmin: true, wantKept: true},
+		{name: "other subject drops upstream groups", upstream: map[string]interface{}{"sub": "root",
+			"email": "root@example.com", "cognito:groups": "admins", "cognito:username": "root"},
+			claims: Claims{Subject: "ada", Email: "ada@example.com", Scope: "read write"}},
+		{name: "same subject, other email", upstream: map[string]interface{}{"sub": "ada", "email": "root@example.com",
+			"cognito:groups": "admins", "cognito:username": "root"},
+			claims: Claims{Subject: "ada", Email: "ada@example.com", Scope: "read"}},
+		{name: "same email, other subject", upstream: map[string]interface{}{"sub": "root", "email": "ada@example.com",
+			"cognito:groups": "admins", "cognito:username": "root"},
+			claims: Claims{Subject: "ada", Email: "ada@example.com", Scope: "read"}},
+		{name: "api key over a signed-in admin", upstream: map[string]interface{}{"sub": "root",
+			"cognito:groups": "admins", "cognito:username": "root"},
+			claims: Claims{Subject: "apikey:reports", Scope: "read"}},
+		{name: "token groups", claims: Claims{Subject: "ada", Scope: "read", Groups: []string{"admins", "ops"}},
+			wantGroups: []string{"admins", "ops"}, wantAdmin: true},
+		{name: "identity replaced", upstream: map[string]interface{}{"sub": "bob", "email": "bob@example.com"},
+			claims: Claims{Subject: "apikey:ops", Scope: "read write admin"}, wantAdmin: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayProxyRequest{}
+			req.RequestContext.Authorizer = map[string]interface{}{"principalId": "p"}
+			if tt.upstream != nil {
+				req.RequestContext.Authorizer["claims"] = tt.upstream
+			}
+
+			got := withClaims(req, &tt.claims)
+			caller, err := CallerFromRequest(got)
+			if err != nil {
+				t.Fatalf("CallerFromRequest() error = %v", err)
+			}
+			if caller.Subject != tt.claims.Subject || caller.Email != tt.claims.Email {
+				t.Errorf("caller = %q <%s>, want %q <%s>", caller.Subject, caller.Email, tt.claims.Subject, tt.claims.Email)
+			}
+			if !reflect.DeepEqual(caller.Groups, tt.wantGroups) {
+				t.Errorf("Groups = %v, want %v", caller.Groups, tt.wantGroups)
+			}
+			if admin := callerIsAdmin(got); admin != tt.wantAdmin {
+				t.Errorf("callerIsAdmin() = %v, want %v", admin, tt.wantAdmin)
+			}
+			claims := got.RequestContext.Authorizer["claims"].(map[string]interface{})
+			if _, kept := claims["cognito:username"]; kept != tt.wantKept {
+				t.Errorf("upstream cognito:username kept = %v, want %v", kept, tt.wantKept)
+			}
+			if got.RequestContext.Authorizer["principalId"] != "p" {
+				t.Error("the other authorizer context was dropped")
+			}
+		})
+	}
+}
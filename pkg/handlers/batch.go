@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+)
+
+// BatchBody represents the response of a batch operation
+type BatchBody struct {
+	Results []user.BatchResult `json:"results"` // Outcome of each item, in request order
+	Created int                `json:"created"` // Number of items created
+	Skipped int                `json:"skipped"` // Number of items skipped
+	Failed  int                `json:"failed"`  // Number of items that failed
+}
+
+// newBatchBody tallies the outcomes of a batch operation.
+func newBatchBody(results []user.BatchResult) BatchBody {
+	body := BatchBody{Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case user.BatchCreated:
+			body.Created++
+		case user.BatchSkipped:
+			body.Skipped++
+		default:
+			body.Failed++
+		}
+	}
+	return body
+}
+
+// status returns the HTTP status summarizing the outcomes: 201 if every item was created,
+// 200 if every item was skipped, 400 if every item failed, and 207 Multi-Status otherwise.
+func (b BatchBody) status() int {
+	switch len(b.Results) {
+	case b.Created:
+		return http.StatusCreated
+	case b.Skipped:
+		return http.StatusOK
+	case b.Failed:
+		return http.StatusBadRequest
+	default:
+		return http.StatusMultiStatus
+	}
+}
+
+// CreateUsers handles POST /users/batch, creating up to 500 users from a JSON array.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest containing the users.
+// - repo: Repository where the new users will be stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+// - APIGatewayProxyResponse with the outcome of each user, or 413 if there are too many.
+func CreateUsers(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+
+	req, invalid := decodeBody(req, maxBulkBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+
+	opts, err := createOptions(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	results, err := user.CreateUsers(req.Body, opts, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+
+	body := newBatchBody(results)
+	var mutations []mutation
+	for _, result := range results {
+		if result.Status == user.BatchCreated {
+			mutations = append(mutations, mutation{action: "created", email: result.Email, after: result.User})
+		}
+	}
+	recordMutations(req, dynaClient, mutations...)
+	for _, result := range results {
+		if result.Status == user.BatchCreated {
+			publishVerification(req, result.User)
+		}
+	}
+	return APIResponse(body.status(), body)
+}
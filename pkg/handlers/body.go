@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Default limits on decoded request bodies. API Gateway accepts up to 10 MB, far more than
+// a user needs.
+const (
+	defaultMaxBodyBytes     = 64 << 10
+	defaultMaxBulkBodyBytes = 1 << 20
+)
+
+// supportedContentEncodings are the Content-Encoding values request bodies may be sent with
+var supportedContentEncodings = []string{"gzip", "identity"}
+
+// Error messages for request bodies
+var (
+	ErrorInvalidBase64Body = "invalid base64 body"
+	ErrorNotJSON           = "request body must be sent with Content-Type: application/json"
+	ErrorBodyTooLarge      = "request body exceeds the limit of %d bytes"
+	ErrorInvalidGzipBody   = "invalid gzip body"
+	ErrorUnsupportedCoding = "unsupported Content-Encoding; supported values are "
+)
+
+// requestBody returns the raw request body, decoding it first when API Gateway
+// delivered it base64-encoded (binary media types or compressed payloads).
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the body.
+//
+// Returns:
+// - The decoded body.
+// - An error if the body is flagged as base64 but cannot be decoded.
+func requestBody(req events.APIGatewayProxyRequest) (string, error) {
+	if !req.IsBase64Encoded {
+		return req.Body, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(req.Body)
+	if err != nil {
+		return "", errors.New(ErrorInvalidBase64Body)
+	}
+	return string(decoded), nil
+}
+
+// withDecodedBody returns a copy of the request whose body is plain text,
+// so the user package can unmarshal it directly.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to normalize.
+//
+// Returns:
+// - The request with a decoded body and IsBase64Encoded cleared.
+// - An error if the body cannot be decoded.
+func withDecodedBody(req events.APIGatewayProxyRequest) (events.APIGatewayProxyRequest, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return req, err
+	}
+	req.Body = body
+	req.IsBase64Encoded = false
+	return req, nil
+}
+
+// maxBodyBytes returns the size limit of single-user bodies: MAX_BODY_BYTES, or 64 KB.
+func maxBodyBytes() int {
+	return bodyLimit("MAX_BODY_BYTES", defaultMaxBodyBytes)
+}
+
+// maxBulkBodyBytes returns the size limit of batch and import bodies: MAX_BULK_BODY_BYTES,
+// or 1 MB.
+func maxBulkBodyBytes() int {
+	return bodyLimit("MAX_BULK_BODY_BYTES", defaultMaxBulkBodyBytes)
+}
+
+// bodyLimit reads a body size limit from an environment variable, falling back to def.
+func bodyLimit(env string, def int) int {
+	limit, err := strconv.Atoi(os.Getenv(env))
+	if err != nil || limit <= 0 {
+		return def
+	}
+	return limit
+}
+
+// decodeBody returns a copy of the request with a plain text body, as withDecodedBody
+// does, once its decoded size is checked against limit. Base64 bodies are sized before
+// they are decoded, so oversized ones are never held twice in memory. Bodies sent with
+// Content-Encoding: gzip are decompressed too; the limit then bounds both the compressed
+// and the decompressed body, and decompression stops as soon as it is exceeded.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the body.
+// - limit: The most bytes the decoded body may have.
+//
+// Returns:
+//   - The request with a decoded body.
+//   - A 415 response listing the supported encodings if the Content-Encoding isn't one of
+//     them, a 413 response naming the limit if the body exceeds it, a 400 response if it
+//     can't be decoded, or nil.
+func decodeBody(req events.APIGatewayProxyRequest, limit int) (events.APIGatewayProxyRequest,
+	*events.APIGatewayProxyResponse) {
+	gzipped, supported := contentEncoding(req)
+	if !supported {
+		resp, _ := APIError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+			ErrorUnsupportedCoding+strings.Join(supportedContentEncodings, ", "))
+		resp.Headers["Accept-Encoding"] = strings.Join(supportedContentEncodings, ", ")
+		return req, resp
+	}
+
+	size := len(req.Body)
+	if req.IsBase64Encoded {
+		size = base64.StdEncoding.DecodedLen(size) - (len(req.Body) - len(strings.TrimRight(req.Body, "=")))
+	}
+	if size > limit {
+		return req, bodyTooLarge(limit)
+	}
+
+	decoded, err := withDecodedBody(req)
+	if err != nil {
+		resp, _ := APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return req, resp
+	}
+	if gzipped {
+		decompressed, tooLarge, err := gunzip(decoded.Body, limit)
+		if tooLarge {
+			return req, bodyTooLarge(limit)
+		}
+		if err != nil {
+			resp, _ := APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorInvalidGzipBody)
+			return req, resp
+		}
+		decoded.Body = decompressed
+	}
+	if len(decoded.Body) > limit {
+		return req, bodyTooLarge(limit)
+	}
+	return decoded, nil
+}
+
+// contentEncoding reads the Content-Encoding of the request body. A missing header means
+// identity, and x-gzip is read as gzip.
+//
+// Returns:
+// - True if the body is gzipped.
+// - False if the encoding isn't supported.
+func contentEncoding(req events.APIGatewayProxyRequest) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(headerValue(req, "Content-Encoding"))) {
+	case "", "identity":
+		return false, true
+	case "gzip", "x-gzip":
+		return true, true
+	}
+	return false, false
+}
+
+// gunzip decompresses a gzipped body, reading at most one byte past limit so a small body
+// inflating to gigabytes is never held in memory.
+//
+// Returns:
+// - The decompressed body.
+// - True if it exceeds limit.
+// - An error if the gzip stream is corrupt or truncated.
+func gunzip(body string, limit int) (string, bool, error) {
+	reader, err := gzip.NewReader(strings.NewReader(body))
+	if err != nil {
+		return "", false, err
+	}
+	defer reader.Close()
+	decompressed, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if len(decompressed) > limit {
+		return "", true, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return string(decompressed), false, nil
+}
+
+// bodyTooLarge builds the 413 response of a body over limit.
+func bodyTooLarge(limit int) *events.APIGatewayProxyResponse {
+	resp, _ := APIError(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, fmt.Sprintf(ErrorBodyTooLarge, limit))
+	return resp
+}
+
+// MediaTypeMergePatch is the media type of a JSON merge patch (RFC 7396), accepted as JSON
+const MediaTypeMergePatch = "application/merge-patch+json"
+
+// requireJSON rejects a request whose body isn't declared as JSON or as a JSON merge patch.
+// The media type is compared case-insensitively and parameters such as charset are ignored.
+// Requests without a Content-Type are accepted as JSON, unless STRICT_CONTENT_TYPE is "true".
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the body.
+//
+// Returns:
+// - A 415 response if the body isn't JSON, or nil if it may be read.
+func requireJSON(req events.APIGatewayProxyRequest) *events.APIGatewayProxyResponse {
+	contentType := headerValue(req, "Content-Type")
+	if contentType == "" && os.Getenv("STRICT_CONTENT_TYPE") != "true" {
+		return nil
+	}
+	// ParseMediaType lower-cases the media type
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && (mediaType == MediaTypeJSON || mediaType == MediaTypeMergePatch) {
+		return nil
+	}
+	resp, _ := APIError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, ErrorNotJSON)
+	return resp
+}
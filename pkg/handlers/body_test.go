@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const adaBody = `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`
+
+func TestDecodeBodyBase64(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		encoded    bool
+		wantBody   string
+		wantStatus int
+	}{
+		{name: "plain", body: adaBody, wantBody: adaBody},
+		{name: "encoded", body: base64.StdEncoding.EncodeToString([]byte(adaBody)), encoded: true, wantBody: adaBody},
+		{name: "encoded empty", encoded: true},
+		{name: "plain that looks encoded", body: "eyJhIjoxfQ==", wantBody: "eyJhIjoxfQ=="},
+		{name: "invalid base64", body: "not base64!", encoded: true, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayProxyRequest{Body: tt.body, IsBase64Encoded: tt.encoded}
+			decoded, resp := decodeBody(req, maxBodyBytes())
+			if tt.wantStatus != 0 {
+				if resp == nil || resp.StatusCode != tt.wantStatus {
+					t.Fatalf("decodeBody() response = %+v, want %d", resp, tt.wantStatus)
+				}
+				return
+			}
+			if resp != nil {
+				t.Fatalf("decodeBody() response = %d: %s", resp.StatusCode, resp.Body)
+			}
+			if decoded.Body != tt.wantBody || decoded.IsBase64Encoded {
+				t.Errorf("decodeBody() = %q (base64 %v), want %q", decoded.Body, decoded.IsBase64Encoded, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCreateUserBase64Body(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		encoded bool
+	}{
+		{name: "plain", body: adaBody},
+		{name: "encoded", body: base64.StdEncoding.EncodeToString([]byte(adaBody)), encoded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := user.NewMemoryRepository()
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/users", Body: tt.body,
+				IsBase64Encoded: tt.encoded, Headers: map[string]string{"Content-Type": "application/json"}}
+
+			resp, err := CreateUser(req, repo, nil)
+			if err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatalf("status = %d, want 201: %s", resp.StatusCode, resp.Body)
+			}
+			if stored, err := repo.Get("ada@example.com", user.ReadOptions{}); err != nil || stored.LastName != "Lovelace" {
+				t.Errorf("stored = %+v, %v; want the decoded user", stored, err)
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		strict      bool
+		wantCreate  int
+		wantUpdate  int
+	}{
+		{name: "json", contentType: "application/json", wantCreate: http.StatusCreated, wantUpdate: http.StatusOK},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", wantCreate: http.StatusCreated,
+			wantUpdate: http.StatusOK},
+		{name: "upper case", contentType: "Application/JSON; Charset=UTF-8", wantCreate: http.StatusCreated,
+			wantUpdate: http.StatusOK},
+		{name: "missing", wantCreate: http.StatusCreated, wantUpdate: http.StatusOK},
+		{name: "missing when strict", strict: true, wantCreate: http.StatusUnsupportedMediaType,
+			wantUpdate: http.StatusUnsupportedMediaType},
+		{name: "json when strict", contentType: "application/json", strict: true, wantCreate: http.StatusCreated,
+			wantUpdate: http.StatusOK},
+		{name: "multipart", contentType: "multipart/form-data; boundary=xyz", wantCreate: http.StatusUnsupportedMediaType,
+			wantUpdate: http.StatusUnsupportedMediaType},
+		{name: "form", contentType: "application/x-www-form-urlencoded", wantCreate: http.StatusUnsupportedMediaType,
+			wantUpdate: http.StatusUnsupportedMediaType},
+		{name: "malformed", contentType: "application/json; charset", wantCreate: http.StatusUnsupportedMediaType,
+			wantUpdate: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.strict {
+				t.Setenv("STRICT_CONTENT_TYPE", "true")
+			}
+			headers := map[string]string{}
+			if tt.contentType != "" {
+				headers["Content-Type"] = tt.contentType
+			}
+
+			created, err := CreateUser(events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/users",
+				Body: adaBody, Headers: headers}, user.NewMemoryRepository(), nil)
+			if err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+			if created.StatusCode != tt.wantCreate {
+				t.Errorf("CreateUser() status = %d, want %d: %s", created.StatusCode, tt.wantCreate, created.Body)
+			}
+
+			repo := user.NewMemoryRepository()
+			if err := repo.Create(&user.User{Email: "ada@example.com", FirstName: "Ada", LastName: "King"}); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+			updated, err := UpdateUser(events.APIGatewayProxyRequest{HTTPMethod: http.MethodPut,
+				Path: "/users/ada%40example.com", PathParameters: map[string]string{"email": "ada@example.com"},
+				Body: adaBody, Headers: headers}, repo, nil)
+			if err != nil {
+				t.Fatalf("UpdateUser() error = %v", err)
+			}
+			if updated.StatusCode != tt.wantUpdate {
+				t.Errorf("UpdateUser() status = %d, want %d: %s", updated.StatusCode, tt.wantUpdate, updated.Body)
+			}
+			if tt.wantCreate == http.StatusUnsupportedMediaType && !strings.Contains(created.Body, MediaTypeJSON) {
+				t.Errorf("415 body = %s, want it to name %s", created.Body, MediaTypeJSON)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyLimit(t *testing.T) {
+	const limit = 100
+	tests := []struct {
+		name     string
+		size     int // Decoded size of the body
+		encoded  bool
+		wantSize bool // Whether the body is within the limit
+	}{
+		{name: "under", size: limit - 1, wantSize: true},
+		{name: "at the limit", size: limit, wantSize: true},
+		{name: "one over", size: limit + 1},
+		// 100 bytes encode to 136 characters, well over the limit, with two padding characters
+		{name: "base64 at the limit", size: limit, encoded: true, wantSize: true},
+		{name: "base64 one over", size: limit + 1, encoded: true},
+		{name: "base64 with one padding character", size: limit - 2, encoded: true, wantSize: true},
+		{name: "base64 without padding", size: limit - 1, encoded: true, wantSize: true},
+		{name: "base64 over without padding", size: limit + 2, encoded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.Repeat("a", tt.size)
+			req := events.APIGatewayProxyRequest{Body: body}
+			if tt.encoded {
+				req.Body, req.IsBase64Encoded = base64.StdEncoding.EncodeToString([]byte(body)), true
+			}
+
+			decoded, resp := decodeBody(req, limit)
+			if !tt.wantSize {
+				if resp == nil || resp.StatusCode != http.StatusRequestEntityTooLarge || !strings.Contains(resp.Body, "100 bytes") {
+					t.Fatalf("decodeBody() response = %+v, want a 413 naming the limit", resp)
+				}
+				return
+			}
+			if resp != nil {
+				t.Fatalf("decodeBody() response = %d: %s", resp.StatusCode, resp.Body)
+			}
+			if decoded.Body != body {
+				t.Errorf("decodeBody() = %d bytes, want the %d bytes sent", len(decoded.Body), len(body))
+			}
+		})
+	}
+}
+
+func TestCreateUserBodyLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxBytes   string
+		size       int
+		encoded    bool
+		wantStatus int
+	}{
+		{name: "at the limit", maxBytes: "200", size: 200, wantStatus: http.StatusCreated},
+		{name: "one over", maxBytes: "200", size: 201, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "base64 at the limit", maxBytes: "200", size: 200, encoded: true, wantStatus: http.StatusCreated},
+		{name: "base64 one over", maxBytes: "200", size: 201, encoded: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "default limit", size: defaultMaxBodyBytes + 1, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "invalid limit uses the default", maxBytes: "lots", size: 200, wantStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_BODY_BYTES", tt.maxBytes)
+			// JSON allows trailing whitespace, which pads the body to the size
+			body := adaBody + strings.Repeat(" ", tt.size-len(adaBody))
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/users", Body: body,
+				Headers: map[string]string{"Content-Type": "application/json"}}
+			if tt.encoded {
+				req.Body, req.IsBase64Encoded = base64.StdEncoding.EncodeToString([]byte(body)), true
+			}
+
+			resp, err := CreateUser(req, user.NewMemoryRepository(), nil)
+			if err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+		})
+	}
+}
+
+func TestBulkBodyLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxBytes  string
+		maxBulk   string
+		wantLimit int
+	}{
+		{name: "default", wantLimit: defaultMaxBulkBodyBytes},
+		{name: "configured", maxBulk: "2048", wantLimit: 2048},
+		{name: "independent of the single-user limit", maxBytes: "100", wantLimit: defaultMaxBulkBodyBytes},
+		{name: "invalid", maxBulk: "-1", wantLimit: defaultMaxBulkBodyBytes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_BODY_BYTES", tt.maxBytes)
+			t.Setenv("MAX_BULK_BODY_BYTES", tt.maxBulk)
+			if got := maxBulkBodyBytes(); got != tt.wantLimit {
+				t.Errorf("maxBulkBodyBytes() = %d, want %d", got, tt.wantLimit)
+			}
+		})
+	}
+}
+
+// gzipped compresses body as a client sending Content-Encoding: gzip would.
+func gzipped(t *testing.T, body string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(body)); err != nil {
+		t.Fatalf("compressing: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("compressing: %v", err)
+	}
+	return buf.String()
+}
+
+func TestGzippedBodies(t *testing.T) {
+	// Trailing whitespace inflates to well over the limit but compresses to a few bytes
+	padded := adaBody + strings.Repeat(" ", 10000)
+	compressed := gzipped(t, adaBody)
+	tests := []struct {
+		name        string
+		encoding    string
+		body        string
+		encoded     bool
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "gzip", encoding: "gzip", body: compressed, wantStatus: http.StatusOK},
+		{name: "gzip in base64", encoding: "gzip", body: base64.StdEncoding.EncodeToString([]byte(compressed)),
+			encoded: true, wantStatus: http.StatusOK},
+		{name: "x-gzip", encoding: "x-gzip", body: compressed, wantStatus: http.StatusOK},
+		{name: "uppercase", encoding: " GZIP ", body: compressed, wantStatus: http.StatusOK},
+		{name: "identity", encoding: "identity", body: adaBody, wantStatus: http.StatusOK},
+		{name: "inflates over the limit", encoding: "gzip", body: gzipped(t, padded),
+			wantStatus: http.StatusRequestEntityTooLarge, wantMessage: "200 bytes"},
+		{name: "truncated", encoding: "gzip", body: compressed[:len(compressed)/2], wantStatus: http.StatusBadRequest,
+			wantMessage: ErrorInvalidGzipBody},
+		{name: "not gzip", encoding: "gzip", body: adaBody, wantStatus: http.StatusBadRequest,
+			wantMessage: ErrorInvalidGzipBody},
+		{name: "unsupported", encoding: "br", body: adaBody, wantStatus: http.StatusUnsupportedMediaType,
+			wantMessage: ErrorUnsupportedCoding + "gzip, identity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_BODY_BYTES", "200")
+			headers := map[string]string{"Content-Type": "application/json", "Content-Encoding": tt.encoding}
+			wantCreate := tt.wantStatus
+			if wantCreate == http.StatusOK {
+				wantCreate = http.StatusCreated
+			}
+
+			repo := user.NewMemoryRepository()
+			created, err := CreateUser(events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/users",
+				Body: tt.body, IsBase64Encoded: tt.encoded, Headers: headers}, repo, nil)
+			if err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+			if created.StatusCode != wantCreate {
+				t.Fatalf("CreateUser() status = %d, want %d: %s", created.StatusCode, wantCreate, created.Body)
+			}
+			if tt.wantMessage != "" {
+				var body ErrorBody
+				if err := json.Unmarshal([]byte(created.Body), &body); err != nil {
+					t.Fatalf("body %s isn't an error: %v", created.Body, err)
+				}
+				if body.ErrorMsg == nil || !strings.Contains(*body.ErrorMsg, tt.wantMessage) {
+					t.Errorf("error = %s, want %q", created.Body, tt.wantMessage)
+				}
+				if tt.wantStatus == http.StatusUnsupportedMediaType && created.Headers["Accept-Encoding"] != "gzip, identity" {
+					t.Errorf("Accept-Encoding = %q, want the supported encodings", created.Headers["Accept-Encoding"])
+				}
+				return
+			}
+			if stored, err := repo.Get("ada@example.com", user.ReadOptions{}); err != nil || stored.LastName != "Lovelace" {
+				t.Fatalf("stored = %+v, %v; want the decompressed user", stored, err)
+			}
+
+			repo = user.NewMemoryRepository()
+			if err := repo.Create(&user.User{Email: "ada@example.com", FirstName: "Ada", LastName: "King"}); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+			updated, err := UpdateUser(events.APIGatewayProxyRequest{HTTPMethod: http.MethodPut,
+				Path: "/users/ada%40example.com", PathParameters: map[string]string{"email": "ada@example.com"},
+				Body: tt.body, IsBase64Encoded: tt.encoded, Headers: headers}, repo, nil)
+			if err != nil {
+				t.Fatalf("UpdateUser() error = %v", err)
+			}
+			if updated.StatusCode != http.StatusOK {
+				t.Errorf("UpdateUser() status = %d, want 200: %s", updated.StatusCode, updated.Body)
+			}
+			if stored, err := repo.Get("ada@example.com", user.ReadOptions{}); err != nil || stored.LastName != "Lovelace" {
+				t.Errorf("stored = %+v, %v; want the decompressed update", stored, err)
+			}
+		})
+	}
+}
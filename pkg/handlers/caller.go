@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/auth"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Error messages for caller authorization failures
+var (
+	ErrorMissingCaller   = "request carries no caller identity"
+	ErrorMalformedClaims = "malformed authorizer claims"
+	ErrorNotOwnUser      = "callers may only access their own user"
+	ErrorAdminRequired   = "this operation requires the admin scope"
+)
+
+// defaultAdminGroup is the Cognito group whose members may operate on any user
+const defaultAdminGroup = "admins"
+
+// Caller is the identity of the caller as asserted by the API Gateway authorizer
+type Caller struct {
+	Subject string   // The "sub" claim
+	Email   string   // The "email" claim
+	Groups  []string // The "cognito:groups" claim
+}
+
+// IsAdmin reports whether the caller belongs to the admin group
+// (ADMIN_GROUP, "admins" by default).
+func (c *Caller) IsAdmin() bool {
+	adminGroup := os.Getenv("ADMIN_GROUP")
+	if adminGroup == "" {
+		adminGroup = defaultAdminGroup
+	}
+	for _, group := range c.Groups {
+		if group == adminGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// CallerFromRequest extracts the caller from the authorizer claims in the request context.
+//
+// Cognito authorizers deliver claims as strings, with cognito:groups flattened to e.g.
+// "admins,editors" (REST APIs) or "[admins editors]" (HTTP APIs); both forms and JSON arrays
+// are accepted.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the authorizer context.
+//
+// Returns:
+// - The caller, or nil if the request carries no claims.
+// - An error if the claims are present but malformed.
+func CallerFromRequest(req events.APIGatewayProxyRequest) (*Caller, error) {
+	raw, ok := req.RequestContext.Authorizer["claims"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	claims, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(ErrorMalformedClaims)
+	}
+
+	caller := &Caller{}
+	for key, target := range map[string]*string{"sub": &caller.Subject, "email": &caller.Email} {
+		if value, present := claims[key]; present {
+			str, ok := value.(string)
+			if !ok {
+				return nil, errors.New(ErrorMalformedClaims)
+			}
+			*target = str
+		}
+	}
+
+	switch groups := claims["cognito:groups"].(type) {
+	case nil:
+	case string:
+		caller.Groups = strings.FieldsFunc(strings.Trim(groups, "[]"), func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	case []interface{}:
+		for _, group := range groups {
+			name, ok := group.(string)
+			if !ok {
+				return nil, errors.New(ErrorMalformedClaims)
+			}
+			caller.Groups = append(caller.Groups, name)
+		}
+	default:
+		return nil, errors.New(ErrorMalformedClaims)
+	}
+
+	if caller.Subject == "" && caller.Email == "" {
+		return nil, errors.New(ErrorMalformedClaims)
+	}
+	return caller, nil
+}
+
+// requireSelfOrAdmin checks that the caller is the user with the given email or an
+// administrator. It guards every operation on a single user, and applies whenever
+// authentication is configured: API keys, bearer tokens or ENFORCE_CALLER_ACCESS.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the authorizer context.
+// - email: The email of the targeted user.
+//
+// Returns:
+// - A 401 or 403 response if access is denied, or nil if it is allowed.
+func requireSelfOrAdmin(req events.APIGatewayProxyRequest, email string) *events.APIGatewayProxyResponse {
+	if !authConfigured() {
+		return nil
+	}
+	return checkSelfOrAdmin(req, email)
+}
+
+// checkSelfOrAdmin lets administrators and the user with the given email through.
+func checkSelfOrAdmin(req events.APIGatewayProxyRequest, email string) *events.APIGatewayProxyResponse {
+	caller, denied := authenticatedCaller(req)
+	if denied != nil {
+		return denied
+	}
+	if isAdmin(req, caller) {
+		return nil
+	}
+	if caller.Email == "" || validators.NormalizeEmail(caller.Email) != validators.NormalizeEmail(email) {
+		resp, _ := APIError(http.StatusForbidden, CodeForbidden, ErrorNotOwnUser)
+		return resp
+	}
+	return nil
+}
+
+// bodyEmail extracts the email field from a JSON request body without validating the rest.
+//
+// Parameters:
+// - body: The decoded request body.
+//
+// Returns:
+// - The email in the body, or an empty string if there is none.
+func bodyEmail(body string) string {
+	var partial struct {
+		Email string `json:"email"`
+	}
+	_ = json.Unmarshal([]byte(body), &partial)
+	return partial.Email
+}
+
+// requireAdmin checks that the caller is an administrator: either a token or API key with
+// the admin scope, or a member of the admin group. The check only applies when
+// authentication is configured.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the authorizer context.
+//
+// Returns:
+// - A 401 or 403 response if the caller is not an administrator, or nil if it is.
+func requireAdmin(req events.APIGatewayProxyRequest) *events.APIGatewayProxyResponse {
+	if !authConfigured() {
+		return nil
+	}
+
+	caller, denied := authenticatedCaller(req)
+	if denied != nil {
+		return denied
+	}
+	if !isAdmin(req, caller) {
+		resp, _ := APIError(http.StatusForbidden, CodeForbidden, ErrorAdminRequired)
+		return resp
+	}
+	return nil
+}
+
+// callerIsAdmin reports whether the request comes from an authenticated administrator.
+// Unlike requireAdmin, it is false when authentication isn't configured, so anonymous
+// callers of an open deployment can't act as administrators.
+func callerIsAdmin(req events.APIGatewayProxyRequest) bool {
+	caller, err := CallerFromRequest(req)
+	return err == nil && caller != nil && isAdmin(req, caller)
+}
+
+// authConfigured reports whether requests carry an authenticated caller: API keys or
+// bearer tokens are configured, or an upstream authorizer is trusted through
+// ENFORCE_CALLER_ACCESS.
+func authConfigured() bool {
+	return jwtEnabled() || auth.APIKeysEnabled() || os.Getenv("ENFORCE_CALLER_ACCESS") == "true"
+}
+
+// authenticatedCaller returns the caller of a request, or a 401 response if the request
+// carries no (or malformed) claims.
+func authenticatedCaller(req events.APIGatewayProxyRequest) (*Caller, *events.APIGatewayProxyResponse) {
+	caller, err := CallerFromRequest(req)
+	if err != nil {
+		resp, _ := APIError(http.StatusUnauthorized, CodeUnauthorized, err.Error())
+		return nil, resp
+	}
+	if caller == nil {
+		resp, _ := APIError(http.StatusUnauthorized, CodeUnauthorized, ErrorMissingCaller)
+		return nil, resp
+	}
+	return caller, nil
+}
+
+// isAdmin reports whether the caller holds the admin scope or belongs to the admin group.
+func isAdmin(req events.APIGatewayProxyRequest, caller *Caller) bool {
+	claims, _ := req.RequestContext.Authorizer["claims"].(map[string]interface{})
+	scope, _ := claims["scope"].(string)
+	for _, granted := range strings.Fields(scope) {
+		if granted == auth.ScopeAdmin {
+			return true
+		}
+	}
+	return caller.IsAdmin()
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// withAuthorizer returns a request carrying the given authorizer context.
+func withAuthorizer(authorizer map[string]interface{}) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer}}
+}
+
+func TestCallerFromRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		authorizer map[string]interface{}
+		want       *Caller
+		wantErr    bool
+	}{
+		{name: "no authorizer"},
+		{name: "no claims", authorizer: map[string]interface{}{"principalId": "user"}},
+		{name: "claims", authorizer: map[string]interface{}{"claims": map[string]interface{}{
+			"sub": "123", "email": "ada@example.com"}},
+			want: &Caller{Subject: "123", Email: "ada@example.com"}},
+		{name: "REST groups", authorizer: map[string]interface{}{"claims": map[string]interface{}{
+			"sub": "123", "cognito:groups": "admins,editors"}},
+			want: &Caller{Subject: "123", Groups: []string{"admins", "editors"}}},
+		{name: "HTTP API groups", authorizer: map[string]interface{}{"claims": map[string]interface{}{
+			"sub": "123", "cognito:groups": "[admins editors]"}},
+			want: &Caller{Subject: "123", Groups: []string{"admins", "editors"}}},
+		{name: "JSON groups", authorizer: map[string]interface{}{"claims": map[string]interface{}{
+			"sub": "123", "cognito:groups": []interface{}{"admins"}}},
+			want: &Caller{Subject: "123", Groups: []string{"admins"}}},
+		{name: "claims not an object", authorizer: map[string]interface{}{"claims": "sub=123"}, wantErr: true},
+		{name: "sub not a string", authorizer: map[string]interface{}{"claims": map[string]interface{}{"sub": 123}},
+			wantErr: true},
+		{name: "group not a string", authorizer: map[string]interface{}{"claims": map[string]interface{}{
+			"sub": "123", "cognito:groups": []interface{}{1}}}, wantErr: true},
+		{name: "groups not a list", authorizer: map[string]interface{}{"claims": map[string]interface{}{
+			"sub": "123", "cognito:groups": 1}}, wantErr: true},
+		{name: "no identity", authorizer: map[string]interface{}{"claims": map[string]interface{}{"scope": "users/read"}},
+			wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CallerFromRequest(withAuthorizer(tt.authorizer))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CallerFromRequest() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CallerFromRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireSelfOrAdmin(t *testing.T) {
+	claims := func(values map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"claims": values}
+	}
+	tests := []struct {
+		name       string
+		enforce    bool
+		jwt        bool // Bearer tokens configured, without ENFORCE_CALLER_ACCESS
+		authorizer map[string]interface{}
+		wantStatus int
+	}{
+		{name: "not enforced", authorizer: nil},
+		{name: "tokens, other user", jwt: true, authorizer: claims(map[string]interface{}{"sub": "2",
+			"email": "bob@example.com", "scope": "read write"}), wantStatus: http.StatusForbidden},
+		{name: "tokens, self", jwt: true, authorizer: claims(map[string]interface{}{"sub": "1",
+			"email": "ada@example.com", "scope": "read write"})},
+		{name: "tokens, missing authorizer", jwt: true, wantStatus: http.StatusUnauthorized},
+		{name: "missing authorizer", enforce: true, wantStatus: http.StatusUnauthorized},
+		{name: "malformed claims", enforce: true, authorizer: claims(map[string]interface{}{"sub": 1}),
+			wantStatus: http.StatusUnauthorized},
+		{name: "self", enforce: true, authorizer: claims(map[string]interface{}{"sub": "1", "email": "ada@example.com"})},
+		{name: "self in another case", enforce: true, authorizer: claims(map[string]interface{}{"sub": "1", "email": "Ada@Example.com"})},
+		{name: "other user", enforce: true, authorizer: claims(map[string]interface{}{"sub": "2", "email": "bob@example.com"}),
+			wantStatus: http.StatusForbidden},
+		{name: "no email claim", enforce: true, authorizer: claims(map[string]interface{}{"sub": "2"}),
+			wantStatus: http.StatusForbidden},
+		{name: "admin group", enforce: true, authorizer: claims(map[string]interface{}{"sub": "2", "email": "bob@example.com",
+			"cognito:groups": "admins"})},
+		{name: "admin scope", enforce: true, authorizer: claims(map[string]interface{}{"sub": "2", "scope": "read admin"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.enforce {
+				t.Setenv("ENFORCE_CALLER_ACCESS", "true")
+			}
+			if tt.jwt {
+				t.Setenv("JWT_SECRET", "secret")
+			}
+			resp := requireSelfOrAdmin(withAuthorizer(tt.authorizer), "ada@example.com")
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			if status != tt.wantStatus {
+				t.Errorf("requireSelfOrAdmin() = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
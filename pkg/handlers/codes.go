@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// ErrorCodeInfo describes an error code for client generators
+type ErrorCodeInfo struct {
+	Code   ErrorCode `json:"code"`   // The code sent in error responses
+	Status int       `json:"status"` // The HTTP status the code is usually sent with
+}
+
+// errorCodeStatuses lists every error code with its default HTTP status, in the order the
+// codes are declared
+var errorCodeStatuses = []ErrorCodeInfo{
+	{CodeInvalidRequest, http.StatusBadRequest},
+	{CodeUnauthorized, http.StatusUnauthorized},
+	{CodeForbidden, http.StatusForbidden},
+	{CodeValidation, http.StatusBadRequest},
+	{CodeNotFound, http.StatusNotFound},
+	{CodeConflict, http.StatusConflict},
+	{CodePreconditionFailed, http.StatusPreconditionFailed},
+	{CodePreconditionRequired, http.StatusPreconditionRequired},
+	{CodeUnprocessable, http.StatusUnprocessableEntity},
+	{CodePayloadTooLarge, http.StatusRequestEntityTooLarge},
+	{CodeUnsupportedMediaType, http.StatusUnsupportedMediaType},
+	{CodeNotAcceptable, http.StatusNotAcceptable},
+	{CodeGone, http.StatusGone},
+	{CodeTooManyRequests, http.StatusTooManyRequests},
+	{CodeMethodNotAllowed, http.StatusMethodNotAllowed},
+	{CodeStorage, http.StatusBadGateway},
+	{CodeServiceUnavailable, http.StatusServiceUnavailable},
+	{CodeInternal, http.StatusInternalServerError},
+
+	{CodeUserExists, http.StatusConflict},
+	{CodeUserNotFound, http.StatusNotFound},
+	{CodeUserDeleted, http.StatusConflict},
+	{CodeUserNotDeleted, http.StatusConflict},
+	{CodeVersionMismatch, http.StatusPreconditionFailed},
+	{CodeInvalidUserData, http.StatusBadRequest},
+	{CodeInvalidEmail, http.StatusBadRequest},
+	{CodeInvalidFirstName, http.StatusBadRequest},
+	{CodeInvalidLastName, http.StatusBadRequest},
+	{CodeEmptyBody, http.StatusBadRequest},
+	{CodeMalformedJSON, http.StatusBadRequest},
+	{CodeUnknownField, http.StatusBadRequest},
+	{CodeInvalidFieldType, http.StatusBadRequest},
+	{CodeMissingField, http.StatusBadRequest},
+	{CodeIdempotencyKeyReused, http.StatusUnprocessableEntity},
+	{CodeIdempotencyKeyInProgress, http.StatusConflict},
+	{CodeEmptyBatch, http.StatusBadRequest},
+	{CodeBatchTooLarge, http.StatusRequestEntityTooLarge},
+	{CodeDuplicateInBatch, http.StatusBadRequest},
+	{CodeTooManyEmails, http.StatusRequestEntityTooLarge},
+	{CodeInvalidSort, http.StatusBadRequest},
+	{CodeInvalidOrder, http.StatusBadRequest},
+	{CodeEmptyImport, http.StatusBadRequest},
+	{CodeImportTooLarge, http.StatusRequestEntityTooLarge},
+	{CodeMissingCSVColumn, http.StatusBadRequest},
+	{CodeDuplicateCSVColumn, http.StatusBadRequest},
+	{CodeMalformedCSV, http.StatusBadRequest},
+	{CodeInvalidOnConflict, http.StatusBadRequest},
+	{CodeInvalidExpiresAt, http.StatusBadRequest},
+	{CodeExpiryInPast, http.StatusBadRequest},
+	{CodeExpiryTooFar, http.StatusBadRequest},
+	{CodeInvalidTTLDays, http.StatusBadRequest},
+	{CodeTTLAndExpiresAt, http.StatusBadRequest},
+	{CodeInvalidAddress, http.StatusBadRequest},
+	{CodeInvalidCountry, http.StatusBadRequest},
+	{CodeMissingPostalCode, http.StatusBadRequest},
+	{CodeTooManyTags, http.StatusBadRequest},
+	{CodeInvalidTagKey, http.StatusBadRequest},
+	{CodeReservedTagKey, http.StatusBadRequest},
+	{CodeTagValueTooLong, http.StatusBadRequest},
+	{CodeInvalidStatus, http.StatusBadRequest},
+	{CodeStatusUnchanged, http.StatusConflict},
+	{CodeUserSuspended, http.StatusForbidden},
+	{CodeEmailImmutable, http.StatusBadRequest},
+	{CodeEmailUnchanged, http.StatusBadRequest},
+	{CodeInvalidDomain, http.StatusBadRequest},
+	{CodeTooManyToDelete, http.StatusRequestEntityTooLarge},
+	{CodeInvalidOlderThan, http.StatusBadRequest},
+	{CodePrefixTooShort, http.StatusBadRequest},
+	{CodeInvalidPassword, http.StatusBadRequest},
+	{CodePasswordImmutable, http.StatusBadRequest},
+	{CodeInvalidCredentials, http.StatusUnauthorized},
+	{CodeInvalidVerifyToken, http.StatusGone},
+	{CodeVerifyTokenExpired, http.StatusBadRequest},
+	{CodeAlreadyVerified, http.StatusConflict},
+	{CodeTooManyVerifySends, http.StatusTooManyRequests},
+	{CodeInvalidRole, http.StatusBadRequest},
+	{CodeRoleChangeForbidden, http.StatusForbidden},
+	{CodeDisposableEmail, http.StatusBadRequest},
+	{CodeDomainRejectsMail, http.StatusBadRequest},
+	{CodeDomainNotAllowed, http.StatusBadRequest},
+	{CodeValidationFailed, http.StatusBadRequest},
+	{CodeEncryptedFieldFilter, http.StatusBadRequest},
+	{CodeMergeIntoSelf, http.StatusBadRequest},
+	{CodeInvalidSlug, http.StatusBadRequest},
+	{CodeInvalidOrgName, http.StatusBadRequest},
+	{CodeOrgExists, http.StatusConflict},
+	{CodeOrgNotFound, http.StatusNotFound},
+	{CodeAlreadyMember, http.StatusConflict},
+	{CodeNotMember, http.StatusNotFound},
+}
+
+// AllErrorCodes returns every error code the API sends, with the HTTP status each is
+// usually sent with, for client generators. The general codes come first, then the specific
+// codes of enveloped (v2) responses.
+//
+// Returns:
+// - A copy of the codes, in declaration order.
+func AllErrorCodes() []ErrorCodeInfo {
+	return append([]ErrorCodeInfo(nil), errorCodeStatuses...)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAllErrorCodes(t *testing.T) {
+	codes := AllErrorCodes()
+	seen := map[ErrorCode]bool{}
+	for _, info := range codes {
+		if info.Code == "" || info.Status < http.StatusBadRequest {
+			t.Errorf("%+v isn't an error code with an error status", info)
+		}
+		if seen[info.Code] {
+			t.Errorf("%s is listed twice", info.Code)
+		}
+		seen[info.Code] = true
+	}
+
+	codes[0].Code = "changed"
+	if AllErrorCodes()[0].Code == "changed" {
+		t.Error("AllErrorCodes() returned the package's own slice")
+	}
+}
+
+// TestErrorResponsesHaveCodes sends invalid requests to every operation of the route table,
+// and fails on any error response without a documented code.
+func TestErrorResponsesHaveCodes(t *testing.T) {
+	documented := map[string]bool{}
+	for _, info := range AllErrorCodes() {
+		documented[string(info.Code)] = true
+	}
+	requests := []struct {
+		name    string
+		body    string
+		query   map[string]string
+		headers map[string]string
+	}{
+		{name: "empty body"},
+		{name: "malformed body", body: `{"email": `},
+		{name: "wrong types", body: `{"email": 1, "firstname": [], "lastname": {}, "slug": 2, "role": 3}`},
+		{name: "invalid query", body: `{}`, query: map[string]string{"limit": "-1", "cursor": "!", "sort": "shoe",
+			"order": "sideways", "fields": "nope", "prefix": "a", "olderThan": "soon", "onConflict": "maybe"}},
+		{name: "unsupported content type", body: `{}`, headers: map[string]string{"Content-Type": "text/plain"}},
+		{name: "unsupported encoding", body: `{}`, headers: map[string]string{"Content-Encoding": "br"}},
+		{name: "stale version", body: `{}`, headers: map[string]string{"If-Match": `"99"`}},
+	}
+	params := map[string]string{"email": "ada%40example.com", "slug": "no-such-org"}
+
+	for _, route := range Routes() {
+		for method, operation := range route.Methods {
+			if operation.Handler == nil {
+				continue
+			}
+			path := route.Template
+			for name, value := range params {
+				path = strings.ReplaceAll(path, "{"+name+"}", value)
+			}
+			for _, version := range []APIVersion{V1, V2} {
+				for _, tt := range requests {
+					t.Run(method+" "+route.Template+" v"+strconv.Itoa(int(version))+" "+tt.name, func(t *testing.T) {
+						repo := user.NewMemoryRepository()
+						if err := repo.Create(&user.User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace",
+							Version: 1}); err != nil {
+							t.Fatalf("seeding: %v", err)
+						}
+						req := events.APIGatewayProxyRequest{HTTPMethod: method, Path: path, Body: tt.body,
+							QueryStringParameters: tt.query,
+							Headers:               map[string]string{"Content-Type": MediaTypeJSON, versionHeader: strconv.Itoa(int(version))}}
+						for name, value := range tt.headers {
+							req.Headers[name] = value
+						}
+						_, req = MatchRoute(req)
+
+						resp, err := operation.Handler(req, repo, mocks.NewFakeDynamo())
+						if err != nil || resp == nil || resp.StatusCode < http.StatusBadRequest {
+							return
+						}
+						var body ErrorBody
+						if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+							t.Fatalf("%d body %s isn't an error: %v", resp.StatusCode, resp.Body, err)
+						}
+						if body.Code == nil || !documented[*body.Code] {
+							t.Errorf("%d error %s has no code listed in AllErrorCodes", resp.StatusCode, resp.Body)
+						}
+					})
+				}
+			}
+		}
+	}
+}
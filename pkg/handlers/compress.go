@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/aws/aws-lambda-go/events"
+	"strconv"
+	"strings"
+)
+
+// gzipMinBytes is the body size below which compression isn't worth the CPU time
+const gzipMinBytes = 1024
+
+// acceptsGzip reports whether the Accept-Encoding header allows a gzip response.
+//
+// Parameters:
+// - acceptEncoding: The value of the Accept-Encoding header.
+//
+// Returns:
+// - True if gzip (or "*") is listed without a zero quality value.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		// An explicit q=0 means the coding is not acceptable
+		rejected := false
+		for _, param := range fields[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || !strings.EqualFold(kv[0], "q") {
+				continue
+			}
+			if q, err := strconv.ParseFloat(kv[1], 64); err == nil && q == 0 {
+				rejected = true
+			}
+		}
+		if !rejected {
+			return true
+		}
+	}
+	return false
+}
+
+// addVary appends a header name to the Vary header of a response.
+//
+// Parameters:
+// - resp: APIGatewayProxyResponse to update.
+// - name: The request header the response depends on.
+func addVary(resp *events.APIGatewayProxyResponse, name string) {
+	if existing := resp.Headers["Vary"]; existing != "" {
+		resp.Headers["Vary"] = existing + ", " + name
+		return
+	}
+	resp.Headers["Vary"] = name
+}
+
+// CompressResponse gzips the response body when the client accepts gzip and the body is
+// larger than gzipMinBytes. The compressed bytes are base64-encoded as API Gateway requires
+// for binary bodies. Small bodies and clients without gzip support get the body unchanged.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the Accept-Encoding header.
+// - resp: APIGatewayProxyResponse to compress in place.
+func CompressResponse(req events.APIGatewayProxyRequest, resp *events.APIGatewayProxyResponse) {
+	if resp == nil || resp.IsBase64Encoded || len(resp.Body) < gzipMinBytes {
+		return
+	}
+	if resp.Headers == nil {
+		resp.Headers = map[string]string{}
+	}
+	addVary(resp, "Accept-Encoding")
+	if !acceptsGzip(headerValue(req, "Accept-Encoding")) {
+		return
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(resp.Body)); err != nil {
+		logging.Default.Error("failed to gzip response body", logging.Fields{"error": err})
+		return
+	}
+	if err := writer.Close(); err != nil {
+		logging.Default.Error("failed to gzip response body", logging.Fields{"error": err})
+		return
+	}
+
+	resp.Body = base64.StdEncoding.EncodeToString(buf.Bytes())
+	resp.IsBase64Encoded = true
+	resp.Headers["Content-Encoding"] = "gzip"
+}
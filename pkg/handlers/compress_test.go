@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"github.com/aws/aws-lambda-go/events"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCompressResponse(t *testing.T) {
+	large := `{"users":[` + strings.Repeat(`{"email":"ada@example.com","firstname":"Ada"},`, 100) + `{}]}`
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		body           string
+		wantGzip       bool
+		wantVary       bool
+	}{
+		{name: "gzip", acceptEncoding: "gzip", body: large, wantGzip: true, wantVary: true},
+		{name: "gzip among others", acceptEncoding: "br;q=1.0, gzip;q=0.8, deflate", body: large, wantGzip: true, wantVary: true},
+		{name: "wildcard", acceptEncoding: "*", body: large, wantGzip: true, wantVary: true},
+		{name: "uppercase", acceptEncoding: "GZIP", body: large, wantGzip: true, wantVary: true},
+		{name: "refused", acceptEncoding: "gzip;q=0", body: large, wantVary: true},
+		{name: "not accepted", acceptEncoding: "br", body: large, wantVary: true},
+		{name: "no header", body: large, wantVary: true},
+		{name: "small body", acceptEncoding: "gzip", body: `{"email":"ada@example.com"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayProxyRequest{Headers: map[string]string{"Accept-Encoding": tt.acceptEncoding}}
+			resp := &events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: tt.body,
+				Headers: map[string]string{"Content-Type": "application/json"}}
+
+			CompressResponse(req, resp)
+			if (resp.Headers["Vary"] == "Accept-Encoding") != tt.wantVary {
+				t.Errorf("Vary = %q, want Accept-Encoding %v", resp.Headers["Vary"], tt.wantVary)
+			}
+			if !tt.wantGzip {
+				if resp.Body != tt.body || resp.IsBase64Encoded || resp.Headers["Content-Encoding"] != "" {
+					t.Errorf("response = %+v, want the body unchanged", resp)
+				}
+				return
+			}
+			if !resp.IsBase64Encoded || resp.Headers["Content-Encoding"] != "gzip" {
+				t.Fatalf("response = base64 %v, Content-Encoding %q; want a gzipped body", resp.IsBase64Encoded,
+					resp.Headers["Content-Encoding"])
+			}
+			if got := decompress(t, resp.Body); got != tt.body {
+				t.Errorf("decompressed body = %q, want %q", got, tt.body)
+			}
+			if len(resp.Body) >= len(tt.body) {
+				t.Errorf("compressed body is %d bytes, want less than %d", len(resp.Body), len(tt.body))
+			}
+		})
+	}
+}
+
+// decompress decodes and gunzips a compressed response body.
+func decompress(t *testing.T, body string) string {
+	t.Helper()
+	compressed, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		t.Fatalf("decoding base64: %v", err)
+	}
+	reader, err := gzip.NewReader(strings.NewReader(string(compressed)))
+	if err != nil {
+		t.Fatalf("reading gzip: %v", err)
+	}
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("reading gzip: %v", err)
+	}
+	return string(decompressed)
+}
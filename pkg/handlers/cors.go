@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// corsAllowedHeaders are the request headers advertised to browsers
+const corsAllowedHeaders = "Content-Type, Content-Encoding, Authorization, If-Match, If-None-Match, X-Request-Id"
+
+// corsExposedHeaders are the response headers scripts are allowed to read
+var corsExposedHeaders = strings.Join([]string{
+	RequestIDHeader, TotalCountHeader, NextCursorHeader, PageSizeHeader, TruncatedHeader,
+}, ", ")
+
+// allowedOrigin decides which value to send in Access-Control-Allow-Origin.
+// The allowlist is read from the comma-separated ALLOWED_ORIGINS environment variable,
+// where "*" allows any origin.
+//
+// Parameters:
+// - origin: The Origin header sent by the browser.
+//
+// Returns:
+// - The value for Access-Control-Allow-Origin, or an empty string if the origin is not allowed.
+func allowedOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, allowed := range strings.Split(os.Getenv("ALLOWED_ORIGINS"), ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" {
+			return "*"
+		}
+		if allowed != "" && strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// AddCORSHeaders adds the CORS headers to a response when the request's origin is allowed.
+// Requests from origins outside the allowlist are still served, just without CORS headers.
+// The allowed methods are those of the route, as in the Allow header.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the Origin header.
+// - resp: APIGatewayProxyResponse to decorate.
+func AddCORSHeaders(req events.APIGatewayProxyRequest, resp *events.APIGatewayProxyResponse) {
+	if resp == nil {
+		return
+	}
+	origin := allowedOrigin(headerValue(req, "Origin"))
+	if origin == "" {
+		return
+	}
+	if resp.Headers == nil {
+		resp.Headers = map[string]string{}
+	}
+	resp.Headers["Access-Control-Allow-Origin"] = origin
+	resp.Headers["Access-Control-Allow-Methods"] = strings.Join(AllowedMethods(req), ", ")
+	resp.Headers["Access-Control-Allow-Headers"] = corsAllowedHeaders
+	// Let scripts read the request ID to quote it when reporting an error, and the paging
+	// headers of lists
+	resp.Headers["Access-Control-Expose-Headers"] = corsExposedHeaders
+	if origin != "*" {
+		// The response depends on the Origin header, so caches must key on it
+		addVary(resp, "Origin")
+	}
+}
+
+// Preflight handles OPTIONS requests, sent by browsers before cross-origin calls and by
+// clients discovering what a route supports. The CORS headers themselves are added by
+// AddCORSHeaders.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the route.
+//
+// Returns:
+// - APIGatewayProxyResponse with a 204 status, the route's methods in Allow, and no body.
+func Preflight(req events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	return &events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+		Headers:    map[string]string{"Allow": strings.Join(AllowedMethods(req), ", ")},
+	}, nil
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"testing"
+)
+
+func TestAddCORSHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowed    string
+		origin     string
+		wantOrigin string
+		wantVary   bool
+	}{
+		{name: "wildcard", allowed: "*", origin: "https://app.example.com", wantOrigin: "*"},
+		{name: "exact match", allowed: "https://admin.example.com, https://app.example.com",
+			origin: "https://app.example.com", wantOrigin: "https://app.example.com", wantVary: true},
+		{name: "case-insensitive match", allowed: "https://app.example.com", origin: "https://APP.example.com",
+			wantOrigin: "https://APP.example.com", wantVary: true},
+		{name: "disallowed", allowed: "https://app.example.com", origin: "https://evil.example.com"},
+		{name: "near miss", allowed: "https://app.example.com", origin: "https://app.example.com.evil.net"},
+		{name: "no allowlist", origin: "https://app.example.com"},
+		{name: "no origin", allowed: "*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOWED_ORIGINS", tt.allowed)
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users/ada%40example.com",
+				Headers: map[string]string{"Origin": tt.origin}}
+			resp := &events.APIGatewayProxyResponse{StatusCode: http.StatusOK}
+
+			AddCORSHeaders(req, resp)
+			if got := resp.Headers["Access-Control-Allow-Origin"]; got != tt.wantOrigin {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if tt.wantOrigin == "" {
+				if len(resp.Headers) > 0 {
+					t.Errorf("headers = %v, want no CORS headers", resp.Headers)
+				}
+				return
+			}
+			if resp.Headers["Access-Control-Allow-Methods"] == "" || resp.Headers["Access-Control-Expose-Headers"] == "" {
+				t.Errorf("headers = %v, want the allowed methods and exposed headers", resp.Headers)
+			}
+			if vary := resp.Headers["Vary"] == "Origin"; vary != tt.wantVary {
+				t.Errorf("Vary = %q, want Origin %v", resp.Headers["Vary"], tt.wantVary)
+			}
+		})
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	t.Setenv("ALLOWED_ORIGINS", "https://app.example.com")
+	req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodOptions, Path: "/users/ada%40example.com",
+		Headers: map[string]string{"Origin": "https://app.example.com"}}
+
+	resp, err := Preflight(req)
+	if err != nil {
+		t.Fatalf("Preflight() error = %v", err)
+	}
+	AddCORSHeaders(req, resp)
+	if resp.StatusCode != http.StatusNoContent || resp.Body != "" {
+		t.Errorf("Preflight() = %d %q, want 204 without a body", resp.StatusCode, resp.Body)
+	}
+	if resp.Headers["Allow"] == "" || resp.Headers["Allow"] != resp.Headers["Access-Control-Allow-Methods"] {
+		t.Errorf("Allow = %q, Access-Control-Allow-Methods = %q; want the route's methods in both",
+			resp.Headers["Allow"], resp.Headers["Access-Control-Allow-Methods"])
+	}
+}
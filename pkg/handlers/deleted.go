@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+)
+
+// ListDeletedUsers handles GET /users/deleted, returning only the soft-deleted users in
+// email order, for administrators only. Pages hold "limit" users (100 by default); pass the
+// returned X-Next-Cursor as "cursor" to read the next one. ?fields= trims the users.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest with the paging query parameters.
+// - repo: Repository where user data is stored.
+//
+// Returns:
+// - APIGatewayProxyResponse with the page of deleted users.
+func ListDeletedUsers(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	opts, err := readOptions(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	limit, offset, err := pageParams(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	fields := opts.Fields
+	if len(fields) > 0 {
+		opts.Fields = user.WithFields(fields, "email")
+	}
+
+	page, err := user.ListDeleted(opts, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	user.SortUsers(page.Users, "email", false)
+
+	var nextCursor string
+	if offset > len(page.Users) {
+		offset = len(page.Users)
+	}
+	if end := offset + limit; end < len(page.Users) {
+		page.Users, nextCursor = page.Users[offset:end], pageCursor(end)
+	} else {
+		page.Users = page.Users[offset:]
+	}
+
+	resp, err := APIResponse(http.StatusOK, selectUsers(page.Users, fields))
+	if page.Truncated {
+		resp.Headers[TruncatedHeader] = "true"
+	}
+	if nextCursor != "" {
+		resp.Headers[NextCursorHeader] = nextCursor
+	}
+	return resp, err
+}
+
+// PurgeDeletedUsers handles DELETE /users/deleted?olderThan=30d, removing for good the
+// users soft-deleted longer ago than olderThan: a number of days (d) or hours (h), or an
+// RFC3339 time. The caller must be an administrator when authentication is configured.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest with the olderThan query parameter.
+// - repo: Repository storing the users.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the purged emails and their count, 400 for a malformed
+//     olderThan, or 413 if more users match than MAX_BULK_DELETE allows.
+func PurgeDeletedUsers(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+
+	result, err := user.PurgeDeleted(req.QueryStringParameters["olderThan"], repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	mutations := make([]mutation, len(result.Emails))
+	for i, email := range result.Emails {
+		mutations[i] = mutation{action: "deleted", email: email}
+	}
+	recordMutations(req, dynaClient, mutations...)
+
+	status := http.StatusOK
+	if len(result.Failed) > 0 {
+		status = http.StatusMultiStatus
+	}
+	return APIResponse(status, result)
+}
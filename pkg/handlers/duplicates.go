@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+)
+
+// FindDuplicates handles GET /users/duplicates?firstname=Ada&lastname=Lovelace, listing the
+// users with a similar name, so support can spot an existing account before creating one.
+// The caller must be an administrator when authentication is configured.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest with the firstname and lastname query parameters.
+// - repo: Repository where user data is stored.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the matches and their score, most similar first, or 400
+//     if either name is missing.
+func FindDuplicates(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	result, err := user.FindDuplicates(req.QueryStringParameters["firstname"], req.QueryStringParameters["lastname"], repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	resp, err := APIResponse(http.StatusOK, result)
+	if result.Truncated {
+		resp.Headers[TruncatedHeader] = "true"
+	}
+	return resp, err
+}
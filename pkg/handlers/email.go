@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+)
+
+// ChangeEmail handles POST /users/{email}/change-email, moving the user to the email in
+// the body ({"email": "new@example.com"}). Like PUT, it honors If-Match.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the user, with the new email in the body.
+// - repo: Repository where the user data is stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the moved user and its new Location, 404 if it doesn't
+//     exist, 409 if the new email is taken, or 412 on a stale If-Match.
+func ChangeEmail(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	fields, err := responseFields(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+	req, invalid := decodeBody(req, maxBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	expectedVersion, _, err := ifMatchVersion(req)
+	if err != nil {
+		return APIError(http.StatusPreconditionFailed, CodePreconditionFailed, err.Error())
+	}
+
+	result, previous, err := user.ChangeEmail(email, req.Body, expectedVersion, updateOptions(req), repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	recordMutations(req, dynaClient, mutation{action: "emailChanged", email: result.Email, before: previous, after: result})
+	resp, err := APIResponse(http.StatusOK, selectUser(result, fields), map[string]string{
+		"Location": userLocation(req, result.Email),
+		"ETag":     versionETag(result.Version),
+	})
+	return resp, err
+}
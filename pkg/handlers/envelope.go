@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"strings"
+)
+
+// MediaTypeV2 is the media type a client accepts to receive enveloped responses
+const MediaTypeV2 = "application/vnd.users.v2+json"
+
+// Envelope is the body of enveloped (v2) responses: successes carry the usual body as
+// data, and failures an error object instead of the flat ErrorBody.
+type Envelope struct {
+	Data  json.RawMessage `json:"data,omitempty"`  // The body of a successful response
+	Error *EnvelopeError  `json:"error,omitempty"` // Why the request failed
+	Meta  *EnvelopeMeta   `json:"meta,omitempty"`  // Pagination of a list
+}
+
+// EnvelopeMeta describes the page of a list in an enveloped response
+type EnvelopeMeta struct {
+	NextCursor string `json:"nextCursor,omitempty"` // Cursor of the next page, absent on the last one
+	Truncated  bool   `json:"truncated,omitempty"`  // Whether the list stopped at MAX_LIST_ITEMS
+}
+
+// EnvelopeError describes a failure in an enveloped response
+type EnvelopeError struct {
+	Code      ErrorCode         `json:"code"`                // Machine-readable error code (one of the Code constants)
+	Message   string            `json:"message"`             // Human-readable error message
+	Field     string            `json:"field,omitempty"`     // Offending request field, for validation errors
+	Fields    []user.FieldError `json:"fields,omitempty"`    // Every invalid field, for validation errors
+	RequestID string            `json:"requestId,omitempty"` // ID of the failed request, to quote when reporting it
+}
+
+// statusCodes gives the error code of failures whose body carries none
+var statusCodes = map[int]ErrorCode{
+	http.StatusBadRequest:            CodeInvalidRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusNotAcceptable:         CodeNotAcceptable,
+	http.StatusConflict:              CodeConflict,
+	http.StatusPreconditionFailed:    CodePreconditionFailed,
+	http.StatusGone:                  CodeGone,
+	http.StatusTooManyRequests:       CodeTooManyRequests,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusUnsupportedMediaType:  CodeUnsupportedMediaType,
+	http.StatusUnprocessableEntity:   CodeUnprocessable,
+	http.StatusPreconditionRequired:  CodePreconditionRequired,
+	http.StatusBadGateway:            CodeStorage,
+	http.StatusServiceUnavailable:    CodeServiceUnavailable,
+}
+
+// WantsEnvelope reports whether a request gets enveloped responses, i.e. targets v2.
+func WantsEnvelope(req events.APIGatewayProxyRequest) bool {
+	return RequestVersion(req) == V2
+}
+
+// EnvelopeResponse wraps a JSON response in an Envelope when the request targets v2,
+// copying the pagination headers of lists into its meta.
+// Health checks keep their flat body for monitors, the OpenAPI document stays a valid one,
+// and empty or non-JSON responses such as
+// CSV exports are left as they are. It must run before the response is compressed.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest the response answers.
+// - resp: The response to wrap, modified in place.
+func EnvelopeResponse(req events.APIGatewayProxyRequest, resp *events.APIGatewayProxyResponse) {
+	if resp == nil || resp.Body == "" || !WantsEnvelope(req) || IsHealthCheck(req) || isOpenAPIRequest(req) ||
+		resp.IsBase64Encoded ||
+		!strings.HasPrefix(resp.Headers["Content-Type"], "application/json") {
+		return
+	}
+
+	envelope := Envelope{Data: json.RawMessage(resp.Body)}
+	if cursor, truncated := resp.Headers[NextCursorHeader], resp.Headers[TruncatedHeader] == "true"; cursor != "" || truncated {
+		envelope.Meta = &EnvelopeMeta{NextCursor: cursor, Truncated: truncated}
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		envelope = Envelope{Error: envelopeError(resp)}
+	}
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		logging.Default.Error("failed to marshal response envelope", logging.Fields{"status": resp.StatusCode, "error": err})
+		return
+	}
+	resp.Body = string(body)
+}
+
+// envelopeError reads the error of a failed response. Bodies that aren't an ErrorBody get
+// the code matching the status.
+func envelopeError(resp *events.APIGatewayProxyResponse) *EnvelopeError {
+	var body ErrorBody
+	if err := json.Unmarshal([]byte(resp.Body), &body); err == nil && body.Code != nil {
+		out := &EnvelopeError{Code: ErrorCode(*body.Code)}
+		if body.ErrorMsg != nil {
+			out.Message = *body.ErrorMsg
+		}
+		if body.Field != nil {
+			out.Field = *body.Field
+		}
+		out.Fields = body.Fields
+		if body.RequestID != nil {
+			out.RequestID = *body.RequestID
+		}
+		return out
+	}
+
+	out := &EnvelopeError{Code: CodeInternal, Message: http.StatusText(resp.StatusCode), RequestID: resp.Headers[RequestIDHeader]}
+	if code, ok := statusCodes[resp.StatusCode]; ok {
+		out.Code = code
+	}
+	var message string
+	if err := json.Unmarshal([]byte(resp.Body), &message); err == nil && message != "" {
+		out.Message = message
+	}
+	return out
+}
@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/org"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"net/http"
+	"strings"
+)
+
+// ErrorInternal is the generic message returned when the failure cause must not be exposed
+var ErrorInternal = "internal server error"
+
+// ErrorCode is a machine-readable error code, sent in the "code" field of error responses
+// so clients can tell failures apart without parsing messages
+type ErrorCode string
+
+// Machine-readable error codes sent in the "code" field of error responses. New codes must
+// also be listed with their status in errorCodeStatuses, for AllErrorCodes.
+const (
+	CodeInvalidRequest       ErrorCode = "INVALID_REQUEST"
+	CodeUnauthorized         ErrorCode = "UNAUTHORIZED"
+	CodeForbidden            ErrorCode = "FORBIDDEN"
+	CodeValidation           ErrorCode = "VALIDATION_ERROR"
+	CodeNotFound             ErrorCode = "NOT_FOUND"
+	CodeConflict             ErrorCode = "CONFLICT"
+	CodePreconditionFailed   ErrorCode = "PRECONDITION_FAILED"
+	CodePreconditionRequired ErrorCode = "PRECONDITION_REQUIRED"
+	CodeUnprocessable        ErrorCode = "UNPROCESSABLE_ENTITY"
+	CodePayloadTooLarge      ErrorCode = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	CodeNotAcceptable        ErrorCode = "NOT_ACCEPTABLE"
+	CodeGone                 ErrorCode = "GONE"
+	CodeTooManyRequests      ErrorCode = "TOO_MANY_REQUESTS"
+	CodeMethodNotAllowed     ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeStorage              ErrorCode = "STORAGE_ERROR"
+	CodeServiceUnavailable   ErrorCode = "SERVICE_UNAVAILABLE"
+	CodeInternal             ErrorCode = "INTERNAL_ERROR"
+)
+
+// Specific error codes sent instead of the codes above in enveloped (v2) responses,
+// naming the exact reason a user operation failed
+const (
+	CodeUserExists               ErrorCode = "USER_EXISTS"
+	CodeUserNotFound             ErrorCode = "USER_NOT_FOUND"
+	CodeUserDeleted              ErrorCode = "USER_DELETED"
+	CodeUserNotDeleted           ErrorCode = "USER_NOT_DELETED"
+	CodeVersionMismatch          ErrorCode = "VERSION_MISMATCH"
+	CodeInvalidUserData          ErrorCode = "INVALID_USER_DATA"
+	CodeInvalidEmail             ErrorCode = "INVALID_EMAIL"
+	CodeInvalidFirstName         ErrorCode = "INVALID_FIRSTNAME"
+	CodeInvalidLastName          ErrorCode = "INVALID_LASTNAME"
+	CodeEmptyBody                ErrorCode = "EMPTY_BODY"
+	CodeMalformedJSON            ErrorCode = "MALFORMED_JSON"
+	CodeUnknownField             ErrorCode = "UNKNOWN_FIELD"
+	CodeInvalidFieldType         ErrorCode = "INVALID_FIELD_TYPE"
+	CodeMissingField             ErrorCode = "MISSING_FIELD"
+	CodeIdempotencyKeyReused     ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyKeyInProgress ErrorCode = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	CodeEmptyBatch               ErrorCode = "EMPTY_BATCH"
+	CodeBatchTooLarge            ErrorCode = "BATCH_TOO_LARGE"
+	CodeDuplicateInBatch         ErrorCode = "DUPLICATE_IN_BATCH"
+	CodeTooManyEmails            ErrorCode = "TOO_MANY_EMAILS"
+	CodeInvalidSort              ErrorCode = "INVALID_SORT"
+	CodeInvalidOrder             ErrorCode = "INVALID_ORDER"
+	CodeEmptyImport              ErrorCode = "EMPTY_IMPORT"
+	CodeImportTooLarge           ErrorCode = "IMPORT_TOO_LARGE"
+	CodeMissingCSVColumn         ErrorCode = "MISSING_CSV_COLUMN"
+	CodeDuplicateCSVColumn       ErrorCode = "DUPLICATE_CSV_COLUMN"
+	CodeMalformedCSV             ErrorCode = "MALFORMED_CSV"
+	CodeInvalidOnConflict        ErrorCode = "INVALID_ON_CONFLICT"
+	CodeInvalidExpiresAt         ErrorCode = "INVALID_EXPIRES_AT"
+	CodeExpiryInPast             ErrorCode = "EXPIRY_IN_PAST"
+	CodeExpiryTooFar             ErrorCode = "EXPIRY_TOO_FAR"
+	CodeInvalidTTLDays           ErrorCode = "INVALID_TTL_DAYS"
+	CodeTTLAndExpiresAt          ErrorCode = "TTL_AND_EXPIRES_AT"
+	CodeInvalidAddress           ErrorCode = "INVALID_ADDRESS"
+	CodeInvalidCountry           ErrorCode = "INVALID_COUNTRY"
+	CodeMissingPostalCode        ErrorCode = "MISSING_POSTAL_CODE"
+	CodeTooManyTags              ErrorCode = "TOO_MANY_TAGS"
+	CodeInvalidTagKey            ErrorCode = "INVALID_TAG_KEY"
+	CodeReservedTagKey           ErrorCode = "RESERVED_TAG_KEY"
+	CodeTagValueTooLong          ErrorCode = "TAG_VALUE_TOO_LONG"
+	CodeInvalidStatus            ErrorCode = "INVALID_STATUS"
+	CodeStatusUnchanged          ErrorCode = "STATUS_UNCHANGED"
+	CodeUserSuspended            ErrorCode = "USER_SUSPENDED"
+	CodeEmailImmutable           ErrorCode = "EMAIL_IMMUTABLE"
+	CodeEmailUnchanged           ErrorCode = "EMAIL_UNCHANGED"
+	CodeInvalidDomain            ErrorCode = "INVALID_DOMAIN"
+	CodeTooManyToDelete          ErrorCode = "TOO_MANY_TO_DELETE"
+	CodeInvalidOlderThan         ErrorCode = "INVALID_OLDER_THAN"
+	CodePrefixTooShort           ErrorCode = "PREFIX_TOO_SHORT"
+	CodeInvalidPassword          ErrorCode = "INVALID_PASSWORD"
+	CodePasswordImmutable        ErrorCode = "PASSWORD_IMMUTABLE"
+	CodeInvalidCredentials       ErrorCode = "INVALID_CREDENTIALS"
+	CodeInvalidVerifyToken       ErrorCode = "INVALID_VERIFY_TOKEN"
+	CodeVerifyTokenExpired       ErrorCode = "VERIFY_TOKEN_EXPIRED"
+	CodeAlreadyVerified          ErrorCode = "ALREADY_VERIFIED"
+	CodeTooManyVerifySends       ErrorCode = "TOO_MANY_VERIFY_SENDS"
+	CodeInvalidRole              ErrorCode = "INVALID_ROLE"
+	CodeRoleChangeForbidden      ErrorCode = "ROLE_CHANGE_FORBIDDEN"
+	CodeDisposableEmail          ErrorCode = "DISPOSABLE_EMAIL"
+	CodeDomainRejectsMail        ErrorCode = "DOMAIN_REJECTS_MAIL"
+	CodeDomainNotAllowed         ErrorCode = "EMAIL_DOMAIN_NOT_ALLOWED"
+	CodeValidationFailed         ErrorCode = "VALIDATION_FAILED"
+	CodeEncryptedFieldFilter     ErrorCode = "ENCRYPTED_FIELD_FILTER"
+	CodeMergeIntoSelf            ErrorCode = "MERGE_INTO_SELF"
+	CodeInvalidSlug              ErrorCode = "INVALID_SLUG"
+	CodeInvalidOrgName           ErrorCode = "INVALID_ORG_NAME"
+	CodeOrgExists                ErrorCode = "ORG_EXISTS"
+	CodeOrgNotFound              ErrorCode = "ORG_NOT_FOUND"
+	CodeAlreadyMember            ErrorCode = "ALREADY_MEMBER"
+	CodeNotMember                ErrorCode = "NOT_MEMBER"
+)
+
+// userErrorCodes maps the client-facing messages of the user package to their specific
+// code. Storage and internal failures keep the code of their kind.
+var userErrorCodes = map[string]ErrorCode{
+	user.ErrorUserAlreadyExists:        CodeUserExists,
+	user.ErrorUserDoesNotExist:         CodeUserNotFound,
+	user.ErrorUserDeleted:              CodeUserDeleted,
+	user.ErrorUserNotDeleted:           CodeUserNotDeleted,
+	user.ErrorVersionMismatch:          CodeVersionMismatch,
+	user.ErrorInvalidUserData:          CodeInvalidUserData,
+	user.ErrorInvalidEmail:             CodeInvalidEmail,
+	user.ErrorInvalidFirstName:         CodeInvalidFirstName,
+	user.ErrorInvalidLastName:          CodeInvalidLastName,
+	user.ErrorEmptyBody:                CodeEmptyBody,
+	user.ErrorMalformedJSON:            CodeMalformedJSON,
+	user.ErrorUnknownField:             CodeUnknownField,
+	user.ErrorInvalidFieldType:         CodeInvalidFieldType,
+	user.ErrorMissingField:             CodeMissingField,
+	user.ErrorIdempotencyKeyReused:     CodeIdempotencyKeyReused,
+	user.ErrorIdempotencyKeyInProgress: CodeIdempotencyKeyInProgress,
+	user.ErrorEmptyBatch:               CodeEmptyBatch,
+	user.ErrorBatchTooLarge:            CodeBatchTooLarge,
+	user.ErrorDuplicateInBatch:         CodeDuplicateInBatch,
+	user.ErrorTooManyEmails:            CodeTooManyEmails,
+	user.ErrorInvalidSort:              CodeInvalidSort,
+	user.ErrorInvalidOrder:             CodeInvalidOrder,
+	user.ErrorEmptyImport:              CodeEmptyImport,
+	user.ErrorImportTooLarge:           CodeImportTooLarge,
+	user.ErrorMissingCSVColumn:         CodeMissingCSVColumn,
+	user.ErrorDuplicateColumn:          CodeDuplicateCSVColumn,
+	user.ErrorMalformedCSV:             CodeMalformedCSV,
+	user.ErrorInvalidOnConflict:        CodeInvalidOnConflict,
+	user.ErrorInvalidExpiresAt:         CodeInvalidExpiresAt,
+	user.ErrorExpiryInPast:             CodeExpiryInPast,
+	user.ErrorExpiryTooFar:             CodeExpiryTooFar,
+	user.ErrorInvalidTTLDays:           CodeInvalidTTLDays,
+	user.ErrorTTLAndExpiresAt:          CodeTTLAndExpiresAt,
+	user.ErrorInvalidAddressField:      CodeInvalidAddress,
+	user.ErrorInvalidCountry:           CodeInvalidCountry,
+	user.ErrorMissingPostalCode:        CodeMissingPostalCode,
+	user.ErrorTooManyTags:              CodeTooManyTags,
+	user.ErrorInvalidTagKey:            CodeInvalidTagKey,
+	user.ErrorReservedTagKey:           CodeReservedTagKey,
+	user.ErrorTagValueTooLong:          CodeTagValueTooLong,
+	user.ErrorInvalidStatus:            CodeInvalidStatus,
+	user.ErrorStatusUnchanged:          CodeStatusUnchanged,
+	user.ErrorUserSuspended:            CodeUserSuspended,
+	user.ErrorEmailImmutable:           CodeEmailImmutable,
+	user.ErrorEmailUnchanged:           CodeEmailUnchanged,
+	user.ErrorMissingNewEmail:          CodeInvalidEmail,
+	user.ErrorInvalidDomain:            CodeInvalidDomain,
+	user.ErrorMissingOlderThan:         CodeInvalidOlderThan,
+	user.ErrorInvalidOlderThan:         CodeInvalidOlderThan,
+	user.ErrorPasswordTooShort:         CodeInvalidPassword,
+	user.ErrorPasswordTooLong:          CodeInvalidPassword,
+	user.ErrorPasswordIsEmail:          CodeInvalidPassword,
+	user.ErrorMissingPasswords:         CodeInvalidPassword,
+	user.ErrorPasswordNotUpdatable:     CodePasswordImmutable,
+	user.ErrorInvalidCredentials:       CodeInvalidCredentials,
+	user.ErrorMissingVerifyToken:       CodeInvalidRequest,
+	user.ErrorInvalidVerifyToken:       CodeInvalidVerifyToken,
+	user.ErrorVerifyTokenExpired:       CodeVerifyTokenExpired,
+	user.ErrorAlreadyVerified:          CodeAlreadyVerified,
+	user.ErrorTooManyVerifySends:       CodeTooManyVerifySends,
+	user.ErrorInvalidRole:              CodeInvalidRole,
+	user.ErrorRoleChangeForbidden:      CodeRoleChangeForbidden,
+	user.ErrorDisposableEmail:          CodeDisposableEmail,
+	user.ErrorDomainRejectsMail:        CodeDomainRejectsMail,
+	user.ErrorDomainNotAllowed:         CodeDomainNotAllowed,
+	user.ErrorValidationFailed:         CodeValidationFailed,
+	user.ErrorEncryptedFieldFilter:     CodeEncryptedFieldFilter,
+	user.ErrorMissingMergeEmails:       CodeInvalidEmail,
+	user.ErrorMergeIntoSelf:            CodeMergeIntoSelf,
+	user.ErrorMissingDuplicateName:     CodeInvalidRequest,
+	user.ErrorPrefixTooShort:           CodePrefixTooShort,
+	user.ErrorInvalidSearchCursor:      CodeInvalidRequest,
+
+	// Organizations report their errors as user errors
+	org.ErrorInvalidSlug:      CodeInvalidSlug,
+	org.ErrorInvalidOrgName:   CodeInvalidOrgName,
+	org.ErrorOrgAlreadyExists: CodeOrgExists,
+	org.ErrorOrgDoesNotExist:  CodeOrgNotFound,
+	org.ErrorAlreadyMember:    CodeAlreadyMember,
+	org.ErrorNotMember:        CodeNotMember,
+
+	// Invalid emails are reported with the reason the validators give
+	validators.ErrEmailTooLong.Error():   CodeInvalidEmail,
+	validators.ErrEmailTooShort.Error():  CodeInvalidEmail,
+	validators.ErrEmailBadFormat.Error(): CodeInvalidEmail,
+	validators.ErrEmailBadDomain.Error(): CodeInvalidEmail,
+}
+
+// statusFor maps an error returned by the user package to an HTTP status code.
+//
+// Parameters:
+// - err: The error to classify.
+//
+// Returns:
+// - The HTTP status code matching the error kind, or 500 for unknown errors.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, user.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, user.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, user.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, user.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, user.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, user.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, user.ErrGone):
+		return http.StatusGone
+	case errors.Is(err, user.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, user.ErrUnprocessable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, user.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, user.ErrStorage):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// codeFor maps an error returned by the user package to a machine-readable error code.
+//
+// Parameters:
+// - err: The error to classify.
+//
+// Returns:
+// - The error code matching the error kind, or CodeInternal for unknown errors.
+func codeFor(err error) ErrorCode {
+	switch {
+	case errors.Is(err, user.ErrValidation):
+		return CodeValidation
+	case errors.Is(err, user.ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, user.ErrUnauthorized):
+		return CodeUnauthorized
+	case errors.Is(err, user.ErrForbidden):
+		return CodeForbidden
+	case errors.Is(err, user.ErrConflict):
+		return CodeConflict
+	case errors.Is(err, user.ErrPreconditionFailed):
+		return CodePreconditionFailed
+	case errors.Is(err, user.ErrGone):
+		return CodeGone
+	case errors.Is(err, user.ErrRateLimited):
+		return CodeTooManyRequests
+	case errors.Is(err, user.ErrUnprocessable):
+		return CodeUnprocessable
+	case errors.Is(err, user.ErrTooLarge):
+		return CodePayloadTooLarge
+	case errors.Is(err, user.ErrStorage):
+		return CodeStorage
+	default:
+		return CodeInternal
+	}
+}
+
+// specificCodeFor maps an error returned by the user package to its specific code,
+// falling back to the code of its kind.
+//
+// Parameters:
+// - err: The error to classify.
+//
+// Returns:
+// - The specific error code.
+func specificCodeFor(err error) ErrorCode {
+	var userErr *user.Error
+	if errors.As(err, &userErr) {
+		if code, ok := messageCode(userErr.Message); ok {
+			return code
+		}
+	}
+	return codeFor(err)
+}
+
+// messageCode maps a client-facing message of the user package to its specific code.
+//
+// Returns:
+// - The code, and false if the message has no specific code.
+func messageCode(message string) (ErrorCode, bool) {
+	if code, ok := userErrorCodes[message]; ok {
+		return code, true
+	}
+	// The bulk delete limit is appended to its message
+	if strings.HasPrefix(message, user.ErrorTooManyToDelete) {
+		return CodeTooManyToDelete, true
+	}
+	return "", false
+}
+
+// errorMessage returns the client-facing message for an error.
+// Underlying causes (e.g. AWS SDK errors) are never included.
+//
+// Parameters:
+// - err: The error to describe.
+//
+// Returns:
+// - The message safe to send to the client.
+func errorMessage(err error) string {
+	var userErr *user.Error
+	if errors.As(err, &userErr) {
+		return userErr.Message
+	}
+	return ErrorInternal
+}
+
+// errorResponse builds the error response for an error returned by the user package.
+// The full error, including the wrapped AWS cause, is logged with the request ID
+// while the client only receives the generic message. Enveloped (v2) responses carry the
+// specific code of the error rather than the code of its kind.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest that failed, used for its request ID.
+// - err: The error to report.
+//
+// Returns:
+// - APIGatewayProxyResponse with the mapped status code and the client-facing message.
+func errorResponse(req events.APIGatewayProxyRequest, err error) (*events.APIGatewayProxyResponse, error) {
+	status := statusFor(err)
+	level := logging.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = logging.LevelError
+	}
+	logging.Default.Log(level, "request failed", logging.Fields{
+		"requestId": req.RequestContext.RequestID,
+		"method":    req.HTTPMethod,
+		"route":     RouteName(req),
+		"status":    status,
+		"error":     err,
+	})
+	code := codeFor(err)
+	if WantsEnvelope(req) {
+		code = specificCodeFor(err)
+	}
+	body := ErrorBody{ErrorMsg: aws.String(errorMessage(err)), Code: aws.String(string(code)), Fields: errorFields(err)}
+	var userErr *user.Error
+	if errors.As(err, &userErr) && len(userErr.Field) > 0 {
+		body.Field = aws.String(userErr.Field)
+	}
+	return APIResponse(status, body)
+}
+
+// errorFields lists the invalid fields of a validation error: every field of one wrapping
+// user.ValidationErrors, or the single field it names, so clients can always read "fields".
+//
+// Parameters:
+// - err: The error to describe.
+//
+// Returns:
+// - The invalid fields, or nil if the error concerns no field.
+func errorFields(err error) []user.FieldError {
+	var invalid user.ValidationErrors
+	if errors.As(err, &invalid) {
+		return invalid.Fields()
+	}
+	var userErr *user.Error
+	if errors.As(err, &userErr) && userErr.Field != "" && errors.Is(err, user.ErrValidation) {
+		return []user.FieldError{userErr.FieldError()}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"testing"
+)
+
+func TestCodeFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     error
+		wantCode ErrorCode
+	}{
+		{name: "validation", kind: user.ErrValidation, wantCode: CodeValidation},
+		{name: "not found", kind: user.ErrNotFound, wantCode: CodeNotFound},
+		{name: "unauthorized", kind: user.ErrUnauthorized, wantCode: CodeUnauthorized},
+		{name: "forbidden", kind: user.ErrForbidden, wantCode: CodeForbidden},
+		{name: "conflict", kind: user.ErrConflict, wantCode: CodeConflict},
+		{name: "precondition failed", kind: user.ErrPreconditionFailed, wantCode: CodePreconditionFailed},
+		{name: "gone", kind: user.ErrGone, wantCode: CodeGone},
+		{name: "rate limited", kind: user.ErrRateLimited, wantCode: CodeTooManyRequests},
+		{name: "unprocessable", kind: user.ErrUnprocessable, wantCode: CodeUnprocessable},
+		{name: "too large", kind: user.ErrTooLarge, wantCode: CodePayloadTooLarge},
+		{name: "storage", kind: user.ErrStorage, wantCode: CodeStorage},
+		{name: "internal", kind: user.ErrInternal, wantCode: CodeInternal},
+		{name: "not a user error", kind: errors.New("boom"), wantCode: CodeInternal},
+	}
+	statuses := map[ErrorCode]int{}
+	for _, info := range AllErrorCodes() {
+		statuses[info.Code] = info.Status
+	}
+	kinds := map[ErrorCode]string{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &user.Error{Kind: tt.kind, Message: "message"})
+			code := codeFor(err)
+			if code != tt.wantCode {
+				t.Fatalf("codeFor() = %s, want %s", code, tt.wantCode)
+			}
+			if status, ok := statuses[code]; !ok || status != statusFor(err) {
+				t.Errorf("%s is documented with status %d, want the %d the error is sent with", code, status, statusFor(err))
+			}
+			// Only unknown errors share the internal code with ErrInternal
+			if other, taken := kinds[code]; taken && code != CodeInternal {
+				t.Errorf("%s is the code of both %s and %s", code, other, tt.name)
+			}
+			kinds[code] = tt.name
+		})
+	}
+}
+
+func TestSpecificCodeFor(t *testing.T) {
+	documented := map[ErrorCode]bool{}
+	for _, info := range AllErrorCodes() {
+		documented[info.Code] = true
+	}
+	messages := make([]string, 0, len(userErrorCodes)+1)
+	for message := range userErrorCodes {
+		messages = append(messages, message)
+	}
+	messages = append(messages, user.ErrorTooManyToDelete+"100")
+
+	for _, message := range messages {
+		t.Run(message, func(t *testing.T) {
+			code, ok := messageCode(message)
+			if !ok {
+				t.Fatalf("messageCode(%q) has no code", message)
+			}
+			if !documented[code] {
+				t.Errorf("%s isn't listed in AllErrorCodes", code)
+			}
+			err := &user.Error{Kind: user.ErrValidation, Message: message}
+			if got := specificCodeFor(fmt.Errorf("wrapped: %w", err)); got != code {
+				t.Errorf("specificCodeFor() = %s, want %s", got, code)
+			}
+		})
+	}
+
+	t.Run("message without a specific code", func(t *testing.T) {
+		err := &user.Error{Kind: user.ErrStorage, Message: user.ErrorCouldNotBatchWrite}
+		if got := specificCodeFor(err); got != CodeStorage {
+			t.Errorf("specificCodeFor() = %s, want the code of its kind %s", got, CodeStorage)
+		}
+	})
+}
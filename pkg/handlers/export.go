@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/Vansh3140/golang-serverless/pkg/audit"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"time"
+)
+
+// maxResponseBytes is API Gateway's limit on the size of a Lambda proxy response body
+const maxResponseBytes = 6 * 1024 * 1024
+
+// ErrorExportTooLarge is returned when an export doesn't fit in a single response
+var ErrorExportTooLarge = "export exceeds the 6 MB response limit; narrow it with firstname, lastname or q, " +
+	"or page through GET /users instead"
+
+// ExportUsers handles GET /users/export, returning every user as a CSV attachment ordered
+// by email. The list filters (firstname, lastname, q) apply, but MAX_LIST_ITEMS doesn't:
+// the whole table is read.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest with optional filter parameters.
+// - repo: Repository storing the users.
+//
+// Returns:
+// - APIGatewayProxyResponse with the CSV body, or 413 if it exceeds API Gateway's 6 MB limit.
+func ExportUsers(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	opts, err := readOptions(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	opts.Fields = nil
+	opts.MaxItems = 0
+
+	page, err := user.FetchUsers(opts, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	user.SortUsers(page.Users, "email", false)
+
+	var buf bytes.Buffer
+	if err := user.WriteCSV(&buf, page.Users); err != nil {
+		return errorResponse(req, err)
+	}
+
+	// Non-ASCII bodies are sent base64-encoded so API Gateway passes the bytes through untouched
+	resp := &events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":        "text/csv; charset=utf-8",
+			"Content-Disposition": `attachment; filename="users-` + time.Now().UTC().Format("20060102") + `.csv"`,
+		},
+		Body: buf.String(),
+	}
+	if !isASCII(buf.Bytes()) {
+		resp.Body = base64.StdEncoding.EncodeToString(buf.Bytes())
+		resp.IsBase64Encoded = true
+	}
+	if len(resp.Body) > maxResponseBytes {
+		return APIError(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, ErrorExportTooLarge)
+	}
+	return resp, nil
+}
+
+// isASCII reports whether b contains only 7-bit ASCII.
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// ExportUser handles GET /users/{email}/export, returning everything stored about the user,
+// including its audit entries when AUDIT_TABLE_NAME is set, as a JSON attachment. Only the
+// user themselves or an administrator may export it when authentication is configured.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the user.
+// - repo: Repository storing the users.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+// - APIGatewayProxyResponse with the export document, or 404 if the user doesn't exist.
+func ExportUser(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	export, err := user.ExportUser(email, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	if audit.Enabled() {
+		export.Audit, err = audit.ListAll(export.User.Email, dynaClient)
+		if err != nil {
+			return errorResponse(req, err)
+		}
+	}
+	resp, err := APIResponse(http.StatusOK, export)
+	resp.Headers["Content-Disposition"] = `attachment; filename="user-data.json"`
+	resp.Headers["Cache-Control"] = "no-store"
+	return resp, err
+}
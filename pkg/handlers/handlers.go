@@ -1,116 +1,545 @@
 package handlers
 
 import (
+	"github.com/Vansh3140/golang-serverless/pkg/outbox"
 	"github.com/Vansh3140/golang-serverless/pkg/user"
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// ErrorMethodNotAllowed is the response message for unsupported HTTP methods
-var ErrorMethodNotAllowed = "method not allowed"
+// Error messages returned directly by the handlers
+var (
+	ErrorMethodNotAllowed = "method not allowed"
+	ErrorIfMatchRequired  = "If-Match header is required"
+	ErrorRouteNotFound    = "no route matches the path"
+)
+
+// TruncatedHeader is set to "true" on list responses cut short by MAX_LIST_ITEMS
+const TruncatedHeader = "X-Truncated"
 
 // ErrorBody represents the structure for error responses
 type ErrorBody struct {
-	ErrorMsg *string `json:"error,omitempty"` // Error message in the response body
+	ErrorMsg  *string           `json:"error,omitempty" xml:"error,omitempty"`         // Error message in the response body
+	Code      *string           `json:"code,omitempty" xml:"code,omitempty"`           // Machine-readable error code
+	Field     *string           `json:"field,omitempty" xml:"field,omitempty"`         // Offending request field, for validation errors
+	Fields    []user.FieldError `json:"fields,omitempty" xml:"fields>item,omitempty"`  // Every invalid field, for validation errors
+	RequestID *string           `json:"requestId,omitempty" xml:"requestId,omitempty"` // ID of the failed request, to quote when reporting it
 }
 
 // GetUser handles GET requests to fetch a user by email or all users.
-// If the "email" query parameter is provided, it fetches a specific user; otherwise, it fetches all users.
+// If an email is provided (as /users/{email} or the "email" query parameter), it fetches
+// a specific user; otherwise, it fetches all users.
+// Single users carry an ETag, and a matching If-None-Match yields 304 Not Modified.
+// Reads are strongly consistent with ?consistent=true (or CONSISTENT_READS=true), and
+// ?fields=email,firstname limits both the attributes read and the response to those fields.
+// Several users are fetched at once with ?emails=a@x.com,b@y.com, and ?include=orgs expands
+// a single user with its organization memberships.
+// Lists can be filtered with ?firstname=, ?lastname= and ?q= (substring of either name), and
+// ?createdAfter= and ?createdBefore= (RFC3339, newest first unless ordered otherwise), and
+// ordered with ?sort=lastname|firstname|email|createdAt and ?order=asc|desc.
+// Lists carry the number of users the filters select in X-Total-Count, unless ?skipCount=true
+// spares the COUNT scan, and the most users a page holds in X-Page-Size.
 //
 // Parameters:
 // - req: APIGatewayProxyRequest containing the request data.
-// - tableName: DynamoDB table name where user data is stored.
-// - dynaClient: DynamoDB client interface.
+// - repo: Repository where user data is stored.
+// - dynaClient: DynamoDB client interface, used for the organizations table.
 //
 // Returns:
 // - APIGatewayProxyResponse with user data or error message.
-func GetUser(req events.APIGatewayProxyRequest, tableName string, dynaClient dynamodbiface.DynamoDBAPI) (
+func GetUser(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
 	*events.APIGatewayProxyResponse, error) {
-	email := req.QueryStringParameters["email"]
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
 
-	// Fetch a specific user if the "email" query parameter is provided
+	opts, err := readOptions(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	fields := opts.Fields
+	expandOrgs, err := includesOrgs(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+
+	// Fetch several users at once if a list of emails is provided
+	if emails := emailsParam(req); len(emails) > 0 {
+		if len(email) > 0 || !opts.Filter.IsEmpty() {
+			return APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorEmailsWithEmail)
+		}
+		if expandOrgs {
+			return APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorIncludeWithList)
+		}
+		return getUsersByEmail(req, emails, opts, repo)
+	}
+
+	sortField := req.QueryStringParameters["sort"]
+	desc, err := user.ParseSort(sortField, req.QueryStringParameters["order"])
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	// Signups in a creation time range come newest first unless ?order=asc
+	if sortField == "" && opts.Filter.HasCreatedRange() {
+		sortField, desc = "createdAt", req.QueryStringParameters["order"] != "asc"
+	}
+
+	// Fetch a specific user if an email is provided
 	if len(email) > 0 {
-		result, err := user.FetchUser(email, tableName, dynaClient)
+		if !opts.Filter.IsEmpty() {
+			return APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorFilterWithEmail)
+		}
+		if denied := requireSelfOrAdmin(req, email); denied != nil {
+			return denied, nil
+		}
+
+		// The version is always read so the ETag stays meaningful
+		if len(fields) > 0 {
+			opts.Fields = user.WithFields(fields, "version")
+		}
+
+		result, err := user.FetchUser(email, opts, repo)
 		if err != nil {
-			return apiResponse(http.StatusBadRequest, ErrorBody{aws.String(err.Error())})
+			return errorResponse(req, err)
+		}
+
+		// Let clients holding the current version skip the download
+		etag := versionETag(result.Version)
+		if ifNoneMatch := headerValue(req, "If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+			return notModified(etag)
+		}
+
+		body := selectUser(result, fields)
+		if expandOrgs {
+			if body, err = withOrgs(result, fields, dynaClient); err != nil {
+				return errorResponse(req, err)
+			}
+		}
+		resp, err := APIResponse(http.StatusOK, body)
+		resp.Headers["ETag"] = etag
+		return resp, err
+	}
+
+	// Memberships are only expanded for a single user
+	if expandOrgs {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorIncludeWithList)
+	}
+
+	skipCount := false
+	if raw, ok := req.QueryStringParameters["skipCount"]; ok {
+		if skipCount, err = strconv.ParseBool(raw); err != nil {
+			return APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorInvalidSkipCount)
 		}
-		return apiResponse(http.StatusOK, result)
 	}
 
-	// Fetch all users if no "email" query parameter is provided
-	result, err := user.FetchUsers(tableName, dynaClient)
+	// v2 lists are paginated, in email order unless sorted otherwise so pages are stable
+	paginated := RequestVersion(req) == V2
+	limit, offset := opts.MaxItems, 0
+	if paginated {
+		if limit, offset, err = pageParams(req); err != nil {
+			return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		}
+		if sortField == "" {
+			sortField = "email"
+		}
+	}
+
+	// The sort field must be read even if it isn't returned
+	if len(fields) > 0 && sortField != "" {
+		opts.Fields = user.WithFields(fields, sortField)
+	}
+
+	// Fetch all users if no "email" query parameter is provided, for administrators only
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	page, err := user.FetchUsers(opts, repo)
 	if err != nil {
-		return apiResponse(http.StatusBadRequest, ErrorBody{aws.String(err.Error())})
+		return errorResponse(req, err)
 	}
-	return apiResponse(http.StatusOK, result)
+	var total int64
+	if !skipCount {
+		if total, err = totalCount(opts, repo, time.Now()); err != nil {
+			return errorResponse(req, err)
+		}
+	}
+
+	// Sorting happens after the read, so a truncated list is sorted only within what was read
+	user.SortUsers(page.Users, sortField, desc)
+
+	// Cut the v2 page out of the sorted list
+	var nextCursor string
+	if paginated {
+		if offset > len(page.Users) {
+			offset = len(page.Users)
+		}
+		if end := offset + limit; end < len(page.Users) {
+			page.Users, nextCursor = page.Users[offset:end], pageCursor(end)
+		} else {
+			page.Users = page.Users[offset:]
+		}
+	}
+
+	var resp *events.APIGatewayProxyResponse
+	if wantsCSV(req) {
+		resp, err = csvResponse(req, page.Users)
+	} else {
+		resp, err = APIResponse(http.StatusOK, selectUsers(page.Users, fields))
+	}
+
+	// Tell clients the list stopped at MAX_LIST_ITEMS
+	if page.Truncated {
+		resp.Headers[TruncatedHeader] = "true"
+	}
+	if nextCursor != "" {
+		resp.Headers[NextCursorHeader] = nextCursor
+	}
+	resp.Headers[PageSizeHeader] = strconv.Itoa(limit)
+	if !skipCount {
+		resp.Headers[TotalCountHeader] = strconv.FormatInt(total, 10)
+	}
+	return resp, err
+}
+
+// selectUser returns the user as a response body holding only the selected fields, or
+// the whole user if none are selected.
+func selectUser(u *user.User, fields []string) interface{} {
+	if len(fields) == 0 {
+		return u
+	}
+	return u.Select(fields)
+}
+
+// selectUsers returns the users as a response body holding only the selected fields of
+// each, or the whole users if none are selected.
+func selectUsers(users []user.User, fields []string) interface{} {
+	if len(fields) == 0 {
+		return users
+	}
+	selected := make([]map[string]interface{}, len(users))
+	for i := range users {
+		selected[i] = users[i].Select(fields)
+	}
+	return selected
+}
+
+// getUsersByEmail serves GET /users?emails=a@x.com,b@y.com with the users found and the
+// emails that had none, both in request order.
+func getUsersByEmail(req events.APIGatewayProxyRequest, emails []string, opts user.ReadOptions,
+	repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+	for _, email := range emails {
+		if denied := requireSelfOrAdmin(req, email); denied != nil {
+			return denied, nil
+		}
+	}
+
+	result, err := user.FetchUsersByEmail(emails, opts, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	if len(opts.Fields) == 0 {
+		return APIResponse(http.StatusOK, result)
+	}
+	return APIResponse(http.StatusOK, map[string]interface{}{"users": selectUsers(result.Users, opts.Fields), "missing": result.Missing})
+}
+
+// CountBody represents the user count response
+type CountBody struct {
+	Count int64 `json:"count"` // Number of users
+}
+
+// CountUsers handles GET /users/count by counting the users without downloading them.
+// It accepts the same filters as the list.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest for the count.
+// - repo: Repository where user data is stored.
+//
+// Returns:
+// - APIGatewayProxyResponse with {"count": N} or error message.
+func CountUsers(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+	opts, err := readOptions(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+
+	count, err := user.CountUsers(opts, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	return APIResponse(http.StatusOK, CountBody{Count: count})
 }
 
 // CreateUser handles POST requests to create a new user in DynamoDB.
+// When IDEMPOTENCY_TABLE_NAME is set, an Idempotency-Key header makes retries safe.
+// ?fields=email,version trims the returned user to those fields.
 //
 // Parameters:
 // - req: APIGatewayProxyRequest containing the user data.
-// - tableName: DynamoDB table name where the new user will be stored.
-// - dynaClient: DynamoDB client interface.
+// - repo: Repository where the new user will be stored.
+// - dynaClient: DynamoDB client interface, used for the idempotency table.
 //
 // Returns:
-// - APIGatewayProxyResponse with the created user data or error message.
-func CreateUser(req events.APIGatewayProxyRequest, tableName string, dynaClient dynamodbiface.DynamoDBAPI) (
+// - APIGatewayProxyResponse with the created user data and its Location, or error message.
+func CreateUser(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
 	*events.APIGatewayProxyResponse, error) {
-	result, err := user.CreateUser(req, tableName, dynaClient)
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+
+	req, invalid := decodeBody(req, maxBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+
+	opts, err := createOptions(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	fields, err := responseFields(req)
 	if err != nil {
-		return apiResponse(http.StatusBadRequest, ErrorBody{
-			aws.String(err.Error()),
-		})
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+
+	// Retried creates carrying the same Idempotency-Key return the original user
+	var result *user.User
+	idempotencyKey := headerValue(req, "Idempotency-Key")
+	idempotencyTable := os.Getenv("IDEMPOTENCY_TABLE_NAME")
+	if len(idempotencyKey) > 0 && len(idempotencyTable) > 0 {
+		result, err = user.CreateUserIdempotent(req, idempotencyKey, idempotencyTable, opts, repo, dynaClient)
+	} else {
+		result, err = user.CreateUserWithOptions(req, opts, repo)
 	}
-	return apiResponse(http.StatusCreated, result)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	recordMutations(req, dynaClient, mutation{action: "created", email: result.Email, after: result, outboxed: outbox.Enabled()})
+	publishVerification(req, result)
+	return APIResponse(http.StatusCreated, withVerifyToken(result, fields), map[string]string{"Location": userLocation(req, result.Email)})
 }
 
 // UpdateUser handles PUT requests to update existing user data in DynamoDB.
+// The If-Match header carries the version the client last saw; a stale version yields 412.
+// Requests without If-Match are accepted unless REQUIRE_IF_MATCH is set to "true".
+// Suspended users can only be updated by an administrator.
+// ?fields=email,version trims the returned user to those fields.
 //
 // Parameters:
 // - req: APIGatewayProxyRequest containing the updated user data.
-// - tableName: DynamoDB table name where the user data is stored.
-// - dynaClient: DynamoDB client interface.
+// - repo: Repository where the user data is stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
 //
 // Returns:
 // - APIGatewayProxyResponse with the updated user data or error message.
-func UpdateUser(req events.APIGatewayProxyRequest, tableName string, dynaClient dynamodbiface.DynamoDBAPI) (
+func UpdateUser(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
 	*events.APIGatewayProxyResponse, error) {
-	result, err := user.UpdateUser(req, tableName, dynaClient)
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+
+	fields, err := responseFields(req)
 	if err != nil {
-		return apiResponse(http.StatusBadRequest, ErrorBody{
-			aws.String(err.Error()),
-		})
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+	req, invalid := decodeBody(req, maxBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+
+	// Only the user themselves or an admin may update the record
+	target := email
+	if len(target) == 0 {
+		target = bodyEmail(req.Body)
+	}
+	if denied := requireSelfOrAdmin(req, target); denied != nil {
+		return denied, nil
+	}
+
+	expectedVersion, present, err := ifMatchVersion(req)
+	if err != nil {
+		return APIError(http.StatusPreconditionFailed, CodePreconditionFailed, err.Error())
+	}
+	if !present && os.Getenv("REQUIRE_IF_MATCH") == "true" {
+		return APIError(http.StatusPreconditionRequired, CodePreconditionRequired, ErrorIfMatchRequired)
 	}
-	return apiResponse(http.StatusOK, result)
+
+	result, previous, err := user.UpdateUserWithOptions(req, email, expectedVersion, updateOptions(req), repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	recordMutations(req, dynaClient, mutation{action: "updated", email: result.Email, before: previous, after: result,
+		outboxed: outbox.Enabled()})
+	resp, err := APIResponse(http.StatusOK, selectUser(result, fields))
+	resp.Headers["ETag"] = versionETag(result.Version)
+	return resp, err
+}
+
+// PatchUser handles PATCH requests changing some fields of a user. The body is a JSON merge
+// patch: the fields it names are set, those set to null are cleared, and the others keep
+// their stored value. If-Match works as on UpdateUser; without it, the patch is applied to
+// the latest version of the user.
+// ?fields=email,version trims the returned user to those fields.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest containing the patch.
+// - repo: Repository where the user data is stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+// - APIGatewayProxyResponse with the patched user data or error message.
+func PatchUser(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+
+	fields, err := responseFields(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+	req, invalid := decodeBody(req, maxBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+
+	// Only the user themselves or an admin may patch the record
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	expectedVersion, present, err := ifMatchVersion(req)
+	if err != nil {
+		return APIError(http.StatusPreconditionFailed, CodePreconditionFailed, err.Error())
+	}
+	if !present && os.Getenv("REQUIRE_IF_MATCH") == "true" {
+		return APIError(http.StatusPreconditionRequired, CodePreconditionRequired, ErrorIfMatchRequired)
+	}
+
+	result, previous, err := user.PatchUser(email, req.Body, expectedVersion, updateOptions(req), repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	recordMutations(req, dynaClient, mutation{action: "updated", email: result.Email, before: previous, after: result,
+		outboxed: outbox.Enabled()})
+	resp, err := APIResponse(http.StatusOK, selectUser(result, fields))
+	resp.Headers["ETag"] = versionETag(result.Version)
+	return resp, err
 }
 
 // DeleteUser handles DELETE requests to remove a user from DynamoDB.
+// With SOFT_DELETE=true the user is only flagged as deleted; administrators can still
+// remove it for good with hard=true.
 //
 // Parameters:
-// - req: APIGatewayProxyRequest containing the user ID.
-// - tableName: DynamoDB table name where the user data is stored.
-// - dynaClient: DynamoDB client interface.
+// - req: APIGatewayProxyRequest containing the user's email in the path or query string.
+// - repo: Repository where the user data is stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
 //
 // Returns:
 // - APIGatewayProxyResponse with a success message or error message.
-func DeleteUser(req events.APIGatewayProxyRequest, tableName string, dynaClient dynamodbiface.DynamoDBAPI) (
+func DeleteUser(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+
+	hard := false
+	if raw, ok := req.QueryStringParameters["hard"]; ok {
+		if hard, err = strconv.ParseBool(raw); err != nil {
+			return APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorInvalidHard)
+		}
+	}
+	if hard {
+		if denied := requireAdmin(req); denied != nil {
+			return denied, nil
+		}
+	}
+
+	// Deleting anyone but yourself is reserved to administrators
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	err = user.DeleteUser(email, hard, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	// Soft deletes are updates that bypass the outbox
+	recordMutations(req, dynaClient, mutation{action: "deleted", email: user.NormalizeEmail(email),
+		outboxed: outbox.Enabled() && (hard || !user.SoftDeleteEnabled())})
+	return APIResponse(http.StatusOK, "User deleted successfully")
+}
+
+// RestoreUser handles POST /users/{email}/restore, undoing a soft delete.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the user.
+// - repo: Repository where the user data is stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+// - APIGatewayProxyResponse with the restored user, 404 if it doesn't exist, or 409 if it isn't deleted.
+func RestoreUser(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
 	*events.APIGatewayProxyResponse, error) {
-	err := user.DeleteUser(req, tableName, dynaClient)
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	fields, err := responseFields(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	result, err := user.RestoreUser(email, repo)
 	if err != nil {
-		return apiResponse(http.StatusBadRequest, ErrorBody{
-			aws.String(err.Error()),
-		})
+		return errorResponse(req, err)
 	}
-	return apiResponse(http.StatusOK, "User deleted successfully")
+	recordMutations(req, dynaClient, mutation{action: "restored", email: result.Email, after: result})
+	resp, err := APIResponse(http.StatusOK, selectUser(result, fields))
+	resp.Headers["ETag"] = versionETag(result.Version)
+	return resp, err
 }
 
 // UnhandledMethod handles unsupported HTTP methods and returns a 405 Method Not Allowed response.
 //
+// Parameters:
+// - req: APIGatewayProxyRequest with the unsupported method.
+//
+// Returns:
+// - APIGatewayProxyResponse with a METHOD_NOT_ALLOWED error and the route's methods in Allow.
+func UnhandledMethod(req events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	resp, err := APIError(http.StatusMethodNotAllowed, CodeMethodNotAllowed, ErrorMethodNotAllowed)
+	resp.Headers["Allow"] = strings.Join(AllowedMethods(req), ", ")
+	return resp, err
+}
+
+// RouteNotFound handles requests to paths no route matches and returns a 404 Not Found response.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest with the unknown path.
+//
 // Returns:
-// - APIGatewayProxyResponse with a "method not allowed" error message.
-func UnhandledMethod() (*events.APIGatewayProxyResponse, error) {
-	return apiResponse(http.StatusMethodNotAllowed, ErrorMethodNotAllowed)
+// - APIGatewayProxyResponse with a NOT_FOUND error.
+func RouteNotFound(req events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	return APIError(http.StatusNotFound, CodeNotFound, ErrorRouteNotFound)
 }
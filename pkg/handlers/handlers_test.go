@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// userHandler is the signature the user handlers share
+type userHandler func(events.APIGatewayProxyRequest, user.Repository, dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error)
+
+// TestHandlersOnMemoryRepository runs the handlers through the lifecycle of a user against
+// the in-memory repository, one step after the other.
+func TestHandlersOnMemoryRepository(t *testing.T) {
+	const (
+		ada     = `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`
+		adaKing = `{"email": "ada@example.com", "firstname": "Ada", "lastname": "King"}`
+	)
+	steps := []struct {
+		name         string
+		handler      userHandler
+		method       string
+		email        string
+		body         string
+		ifMatch      string
+		wantStatus   int
+		wantLastname string
+	}{
+		{name: "get missing", handler: GetUser, method: http.MethodGet, email: "ada@example.com",
+			wantStatus: http.StatusNotFound},
+		{name: "create", handler: CreateUser, method: http.MethodPost, body: ada, wantStatus: http.StatusCreated,
+			wantLastname: "Lovelace"},
+		{name: "create duplicate", handler: CreateUser, method: http.MethodPost, body: ada,
+			wantStatus: http.StatusConflict},
+		{name: "create invalid", handler: CreateUser, method: http.MethodPost,
+			body: `{"email": "not-an-email", "firstname": "Ada", "lastname": "Lovelace"}`, wantStatus: http.StatusBadRequest},
+		{name: "get", handler: GetUser, method: http.MethodGet, email: "ada@example.com", wantStatus: http.StatusOK,
+			wantLastname: "Lovelace"},
+		{name: "get by mixed case", handler: GetUser, method: http.MethodGet, email: "Ada@Example.com",
+			wantStatus: http.StatusOK, wantLastname: "Lovelace"},
+		{name: "update stale", handler: UpdateUser, method: http.MethodPut, email: "ada@example.com", body: adaKing,
+			ifMatch: `"7"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "update", handler: UpdateUser, method: http.MethodPut, email: "ada@example.com", body: adaKing,
+			ifMatch: `"1"`, wantStatus: http.StatusOK, wantLastname: "King"},
+		{name: "get updated", handler: GetUser, method: http.MethodGet, email: "ada@example.com",
+			wantStatus: http.StatusOK, wantLastname: "King"},
+		{name: "update missing", handler: UpdateUser, method: http.MethodPut, email: "alan@example.com",
+			body: `{"email": "alan@example.com", "firstname": "Alan", "lastname": "Turing"}`, wantStatus: http.StatusNotFound},
+		{name: "delete", handler: DeleteUser, method: http.MethodDelete, email: "ada@example.com",
+			wantStatus: http.StatusOK},
+		{name: "get deleted", handler: GetUser, method: http.MethodGet, email: "ada@example.com",
+			wantStatus: http.StatusNotFound},
+		{name: "delete again", handler: DeleteUser, method: http.MethodDelete, email: "ada@example.com",
+			wantStatus: http.StatusNotFound},
+	}
+
+	repo := user.NewMemoryRepository()
+	for _, step := range steps {
+		req := events.APIGatewayProxyRequest{HTTPMethod: step.method, Body: step.body,
+			Headers: map[string]string{"Content-Type": "application/json"}}
+		if step.email != "" {
+			req.PathParameters = map[string]string{"email": step.email}
+		}
+		if step.ifMatch != "" {
+			req.Headers["If-Match"] = step.ifMatch
+		}
+
+		resp, err := step.handler(req, repo, nil)
+		if err != nil {
+			t.Fatalf("%s: handler error = %v", step.name, err)
+		}
+		if resp.StatusCode != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, resp.StatusCode, step.wantStatus, resp.Body)
+		}
+		if step.wantLastname == "" {
+			continue
+		}
+		var got user.User
+		if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+			t.Fatalf("%s: body %q isn't a user: %v", step.name, resp.Body, err)
+		}
+		if got.Email != "ada@example.com" || got.LastName != step.wantLastname {
+			t.Errorf("%s: user = %s %s, want ada@example.com %s", step.name, got.Email, got.LastName, step.wantLastname)
+		}
+	}
+}
+
+func TestListTruncated(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxItems      string
+		v2            bool
+		wantUsers     int
+		wantTruncated bool
+	}{
+		{name: "complete", wantUsers: 3},
+		{name: "truncated", maxItems: "2", wantUsers: 2, wantTruncated: true},
+		{name: "truncated v2", maxItems: "2", v2: true, wantUsers: 2, wantTruncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_LIST_ITEMS", tt.maxItems)
+			repo := user.NewMemoryRepository()
+			for _, email := range []string{"ada@example.com", "alan@example.com", "grace@example.com"} {
+				if err := repo.Create(&user.User{Email: email, FirstName: "Test", LastName: "User"}); err != nil {
+					t.Fatalf("seeding: %v", err)
+				}
+			}
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users", Headers: map[string]string{}}
+			if tt.v2 {
+				req.Headers[versionHeader] = strconv.Itoa(int(V2))
+			}
+
+			resp, err := GetUser(req, repo, nil)
+			if err != nil {
+				t.Fatalf("GetUser() error = %v", err)
+			}
+			EnvelopeResponse(req, resp)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, http.StatusOK, resp.Body)
+			}
+			if truncated := resp.Headers[TruncatedHeader] == "true"; truncated != tt.wantTruncated {
+				t.Errorf("%s = %q, want truncated %v", TruncatedHeader, resp.Headers[TruncatedHeader], tt.wantTruncated)
+			}
+
+			var users []user.User
+			if tt.v2 {
+				var envelope Envelope
+				if err := json.Unmarshal([]byte(resp.Body), &envelope); err != nil {
+					t.Fatalf("body %s isn't an envelope: %v", resp.Body, err)
+				}
+				if envelope.Meta == nil || envelope.Meta.Truncated != tt.wantTruncated {
+					t.Errorf("envelope meta = %+v, want truncated %v", envelope.Meta, tt.wantTruncated)
+				}
+				resp.Body = string(envelope.Data)
+			}
+			if err := json.Unmarshal([]byte(resp.Body), &users); err != nil {
+				t.Fatalf("body %s isn't a list of users: %v", resp.Body, err)
+			}
+			if len(users) != tt.wantUsers {
+				t.Errorf("listed %d users, want %d", len(users), tt.wantUsers)
+			}
+		})
+	}
+}
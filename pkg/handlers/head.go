@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TotalCountHeader carries the number of users a list would return
+const TotalCountHeader = "X-Total-Count"
+
+// HeadUser handles HEAD requests, so clients can check whether an email is taken without
+// downloading the user. A single user is read with only its key and version, answering
+// 200 with its ETag (or 304 for a matching If-None-Match) or 404. The collection answers
+// 200 with the number of users in X-Total-Count, honoring the list filters, like lists do.
+// The body of the response is removed by StripBody.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting a user or the collection.
+// - repo: Repository where user data is stored.
+//
+// Returns:
+// - APIGatewayProxyResponse with the status and headers of the lookup.
+func HeadUser(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	opts, err := readOptions(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+
+	// Count the collection instead of listing it
+	if len(email) == 0 {
+		count, err := totalCount(opts, repo, time.Now())
+		if err != nil {
+			return errorResponse(req, err)
+		}
+		return &events.APIGatewayProxyResponse{
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{TotalCountHeader: strconv.FormatInt(count, 10)},
+		}, nil
+	}
+
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	// The key and the version are all an existence check and the ETag need
+	opts.Fields = []string{"email", "version"}
+	result, err := user.FetchUser(email, opts, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+
+	etag := versionETag(result.Version)
+	if ifNoneMatch := headerValue(req, "If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		return notModified(etag)
+	}
+	return &events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"ETag": etag},
+	}, nil
+}
+
+// StripBody removes the body of a response to a HEAD request, keeping its status and headers.
+//
+// Parameters:
+// - resp: The response, modified in place.
+func StripBody(resp *events.APIGatewayProxyResponse) {
+	if resp != nil {
+		resp.Body = ""
+		resp.IsBase64Encoded = false
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrorInvalidIfMatch is returned when the If-Match header cannot be parsed as a version
+var ErrorInvalidIfMatch = "invalid If-Match header"
+
+// headerValue looks up a request header case-insensitively.
+// API Gateway forwards headers with whatever casing the client used.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the headers.
+// - name: The header name to look up.
+//
+// Returns:
+// - The header value, or an empty string if the header is absent.
+func headerValue(req events.APIGatewayProxyRequest, name string) string {
+	if value, ok := req.Headers[name]; ok {
+		return value
+	}
+	for key, value := range req.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// versionETag formats a user version as a strong ETag value.
+//
+// Parameters:
+// - version: The version number of the user record.
+//
+// Returns:
+// - The quoted ETag string (e.g. "3").
+func versionETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatchVersion extracts the expected version from the If-Match header.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the headers.
+//
+// Returns:
+// - The expected version, or 0 if the header is absent or "*".
+// - A boolean indicating whether the header was present.
+// - An error if the header does not contain a valid version ETag.
+func ifMatchVersion(req events.APIGatewayProxyRequest) (int, bool, error) {
+	value := strings.TrimSpace(headerValue(req, "If-Match"))
+	if value == "" {
+		return 0, false, nil
+	}
+	if value == "*" {
+		return 0, true, nil
+	}
+
+	// Accept both strong and weak forms of the ETag
+	value = strings.TrimPrefix(value, "W/")
+	version, err := strconv.Atoi(strings.Trim(value, `"`))
+	if err != nil || version < 0 {
+		return 0, true, errors.New(ErrorInvalidIfMatch)
+	}
+	return version, true, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches an ETag.
+// Comparison is weak, as RFC 7232 requires for If-None-Match, so W/ prefixes are ignored.
+//
+// Parameters:
+// - header: The value of the If-None-Match header (a comma-separated list or "*").
+// - etag: The current ETag of the resource.
+//
+// Returns:
+// - True if the header lists the ETag or is "*".
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified builds a 304 response with an empty body for a cached resource.
+//
+// Parameters:
+// - etag: The current ETag of the resource.
+//
+// Returns:
+// - APIGatewayProxyResponse with a 304 status and the ETag header.
+func notModified(etag string) (*events.APIGatewayProxyResponse, error) {
+	return &events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNotModified,
+		Headers:    map[string]string{"ETag": etag},
+	}, nil
+}
+
+// requestPath returns the path the client called, which includes the stage and any version
+// prefix, falling back to the stage and the routed path.
+func requestPath(req events.APIGatewayProxyRequest) string {
+	if req.RequestContext.Path != "" {
+		return req.RequestContext.Path
+	}
+	base := req.Path
+	if stage := req.RequestContext.Stage; stage != "" && stage != "$default" {
+		base = "/" + stage + base
+	}
+	return base
+}
+
+// userLocation returns the path of a user's resource, for the Location of a created user.
+// It is built from the path the client called, which includes the stage and any version
+// prefix, falling back to the stage and the routed path. Requests to a user action such as
+// /users/{email}/change-email or to /users/merge are resolved to the collection first.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest that targeted the users collection, a user action or a merge.
+// - email: The email of the user.
+//
+// Returns:
+// - The path, with the email percent-encoded as one segment.
+func userLocation(req events.APIGatewayProxyRequest, email string) string {
+	base := strings.TrimSuffix(requestPath(req), "/")
+	if userAction(req) != "" {
+		base = path.Dir(path.Dir(base))
+	} else if IsMergeRequest(req) {
+		base = path.Dir(base)
+	}
+
+	// "+" is valid in a path segment, but some clients decode it as a space
+	segment := strings.ReplaceAll(url.PathEscape(email), "+", "%2B")
+	return base + "/" + segment
+}
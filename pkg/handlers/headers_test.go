@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"testing"
+)
+
+func TestGetUserIfNoneMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		ifNoneMatch string
+		wantStatus  int
+		wantETag    bool
+	}{
+		{name: "no header", path: "/users/ada%40example.com", wantStatus: http.StatusOK, wantETag: true},
+		{name: "match", path: "/users/ada%40example.com", ifNoneMatch: `"3"`, wantStatus: http.StatusNotModified, wantETag: true},
+		{name: "weak match", path: "/users/ada%40example.com", ifNoneMatch: `W/"3"`, wantStatus: http.StatusNotModified, wantETag: true},
+		{name: "match in a list", path: "/users/ada%40example.com", ifNoneMatch: `"1", "3"`, wantStatus: http.StatusNotModified, wantETag: true},
+		{name: "wildcard", path: "/users/ada%40example.com", ifNoneMatch: "*", wantStatus: http.StatusNotModified, wantETag: true},
+		{name: "mismatch", path: "/users/ada%40example.com", ifNoneMatch: `"2"`, wantStatus: http.StatusOK, wantETag: true},
+		{name: "list unaffected", path: "/users", ifNoneMatch: `"3"`, wantStatus: http.StatusOK},
+		{name: "list unaffected by wildcard", path: "/users", ifNoneMatch: "*", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := user.NewMemoryRepository()
+			if err := repo.Create(&user.User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 3}); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: tt.path,
+				Headers: map[string]string{"If-None-Match": tt.ifNoneMatch}}
+
+			resp, err := GetUser(req, repo, nil)
+			if err != nil {
+				t.Fatalf("GetUser() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			if etag := resp.Headers["ETag"]; (etag == `"3"`) != tt.wantETag {
+				t.Errorf("ETag = %q, want it %v", etag, tt.wantETag)
+			}
+			if tt.wantStatus == http.StatusNotModified && resp.Body != "" {
+				t.Errorf("304 body = %q, want it empty", resp.Body)
+			}
+		})
+	}
+}
+
+func TestCreateUserLocation(t *testing.T) {
+	tests := []struct {
+		name         string
+		email        string
+		path         string
+		stage        string
+		contextPath  string
+		wantLocation string
+	}{
+		{name: "plain", email: "ada@example.com", path: "/users", wantLocation: "/users/ada@example.com"},
+		{name: "plus", email: "ada+news@example.com", path: "/users", wantLocation: "/users/ada%2Bnews@example.com"},
+		{name: "stage", email: "ada@example.com", path: "/users", stage: "prod", wantLocation: "/prod/users/ada@example.com"},
+		{name: "default stage", email: "ada@example.com", path: "/users", stage: "$default",
+			wantLocation: "/users/ada@example.com"},
+		{name: "called path", email: "ada+news@example.com", path: "/users", stage: "prod", contextPath: "/prod/v2/users/",
+			wantLocation: "/prod/v2/users/ada%2Bnews@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: tt.path,
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    `{"email": "` + tt.email + `", "firstname": "Ada", "lastname": "Lovelace"}`}
+			req.RequestContext.Stage = tt.stage
+			req.RequestContext.Path = tt.contextPath
+
+			resp, err := CreateUser(req, user.NewMemoryRepository(), nil)
+			if err != nil {
+				t.Fatalf("CreateUser() error = %v", err)
+			}
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, http.StatusCreated, resp.Body)
+			}
+			if location := resp.Headers["Location"]; location != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", location, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestUserLocationEncoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		email       string
+		wantSegment string
+	}{
+		{name: "plain", email: "ada@example.com", wantSegment: "ada@example.com"},
+		{name: "plus", email: "ada+news@example.com", wantSegment: "ada%2Bnews@example.com"},
+		{name: "unicode local part", email: "josé@example.com", wantSegment: "jos%C3%A9@example.com"},
+		{name: "unicode domain", email: "ada@bücher.example", wantSegment: "ada@b%C3%BCcher.example"},
+		{name: "slash", email: "a/b@example.com", wantSegment: "a%2Fb@example.com"},
+		{name: "space", email: "a b@example.com", wantSegment: "a%20b@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/users"}
+			if got, want := userLocation(req, tt.email), "/users/"+tt.wantSegment; got != want {
+				t.Errorf("userLocation() = %q, want %q", got, want)
+			}
+		})
+	}
+}
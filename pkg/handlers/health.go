@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"time"
+)
+
+// HealthPath is the path of the health check route
+const HealthPath = "/health"
+
+// healthCheckTimeout bounds the DynamoDB call so a stuck check can't use up the Lambda timeout
+const healthCheckTimeout = 2 * time.Second
+
+// Failure reasons reported by the health check
+var (
+	ErrorTableNotFound       = "table not found"
+	ErrorDynamoDBUnreachable = "dynamodb unreachable"
+)
+
+// HealthBody represents the health check response
+type HealthBody struct {
+	Status          string `json:"status"`                    // "ok" or "unavailable"
+	Table           string `json:"table"`                     // The checked table
+	ItemCountApprox *int64 `json:"itemCountApprox,omitempty"` // Item count, refreshed by DynamoDB about every 6 hours
+	Error           string `json:"error,omitempty"`           // Failure reason when unavailable
+}
+
+// IsHealthCheck reports whether the request targets the health check route.
+func IsHealthCheck(req events.APIGatewayProxyRequest) bool {
+	return (req.HTTPMethod == http.MethodGet || req.HTTPMethod == http.MethodHead) &&
+		(req.Path == HealthPath || req.Resource == HealthPath)
+}
+
+// Health handles GET /health by describing the users table.
+// It is not authenticated so monitoring can call it.
+//
+// Parameters:
+// - ctx: The invocation context.
+// - req: APIGatewayProxyRequest, used for its request ID.
+// - tableName: DynamoDB table name to check.
+// - dynaClient: DynamoDB client interface.
+//
+// Returns:
+// - APIGatewayProxyResponse with 200 if the table is reachable, or 503 with the failure reason.
+func Health(ctx context.Context, req events.APIGatewayProxyRequest, tableName string,
+	dynaClient dynamodbiface.DynamoDBAPI) (*events.APIGatewayProxyResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	out, err := dynaClient.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		reason := ErrorDynamoDBUnreachable
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+			reason = ErrorTableNotFound
+		}
+		logging.Default.Error("health check failed", logging.Fields{
+			"requestId": req.RequestContext.RequestID,
+			"table":     tableName,
+			"error":     err,
+		})
+		return APIResponse(http.StatusServiceUnavailable, HealthBody{Status: "unavailable", Table: tableName, Error: reason})
+	}
+
+	body := HealthBody{Status: "ok", Table: tableName}
+	if out.Table != nil {
+		body.ItemCountApprox = out.Table.ItemCount
+	}
+	return APIResponse(http.StatusOK, body)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"mime"
+	"net/http"
+)
+
+// ErrorNotCSV is returned when an import body isn't declared as CSV
+var ErrorNotCSV = "import body must be sent with Content-Type: text/csv"
+
+// ImportBody represents the response of a CSV import
+type ImportBody struct {
+	Results []user.ImportResult `json:"results"` // Outcome of each row, in document order
+	Created int                 `json:"created"` // Number of users created
+	Updated int                 `json:"updated"` // Number of existing users replaced
+	Skipped int                 `json:"skipped"` // Number of rows skipped
+	Failed  int                 `json:"failed"`  // Number of rows that failed
+}
+
+// ImportUsers handles POST /users/import, creating users from a text/csv body.
+// The onConflict query parameter chooses whether existing emails are skipped (the
+// default) or overwritten.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest containing the CSV document.
+// - repo: Repository where the users will be stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the outcome of each row, 400 for malformed CSV,
+//     415 for a non-CSV body, or 413 if there are too many rows.
+func ImportUsers(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	if mediaType, _, err := mime.ParseMediaType(headerValue(req, "Content-Type")); err != nil || mediaType != "text/csv" {
+		return APIError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, ErrorNotCSV)
+	}
+
+	req, invalid := decodeBody(req, maxBulkBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+
+	onConflict := req.QueryStringParameters["onConflict"]
+	if onConflict == "" {
+		onConflict = user.ImportSkip
+	}
+
+	results, err := user.ImportCSV(req.Body, onConflict, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+
+	body := ImportBody{Results: results}
+	var mutations []mutation
+	for _, result := range results {
+		switch result.Status {
+		case user.BatchCreated:
+			body.Created++
+			mutations = append(mutations, mutation{action: "created", email: result.Email, after: result.User})
+		case user.BatchUpdated:
+			body.Updated++
+			mutations = append(mutations, mutation{action: "updated", email: result.Email, after: result.User})
+		case user.BatchSkipped:
+			body.Skipped++
+		default:
+			body.Failed++
+		}
+	}
+	recordMutations(req, dynaClient, mutations...)
+
+	status := http.StatusMultiStatus
+	switch len(results) {
+	case body.Created:
+		status = http.StatusCreated
+	case body.Created + body.Updated, body.Skipped:
+		status = http.StatusOK
+	case body.Failed:
+		status = http.StatusBadRequest
+	}
+	return APIResponse(status, body)
+}
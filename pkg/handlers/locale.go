@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"embed"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultLocale is the language of the messages in the code, sent when the client accepts
+// no supported locale
+const defaultLocale = "en"
+
+// localeFiles holds a message catalog per locale besides English, named after the locale
+// and keyed by error code
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogs maps each locale to its messages, keyed by error code. English has none: its
+// messages are the ones the handlers send.
+var catalogs = loadCatalogs()
+
+// supportedLocales lists the locales error messages are sent in, the default first
+var supportedLocales = localeNames()
+
+// languageRange is a language range of an Accept-Language header, with its quality
+type languageRange struct {
+	tag     string
+	quality float64
+}
+
+// loadCatalogs reads the embedded message catalogs. They ship with the binary, so a
+// malformed one is a build error and panics at cold start.
+func loadCatalogs() map[string]map[ErrorCode]string {
+	files, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]map[ErrorCode]string, len(files))
+	for _, file := range files {
+		data, err := localeFiles.ReadFile("locales/" + file.Name())
+		if err != nil {
+			panic(err)
+		}
+		var messages map[ErrorCode]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic("locales/" + file.Name() + ": " + err.Error())
+		}
+		loaded[strings.TrimSuffix(file.Name(), path.Ext(file.Name()))] = messages
+	}
+	return loaded
+}
+
+// localeNames returns the default locale followed by those of the catalogs, in order.
+func localeNames() []string {
+	names := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		names = append(names, locale)
+	}
+	sort.Strings(names)
+	return append([]string{defaultLocale}, names...)
+}
+
+// parseAcceptLanguage splits an Accept-Language header into its language ranges, highest
+// quality first, keeping the order of the header between equal qualities. Tags are
+// lowercased, and ranges with a malformed quality are skipped.
+func parseAcceptLanguage(header string) []languageRange {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		quality, valid := 1.0, true
+		for _, param := range fields[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) != 2 || !strings.EqualFold(kv[0], "q") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+				break
+			}
+			quality = q
+		}
+		if valid {
+			ranges = append(ranges, languageRange{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+	return ranges
+}
+
+// PreferredLocale picks the supported locale the client ranks highest in its
+// Accept-Language header. A range with a region falls back to its language, so es-MX
+// selects es, and * selects the first supported locale the header doesn't refuse with q=0.
+// Requests without the header, or accepting no supported locale, get English.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the Accept-Language header.
+//
+// Returns:
+// - The chosen locale, e.g. "es".
+func PreferredLocale(req events.APIGatewayProxyRequest) string {
+	ranges := parseAcceptLanguage(headerValue(req, "Accept-Language"))
+	refused := map[string]bool{}
+	for _, r := range ranges {
+		if r.quality == 0 {
+			refused[r.tag] = true
+		}
+	}
+
+	for _, r := range ranges {
+		if r.quality == 0 {
+			break
+		}
+		if r.tag == "*" {
+			for _, locale := range supportedLocales {
+				if !refused[locale] {
+					return locale
+				}
+			}
+			continue
+		}
+		language := strings.SplitN(r.tag, "-", 2)[0]
+		if _, ok := catalogs[language]; ok || language == defaultLocale {
+			return language
+		}
+	}
+	return defaultLocale
+}
+
+// translate returns the message of locale for an error message. The message is looked up
+// by the specific code of the user error it is, if any, and otherwise by code; messages
+// without a translation are returned unchanged, in English.
+//
+// Parameters:
+// - locale: The locale to translate to.
+// - message: The English message.
+// - code: The code the message was sent with, or an empty code for field messages.
+//
+// Returns:
+// - The translated message, or message.
+func translate(locale string, message string, code ErrorCode) string {
+	if specific, ok := messageCode(message); ok {
+		code = specific
+	}
+	if translated := catalogs[locale][code]; translated != "" {
+		return translated
+	}
+	return message
+}
+
+// LocalizeResponse translates the messages of an error response to the locale the client
+// prefers in Accept-Language, and names it in Content-Language. Codes are left untouched,
+// so clients can keep matching on them. Error bodies that aren't an ErrorBody are left as
+// they are. It must run before the response is enveloped, negotiated or compressed.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest the response answers.
+// - resp: The response to translate, modified in place.
+func LocalizeResponse(req events.APIGatewayProxyRequest, resp *events.APIGatewayProxyResponse) {
+	if resp == nil || resp.StatusCode < http.StatusBadRequest || resp.IsBase64Encoded {
+		return
+	}
+	var body ErrorBody
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil || body.Code == nil {
+		return
+	}
+
+	locale := PreferredLocale(req)
+	if resp.Headers == nil {
+		resp.Headers = map[string]string{}
+	}
+	resp.Headers["Content-Language"] = locale
+	addVary(resp, "Accept-Language")
+	if locale == defaultLocale {
+		return
+	}
+
+	if body.ErrorMsg != nil {
+		body.ErrorMsg = aws.String(translate(locale, *body.ErrorMsg, ErrorCode(*body.Code)))
+	}
+	for i := range body.Fields {
+		body.Fields[i].Message = translate(locale, body.Fields[i].Message, "")
+	}
+	if encoded, err := json.Marshal(body); err == nil {
+		resp.Body = string(encoded)
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"testing"
+)
+
+func TestPreferredLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "no header", want: "en"},
+		{name: "empty", header: " ", want: "en"},
+		{name: "exact", header: "es", want: "es"},
+		{name: "region falls back to the language", header: "es-MX", want: "es"},
+		{name: "uppercase", header: "HI-IN", want: "hi"},
+		{name: "english", header: "en-GB", want: "en"},
+		{name: "unsupported", header: "fr-FR, de", want: "en"},
+		{name: "first supported wins", header: "fr, hi, es", want: "hi"},
+		{name: "quality", header: "es;q=0.5, hi;q=0.8", want: "hi"},
+		{name: "equal quality keeps the header order", header: "es;q=0.7, hi;q=0.7", want: "es"},
+		{name: "default quality is 1", header: "hi;q=0.9, es", want: "es"},
+		{name: "spaces around parameters", header: "hi ;q=0.2 , es ; q=0.4", want: "es"},
+		{name: "uppercase q", header: "es;Q=0.1, hi;Q=0.2", want: "hi"},
+		{name: "unsupported ranked higher", header: "fr;q=1, es;q=0.1", want: "es"},
+		{name: "refused", header: "es;q=0", want: "en"},
+		{name: "refused language, accepted region", header: "es;q=0, es-MX", want: "es"},
+		{name: "malformed quality is skipped", header: "es;q=high, hi;q=0.1", want: "hi"},
+		{name: "quality over 1 is skipped", header: "es;q=2, hi;q=0.1", want: "hi"},
+		{name: "negative quality is skipped", header: "es;q=-1", want: "en"},
+		{name: "other parameters ignored", header: "es;level=1;q=0.9", want: "es"},
+		{name: "empty ranges", header: ",,es,,", want: "es"},
+		{name: "wildcard", header: "*", want: "en"},
+		{name: "wildcard after unsupported", header: "fr, *;q=0.5", want: "en"},
+		{name: "wildcard with english refused", header: "en;q=0, *", want: "es"},
+		{name: "wildcard with everything refused", header: "en;q=0, es;q=0, hi;q=0, *", want: "en"},
+		{name: "garbage", header: ";;;q=,=", want: "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayProxyRequest{Headers: map[string]string{"accept-language": tt.header}}
+			if got := PreferredLocale(req); got != tt.want {
+				t.Errorf("PreferredLocale(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalogs(t *testing.T) {
+	documented := map[ErrorCode]bool{}
+	for _, info := range AllErrorCodes() {
+		documented[info.Code] = true
+	}
+	for _, locale := range []string{"es", "hi"} {
+		messages, ok := catalogs[locale]
+		if !ok {
+			t.Fatalf("no catalog for %s", locale)
+		}
+		for code, message := range messages {
+			if !documented[code] {
+				t.Errorf("%s translates %s, which isn't an error code", locale, code)
+			}
+			if message == "" {
+				t.Errorf("%s translates %s to an empty message", locale, code)
+			}
+		}
+	}
+}
+
+func TestLocalizeResponse(t *testing.T) {
+	repo := user.NewMemoryRepository()
+	tests := []struct {
+		name         string
+		locale       string
+		send         func() (*events.APIGatewayProxyResponse, error)
+		missing      ErrorCode // Translation removed from the catalog for the test
+		wantCode     string
+		wantMessage  string
+		wantFields   []string
+		wantLanguage string
+	}{
+		{name: "spanish", locale: "es-MX,es;q=0.9,en;q=0.5",
+			send: func() (*events.APIGatewayProxyResponse, error) {
+				return GetUser(events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users/nobody%40example.com",
+					PathParameters: map[string]string{"email": "nobody%40example.com"}}, repo, nil)
+			},
+			wantCode: string(CodeNotFound), wantMessage: "El usuario no existe.", wantLanguage: "es"},
+		{name: "hindi fields", locale: "hi",
+			send: func() (*events.APIGatewayProxyResponse, error) {
+				return CreateUser(events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/users",
+					Body: `{"email": "not an email", "firstname": "Ada", "lastname": "Lovelace"}`}, repo, nil)
+			},
+			wantCode: string(CodeValidation), wantFields: []string{"ईमेल अमान्य है।"}, wantLanguage: "hi"},
+		{name: "missing translation falls back to english", locale: "es", missing: CodeUserNotFound,
+			send: func() (*events.APIGatewayProxyResponse, error) {
+				return GetUser(events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users/nobody%40example.com",
+					PathParameters: map[string]string{"email": "nobody%40example.com"}}, repo, nil)
+			},
+			wantCode: string(CodeNotFound), wantMessage: user.ErrorUserDoesNotExist, wantLanguage: "es"},
+		{name: "english", locale: "fr, en",
+			send: func() (*events.APIGatewayProxyResponse, error) {
+				return GetUser(events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users/nobody%40example.com",
+					PathParameters: map[string]string{"email": "nobody%40example.com"}}, repo, nil)
+			},
+			wantCode: string(CodeNotFound), wantMessage: user.ErrorUserDoesNotExist, wantLanguage: "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.missing != "" {
+				for _, messages := range catalogs {
+					if translated, ok := messages[tt.missing]; ok {
+						delete(messages, tt.missing)
+						defer func(messages map[ErrorCode]string) { messages[tt.missing] = translated }(messages)
+					}
+				}
+			}
+			resp, err := tt.send()
+			if err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+
+			LocalizeResponse(events.APIGatewayProxyRequest{Headers: map[string]string{"Accept-Language": tt.locale}}, resp)
+			var body ErrorBody
+			if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+				t.Fatalf("body %s isn't an error: %v", resp.Body, err)
+			}
+			if body.Code == nil || *body.Code != tt.wantCode {
+				t.Errorf("code of %s, want %s untouched", resp.Body, tt.wantCode)
+			}
+			if tt.wantMessage != "" && (body.ErrorMsg == nil || *body.ErrorMsg != tt.wantMessage) {
+				t.Errorf("message of %s, want %q", resp.Body, tt.wantMessage)
+			}
+			if len(body.Fields) != len(tt.wantFields) {
+				t.Fatalf("fields of %s, want %q", resp.Body, tt.wantFields)
+			}
+			for i, message := range tt.wantFields {
+				if body.Fields[i].Message != message {
+					t.Errorf("field %s message = %q, want %q", body.Fields[i].Field, body.Fields[i].Message, message)
+				}
+			}
+			if resp.Headers["Content-Language"] != tt.wantLanguage || resp.Headers["Vary"] != "Accept-Language" {
+				t.Errorf("Content-Language = %q, Vary = %q; want %q and Accept-Language", resp.Headers["Content-Language"],
+					resp.Headers["Vary"], tt.wantLanguage)
+			}
+		})
+	}
+}
+
+func TestLocalizeResponseLeavesOtherResponses(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *events.APIGatewayProxyResponse
+	}{
+		{name: "success", resp: &events.APIGatewayProxyResponse{StatusCode: http.StatusOK,
+			Body: `{"error": "not an error", "code": "NOT_FOUND"}`}},
+		{name: "not json", resp: &events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: "bad request"}},
+		{name: "no code", resp: &events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: `{"error": "bad"}`}},
+		{name: "compressed", resp: &events.APIGatewayProxyResponse{StatusCode: http.StatusNotFound, IsBase64Encoded: true,
+			Body: "H4sIAAAAAAAA"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.resp.Body
+			LocalizeResponse(events.APIGatewayProxyRequest{Headers: map[string]string{"Accept-Language": "es"}}, tt.resp)
+			if tt.resp.Body != body || tt.resp.Headers["Content-Language"] != "" {
+				t.Errorf("response = %+v, want it unchanged", tt.resp)
+			}
+		})
+	}
+	LocalizeResponse(events.APIGatewayProxyRequest{}, nil)
+}
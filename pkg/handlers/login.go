@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/auth"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginPath is the path of the login route
+const LoginPath = "/login"
+
+// Failed logins are tracked per email for the container lifetime. Past maxLoginFailures,
+// every attempt on the email is slowed down by loginFailureDelay to blunt password guessing.
+const (
+	maxLoginFailures  = 5
+	loginFailureDelay = 2 * time.Second
+	maxTrackedLogins  = 10000 // Emails tracked at once, so guessing random emails can't exhaust memory
+)
+
+// defaultTokenTTL is how long issued tokens are valid unless JWT_TTL_MINUTES says otherwise
+const defaultTokenTTL = time.Hour
+
+// Error messages for logins
+var (
+	ErrorLoginDisabled   = "login is disabled; set JWT_SIGNING_KEY, JWT_SECRET or JWT_PRIVATE_KEY"
+	ErrorInvalidLoginKey = "JWT_PRIVATE_KEY is not a PEM-encoded RSA private key"
+)
+
+// LoginBody represents the login response
+type LoginBody struct {
+	Token     string `json:"token" xml:"token"`         // The signed JWT
+	TokenType string `json:"tokenType" xml:"tokenType"` // Always "Bearer"
+	ExpiresIn int64  `json:"expiresIn" xml:"expiresIn"` // Seconds until the token expires
+}
+
+// loginFailures counts the failed logins of each normalized email
+var loginFailures = struct {
+	sync.Mutex
+	counts map[string]int
+}{counts: map[string]int{}}
+
+// loginKeyCache keeps the parsed JWT_PRIVATE_KEY, reparsed only when the variable changes
+var loginKeyCache = struct {
+	sync.Mutex
+	pem   string
+	key   *rsa.PrivateKey
+	keyID string
+}{}
+
+// IsLoginRequest reports whether the request is a POST to the login route.
+func IsLoginRequest(req events.APIGatewayProxyRequest) bool {
+	return req.HTTPMethod == http.MethodPost && (req.Path == LoginPath || req.Resource == LoginPath)
+}
+
+// Login handles POST /login, exchanging {"email": "...", "password": "..."} for a signed
+// JWT accepted by RequireAuth. It is not authenticated, since it is how callers get a token.
+// Unknown emails and wrong passwords get the same 401.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest with the credentials in the body.
+// - repo: Repository where the user data is stored.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the token, 400 for a malformed body, 401 if the
+//     credentials don't match, or 404 if no signing key is configured.
+func Login(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+	if hmacSecret() == "" && os.Getenv("JWT_PRIVATE_KEY") == "" {
+		return APIError(http.StatusNotFound, CodeNotFound, ErrorLoginDisabled)
+	}
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+	req, invalid := decodeBody(req, maxBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+	creds, err := user.ParseCredentials(req.Body)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+
+	email := user.NormalizeEmail(creds.Email)
+	if loginFailureCount(email) >= maxLoginFailures {
+		time.Sleep(loginFailureDelay)
+	}
+	u, err := user.Authenticate(email, creds.Password, repo)
+	if errors.Is(err, user.ErrUnauthorized) {
+		recordLoginFailure(email)
+	}
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	resetLoginFailures(email)
+
+	ttl := tokenTTL()
+	token, err := issueToken(u, time.Now(), ttl)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	return APIResponse(http.StatusOK, LoginBody{Token: token, TokenType: "Bearer", ExpiresIn: int64(ttl / time.Second)},
+		map[string]string{"Cache-Control": "no-store"})
+}
+
+// loginFailureCount returns the number of failed logins of an email.
+func loginFailureCount(email string) int {
+	loginFailures.Lock()
+	defer loginFailures.Unlock()
+	return loginFailures.counts[email]
+}
+
+// recordLoginFailure counts a failed login of an email, forgetting every email once too
+// many are tracked.
+func recordLoginFailure(email string) {
+	loginFailures.Lock()
+	defer loginFailures.Unlock()
+	if _, ok := loginFailures.counts[email]; !ok && len(loginFailures.counts) >= maxTrackedLogins {
+		loginFailures.counts = map[string]int{}
+	}
+	loginFailures.counts[email]++
+}
+
+// resetLoginFailures forgets the failed logins of an email after it logs in.
+func resetLoginFailures(email string) {
+	loginFailures.Lock()
+	defer loginFailures.Unlock()
+	delete(loginFailures.counts, email)
+}
+
+// tokenTTL returns how long issued tokens are valid: JWT_TTL_MINUTES, or an hour.
+func tokenTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("JWT_TTL_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return defaultTokenTTL
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// issueToken signs a JWT for a user with RS256 when JWT_PRIVATE_KEY is set, or HS256 with
+// the HMAC secret otherwise. The token grants the read and write scopes, plus the admin
+// scope for administrators, and names the user in "sub" and "email": the scopes decide which
+// operations it may call, and its email limits the operations on a single user to that user.
+//
+// Parameters:
+// - u: The user.
+// - now: The issue time.
+// - ttl: How long the token is valid.
+//
+// Returns:
+// - The compact JWT.
+// - An error if the private key can't be parsed or signing fails.
+func issueToken(u *user.User, now time.Time, ttl time.Duration) (string, error) {
+	scope := auth.ScopeRead + " " + auth.ScopeWrite
+	if u.CurrentRole() == user.RoleAdmin {
+		scope += " " + auth.ScopeAdmin
+	}
+	claims := map[string]interface{}{
+		"sub":   u.Email,
+		"email": u.Email,
+		"scope": scope,
+		"iat":   now.Unix(),
+		"exp":   now.Add(ttl).Unix(),
+	}
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		claims["iss"] = issuer
+	}
+
+	key, keyID, err := loginKey()
+	if err != nil {
+		return "", err
+	}
+	header := jwtHeader{Algorithm: "HS256"}
+	if key != nil {
+		header = jwtHeader{Algorithm: "RS256", KeyID: keyID}
+	}
+
+	encodedHeader, err := encodeSegment(header)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodedHeader + "." + encodedClaims
+
+	var signature []byte
+	if key != nil {
+		digest := sha256.Sum256([]byte(signingInput))
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			return "", err
+		}
+	} else {
+		mac := hmac.New(sha256.New, []byte(hmacSecret()))
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// encodeSegment marshals v as JSON and base64url-encodes it as a token segment.
+func encodeSegment(v interface{}) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// loginKey returns the RSA private key in JWT_PRIVATE_KEY (PKCS #1 or PKCS #8 PEM) and its
+// key ID, a digest of its modulus.
+//
+// Returns:
+// - The key and its ID, or a nil key if JWT_PRIVATE_KEY is unset.
+// - An error if the variable doesn't hold an RSA private key.
+func loginKey() (*rsa.PrivateKey, string, error) {
+	encoded := os.Getenv("JWT_PRIVATE_KEY")
+	if encoded == "" {
+		return nil, "", nil
+	}
+
+	loginKeyCache.Lock()
+	defer loginKeyCache.Unlock()
+	if loginKeyCache.key != nil && loginKeyCache.pem == encoded {
+		return loginKeyCache.key, loginKeyCache.keyID, nil
+	}
+
+	// Environment variables often carry the PEM with escaped newlines
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(encoded, `\n`, "\n")))
+	if block == nil {
+		return nil, "", errors.New(ErrorInvalidLoginKey)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if err8 != nil || !ok {
+			return nil, "", errors.New(ErrorInvalidLoginKey)
+		}
+		key = rsaKey
+	}
+
+	digest := sha256.Sum256(key.PublicKey.N.Bytes())
+	loginKeyCache.pem, loginKeyCache.key, loginKeyCache.keyID = encoded, key, hex.EncodeToString(digest[:8])
+	return key, loginKeyCache.keyID, nil
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+)
+
+// IsMergeRequest reports whether the request targets /users/merge.
+func IsMergeRequest(req events.APIGatewayProxyRequest) bool {
+	return isSubresource(req, mergePath)
+}
+
+// MergeUsers handles POST /users/merge with {"primary": "a@x.com", "duplicate": "b@x.com"},
+// merging the duplicate account into the primary one and removing it, for administrators
+// only. The merge is audited as "merged" on the primary and "deleted" on the duplicate.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest naming both users in the body.
+// - repo: Repository where the user data is stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the merged primary and its Location, 400 for a merge
+//     of a user into itself, 404 if either user doesn't exist, or 412 if either changed
+//     while being merged.
+func MergeUsers(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	fields, err := responseFields(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+	req, invalid := decodeBody(req, maxBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+
+	merged, primary, duplicate, err := user.MergeUsers(req.Body, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	recordMutations(req, dynaClient,
+		mutation{action: "merged", email: merged.Email, before: primary, after: merged},
+		mutation{action: "deleted", email: duplicate.Email, before: duplicate})
+	resp, err := APIResponse(http.StatusOK, selectUser(merged, fields), map[string]string{
+		"Location": userLocation(req, merged.Email),
+		"ETag":     versionETag(merged.Version),
+	})
+	return resp, err
+}
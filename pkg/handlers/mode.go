@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// Error messages for the service modes
+var (
+	ErrorReadOnly    = "writes are temporarily disabled; the API is read-only"
+	ErrorMaintenance = "the API is down for maintenance"
+)
+
+// writeMethods are the HTTP methods rejected in read-only mode
+var writeMethods = map[string]bool{
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// CheckServiceMode rejects requests the current service mode doesn't allow. With
+// MAINTENANCE_MODE=true every request is rejected; with READ_ONLY=true writes are,
+// including logins. The flags are read on every call, so changing the function's
+// environment takes effect without a deploy. The health check is routed before
+// this, so it keeps working in both modes.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to check.
+//
+// Returns:
+//   - A 503 response if the mode doesn't allow the request, carrying a Retry-After header
+//     from READ_ONLY_RETRY_AFTER in read-only mode, or nil if it may proceed.
+func CheckServiceMode(req events.APIGatewayProxyRequest) *events.APIGatewayProxyResponse {
+	if os.Getenv("MAINTENANCE_MODE") == "true" {
+		resp, _ := APIError(http.StatusServiceUnavailable, CodeServiceUnavailable, ErrorMaintenance)
+		return resp
+	}
+	if os.Getenv("READ_ONLY") != "true" || !writeMethods[req.HTTPMethod] {
+		return nil
+	}
+
+	resp, _ := APIError(http.StatusServiceUnavailable, CodeServiceUnavailable, ErrorReadOnly)
+	if seconds, err := strconv.Atoi(os.Getenv("READ_ONLY_RETRY_AFTER")); err == nil && seconds > 0 {
+		resp.Headers["Retry-After"] = strconv.Itoa(seconds)
+	}
+	return resp
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Media types a response can be serialized as
+const (
+	MediaTypeJSON = "application/json"
+	MediaTypeXML  = "application/xml"
+	MediaTypeCSV  = "text/csv"
+)
+
+// xmlRoot is the root element of XML bodies, and xmlItem the element of each list entry
+const (
+	xmlRoot = "response"
+	xmlItem = "item"
+)
+
+// ErrorNotAcceptable is returned when STRICT_ACCEPT is set and no media type the client
+// accepts can be produced
+var ErrorNotAcceptable = "response can only be sent as application/json, application/xml, or text/csv for lists"
+
+// bodyTypes are the media types of every response, and listTypes those of user lists,
+// in the order preferred when a client ranks several equally
+var (
+	bodyTypes = []string{MediaTypeJSON, MediaTypeXML}
+	listTypes = []string{MediaTypeJSON, MediaTypeXML, MediaTypeCSV}
+)
+
+// acceptRange is one media range of an Accept header with its quality value
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept reads the media ranges of an Accept header, skipping malformed ones. The v2
+// media type is read as JSON and text/xml as XML, since both get the same serialization.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		// ParseMediaType lower-cases the media type
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case MediaTypeV2:
+			mediaType = MediaTypeJSON
+		case "text/xml":
+			mediaType = MediaTypeXML
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil || quality < 0 || quality > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+	return ranges
+}
+
+// rangeSpecificity ranks how closely a media range matches a media type: 3 for an exact
+// match, 2 for type/*, 1 for */*, and 0 for no match.
+func rangeSpecificity(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 3
+	case mediaRange == "*/*":
+		return 1
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 2
+	}
+	return 0
+}
+
+// preferredType picks the offered media type the client ranks highest in its Accept
+// header. Each offer takes the quality of the most specific range matching it, and ties go
+// to the earlier offer. Requests without an Accept header get the first offer.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the Accept header.
+// - offers: The media types the response can be sent as, most preferred first.
+//
+// Returns:
+// - The chosen media type, or "" if the client accepts none of the offers.
+func preferredType(req events.APIGatewayProxyRequest, offers []string) string {
+	ranges := parseAccept(headerValue(req, "Accept"))
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		quality, specificity := 0.0, 0
+		for _, r := range ranges {
+			if s := rangeSpecificity(r.mediaType, offer); s > specificity {
+				quality, specificity = r.quality, s
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+	return best
+}
+
+// wantsCSV reports whether a user list should be sent as CSV.
+func wantsCSV(req events.APIGatewayProxyRequest) bool {
+	return preferredType(req, listTypes) == MediaTypeCSV
+}
+
+// csvResponse sends a list of users as CSV with the columns of user.CSVColumns. Field
+// selection doesn't apply. Non-ASCII bodies are sent base64-encoded like exports.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest the list answers.
+// - users: The users to send, in order.
+//
+// Returns:
+// - APIGatewayProxyResponse with the CSV body.
+func csvResponse(req events.APIGatewayProxyRequest, users []user.User) (*events.APIGatewayProxyResponse, error) {
+	var buf bytes.Buffer
+	if err := user.WriteCSV(&buf, users); err != nil {
+		return errorResponse(req, err)
+	}
+
+	resp := &events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": MediaTypeCSV + "; charset=utf-8"},
+		Body:       buf.String(),
+	}
+	addVary(resp, "Accept")
+	if !isASCII(buf.Bytes()) {
+		resp.Body = base64.StdEncoding.EncodeToString(buf.Bytes())
+		resp.IsBase64Encoded = true
+	}
+	return resp, nil
+}
+
+// NegotiateResponse serializes a JSON response as XML when the client prefers it. Clients
+// accepting neither JSON nor XML keep JSON, unless STRICT_ACCEPT is "true" and they get a
+// 406 instead. Health checks, empty and non-JSON responses such as CSV lists are left as
+// they are. It must run after the envelope and before the response is compressed.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest carrying the Accept header.
+// - resp: The response to serialize, modified in place.
+func NegotiateResponse(req events.APIGatewayProxyRequest, resp *events.APIGatewayProxyResponse) {
+	if resp == nil || resp.Body == "" || IsHealthCheck(req) || resp.IsBase64Encoded ||
+		!strings.HasPrefix(resp.Headers["Content-Type"], MediaTypeJSON) {
+		return
+	}
+	addVary(resp, "Accept")
+
+	switch preferredType(req, bodyTypes) {
+	case MediaTypeJSON:
+		return
+	case MediaTypeXML:
+		body, err := jsonToXML([]byte(resp.Body))
+		if err != nil {
+			logging.Default.Error("failed to serialize response as XML", logging.Fields{"status": resp.StatusCode, "error": err})
+			return
+		}
+		resp.Headers["Content-Type"] = MediaTypeXML + "; charset=utf-8"
+		resp.Body = string(body)
+	default:
+		if os.Getenv("STRICT_ACCEPT") != "true" {
+			return
+		}
+		notAcceptable, _ := APIError(http.StatusNotAcceptable, CodeNotAcceptable, ErrorNotAcceptable)
+		notAcceptable.Headers["Vary"] = resp.Headers["Vary"]
+		*resp = *notAcceptable
+		EnvelopeResponse(req, resp)
+	}
+}
+
+// jsonToXML rewrites a JSON document as XML under a <response> root. Object members
+// become elements named after their keys, list entries <item> elements, and nulls are
+// left out, so an object body unmarshals into the same struct as its JSON.
+//
+// Parameters:
+// - body: The JSON document.
+//
+// Returns:
+// - The XML document, with an XML declaration.
+// - An error if the JSON is malformed.
+func jsonToXML(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	if err := writeXMLValue(decoder, encoder, xmlRoot); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeXMLValue reads the next JSON value from decoder and writes it as an element.
+func writeXMLValue(decoder *json.Decoder, encoder *xml.Encoder, name string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch value := token.(type) {
+	case nil:
+		return nil
+	case json.Delim:
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		for decoder.More() {
+			childName := xmlItem
+			if value == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				childName = xmlName(key.(string))
+			}
+			if err := writeXMLValue(decoder, encoder, childName); err != nil {
+				return err
+			}
+		}
+		// Consume the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+		return encoder.EncodeToken(start.End())
+	default:
+		return encoder.EncodeElement(fmt.Sprint(value), start)
+	}
+}
+
+// xmlName returns a JSON key as an element name, or "field" if the key isn't a valid one.
+func xmlName(key string) string {
+	if key == "" || strings.HasPrefix(strings.ToLower(key), "xml") {
+		return "field"
+	}
+	for i, r := range key {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r) && r != '-' && r != '.') {
+			return "field"
+		}
+	}
+	return key
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreferredType(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{name: "missing", want: MediaTypeJSON},
+		{name: "json", accept: "application/json", want: MediaTypeJSON},
+		{name: "xml", accept: "application/xml", want: MediaTypeXML},
+		{name: "text xml", accept: "text/xml", want: MediaTypeXML},
+		{name: "csv", accept: "text/csv", want: MediaTypeCSV},
+		{name: "wildcard", accept: "*/*", want: MediaTypeJSON},
+		{name: "highest quality", accept: "application/json;q=0.5, application/xml;q=0.9", want: MediaTypeXML},
+		{name: "equal quality keeps the preferred order", accept: "text/csv, application/xml", want: MediaTypeXML},
+		{name: "specific range beats the wildcard", accept: "*/*;q=0.1, text/csv", want: MediaTypeCSV},
+		{name: "type wildcard", accept: "text/*", want: MediaTypeCSV},
+		{name: "refused", accept: "application/json;q=0, text/csv", want: MediaTypeCSV},
+		{name: "malformed quality skipped", accept: "application/xml;q=2, text/csv", want: MediaTypeCSV},
+		{name: "unsupported", accept: "image/png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayProxyRequest{Headers: map[string]string{"Accept": tt.accept}}
+			if got := preferredType(req, listTypes); got != tt.want {
+				t.Errorf("preferredType(%q) = %q, want %q", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	ada := user.User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace",
+		ExpiresAt: user.Expiry(time.Now().Add(24 * time.Hour).Unix()),
+		Address:   &user.Address{Line1: "12 St James's Square", City: "London", PostalCode: "SW1Y 4JH", Country: "GB"},
+		Tags:      user.Tags{"plan": "pro", "team": "engines"}}
+	grace := user.User{Email: "grace@example.com", FirstName: "Grace", LastName: "Hopper"}
+
+	tests := []struct {
+		name       string
+		path       string
+		email      string
+		wantStatus int
+		decode     func(t *testing.T, body []byte, repo user.Repository)
+	}{
+		{name: "user", path: "/users/ada%40example.com", email: "ada@example.com", wantStatus: http.StatusOK,
+			decode: func(t *testing.T, body []byte, repo user.Repository) {
+				var got user.User
+				if err := xml.Unmarshal(body, &got); err != nil {
+					t.Fatalf("body %s isn't a user: %v", body, err)
+				}
+				want, _ := repo.Get("ada@example.com", user.ReadOptions{})
+				if !reflect.DeepEqual(&got, want) {
+					t.Errorf("decoded %+v, want %+v", got, *want)
+				}
+			}},
+		{name: "list", path: "/users", wantStatus: http.StatusOK,
+			decode: func(t *testing.T, body []byte, repo user.Repository) {
+				var got struct {
+					Items []user.User `xml:"item"`
+				}
+				if err := xml.Unmarshal(body, &got); err != nil {
+					t.Fatalf("body %s isn't a list of users: %v", body, err)
+				}
+				if len(got.Items) != 2 || got.Items[0].Email != ada.Email || got.Items[1].Email != grace.Email ||
+					!reflect.DeepEqual(got.Items[0].Tags, ada.Tags) {
+					t.Errorf("decoded %+v, want ada and grace", got.Items)
+				}
+			}},
+		{name: "error", path: "/users/alan%40example.com", email: "alan@example.com", wantStatus: http.StatusNotFound,
+			decode: func(t *testing.T, body []byte, repo user.Repository) {
+				var got ErrorBody
+				if err := xml.Unmarshal(body, &got); err != nil {
+					t.Fatalf("body %s isn't an error: %v", body, err)
+				}
+				if got.ErrorMsg == nil || *got.ErrorMsg != user.ErrorUserDoesNotExist ||
+					got.Code == nil || *got.Code != string(CodeNotFound) {
+					t.Errorf("decoded %+v, want the not found error", got)
+				}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := user.NewMemoryRepository()
+			for _, u := range []user.User{ada, grace} {
+				u := u
+				if err := repo.Create(&u); err != nil {
+					t.Fatalf("seeding: %v", err)
+				}
+			}
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: tt.path,
+				Headers: map[string]string{"Accept": "application/json;q=0.5, application/xml"}}
+			if tt.email != "" {
+				req.PathParameters = map[string]string{"email": tt.email}
+			}
+
+			resp, err := GetUser(req, repo, nil)
+			if err != nil {
+				t.Fatalf("GetUser() error = %v", err)
+			}
+			NegotiateResponse(req, resp)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			if !strings.HasPrefix(resp.Headers["Content-Type"], MediaTypeXML) {
+				t.Fatalf("Content-Type = %q, want %s", resp.Headers["Content-Type"], MediaTypeXML)
+			}
+			tt.decode(t, []byte(resp.Body), repo)
+		})
+	}
+}
+
+func TestNotAcceptable(t *testing.T) {
+	tests := []struct {
+		name       string
+		strict     bool
+		wantStatus int
+	}{
+		{name: "lenient", wantStatus: http.StatusOK},
+		{name: "strict", strict: true, wantStatus: http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.strict {
+				t.Setenv("STRICT_ACCEPT", "true")
+			}
+			repo := user.NewMemoryRepository()
+			if err := repo.Create(&user.User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace"}); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users/ada%40example.com",
+				PathParameters: map[string]string{"email": "ada@example.com"},
+				Headers:        map[string]string{"Accept": "image/png"}}
+
+			resp, err := GetUser(req, repo, nil)
+			if err != nil {
+				t.Fatalf("GetUser() error = %v", err)
+			}
+			NegotiateResponse(req, resp)
+			if resp.StatusCode != tt.wantStatus || !strings.HasPrefix(resp.Headers["Content-Type"], MediaTypeJSON) {
+				t.Errorf("response = %d %s, want %d as JSON", resp.StatusCode, resp.Headers["Content-Type"], tt.wantStatus)
+			}
+		})
+	}
+}
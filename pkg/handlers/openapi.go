@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// OpenAPIPath is the path of the route serving the OpenAPI document
+const OpenAPIPath = "/openapi.json"
+
+// openAPIVersion is the version of the OpenAPI specification the document follows
+const openAPIVersion = "3.0.3"
+
+// schemaRefPrefix starts the references to the schemas of the document's components
+const schemaRefPrefix = "#/components/schemas/"
+
+// requestErrors are the codes any request may fail with, and bodyErrors those any request
+// with a body may fail with
+var (
+	requestErrors = []ErrorCode{CodeUnauthorized, CodeForbidden, CodeTooManyRequests, CodeInternal,
+		CodeServiceUnavailable}
+	bodyErrors = []ErrorCode{CodeInvalidRequest, CodePayloadTooLarge, CodeUnsupportedMediaType}
+)
+
+// schemaOverrides gives the schema of types whose JSON encoding differs from their Go type
+var schemaOverrides = map[reflect.Type]map[string]interface{}{
+	reflect.TypeOf(user.Expiry(0)): {"type": "string", "format": "date-time"},
+}
+
+// pageHeaders describes the headers of a page of a list
+var pageHeaders = map[string]interface{}{
+	NextCursorHeader: map[string]interface{}{
+		"description": "Cursor of the next page, absent on the last one",
+		"schema":      map[string]interface{}{"type": "string"},
+	},
+	TotalCountHeader: map[string]interface{}{
+		"description": "Number of users the filters select, unless skipCount=true",
+		"schema":      map[string]interface{}{"type": "integer"},
+	},
+	PageSizeHeader: map[string]interface{}{
+		"description": "Most users a page holds",
+		"schema":      map[string]interface{}{"type": "integer"},
+	},
+	TruncatedHeader: map[string]interface{}{
+		"description": "true if the list stopped at MAX_LIST_ITEMS",
+		"schema":      map[string]interface{}{"type": "string"},
+	},
+}
+
+// isOpenAPIRequest reports whether the request targets the OpenAPI document.
+func isOpenAPIRequest(req events.APIGatewayProxyRequest) bool {
+	return req.Path == OpenAPIPath || req.Resource == OpenAPIPath
+}
+
+// OpenAPIDocument handles GET /openapi.json, describing the API as an OpenAPI 3.0 document.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest for the document.
+// - repo: Repository where user data is stored (unused).
+// - dynaClient: DynamoDB client interface (unused).
+//
+// Returns:
+// - APIGatewayProxyResponse with the document.
+func OpenAPIDocument(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	return APIResponse(http.StatusOK, OpenAPI())
+}
+
+// OpenAPI generates the OpenAPI 3.0 document of the API from the route table. The schemas
+// of the bodies are derived from their Go types through their JSON tags, so the document
+// can't drift from the code.
+//
+// Returns:
+// - The document, ready to be encoded as JSON.
+func OpenAPI() map[string]interface{} {
+	spec := &specBuilder{schemas: map[string]interface{}{}, names: map[reflect.Type]string{}}
+	spec.schemas["ErrorEnvelope"] = map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"error": spec.schema(reflect.TypeOf(EnvelopeError{}))},
+	}
+	spec.errorContent = map[string]interface{}{
+		MediaTypeJSON: map[string]interface{}{"schema": spec.schema(reflect.TypeOf(ErrorBody{}))},
+		MediaTypeV2:   map[string]interface{}{"schema": map[string]interface{}{"$ref": schemaRefPrefix + "ErrorEnvelope"}},
+	}
+
+	paths := map[string]interface{}{}
+	for _, route := range Routes() {
+		item := map[string]interface{}{}
+		if params := pathParameters(route.Template); len(params) > 0 {
+			item["parameters"] = params
+		}
+		for _, method := range methodOrder {
+			if operation, ok := route.Methods[method]; ok {
+				item[strings.ToLower(method)] = spec.operation(method, route.Template, operation)
+			}
+		}
+		paths[route.Template] = item
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "Users API",
+			"version": "1.0.0",
+			"description": "Errors carry a machine-readable code; v1 responses send the general code " +
+				"of their status (e.g. VALIDATION_ERROR) where v2 responses send the specific one listed.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": spec.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		// Authentication is only enforced when a signing key is configured
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{},
+		},
+	}
+}
+
+// Undocumented lists the operations of a route missing the metadata the OpenAPI document
+// needs: a summary and the status of a successful response.
+//
+// Returns:
+// - The methods of the incomplete operations, or nil if every one is documented.
+func (r *Route) Undocumented() []string {
+	var methods []string
+	for _, method := range methodOrder {
+		if operation, ok := r.Methods[method]; ok && (operation.Summary == "" || operation.Status == 0) {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// specBuilder collects the named schemas of the document while the operations are described
+type specBuilder struct {
+	schemas      map[string]interface{}  // Schemas of the document's components, by name
+	names        map[reflect.Type]string // Names of the schemas of the types already described
+	errorContent map[string]interface{}  // Content of every error response
+}
+
+// operation describes an operation of a route.
+//
+// Parameters:
+// - method: The HTTP method of the operation.
+// - template: The path template of the route.
+// - operation: The operation.
+//
+// Returns:
+// - The OpenAPI Operation Object.
+func (b *specBuilder) operation(method string, template string, operation Operation) map[string]interface{} {
+	described := map[string]interface{}{
+		"summary":     operation.Summary,
+		"operationId": operationID(method, template),
+	}
+
+	query := operation.Query
+	if operation.Paginated {
+		query = concatParams(query, cursorQuery)
+	}
+	if len(query) > 0 {
+		params := make([]interface{}, len(query))
+		for i, param := range query {
+			params[i] = map[string]interface{}{
+				"name":        param.Name,
+				"in":          "query",
+				"description": param.Description,
+				"schema":      map[string]interface{}{"type": param.Type},
+			}
+		}
+		described["parameters"] = params
+	}
+
+	if operation.Body != nil {
+		mediaType := operation.BodyType
+		if mediaType == "" {
+			mediaType = MediaTypeJSON
+		}
+		described["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				mediaType: map[string]interface{}{"schema": b.schema(reflect.TypeOf(operation.Body))},
+			},
+		}
+	}
+
+	success := map[string]interface{}{"description": http.StatusText(operation.Status)}
+	if operation.Response != nil {
+		success["content"] = b.responseContent(operation)
+	}
+	if operation.Paginated {
+		success["headers"] = pageHeaders
+	}
+	responses := map[string]interface{}{strconv.Itoa(operation.Status): success}
+
+	codes := concatCodes(operation.Errors, requestErrors)
+	if operation.Body != nil {
+		codes = concatCodes(codes, bodyErrors)
+	}
+	for status, codes := range codesByStatus(codes) {
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": http.StatusText(status) + ": " + strings.Join(codes, ", "),
+			"content":     b.errorContent,
+		}
+	}
+	described["responses"] = responses
+	return described
+}
+
+// responseContent describes the body of a successful response: JSON, also wrapped in an
+// Envelope for v2 clients, or the media type the operation sends instead.
+func (b *specBuilder) responseContent(operation Operation) map[string]interface{} {
+	schema := b.schema(reflect.TypeOf(operation.Response))
+	if operation.ResponseType != "" {
+		return map[string]interface{}{operation.ResponseType: map[string]interface{}{"schema": schema}}
+	}
+
+	envelope := map[string]interface{}{"data": schema}
+	if operation.Paginated {
+		envelope["meta"] = b.schema(reflect.TypeOf(EnvelopeMeta{}))
+	}
+	return map[string]interface{}{
+		MediaTypeJSON: map[string]interface{}{"schema": schema},
+		MediaTypeV2: map[string]interface{}{"schema": map[string]interface{}{
+			"type":       "object",
+			"properties": envelope,
+		}},
+	}
+}
+
+// schema describes the JSON encoding of a Go type. Structs are described once, as named
+// schemas of the document's components, and referenced.
+func (b *specBuilder) schema(t reflect.Type) map[string]interface{} {
+	if schema, ok := schemaOverrides[t]; ok {
+		return schema
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		return map[string]interface{}{"$ref": schemaRefPrefix + b.name(t)}
+	}
+	// Interfaces hold any JSON value
+	return map[string]interface{}{}
+}
+
+// name returns the name of the schema of a struct, describing it first if it wasn't yet.
+// Structs of the same name in different packages are told apart by their package.
+func (b *specBuilder) name(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := exportedName(t.Name())
+	if _, taken := b.schemas[name]; taken {
+		name = exportedName(t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]) + name
+	}
+	b.names[t] = name
+	// Claim the name before describing the fields, which may refer back to the struct
+	b.schemas[name] = nil
+
+	properties := map[string]interface{}{}
+	b.addProperties(t, properties)
+	b.schemas[name] = map[string]interface{}{"type": "object", "properties": properties}
+	return name
+}
+
+// addProperties describes the fields of a struct by their JSON names. Fields of embedded
+// structs are promoted, and fields tagged "-" left out.
+func (b *specBuilder) addProperties(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addProperties(embedded, properties)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+	}
+}
+
+// pathParameters describes the parameters of a path template, e.g. email for
+// /users/{email}.
+func pathParameters(template string) []interface{} {
+	var params []interface{}
+	for _, segment := range strings.Split(template, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, map[string]interface{}{
+				"name":     segment[1 : len(segment)-1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+	return params
+}
+
+// codesByStatus groups error codes by their default HTTP status, sorted and without
+// duplicates.
+func codesByStatus(codes []ErrorCode) map[int][]string {
+	statuses := make(map[ErrorCode]int, len(errorCodeStatuses))
+	for _, info := range errorCodeStatuses {
+		statuses[info.Code] = info.Status
+	}
+
+	grouped := map[int][]string{}
+	seen := map[ErrorCode]bool{}
+	for _, code := range codes {
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		status := statuses[code]
+		grouped[status] = append(grouped[status], string(code))
+	}
+	for _, codes := range grouped {
+		sort.Strings(codes)
+	}
+	return grouped
+}
+
+// operationID derives a unique operation ID from a method and a path template, e.g.
+// "getUsersEmailExport" for GET /users/{email}/export.
+func operationID(method string, template string) string {
+	id := strings.ToLower(method)
+	for _, word := range strings.FieldsFunc(template, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		id += exportedName(word)
+	}
+	return id
+}
+
+// exportedName capitalizes the first letter of a name.
+func exportedName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,609 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Fields the OpenAPI 3.0 schema allows on each object, besides x- extensions, and the
+// values it allows for enumerated fields
+var (
+	oasDocumentFields = fieldSet("openapi", "info", "externalDocs", "servers", "security", "tags", "paths",
+		"components")
+	oasInfoFields      = fieldSet("title", "description", "termsOfService", "contact", "license", "version")
+	oasComponentFields = fieldSet("schemas", "responses", "parameters", "examples", "requestBodies", "headers",
+		"securitySchemes", "links", "callbacks")
+	oasPathItemFields = fieldSet("$ref", "summary", "description", "servers", "parameters", "get", "put", "post",
+		"delete", "options", "head", "patch", "trace")
+	oasOperationFields = fieldSet("tags", "summary", "description", "externalDocs", "operationId", "parameters",
+		"requestBody", "responses", "callbacks", "deprecated", "security", "servers")
+	oasParameterFields = fieldSet("name", "in", "description", "required", "deprecated", "allowEmptyValue", "style",
+		"explode", "allowReserved", "schema", "content", "example", "examples")
+	oasHeaderFields = fieldSet("description", "required", "deprecated", "allowEmptyValue", "style", "explode",
+		"allowReserved", "schema", "content", "example", "examples")
+	oasRequestBodyFields = fieldSet("description", "content", "required")
+	oasResponseFields    = fieldSet("description", "headers", "content", "links")
+	oasMediaTypeFields   = fieldSet("schema", "example", "examples", "encoding")
+	oasSchemaFields      = fieldSet("title", "multipleOf", "maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum",
+		"maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems", "maxProperties",
+		"minProperties", "required", "enum", "type", "not", "allOf", "oneOf", "anyOf", "items", "properties",
+		"additionalProperties", "description", "format", "default", "nullable", "discriminator", "readOnly",
+		"writeOnly", "example", "externalDocs", "deprecated", "xml", "$ref")
+	oasSchemaTypes       = fieldSet("array", "boolean", "integer", "number", "object", "string")
+	oasParameterLocation = fieldSet("query", "header", "path", "cookie")
+	oasSecurityTypes     = fieldSet("apiKey", "http", "oauth2", "openIdConnect")
+	oasMethodFields      = fieldSet("get", "put", "post", "delete", "options", "head", "patch", "trace")
+)
+
+var (
+	openAPIVersionPattern = regexp.MustCompile(`^3\.0\.\d(-.+)?$`)
+	statusPattern         = regexp.MustCompile(`^[1-5](\d{2}|XX)$`)
+	componentNamePattern  = regexp.MustCompile(`^[a-zA-Z0-9.\-_]+$`)
+	templateParamPattern  = regexp.MustCompile(`{([^}]+)}`)
+)
+
+// fieldSet builds the set of the given fields.
+func fieldSet(fields ...string) map[string]bool {
+	set := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		set[field] = true
+	}
+	return set
+}
+
+// specValidator checks an OpenAPI document, decoded from JSON, against the rules of the
+// OpenAPI 3.0 schema and the constraints the specification adds to it, such as path
+// parameters being required and operation IDs unique.
+type specValidator struct {
+	doc          map[string]interface{}
+	problems     []string
+	operationIDs map[string]string
+}
+
+// validateSpec returns the problems of a document, each prefixed with the JSON pointer of
+// the offending value.
+func validateSpec(doc map[string]interface{}) []string {
+	v := &specValidator{doc: doc, operationIDs: map[string]string{}}
+	v.fields("", doc, oasDocumentFields, "openapi", "info", "paths")
+	if version, ok := doc["openapi"].(string); !ok || !openAPIVersionPattern.MatchString(version) {
+		v.errorf("/openapi", "%v isn't an OpenAPI 3.0 version", doc["openapi"])
+	}
+	if info, ok := v.object("/info", doc["info"]); ok {
+		v.fields("/info", info, oasInfoFields, "title", "version")
+		v.strings("/info", info, "title", "version", "description")
+	}
+	if components, ok := doc["components"]; ok {
+		v.components(components)
+	}
+	v.security("/security", doc["security"])
+	if paths, ok := v.object("/paths", doc["paths"]); ok {
+		for _, path := range sortedKeys(paths) {
+			v.pathItem(path, paths[path])
+		}
+	}
+	return v.problems
+}
+
+func (v *specValidator) errorf(pointer string, format string, args ...interface{}) {
+	v.problems = append(v.problems, pointer+": "+fmt.Sprintf(format, args...))
+}
+
+// object asserts a value is a JSON object.
+func (v *specValidator) object(pointer string, value interface{}) (map[string]interface{}, bool) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		v.errorf(pointer, "%v isn't an object", value)
+	}
+	return object, ok
+}
+
+// fields asserts an object holds the required fields, and no field the schema doesn't allow.
+func (v *specValidator) fields(pointer string, object map[string]interface{}, allowed map[string]bool, required ...string) {
+	for _, field := range required {
+		if _, ok := object[field]; !ok {
+			v.errorf(pointer, "missing %q", field)
+		}
+	}
+	for field := range object {
+		if !allowed[field] && !strings.HasPrefix(field, "x-") {
+			v.errorf(pointer, "%q isn't allowed", field)
+		}
+	}
+}
+
+// strings asserts the fields of an object, where present, are strings.
+func (v *specValidator) strings(pointer string, object map[string]interface{}, fields ...string) {
+	for _, field := range fields {
+		if value, ok := object[field]; ok {
+			if _, ok := value.(string); !ok {
+				v.errorf(pointer+"/"+field, "%v isn't a string", value)
+			}
+		}
+	}
+}
+
+func (v *specValidator) components(value interface{}) {
+	components, ok := v.object("/components", value)
+	if !ok {
+		return
+	}
+	v.fields("/components", components, oasComponentFields)
+	if schemas, ok := components["schemas"]; ok {
+		if schemas, ok := v.object("/components/schemas", schemas); ok {
+			for _, name := range sortedKeys(schemas) {
+				if !componentNamePattern.MatchString(name) {
+					v.errorf("/components/schemas", "%q isn't a valid component name", name)
+				}
+				v.schema("/components/schemas/"+name, schemas[name])
+			}
+		}
+	}
+	if schemes, ok := components["securitySchemes"]; ok {
+		if schemes, ok := v.object("/components/securitySchemes", schemes); ok {
+			for _, name := range sortedKeys(schemes) {
+				pointer := "/components/securitySchemes/" + name
+				scheme, ok := v.object(pointer, schemes[name])
+				if !ok {
+					continue
+				}
+				if kind, _ := scheme["type"].(string); !oasSecurityTypes[kind] {
+					v.errorf(pointer, "%v isn't a security scheme type", scheme["type"])
+				}
+				if scheme["type"] == "http" {
+					if _, ok := scheme["scheme"].(string); !ok {
+						v.errorf(pointer, "missing \"scheme\"")
+					}
+				}
+			}
+		}
+	}
+}
+
+// security asserts each security requirement names declared schemes.
+func (v *specValidator) security(pointer string, value interface{}) {
+	if value == nil {
+		return
+	}
+	requirements, ok := value.([]interface{})
+	if !ok {
+		v.errorf(pointer, "%v isn't an array", value)
+		return
+	}
+	components, _ := v.doc["components"].(map[string]interface{})
+	schemes, _ := components["securitySchemes"].(map[string]interface{})
+	for i, requirement := range requirements {
+		requirement, ok := v.object(fmt.Sprintf("%s/%d", pointer, i), requirement)
+		if !ok {
+			continue
+		}
+		for name, scopes := range requirement {
+			if _, ok := schemes[name]; !ok {
+				v.errorf(fmt.Sprintf("%s/%d", pointer, i), "%q isn't a declared security scheme", name)
+			}
+			if _, ok := scopes.([]interface{}); !ok {
+				v.errorf(fmt.Sprintf("%s/%d/%s", pointer, i, name), "%v isn't an array of scopes", scopes)
+			}
+		}
+	}
+}
+
+func (v *specValidator) pathItem(path string, value interface{}) {
+	pointer := "/paths/" + strings.ReplaceAll(path, "/", "~1")
+	if !strings.HasPrefix(path, "/") {
+		v.errorf(pointer, "path doesn't start with /")
+	}
+	item, ok := v.object(pointer, value)
+	if !ok {
+		return
+	}
+	v.fields(pointer, item, oasPathItemFields)
+	shared := v.parameters(pointer+"/parameters", item["parameters"])
+
+	for _, method := range sortedKeys(item) {
+		if !oasMethodFields[method] {
+			continue
+		}
+		operationPointer := pointer + "/" + method
+		operation, ok := v.object(operationPointer, item[method])
+		if !ok {
+			continue
+		}
+		v.fields(operationPointer, operation, oasOperationFields, "responses")
+		v.strings(operationPointer, operation, "summary", "description", "operationId")
+		if id, ok := operation["operationId"].(string); ok {
+			if other, taken := v.operationIDs[id]; taken {
+				v.errorf(operationPointer, "operationId %q is also used by %s", id, other)
+			}
+			v.operationIDs[id] = operationPointer
+		}
+
+		declared := map[string]bool{}
+		for name := range shared {
+			declared[name] = true
+		}
+		for name := range v.parameters(operationPointer+"/parameters", operation["parameters"]) {
+			declared[name] = true
+		}
+		for _, match := range templateParamPattern.FindAllStringSubmatch(path, -1) {
+			if !declared["path "+match[1]] {
+				v.errorf(operationPointer, "path parameter %q isn't declared", match[1])
+			}
+		}
+		for name := range declared {
+			if strings.HasPrefix(name, "path ") && !strings.Contains(path, "{"+strings.TrimPrefix(name, "path ")+"}") {
+				v.errorf(operationPointer, "path parameter %q isn't in the path", strings.TrimPrefix(name, "path "))
+			}
+		}
+
+		if body, ok := operation["requestBody"]; ok {
+			if body, ok := v.object(operationPointer+"/requestBody", body); ok {
+				v.fields(operationPointer+"/requestBody", body, oasRequestBodyFields, "content")
+				v.content(operationPointer+"/requestBody/content", body["content"])
+			}
+		}
+		v.responses(operationPointer+"/responses", operation["responses"])
+		v.security(operationPointer+"/security", operation["security"])
+	}
+}
+
+// parameters checks a list of parameters, returning them keyed by location and name.
+func (v *specValidator) parameters(pointer string, value interface{}) map[string]bool {
+	declared := map[string]bool{}
+	if value == nil {
+		return declared
+	}
+	params, ok := value.([]interface{})
+	if !ok {
+		v.errorf(pointer, "%v isn't an array", value)
+		return declared
+	}
+	for i, param := range params {
+		paramPointer := fmt.Sprintf("%s/%d", pointer, i)
+		param, ok := v.object(paramPointer, param)
+		if !ok {
+			continue
+		}
+		v.fields(paramPointer, param, oasParameterFields, "name", "in")
+		v.strings(paramPointer, param, "name", "in", "description")
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if !oasParameterLocation[in] {
+			v.errorf(paramPointer, "%q isn't a parameter location", in)
+		}
+		if in == "path" && param["required"] != true {
+			v.errorf(paramPointer, "path parameter %q isn't required", name)
+		}
+		key := in + " " + name
+		if declared[key] {
+			v.errorf(paramPointer, "%s parameter %q is declared twice", in, name)
+		}
+		declared[key] = true
+		v.schemaOrContent(paramPointer, param)
+	}
+	return declared
+}
+
+// schemaOrContent checks the schema or content of a parameter or header, which must have
+// exactly one of them.
+func (v *specValidator) schemaOrContent(pointer string, object map[string]interface{}) {
+	schema, hasSchema := object["schema"]
+	content, hasContent := object["content"]
+	switch {
+	case hasSchema == hasContent:
+		v.errorf(pointer, "has both or neither of schema and content")
+	case hasSchema:
+		v.schema(pointer+"/schema", schema)
+	default:
+		v.content(pointer+"/content", content)
+	}
+}
+
+func (v *specValidator) content(pointer string, value interface{}) {
+	content, ok := v.object(pointer, value)
+	if !ok {
+		return
+	}
+	for _, mediaType := range sortedKeys(content) {
+		mediaPointer := pointer + "/" + strings.ReplaceAll(mediaType, "/", "~1")
+		if _, _, err := mime.ParseMediaType(mediaType); err != nil {
+			v.errorf(mediaPointer, "%q isn't a media type: %v", mediaType, err)
+		}
+		media, ok := v.object(mediaPointer, content[mediaType])
+		if !ok {
+			continue
+		}
+		v.fields(mediaPointer, media, oasMediaTypeFields)
+		if schema, ok := media["schema"]; ok {
+			v.schema(mediaPointer+"/schema", schema)
+		}
+	}
+}
+
+func (v *specValidator) responses(pointer string, value interface{}) {
+	responses, ok := v.object(pointer, value)
+	if !ok {
+		return
+	}
+	if len(responses) == 0 {
+		v.errorf(pointer, "no responses")
+	}
+	for _, status := range sortedKeys(responses) {
+		responsePointer := pointer + "/" + status
+		if status != "default" && !statusPattern.MatchString(status) && !strings.HasPrefix(status, "x-") {
+			v.errorf(responsePointer, "%q isn't a status code", status)
+		}
+		response, ok := v.object(responsePointer, responses[status])
+		if !ok {
+			continue
+		}
+		v.fields(responsePointer, response, oasResponseFields, "description")
+		v.strings(responsePointer, response, "description")
+		if headers, ok := response["headers"]; ok {
+			if headers, ok := v.object(responsePointer+"/headers", headers); ok {
+				for _, name := range sortedKeys(headers) {
+					headerPointer := responsePointer + "/headers/" + name
+					if header, ok := v.object(headerPointer, headers[name]); ok {
+						v.fields(headerPointer, header, oasHeaderFields)
+						v.schemaOrContent(headerPointer, header)
+					}
+				}
+			}
+		}
+		if content, ok := response["content"]; ok {
+			v.content(responsePointer+"/content", content)
+		}
+	}
+}
+
+// schema checks a Schema Object of OpenAPI 3.0, a subset of JSON Schema: type is a single
+// name, arrays declare their items, and a reference stands alone and resolves.
+func (v *specValidator) schema(pointer string, value interface{}) {
+	schema, ok := v.object(pointer, value)
+	if !ok {
+		return
+	}
+	v.fields(pointer, schema, oasSchemaFields)
+	if ref, ok := schema["$ref"]; ok {
+		if len(schema) > 1 {
+			v.errorf(pointer, "$ref has sibling fields, which OpenAPI 3.0 ignores")
+		}
+		v.ref(pointer, ref)
+		return
+	}
+	if kind, ok := schema["type"]; ok {
+		if kind, _ := kind.(string); !oasSchemaTypes[kind] {
+			v.errorf(pointer+"/type", "%v isn't a schema type", schema["type"])
+		}
+	}
+	if schema["type"] == "array" {
+		if items, ok := schema["items"]; !ok {
+			v.errorf(pointer, "array without items")
+		} else {
+			v.schema(pointer+"/items", items)
+		}
+	}
+	if properties, ok := schema["properties"]; ok {
+		if properties, ok := v.object(pointer+"/properties", properties); ok {
+			for _, name := range sortedKeys(properties) {
+				v.schema(pointer+"/properties/"+name, properties[name])
+			}
+		}
+	}
+	if additional, ok := schema["additionalProperties"]; ok {
+		if _, ok := additional.(bool); !ok {
+			v.schema(pointer+"/additionalProperties", additional)
+		}
+	}
+}
+
+// ref asserts a reference points to a schema of the document's components.
+func (v *specValidator) ref(pointer string, value interface{}) {
+	ref, _ := value.(string)
+	if !strings.HasPrefix(ref, schemaRefPrefix) {
+		v.errorf(pointer, "%v doesn't reference a component schema", value)
+		return
+	}
+	components, _ := v.doc["components"].(map[string]interface{})
+	schemas, _ := components["schemas"].(map[string]interface{})
+	if _, ok := schemas[strings.TrimPrefix(ref, schemaRefPrefix)]; !ok {
+		v.errorf(pointer, "%s doesn't resolve", ref)
+	}
+}
+
+// sortedKeys returns the keys of an object in order, so problems are reported in a stable order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// decodedSpec returns the document as clients read it: encoded to JSON and decoded again.
+func decodedSpec(t *testing.T, spec interface{}) map[string]interface{} {
+	t.Helper()
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("encoding the document: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		t.Fatalf("decoding the document: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPIIsValid(t *testing.T) {
+	resp, err := OpenAPIDocument(events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: OpenAPIPath}, nil, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("OpenAPIDocument() = %+v, %v; want the document", resp, err)
+	}
+	var served map[string]interface{}
+	if err := json.Unmarshal([]byte(resp.Body), &served); err != nil {
+		t.Fatalf("served document %s isn't JSON: %v", resp.Body, err)
+	}
+
+	doc := decodedSpec(t, OpenAPI())
+	for _, problem := range validateSpec(doc) {
+		t.Error(problem)
+	}
+	if len(served) != len(doc) || served["openapi"] != doc["openapi"] {
+		t.Errorf("served document differs from OpenAPI()")
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, route := range Routes() {
+		item, ok := paths[route.Template].(map[string]interface{})
+		if !ok {
+			t.Errorf("%s isn't in the document", route.Template)
+			continue
+		}
+		for method, operation := range route.Methods {
+			described, ok := item[strings.ToLower(method)].(map[string]interface{})
+			if !ok {
+				t.Errorf("%s %s isn't in the document", method, route.Template)
+				continue
+			}
+			responses, _ := described["responses"].(map[string]interface{})
+			if _, ok := responses[fmt.Sprint(operation.Status)]; !ok {
+				t.Errorf("%s %s doesn't describe its %d response", method, route.Template, operation.Status)
+			}
+		}
+	}
+}
+
+func TestValidateSpec(t *testing.T) {
+	valid := func() map[string]interface{} {
+		return decodedSpec(t, OpenAPI())
+	}
+	// operation returns the GET operation of /users/{email} of a document
+	operation := func(doc map[string]interface{}) map[string]interface{} {
+		item := doc["paths"].(map[string]interface{})[usersPathPrefix+"{email}"].(map[string]interface{})
+		return item["get"].(map[string]interface{})
+	}
+	tests := []struct {
+		name        string
+		mutate      func(doc map[string]interface{})
+		wantProblem string
+	}{
+		{name: "valid", mutate: func(doc map[string]interface{}) {}},
+		{name: "swagger 2", mutate: func(doc map[string]interface{}) { doc["openapi"] = "2.0" },
+			wantProblem: "isn't an OpenAPI 3.0 version"},
+		{name: "no title", mutate: func(doc map[string]interface{}) { delete(doc["info"].(map[string]interface{}), "title") },
+			wantProblem: `missing "title"`},
+		{name: "unknown field", mutate: func(doc map[string]interface{}) { doc["definitions"] = map[string]interface{}{} },
+			wantProblem: `"definitions" isn't allowed`},
+		{name: "extension", mutate: func(doc map[string]interface{}) { doc["x-generator"] = "genspec" }},
+		{name: "no responses", mutate: func(doc map[string]interface{}) { delete(operation(doc), "responses") },
+			wantProblem: `missing "responses"`},
+		{name: "bad status", mutate: func(doc map[string]interface{}) {
+			operation(doc)["responses"].(map[string]interface{})["ok"] = map[string]interface{}{"description": "OK"}
+		}, wantProblem: `"ok" isn't a status code`},
+		{name: "response without description", mutate: func(doc map[string]interface{}) {
+			operation(doc)["responses"].(map[string]interface{})["200"] = map[string]interface{}{}
+		}, wantProblem: `missing "description"`},
+		{name: "undeclared path parameter", mutate: func(doc map[string]interface{}) {
+			delete(doc["paths"].(map[string]interface{})[usersPathPrefix+"{email}"].(map[string]interface{}), "parameters")
+		}, wantProblem: `path parameter "email" isn't declared`},
+		{name: "optional path parameter", mutate: func(doc map[string]interface{}) {
+			item := doc["paths"].(map[string]interface{})[usersPathPrefix+"{email}"].(map[string]interface{})
+			item["parameters"].([]interface{})[0].(map[string]interface{})["required"] = false
+		}, wantProblem: `path parameter "email" isn't required`},
+		{name: "duplicate operation ID", mutate: func(doc map[string]interface{}) {
+			operation(doc)["operationId"] = "postUsers"
+		}, wantProblem: `operationId "postUsers" is also used`},
+		{name: "dangling reference", mutate: func(doc map[string]interface{}) {
+			delete(doc["components"].(map[string]interface{})["schemas"].(map[string]interface{}), "ErrorBody")
+		}, wantProblem: "#/components/schemas/ErrorBody doesn't resolve"},
+		{name: "type list", mutate: func(doc map[string]interface{}) {
+			schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+			schemas["Nullable"] = map[string]interface{}{"type": []interface{}{"string", "null"}}
+		}, wantProblem: "isn't a schema type"},
+		{name: "array without items", mutate: func(doc map[string]interface{}) {
+			schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+			schemas["List"] = map[string]interface{}{"type": "array"}
+		}, wantProblem: "array without items"},
+		{name: "undeclared security scheme", mutate: func(doc map[string]interface{}) {
+			doc["security"] = []interface{}{map[string]interface{}{"apiKey": []interface{}{}}}
+		}, wantProblem: `"apiKey" isn't a declared security scheme`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := valid()
+			tt.mutate(doc)
+			problems := validateSpec(doc)
+			if tt.wantProblem == "" {
+				if len(problems) > 0 {
+					t.Errorf("validateSpec() = %q, want no problem", problems)
+				}
+				return
+			}
+			found := false
+			for _, problem := range problems {
+				found = found || strings.Contains(problem, tt.wantProblem)
+			}
+			if !found {
+				t.Errorf("validateSpec() = %q, want %q", problems, tt.wantProblem)
+			}
+		})
+	}
+}
+
+// TestRoutesAreDocumented fails when a route is added to the table without the metadata
+// the OpenAPI document needs.
+func TestRoutesAreDocumented(t *testing.T) {
+	for _, route := range Routes() {
+		if methods := route.Undocumented(); len(methods) > 0 {
+			t.Errorf("%v %s lack a summary or success status", methods, route.Template)
+		}
+		for method, operation := range route.Methods {
+			if operation.Handler == nil && route.Template != HealthPath {
+				t.Errorf("%s %s has no handler", method, route.Template)
+			}
+		}
+	}
+}
+
+func TestUndocumented(t *testing.T) {
+	handler := func(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+		*events.APIGatewayProxyResponse, error) {
+		return nil, nil
+	}
+	tests := []struct {
+		name    string
+		methods map[string]Operation
+		want    []string
+	}{
+		{name: "documented", methods: map[string]Operation{
+			http.MethodGet: {Handler: handler, Summary: "Read a widget", Status: http.StatusOK}}},
+		{name: "no summary", methods: map[string]Operation{
+			http.MethodGet:    {Handler: handler, Summary: "Read a widget", Status: http.StatusOK},
+			http.MethodDelete: {Handler: handler, Status: http.StatusNoContent}},
+			want: []string{http.MethodDelete}},
+		{name: "no status", methods: map[string]Operation{
+			http.MethodPost: {Handler: handler, Summary: "Create a widget"}},
+			want: []string{http.MethodPost}},
+		{name: "no metadata", methods: map[string]Operation{
+			http.MethodPut: {Handler: handler}, http.MethodGet: {Handler: handler}},
+			want: []string{http.MethodGet, http.MethodPut}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := Route{Template: "/widgets/{id}", Methods: tt.methods}
+			if got := route.Undocumented(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Undocumented() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
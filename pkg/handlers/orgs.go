@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/org"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Error messages for the "include" query parameter
+var (
+	ErrorInvalidInclude  = "include must be orgs"
+	ErrorIncludeWithList = "include is only supported for a single user"
+)
+
+// includeOrgs is the "include" value expanding a user with its memberships
+const includeOrgs = "orgs"
+
+// orgRequest is the body of POST /orgs
+type orgRequest struct {
+	Slug string `json:"slug"` // Unique, URL-safe identifier of the new organization
+	Name string `json:"name"` // Display name
+}
+
+// memberRequest is the body of POST /orgs/{slug}/members
+type memberRequest struct {
+	Email string `json:"email"` // Email of the user to add
+}
+
+// ListOrgs handles GET /orgs, returning every organization in slug order.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest for the list.
+// - repo: Repository where user data is stored (unused).
+// - dynaClient: DynamoDB client interface, used for the organizations table.
+//
+// Returns:
+// - APIGatewayProxyResponse with the organizations, or 404 if organizations are off.
+func ListOrgs(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	orgs, err := org.List(dynaClient)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	return APIResponse(http.StatusOK, orgs)
+}
+
+// CreateOrg handles POST /orgs with {"slug": "acme", "name": "Acme"}, for administrators
+// only.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest with the organization in the body.
+// - repo: Repository where user data is stored (unused).
+// - dynaClient: DynamoDB client interface, used for the organizations table.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the created organization and its Location, 400 for an
+//     invalid slug or name, or 409 if the slug is taken.
+func CreateOrg(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+	req, invalid := decodeBody(req, maxBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+
+	var body orgRequest
+	if err := user.DecodeJSON(req.Body, &body); err != nil {
+		return errorResponse(req, err)
+	}
+	created := &org.Org{Slug: body.Slug, Name: body.Name}
+	if err := org.Create(created, dynaClient); err != nil {
+		return errorResponse(req, err)
+	}
+	location := strings.TrimSuffix(requestPath(req), "/") + "/" + created.Slug
+	return APIResponse(http.StatusCreated, created, map[string]string{"Location": location})
+}
+
+// GetOrg handles GET /orgs/{slug}.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the organization.
+// - repo: Repository where user data is stored (unused).
+// - dynaClient: DynamoDB client interface, used for the organizations table.
+//
+// Returns:
+// - APIGatewayProxyResponse with the organization, or 404 if it doesn't exist.
+func GetOrg(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	slug, invalid := slugParam(req)
+	if invalid != nil {
+		return invalid, nil
+	}
+	found, err := org.Get(slug, dynaClient)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	return APIResponse(http.StatusOK, found)
+}
+
+// DeleteOrg handles DELETE /orgs/{slug}, removing the organization and its memberships,
+// for administrators only.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the organization.
+// - repo: Repository where user data is stored (unused).
+// - dynaClient: DynamoDB client interface, used for the organizations table.
+//
+// Returns:
+// - APIGatewayProxyResponse with a success message, or 404 if it doesn't exist.
+func DeleteOrg(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	slug, invalid := slugParam(req)
+	if invalid != nil {
+		return invalid, nil
+	}
+	if err := org.Delete(slug, dynaClient); err != nil {
+		return errorResponse(req, err)
+	}
+	return APIResponse(http.StatusOK, "Organization deleted successfully")
+}
+
+// ListOrgMembers handles GET /orgs/{slug}/members, returning the memberships of the
+// organization in email order.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the organization.
+// - repo: Repository where user data is stored (unused).
+// - dynaClient: DynamoDB client interface, used for the organizations table.
+//
+// Returns:
+// - APIGatewayProxyResponse with the memberships, or 404 if the organization doesn't exist.
+func ListOrgMembers(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	slug, invalid := slugParam(req)
+	if invalid != nil {
+		return invalid, nil
+	}
+	if _, err := org.Get(slug, dynaClient); err != nil {
+		return errorResponse(req, err)
+	}
+	members, err := org.Members(slug, dynaClient)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	return APIResponse(http.StatusOK, members)
+}
+
+// AddOrgMember handles POST /orgs/{slug}/members with {"email": "ada@example.com"},
+// linking an existing user to the organization, for administrators only.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the organization, with the user's email in the body.
+// - repo: Repository where user data is stored, to check the user exists.
+// - dynaClient: DynamoDB client interface, used for the organizations table.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the membership, 404 if the organization or user doesn't
+//     exist, or 409 if the user is already a member.
+func AddOrgMember(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	slug, invalid := slugParam(req)
+	if invalid != nil {
+		return invalid, nil
+	}
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+	req, invalid = decodeBody(req, maxBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+
+	var body memberRequest
+	if err := user.DecodeJSON(req.Body, &body); err != nil {
+		return errorResponse(req, err)
+	}
+	if strings.TrimSpace(body.Email) == "" {
+		return errorResponse(req, &user.Error{Kind: user.ErrValidation, Message: user.ErrorInvalidEmail, Field: "email"})
+	}
+
+	// Only existing users can be linked; FetchUser also hides deleted and expired ones
+	member, err := user.FetchUser(body.Email, user.ReadOptions{Fields: []string{"email"}}, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	membership, err := org.AddMember(slug, member.Email, dynaClient)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	return APIResponse(http.StatusCreated, membership)
+}
+
+// RemoveOrgMember handles DELETE /orgs/{slug}/members/{email}, for administrators only.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the membership.
+// - repo: Repository where user data is stored (unused).
+// - dynaClient: DynamoDB client interface, used for the organizations table.
+//
+// Returns:
+// - APIGatewayProxyResponse with a success message, or 404 if the user isn't a member.
+func RemoveOrgMember(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	slug, invalid := slugParam(req)
+	if invalid != nil {
+		return invalid, nil
+	}
+	email, err := url.PathUnescape(req.PathParameters["email"])
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorInvalidPathParameter)
+	}
+	if err := org.RemoveMember(slug, user.NormalizeEmail(email), dynaClient); err != nil {
+		return errorResponse(req, err)
+	}
+	return APIResponse(http.StatusOK, "Member removed successfully")
+}
+
+// includesOrgs parses the "include" query parameter of GET /users/{email}.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to inspect.
+//
+// Returns:
+// - Whether the user is expanded with its memberships (include=orgs).
+// - An error if "include" names anything else.
+func includesOrgs(req events.APIGatewayProxyRequest) (bool, error) {
+	raw, ok := req.QueryStringParameters["include"]
+	if !ok {
+		return false, nil
+	}
+	for _, value := range strings.Split(raw, ",") {
+		if strings.TrimSpace(value) != includeOrgs {
+			return false, errors.New(ErrorInvalidInclude)
+		}
+	}
+	return true, nil
+}
+
+// withOrgs returns a user as a response body expanded with its memberships under "orgs".
+//
+// Parameters:
+// - u: The user.
+// - fields: The selected fields, or nil for the whole user.
+// - dynaClient: DynamoDB client interface, used for the organizations table.
+//
+// Returns:
+// - The response body.
+// - An error if the memberships can't be read.
+func withOrgs(u *user.User, fields []string, dynaClient dynamodbiface.DynamoDBAPI) (interface{}, error) {
+	memberships, err := org.UserMemberships(u.Email, dynaClient)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		fields = user.Fields
+	}
+	body := u.Select(fields)
+	body["orgs"] = memberships
+	return body, nil
+}
+
+// slugParam resolves the slug a request targets.
+//
+// Returns:
+// - The slug.
+// - A 400 response if it isn't a valid slug, or nil.
+func slugParam(req events.APIGatewayProxyRequest) (string, *events.APIGatewayProxyResponse) {
+	slug, err := url.PathUnescape(req.PathParameters["slug"])
+	if err != nil {
+		resp, _ := APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorInvalidPathParameter)
+		return "", resp
+	}
+	if err := org.ValidateSlug(slug); err != nil {
+		resp, _ := errorResponse(req, err)
+		return "", resp
+	}
+	return slug, nil
+}
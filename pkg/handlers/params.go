@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Error messages for malformed request parameters
+var (
+	ErrorInvalidPathParameter = "invalid path parameter"
+	ErrorInvalidConsistent    = "consistent must be true or false"
+	ErrorFilterWithEmail      = "firstname, lastname, q, status, tag and creation time filters can't be combined with email"
+	ErrorEmailsWithEmail      = "emails can't be combined with email or filters"
+	ErrorInvalidHard          = "hard must be true or false"
+	ErrorInvalidDeleted       = "includeDeleted must be true or false"
+	ErrorInvalidOnDeleted     = "onDeletedConflict must be reject or overwrite"
+)
+
+// tagFilterPrefix starts the query parameters filtering lists by tag (tag.<key>=<value>)
+const tagFilterPrefix = "tag."
+
+// usersPath is the path of the users collection
+const usersPath = "/users"
+
+// usersPathPrefix is the path prefix of the single-user resource (/users/{email})
+const usersPathPrefix = "/users/"
+
+// Paths of the collection-level resources nested under /users
+const (
+	countPath      = usersPathPrefix + "count"
+	batchPath      = usersPathPrefix + "batch"
+	exportPath     = usersPathPrefix + "export"
+	importPath     = usersPathPrefix + "import"
+	mergePath      = usersPathPrefix + "merge"
+	deletedPath    = usersPathPrefix + "deleted"
+	duplicatesPath = usersPathPrefix + "duplicates"
+	searchPath     = usersPathPrefix + "search"
+)
+
+// Actions nested under a single user (/users/{email}/<action>)
+const (
+	exportAction  = "export"
+	restoreAction = "restore"
+	auditAction   = "audit"
+
+	activateAction   = "activate"
+	deactivateAction = "deactivate"
+
+	changeEmailAction = "change-email"
+	passwordAction    = "password"
+
+	verifyAction = "verify"
+	resendAction = "verify/resend"
+)
+
+// userActions maps the known actions to the HTTP method they answer, so other nested
+// paths aren't mistaken for a user
+var userActions = map[string]string{
+	exportAction:  http.MethodGet,
+	restoreAction: http.MethodPost,
+	auditAction:   http.MethodGet,
+
+	activateAction:   http.MethodPost,
+	deactivateAction: http.MethodPost,
+
+	changeEmailAction: http.MethodPost,
+	passwordAction:    http.MethodPost,
+
+	verifyAction: http.MethodPost,
+	resendAction: http.MethodPost,
+}
+
+// RouteName returns the route template a request matched, e.g. "/users/{email}",
+// so it can be logged without the email embedded in the path.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to describe.
+//
+// Returns:
+//   - The API Gateway resource, or the template of the route matching the path, or the path
+//     itself if no route does.
+func RouteName(req events.APIGatewayProxyRequest) string {
+	if len(req.Resource) > 0 && !strings.Contains(req.Resource, "{proxy+}") {
+		return req.Resource
+	}
+	if route, _ := MatchRoute(req); route != nil {
+		return route.Template
+	}
+	return req.Path
+}
+
+// isSubresource reports whether the request targets a collection-level resource such as
+// /users/count. It is checked before the email is resolved so "count" isn't looked up as
+// a user, including when API Gateway matched the request as /users/{email}.
+func isSubresource(req events.APIGatewayProxyRequest, path string) bool {
+	return req.Resource == path || req.Path == path ||
+		usersPathPrefix+req.PathParameters["email"] == path
+}
+
+// userAction returns the action a request targets below a single user, e.g. "export" for
+// /users/{email}/export, or an empty string if it targets no known action.
+func userAction(req events.APIGatewayProxyRequest) string {
+	path := req.Resource
+	if !strings.HasPrefix(path, usersPathPrefix+"{email}/") {
+		path = req.Path
+	}
+	rest := strings.TrimPrefix(path, usersPathPrefix)
+	if rest == path {
+		return ""
+	}
+	if i := strings.Index(rest, "/"); i > 0 && userActions[rest[i+1:]] != "" {
+		return rest[i+1:]
+	}
+	return ""
+}
+
+// AllowedMethods returns the HTTP methods the route a request targets answers, as
+// advertised in the Allow header. HEAD is answered wherever GET is.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to inspect.
+//
+// Returns:
+// - The methods, in a stable order; only OPTIONS if no route matches.
+func AllowedMethods(req events.APIGatewayProxyRequest) []string {
+	if route, _ := MatchRoute(req); route != nil {
+		return route.AllowedMethods()
+	}
+	return []string{http.MethodOptions}
+}
+
+// emailParam resolves the email a request targets.
+// The path parameter (/users/{email}) takes precedence over the "email" query string
+// parameter, which is kept for backward compatibility. When API Gateway didn't populate
+// the path parameters (e.g. a {proxy+} resource), the email is taken from the path itself,
+// including paths of actions such as /users/{email}/export.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to inspect.
+//
+// Returns:
+// - The URL-decoded email, or an empty string if the request doesn't target a user.
+// - An error if the email in the path is not validly URL-encoded.
+func emailParam(req events.APIGatewayProxyRequest) (string, error) {
+	raw := req.PathParameters["email"]
+	if raw == "" && strings.HasPrefix(req.Path, usersPathPrefix) {
+		rest := strings.TrimPrefix(req.Path, usersPathPrefix)
+		if action := userAction(req); action != "" {
+			rest = strings.TrimSuffix(rest, "/"+action)
+		}
+		if !strings.Contains(rest, "/") {
+			raw = rest
+		}
+	}
+	if raw == "" {
+		return req.QueryStringParameters["email"], nil
+	}
+
+	// Path segments are percent-encoded; "+" is a literal plus, not a space
+	email, err := url.PathUnescape(raw)
+	if err != nil {
+		return "", errors.New(ErrorInvalidPathParameter)
+	}
+	return email, nil
+}
+
+// responseFields parses the "fields" selection of a write, which trims only the user
+// returned: writes always store and read back whole users.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to inspect.
+//
+// Returns:
+// - The selected fields, or nil to return the whole user.
+// - An error if "fields" names an unknown field.
+func responseFields(req events.APIGatewayProxyRequest) ([]string, error) {
+	return user.ParseFields(req.QueryStringParameters["fields"])
+}
+
+// readOptions resolves how a GET request reads users.
+// The "consistent" query parameter overrides the CONSISTENT_READS default, and
+// "fields" (e.g. "email,firstname") limits the attributes read, and "firstname",
+// "lastname" (exact) and "q" (substring of either name) filter lists and counts, as do
+// "createdAfter" and "createdBefore" (RFC3339, inclusive), and "includeDeleted" also returns
+// soft-deleted users.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to inspect.
+//
+// Returns:
+// - The read options.
+//   - An error if "consistent" or "includeDeleted" is not a boolean, "fields" names an unknown
+//     field, or "createdAfter" or "createdBefore" isn't an RFC3339 time.
+func readOptions(req events.APIGatewayProxyRequest) (user.ReadOptions, error) {
+	opts := user.DefaultReadOptions()
+	if raw, ok := req.QueryStringParameters["consistent"]; ok {
+		consistent, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, errors.New(ErrorInvalidConsistent)
+		}
+		opts.ConsistentRead = consistent
+	}
+	if raw, ok := req.QueryStringParameters["includeDeleted"]; ok {
+		includeDeleted, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, errors.New(ErrorInvalidDeleted)
+		}
+		opts.IncludeDeleted = includeDeleted
+	}
+
+	fields, err := user.ParseFields(req.QueryStringParameters["fields"])
+	if err != nil {
+		return opts, err
+	}
+	opts.Fields = fields
+
+	opts.Filter = user.Filter{
+		FirstName: req.QueryStringParameters["firstname"],
+		LastName:  req.QueryStringParameters["lastname"],
+		Query:     req.QueryStringParameters["q"],
+	}
+	opts.Filter.Tags, err = tagFilters(req)
+	if err != nil {
+		return opts, err
+	}
+	opts.Filter.Status = req.QueryStringParameters["status"]
+	if err := user.ParseStatus(opts.Filter.Status); err != nil {
+		return opts, err
+	}
+	opts.Filter.CreatedAfter, opts.Filter.CreatedBefore, err = user.ParseCreatedRange(
+		req.QueryStringParameters["createdAfter"], req.QueryStringParameters["createdBefore"])
+	if err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// tagFilters collects the "tag.<key>" query parameters of a list, e.g. tag.plan=pro.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to inspect.
+//
+// Returns:
+// - The tag values to match, or nil if there are none.
+// - An error if a parameter names no key.
+func tagFilters(req events.APIGatewayProxyRequest) (user.Tags, error) {
+	var tags user.Tags
+	for name, value := range req.QueryStringParameters {
+		if !strings.HasPrefix(name, tagFilterPrefix) {
+			continue
+		}
+		key := strings.TrimPrefix(name, tagFilterPrefix)
+		if key == "" {
+			return nil, errors.New(user.ErrorInvalidTagFilter)
+		}
+		if tags == nil {
+			tags = user.Tags{}
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+// createOptions resolves how a POST request creates a user. The "onDeletedConflict"
+// query parameter chooses whether an email held by a soft-deleted user is rejected with
+// 409 (reject, the default) or replaced (overwrite). Only administrators may create
+// suspended users or set the role.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to inspect.
+//
+// Returns:
+// - The create options.
+// - An error if "onDeletedConflict" has an unknown value.
+func createOptions(req events.APIGatewayProxyRequest) (user.CreateOptions, error) {
+	admin := callerIsAdmin(req)
+	opts := user.CreateOptions{AllowSuspended: admin, AllowRoleChange: admin, RejectRoleChange: strictRoles()}
+	switch req.QueryStringParameters["onDeletedConflict"] {
+	case "", "reject":
+		return opts, nil
+	case "overwrite":
+		opts.OverwriteDeleted = true
+		return opts, nil
+	default:
+		return opts, errors.New(ErrorInvalidOnDeleted)
+	}
+}
+
+// emailsParam parses the comma-separated "emails" query parameter.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to inspect.
+//
+// Returns:
+// - The emails, or nil if the parameter is absent.
+func emailsParam(req events.APIGatewayProxyRequest) []string {
+	var emails []string
+	for _, email := range strings.Split(req.QueryStringParameters["emails"], ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
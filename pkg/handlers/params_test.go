@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEmailParam(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     events.APIGatewayProxyRequest
+		want    string
+		wantErr bool
+	}{
+		{name: "encoded at", req: events.APIGatewayProxyRequest{PathParameters: map[string]string{"email": "ada%40example.com"}},
+			want: "ada@example.com"},
+		{name: "raw at", req: events.APIGatewayProxyRequest{PathParameters: map[string]string{"email": "ada@example.com"}},
+			want: "ada@example.com"},
+		{name: "literal plus", req: events.APIGatewayProxyRequest{PathParameters: map[string]string{"email": "ada+news@example.com"}},
+			want: "ada+news@example.com"},
+		{name: "encoded plus", req: events.APIGatewayProxyRequest{PathParameters: map[string]string{"email": "ada%2Bnews%40example.com"}},
+			want: "ada+news@example.com"},
+		{name: "from the path", req: events.APIGatewayProxyRequest{Path: "/users/ada%2Bnews%40example.com"},
+			want: "ada+news@example.com"},
+		{name: "action path", req: events.APIGatewayProxyRequest{Path: "/users/ada%40example.com/export"},
+			want: "ada@example.com"},
+		{name: "path wins over query", req: events.APIGatewayProxyRequest{
+			PathParameters:        map[string]string{"email": "ada%40example.com"},
+			QueryStringParameters: map[string]string{"email": "bob@example.com"}},
+			want: "ada@example.com"},
+		{name: "query", req: events.APIGatewayProxyRequest{Path: "/users",
+			QueryStringParameters: map[string]string{"email": "ada+news@example.com"}},
+			want: "ada+news@example.com"},
+		{name: "no email", req: events.APIGatewayProxyRequest{Path: "/users"}},
+		{name: "malformed encoding", req: events.APIGatewayProxyRequest{PathParameters: map[string]string{"email": "ada%4"}},
+			wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := emailParam(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("emailParam() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("emailParam() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsistentReads(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            string
+		query          map[string]string
+		wantStatus     int
+		wantConsistent bool
+	}{
+		{name: "default", wantStatus: http.StatusNotFound},
+		{name: "requested", query: map[string]string{"consistent": "true"}, wantStatus: http.StatusNotFound,
+			wantConsistent: true},
+		{name: "env default", env: "true", wantStatus: http.StatusNotFound, wantConsistent: true},
+		{name: "env default overridden", env: "true", query: map[string]string{"consistent": "false"},
+			wantStatus: http.StatusNotFound},
+		{name: "malformed", query: map[string]string{"consistent": "yes please"}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONSISTENT_READS", tt.env)
+			fake := mocks.NewFakeDynamo()
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet,
+				PathParameters: map[string]string{"email": "ada@example.com"}, QueryStringParameters: tt.query}
+
+			resp, err := GetUser(req, user.NewDynamoRepository("users", fake), nil)
+			if err != nil {
+				t.Fatalf("GetUser() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			inputs := fake.Inputs("GetItem")
+			if tt.wantStatus == http.StatusBadRequest {
+				if len(inputs) != 0 {
+					t.Errorf("GetItem called %d times, want no read", len(inputs))
+				}
+				return
+			}
+			if len(inputs) != 1 {
+				t.Fatalf("GetItem called %d times, want 1", len(inputs))
+			}
+			if got := inputs[0].(*dynamodb.GetItemInput).ConsistentRead; aws.BoolValue(got) != tt.wantConsistent {
+				t.Errorf("ConsistentRead = %v, want %v", aws.BoolValue(got), tt.wantConsistent)
+			}
+		})
+	}
+}
+
+func TestConsistentReadOnIndex(t *testing.T) {
+	t.Setenv("LASTNAME_INDEX", "lastname-index")
+	fake := mocks.NewFakeDynamo()
+	req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users",
+		QueryStringParameters: map[string]string{"lastname": "Lovelace", "consistent": "true"}}
+
+	resp, err := GetUser(req, user.NewDynamoRepository("users", fake), nil)
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", resp.StatusCode, http.StatusBadRequest, resp.Body)
+	}
+	if len(fake.Calls()) != 0 {
+		t.Errorf("DynamoDB received %v, want no call", fake.Calls())
+	}
+}
+
+func TestFieldsParameter(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		email          string
+		fields         string
+		wantStatus     int
+		wantExpression string
+		wantNames      []string
+		wantKeys       []string
+	}{
+		// Single users also read whether they are deleted or expired
+		{name: "single user", email: "ada@example.com", fields: "email,version", wantStatus: http.StatusOK,
+			wantExpression: "#f0, #f1, #f2, #f3", wantNames: []string{"deletedAt", "email", "expiresAt", "version"},
+			wantKeys: []string{"email", "version"}},
+		{name: "single user, all fields", email: "ada@example.com", wantStatus: http.StatusOK,
+			wantKeys: []string{"email", "lastname", "version"}},
+		{name: "list", path: "/users", fields: "email", wantStatus: http.StatusOK, wantExpression: "#f0",
+			wantNames: []string{"email"}, wantKeys: []string{"email"}},
+		{name: "unknown field", email: "ada@example.com", fields: "email,password", wantStatus: http.StatusBadRequest},
+	}
+	item := map[string]*dynamodb.AttributeValue{
+		"email":    {S: aws.String("ada@example.com")},
+		"lastname": {S: aws.String("Lovelace")},
+		"version":  {N: aws.String("3")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := mocks.NewFakeDynamo()
+			fake.OnGetItem(func(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+				return &dynamodb.GetItemOutput{Item: item}, nil
+			})
+			fake.OnScan(func(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+				return &dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{item}, Count: aws.Int64(1)}, nil
+			})
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: tt.path,
+				QueryStringParameters: map[string]string{"skipCount": "true"}}
+			if tt.email != "" {
+				req.PathParameters = map[string]string{"email": tt.email}
+			}
+			if tt.fields != "" {
+				req.QueryStringParameters["fields"] = tt.fields
+			}
+
+			resp, err := GetUser(req, user.NewDynamoRepository("users", fake), nil)
+			if err != nil {
+				t.Fatalf("GetUser() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if !strings.Contains(resp.Body, user.ErrorUnknownFields) {
+					t.Errorf("body = %s, want it to list the valid fields", resp.Body)
+				}
+				return
+			}
+
+			var expression *string
+			var names map[string]*string
+			if tt.email != "" {
+				input := fake.Inputs("GetItem")[0].(*dynamodb.GetItemInput)
+				expression, names = input.ProjectionExpression, input.ExpressionAttributeNames
+			} else {
+				input := fake.Inputs("Scan")[0].(*dynamodb.ScanInput)
+				expression, names = input.ProjectionExpression, input.ExpressionAttributeNames
+			}
+			if aws.StringValue(expression) != tt.wantExpression {
+				t.Errorf("ProjectionExpression = %q, want %q", aws.StringValue(expression), tt.wantExpression)
+			}
+			var projected []string
+			for _, placeholder := range strings.Split(aws.StringValue(expression), ", ") {
+				if name := names[placeholder]; name != nil {
+					projected = append(projected, *name)
+				}
+			}
+			sort.Strings(projected)
+			if !reflect.DeepEqual(projected, tt.wantNames) {
+				t.Errorf("projected attributes = %v, want %v", projected, tt.wantNames)
+			}
+			if keys := responseKeys(t, resp.Body, tt.email == ""); !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("response fields = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+// responseKeys returns the sorted non-empty fields of the user in a response body, or of
+// the first user of a list.
+func responseKeys(t *testing.T, body string, list bool) []string {
+	t.Helper()
+	var document map[string]interface{}
+	if list {
+		var users []map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &users); err != nil || len(users) != 1 {
+			t.Fatalf("body %s isn't a list of one user: %v", body, err)
+		}
+		document = users[0]
+	} else if err := json.Unmarshal([]byte(body), &document); err != nil {
+		t.Fatalf("body %s isn't a user: %v", body, err)
+	}
+
+	var keys []string
+	for key, value := range document {
+		if value != nil && value != "" && value != false && value != float64(0) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestFilterWithEmail(t *testing.T) {
+	tests := []struct {
+		name       string
+		email      string
+		query      map[string]string
+		wantStatus int
+	}{
+		{name: "filter alone", query: map[string]string{"q": "Ada"}, wantStatus: http.StatusOK},
+		{name: "email alone", email: "ada@example.com", wantStatus: http.StatusOK},
+		{name: "email and q", email: "ada@example.com", query: map[string]string{"q": "Ada"},
+			wantStatus: http.StatusBadRequest},
+		{name: "email and firstname", email: "ada@example.com", query: map[string]string{"firstname": "Ada"},
+			wantStatus: http.StatusBadRequest},
+		{name: "email and lastname", email: "ada@example.com", query: map[string]string{"lastname": "Lovelace"},
+			wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := user.NewMemoryRepository()
+			if err := repo.Create(&user.User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace"}); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users", QueryStringParameters: tt.query}
+			if tt.email != "" {
+				req.PathParameters = map[string]string{"email": tt.email}
+			}
+
+			resp, err := GetUser(req, repo, nil)
+			if err != nil {
+				t.Fatalf("GetUser() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			if tt.wantStatus == http.StatusBadRequest && !strings.Contains(resp.Body, ErrorFilterWithEmail) {
+				t.Errorf("body = %s, want %q", resp.Body, ErrorFilterWithEmail)
+			}
+		})
+	}
+}
+
+func TestSortParameter(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      map[string]string
+		wantStatus int
+		want       []string
+	}{
+		{name: "by lastname", query: map[string]string{"sort": "lastname"}, wantStatus: http.StatusOK,
+			want: []string{"alan@example.com", "ada@example.com", "grace@example.com"}},
+		{name: "by lastname descending", query: map[string]string{"sort": "lastname", "order": "desc"},
+			wantStatus: http.StatusOK, want: []string{"grace@example.com", "ada@example.com", "alan@example.com"}},
+		{name: "unknown field", query: map[string]string{"sort": "password"}, wantStatus: http.StatusBadRequest},
+		{name: "unknown order", query: map[string]string{"sort": "email", "order": "sideways"},
+			wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := user.NewMemoryRepository()
+			for _, u := range []user.User{
+				{Email: "ada@example.com", FirstName: "Ada", LastName: "Byron"},
+				{Email: "grace@example.com", FirstName: "Grace", LastName: "Hopper"},
+				{Email: "alan@example.com", FirstName: "Alan", LastName: "babbage"},
+			} {
+				if err := repo.Create(&u); err != nil {
+					t.Fatalf("seeding: %v", err)
+				}
+			}
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users", QueryStringParameters: tt.query}
+
+			resp, err := GetUser(req, repo, nil)
+			if err != nil {
+				t.Fatalf("GetUser() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var users []user.User
+			if err := json.Unmarshal([]byte(resp.Body), &users); err != nil {
+				t.Fatalf("body %s isn't a list of users: %v", resp.Body, err)
+			}
+			var got []string
+			for _, u := range users {
+				got = append(got, u.Email)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSparseFieldsets(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    userHandler
+		method     string
+		path       string
+		email      string
+		body       string
+		query      map[string]string
+		v2         bool
+		wantStatus int
+		wantKeys   [][]string // Keys of each returned user, in order
+		wantEmails []string
+	}{
+		{name: "get", handler: GetUser, method: http.MethodGet, email: "ada@example.com",
+			query: map[string]string{"fields": "email,version"}, wantStatus: http.StatusOK,
+			wantKeys: [][]string{{"email", "version"}}},
+		{name: "get unset field", handler: GetUser, method: http.MethodGet, email: "ada@example.com",
+			query: map[string]string{"fields": "email,deletedAt,expiresAt"}, wantStatus: http.StatusOK,
+			wantKeys: [][]string{{"email"}}},
+		{name: "list sorted and paged", handler: GetUser, method: http.MethodGet, path: "/users",
+			query: map[string]string{"fields": "email", "sort": "lastname", "order": "desc", "limit": "2"}, v2: true,
+			wantStatus: http.StatusOK, wantKeys: [][]string{{"email"}, {"email"}},
+			wantEmails: []string{"ada@example.com", "grace@example.com"}},
+		{name: "create", handler: CreateUser, method: http.MethodPost, path: "/users",
+			body:  `{"email": "alan@example.com", "firstname": "Alan", "lastname": "Turing"}`,
+			query: map[string]string{"fields": "email,version"}, wantStatus: http.StatusCreated,
+			wantKeys: [][]string{{"email", "version"}}},
+		{name: "update", handler: UpdateUser, method: http.MethodPut, email: "ada@example.com",
+			body:  `{"email": "ada@example.com", "firstname": "Ada", "lastname": "King"}`,
+			query: map[string]string{"fields": "lastname"}, wantStatus: http.StatusOK, wantKeys: [][]string{{"lastname"}}},
+		{name: "unknown field", handler: GetUser, method: http.MethodGet, path: "/users",
+			query: map[string]string{"fields": "email,password"}, wantStatus: http.StatusBadRequest},
+		{name: "unknown field on create", handler: CreateUser, method: http.MethodPost, path: "/users",
+			body:  `{"email": "alan@example.com", "firstname": "Alan", "lastname": "Turing"}`,
+			query: map[string]string{"fields": "password"}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := user.NewMemoryRepository()
+			for _, u := range []user.User{
+				{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace"},
+				{Email: "grace@example.com", FirstName: "Grace", LastName: "Hopper"},
+				{Email: "bob@example.com", FirstName: "Bob", LastName: "Barker"},
+			} {
+				if err := repo.Create(&u); err != nil {
+					t.Fatalf("seeding: %v", err)
+				}
+			}
+			req := events.APIGatewayProxyRequest{HTTPMethod: tt.method, Path: tt.path, Body: tt.body,
+				QueryStringParameters: tt.query, Headers: map[string]string{"Content-Type": "application/json"}}
+			if tt.email != "" {
+				req.PathParameters = map[string]string{"email": tt.email}
+			}
+			if tt.v2 {
+				req.Headers[versionHeader] = strconv.Itoa(int(V2))
+			}
+
+			resp, err := tt.handler(req, repo, nil)
+			if err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+			EnvelopeResponse(req, resp)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			if tt.wantStatus >= http.StatusBadRequest {
+				return
+			}
+
+			body := []byte(resp.Body)
+			if tt.v2 {
+				var envelope Envelope
+				if err := json.Unmarshal(body, &envelope); err != nil {
+					t.Fatalf("body %s isn't an envelope: %v", resp.Body, err)
+				}
+				body = envelope.Data
+			}
+			var documents []map[string]interface{}
+			if list := tt.method == http.MethodGet && tt.email == ""; !list {
+				var document map[string]interface{}
+				if err := json.Unmarshal(body, &document); err != nil {
+					t.Fatalf("body %s isn't a user: %v", body, err)
+				}
+				documents = append(documents, document)
+			} else if err := json.Unmarshal(body, &documents); err != nil {
+				t.Fatalf("body %s isn't a list of users: %v", body, err)
+			}
+
+			// Every key counts, even empty ones: omitted fields must be absent from the JSON
+			var keys [][]string
+			var emails []string
+			for _, document := range documents {
+				var documentKeys []string
+				for key := range document {
+					documentKeys = append(documentKeys, key)
+				}
+				sort.Strings(documentKeys)
+				keys = append(keys, documentKeys)
+				if email, ok := document["email"].(string); ok {
+					emails = append(emails, email)
+				}
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("returned keys = %v, want %v in %s", keys, tt.wantKeys, body)
+			}
+			if tt.wantEmails != nil && !reflect.DeepEqual(emails, tt.wantEmails) {
+				t.Errorf("listed %v, want %v", emails, tt.wantEmails)
+			}
+		})
+	}
+}
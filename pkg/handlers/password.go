@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+)
+
+// ChangePassword handles POST /users/{email}/password, replacing the user's password with
+// {"currentPassword": "...", "newPassword": "..."}. A missing user and a wrong current
+// password get the same 401, so the endpoint doesn't reveal which emails exist.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the user, with both passwords in the body.
+// - repo: Repository where the user data is stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the updated user, 400 for a new password breaking the
+//     policy, or 401 if the user or current password doesn't match.
+func ChangePassword(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	fields, err := responseFields(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+	req, invalid := decodeBody(req, maxBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	result, err := user.ChangePassword(email, req.Body, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	recordMutations(req, dynaClient, mutation{action: "passwordChanged", email: result.Email, after: result})
+	resp, err := APIResponse(http.StatusOK, selectUser(result, fields))
+	resp.Headers["ETag"] = versionETag(result.Version)
+	return resp, err
+}
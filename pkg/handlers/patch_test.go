@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"testing"
+)
+
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentType  string
+		ifMatch      string
+		body         string
+		wantStatus   int
+		wantLastname string
+	}{
+		{name: "merge patch", contentType: MediaTypeMergePatch, body: `{"lastname": "King"}`, wantStatus: http.StatusOK,
+			wantLastname: "King"},
+		{name: "plain JSON", contentType: "application/json; charset=utf-8", body: `{"lastname": "King"}`,
+			wantStatus: http.StatusOK, wantLastname: "King"},
+		{name: "matching If-Match", contentType: MediaTypeMergePatch, ifMatch: `"1"`, body: `{"lastname": "King"}`,
+			wantStatus: http.StatusOK, wantLastname: "King"},
+		{name: "stale If-Match", contentType: MediaTypeMergePatch, ifMatch: `"7"`, body: `{"lastname": "King"}`,
+			wantStatus: http.StatusPreconditionFailed, wantLastname: "Lovelace"},
+		{name: "not JSON", contentType: "text/plain", body: `{"lastname": "King"}`,
+			wantStatus: http.StatusUnsupportedMediaType, wantLastname: "Lovelace"},
+		{name: "password", contentType: MediaTypeMergePatch, body: `{"password": "s3cret-passw0rd"}`,
+			wantStatus: http.StatusBadRequest, wantLastname: "Lovelace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := user.NewMemoryRepository()
+			stored := &user.User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 1,
+				CreatedAt: "2020-01-01T00:00:00Z", UpdatedAt: "2020-01-01T00:00:00Z"}
+			if err := repo.Create(stored); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+			req := events.APIGatewayProxyRequest{
+				HTTPMethod:     http.MethodPatch,
+				PathParameters: map[string]string{"email": stored.Email},
+				Headers:        map[string]string{"Content-Type": tt.contentType},
+				Body:           tt.body,
+			}
+			if tt.ifMatch != "" {
+				req.Headers["If-Match"] = tt.ifMatch
+			}
+
+			resp, err := PatchUser(req, repo, nil)
+			if err != nil {
+				t.Fatalf("PatchUser() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				var got user.User
+				if err := json.Unmarshal([]byte(resp.Body), &got); err != nil {
+					t.Fatalf("decoding the response: %v", err)
+				}
+				if resp.Headers["ETag"] != versionETag(got.Version) || got.Version != 2 {
+					t.Errorf("ETag = %q for version %d, want version 2", resp.Headers["ETag"], got.Version)
+				}
+			}
+			if current, _ := repo.Get(stored.Email, user.ReadOptions{}); current.LastName != tt.wantLastname {
+				t.Errorf("stored lastname = %q, want %q", current.LastName, tt.wantLastname)
+			}
+		})
+	}
+}
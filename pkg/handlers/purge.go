@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"strconv"
+)
+
+// Error messages for malformed bulk delete requests
+var (
+	ErrorInvalidDryRun   = "dryRun must be true or false"
+	ErrorDomainWithEmail = "domain can't be combined with email"
+)
+
+// IsBulkDeleteRequest reports whether a DELETE request targets the users of a domain
+// (DELETE /users?domain=example.com) rather than a single user.
+func IsBulkDeleteRequest(req events.APIGatewayProxyRequest) bool {
+	_, ok := req.QueryStringParameters["domain"]
+	return ok
+}
+
+// DeleteUsers handles DELETE /users?domain=example.com, deleting every user of the domain.
+// With dryRun=true the matching users are listed without deleting anything. The caller
+// must be an administrator when authentication is configured.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest with the domain and dryRun query parameters.
+// - repo: Repository storing the users.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the deleted (or matching) emails and their count,
+//     or 413 if more users match than MAX_BULK_DELETE allows.
+func DeleteUsers(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if len(email) > 0 {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorDomainWithEmail)
+	}
+
+	dryRun := false
+	if raw, ok := req.QueryStringParameters["dryRun"]; ok {
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			return APIError(http.StatusBadRequest, CodeInvalidRequest, ErrorInvalidDryRun)
+		}
+	}
+
+	result, err := user.DeleteUsersByDomain(req.QueryStringParameters["domain"], dryRun, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	if !dryRun {
+		mutations := make([]mutation, len(result.Emails))
+		for i, email := range result.Emails {
+			mutations[i] = mutation{action: "deleted", email: email}
+		}
+		recordMutations(req, dynaClient, mutations...)
+	}
+
+	status := http.StatusOK
+	if len(result.Failed) > 0 {
+		status = http.StatusMultiStatus
+	}
+	return APIResponse(status, result)
+}
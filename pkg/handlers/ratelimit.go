@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultRateLimitWindow is the length of a rate limit window unless
+// RATE_LIMIT_WINDOW_SECONDS says otherwise
+const defaultRateLimitWindow = time.Minute
+
+// maxExhaustedClients is how many exhausted clients are remembered at once, so many
+// clients can't exhaust memory
+const maxExhaustedClients = 10000
+
+// ErrorRateLimited is returned to clients past their request limit
+var ErrorRateLimited = "too many requests; retry once the rate limit window resets"
+
+// exhaustedClients remembers, per client, the end of a window known to be exhausted, so
+// further requests in it are rejected without a DynamoDB write
+var exhaustedClients = struct {
+	sync.Mutex
+	windowEnds map[string]time.Time
+}{windowEnds: map[string]time.Time{}}
+
+// rateLimit returns the settings of the rate limiter: RATE_LIMIT_MAX requests per client
+// in each window of RATE_LIMIT_WINDOW_SECONDS (a minute by default).
+//
+// Returns:
+// - The maximum number of requests per window, or 0 if rate limiting is disabled.
+// - The window length.
+func rateLimit() (int64, time.Duration) {
+	if os.Getenv("RATE_LIMIT_TABLE") == "" {
+		return 0, 0
+	}
+	max, err := strconv.ParseInt(os.Getenv("RATE_LIMIT_MAX"), 10, 64)
+	if err != nil || max <= 0 {
+		return 0, 0
+	}
+	window := defaultRateLimitWindow
+	if seconds, err := strconv.Atoi(os.Getenv("RATE_LIMIT_WINDOW_SECONDS")); err == nil && seconds > 0 {
+		window = time.Duration(seconds) * time.Second
+	}
+	return max, window
+}
+
+// RateLimit counts a request against its client's limit in a fixed-window counter stored in
+// RATE_LIMIT_TABLE. Clients are told apart by the subject auth verified, which names the
+// API key of key callers, then by source IP.
+// It is disabled unless RATE_LIMIT_TABLE and RATE_LIMIT_MAX are set.
+//
+// Requests are let through if the counter can't be updated, so an outage of the limits
+// table doesn't take the API down with it.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to count, carrying the caller's claims if authenticated.
+// - dynaClient: DynamoDB client interface used to update the counter.
+//
+// Returns:
+// - A 429 response with a Retry-After header if the client is past its limit, or nil.
+func RateLimit(req events.APIGatewayProxyRequest, dynaClient dynamodbiface.DynamoDBAPI) *events.APIGatewayProxyResponse {
+	max, window := rateLimit()
+	if max == 0 {
+		return nil
+	}
+
+	client := rateLimitClient(req)
+	now := time.Now()
+	start := now.Truncate(window)
+	end := start.Add(window)
+	if windowExhausted(client, now) {
+		return tooManyRequests(end.Sub(now))
+	}
+
+	count, err := countRequest(client+"#"+strconv.FormatInt(start.Unix(), 10), end, dynaClient)
+	if err != nil {
+		logging.Default.Error("failed to update rate limit counter", logging.Fields{
+			"requestId": req.RequestContext.RequestID,
+			"error":     err,
+		})
+		return nil
+	}
+	if count > max {
+		markExhausted(client, end)
+		return tooManyRequests(end.Sub(now))
+	}
+	return nil
+}
+
+// rateLimitClient names the client of a request: the subject of its claims, such as
+// "apikey:<name>" once RequireAuth has validated its API key, or its source IP. The
+// X-Api-Key header itself is never used, so callers can't get a fresh window by sending a
+// made-up key, as they could on login or where API keys are disabled.
+func rateLimitClient(req events.APIGatewayProxyRequest) string {
+	claims, _ := req.RequestContext.Authorizer["claims"].(map[string]interface{})
+	if subject, _ := claims["sub"].(string); subject != "" {
+		return "sub:" + subject
+	}
+	// Local runs report the remote address with its port
+	ip := req.RequestContext.Identity.SourceIP
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return "ip:" + ip
+}
+
+// countRequest atomically increments the counter of a client's window. Counters expire
+// with the window through the table's expiresAt TTL attribute.
+//
+// Returns:
+// - The number of requests counted in the window, including this one.
+// - An error if the update fails.
+func countRequest(limitKey string, windowEnd time.Time, dynaClient dynamodbiface.DynamoDBAPI) (int64, error) {
+	input := &dynamodb.UpdateItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"limitKey": {
+				S: aws.String(limitKey),
+			},
+		},
+		TableName:        aws.String(os.Getenv("RATE_LIMIT_TABLE")),
+		UpdateExpression: aws.String("ADD #count :one SET #expiresAt = :expiresAt"),
+		ExpressionAttributeNames: map[string]*string{
+			"#count":     aws.String("count"),
+			"#expiresAt": aws.String("expiresAt"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":one":       {N: aws.String("1")},
+			":expiresAt": {N: aws.String(strconv.FormatInt(windowEnd.Unix(), 10))},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueUpdatedNew),
+	}
+
+	result, err := dynaClient.UpdateItem(input)
+	if err != nil {
+		return 0, err
+	}
+	count := result.Attributes["count"]
+	if count == nil {
+		return 0, errors.New("rate limit counter missing from the update result")
+	}
+	return strconv.ParseInt(aws.StringValue(count.N), 10, 64)
+}
+
+// windowExhausted reports whether the client's current window is known to be exhausted.
+func windowExhausted(client string, now time.Time) bool {
+	exhaustedClients.Lock()
+	defer exhaustedClients.Unlock()
+	end, ok := exhaustedClients.windowEnds[client]
+	if ok && !now.Before(end) {
+		delete(exhaustedClients.windowEnds, client)
+		return false
+	}
+	return ok
+}
+
+// markExhausted remembers that the client's window is exhausted until end, forgetting every
+// client once too many are remembered.
+func markExhausted(client string, end time.Time) {
+	exhaustedClients.Lock()
+	defer exhaustedClients.Unlock()
+	if _, ok := exhaustedClients.windowEnds[client]; !ok && len(exhaustedClients.windowEnds) >= maxExhaustedClients {
+		exhaustedClients.windowEnds = map[string]time.Time{}
+	}
+	exhaustedClients.windowEnds[client] = end
+}
+
+// tooManyRequests builds a 429 response whose Retry-After header gives the whole seconds
+// left in the window.
+func tooManyRequests(remaining time.Duration) *events.APIGatewayProxyResponse {
+	seconds := int64((remaining + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	resp, _ := APIError(http.StatusTooManyRequests, CodeTooManyRequests, ErrorRateLimited)
+	resp.Headers["Retry-After"] = strconv.FormatInt(seconds, 10)
+	return resp
+}
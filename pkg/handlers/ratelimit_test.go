@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		apiKey   string
+		claims   map[string]interface{}
+		sourceIP string
+		want     string
+	}{
+		{name: "source IP", sourceIP: "192.0.2.1", want: "ip:192.0.2.1"},
+		{name: "source IP with port", sourceIP: "192.0.2.1:5000", want: "ip:192.0.2.1"},
+		{name: "unvalidated API key", apiKey: "made-up", sourceIP: "192.0.2.1", want: "ip:192.0.2.1"},
+		{name: "token subject", claims: map[string]interface{}{"sub": "ada"}, sourceIP: "192.0.2.1", want: "sub:ada"},
+		{name: "validated API key", apiKey: "secret", claims: map[string]interface{}{"sub": "apikey:reports"},
+			sourceIP: "192.0.2.1", want: "sub:apikey:reports"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := events.APIGatewayProxyRequest{Headers: map[string]string{}}
+			if tt.apiKey != "" {
+				req.Headers["X-Api-Key"] = tt.apiKey
+			}
+			if tt.claims != nil {
+				req.RequestContext.Authorizer = map[string]interface{}{"claims": tt.claims}
+			}
+			req.RequestContext.Identity.SourceIP = tt.sourceIP
+			if got := rateLimitClient(req); got != tt.want {
+				t.Errorf("rateLimitClient() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitIgnoresMadeUpKeys(t *testing.T) {
+	t.Setenv("RATE_LIMIT_TABLE", "limits")
+	t.Setenv("RATE_LIMIT_MAX", "3")
+	t.Setenv("RATE_LIMIT_WINDOW_SECONDS", "3600")
+	counts := map[string]int64{}
+	fake := mocks.NewFakeDynamo()
+	fake.OnUpdateItem(func(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+		key := aws.StringValue(in.Key["limitKey"].S)
+		counts[key]++
+		return &dynamodb.UpdateItemOutput{Attributes: map[string]*dynamodb.AttributeValue{
+			"count": {N: aws.String(strconv.FormatInt(counts[key], 10))}}}, nil
+	})
+	defer func() { exhaustedClients.windowEnds = map[string]time.Time{} }()
+
+	// Every login attempt sends a new key, as a client trying to dodge the limit would
+	var statuses []int
+	for i := 0; i < 5; i++ {
+		req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: LoginPath,
+			Headers: map[string]string{"X-Api-Key": "key-" + strconv.Itoa(i)}}
+		req.RequestContext.Identity.SourceIP = "198.51.100.7"
+		status := http.StatusOK
+		if resp := RateLimit(req, fake); resp != nil {
+			status = resp.StatusCode
+		}
+		statuses = append(statuses, status)
+	}
+
+	want := []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", statuses, want)
+		}
+	}
+	for key := range counts {
+		if !strings.HasPrefix(key, "ip:198.51.100.7#") {
+			t.Errorf("counted under %q, want the source IP", key)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"net/http"
+)
+
+// RequestIDHeader carries the ID of a request: upstream systems may send one, and every
+// response returns the ID the request was served under
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds the inbound request IDs that are trusted, since they end up
+// in every log line of the request
+const maxRequestIDLength = 128
+
+// ResolveRequestID decides once under which ID a request is logged, audited and answered.
+// An X-Request-Id header sent by an upstream system is preferred, so its logs and ours can
+// be joined; otherwise the API Gateway request ID is kept. Inbound IDs that are too long or
+// contain anything but printable ASCII are ignored.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to resolve.
+//
+// Returns:
+// - The request, with the ID to use in RequestContext.RequestID.
+func ResolveRequestID(req events.APIGatewayProxyRequest) events.APIGatewayProxyRequest {
+	if id := headerValue(req, RequestIDHeader); isRequestIDValid(id) {
+		req.RequestContext.RequestID = id
+	}
+	return req
+}
+
+// isRequestIDValid reports whether an inbound request ID is 1 to maxRequestIDLength
+// printable ASCII characters without spaces.
+func isRequestIDValid(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// AddRequestID returns the request ID in the X-Request-Id header of a response and, for
+// errors, as "requestId" in the body, so a client reporting an error can quote it. Error
+// bodies that aren't an ErrorBody, such as the one of an unhealthy check, only get the header.
+// It must run before the response is enveloped, negotiated or compressed.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest the response answers.
+// - resp: The response to decorate, modified in place.
+func AddRequestID(req events.APIGatewayProxyRequest, resp *events.APIGatewayProxyResponse) {
+	id := req.RequestContext.RequestID
+	if resp == nil || id == "" {
+		return
+	}
+	if resp.Headers == nil {
+		resp.Headers = map[string]string{}
+	}
+	resp.Headers[RequestIDHeader] = id
+
+	if resp.StatusCode < http.StatusBadRequest || resp.IsBase64Encoded {
+		return
+	}
+	var body ErrorBody
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil || body.Code == nil {
+		return
+	}
+	body.RequestID = aws.String(id)
+	if encoded, err := json.Marshal(body); err == nil {
+		resp.Body = string(encoded)
+	}
+}
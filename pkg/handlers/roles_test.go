@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"testing"
+)
+
+// roleCallers are the callers of the role matrix, by the claims an authorizer attaches
+var roleCallers = map[string]map[string]interface{}{
+	"anonymous":   nil,
+	"self":        {"sub": "ada", "email": "ada@example.com"},
+	"other user":  {"sub": "bob", "email": "bob@example.com"},
+	"admin group": {"sub": "root", "email": "root@example.com", "cognito:groups": "[admins ops]"},
+	"admin scope": {"sub": "apikey:ops", "scope": "read write admin"},
+}
+
+// roleOperations are the operations of the role matrix, each run against a fresh
+// repository holding the users ada and bob
+var roleOperations = map[string]func(req events.APIGatewayProxyRequest, repo user.Repository) (
+	*events.APIGatewayProxyResponse, error){
+	"list": func(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+		req.HTTPMethod = http.MethodGet
+		return GetUser(req, repo, nil)
+	},
+	"batch create": func(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+		req.HTTPMethod = http.MethodPost
+		req.Body = `[{"email": "carol@example.com", "firstname": "Carol", "lastname": "Shaw"}]`
+		return CreateUsers(req, repo, nil)
+	},
+	"delete self": func(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+		req.HTTPMethod = http.MethodDelete
+		req.PathParameters = map[string]string{"email": "ada@example.com"}
+		return DeleteUser(req, repo, nil)
+	},
+	"delete other": func(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+		req.HTTPMethod = http.MethodDelete
+		req.PathParameters = map[string]string{"email": "bob@example.com"}
+		return DeleteUser(req, repo, nil)
+	},
+	"create admin": func(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+		req.HTTPMethod = http.MethodPost
+		req.Body = `{"email": "carol@example.com", "firstname": "Carol", "lastname": "Shaw", "role": "admin"}`
+		return CreateUser(req, repo, nil)
+	},
+	"read other": func(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+		req.HTTPMethod = http.MethodGet
+		req.PathParameters = map[string]string{"email": "bob@example.com"}
+		return GetUser(req, repo, nil)
+	},
+	"update other": func(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+		req.HTTPMethod = http.MethodPut
+		req.PathParameters = map[string]string{"email": "bob@example.com"}
+		req.Body = `{"firstname": "Bob", "lastname": "Metcalfe"}`
+		return UpdateUser(req, repo, nil)
+	},
+	"patch other": func(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+		req.HTTPMethod = http.MethodPatch
+		req.PathParameters = map[string]string{"email": "bob@example.com"}
+		req.Body = `{"lastname": "Metcalfe"}`
+		return PatchUser(req, repo, nil)
+	},
+	"promote with PUT": func(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+		req.HTTPMethod = http.MethodPut
+		req.PathParameters = map[string]string{"email": "ada@example.com"}
+		req.Body = `{"firstname": "Ada", "lastname": "Lovelace", "role": "admin"}`
+		return UpdateUser(req, repo, nil)
+	},
+	"promote with PATCH": func(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+		req.HTTPMethod = http.MethodPatch
+		req.PathParameters = map[string]string{"email": "ada@example.com"}
+		req.Body = `{"role": "admin"}`
+		return PatchUser(req, repo, nil)
+	},
+}
+
+func TestRoleMatrix(t *testing.T) {
+	const (
+		ok        = http.StatusOK
+		created   = http.StatusCreated
+		anonymous = http.StatusUnauthorized
+		forbidden = http.StatusForbidden
+	)
+	tests := []struct {
+		caller     string
+		operation  string
+		wantStatus int
+		wantRole   string // Role of the created or promoted user
+	}{
+		{"anonymous", "list", anonymous, ""},
+		{"self", "list", forbidden, ""},
+		{"other user", "list", forbidden, ""},
+		{"admin group", "list", ok, ""},
+		{"admin scope", "list", ok, ""},
+
+		{"anonymous", "batch create", anonymous, ""},
+		{"self", "batch create", forbidden, ""},
+		{"admin group", "batch create", created, ""},
+		{"admin scope", "batch create", created, ""},
+
+		{"anonymous", "delete self", anonymous, ""},
+		{"self", "delete self", ok, ""},
+		{"other user", "delete self", forbidden, ""},
+		{"admin group", "delete self", ok, ""},
+
+		{"self", "delete other", forbidden, ""},
+		{"other user", "delete other", ok, ""},
+		{"admin scope", "delete other", ok, ""},
+
+		{"self", "read other", forbidden, ""},
+		{"other user", "read other", ok, ""},
+		{"admin group", "read other", ok, ""},
+		{"self", "update other", forbidden, ""},
+		{"admin scope", "update other", ok, ""},
+		{"self", "patch other", forbidden, ""},
+		{"admin group", "patch other", ok, ""},
+
+		{"anonymous", "create admin", created, user.RoleUser},
+		{"self", "create admin", created, user.RoleUser},
+		{"admin group", "create admin", created, user.RoleAdmin},
+		{"admin scope", "create admin", created, user.RoleAdmin},
+
+		{"anonymous", "promote with PUT", anonymous, user.RoleUser},
+		{"self", "promote with PUT", ok, user.RoleUser},
+		{"other user", "promote with PUT", forbidden, user.RoleUser},
+		{"admin group", "promote with PUT", ok, user.RoleAdmin},
+		{"admin scope", "promote with PUT", ok, user.RoleAdmin},
+
+		{"anonymous", "promote with PATCH", anonymous, user.RoleUser},
+		{"self", "promote with PATCH", ok, user.RoleUser},
+		{"other user", "promote with PATCH", forbidden, user.RoleUser},
+		{"admin group", "promote with PATCH", ok, user.RoleAdmin},
+		{"admin scope", "promote with PATCH", ok, user.RoleAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caller+"/"+tt.operation, func(t *testing.T) {
+			t.Setenv("ENFORCE_CALLER_ACCESS", "true")
+			got, role := runRoleOperation(t, tt.caller, tt.operation)
+			if got != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got, tt.wantStatus)
+			}
+			if tt.wantRole != "" && role != tt.wantRole {
+				t.Errorf("role = %q, want %q", role, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestOwnUserWithTokens(t *testing.T) {
+	// Bearer tokens alone, without ENFORCE_CALLER_ACCESS, confine callers to their own user
+	tests := []struct {
+		caller     string
+		operation  string
+		wantStatus int
+	}{
+		{"self", "read other", http.StatusForbidden},
+		{"self", "update other", http.StatusForbidden},
+		{"self", "patch other", http.StatusForbidden},
+		{"self", "delete other", http.StatusForbidden},
+		{"other user", "read other", http.StatusOK},
+		{"other user", "patch other", http.StatusOK},
+		{"admin scope", "update other", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caller+"/"+tt.operation, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", "secret")
+			if got, _ := runRoleOperation(t, tt.caller, tt.operation); got != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRolesWithoutAuthentication(t *testing.T) {
+	// Without authentication everyone may list and delete, but nobody is an administrator
+	tests := []struct {
+		operation  string
+		wantStatus int
+		wantRole   string
+	}{
+		{"list", http.StatusOK, ""},
+		{"delete other", http.StatusOK, ""},
+		{"create admin", http.StatusCreated, user.RoleUser},
+		{"promote with PUT", http.StatusOK, user.RoleUser},
+		{"promote with PATCH", http.StatusOK, user.RoleUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			got, role := runRoleOperation(t, "anonymous", tt.operation)
+			if got != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got, tt.wantStatus)
+			}
+			if tt.wantRole != "" && role != tt.wantRole {
+				t.Errorf("role = %q, want %q", role, tt.wantRole)
+			}
+		})
+	}
+}
+
+// runRoleOperation runs an operation of the matrix as a caller, returning the status and
+// the stored role of the user the operation creates or promotes.
+func runRoleOperation(t *testing.T, caller string, operation string) (int, string) {
+	t.Helper()
+	repo := user.NewMemoryRepository()
+	for _, seeded := range []user.User{
+		{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Role: user.RoleUser, Version: 1},
+		{Email: "bob@example.com", FirstName: "Bob", LastName: "Kahn", Role: user.RoleUser, Version: 1},
+	} {
+		seeded := seeded
+		if err := repo.Create(&seeded); err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+
+	req := events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "application/json"}}
+	if claims := roleCallers[caller]; claims != nil {
+		req.RequestContext.Authorizer = map[string]interface{}{"claims": claims}
+	}
+	resp, err := roleOperations[operation](req, repo)
+	if err != nil {
+		t.Fatalf("%s error = %v", operation, err)
+	}
+
+	role := ""
+	for _, email := range []string{"carol@example.com", "ada@example.com"} {
+		if u, err := repo.Get(email, user.ReadOptions{}); err == nil {
+			role = u.Role
+			break
+		}
+	}
+	return resp.StatusCode, role
+}
@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/audit"
+	"github.com/Vansh3140/golang-serverless/pkg/org"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Route templates of the organizations resource
+const (
+	OrgsRoute       = "/orgs"
+	OrgRoute        = "/orgs/{slug}"
+	OrgMembersRoute = "/orgs/{slug}/members"
+	OrgMemberRoute  = "/orgs/{slug}/members/{email}"
+)
+
+// methodOrder is the order methods are advertised in the Allow header
+var methodOrder = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+	http.MethodOptions}
+
+// HandlerFunc serves a request matched by a Route
+type HandlerFunc func(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error)
+
+// Param describes a query parameter of an operation
+type Param struct {
+	Name        string // Name of the parameter, e.g. "limit"
+	Type        string // JSON Schema type of its value: "string", "integer" or "boolean"
+	Description string // What the parameter does
+}
+
+// Operation is what a route does for one HTTP method: the handler serving it, and the
+// metadata the OpenAPI document is generated from.
+type Operation struct {
+	Handler      HandlerFunc // Serves the request; nil for routes answered before routing, such as /health
+	Summary      string      // One-line description of the operation
+	Query        []Param     // Query parameters the operation reads
+	Body         interface{} // Zero value of the request body, or nil if it takes none
+	BodyType     string      // Media type of the body if it isn't JSON, e.g. MediaTypeCSV
+	Status       int         // Status of a successful response
+	Response     interface{} // Zero value of the response body, or nil if it has none
+	ResponseType string      // Media type of the response if it isn't JSON, e.g. MediaTypeCSV
+	Paginated    bool        // Whether the response is a page read with limit and cursor
+	Errors       []ErrorCode // Codes the operation fails with, besides those of every request
+}
+
+// Route maps a path template, e.g. "/orgs/{slug}", to the operation of each method it
+// answers. HEAD and OPTIONS are answered wherever GET is and everywhere, respectively.
+type Route struct {
+	Template string               // Path template, with {name} standing for one path segment
+	Methods  map[string]Operation // Operations by HTTP method
+}
+
+// routeTable holds the routes, built on first use
+var routeTable struct {
+	once   sync.Once
+	routes []Route
+}
+
+// Routes returns the routes of the API. Fixed paths such as /users/count come before the
+// templates that would otherwise match them, such as /users/{email}.
+//
+// Returns:
+// - The routes, matched in order.
+func Routes() []Route {
+	routeTable.once.Do(func() {
+		routeTable.routes = buildRoutes()
+	})
+	return routeTable.routes
+}
+
+// Query parameters shared by several operations
+var (
+	readQuery = []Param{
+		{"fields", "string", "Comma-separated fields to return, e.g. email,firstname"},
+		{"consistent", "boolean", "Read with strong consistency, overriding CONSISTENT_READS"},
+		{"includeDeleted", "boolean", "Also return soft-deleted users"},
+	}
+	filterQuery = []Param{
+		{"firstname", "string", "Exact first name"},
+		{"lastname", "string", "Exact last name"},
+		{"q", "string", "Substring of either name"},
+		{"status", "string", "Account status, e.g. suspended"},
+		{"createdAfter", "string", "RFC3339 time the users were created at or after"},
+		{"createdBefore", "string", "RFC3339 time the users were created at or before"},
+	}
+	listQuery = concatParams(readQuery, filterQuery, []Param{
+		{"email", "string", "Email of a single user to return instead of the list"},
+		{"emails", "string", "Comma-separated emails of the users to return instead of the list"},
+		{"sort", "string", "Field to sort by: lastname, firstname, email or createdAt"},
+		{"order", "string", "Sort order: asc or desc"},
+		{"skipCount", "boolean", "Leave out X-Total-Count, sparing the count"},
+	})
+	fieldsQuery = []Param{{"fields", "string", "Comma-separated fields of the returned user"}}
+	cursorQuery = []Param{
+		{"limit", "integer", "Most items the page holds"},
+		{"cursor", "string", "nextCursor of the previous page"},
+	}
+)
+
+// Error codes shared by several operations
+var (
+	userBodyErrors = []ErrorCode{
+		CodeValidation, CodeInvalidUserData, CodeInvalidEmail, CodeInvalidFirstName, CodeInvalidLastName,
+		CodeEmptyBody, CodeMalformedJSON, CodeUnknownField, CodeInvalidFieldType, CodeMissingField, CodeInvalidExpiresAt,
+		CodeExpiryInPast, CodeExpiryTooFar, CodeInvalidTTLDays, CodeTTLAndExpiresAt, CodeInvalidAddress,
+		CodeInvalidCountry, CodeMissingPostalCode, CodeTooManyTags, CodeInvalidTagKey, CodeReservedTagKey,
+		CodeTagValueTooLong, CodeInvalidStatus, CodeInvalidRole, CodeRoleChangeForbidden,
+		CodeDisposableEmail, CodeDomainRejectsMail, CodeDomainNotAllowed, CodeValidationFailed,
+	}
+	listErrors = []ErrorCode{
+		CodeInvalidRequest, CodeInvalidSort, CodeInvalidOrder, CodeTooManyEmails, CodeEncryptedFieldFilter,
+		CodeNotAcceptable,
+	}
+	writeErrors = []ErrorCode{
+		CodeInvalidRequest, CodeUserNotFound, CodeVersionMismatch, CodePreconditionRequired, CodeStorage,
+	}
+)
+
+// buildRoutes returns the route table.
+func buildRoutes() []Route {
+	return []Route{
+		{HealthPath, map[string]Operation{
+			http.MethodGet: {Summary: "Check that the users table is reachable", Status: http.StatusOK,
+				Response: HealthBody{}, Errors: []ErrorCode{CodeServiceUnavailable}},
+		}},
+		{LoginPath, map[string]Operation{
+			http.MethodPost: {Handler: repoOnly(Login), Summary: "Exchange an email and password for a token",
+				Body: user.Credentials{}, Status: http.StatusOK, Response: LoginBody{},
+				Errors: []ErrorCode{CodeInvalidCredentials, CodeUserSuspended, CodeNotFound}},
+		}},
+		{OpenAPIPath, map[string]Operation{
+			http.MethodGet: {Handler: OpenAPIDocument, Summary: "Describe the API as an OpenAPI 3.0 document",
+				Status: http.StatusOK, Response: map[string]interface{}{}},
+		}},
+		{countPath, map[string]Operation{
+			http.MethodGet: {Handler: repoOnly(CountUsers), Summary: "Count the users the filters select",
+				Query:  concatParams(filterQuery, []Param{{"includeDeleted", "boolean", "Also count soft-deleted users"}}),
+				Status: http.StatusOK, Response: CountBody{}, Errors: listErrors},
+		}},
+		{batchPath, map[string]Operation{
+			http.MethodPost: {Handler: CreateUsers, Summary: "Create several users; 207 if only some were created",
+				Query: []Param{{"onDeletedConflict", "string", "reject or overwrite a soft-deleted user with the same email"}},
+				Body:  []user.UserRequest{}, Status: http.StatusCreated, Response: BatchBody{},
+				Errors: concatCodes(userBodyErrors, []ErrorCode{CodeEmptyBatch, CodeBatchTooLarge, CodeDuplicateInBatch,
+					CodeUserExists, CodeUserDeleted, CodeIdempotencyKeyReused, CodeStorage})},
+		}},
+		{exportPath, map[string]Operation{
+			http.MethodGet: {Handler: repoOnly(ExportUsers), Summary: "Export the users the filters select as CSV",
+				Query: concatParams(readQuery, filterQuery), Status: http.StatusOK, Response: "",
+				ResponseType: MediaTypeCSV, Errors: concatCodes(listErrors, []ErrorCode{CodePayloadTooLarge})},
+		}},
+		{importPath, map[string]Operation{
+			http.MethodPost: {Handler: ImportUsers, Summary: "Create users from CSV rows",
+				Query: []Param{{"onConflict", "string", "skip or overwrite users that already exist"}},
+				Body:  "", BodyType: MediaTypeCSV, Status: http.StatusCreated, Response: ImportBody{},
+				Errors: concatCodes(userBodyErrors, []ErrorCode{CodeEmptyImport, CodeImportTooLarge, CodeMissingCSVColumn,
+					CodeDuplicateCSVColumn, CodeMalformedCSV, CodeInvalidOnConflict, CodeStorage})},
+		}},
+		{mergePath, map[string]Operation{
+			http.MethodPost: {Handler: MergeUsers, Summary: "Merge a duplicate user into a primary one",
+				Query: fieldsQuery, Body: user.MergeRequest{}, Status: http.StatusOK, Response: user.User{},
+				Errors: concatCodes(userBodyErrors, writeErrors, []ErrorCode{CodeMergeIntoSelf})},
+		}},
+		{deletedPath, map[string]Operation{
+			http.MethodGet: {Handler: repoOnly(ListDeletedUsers), Summary: "List the soft-deleted users",
+				Query: readQuery, Status: http.StatusOK, Response: []user.User{}, Paginated: true,
+				Errors: []ErrorCode{CodeInvalidRequest, CodeNotAcceptable}},
+			http.MethodDelete: {Handler: PurgeDeletedUsers, Summary: "Remove for good the users soft-deleted long enough ago",
+				Query:  []Param{{"olderThan", "string", "How long ago the users were deleted, e.g. 30d, 12h or an RFC3339 time"}},
+				Status: http.StatusOK, Response: user.BulkDeleteResult{},
+				Errors: []ErrorCode{CodeInvalidOlderThan, CodeTooManyToDelete, CodeStorage}},
+		}},
+		{duplicatesPath, map[string]Operation{
+			http.MethodGet: {Handler: repoOnly(FindDuplicates), Summary: "Find users that may be the same person",
+				Query: []Param{
+					{"firstname", "string", "First name of the person"},
+					{"lastname", "string", "Last name of the person"},
+				},
+				Status: http.StatusOK, Response: user.DuplicateResult{}, Errors: []ErrorCode{CodeValidation}},
+		}},
+		{searchPath, map[string]Operation{
+			http.MethodGet: {Handler: repoOnly(SearchUsers), Summary: "Find the users whose email starts with a prefix",
+				Query:  concatParams([]Param{{"prefix", "string", "Start of the emails, at least 2 characters"}}, cursorQuery),
+				Status: http.StatusOK, Response: []user.User{}, Errors: []ErrorCode{CodePrefixTooShort, CodeValidation}},
+		}},
+		{userActionPath(exportAction), map[string]Operation{
+			http.MethodGet: {Handler: ExportUser, Summary: "Export everything stored about a user",
+				Status: http.StatusOK, Response: user.DataExport{},
+				Errors: []ErrorCode{CodeInvalidRequest, CodeUserNotFound}},
+		}},
+		{userActionPath(auditAction), map[string]Operation{
+			http.MethodGet: {Handler: auditLog, Summary: "Read the audit log of a user, newest first",
+				Query: cursorQuery, Status: http.StatusOK, Response: audit.Page{},
+				Errors: []ErrorCode{CodeInvalidRequest, CodeNotFound}},
+		}},
+		{userActionPath(restoreAction), map[string]Operation{
+			http.MethodPost: {Handler: RestoreUser, Summary: "Restore a soft-deleted user",
+				Query: fieldsQuery, Status: http.StatusOK, Response: user.User{},
+				Errors: concatCodes(writeErrors, []ErrorCode{CodeUserNotDeleted})},
+		}},
+		{userActionPath(activateAction), map[string]Operation{
+			http.MethodPost: {Handler: SetUserStatus, Summary: "Activate a user", Query: fieldsQuery,
+				Status: http.StatusOK, Response: user.User{}, Errors: concatCodes(writeErrors, []ErrorCode{CodeStatusUnchanged})},
+		}},
+		{userActionPath(deactivateAction), map[string]Operation{
+			http.MethodPost: {Handler: SetUserStatus, Summary: "Deactivate a user", Query: fieldsQuery,
+				Status: http.StatusOK, Response: user.User{}, Errors: concatCodes(writeErrors, []ErrorCode{CodeStatusUnchanged})},
+		}},
+		{userActionPath(changeEmailAction), map[string]Operation{
+			http.MethodPost: {Handler: ChangeEmail, Summary: "Move a user to a new email", Query: fieldsQuery,
+				Body: user.EmailChangeRequest{}, Status: http.StatusOK, Response: user.User{},
+				Errors: concatCodes(userBodyErrors, writeErrors, []ErrorCode{CodeEmailUnchanged, CodeUserExists})},
+		}},
+		{userActionPath(passwordAction), map[string]Operation{
+			http.MethodPost: {Handler: ChangePassword, Summary: "Change the password of a user", Query: fieldsQuery,
+				Body: user.PasswordChangeRequest{}, Status: http.StatusOK, Response: user.User{},
+				Errors: concatCodes(writeErrors, []ErrorCode{CodeInvalidPassword, CodeInvalidCredentials})},
+		}},
+		{userActionPath(verifyAction), map[string]Operation{
+			http.MethodPost: {Handler: VerifyEmail, Summary: "Prove a user owns its email with the token sent to it",
+				Query: fieldsQuery, Body: user.VerifyRequest{}, Status: http.StatusOK, Response: user.User{},
+				Errors: concatCodes(writeErrors, []ErrorCode{CodeInvalidVerifyToken, CodeVerifyTokenExpired,
+					CodeAlreadyVerified})},
+		}},
+		{userActionPath(resendAction), map[string]Operation{
+			http.MethodPost: {Handler: repoOnly(ResendVerification), Summary: "Send a new verification token",
+				Status: http.StatusAccepted, Response: map[string]string{},
+				Errors: []ErrorCode{CodeInvalidRequest, CodeUserNotFound, CodeAlreadyVerified, CodeTooManyVerifySends}},
+		}},
+		{usersPathPrefix + "{email}", map[string]Operation{
+			http.MethodGet: {Handler: GetUser, Summary: "Read a user; 304 if it matches If-None-Match",
+				Query:  concatParams(readQuery, []Param{{"include", "string", "orgs to add the user's memberships"}}),
+				Status: http.StatusOK, Response: user.User{},
+				Errors: []ErrorCode{CodeInvalidRequest, CodeUserNotFound, CodeNotAcceptable}},
+			http.MethodHead: {Handler: repoOnly(HeadUser), Summary: "Check whether a user exists, reading only its ETag",
+				Status: http.StatusOK, Errors: []ErrorCode{CodeInvalidRequest, CodeUserNotFound}},
+			http.MethodPut: {Handler: UpdateUser, Summary: "Update a user; send If-Match to avoid lost updates",
+				Query: fieldsQuery, Body: user.UserRequest{}, Status: http.StatusOK, Response: user.User{},
+				Errors: concatCodes(userBodyErrors, writeErrors, []ErrorCode{CodeEmailImmutable, CodePasswordImmutable})},
+			http.MethodPatch: {Handler: PatchUser, Summary: "Change some fields of a user with a JSON merge patch",
+				Query: fieldsQuery, Body: user.UserRequest{}, BodyType: MediaTypeMergePatch, Status: http.StatusOK,
+				Response: user.User{},
+				Errors:   concatCodes(userBodyErrors, writeErrors, []ErrorCode{CodeEmailImmutable, CodePasswordImmutable})},
+			http.MethodDelete: {Handler: DeleteUser, Summary: "Soft-delete a user, or remove it for good with hard=true",
+				Query:  []Param{{"hard", "boolean", "Remove the user for good instead of soft-deleting it"}},
+				Status: http.StatusOK, Response: "", Errors: writeErrors},
+		}},
+		{usersPath, map[string]Operation{
+			http.MethodGet: {Handler: GetUser, Summary: "List the users the filters select; tag.<key>=<value> filters on a tag",
+				Query:  listQuery,
+				Status: http.StatusOK, Response: []user.User{}, Paginated: true, Errors: listErrors},
+			http.MethodHead: {Handler: repoOnly(HeadUser), Summary: "Count the users the filters select in X-Total-Count",
+				Query:  concatParams(filterQuery, []Param{{"email", "string", "Email of a single user to check instead"}}),
+				Status: http.StatusOK, Errors: listErrors},
+			http.MethodPost: {Handler: CreateUser, Summary: "Create a user",
+				Query: concatParams(fieldsQuery, []Param{
+					{"onDeletedConflict", "string", "reject or overwrite a soft-deleted user with the same email"},
+				}),
+				Body: user.UserRequest{}, Status: http.StatusCreated, Response: user.User{},
+				Errors: concatCodes(userBodyErrors, []ErrorCode{CodeUserExists, CodeUserDeleted, CodeIdempotencyKeyReused,
+					CodeIdempotencyKeyInProgress, CodeInvalidPassword, CodeStorage})},
+			http.MethodPut: {Handler: UpdateUser, Summary: "Update the user named by the email query parameter",
+				Query: concatParams(fieldsQuery, []Param{{"email", "string", "Email of the user to update"}}),
+				Body:  user.UserRequest{}, Status: http.StatusOK, Response: user.User{},
+				Errors: concatCodes(userBodyErrors, writeErrors, []ErrorCode{CodeEmailImmutable, CodePasswordImmutable})},
+			http.MethodDelete: {Handler: deleteUsers, Summary: "Remove every user of a domain, or the user named by email",
+				Query: []Param{
+					{"domain", "string", "Domain whose users are all removed"},
+					{"dryRun", "boolean", "Count the users of the domain without removing them"},
+					{"email", "string", "Email of a single user to delete instead"},
+				},
+				Status: http.StatusOK, Response: user.BulkDeleteResult{},
+				Errors: concatCodes(writeErrors, []ErrorCode{CodeInvalidDomain, CodeTooManyToDelete})},
+		}},
+		{OrgsRoute, map[string]Operation{
+			http.MethodGet: {Handler: ListOrgs, Summary: "List the organizations", Status: http.StatusOK,
+				Response: []org.Org{}, Errors: []ErrorCode{CodeNotFound}},
+			http.MethodPost: {Handler: CreateOrg, Summary: "Create an organization", Body: orgRequest{},
+				Status: http.StatusCreated, Response: org.Org{},
+				Errors: []ErrorCode{CodeNotFound, CodeInvalidSlug, CodeInvalidOrgName, CodeOrgExists}},
+		}},
+		{OrgRoute, map[string]Operation{
+			http.MethodGet: {Handler: GetOrg, Summary: "Read an organization", Status: http.StatusOK,
+				Response: org.Org{}, Errors: []ErrorCode{CodeOrgNotFound}},
+			http.MethodDelete: {Handler: DeleteOrg, Summary: "Delete an organization and its memberships",
+				Status: http.StatusOK, Response: "", Errors: []ErrorCode{CodeOrgNotFound}},
+		}},
+		{OrgMembersRoute, map[string]Operation{
+			http.MethodGet: {Handler: ListOrgMembers, Summary: "List the members of an organization",
+				Status: http.StatusOK, Response: []org.Membership{}, Errors: []ErrorCode{CodeOrgNotFound}},
+			http.MethodPost: {Handler: AddOrgMember, Summary: "Add a user to an organization", Body: memberRequest{},
+				Status: http.StatusCreated, Response: org.Membership{},
+				Errors: []ErrorCode{CodeOrgNotFound, CodeUserNotFound, CodeAlreadyMember}},
+		}},
+		{OrgMemberRoute, map[string]Operation{
+			http.MethodDelete: {Handler: RemoveOrgMember, Summary: "Remove a user from an organization",
+				Status: http.StatusOK, Response: "", Errors: []ErrorCode{CodeOrgNotFound, CodeNotMember}},
+		}},
+	}
+}
+
+// repoOnly adapts a handler that needs no DynamoDB client to a HandlerFunc.
+func repoOnly(handle func(req events.APIGatewayProxyRequest, repo user.Repository) (
+	*events.APIGatewayProxyResponse, error)) HandlerFunc {
+	return func(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+		*events.APIGatewayProxyResponse, error) {
+		return handle(req, repo)
+	}
+}
+
+// auditLog serves AuditLog as a HandlerFunc.
+func auditLog(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	return AuditLog(req, dynaClient)
+}
+
+// deleteUsers serves DELETE /users: the users of a domain with ?domain=, or else the user
+// named by ?email=.
+func deleteUsers(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	if IsBulkDeleteRequest(req) {
+		return DeleteUsers(req, repo, dynaClient)
+	}
+	return DeleteUser(req, repo, dynaClient)
+}
+
+// userActionPath returns the route template of an action nested under a user.
+func userActionPath(action string) string {
+	return usersPathPrefix + "{email}/" + action
+}
+
+// concatParams joins lists of query parameters into a new one.
+func concatParams(lists ...[]Param) []Param {
+	var params []Param
+	for _, list := range lists {
+		params = append(params, list...)
+	}
+	return params
+}
+
+// concatCodes joins lists of error codes into a new one.
+func concatCodes(lists ...[]ErrorCode) []ErrorCode {
+	var codes []ErrorCode
+	for _, list := range lists {
+		codes = append(codes, list...)
+	}
+	return codes
+}
+
+// MatchRoute finds the route a request targets: the first whose template matches the path,
+// or the resource API Gateway matched with its path parameters filled in, so that
+// /users/count isn't taken for a user even when API Gateway matched it as /users/{email}.
+// Failing that, the route API Gateway matched as its resource is used. The path parameters
+// of the template are added to the request, still URL-encoded, as API Gateway would.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to match.
+//
+// Returns:
+// - The route, or nil if the request targets none of them.
+// - The request with the route's path parameters.
+func MatchRoute(req events.APIGatewayProxyRequest) (*Route, events.APIGatewayProxyRequest) {
+	paths := []string{req.Path}
+	if resolved := resolveTemplate(req.Resource, req.PathParameters); resolved != "" && resolved != req.Path {
+		paths = append(paths, resolved)
+	}
+
+	routes := Routes()
+	for i := range routes {
+		for _, path := range paths {
+			params, ok := matchTemplate(routes[i].Template, path)
+			if !ok {
+				continue
+			}
+			merged := make(map[string]string, len(req.PathParameters)+len(params))
+			for name, value := range req.PathParameters {
+				merged[name] = value
+			}
+			for name, value := range params {
+				merged[name] = value
+			}
+			req.PathParameters = merged
+			return &routes[i], req
+		}
+	}
+	for i := range routes {
+		if req.Resource == routes[i].Template {
+			return &routes[i], req
+		}
+	}
+	return nil, req
+}
+
+// AllowedMethods returns the methods a route answers, in a stable order.
+func (r *Route) AllowedMethods() []string {
+	var methods []string
+	_, hasGet := r.Methods[http.MethodGet]
+	for _, method := range methodOrder {
+		_, ok := r.Methods[method]
+		if ok || method == http.MethodOptions || (method == http.MethodHead && hasGet) {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// Allows reports whether a route answers a method.
+func (r *Route) Allows(method string) bool {
+	for _, allowed := range r.AllowedMethods() {
+		if method == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTemplate fills the parameters of an API Gateway resource such as /users/{email}
+// or /{proxy+} in with their values.
+//
+// Returns:
+// - The path, or an empty string if the resource is empty or a parameter has no value.
+func resolveTemplate(resource string, params map[string]string) string {
+	if resource == "" {
+		return ""
+	}
+	segments := strings.Split(resource, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		value := params[strings.TrimSuffix(segment[1:len(segment)-1], "+")]
+		if value == "" {
+			return ""
+		}
+		segments[i] = value
+	}
+	return strings.Join(segments, "/")
+}
+
+// matchTemplate matches a path against a route template segment by segment.
+//
+// Parameters:
+// - template: The template, e.g. "/orgs/{slug}".
+// - path: The request path, e.g. "/orgs/acme".
+//
+// Returns:
+// - The values of the template's parameters, keyed by name.
+// - Whether the path matches; empty segments never match a parameter.
+func matchTemplate(template string, path string) (map[string]string, bool) {
+	templateSegments := strings.Split(strings.Trim(template, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(templateSegments) != len(pathSegments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
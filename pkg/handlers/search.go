@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"strconv"
+)
+
+// Page sizes of email searches
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// ErrorInvalidSearchLimit is returned for a search limit out of range
+var ErrorInvalidSearchLimit = "limit must be a number between 1 and " + strconv.Itoa(maxSearchLimit)
+
+// SearchUsers handles GET /users/search?prefix=ali, returning the users whose email starts
+// with the prefix, for type-ahead in admin tools. Pages hold "limit" users (20 by default,
+// at most 100); pass the returned X-Next-Cursor as "cursor" to read the next one, and
+// ?fields= trims the users. The caller must be an administrator when authentication is
+// configured.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest with the prefix and paging query parameters.
+// - repo: Repository where user data is stored.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the page of users, or 400 for a prefix shorter than 2
+//     characters, a limit out of range or a malformed cursor.
+func SearchUsers(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+	if denied := requireAdmin(req); denied != nil {
+		return denied, nil
+	}
+	fields, err := responseFields(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	limit, err := searchLimit(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+
+	page, err := user.SearchUsers(req.QueryStringParameters["prefix"], limit, req.QueryStringParameters["cursor"], repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	resp, err := APIResponse(http.StatusOK, selectUsers(page.Users, fields))
+	if page.NextCursor != "" {
+		resp.Headers[NextCursorHeader] = page.NextCursor
+	}
+	return resp, err
+}
+
+// searchLimit parses the "limit" query parameter of a search.
+//
+// Returns:
+// - The page size, defaultSearchLimit if unset.
+// - An error if it isn't a number between 1 and maxSearchLimit.
+func searchLimit(req events.APIGatewayProxyRequest) (int, error) {
+	raw, ok := req.QueryStringParameters["limit"]
+	if !ok {
+		return defaultSearchLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxSearchLimit {
+		return 0, errors.New(ErrorInvalidSearchLimit)
+	}
+	return limit, nil
+}
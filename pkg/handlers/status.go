@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"os"
+)
+
+// actionStatuses maps the status actions nested under a user to the status they set
+var actionStatuses = map[string]string{
+	activateAction:   user.StatusActive,
+	deactivateAction: user.StatusInactive,
+}
+
+// SetUserStatus handles POST /users/{email}/activate and POST /users/{email}/deactivate,
+// moving the user to the active or inactive status. Suspended users can only be changed
+// by an administrator.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the user.
+// - repo: Repository where the user data is stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the updated user, 403 for a suspended user, 404 if it
+//     doesn't exist, or 409 if it already has the status.
+func SetUserStatus(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	fields, err := responseFields(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	status := actionStatuses[userAction(req)]
+	result, err := user.SetStatus(email, status, updateOptions(req), repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	action := "deactivated"
+	if status == user.StatusActive {
+		action = "activated"
+	}
+	recordMutations(req, dynaClient, mutation{action: action, email: result.Email, after: result})
+	resp, err := APIResponse(http.StatusOK, selectUser(result, fields))
+	resp.Headers["ETag"] = versionETag(result.Version)
+	return resp, err
+}
+
+// updateOptions resolves how a request may change a user: only administrators may touch
+// suspended users or change roles.
+func updateOptions(req events.APIGatewayProxyRequest) user.UpdateOptions {
+	admin := callerIsAdmin(req)
+	return user.UpdateOptions{AllowSuspended: admin, AllowRoleChange: admin, RejectRoleChange: strictRoles()}
+}
+
+// strictRoles reports whether a role change by a non-administrator is rejected with 403,
+// as STRICT_ROLES enables, rather than ignored.
+func strictRoles() bool {
+	return os.Getenv("STRICT_ROLES") == "true"
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PageSizeHeader carries the most users a page of a list holds
+const PageSizeHeader = "X-Page-Size"
+
+// maxCachedCounts is how many list totals are cached at once, so many distinct filters
+// can't exhaust memory
+const maxCachedCounts = 1000
+
+// ErrorInvalidSkipCount is returned for a skipCount that isn't a boolean
+var ErrorInvalidSkipCount = "skipCount must be true or false"
+
+// cachedCounts remembers the totals of lists by filter until they expire, so a front end
+// paging through a list doesn't pay for a COUNT scan on every page
+var cachedCounts = struct {
+	sync.Mutex
+	entries map[string]cachedCount
+}{entries: map[string]cachedCount{}}
+
+// cachedCount is a list total and the time it expires at
+type cachedCount struct {
+	count   int64
+	expires time.Time
+}
+
+// countCacheTTL returns how long list totals are cached per container
+// (COUNT_CACHE_SECONDS), or 0 if they are counted on every request.
+func countCacheTTL() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("COUNT_CACHE_SECONDS"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// totalCount counts the users a list selects for X-Total-Count, from the cache while
+// COUNT_CACHE_SECONDS allows. Strongly consistent reads always count afresh.
+//
+// Parameters:
+// - opts: The read options of the list; the fields and limit are ignored.
+// - repo: Repository where user data is stored.
+// - now: The current time.
+//
+// Returns:
+// - The number of users.
+// - An error if the users cannot be counted.
+func totalCount(opts user.ReadOptions, repo user.Repository, now time.Time) (int64, error) {
+	ttl := countCacheTTL()
+	if ttl == 0 || opts.ConsistentRead {
+		return user.CountUsers(opts, repo)
+	}
+
+	key := countCacheKey(opts)
+	cachedCounts.Lock()
+	cached, ok := cachedCounts.entries[key]
+	cachedCounts.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.count, nil
+	}
+
+	count, err := user.CountUsers(opts, repo)
+	if err != nil {
+		return 0, err
+	}
+	cachedCounts.Lock()
+	defer cachedCounts.Unlock()
+	if _, ok := cachedCounts.entries[key]; !ok && len(cachedCounts.entries) >= maxCachedCounts {
+		cachedCounts.entries = map[string]cachedCount{}
+	}
+	cachedCounts.entries[key] = cachedCount{count: count, expires: now.Add(ttl)}
+	return count, nil
+}
+
+// countCacheKey identifies the users a count selects: its filter and whether deleted
+// users are included.
+func countCacheKey(opts user.ReadOptions) string {
+	// Tags marshal in key order, so equal filters give equal keys
+	encoded, _ := json.Marshal(opts.Filter)
+	return strconv.FormatBool(opts.IncludeDeleted) + string(encoded)
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingRepository counts the COUNT scans made through a repository
+type countingRepository struct {
+	user.Repository
+	counts int
+}
+
+func (r *countingRepository) Count(opts user.ReadOptions) (int64, error) {
+	r.counts++
+	return r.Repository.Count(opts)
+}
+
+// newCountingRepository returns a countingRepository over a memory repository holding
+// users with the given emails, each named after its local part.
+func newCountingRepository(t *testing.T, emails ...string) *countingRepository {
+	t.Helper()
+	repo := user.NewMemoryRepository()
+	for _, email := range emails {
+		name := strings.SplitN(email, "@", 2)[0]
+		if err := repo.Create(&user.User{Email: email, FirstName: name, LastName: name}); err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+	return &countingRepository{Repository: repo}
+}
+
+// forgetCounts empties the cache of list totals, as in a new container.
+func forgetCounts() {
+	cachedCounts.Lock()
+	defer cachedCounts.Unlock()
+	cachedCounts.entries = map[string]cachedCount{}
+}
+
+func TestListPaginationHeaders(t *testing.T) {
+	tests := []struct {
+		name           string
+		v2             bool
+		query          map[string]string
+		wantStatus     int
+		wantTotal      string // "" if X-Total-Count must be absent
+		wantNextCursor string
+		wantPageSize   string // "" if any page size goes
+		wantCounts     int
+	}{
+		{name: "v1", wantStatus: http.StatusOK, wantTotal: "3", wantCounts: 1},
+		{name: "v1 filtered", query: map[string]string{"firstname": "grace"}, wantStatus: http.StatusOK, wantTotal: "1",
+			wantCounts: 1},
+		{name: "v2 first page", v2: true, query: map[string]string{"limit": "2"}, wantStatus: http.StatusOK,
+			wantTotal: "3", wantNextCursor: pageCursor(2), wantPageSize: "2", wantCounts: 1},
+		{name: "v2 last page", v2: true, query: map[string]string{"limit": "2", "cursor": pageCursor(2)},
+			wantStatus: http.StatusOK, wantTotal: "3", wantPageSize: "2", wantCounts: 1},
+		{name: "skip count", v2: true, query: map[string]string{"limit": "2", "skipCount": "true"},
+			wantStatus: http.StatusOK, wantNextCursor: pageCursor(2), wantPageSize: "2"},
+		{name: "skip count false", query: map[string]string{"skipCount": "false"}, wantStatus: http.StatusOK,
+			wantTotal: "3", wantCounts: 1},
+		{name: "invalid skip count", query: map[string]string{"skipCount": "sometimes"}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forgetCounts()
+			repo := newCountingRepository(t, "ada@example.com", "alan@example.com", "grace@example.org")
+			req := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/users",
+				QueryStringParameters: tt.query, Headers: map[string]string{}}
+			if tt.v2 {
+				req.Headers[versionHeader] = strconv.Itoa(int(V2))
+			}
+
+			resp, err := GetUser(req, repo, nil)
+			if err != nil {
+				t.Fatalf("GetUser() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, resp.Body)
+			}
+			if repo.counts != tt.wantCounts {
+				t.Errorf("COUNT scans = %d, want %d", repo.counts, tt.wantCounts)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if total, ok := resp.Headers[TotalCountHeader]; total != tt.wantTotal || ok != (tt.wantTotal != "") {
+				t.Errorf("%s = %q (set %v), want %q", TotalCountHeader, total, ok, tt.wantTotal)
+			}
+			if cursor := resp.Headers[NextCursorHeader]; cursor != tt.wantNextCursor {
+				t.Errorf("%s = %q, want %q", NextCursorHeader, cursor, tt.wantNextCursor)
+			}
+			pageSize, ok := resp.Headers[PageSizeHeader]
+			if !ok || (tt.wantPageSize != "" && pageSize != tt.wantPageSize) {
+				t.Errorf("%s = %q (set %v), want %q", PageSizeHeader, pageSize, ok, tt.wantPageSize)
+			}
+		})
+	}
+}
+
+func TestTotalCountCache(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name       string
+		at         time.Duration // Time of the step after start
+		opts       user.ReadOptions
+		addUser    string // Email of a user created before the step, if any
+		wantCount  int64
+		wantCounts int // COUNT scans made so far
+	}{
+		{name: "first count", wantCount: 2, wantCounts: 1},
+		{name: "cached", at: 5 * time.Second, addUser: "alan@example.com", wantCount: 2, wantCounts: 1},
+		{name: "other filter", at: 5 * time.Second, opts: user.ReadOptions{Filter: user.Filter{Domain: "example.org"}},
+			wantCount: 1, wantCounts: 2},
+		{name: "consistent read", at: 6 * time.Second, opts: user.ReadOptions{ConsistentRead: true}, wantCount: 3,
+			wantCounts: 3},
+		{name: "still cached", at: 9 * time.Second, wantCount: 2, wantCounts: 3},
+		{name: "expired", at: 10 * time.Second, wantCount: 3, wantCounts: 4},
+		{name: "cached again", at: 15 * time.Second, wantCount: 3, wantCounts: 4},
+	}
+	t.Setenv("COUNT_CACHE_SECONDS", "10")
+	forgetCounts()
+	t.Cleanup(forgetCounts)
+	repo := newCountingRepository(t, "ada@example.com", "grace@example.org")
+	for _, step := range steps {
+		if step.addUser != "" {
+			if err := repo.Create(&user.User{Email: step.addUser, FirstName: "Alan", LastName: "Turing"}); err != nil {
+				t.Fatalf("%s: creating: %v", step.name, err)
+			}
+		}
+		count, err := totalCount(step.opts, repo, start.Add(step.at))
+		if err != nil {
+			t.Fatalf("%s: totalCount() error = %v", step.name, err)
+		}
+		if count != step.wantCount || repo.counts != step.wantCounts {
+			t.Errorf("%s: totalCount() = %d after %d scans, want %d after %d", step.name, count, repo.counts,
+				step.wantCount, step.wantCounts)
+		}
+	}
+}
+
+func TestTotalCountWithoutCache(t *testing.T) {
+	t.Setenv("COUNT_CACHE_SECONDS", "")
+	forgetCounts()
+	repo := newCountingRepository(t, "ada@example.com")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 3; i++ {
+		if _, err := totalCount(user.ReadOptions{}, repo, now); err != nil {
+			t.Fatalf("totalCount() error = %v", err)
+		}
+		if repo.counts != i {
+			t.Errorf("COUNT scans after %d lists = %d, want one per list", i, repo.counts)
+		}
+	}
+}
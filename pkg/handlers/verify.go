@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	userevents "github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"net/http"
+	"os"
+)
+
+// VerifyEmail handles POST /users/{email}/verify, marking the user verified with the token
+// sent to its email ({"token": "..."}).
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the user, with the token in the body.
+// - repo: Repository where the user data is stored.
+// - dynaClient: DynamoDB client interface, used for the audit table.
+//
+// Returns:
+//   - APIGatewayProxyResponse with the verified user, 400 for a wrong token, 404 if the user
+//     doesn't exist, 409 if it is already verified, or 410 for an expired token.
+func VerifyEmail(req events.APIGatewayProxyRequest, repo user.Repository, dynaClient dynamodbiface.DynamoDBAPI) (
+	*events.APIGatewayProxyResponse, error) {
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	fields, err := responseFields(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if unsupported := requireJSON(req); unsupported != nil {
+		return unsupported, nil
+	}
+	req, invalid := decodeBody(req, maxBodyBytes())
+	if invalid != nil {
+		return invalid, nil
+	}
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	result, err := user.VerifyEmail(email, req.Body, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	recordMutations(req, dynaClient, mutation{action: "verified", email: result.Email, after: result})
+	resp, err := APIResponse(http.StatusOK, selectUser(result, fields))
+	resp.Headers["ETag"] = versionETag(result.Version)
+	return resp, err
+}
+
+// ResendVerification handles POST /users/{email}/verify/resend, replacing the user's
+// verification token and publishing the new one. At most 3 tokens are sent per hour.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest targeting the user.
+// - repo: Repository where the user data is stored.
+//
+// Returns:
+//   - APIGatewayProxyResponse with 202, 404 if the user doesn't exist, 409 if it is already
+//     verified, or 429 past the limit.
+func ResendVerification(req events.APIGatewayProxyRequest, repo user.Repository) (*events.APIGatewayProxyResponse, error) {
+	email, err := emailParam(req)
+	if err != nil {
+		return APIError(http.StatusBadRequest, CodeInvalidRequest, err.Error())
+	}
+	if denied := requireSelfOrAdmin(req, email); denied != nil {
+		return denied, nil
+	}
+
+	result, err := user.ResendVerification(email, repo)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	publishVerification(req, result)
+	body := map[string]interface{}{"email": result.Email}
+	if returnVerifyToken() {
+		body["verifyToken"] = result.VerifyToken
+	}
+	return APIResponse(http.StatusAccepted, body)
+}
+
+// returnVerifyToken reports whether verification tokens are returned in responses, which
+// RETURN_VERIFY_TOKEN enables for development without a mailer.
+func returnVerifyToken() bool {
+	return os.Getenv("RETURN_VERIFY_TOKEN") == "true"
+}
+
+// withVerifyToken returns the response body of a created user, adding its verification
+// token as "verifyToken" when RETURN_VERIFY_TOKEN is set.
+func withVerifyToken(u *user.User, fields []string) interface{} {
+	if !returnVerifyToken() || u.VerifyToken == "" {
+		return selectUser(u, fields)
+	}
+	if len(fields) == 0 {
+		fields = user.Fields
+	}
+	body := u.Select(fields)
+	body["verifyToken"] = u.VerifyToken
+	return body
+}
+
+// publishVerification publishes the verification token of a user with the default
+// publisher, so a mailer can send it, logging a failure even after a retry.
+func publishVerification(req events.APIGatewayProxyRequest, u *user.User) {
+	if u.VerifyToken == "" {
+		return
+	}
+	event := userevents.NewEvent(userevents.UserVerificationRequested, u.Email, nil, req.RequestContext.RequestID)
+	event.Token = u.VerifyToken
+	for _, failure := range userevents.PublishAll(userevents.Default, []userevents.Event{event}) {
+		logging.Default.Error("failed to publish event", logging.Fields{
+			"requestId": req.RequestContext.RequestID,
+			"type":      failure.Event.Type,
+			"email":     logging.Email(failure.Event.Email),
+			"error":     failure.Err,
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"mime"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// APIVersion is the version of the API a request targets
+type APIVersion int
+
+// API versions, chosen with a /v1 or /v2 path prefix
+const (
+	V1 APIVersion = 1 // Flat responses, unchanged for existing clients
+	V2 APIVersion = 2 // Enveloped responses and paginated lists
+)
+
+// versionHeader carries the version resolved by ResolveVersion to the handlers. Any
+// value sent by the client is dropped.
+const versionHeader = "X-Resolved-Api-Version"
+
+// NextCursorHeader carries the cursor of the next page of a v2 list
+const NextCursorHeader = "X-Next-Cursor"
+
+// Page sizes of v2 lists
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// Error messages for v2 list pagination
+var (
+	ErrorInvalidPageSize = "limit must be a number between 1 and " + strconv.Itoa(maxPageSize)
+	ErrorInvalidCursor   = "invalid cursor"
+)
+
+// ResolveVersion decides once which API version a request targets, and strips the
+// version prefix from its path so routing is the same for every version.
+// Paths prefixed with /v1 or /v2 target that version. Unprefixed paths target v1, unless
+// API_RESPONSE_V2 is set or the Accept header asks for MediaTypeV2.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to resolve.
+//
+// Returns:
+// - The request without the version prefix, carrying the version for RequestVersion.
+func ResolveVersion(req events.APIGatewayProxyRequest) events.APIGatewayProxyRequest {
+	version, explicit := V1, false
+	for _, candidate := range []APIVersion{V1, V2} {
+		prefix := "/v" + strconv.Itoa(int(candidate))
+		if req.Path == prefix || strings.HasPrefix(req.Path, prefix+"/") {
+			req.Path = strings.TrimPrefix(req.Path, prefix)
+			req.Resource = strings.TrimPrefix(req.Resource, prefix)
+			version, explicit = candidate, true
+		}
+	}
+	if !explicit && acceptsV2(req) {
+		version = V2
+	}
+
+	headers := make(map[string]string, len(req.Headers)+1)
+	for name, value := range req.Headers {
+		if !strings.EqualFold(name, versionHeader) {
+			headers[name] = value
+		}
+	}
+	headers[versionHeader] = strconv.Itoa(int(version))
+	req.Headers = headers
+	for name := range req.MultiValueHeaders {
+		if strings.EqualFold(name, versionHeader) {
+			multiValue := make(map[string][]string, len(req.MultiValueHeaders))
+			for name, values := range req.MultiValueHeaders {
+				if !strings.EqualFold(name, versionHeader) {
+					multiValue[name] = values
+				}
+			}
+			req.MultiValueHeaders = multiValue
+			break
+		}
+	}
+	return req
+}
+
+// RequestVersion returns the API version resolved for a request, or V1 if it wasn't.
+func RequestVersion(req events.APIGatewayProxyRequest) APIVersion {
+	if req.Headers[versionHeader] == strconv.Itoa(int(V2)) {
+		return V2
+	}
+	return V1
+}
+
+// acceptsV2 reports whether an unprefixed request opted into v2 responses, either because
+// API_RESPONSE_V2 is set or because its Accept header asks for MediaTypeV2.
+func acceptsV2(req events.APIGatewayProxyRequest) bool {
+	if os.Getenv("API_RESPONSE_V2") == "true" {
+		return true
+	}
+	for _, accepted := range strings.Split(headerValue(req, "Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accepted); err == nil && mediaType == MediaTypeV2 {
+			return true
+		}
+	}
+	return false
+}
+
+// pageParams parses the "limit" and "cursor" query parameters of a v2 list.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest to inspect.
+//
+// Returns:
+// - The page size, 100 by default.
+// - The offset of the page in the list.
+// - An error if either parameter is invalid.
+func pageParams(req events.APIGatewayProxyRequest) (int, int, error) {
+	limit := defaultPageSize
+	if raw, ok := req.QueryStringParameters["limit"]; ok {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, errors.New(ErrorInvalidPageSize)
+		}
+	}
+
+	offset := 0
+	if cursor := req.QueryStringParameters["cursor"]; cursor != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err == nil {
+			offset, err = strconv.Atoi(string(decoded))
+		}
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New(ErrorInvalidCursor)
+		}
+	}
+	return limit, offset, nil
+}
+
+// pageCursor encodes the offset of the next page.
+func pageCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
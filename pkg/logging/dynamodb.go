@@ -0,0 +1,240 @@
+package logging
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Call describes a single DynamoDB call made while serving a request
+type Call struct {
+	Operation  string  `json:"op"`               // DynamoDB operation name
+	Table      string  `json:"table,omitempty"`  // Table the call targeted, or the tables it consumed capacity on
+	DurationMs float64 `json:"ms"`               // Wall-clock latency in milliseconds
+	ReadUnits  float64 `json:"rcu,omitempty"`    // Read capacity units consumed, if the call asked for them
+	WriteUnits float64 `json:"wcu,omitempty"`    // Write capacity units consumed, if the call asked for them
+	Failed     bool    `json:"failed,omitempty"` // Whether the call returned an error
+}
+
+// readOperations are the operations whose consumed capacity is read capacity
+var readOperations = map[string]bool{"GetItem": true, "Scan": true, "Query": true, "BatchGetItem": true}
+
+// CallRecorder collects the DynamoDB calls made while serving one request
+type CallRecorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+// record appends a call to table that started at start and finished now with out and err.
+// The consumed capacity is read from out, where DynamoDB reports it if the input set
+// ReturnConsumedCapacity; calls to several tables are attributed to the tables it names.
+func (r *CallRecorder) record(operation string, table string, start time.Time, out interface{}, err error) {
+	call := Call{
+		Operation:  operation,
+		Table:      table,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		Failed:     err != nil,
+	}
+	var units float64
+	var tables []string
+	for _, consumed := range consumedCapacity(out) {
+		if consumed == nil {
+			continue
+		}
+		units += aws.Float64Value(consumed.CapacityUnits)
+		if name := aws.StringValue(consumed.TableName); name != "" {
+			tables = append(tables, name)
+		}
+	}
+	if call.Table == "" {
+		call.Table = strings.Join(tables, ",")
+	}
+	if readOperations[operation] {
+		call.ReadUnits = units
+	} else {
+		call.WriteUnits = units
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+// Calls returns the calls recorded so far.
+func (r *CallRecorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Capacity returns the capacity consumed by the calls recorded so far.
+//
+// Returns:
+// - The read capacity units consumed.
+// - The write capacity units consumed.
+func (r *CallRecorder) Capacity() (float64, float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var read, write float64
+	for _, call := range r.calls {
+		read += call.ReadUnits
+		write += call.WriteUnits
+	}
+	return read, write
+}
+
+// consumedCapacity returns the capacity reported in the output of a call, if any.
+func consumedCapacity(out interface{}) []*dynamodb.ConsumedCapacity {
+	switch out := out.(type) {
+	case *dynamodb.GetItemOutput:
+		if out != nil {
+			return []*dynamodb.ConsumedCapacity{out.ConsumedCapacity}
+		}
+	case *dynamodb.PutItemOutput:
+		if out != nil {
+			return []*dynamodb.ConsumedCapacity{out.ConsumedCapacity}
+		}
+	case *dynamodb.UpdateItemOutput:
+		if out != nil {
+			return []*dynamodb.ConsumedCapacity{out.ConsumedCapacity}
+		}
+	case *dynamodb.DeleteItemOutput:
+		if out != nil {
+			return []*dynamodb.ConsumedCapacity{out.ConsumedCapacity}
+		}
+	case *dynamodb.ScanOutput:
+		if out != nil {
+			return []*dynamodb.ConsumedCapacity{out.ConsumedCapacity}
+		}
+	case *dynamodb.QueryOutput:
+		if out != nil {
+			return []*dynamodb.ConsumedCapacity{out.ConsumedCapacity}
+		}
+	case *dynamodb.BatchGetItemOutput:
+		if out != nil {
+			return out.ConsumedCapacity
+		}
+	case *dynamodb.BatchWriteItemOutput:
+		if out != nil {
+			return out.ConsumedCapacity
+		}
+	case *dynamodb.TransactWriteItemsOutput:
+		if out != nil {
+			return out.ConsumedCapacity
+		}
+	}
+	return nil
+}
+
+// timedClient wraps a DynamoDB client and records the latency of every call
+type timedClient struct {
+	dynamodbiface.DynamoDBAPI
+	recorder *CallRecorder
+}
+
+// NewTimedClient wraps a DynamoDB client so every call used by the application is timed
+// into the recorder. Operations that aren't wrapped pass through untimed.
+//
+// Parameters:
+// - client: The DynamoDB client to wrap.
+// - recorder: Where the calls are recorded.
+//
+// Returns:
+// - The wrapping client.
+func NewTimedClient(client dynamodbiface.DynamoDBAPI, recorder *CallRecorder) dynamodbiface.DynamoDBAPI {
+	return &timedClient{DynamoDBAPI: client, recorder: recorder}
+}
+
+// GetItem times dynamodb.GetItem.
+func (c *timedClient) GetItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.GetItem(in)
+	c.recorder.record("GetItem", aws.StringValue(in.TableName), start, out, err)
+	return out, err
+}
+
+// GetItemWithContext times dynamodb.GetItemWithContext.
+func (c *timedClient) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput,
+	opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.GetItemWithContext(ctx, in, opts...)
+	c.recorder.record("GetItem", aws.StringValue(in.TableName), start, out, err)
+	return out, err
+}
+
+// PutItem times dynamodb.PutItem.
+func (c *timedClient) PutItem(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.PutItem(in)
+	c.recorder.record("PutItem", aws.StringValue(in.TableName), start, out, err)
+	return out, err
+}
+
+// UpdateItem times dynamodb.UpdateItem.
+func (c *timedClient) UpdateItem(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.UpdateItem(in)
+	c.recorder.record("UpdateItem", aws.StringValue(in.TableName), start, out, err)
+	return out, err
+}
+
+// DeleteItem times dynamodb.DeleteItem.
+func (c *timedClient) DeleteItem(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.DeleteItem(in)
+	c.recorder.record("DeleteItem", aws.StringValue(in.TableName), start, out, err)
+	return out, err
+}
+
+// Scan times dynamodb.Scan.
+func (c *timedClient) Scan(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.Scan(in)
+	c.recorder.record("Scan", aws.StringValue(in.TableName), start, out, err)
+	return out, err
+}
+
+// ScanWithContext times dynamodb.ScanWithContext.
+func (c *timedClient) ScanWithContext(ctx aws.Context, in *dynamodb.ScanInput,
+	opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.ScanWithContext(ctx, in, opts...)
+	c.recorder.record("Scan", aws.StringValue(in.TableName), start, out, err)
+	return out, err
+}
+
+// Query times dynamodb.Query.
+func (c *timedClient) Query(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.Query(in)
+	c.recorder.record("Query", aws.StringValue(in.TableName), start, out, err)
+	return out, err
+}
+
+// BatchGetItem times dynamodb.BatchGetItem.
+func (c *timedClient) BatchGetItem(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.BatchGetItem(in)
+	c.recorder.record("BatchGetItem", "", start, out, err)
+	return out, err
+}
+
+// BatchWriteItem times dynamodb.BatchWriteItem.
+func (c *timedClient) BatchWriteItem(in *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.BatchWriteItem(in)
+	c.recorder.record("BatchWriteItem", "", start, out, err)
+	return out, err
+}
+
+// TransactWriteItems times dynamodb.TransactWriteItems.
+func (c *timedClient) TransactWriteItems(in *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	start := time.Now()
+	out, err := c.DynamoDBAPI.TransactWriteItems(in)
+	c.recorder.record("TransactWriteItems", "", start, out, err)
+	return out, err
+}
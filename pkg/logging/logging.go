@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log line
+type Level int
+
+// Supported log levels, from most to least verbose
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelNames maps levels to the names written in log lines and read from LOG_LEVEL
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// Fields are the structured attributes of a log line
+type Fields map[string]interface{}
+
+// Logger writes one JSON object per line to its output
+type Logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level Level
+}
+
+// Default is the logger used across the application, replaced in main() at cold start
+var Default = New(os.Stdout, LevelInfo)
+
+// New creates a Logger writing lines at or above the given level.
+//
+// Parameters:
+// - out: Where log lines are written (CloudWatch captures stdout in Lambda).
+// - level: The minimum level to write.
+//
+// Returns:
+// - A pointer to the new Logger.
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{out: out, level: level}
+}
+
+// LevelFromEnv reads the minimum log level from the LOG_LEVEL environment variable.
+//
+// Returns:
+// - The configured level, or LevelInfo if LOG_LEVEL is unset or unknown.
+func LevelFromEnv() Level {
+	configured := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_LEVEL")))
+	for level, name := range levelNames {
+		if name == configured {
+			return level
+		}
+	}
+	return LevelInfo
+}
+
+// Enabled reports whether lines at the given level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Log writes a JSON line with the time, level, message and fields.
+// Marshaling failures are reported in the line itself rather than dropped.
+//
+// Parameters:
+// - level: The severity of the line.
+// - msg: A short, constant description of the event.
+// - fields: Structured attributes of the event; may be nil.
+func (l *Logger) Log(level Level, msg string, fields Fields) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	line := Fields{}
+	for key, value := range fields {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		line[key] = value
+	}
+	line["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["level"] = levelNames[level]
+	line["msg"] = msg
+
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		encoded, _ = json.Marshal(Fields{"level": levelNames[LevelError], "msg": "unloggable fields", "error": err.Error()})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(append(encoded, '\n'))
+}
+
+// Debug writes a line at debug level.
+func (l *Logger) Debug(msg string, fields Fields) { l.Log(LevelDebug, msg, fields) }
+
+// Info writes a line at info level.
+func (l *Logger) Info(msg string, fields Fields) { l.Log(LevelInfo, msg, fields) }
+
+// Warn writes a line at warn level.
+func (l *Logger) Warn(msg string, fields Fields) { l.Log(LevelWarn, msg, fields) }
+
+// Error writes a line at error level.
+func (l *Logger) Error(msg string, fields Fields) { l.Log(LevelError, msg, fields) }
+
+// Email prepares an email address for logging. Unless LOG_PII is "true", the address
+// is replaced by a short SHA-256 prefix so log lines about the same user can still be
+// correlated without exposing the address.
+//
+// Parameters:
+// - email: The email address to log.
+//
+// Returns:
+// - The email itself, or its hashed form.
+func Email(email string) string {
+	if os.Getenv("LOG_PII") == "true" || email == "" {
+		return email
+	}
+	sum := sha256.Sum256([]byte(email))
+	return "sha256:" + hex.EncodeToString(sum[:])[:16]
+}
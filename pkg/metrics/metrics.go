@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultNamespace is the CloudWatch namespace of the metrics unless METRICS_NAMESPACE is set
+const DefaultNamespace = "UsersService"
+
+// Metric units understood by CloudWatch
+const (
+	UnitCount        = "Count"
+	UnitMilliseconds = "Milliseconds"
+)
+
+// Output is where metric lines are written; CloudWatch extracts the metrics of the lines
+// Lambda writes to stdout
+var Output io.Writer = os.Stdout
+
+// outputMu keeps concurrent lines from interleaving
+var outputMu sync.Mutex
+
+// Request describes one served request
+type Request struct {
+	Method         string        // HTTP method
+	Route          string        // Route template, e.g. "/users/{email}"
+	Status         int           // HTTP status of the response
+	Duration       time.Duration // Time spent serving the request
+	DynamoDuration time.Duration // Time spent in DynamoDB calls, summed over the calls
+	ReadUnits      float64       // DynamoDB read capacity units consumed
+	WriteUnits     float64       // DynamoDB write capacity units consumed
+}
+
+// metricDefinition names a metric of a line and its unit
+type metricDefinition struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// metricDirective tells CloudWatch which members of a line are metrics and dimensions
+type metricDirective struct {
+	Namespace  string             `json:"Namespace"`
+	Dimensions [][]string         `json:"Dimensions"`
+	Metrics    []metricDefinition `json:"Metrics"`
+}
+
+// metadata is the "_aws" member of a line in the Embedded Metric Format
+type metadata struct {
+	Timestamp         int64             `json:"Timestamp"`
+	CloudWatchMetrics []metricDirective `json:"CloudWatchMetrics"`
+}
+
+// requestMetrics are the metrics of a request, in the order they are declared
+var requestMetrics = []metricDefinition{
+	{"RequestCount", UnitCount},
+	{"ErrorCount", UnitCount},
+	{"ErrorCount4xx", UnitCount},
+	{"ErrorCount5xx", UnitCount},
+	{"DurationMs", UnitMilliseconds},
+	{"DynamoDurationMs", UnitMilliseconds},
+	{"ConsumedReadCapacityUnits", UnitCount},
+	{"ConsumedWriteCapacityUnits", UnitCount},
+}
+
+// Enabled reports whether metrics are emitted, which ENABLE_METRICS=true turns on.
+func Enabled() bool {
+	return os.Getenv("ENABLE_METRICS") == "true"
+}
+
+// namespace returns the CloudWatch namespace from METRICS_NAMESPACE, or DefaultNamespace.
+func namespace() string {
+	if ns := os.Getenv("METRICS_NAMESPACE"); ns != "" {
+		return ns
+	}
+	return DefaultNamespace
+}
+
+// EmitRequest writes the metrics of a request as one line in the CloudWatch Embedded Metric
+// Format, dimensioned by method and route: a request count, error counts (overall and by
+// status class, 0 or 1), the durations of the request and of its DynamoDB calls, and the
+// capacity those calls consumed.
+//
+// It does nothing unless metrics are enabled, and failures are logged rather than returned,
+// so metrics can never fail a request. It is called once per request by the router.
+//
+// Parameters:
+// - r: The request to report.
+func EmitRequest(r Request) {
+	if !Enabled() {
+		return
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logging.Default.Error("failed to emit metrics", logging.Fields{"panic": fmt.Sprint(recovered)})
+		}
+	}()
+
+	line := map[string]interface{}{
+		"_aws": metadata{
+			Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+			CloudWatchMetrics: []metricDirective{{
+				Namespace:  namespace(),
+				Dimensions: [][]string{{"Method", "Route"}},
+				Metrics:    requestMetrics,
+			}},
+		},
+		"Method":                     r.Method,
+		"Route":                      r.Route,
+		"RequestCount":               1,
+		"ErrorCount":                 count(r.Status >= 400),
+		"ErrorCount4xx":              count(r.Status >= 400 && r.Status < 500),
+		"ErrorCount5xx":              count(r.Status >= 500),
+		"DurationMs":                 milliseconds(r.Duration),
+		"DynamoDurationMs":           milliseconds(r.DynamoDuration),
+		"ConsumedReadCapacityUnits":  r.ReadUnits,
+		"ConsumedWriteCapacityUnits": r.WriteUnits,
+	}
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		logging.Default.Error("failed to emit metrics", logging.Fields{"error": err})
+		return
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if _, err := Output.Write(append(encoded, '\n')); err != nil {
+		logging.Default.Error("failed to emit metrics", logging.Fields{"error": err})
+	}
+}
+
+// count returns 1 if the condition holds, and 0 otherwise.
+func count(condition bool) int {
+	if condition {
+		return 1
+	}
+	return 0
+}
+
+// milliseconds converts a duration to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureStdout runs emit with Output and os.Stdout on a pipe, and returns what was written.
+func captureStdout(t *testing.T, emit func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error = %v", err)
+	}
+	defer func(stdout *os.File, output io.Writer) { os.Stdout, Output = stdout, output }(os.Stdout, Output)
+	os.Stdout, Output = w, w
+
+	emit()
+	w.Close()
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, r); err != nil {
+		t.Fatalf("reading stdout: %v", err)
+	}
+	return out.String()
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("closed")
+}
+
+func TestEmitRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		request   Request
+		want      map[string]float64
+	}{
+		{name: "success", request: Request{Method: "GET", Route: "/users/{email}", Status: 200,
+			Duration: 12500 * time.Microsecond, DynamoDuration: 4 * time.Millisecond, ReadUnits: 0.5},
+			want: map[string]float64{"RequestCount": 1, "ErrorCount": 0, "ErrorCount4xx": 0, "ErrorCount5xx": 0,
+				"DurationMs": 12.5, "DynamoDurationMs": 4, "ConsumedReadCapacityUnits": 0.5}},
+		{name: "client error", namespace: "Staging", request: Request{Method: "POST", Route: "/users", Status: 409,
+			WriteUnits: 1},
+			want: map[string]float64{"RequestCount": 1, "ErrorCount": 1, "ErrorCount4xx": 1, "ErrorCount5xx": 0,
+				"ConsumedWriteCapacityUnits": 1}},
+		{name: "server error", request: Request{Method: "DELETE", Route: "/users/{email}", Status: 503},
+			want: map[string]float64{"RequestCount": 1, "ErrorCount": 1, "ErrorCount4xx": 0, "ErrorCount5xx": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENABLE_METRICS", "true")
+			t.Setenv("METRICS_NAMESPACE", tt.namespace)
+			out := captureStdout(t, func() { EmitRequest(tt.request) })
+			if strings.Count(out, "\n") != 1 || !strings.HasSuffix(out, "\n") {
+				t.Fatalf("stdout = %q, want one line", out)
+			}
+
+			var line map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(out), &line); err != nil {
+				t.Fatalf("stdout %q isn't JSON: %v", out, err)
+			}
+			var aws struct {
+				Timestamp         *int64 `json:"Timestamp"`
+				CloudWatchMetrics []struct {
+					Namespace  *string    `json:"Namespace"`
+					Dimensions [][]string `json:"Dimensions"`
+					Metrics    []struct {
+						Name string `json:"Name"`
+						Unit string `json:"Unit"`
+					} `json:"Metrics"`
+				} `json:"CloudWatchMetrics"`
+			}
+			if err := json.Unmarshal(line["_aws"], &aws); err != nil {
+				t.Fatalf("_aws = %s: %v", line["_aws"], err)
+			}
+			if aws.Timestamp == nil || *aws.Timestamp <= 0 {
+				t.Errorf("_aws.Timestamp = %v, want epoch milliseconds", aws.Timestamp)
+			}
+			if len(aws.CloudWatchMetrics) != 1 {
+				t.Fatalf("_aws.CloudWatchMetrics = %s, want one directive", line["_aws"])
+			}
+			directive := aws.CloudWatchMetrics[0]
+			wantNamespace := tt.namespace
+			if wantNamespace == "" {
+				wantNamespace = DefaultNamespace
+			}
+			if directive.Namespace == nil || *directive.Namespace != wantNamespace {
+				t.Errorf("Namespace = %v, want %q", directive.Namespace, wantNamespace)
+			}
+
+			// Every dimension and metric named by the directive is a member of the line
+			if len(directive.Dimensions) != 1 || strings.Join(directive.Dimensions[0], ",") != "Method,Route" {
+				t.Errorf("Dimensions = %v, want [[Method Route]]", directive.Dimensions)
+			}
+			for _, dimensions := range directive.Dimensions {
+				for _, dimension := range dimensions {
+					var value string
+					if err := json.Unmarshal(line[dimension], &value); err != nil || value == "" {
+						t.Errorf("dimension %s = %s, want a string", dimension, line[dimension])
+					}
+				}
+			}
+			if len(directive.Metrics) != len(requestMetrics) {
+				t.Errorf("Metrics = %v, want %d metrics", directive.Metrics, len(requestMetrics))
+			}
+			for _, metric := range directive.Metrics {
+				if metric.Unit != UnitCount && metric.Unit != UnitMilliseconds {
+					t.Errorf("metric %s unit = %q", metric.Name, metric.Unit)
+				}
+				var value float64
+				if err := json.Unmarshal(line[metric.Name], &value); err != nil {
+					t.Errorf("metric %s = %s, want a number", metric.Name, line[metric.Name])
+					continue
+				}
+				if value != tt.want[metric.Name] {
+					t.Errorf("metric %s = %v, want %v", metric.Name, value, tt.want[metric.Name])
+				}
+			}
+			if string(line["Method"]) != `"`+tt.request.Method+`"` || string(line["Route"]) != `"`+tt.request.Route+`"` {
+				t.Errorf("Method, Route = %s, %s, want %s, %s", line["Method"], line["Route"], tt.request.Method,
+					tt.request.Route)
+			}
+		})
+	}
+}
+
+func TestEmitRequestDisabled(t *testing.T) {
+	for _, enabled := range []string{"", "false", "1"} {
+		t.Setenv("ENABLE_METRICS", enabled)
+		if out := captureStdout(t, func() { EmitRequest(Request{Method: "GET", Route: "/users", Status: 200}) }); out != "" {
+			t.Errorf("ENABLE_METRICS=%q wrote %q, want nothing", enabled, out)
+		}
+	}
+}
+
+func TestEmitRequestFailingOutput(t *testing.T) {
+	t.Setenv("ENABLE_METRICS", "true")
+	defer func(output io.Writer) { Output = output }(Output)
+	for _, output := range []io.Writer{failingWriter{}, nil} {
+		Output = output
+		func() {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					t.Errorf("EmitRequest() panicked with output %T: %v", output, recovered)
+				}
+			}()
+			EmitRequest(Request{Method: "GET", Route: "/users", Status: 200})
+		}()
+	}
+}
@@ -0,0 +1,367 @@
+package mocks
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"sync"
+)
+
+// Call is an operation received by a FakeDynamo
+type Call struct {
+	Operation string      // Operation name, e.g. "GetItem"
+	Input     interface{} // The input the operation received, e.g. *dynamodb.GetItemInput
+}
+
+// FakeDynamo is a scriptable dynamodbiface.DynamoDBAPI for tests.
+//
+// Responses are registered per operation with the On* methods; operations without a
+// registered response succeed with an empty output (so GetItem finds nothing).
+// The WithContext variants share the responses of their plain counterparts.
+// Operations not implemented here panic through the nil embedded interface.
+// FakeDynamo is safe for concurrent use.
+type FakeDynamo struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu            sync.Mutex
+	calls         []Call
+	getItem       func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error)
+	putItem       func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
+	updateItem    func(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error)
+	deleteItem    func(*dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error)
+	scan          func(*dynamodb.ScanInput) (*dynamodb.ScanOutput, error)
+	query         func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error)
+	describeTable func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error)
+	createTable   func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error)
+	batchGet      func(*dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error)
+	batchWrite    func(*dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error)
+	transactWrite func(*dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+// NewFakeDynamo creates a FakeDynamo with no registered responses.
+func NewFakeDynamo() *FakeDynamo {
+	return &FakeDynamo{}
+}
+
+// ThrottlingError returns the error DynamoDB sends when a request is throttled.
+func ThrottlingError() error {
+	return awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throughput exceeded", nil)
+}
+
+// ConditionalCheckFailedError returns the error DynamoDB sends when a condition expression fails.
+func ConditionalCheckFailedError() error {
+	return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "the conditional request failed", nil)
+}
+
+// ResourceNotFoundError returns the error DynamoDB sends when the table doesn't exist.
+func ResourceNotFoundError() error {
+	return awserr.New(dynamodb.ErrCodeResourceNotFoundException, "requested resource not found", nil)
+}
+
+// Calls returns every operation received so far, in order.
+func (f *FakeDynamo) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Inputs returns the inputs received by one operation, in order.
+//
+// Parameters:
+// - operation: The operation name, e.g. "PutItem".
+//
+// Returns:
+// - The inputs, to be type-asserted to the operation's input type.
+func (f *FakeDynamo) Inputs(operation string) []interface{} {
+	var inputs []interface{}
+	for _, call := range f.Calls() {
+		if call.Operation == operation {
+			inputs = append(inputs, call.Input)
+		}
+	}
+	return inputs
+}
+
+// record stores a received call.
+func (f *FakeDynamo) record(operation string, input interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Operation: operation, Input: input})
+}
+
+// OnGetItem registers the response to GetItem.
+func (f *FakeDynamo) OnGetItem(fn func(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.getItem = fn
+}
+
+// OnPutItem registers the response to PutItem.
+func (f *FakeDynamo) OnPutItem(fn func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.putItem = fn
+}
+
+// OnUpdateItem registers the response to UpdateItem.
+func (f *FakeDynamo) OnUpdateItem(fn func(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updateItem = fn
+}
+
+// OnDeleteItem registers the response to DeleteItem.
+func (f *FakeDynamo) OnDeleteItem(fn func(*dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleteItem = fn
+}
+
+// OnScan registers the response to Scan.
+func (f *FakeDynamo) OnScan(fn func(*dynamodb.ScanInput) (*dynamodb.ScanOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scan = fn
+}
+
+// OnQuery registers the response to Query.
+func (f *FakeDynamo) OnQuery(fn func(*dynamodb.QueryInput) (*dynamodb.QueryOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.query = fn
+}
+
+// OnDescribeTable registers the response to DescribeTable.
+func (f *FakeDynamo) OnDescribeTable(fn func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.describeTable = fn
+}
+
+// OnCreateTable registers the response to CreateTable.
+func (f *FakeDynamo) OnCreateTable(fn func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.createTable = fn
+}
+
+// OnBatchGetItem registers the response to BatchGetItem.
+func (f *FakeDynamo) OnBatchGetItem(fn func(*dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batchGet = fn
+}
+
+// OnBatchWriteItem registers the response to BatchWriteItem.
+func (f *FakeDynamo) OnBatchWriteItem(fn func(*dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batchWrite = fn
+}
+
+// OnTransactWriteItems registers the response to TransactWriteItems.
+func (f *FakeDynamo) OnTransactWriteItems(fn func(*dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.transactWrite = fn
+}
+
+// GetItem records the input and returns the registered response.
+func (f *FakeDynamo) GetItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	f.record("GetItem", in)
+	f.mu.Lock()
+	fn := f.getItem
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	return fn(in)
+}
+
+// GetItemWithContext behaves like GetItem.
+func (f *FakeDynamo) GetItemWithContext(_ aws.Context, in *dynamodb.GetItemInput, _ ...request.Option) (
+	*dynamodb.GetItemOutput, error) {
+	return f.GetItem(in)
+}
+
+// PutItem records the input and returns the registered response.
+func (f *FakeDynamo) PutItem(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	f.record("PutItem", in)
+	f.mu.Lock()
+	fn := f.putItem
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.PutItemOutput{}, nil
+	}
+	return fn(in)
+}
+
+// PutItemWithContext behaves like PutItem.
+func (f *FakeDynamo) PutItemWithContext(_ aws.Context, in *dynamodb.PutItemInput, _ ...request.Option) (
+	*dynamodb.PutItemOutput, error) {
+	return f.PutItem(in)
+}
+
+// UpdateItem records the input and returns the registered response.
+func (f *FakeDynamo) UpdateItem(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	f.record("UpdateItem", in)
+	f.mu.Lock()
+	fn := f.updateItem
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.UpdateItemOutput{}, nil
+	}
+	return fn(in)
+}
+
+// UpdateItemWithContext behaves like UpdateItem.
+func (f *FakeDynamo) UpdateItemWithContext(_ aws.Context, in *dynamodb.UpdateItemInput, _ ...request.Option) (
+	*dynamodb.UpdateItemOutput, error) {
+	return f.UpdateItem(in)
+}
+
+// DeleteItem records the input and returns the registered response.
+func (f *FakeDynamo) DeleteItem(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	f.record("DeleteItem", in)
+	f.mu.Lock()
+	fn := f.deleteItem
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.DeleteItemOutput{}, nil
+	}
+	return fn(in)
+}
+
+// DeleteItemWithContext behaves like DeleteItem.
+func (f *FakeDynamo) DeleteItemWithContext(_ aws.Context, in *dynamodb.DeleteItemInput, _ ...request.Option) (
+	*dynamodb.DeleteItemOutput, error) {
+	return f.DeleteItem(in)
+}
+
+// Scan records the input and returns the registered response.
+func (f *FakeDynamo) Scan(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	f.record("Scan", in)
+	f.mu.Lock()
+	fn := f.scan
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.ScanOutput{}, nil
+	}
+	return fn(in)
+}
+
+// ScanWithContext behaves like Scan.
+func (f *FakeDynamo) ScanWithContext(_ aws.Context, in *dynamodb.ScanInput, _ ...request.Option) (
+	*dynamodb.ScanOutput, error) {
+	return f.Scan(in)
+}
+
+// Query records the input and returns the registered response.
+func (f *FakeDynamo) Query(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	f.record("Query", in)
+	f.mu.Lock()
+	fn := f.query
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.QueryOutput{}, nil
+	}
+	return fn(in)
+}
+
+// QueryWithContext behaves like Query.
+func (f *FakeDynamo) QueryWithContext(_ aws.Context, in *dynamodb.QueryInput, _ ...request.Option) (
+	*dynamodb.QueryOutput, error) {
+	return f.Query(in)
+}
+
+// DescribeTable records the input and returns the registered response.
+func (f *FakeDynamo) DescribeTable(in *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	f.record("DescribeTable", in)
+	f.mu.Lock()
+	fn := f.describeTable
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.DescribeTableOutput{}, nil
+	}
+	return fn(in)
+}
+
+// DescribeTableWithContext behaves like DescribeTable.
+func (f *FakeDynamo) DescribeTableWithContext(_ aws.Context, in *dynamodb.DescribeTableInput, _ ...request.Option) (
+	*dynamodb.DescribeTableOutput, error) {
+	return f.DescribeTable(in)
+}
+
+// CreateTable records the input and returns the registered response.
+func (f *FakeDynamo) CreateTable(in *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	f.record("CreateTable", in)
+	f.mu.Lock()
+	fn := f.createTable
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	return fn(in)
+}
+
+// CreateTableWithContext behaves like CreateTable.
+func (f *FakeDynamo) CreateTableWithContext(_ aws.Context, in *dynamodb.CreateTableInput, _ ...request.Option) (
+	*dynamodb.CreateTableOutput, error) {
+	return f.CreateTable(in)
+}
+
+// BatchGetItem records the input and returns the registered response.
+func (f *FakeDynamo) BatchGetItem(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	f.record("BatchGetItem", in)
+	f.mu.Lock()
+	fn := f.batchGet
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.BatchGetItemOutput{}, nil
+	}
+	return fn(in)
+}
+
+// BatchGetItemWithContext behaves like BatchGetItem.
+func (f *FakeDynamo) BatchGetItemWithContext(_ aws.Context, in *dynamodb.BatchGetItemInput, _ ...request.Option) (
+	*dynamodb.BatchGetItemOutput, error) {
+	return f.BatchGetItem(in)
+}
+
+// BatchWriteItem records the input and returns the registered response.
+func (f *FakeDynamo) BatchWriteItem(in *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	f.record("BatchWriteItem", in)
+	f.mu.Lock()
+	fn := f.batchWrite
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.BatchWriteItemOutput{}, nil
+	}
+	return fn(in)
+}
+
+// BatchWriteItemWithContext behaves like BatchWriteItem.
+func (f *FakeDynamo) BatchWriteItemWithContext(_ aws.Context, in *dynamodb.BatchWriteItemInput, _ ...request.Option) (
+	*dynamodb.BatchWriteItemOutput, error) {
+	return f.BatchWriteItem(in)
+}
+
+// TransactWriteItems records the input and returns the registered response.
+func (f *FakeDynamo) TransactWriteItems(in *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	f.record("TransactWriteItems", in)
+	f.mu.Lock()
+	fn := f.transactWrite
+	f.mu.Unlock()
+	if fn == nil {
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	}
+	return fn(in)
+}
+
+// TransactWriteItemsWithContext behaves like TransactWriteItems.
+func (f *FakeDynamo) TransactWriteItemsWithContext(_ aws.Context, in *dynamodb.TransactWriteItemsInput, _ ...request.Option) (
+	*dynamodb.TransactWriteItemsOutput, error) {
+	return f.TransactWriteItems(in)
+}
@@ -0,0 +1,487 @@
+package org
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/store"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Error messages for organizations, returned as *user.Error so handlers map them like the
+// errors of users
+var (
+	ErrorOrgsDisabled         = "organizations are not enabled"
+	ErrorInvalidSlug          = "slug must be 3 to 63 lowercase letters, digits or hyphens, not starting or ending with a hyphen"
+	ErrorInvalidOrgName       = "name is required and must be at most 100 characters"
+	ErrorOrgAlreadyExists     = "organization already exists"
+	ErrorOrgDoesNotExist      = "organization does not exist"
+	ErrorAlreadyMember        = "user is already a member of the organization"
+	ErrorNotMember            = "user is not a member of the organization"
+	ErrorCouldNotReadOrgs     = "failed to read organizations"
+	ErrorCouldNotWriteOrgs    = "failed to write organizations"
+	ErrorCouldNotMarshalOrg   = "could not marshal organization"
+	ErrorCouldNotUnmarshalOrg = "failed to unmarshal organization"
+)
+
+// maxNameLength is the longest organization name accepted, in characters
+const maxNameLength = 100
+
+// Keys of the organization items. An organization is stored under ORG#<slug> with the sort
+// key META, and each membership twice: under the organization (MEMBER#<email>) and under
+// the user's partition (ORG#<slug>), so both sides are read with a single query.
+const (
+	orgKeyPrefix    = "ORG#"
+	orgSortKey      = "META"
+	memberKeyPrefix = "MEMBER#"
+)
+
+// EntityType is the entityType of organization items, which partitions the entity index
+const EntityType = "ORG"
+
+// writeBatchSize is the most items a single BatchWriteItem call accepts
+const writeBatchSize = 25
+
+// writeMaxAttempts bounds the retries of unprocessed deletes
+const writeMaxAttempts = 3
+
+// slugPattern matches the slugs organizations are addressed by, e.g. "acme-corp"
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+
+// Org is an organization users can belong to
+type Org struct {
+	Slug      string `json:"slug"`      // Unique, URL-safe identifier, e.g. "acme-corp"
+	Name      string `json:"name"`      // Display name
+	CreatedAt string `json:"createdAt"` // RFC3339 creation time
+}
+
+// Membership links a user to an organization
+type Membership struct {
+	Org      string `json:"org"`      // Slug of the organization
+	Email    string `json:"email"`    // Email of the member
+	JoinedAt string `json:"joinedAt"` // RFC3339 time the user joined
+}
+
+// TableName returns the table organizations are stored in: ORGS_TABLE_NAME, or the users
+// table (TABLE_NAME) in single-table mode, or an empty string when organizations are off.
+func TableName() string {
+	if name := os.Getenv("ORGS_TABLE_NAME"); name != "" {
+		return name
+	}
+	if user.SingleTable() {
+		return os.Getenv("TABLE_NAME")
+	}
+	return ""
+}
+
+// Enabled reports whether organizations are stored anywhere.
+func Enabled() bool {
+	return TableName() != ""
+}
+
+// ValidateSlug checks that a slug can address an organization.
+//
+// Parameters:
+// - slug: The slug to check.
+//
+// Returns:
+// - A validation error naming the slug field, or nil.
+func ValidateSlug(slug string) error {
+	if !slugPattern.MatchString(slug) {
+		return &user.Error{Kind: user.ErrValidation, Message: ErrorInvalidSlug, Field: "slug"}
+	}
+	return nil
+}
+
+// orgs returns the store of the organization items.
+func orgs(dynaClient dynamodbiface.DynamoDBAPI) *store.Store[Org] {
+	return &store.Store[Org]{
+		Client:       dynaClient,
+		TableName:    TableName(),
+		KeyAttribute: user.PartitionKeyAttribute,
+		Key:          func(o *Org) string { return o.Slug },
+		KeyOf: func(slug string) store.Item {
+			return itemKey(orgKeyPrefix+slug, orgSortKey)
+		},
+		Encode: func(item store.Item) error {
+			slug := aws.StringValue(item["slug"].S)
+			for name, value := range itemKey(orgKeyPrefix+slug, orgSortKey) {
+				item[name] = value
+			}
+			item[user.EntityTypeAttribute] = &dynamodb.AttributeValue{S: aws.String(EntityType)}
+			return nil
+		},
+		Decode: stripKeys,
+	}
+}
+
+// memberships returns the store membership items are read with.
+func memberships(dynaClient dynamodbiface.DynamoDBAPI) *store.Store[Membership] {
+	return &store.Store[Membership]{
+		Client:       dynaClient,
+		TableName:    TableName(),
+		KeyAttribute: user.PartitionKeyAttribute,
+		Key:          func(m *Membership) string { return m.Org },
+		Decode:       stripKeys,
+	}
+}
+
+// Create stores a new organization, stamped with its creation time.
+//
+// Parameters:
+// - o: The organization; Slug and Name are validated and CreatedAt is set.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - A validation error, a conflict if the slug is taken, or a storage error.
+func Create(o *Org, dynaClient dynamodbiface.DynamoDBAPI) error {
+	if !Enabled() {
+		return &user.Error{Kind: user.ErrNotFound, Message: ErrorOrgsDisabled}
+	}
+	if err := ValidateSlug(o.Slug); err != nil {
+		return err
+	}
+	o.Name = strings.TrimSpace(o.Name)
+	if o.Name == "" || utf8.RuneCountInString(o.Name) > maxNameLength {
+		return &user.Error{Kind: user.ErrValidation, Message: ErrorInvalidOrgName, Field: "name"}
+	}
+	o.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	err := orgs(dynaClient).Put(o, &store.Condition{
+		Expression: "attribute_not_exists(#pk)",
+		Names:      map[string]*string{"#pk": aws.String(user.PartitionKeyAttribute)},
+	})
+	if errors.Is(err, store.ErrConditionFailed) {
+		return &user.Error{Kind: user.ErrConflict, Message: ErrorOrgAlreadyExists, Err: err}
+	}
+	return storeError(err, ErrorCouldNotWriteOrgs)
+}
+
+// Get reads an organization.
+//
+// Parameters:
+// - slug: The slug of the organization.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - The organization.
+// - A not-found error if there is none, or a storage error.
+func Get(slug string, dynaClient dynamodbiface.DynamoDBAPI) (*Org, error) {
+	if !Enabled() {
+		return nil, &user.Error{Kind: user.ErrNotFound, Message: ErrorOrgsDisabled}
+	}
+	o, err := orgs(dynaClient).Get(slug, store.ReadOptions{})
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, &user.Error{Kind: user.ErrNotFound, Message: ErrorOrgDoesNotExist}
+	}
+	if err != nil {
+		return nil, storeError(err, ErrorCouldNotReadOrgs)
+	}
+	return o, nil
+}
+
+// List reads every organization from the entity index, in slug order.
+//
+// Parameters:
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - The organizations.
+// - A storage error if the index can't be queried.
+func List(dynaClient dynamodbiface.DynamoDBAPI) ([]Org, error) {
+	if !Enabled() {
+		return nil, &user.Error{Kind: user.ErrNotFound, Message: ErrorOrgsDisabled}
+	}
+	found, err := orgs(dynaClient).Query(&dynamodb.QueryInput{
+		IndexName:                 aws.String(user.EntityIndex()),
+		KeyConditionExpression:    aws.String("#entityType = :entityType"),
+		ExpressionAttributeNames:  map[string]*string{"#entityType": aws.String(user.EntityTypeAttribute)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":entityType": {S: aws.String(EntityType)}},
+		ReturnConsumedCapacity:    aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		return nil, storeError(err, ErrorCouldNotReadOrgs)
+	}
+	return found, nil
+}
+
+// Delete removes an organization and every membership in it.
+//
+// Parameters:
+// - slug: The slug of the organization.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - A not-found error if there is no such organization, or a storage error.
+func Delete(slug string, dynaClient dynamodbiface.DynamoDBAPI) error {
+	if _, err := Get(slug, dynaClient); err != nil {
+		return err
+	}
+	members, err := Members(slug, dynaClient)
+	if err != nil {
+		return err
+	}
+
+	// Memberships go first, so a failure leaves the organization to retry the delete on
+	keys := make([]store.Item, 0, 2*len(members)+1)
+	for _, m := range members {
+		keys = append(keys, orgSideKey(m.Org, m.Email), userSideKey(m.Org, m.Email))
+	}
+	if err := deleteItems(keys, dynaClient); err != nil {
+		return &user.Error{Kind: user.ErrStorage, Message: ErrorCouldNotWriteOrgs, Err: err}
+	}
+	err = orgs(dynaClient).Delete(slug, &store.Condition{
+		Expression: "attribute_exists(#pk)",
+		Names:      map[string]*string{"#pk": aws.String(user.PartitionKeyAttribute)},
+	})
+	if errors.Is(err, store.ErrConditionFailed) {
+		return &user.Error{Kind: user.ErrNotFound, Message: ErrorOrgDoesNotExist, Err: err}
+	}
+	return storeError(err, ErrorCouldNotWriteOrgs)
+}
+
+// Members reads the memberships of an organization, in email order.
+//
+// Parameters:
+// - slug: The slug of the organization.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - The memberships.
+// - A storage error if the query fails.
+func Members(slug string, dynaClient dynamodbiface.DynamoDBAPI) ([]Membership, error) {
+	return queryMemberships(orgKeyPrefix+slug, memberKeyPrefix, dynaClient)
+}
+
+// UserMemberships reads the memberships of a user, in slug order.
+//
+// Parameters:
+// - email: The normalized email of the user.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - The memberships.
+// - A storage error if the query fails.
+func UserMemberships(email string, dynaClient dynamodbiface.DynamoDBAPI) ([]Membership, error) {
+	return queryMemberships(user.PartitionKey(email), orgKeyPrefix, dynaClient)
+}
+
+// AddMember makes a user a member of an organization, writing both sides of the membership
+// in one TransactWriteItems that fails if the organization is gone. The caller checks that
+// the user exists.
+//
+// Parameters:
+// - slug: The slug of the organization.
+// - email: The normalized email of the user.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+//   - The membership.
+//   - A not-found error if there is no such organization, a conflict if the user is already
+//     a member, or a storage error.
+func AddMember(slug string, email string, dynaClient dynamodbiface.DynamoDBAPI) (*Membership, error) {
+	if !Enabled() {
+		return nil, &user.Error{Kind: user.ErrNotFound, Message: ErrorOrgsDisabled}
+	}
+	m := &Membership{Org: slug, Email: email, JoinedAt: time.Now().UTC().Format(time.RFC3339)}
+	orgSide, err := memberships(dynaClient).Marshal(m)
+	if err != nil {
+		return nil, storeError(err, ErrorCouldNotMarshalOrg)
+	}
+	userSide, err := memberships(dynaClient).Marshal(m)
+	if err != nil {
+		return nil, storeError(err, ErrorCouldNotMarshalOrg)
+	}
+	for name, value := range orgSideKey(slug, email) {
+		orgSide[name] = value
+	}
+	for name, value := range userSideKey(slug, email) {
+		userSide[name] = value
+	}
+
+	table := aws.String(TableName())
+	pkName := map[string]*string{"#pk": aws.String(user.PartitionKeyAttribute)}
+	_, err = dynaClient.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{ConditionCheck: &dynamodb.ConditionCheck{
+				TableName:                table,
+				Key:                      itemKey(orgKeyPrefix+slug, orgSortKey),
+				ConditionExpression:      aws.String("attribute_exists(#pk)"),
+				ExpressionAttributeNames: pkName,
+			}},
+			{Put: &dynamodb.Put{
+				TableName:                table,
+				Item:                     orgSide,
+				ConditionExpression:      aws.String("attribute_not_exists(#pk)"),
+				ExpressionAttributeNames: pkName,
+			}},
+			{Put: &dynamodb.Put{TableName: table, Item: userSide}},
+		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	switch failed := canceledItem(err); {
+	case err == nil:
+		return m, nil
+	case failed == 0:
+		return nil, &user.Error{Kind: user.ErrNotFound, Message: ErrorOrgDoesNotExist, Err: err}
+	case failed == 1:
+		return nil, &user.Error{Kind: user.ErrConflict, Message: ErrorAlreadyMember, Err: err}
+	}
+	return nil, &user.Error{Kind: user.ErrStorage, Message: ErrorCouldNotWriteOrgs, Err: err}
+}
+
+// RemoveMember removes a user from an organization, deleting both sides of the membership
+// in one TransactWriteItems.
+//
+// Parameters:
+// - slug: The slug of the organization.
+// - email: The normalized email of the user.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - A not-found error if the user isn't a member, or a storage error.
+func RemoveMember(slug string, email string, dynaClient dynamodbiface.DynamoDBAPI) error {
+	if !Enabled() {
+		return &user.Error{Kind: user.ErrNotFound, Message: ErrorOrgsDisabled}
+	}
+	table := aws.String(TableName())
+	_, err := dynaClient.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Delete: &dynamodb.Delete{
+				TableName:                table,
+				Key:                      orgSideKey(slug, email),
+				ConditionExpression:      aws.String("attribute_exists(#pk)"),
+				ExpressionAttributeNames: map[string]*string{"#pk": aws.String(user.PartitionKeyAttribute)},
+			}},
+			{Delete: &dynamodb.Delete{TableName: table, Key: userSideKey(slug, email)}},
+		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	switch failed := canceledItem(err); {
+	case err == nil:
+		return nil
+	case failed == 0:
+		return &user.Error{Kind: user.ErrNotFound, Message: ErrorNotMember, Err: err}
+	}
+	return &user.Error{Kind: user.ErrStorage, Message: ErrorCouldNotWriteOrgs, Err: err}
+}
+
+// queryMemberships reads the membership items of a partition whose sort key starts with
+// the prefix.
+func queryMemberships(partition string, sortPrefix string, dynaClient dynamodbiface.DynamoDBAPI) (
+	[]Membership, error) {
+	if !Enabled() {
+		return nil, &user.Error{Kind: user.ErrNotFound, Message: ErrorOrgsDisabled}
+	}
+	found, err := memberships(dynaClient).Query(&dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("#pk = :pk AND begins_with(#sk, :prefix)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#pk": aws.String(user.PartitionKeyAttribute),
+			"#sk": aws.String(user.SortKeyAttribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pk":     {S: aws.String(partition)},
+			":prefix": {S: aws.String(sortPrefix)},
+		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		return nil, storeError(err, ErrorCouldNotReadOrgs)
+	}
+	return found, nil
+}
+
+// deleteItems deletes items by key with BatchWriteItem, in chunks of 25, retrying
+// unprocessed items a few times.
+func deleteItems(keys []store.Item, dynaClient dynamodbiface.DynamoDBAPI) error {
+	table := TableName()
+	for start := 0; start < len(keys); start += writeBatchSize {
+		end := start + writeBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		requests := make([]*dynamodb.WriteRequest, 0, end-start)
+		for _, key := range keys[start:end] {
+			requests = append(requests, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: key}})
+		}
+
+		pending := map[string][]*dynamodb.WriteRequest{table: requests}
+		for attempt := 0; len(pending[table]) > 0; attempt++ {
+			if attempt == writeMaxAttempts {
+				return errors.New("some membership deletes were not processed")
+			}
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt) * 50 * time.Millisecond)
+			}
+			result, err := dynaClient.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return err
+			}
+			pending = result.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// canceledItem returns the index of the first item whose condition failed in a canceled
+// transaction, or -1 if err is not such a cancellation.
+func canceledItem(err error) int {
+	var canceled *dynamodb.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return -1
+	}
+	// Reasons are listed in the order of the transaction's items
+	for i, reason := range canceled.CancellationReasons {
+		if reason != nil && aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+			return i
+		}
+	}
+	return -1
+}
+
+// itemKey returns the PK/SK key of an item.
+func itemKey(partition string, sort string) store.Item {
+	return store.Item{
+		user.PartitionKeyAttribute: {S: aws.String(partition)},
+		user.SortKeyAttribute:      {S: aws.String(sort)},
+	}
+}
+
+// orgSideKey returns the key of a membership as stored under its organization.
+func orgSideKey(slug string, email string) store.Item {
+	return itemKey(orgKeyPrefix+slug, memberKeyPrefix+email)
+}
+
+// userSideKey returns the key of a membership as stored under its user.
+func userSideKey(slug string, email string) store.Item {
+	return itemKey(user.PartitionKey(email), orgKeyPrefix+slug)
+}
+
+// stripKeys removes the key attributes of an item read, in place, so it unmarshals to an
+// organization or membership.
+func stripKeys(item store.Item) error {
+	delete(item, user.PartitionKeyAttribute)
+	delete(item, user.SortKeyAttribute)
+	delete(item, user.EntityTypeAttribute)
+	return nil
+}
+
+// storeError converts an error of the generic store to a *user.Error.
+func storeError(err error, message string) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, store.ErrMarshal):
+		return &user.Error{Kind: user.ErrInternal, Message: ErrorCouldNotMarshalOrg, Err: err}
+	case errors.Is(err, store.ErrUnmarshal):
+		return &user.Error{Kind: user.ErrInternal, Message: ErrorCouldNotUnmarshalOrg, Err: err}
+	}
+	return &user.Error{Kind: user.ErrStorage, Message: message, Err: err}
+}
@@ -0,0 +1,168 @@
+package outbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"os"
+	"strconv"
+	"time"
+)
+
+// retention is how long sent entries are kept before DynamoDB TTL removes them
+const retention = 7 * 24 * time.Hour
+
+// Entry is an event recorded in the outbox in the same transaction as the write it
+// describes, until the relay publishes it. The outbox table uses id as partition key, and
+// expiresAt as TTL attribute.
+type Entry struct {
+	ID        string `json:"id"`                  // Deterministic ID of the event, the same on every delivery
+	Action    string `json:"action"`              // What happened to the user: "created", "updated" or "deleted"
+	Email     string `json:"email"`               // Email of the affected user
+	User      string `json:"user,omitempty"`      // The new record as client-facing JSON, for creates and updates
+	RequestID string `json:"requestId,omitempty"` // ID of the request that made the change, if known
+	Time      string `json:"time"`                // RFC3339 time of the change
+	SentAt    string `json:"sentAt,omitempty"`    // RFC3339 time the event was published; unset while pending
+	ExpiresAt int64  `json:"expiresAt,omitempty"` // Epoch seconds DynamoDB TTL removes the entry at, once sent
+}
+
+// TableName returns the outbox table (OUTBOX_TABLE_NAME), or an empty string when events
+// are published right after the writes instead.
+func TableName() string {
+	return os.Getenv("OUTBOX_TABLE_NAME")
+}
+
+// Enabled reports whether events are written to the outbox with the writes they describe.
+func Enabled() bool {
+	return TableName() != ""
+}
+
+// EventID derives the ID of an event from the write it describes, so the same write always
+// yields the same ID and consumers can drop duplicate deliveries.
+//
+// Parameters:
+// - action: What happened to the user, e.g. "created".
+// - email: The email of the affected user.
+// - version: The version of the user the write produced, or 0 for deletes.
+// - at: The RFC3339 time of the write.
+//
+// Returns:
+// - The ID, 32 hex digits.
+func EventID(action string, email string, version int, at string) string {
+	sum := sha256.Sum256([]byte(action + "\x00" + email + "\x00" + strconv.Itoa(version) + "\x00" + at))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NewEntry builds the pending entry of a write.
+//
+// Parameters:
+// - action: What happened to the user, e.g. "created".
+// - email: The email of the affected user.
+// - version: The version of the user the write produced, or 0 for deletes.
+// - record: The new record as JSON, or nil for deletes.
+// - requestID: The ID of the request that made the write, or an empty string.
+// - at: The RFC3339 time of the write.
+//
+// Returns:
+// - The entry.
+func NewEntry(action string, email string, version int, record []byte, requestID string, at string) *Entry {
+	return &Entry{
+		ID:        EventID(action, email, version, at),
+		Action:    action,
+		Email:     email,
+		User:      string(record),
+		RequestID: requestID,
+		Time:      at,
+	}
+}
+
+// Put returns the transaction item writing an entry to the outbox table, to be written in
+// the same TransactWriteItems as the change it records. An entry already written by an
+// earlier attempt of the same write is replaced, as it has the same ID.
+//
+// Returns:
+// - The transaction item.
+// - An error if the entry can't be marshaled.
+func Put(entry *Entry) (*dynamodb.TransactWriteItem, error) {
+	item, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+		TableName: aws.String(TableName()),
+		Item:      item,
+	}}, nil
+}
+
+// Pending reads the entries not published yet, up to maxEntries, in no particular order.
+//
+// Parameters:
+// - ctx: The context of the scan.
+// - maxEntries: The most entries to return.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - The entries.
+// - An error if the scan or unmarshaling fails.
+func Pending(ctx context.Context, maxEntries int, dynaClient dynamodbiface.DynamoDBAPI) ([]Entry, error) {
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(TableName()),
+		FilterExpression:         aws.String("attribute_not_exists(#sentAt)"),
+		ExpressionAttributeNames: map[string]*string{"#sentAt": aws.String("sentAt")},
+	}
+	var items []map[string]*dynamodb.AttributeValue
+	for len(items) < maxEntries {
+		result, err := dynaClient.ScanWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Items...)
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+	if len(items) > maxEntries {
+		items = items[:maxEntries]
+	}
+
+	entries := []Entry{}
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// MarkSent flags an entry as published at now, and schedules its removal by TTL.
+//
+// Parameters:
+// - ctx: The context of the update.
+// - id: The ID of the entry.
+// - now: The time the entry was published.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - An error if the update fails; the entry then stays pending and is published again.
+func MarkSent(ctx context.Context, id string, now time.Time, dynaClient dynamodbiface.DynamoDBAPI) error {
+	_, err := dynaClient.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(TableName()),
+		Key:              map[string]*dynamodb.AttributeValue{"id": {S: aws.String(id)}},
+		UpdateExpression: aws.String("SET #sentAt = :sentAt, #expiresAt = :expiresAt"),
+		// Don't resurrect an entry removed meanwhile as a bare key
+		ConditionExpression: aws.String("attribute_exists(#id)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id":        aws.String("id"),
+			"#sentAt":    aws.String("sentAt"),
+			"#expiresAt": aws.String("expiresAt"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":sentAt":    {S: aws.String(now.UTC().Format(time.RFC3339))},
+			":expiresAt": {N: aws.String(strconv.FormatInt(now.Add(retention).Unix(), 10))},
+		},
+	})
+	return err
+}
@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/audit"
+	userevents "github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/outbox"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/Vansh3140/golang-serverless/pkg/webhook"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// actor is the audit actor of the users created from the queue
+const actor = "queue"
+
+// Handler creates users from the messages of an SQS queue
+type Handler struct {
+	Repository user.Repository           // The repository storing the users
+	DynaClient dynamodbiface.DynamoDBAPI // The DynamoDB client interface, used for the audit table
+}
+
+// Handle creates a user from each message of a batch. Every message body is a user as
+// JSON, validated and created like the body of POST /users.
+//
+// A user that already exists counts as created, so a message delivered twice (or a job
+// enqueued twice) doesn't end up in the dead-letter queue. Only the messages that failed,
+// malformed or not, are reported, so SQS redrives just those. The users created are
+// audited, published and posted to WEBHOOK_URL like those created through the API.
+//
+// Parameters:
+// - ctx: The invocation context.
+// - event: The batch of messages.
+//
+// Returns:
+// - The response naming the failed messages.
+// - Always a nil error, as failures are reported per message.
+func (h *Handler) Handle(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	response := events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{}}
+	var entries []audit.Entry
+	var published []userevents.Event
+	for _, message := range event.Records {
+		newUser, err := user.CreateUserFromJSON(message.Body, user.CreateOptions{}, h.Repository)
+		switch {
+		case errors.Is(err, user.ErrConflict):
+			logging.Default.Info("user already exists", logging.Fields{"messageId": message.MessageId})
+		case err != nil:
+			logging.Default.Error("failed to create user from message", logging.Fields{
+				"messageId": message.MessageId,
+				"error":     err,
+			})
+			response.BatchItemFailures = append(response.BatchItemFailures,
+				events.SQSBatchItemFailure{ItemIdentifier: message.MessageId})
+		default:
+			logging.Default.Info("user created", logging.Fields{
+				"messageId": message.MessageId,
+				"email":     logging.Email(newUser.Email),
+			})
+			entries = append(entries, audit.NewEntry("created", newUser.Email, actor, message.MessageId))
+			published = append(published, userevents.NewEvent(userevents.UserCreated, newUser.Email, newUser, message.MessageId))
+		}
+	}
+
+	// The users exist at this point, so failing to record them must not redrive the messages
+	if audit.Enabled() && len(entries) > 0 {
+		if err := audit.Record(entries, h.DynaClient); err != nil {
+			logging.Default.Error("failed to write audit log", logging.Fields{"entries": len(entries), "error": err})
+		}
+	}
+	// With an outbox, the creates were recorded in it and are published from there
+	if outbox.Enabled() {
+		return response, nil
+	}
+	for _, failure := range userevents.PublishAll(userevents.Default, published) {
+		logging.Default.Error("failed to publish event", logging.Fields{
+			"type":  failure.Event.Type,
+			"email": logging.Email(failure.Event.Email),
+			"error": failure.Err,
+		})
+	}
+	webhook.DeliverAll(ctx, published)
+	return response, nil
+}
@@ -0,0 +1,88 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"reflect"
+	"testing"
+)
+
+// failingRepository is an in-memory repository whose creates of one email fail
+type failingRepository struct {
+	*user.MemoryRepository
+	failEmail string
+}
+
+func (r *failingRepository) Create(u *user.User) error {
+	if u.Email == r.failEmail {
+		return errors.New("throughput exceeded")
+	}
+	return r.MemoryRepository.Create(u)
+}
+
+func TestHandle(t *testing.T) {
+	const (
+		ada   = `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`
+		alan  = `{"email": "alan@example.com", "firstname": "Alan", "lastname": "Turing"}`
+		grace = `{"email": "grace@example.com", "firstname": "Grace", "lastname": "Hopper"}`
+	)
+	tests := []struct {
+		name         string
+		bodies       []string
+		failEmail    string
+		wantFailures []string
+		wantStored   []string
+	}{
+		{name: "valid", bodies: []string{alan, grace}, wantFailures: []string{},
+			wantStored: []string{"ada@example.com", "alan@example.com", "grace@example.com"}},
+		{name: "duplicate of a stored user", bodies: []string{ada, alan}, wantFailures: []string{},
+			wantStored: []string{"ada@example.com", "alan@example.com"}},
+		{name: "duplicate within the batch", bodies: []string{alan, alan}, wantFailures: []string{},
+			wantStored: []string{"ada@example.com", "alan@example.com"}},
+		{name: "malformed", bodies: []string{alan, `{"email": "grace@example.com"`, `not json`},
+			wantFailures: []string{"msg-1", "msg-2"}, wantStored: []string{"ada@example.com", "alan@example.com"}},
+		{name: "invalid", bodies: []string{`{"email": "not-an-email", "firstname": "Bob", "lastname": "Smith"}`, grace},
+			wantFailures: []string{"msg-0"}, wantStored: []string{"ada@example.com", "grace@example.com"}},
+		{name: "mixed", bodies: []string{ada, `[]`, alan, grace}, failEmail: "grace@example.com",
+			wantFailures: []string{"msg-1", "msg-3"}, wantStored: []string{"ada@example.com", "alan@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &failingRepository{MemoryRepository: user.NewMemoryRepository(), failEmail: tt.failEmail}
+			if err := repo.MemoryRepository.Create(&user.User{Email: "ada@example.com", FirstName: "Ada",
+				LastName: "Lovelace"}); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+			event := events.SQSEvent{}
+			for i, body := range tt.bodies {
+				event.Records = append(event.Records, events.SQSMessage{MessageId: "msg-" + string(rune('0'+i)), Body: body})
+			}
+
+			response, err := (&Handler{Repository: repo}).Handle(context.Background(), event)
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			failures := []string{}
+			for _, failure := range response.BatchItemFailures {
+				failures = append(failures, failure.ItemIdentifier)
+			}
+			if !reflect.DeepEqual(failures, tt.wantFailures) {
+				t.Errorf("BatchItemFailures = %v, want %v", failures, tt.wantFailures)
+			}
+
+			page, err := repo.List(user.ReadOptions{})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var stored []string
+			for _, u := range page.Users {
+				stored = append(stored, u.Email)
+			}
+			if !reflect.DeepEqual(stored, tt.wantStored) {
+				t.Errorf("stored %v, want %v", stored, tt.wantStored)
+			}
+		})
+	}
+}
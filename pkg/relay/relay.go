@@ -0,0 +1,120 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/outbox"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/Vansh3140/golang-serverless/pkg/webhook"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"time"
+)
+
+// maxEntries bounds the entries relayed by one invocation; the rest wait for the next one
+const maxEntries = 500
+
+// eventTypes maps outbox actions to the lifecycle event they publish
+var eventTypes = map[string]string{
+	"created": events.UserCreated,
+	"updated": events.UserUpdated,
+	"deleted": events.UserDeleted,
+}
+
+// Result sums up an invocation of the relay
+type Result struct {
+	Published int `json:"published"` // Entries published and marked sent
+	Failed    int `json:"failed"`    // Entries left pending, to be retried by the next invocation
+}
+
+// Handler publishes the pending entries of the outbox (OUTBOX_TABLE_NAME)
+type Handler struct {
+	DynaClient dynamodbiface.DynamoDBAPI // The DynamoDB client interface
+	Publisher  events.Publisher          // Where events are published; events.Default if nil
+}
+
+// Handle publishes the pending outbox entries, then marks them sent. It is meant to run on
+// a schedule. An entry whose publish fails stays pending and is retried by the next
+// invocation; so is one published but not marked sent, so consumers must drop duplicates
+// by the event ID. The events published are also posted to WEBHOOK_URL, if set.
+//
+// Parameters:
+// - ctx: The invocation context.
+//
+// Returns:
+// - The number of entries published and left pending.
+// - An error if the outbox can't be read.
+func (h *Handler) Handle(ctx context.Context) (Result, error) {
+	entries, err := outbox.Pending(ctx, maxEntries, h.DynaClient)
+	if err != nil {
+		logging.Default.Error("failed to read outbox", logging.Fields{"error": err})
+		return Result{}, err
+	}
+
+	publisher := h.Publisher
+	if publisher == nil {
+		publisher = events.Default
+	}
+	var result Result
+	pending := make([]events.Event, 0, len(entries))
+	for _, entry := range entries {
+		event, err := NewEvent(entry)
+		if err != nil {
+			logging.Default.Error("failed to read outbox entry", logging.Fields{"id": entry.ID, "error": err})
+			result.Failed++
+			continue
+		}
+		pending = append(pending, event)
+	}
+
+	failed := map[string]bool{}
+	for _, failure := range events.PublishAll(publisher, pending) {
+		logging.Default.Error("failed to publish event", logging.Fields{
+			"id":    failure.Event.ID,
+			"type":  failure.Event.Type,
+			"email": logging.Email(failure.Event.Email),
+			"error": failure.Err,
+		})
+		failed[failure.Event.ID] = true
+	}
+
+	published := make([]events.Event, 0, len(pending))
+	for _, event := range pending {
+		if failed[event.ID] {
+			result.Failed++
+			continue
+		}
+		published = append(published, event)
+		if err := outbox.MarkSent(ctx, event.ID, time.Now(), h.DynaClient); err != nil {
+			logging.Default.Error("failed to mark outbox entry sent", logging.Fields{"id": event.ID, "error": err})
+		}
+		result.Published++
+	}
+	webhook.DeliverAll(ctx, published)
+
+	logging.Default.Info("relayed outbox", logging.Fields{"published": result.Published, "failed": result.Failed})
+	return result, nil
+}
+
+// NewEvent converts an outbox entry to the event it records, with the entry's ID.
+//
+// Returns:
+// - The event.
+// - An error if the entry's record isn't valid JSON.
+func NewEvent(entry outbox.Entry) (events.Event, error) {
+	event := events.Event{
+		ID:        entry.ID,
+		Type:      eventTypes[entry.Action],
+		Email:     entry.Email,
+		RequestID: entry.RequestID,
+		Time:      entry.Time,
+	}
+	if entry.User != "" {
+		event.User = &user.User{}
+		if err := json.Unmarshal([]byte(entry.User), event.User); err != nil {
+			return events.Event{}, err
+		}
+	}
+	return event, nil
+}
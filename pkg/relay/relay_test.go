@@ -0,0 +1,195 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/Vansh3140/golang-serverless/pkg/outbox"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"testing"
+)
+
+// newOutboxTable returns a FakeDynamo holding the entries in an outbox table: scans return
+// the entries without sentAt, and updates set it.
+func newOutboxTable(t *testing.T, entries ...*outbox.Entry) (*mocks.FakeDynamo, map[string]map[string]*dynamodb.AttributeValue) {
+	t.Helper()
+	items := map[string]map[string]*dynamodb.AttributeValue{}
+	for _, entry := range entries {
+		item, err := dynamodbattribute.MarshalMap(entry)
+		if err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+		items[entry.ID] = item
+	}
+	fake := mocks.NewFakeDynamo()
+	fake.OnScan(func(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+		var pending []map[string]*dynamodb.AttributeValue
+		for _, item := range items {
+			if item["sentAt"] == nil {
+				pending = append(pending, item)
+			}
+		}
+		return &dynamodb.ScanOutput{Items: pending}, nil
+	})
+	fake.OnUpdateItem(func(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+		item := items[aws.StringValue(in.Key["id"].S)]
+		if item == nil {
+			return nil, mocks.ConditionalCheckFailedError()
+		}
+		item["sentAt"] = in.ExpressionAttributeValues[":sentAt"]
+		item["expiresAt"] = in.ExpressionAttributeValues[":expiresAt"]
+		return &dynamodb.UpdateItemOutput{}, nil
+	})
+	return fake, items
+}
+
+// failingPublisher fails to publish the events of the emails in failing, and keeps the others
+type failingPublisher struct {
+	failing   map[string]bool
+	published []events.Event
+}
+
+func (p *failingPublisher) Publish(event events.Event) error {
+	if p.failing[event.Email] {
+		return errors.New("sink unavailable")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	ada := outbox.NewEntry("created", "ada@example.com", 1, []byte(`{"email":"ada@example.com","version":1}`), "req-1",
+		"2024-01-01T00:00:00Z")
+	grace := outbox.NewEntry("deleted", "grace@example.com", 0, nil, "req-2", "2024-01-01T00:00:01Z")
+	tests := []struct {
+		name          string
+		failing       map[string]bool
+		failMarkSent  bool
+		wantResult    Result
+		wantPending   []string // Entries left unsent after the first invocation
+		wantPublished []string // Emails of the events published over both invocations
+	}{
+		{name: "published", wantResult: Result{Published: 2},
+			wantPublished: []string{"ada@example.com", "grace@example.com"}},
+		{name: "publisher failure", failing: map[string]bool{"grace@example.com": true},
+			wantResult: Result{Published: 1, Failed: 1}, wantPending: []string{grace.ID},
+			wantPublished: []string{"ada@example.com", "grace@example.com"}},
+		{name: "sink down", failing: map[string]bool{"ada@example.com": true, "grace@example.com": true},
+			wantResult: Result{Failed: 2}, wantPending: []string{ada.ID, grace.ID},
+			wantPublished: []string{"ada@example.com", "grace@example.com"}},
+		// Published but not marked sent, so published again: consumers drop the duplicates by ID
+		{name: "mark sent failure", failMarkSent: true, wantResult: Result{Published: 2},
+			wantPending:   []string{ada.ID, grace.ID},
+			wantPublished: []string{"ada@example.com", "grace@example.com", "ada@example.com", "grace@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OUTBOX_TABLE_NAME", "outbox")
+			fake, items := newOutboxTable(t, ada, grace)
+			if tt.failMarkSent {
+				fake.OnUpdateItem(func(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+					return nil, mocks.ThrottlingError()
+				})
+			}
+			publisher := &failingPublisher{failing: tt.failing}
+			h := &Handler{DynaClient: fake, Publisher: publisher}
+
+			result, err := h.Handle(context.Background())
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if result != tt.wantResult {
+				t.Errorf("Handle() = %+v, want %+v", result, tt.wantResult)
+			}
+			pending := map[string]bool{}
+			for id, item := range items {
+				if item["sentAt"] == nil {
+					pending[id] = true
+				}
+			}
+			if len(pending) != len(tt.wantPending) {
+				t.Errorf("pending entries = %v, want %v", pending, tt.wantPending)
+			}
+			for _, id := range tt.wantPending {
+				if !pending[id] {
+					t.Errorf("entry %s was marked sent, want it left for a retry", id)
+				}
+			}
+
+			// The next invocation retries what is still pending, under the same event IDs
+			publisher.failing = nil
+			if _, err := h.Handle(context.Background()); err != nil {
+				t.Fatalf("second Handle() error = %v", err)
+			}
+			emails := map[string]int{}
+			for _, event := range publisher.published {
+				emails[event.Email]++
+				if want := map[string]string{ada.Email: ada.ID, grace.Email: grace.ID}[event.Email]; event.ID != want {
+					t.Errorf("event of %s has ID %s, want %s", event.Email, event.ID, want)
+				}
+			}
+			wantEmails := map[string]int{}
+			for _, email := range tt.wantPublished {
+				wantEmails[email]++
+			}
+			if len(emails) != len(wantEmails) || emails[ada.Email] != wantEmails[ada.Email] ||
+				emails[grace.Email] != wantEmails[grace.Email] {
+				t.Errorf("published %v, want %v", emails, wantEmails)
+			}
+		})
+	}
+}
+
+func TestHandleOutboxUnreadable(t *testing.T) {
+	t.Setenv("OUTBOX_TABLE_NAME", "outbox")
+	fake := mocks.NewFakeDynamo()
+	fake.OnScan(func(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+		return nil, mocks.ThrottlingError()
+	})
+	publisher := &failingPublisher{}
+
+	if _, err := (&Handler{DynaClient: fake, Publisher: publisher}).Handle(context.Background()); err == nil {
+		t.Errorf("Handle() error = nil, want the scan's error")
+	}
+	if len(publisher.published) != 0 {
+		t.Errorf("published %v, want nothing", publisher.published)
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    *outbox.Entry
+		wantType string
+		wantErr  bool
+	}{
+		{name: "created", entry: outbox.NewEntry("created", "ada@example.com", 1, []byte(`{"email":"ada@example.com"}`), "",
+			"2024-01-01T00:00:00Z"), wantType: events.UserCreated},
+		{name: "updated", entry: outbox.NewEntry("updated", "ada@example.com", 2, []byte(`{"email":"ada@example.com"}`), "",
+			"2024-01-01T00:00:00Z"), wantType: events.UserUpdated},
+		{name: "deleted", entry: outbox.NewEntry("deleted", "ada@example.com", 0, nil, "", "2024-01-01T00:00:00Z"),
+			wantType: events.UserDeleted},
+		{name: "invalid record", entry: outbox.NewEntry("created", "ada@example.com", 1, []byte(`{`), "",
+			"2024-01-01T00:00:00Z"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := NewEvent(*tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewEvent() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if event.ID != tt.entry.ID || event.Type != tt.wantType || event.Email != "ada@example.com" {
+				t.Errorf("NewEvent() = %+v, want a %s event with ID %s", event, tt.wantType, tt.entry.ID)
+			}
+			if (event.User != nil) != (tt.entry.User != "") {
+				t.Errorf("NewEvent() user = %+v, want one only for creates and updates", event.User)
+			}
+		})
+	}
+}
@@ -0,0 +1,462 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format checks a string against a named "format", e.g. "email", explaining why it fails
+type Format func(value string) error
+
+// Violation is a way a document fails a schema
+type Violation struct {
+	Pointer string // JSON pointer of the offending value, e.g. "/address/city"; empty for the document itself
+	Keyword string // The keyword the value fails, e.g. "maxLength"
+	Message string // Why the value fails
+}
+
+// Registry holds the schemas compiled from a set of JSON Schema documents, which may refer
+// to each other by file name
+type Registry struct {
+	schemas map[string]*Schema
+}
+
+// Schema is a compiled JSON Schema. A subset of draft 2020-12 is supported: the keywords
+// $ref, $defs, type, enum, properties, required, additionalProperties, propertyNames,
+// maxProperties, items, minLength, maxLength, pattern, format, minimum and maximum, plus
+// the annotations $schema, $id, title and description. errorMessage replaces the message
+// of every violation of the schema's own value, except type mismatches.
+type Schema struct {
+	Meta        string `json:"$schema"`
+	ID          string `json:"$id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+
+	Ref  string             `json:"$ref"`
+	Defs map[string]*Schema `json:"$defs"`
+
+	Type                 types         `json:"type"`
+	Enum                 []interface{} `json:"enum"`
+	Properties           properties    `json:"properties"`
+	Required             []string      `json:"required"`
+	AdditionalProperties *Schema       `json:"additionalProperties"`
+	PropertyNames        *Schema       `json:"propertyNames"`
+	MaxProperties        *int          `json:"maxProperties"`
+	Items                *Schema       `json:"items"`
+	MinLength            *int          `json:"minLength"`
+	MaxLength            *int          `json:"maxLength"`
+	Pattern              string        `json:"pattern"`
+	Format               string        `json:"format"`
+	Minimum              *float64      `json:"minimum"`
+	Maximum              *float64      `json:"maximum"`
+	ErrorMessage         string        `json:"errorMessage"`
+
+	allows  *bool          // Set for the boolean schemas true and false
+	ref     *Schema        // The schema $ref resolves to
+	pattern *regexp.Regexp // The compiled pattern
+	format  Format         // The checker of the format
+}
+
+// types is the "type" keyword: one type name or a list of them
+type types []string
+
+// properties is the "properties" keyword, keeping the order the properties are declared in
+// so violations are reported in that order
+type properties struct {
+	names   []string
+	schemas map[string]*Schema
+}
+
+// Compile compiles every .json file at the root of a file system. Each is a schema named
+// after its file, e.g. "create.json", and may refer to the others with $ref, e.g.
+// "user.json#/properties/email".
+//
+// Parameters:
+// - files: The file system holding the schemas.
+// - formats: The checkers of the formats the schemas use, by name.
+//
+// Returns:
+//   - The registry of the compiled schemas.
+//   - An error naming the file if a schema is malformed, uses an unknown keyword or format,
+//     or refers to a schema that doesn't exist.
+func Compile(files fs.FS, formats map[string]Format) (*Registry, error) {
+	names, err := fs.Glob(files, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &Registry{schemas: map[string]*Schema{}}
+	for _, name := range names {
+		data, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		var schema Schema
+		if err := decoder.Decode(&schema); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		registry.schemas[name] = &schema
+	}
+	for _, name := range names {
+		if err := registry.link(name, registry.schemas[name], formats); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return registry, nil
+}
+
+// Schema returns the schema compiled from a file, or nil if there is none.
+func (r *Registry) Schema(name string) *Schema {
+	return r.schemas[name]
+}
+
+// link resolves the references, patterns and formats of a schema and its subschemas.
+func (r *Registry) link(file string, s *Schema, formats map[string]Format) error {
+	if s == nil || s.allows != nil {
+		return nil
+	}
+	if s.Ref != "" {
+		ref, err := r.resolve(file, s.Ref)
+		if err != nil {
+			return err
+		}
+		s.ref = ref
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = pattern
+	}
+	if s.Format != "" {
+		if s.format = formats[s.Format]; s.format == nil {
+			return fmt.Errorf("unknown format %q", s.Format)
+		}
+	}
+	for _, name := range s.Type {
+		if !knownTypes[name] {
+			return fmt.Errorf("unknown type %q", name)
+		}
+	}
+
+	subschemas := []*Schema{s.AdditionalProperties, s.PropertyNames, s.Items}
+	for _, name := range s.Properties.names {
+		subschemas = append(subschemas, s.Properties.schemas[name])
+	}
+	for _, def := range s.Defs {
+		subschemas = append(subschemas, def)
+	}
+	for _, subschema := range subschemas {
+		if err := r.link(file, subschema, formats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve finds the schema a $ref names: a file, optionally followed by a fragment made of
+// $defs/<name> and properties/<name> steps, e.g. "user.json#/properties/email". A ref
+// starting with "#" refers to its own file.
+func (r *Registry) resolve(file string, ref string) (*Schema, error) {
+	target, fragment := ref, ""
+	if i := strings.Index(ref, "#"); i >= 0 {
+		target, fragment = ref[:i], ref[i+1:]
+	}
+	if target == "" {
+		target = file
+	}
+	s := r.schemas[path.Clean(target)]
+	if s == nil {
+		return nil, fmt.Errorf("unknown schema %q", ref)
+	}
+
+	steps := strings.Split(strings.Trim(fragment, "/"), "/")
+	if fragment == "" || fragment == "/" {
+		steps = nil
+	}
+	if len(steps)%2 != 0 {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+	for i := 0; i < len(steps); i += 2 {
+		name := unescape(steps[i+1])
+		switch steps[i] {
+		case "$defs":
+			s = s.Defs[name]
+		case "properties":
+			s = s.Properties.schemas[name]
+		default:
+			return nil, fmt.Errorf("unsupported $ref %q", ref)
+		}
+		if s == nil {
+			return nil, fmt.Errorf("unknown schema %q", ref)
+		}
+	}
+	return s, nil
+}
+
+// Validate checks a document decoded by encoding/json, e.g. into an interface{}, against
+// the schema.
+//
+// Returns:
+// - Every violation found, in document order where it has one; nil if the document is valid.
+func (s *Schema) Validate(document interface{}) []Violation {
+	var violations []Violation
+	s.validate(document, "", &violations)
+	return violations
+}
+
+// validate checks a value at a pointer, appending the violations found.
+func (s *Schema) validate(value interface{}, pointer string, violations *[]Violation) {
+	if s.allows != nil {
+		if !*s.allows {
+			*violations = append(*violations, Violation{pointer, "false", "is not allowed"})
+		}
+		return
+	}
+
+	var found []Violation
+	if s.ref != nil {
+		s.ref.validate(value, pointer, &found)
+	}
+	if len(s.Type) > 0 && !s.Type.match(value) {
+		found = append(found, Violation{pointer, "type", "must be of type " + strings.Join(s.Type, " or ")})
+	} else {
+		s.validateValue(value, pointer, &found)
+	}
+
+	for i := range found {
+		if s.ErrorMessage != "" && found[i].Pointer == pointer && found[i].Keyword != "type" {
+			found[i].Message = s.ErrorMessage
+		}
+	}
+	*violations = append(*violations, found...)
+}
+
+// validateValue checks the keywords that apply to the type of a value.
+func (s *Schema) validateValue(value interface{}, pointer string, violations *[]Violation) {
+	if len(s.Enum) > 0 && !s.enumerates(value) {
+		*violations = append(*violations, Violation{pointer, "enum", "must be one of the allowed values"})
+	}
+
+	switch value := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(value)
+		if s.MinLength != nil && length < *s.MinLength {
+			*violations = append(*violations, Violation{pointer, "minLength",
+				"must be at least " + strconv.Itoa(*s.MinLength) + " characters"})
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			*violations = append(*violations, Violation{pointer, "maxLength",
+				"must be at most " + strconv.Itoa(*s.MaxLength) + " characters"})
+		}
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			*violations = append(*violations, Violation{pointer, "pattern", "must match " + s.Pattern})
+		}
+		if s.format != nil {
+			if err := s.format(value); err != nil {
+				*violations = append(*violations, Violation{pointer, "format", err.Error()})
+			}
+		}
+	case float64:
+		if s.Minimum != nil && value < *s.Minimum {
+			*violations = append(*violations, Violation{pointer, "minimum",
+				"must be at least " + strconv.FormatFloat(*s.Minimum, 'f', -1, 64)})
+		}
+		if s.Maximum != nil && value > *s.Maximum {
+			*violations = append(*violations, Violation{pointer, "maximum",
+				"must be at most " + strconv.FormatFloat(*s.Maximum, 'f', -1, 64)})
+		}
+	case map[string]interface{}:
+		s.validateObject(value, pointer, violations)
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				s.Items.validate(item, pointer+"/"+strconv.Itoa(i), violations)
+			}
+		}
+	}
+}
+
+// validateObject checks the keywords of objects, in the order the properties are declared
+// and then in key order.
+func (s *Schema) validateObject(object map[string]interface{}, pointer string, violations *[]Violation) {
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			*violations = append(*violations, Violation{pointer + "/" + escape(name), "required", "is required"})
+		}
+	}
+	if s.MaxProperties != nil && len(object) > *s.MaxProperties {
+		*violations = append(*violations, Violation{pointer, "maxProperties",
+			"must have at most " + strconv.Itoa(*s.MaxProperties) + " properties"})
+	}
+
+	for _, name := range s.Properties.names {
+		if value, ok := object[name]; ok {
+			s.Properties.schemas[name].validate(value, pointer+"/"+escape(name), violations)
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		child := pointer + "/" + escape(key)
+		if s.PropertyNames != nil {
+			s.PropertyNames.validate(key, child, violations)
+		}
+		if _, declared := s.Properties.schemas[key]; declared || s.AdditionalProperties == nil {
+			continue
+		}
+		if allows := s.AdditionalProperties.allows; allows != nil && !*allows {
+			*violations = append(*violations, Violation{child, "additionalProperties", "is not allowed"})
+			continue
+		}
+		s.AdditionalProperties.validate(object[key], child, violations)
+	}
+}
+
+// enumerates reports whether a value is one of the enum's.
+func (s *Schema) enumerates(value interface{}) bool {
+	encoded, _ := json.Marshal(value)
+	for _, allowed := range s.Enum {
+		if candidate, _ := json.Marshal(allowed); bytes.Equal(candidate, encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+// knownTypes are the names the "type" keyword accepts
+var knownTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// match reports whether a value decoded by encoding/json has one of the types.
+func (t types) match(value interface{}) bool {
+	for _, name := range t {
+		switch value := value.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case float64:
+			if name == "number" || name == "integer" && value == math.Trunc(value) {
+				return true
+			}
+		case map[string]interface{}:
+			if name == "object" {
+				return true
+			}
+		case []interface{}:
+			if name == "array" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON decodes a schema, which may be the boolean schema true or false. Unknown
+// keywords are rejected, so a misspelled one doesn't silently weaken the schema.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var allows bool
+	if err := json.Unmarshal(data, &allows); err == nil {
+		s.allows = &allows
+		return nil
+	}
+
+	// The alias has the fields of Schema without its methods, so decoding doesn't recurse
+	type plain Schema
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plain)(s))
+}
+
+// UnmarshalJSON decodes one type name or a list of them.
+func (t *types) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = types{name}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// UnmarshalJSON decodes the properties, recording the order they are declared in.
+func (p *properties) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return fmt.Errorf("properties must be an object")
+	}
+	p.schemas = map[string]*Schema{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		name := token.(string)
+		var schema Schema
+		if err := decoder.Decode(&schema); err != nil {
+			return err
+		}
+		if _, ok := p.schemas[name]; !ok {
+			p.names = append(p.names, name)
+		}
+		p.schemas[name] = &schema
+	}
+	return nil
+}
+
+// escape encodes a property name as a JSON pointer token (RFC 6901).
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// unescape decodes a JSON pointer token (RFC 6901).
+func unescape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// Tokens splits a JSON pointer into its decoded tokens, e.g. ["address", "city"] for
+// "/address/city".
+func Tokens(pointer string) []string {
+	if pointer == "" {
+		return nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, token := range tokens {
+		tokens[i] = unescape(token)
+	}
+	return tokens
+}
+
+// Pointer joins tokens into a JSON pointer, e.g. "/address/city" for ["address", "city"].
+func Pointer(tokens ...string) string {
+	var pointer strings.Builder
+	for _, token := range tokens {
+		pointer.WriteString("/" + escape(token))
+	}
+	return pointer.String()
+}
@@ -0,0 +1,365 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"sync"
+	"sync/atomic"
+)
+
+// Errors of a Store, which callers map to their own; the DynamoDB error, if any, is wrapped
+var (
+	ErrNotFound        = errors.New("item not found")
+	ErrConditionFailed = errors.New("condition not met")
+	ErrMarshal         = errors.New("couldn't marshal the item")
+	ErrUnmarshal       = errors.New("couldn't unmarshal the item")
+)
+
+// Item is an entity as stored in DynamoDB
+type Item = map[string]*dynamodb.AttributeValue
+
+// Condition is the ConditionExpression of a write, with the attribute names and values it uses
+type Condition struct {
+	Expression string
+	Names      map[string]*string
+	Values     map[string]*dynamodb.AttributeValue
+}
+
+// ReadOptions tune a Get
+type ReadOptions struct {
+	ConsistentRead bool               // Read with strong consistency
+	Projection     *string            // ProjectionExpression limiting the attributes read, or nil for all
+	Names          map[string]*string // ExpressionAttributeNames used by the projection
+}
+
+// Store reads and writes the entities of type T in a DynamoDB table, marshaled with
+// dynamodbattribute. The hooks adapt it to an entity's layout; only KeyAttribute and Key
+// are required.
+type Store[T any] struct {
+	Client       dynamodbiface.DynamoDBAPI // The DynamoDB client interface
+	TableName    string                    // The name of the table
+	KeyAttribute string                    // Partition key attribute holding the key of each entity
+	Key          func(entity *T) string    // Extracts the key of an entity
+
+	// Checks an entity before it is put, if set
+	Validate func(entity *T) error
+	// Builds the DynamoDB key of an entity key, e.g. a composite one; {KeyAttribute: key} if nil
+	KeyOf func(key string) Item
+	// Adjusts a marshaled item before it is written, e.g. to add keys or encrypt attributes
+	Encode func(item Item) error
+	// Adjusts an item read before it is unmarshaled, undoing Encode
+	Decode func(item Item) error
+	// Parent of the contexts of cancellable calls; context.Background() if nil
+	Context context.Context
+}
+
+// ItemKey returns the DynamoDB key of the entity with the given key.
+func (s *Store[T]) ItemKey(key string) Item {
+	if s.KeyOf != nil {
+		return s.KeyOf(key)
+	}
+	return Item{s.KeyAttribute: {S: aws.String(key)}}
+}
+
+// Marshal converts an entity to the item storing it.
+//
+// Parameters:
+// - entity: The entity to store.
+//
+// Returns:
+// - The item, adjusted by Encode.
+// - An ErrMarshal error, or the error of Encode.
+func (s *Store[T]) Marshal(entity *T) (Item, error) {
+	item, err := dynamodbattribute.MarshalMap(entity)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMarshal, err)
+	}
+	if s.Encode != nil {
+		if err := s.Encode(item); err != nil {
+			return nil, err
+		}
+	}
+	return item, nil
+}
+
+// Unmarshal converts an item read from the table to its entity. The item is adjusted by
+// Decode in place.
+//
+// Returns:
+// - The entity.
+// - An ErrUnmarshal error, or the error of Decode.
+func (s *Store[T]) Unmarshal(item Item) (*T, error) {
+	if s.Decode != nil {
+		if err := s.Decode(item); err != nil {
+			return nil, err
+		}
+	}
+	entity := new(T)
+	if err := dynamodbattribute.UnmarshalMap(item, entity); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnmarshal, err)
+	}
+	return entity, nil
+}
+
+// UnmarshalAll converts items read from the table to their entities, like Unmarshal.
+func (s *Store[T]) UnmarshalAll(items []Item) ([]T, error) {
+	if s.Decode != nil {
+		for _, item := range items {
+			if err := s.Decode(item); err != nil {
+				return nil, err
+			}
+		}
+	}
+	entities := []T{}
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, &entities); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnmarshal, err)
+	}
+	return entities, nil
+}
+
+// Get reads the entity with the given key.
+//
+// Parameters:
+// - key: The key of the entity.
+// - opts: Options tuning the read.
+//
+// Returns:
+// - The entity.
+// - ErrNotFound if there is none, or an error if the read or unmarshaling fails.
+func (s *Store[T]) Get(key string, opts ReadOptions) (*T, error) {
+	result, err := s.Client.GetItem(&dynamodb.GetItemInput{
+		Key:                      s.ItemKey(key),
+		TableName:                aws.String(s.TableName),
+		ConsistentRead:           aws.Bool(opts.ConsistentRead),
+		ProjectionExpression:     opts.Projection,
+		ExpressionAttributeNames: opts.Names,
+		ReturnConsumedCapacity:   aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Item) == 0 {
+		return nil, ErrNotFound
+	}
+	return s.Unmarshal(result.Item)
+}
+
+// Put writes an entity, replacing the stored one, if the condition holds.
+//
+// Parameters:
+// - entity: The entity to write.
+// - condition: The condition of the write, or nil.
+//
+// Returns:
+//   - The error of Validate, a marshaling error, an ErrConditionFailed error, or the
+//     error of the write.
+func (s *Store[T]) Put(entity *T, condition *Condition) error {
+	if s.Validate != nil {
+		if err := s.Validate(entity); err != nil {
+			return err
+		}
+	}
+	item, err := s.Marshal(entity)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		Item:                   item,
+		TableName:              aws.String(s.TableName),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+	if condition != nil {
+		input.ConditionExpression = aws.String(condition.Expression)
+		input.ExpressionAttributeNames = condition.Names
+		input.ExpressionAttributeValues = condition.Values
+	}
+	_, err = s.Client.PutItem(input)
+	return conditionError(err)
+}
+
+// Delete removes the entity with the given key if the condition holds.
+//
+// Parameters:
+// - key: The key of the entity.
+// - condition: The condition of the delete, or nil.
+//
+// Returns:
+// - An ErrConditionFailed error, or the error of the delete.
+func (s *Store[T]) Delete(key string, condition *Condition) error {
+	input := &dynamodb.DeleteItemInput{
+		Key:                    s.ItemKey(key),
+		TableName:              aws.String(s.TableName),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+	if condition != nil {
+		input.ConditionExpression = aws.String(condition.Expression)
+		input.ExpressionAttributeNames = condition.Names
+		input.ExpressionAttributeValues = condition.Values
+	}
+	_, err := s.Client.DeleteItem(input)
+	return conditionError(err)
+}
+
+// Query reads every entity a query selects, paging until it is exhausted.
+//
+// Parameters:
+// - input: The query input; TableName is set if empty, and ExclusiveStartKey is advanced.
+//
+// Returns:
+// - The entities in key order.
+// - An error if the query or unmarshaling fails.
+func (s *Store[T]) Query(input *dynamodb.QueryInput) ([]T, error) {
+	if input.TableName == nil {
+		input.TableName = aws.String(s.TableName)
+	}
+	var items []Item
+	for {
+		result, err := s.Client.Query(input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Items...)
+		if len(result.LastEvaluatedKey) == 0 {
+			return s.UnmarshalAll(items)
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// Scan reads the entities a scan selects, like ScanItems.
+//
+// Returns:
+// - The entities.
+// - Whether items were left unread because of maxItems.
+// - An error if the scan or unmarshaling fails.
+func (s *Store[T]) Scan(input *dynamodb.ScanInput, segments int, maxItems int) ([]T, bool, error) {
+	items, truncated, err := s.ScanItems(input, segments, maxItems)
+	if err != nil {
+		return nil, false, err
+	}
+	entities, err := s.UnmarshalAll(items)
+	return entities, truncated, err
+}
+
+// ScanItems pages through a scan until the table is exhausted or maxItems items were
+// collected. With more than one segment, each segment is scanned by its own goroutine and
+// the first failing segment cancels the others.
+//
+// Parameters:
+// - input: The scan input; it is copied, not modified, and TableName is set if empty.
+// - segments: The number of segments (TotalSegments), or 1 for a sequential scan.
+// - maxItems: The maximum number of items to return.
+//
+// Returns:
+// - The items, grouped by segment in segment order, not yet decoded.
+// - Whether items were left unread because of maxItems.
+// - An error if any segment fails.
+func (s *Store[T]) ScanItems(input *dynamodb.ScanInput, segments int, maxItems int) ([]Item, bool, error) {
+	parent := s.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var (
+		wg        sync.WaitGroup
+		collected int64
+		truncated int32
+		errOnce   sync.Once
+		firstErr  error
+		results   = make([][]Item, segments)
+	)
+	for segment := 0; segment < segments; segment++ {
+		wg.Add(1)
+		go func(segment int) {
+			defer wg.Done()
+
+			segmentInput := *input
+			if segmentInput.TableName == nil {
+				segmentInput.TableName = aws.String(s.TableName)
+			}
+			if segments > 1 {
+				segmentInput.Segment = aws.Int64(int64(segment))
+				segmentInput.TotalSegments = aws.Int64(int64(segments))
+			}
+			for {
+				out, err := s.Client.ScanWithContext(ctx, &segmentInput)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				results[segment] = append(results[segment], out.Items...)
+				total := atomic.AddInt64(&collected, int64(len(out.Items)))
+				if len(out.LastEvaluatedKey) == 0 {
+					return
+				}
+				if total >= int64(maxItems) {
+					atomic.StoreInt32(&truncated, 1)
+					return
+				}
+				segmentInput.ExclusiveStartKey = out.LastEvaluatedKey
+			}
+		}(segment)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, false, firstErr
+	}
+
+	// Pages may overshoot the cap
+	items := make([]Item, 0, collected)
+	for _, segmentItems := range results {
+		items = append(items, segmentItems...)
+	}
+	if len(items) > maxItems {
+		return items[:maxItems], true, nil
+	}
+	return items, truncated == 1, nil
+}
+
+// conditionError wraps a conditional check failure in ErrConditionFailed.
+func conditionError(err error) error {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ConditionFailed(err)
+	}
+	return err
+}
+
+// conditionFailedError is an ErrConditionFailed error keeping the DynamoDB error behind it,
+// so errors.As still finds the AWS error
+type conditionFailedError struct {
+	err error
+}
+
+// Error describes the failed condition and the DynamoDB error.
+func (e *conditionFailedError) Error() string {
+	return ErrConditionFailed.Error() + ": " + e.err.Error()
+}
+
+// Is reports whether target is ErrConditionFailed.
+func (e *conditionFailedError) Is(target error) bool {
+	return target == ErrConditionFailed
+}
+
+// Unwrap returns the DynamoDB error.
+func (e *conditionFailedError) Unwrap() error {
+	return e.err
+}
+
+// ConditionFailed wraps the error of a write whose condition failed in ErrConditionFailed,
+// keeping err reachable with errors.As.
+func ConditionFailed(err error) error {
+	return &conditionFailedError{err: err}
+}
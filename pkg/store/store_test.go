@@ -0,0 +1,210 @@
+package store
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"strconv"
+	"testing"
+)
+
+// team is an example second entity, to show a Store needs nothing from pkg/user
+type team struct {
+	Slug    string `json:"slug"`
+	Name    string `json:"name"`
+	Members int    `json:"members"`
+}
+
+// errNoName is the validation error of a team without a name
+var errNoName = errors.New("name is required")
+
+// newTeamStore returns a Store of teams keyed by slug, backed by a FakeDynamo that keeps
+// the items it is sent in a map, like a table would.
+func newTeamStore() (*Store[team], *mocks.FakeDynamo, map[string]Item) {
+	table := map[string]Item{}
+	fake := mocks.NewFakeDynamo()
+	fake.OnPutItem(func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+		slug := aws.StringValue(in.Item["slug"].S)
+		if in.ConditionExpression != nil && table[slug] != nil {
+			return nil, mocks.ConditionalCheckFailedError()
+		}
+		table[slug] = in.Item
+		return &dynamodb.PutItemOutput{}, nil
+	})
+	fake.OnGetItem(func(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+		return &dynamodb.GetItemOutput{Item: table[aws.StringValue(in.Key["slug"].S)]}, nil
+	})
+	fake.OnDeleteItem(func(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+		delete(table, aws.StringValue(in.Key["slug"].S))
+		return &dynamodb.DeleteItemOutput{}, nil
+	})
+
+	s := &Store[team]{
+		Client:       fake,
+		TableName:    "teams",
+		KeyAttribute: "slug",
+		Key:          func(t *team) string { return t.Slug },
+		Validate: func(t *team) error {
+			if t.Name == "" {
+				return errNoName
+			}
+			return nil
+		},
+	}
+	return s, fake, table
+}
+
+func TestStorePut(t *testing.T) {
+	notExists := &Condition{Expression: "attribute_not_exists(slug)"}
+	tests := []struct {
+		name      string
+		existing  *team
+		put       team
+		condition *Condition
+		wantErr   error
+		wantName  string
+	}{
+		{name: "new", put: team{Slug: "core", Name: "Core", Members: 3}, wantName: "Core"},
+		{name: "replaces", existing: &team{Slug: "core", Name: "Old"}, put: team{Slug: "core", Name: "New"}, wantName: "New"},
+		{name: "condition holds", put: team{Slug: "core", Name: "Core"}, condition: notExists, wantName: "Core"},
+		{name: "condition fails", existing: &team{Slug: "core", Name: "Old"}, put: team{Slug: "core", Name: "New"},
+			condition: notExists, wantErr: ErrConditionFailed, wantName: "Old"},
+		{name: "invalid", put: team{Slug: "core"}, wantErr: errNoName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, fake, _ := newTeamStore()
+			if tt.existing != nil {
+				if err := s.Put(tt.existing, nil); err != nil {
+					t.Fatalf("seeding: %v", err)
+				}
+			}
+
+			err := s.Put(&tt.put, tt.condition)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Put() error = %v, want %v", err, tt.wantErr)
+			}
+			if input := fake.Inputs("PutItem"); len(input) > 0 {
+				if table := aws.StringValue(input[len(input)-1].(*dynamodb.PutItemInput).TableName); table != "teams" {
+					t.Errorf("PutItem TableName = %q, want %q", table, "teams")
+				}
+			}
+
+			got, err := s.Get("core", ReadOptions{})
+			if tt.wantName == "" {
+				if !errors.Is(err, ErrNotFound) {
+					t.Fatalf("Get() error = %v, want ErrNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got.Name != tt.wantName {
+				t.Errorf("Get().Name = %q, want %q", got.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestStoreGetRoundTrip(t *testing.T) {
+	s, _, _ := newTeamStore()
+	want := team{Slug: "platform", Name: "Platform", Members: 12}
+	if err := s.Put(&want, nil); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	got, err := s.Get("platform", ReadOptions{ConsistentRead: true})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if *got != want {
+		t.Errorf("Get() = %+v, want %+v", *got, want)
+	}
+
+	if err := s.Delete("platform", nil); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get("platform", ReadOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreKeyHooks(t *testing.T) {
+	s, _, _ := newTeamStore()
+	s.KeyOf = func(key string) Item {
+		return Item{"PK": {S: aws.String("TEAM#" + key)}, "SK": {S: aws.String("TEAM")}}
+	}
+	s.Encode = func(item Item) error {
+		for name, value := range s.KeyOf(aws.StringValue(item["slug"].S)) {
+			item[name] = value
+		}
+		return nil
+	}
+
+	item, err := s.Marshal(&team{Slug: "core", Name: "Core"})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if pk := aws.StringValue(item["PK"].S); pk != "TEAM#core" {
+		t.Errorf("PK = %q, want %q", pk, "TEAM#core")
+	}
+	if key := s.ItemKey("core"); aws.StringValue(key["SK"].S) != "TEAM" {
+		t.Errorf("ItemKey() = %v, want the composite key", key)
+	}
+}
+
+func TestStoreScan(t *testing.T) {
+	tests := []struct {
+		name          string
+		segments      int
+		maxItems      int
+		wantCount     int
+		wantTruncated bool
+	}{
+		{name: "sequential", segments: 1, maxItems: 100, wantCount: 6},
+		{name: "segmented", segments: 3, maxItems: 100, wantCount: 18},
+		{name: "capped", segments: 1, maxItems: 4, wantCount: 4, wantTruncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, fake, _ := newTeamStore()
+			// Every segment holds two pages of three teams
+			fake.OnScan(func(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+				segment := strconv.FormatInt(aws.Int64Value(in.Segment), 10)
+				page := "1"
+				if in.ExclusiveStartKey != nil {
+					page = "2"
+				}
+				out := &dynamodb.ScanOutput{}
+				for i := 0; i < 3; i++ {
+					item, _ := s.Marshal(&team{Slug: segment + "-" + page + "-" + strconv.Itoa(i), Name: "Team"})
+					out.Items = append(out.Items, item)
+				}
+				if page == "1" {
+					out.LastEvaluatedKey = Item{"slug": {S: aws.String("last")}}
+				}
+				return out, nil
+			})
+
+			teams, truncated, err := s.Scan(&dynamodb.ScanInput{}, tt.segments, tt.maxItems)
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if len(teams) != tt.wantCount || truncated != tt.wantTruncated {
+				t.Errorf("Scan() = %d teams, truncated %v; want %d, %v", len(teams), truncated, tt.wantCount, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestStoreScanError(t *testing.T) {
+	s, fake, _ := newTeamStore()
+	fake.OnScan(func(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+		return nil, mocks.ThrottlingError()
+	})
+	if _, _, err := s.Scan(&dynamodb.ScanInput{}, 4, 100); err == nil {
+		t.Fatal("Scan() error = nil, want the throttling error")
+	}
+}
@@ -0,0 +1,70 @@
+package stream
+
+import (
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// toAttributeValues converts a stream image into SDK attribute values, so it can be read
+// with dynamodbattribute like any item. Lambda delivers stream images with its own
+// AttributeValue type, which dynamodbattribute doesn't understand.
+//
+// Parameters:
+// - image: The NewImage or OldImage of a stream record.
+//
+// Returns:
+// - The image as SDK attribute values.
+// - An error if the image holds a value of unknown type.
+func toAttributeValues(image map[string]events.DynamoDBAttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	item := make(map[string]*dynamodb.AttributeValue, len(image))
+	for name, value := range image {
+		converted, err := toAttributeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		item[name] = converted
+	}
+	return item, nil
+}
+
+// toAttributeValue converts a single stream attribute value, recursing into lists and maps.
+func toAttributeValue(value events.DynamoDBAttributeValue) (*dynamodb.AttributeValue, error) {
+	switch value.DataType() {
+	case events.DataTypeString:
+		return &dynamodb.AttributeValue{S: aws.String(value.String())}, nil
+	case events.DataTypeNumber:
+		return &dynamodb.AttributeValue{N: aws.String(value.Number())}, nil
+	case events.DataTypeBinary:
+		return &dynamodb.AttributeValue{B: value.Binary()}, nil
+	case events.DataTypeBoolean:
+		return &dynamodb.AttributeValue{BOOL: aws.Bool(value.Boolean())}, nil
+	case events.DataTypeNull:
+		return &dynamodb.AttributeValue{NULL: aws.Bool(true)}, nil
+	case events.DataTypeStringSet:
+		return &dynamodb.AttributeValue{SS: aws.StringSlice(value.StringSet())}, nil
+	case events.DataTypeNumberSet:
+		return &dynamodb.AttributeValue{NS: aws.StringSlice(value.NumberSet())}, nil
+	case events.DataTypeBinarySet:
+		return &dynamodb.AttributeValue{BS: value.BinarySet()}, nil
+	case events.DataTypeList:
+		list := make([]*dynamodb.AttributeValue, 0, len(value.List()))
+		for _, element := range value.List() {
+			converted, err := toAttributeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, converted)
+		}
+		return &dynamodb.AttributeValue{L: list}, nil
+	case events.DataTypeMap:
+		m, err := toAttributeValues(value.Map())
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{M: m}, nil
+	default:
+		return nil, fmt.Errorf("unsupported data type %d", value.DataType())
+	}
+}
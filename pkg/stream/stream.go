@@ -0,0 +1,209 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"os"
+	"time"
+)
+
+// Operations of a change, as named by DynamoDB Streams
+const (
+	OperationInsert = "INSERT"
+	OperationModify = "MODIFY"
+	OperationRemove = "REMOVE"
+)
+
+// ErrNotUser is returned by NewChange for records of other entities sharing the table in
+// single-table mode, which the handler skips
+var ErrNotUser = errors.New("record is not a user")
+
+// Change is a normalized change to a user, read from a stream record
+type Change struct {
+	Operation      string     `json:"operation"`     // OperationInsert, OperationModify or OperationRemove
+	Email          string     `json:"email"`         // Email of the changed user
+	Old            *user.User `json:"old,omitempty"` // The user before the change, absent for inserts
+	New            *user.User `json:"new,omitempty"` // The user after the change, absent for removes
+	SequenceNumber string     `json:"sequenceNumber"`
+	Time           string     `json:"time"` // RFC3339 time DynamoDB recorded the change
+}
+
+// Sink receives the changes read from the stream
+type Sink interface {
+	// Send delivers one change. An error makes the record, and the ones after it, retried.
+	Send(change Change) error
+}
+
+// SinkFunc adapts a function to the Sink interface
+type SinkFunc func(change Change) error
+
+// Send calls f(change).
+func (f SinkFunc) Send(change Change) error {
+	return f(change)
+}
+
+// LogSink writes every change to the application log
+type LogSink struct{}
+
+// Send logs the change, with the email hashed unless LOG_PII is set.
+func (LogSink) Send(change Change) error {
+	logging.Default.Info("user changed", logging.Fields{
+		"operation":      change.Operation,
+		"email":          logging.Email(change.Email),
+		"sequenceNumber": change.SequenceNumber,
+	})
+	return nil
+}
+
+// SNSSink publishes every change to an SNS topic
+type SNSSink struct {
+	Client   snsiface.SNSAPI // The SNS client interface
+	TopicArn string          // The ARN of the topic
+}
+
+// Send publishes the change as the message, with its operation as the "operation"
+// message attribute so subscriptions can filter on it.
+func (s *SNSSink) Send(change Change) error {
+	message, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	_, err = s.Client.Publish(&sns.PublishInput{
+		TopicArn: aws.String(s.TopicArn),
+		Message:  aws.String(string(message)),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"operation": {DataType: aws.String("String"), StringValue: aws.String(change.Operation)},
+		},
+	})
+	return err
+}
+
+// NewSink creates the sink configured by the environment: the SNS topic given by
+// STREAM_TOPIC_ARN, or the application log when it is unset.
+//
+// Parameters:
+// - sess: The AWS session the SNS client is created from.
+//
+// Returns:
+// - The sink.
+func NewSink(sess *session.Session) Sink {
+	if topic := os.Getenv("STREAM_TOPIC_ARN"); topic != "" {
+		return &SNSSink{Client: sns.New(sess), TopicArn: topic}
+	}
+	return LogSink{}
+}
+
+// Handler consumes the users table's stream
+type Handler struct {
+	Sink Sink // Where the changes are sent
+}
+
+// Handle forwards the changes of a batch of stream records to the sink, in order.
+//
+// Records of a shard must be handled in order, and Lambda retries a stream batch from
+// the lowest sequence number reported as failed. Handling therefore stops at the first
+// record that can't be read or sent, and only that record is reported, so the records
+// before it aren't replayed.
+//
+// Parameters:
+// - ctx: The invocation context.
+// - event: The batch of stream records.
+//
+// Returns:
+// - The response naming the failed record, if any.
+// - Always a nil error, as failures are reported per record.
+func (h *Handler) Handle(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	response := events.DynamoDBEventResponse{BatchItemFailures: []events.DynamoDBBatchItemFailure{}}
+	for _, record := range event.Records {
+		change, err := NewChange(record)
+		if errors.Is(err, ErrNotUser) {
+			continue
+		}
+		if err == nil {
+			err = h.Sink.Send(change)
+		}
+		if err != nil {
+			logging.Default.Error("failed to handle stream record", logging.Fields{
+				"eventId":        record.EventID,
+				"sequenceNumber": record.Change.SequenceNumber,
+				"error":          err,
+			})
+			response.BatchItemFailures = append(response.BatchItemFailures,
+				events.DynamoDBBatchItemFailure{ItemIdentifier: record.Change.SequenceNumber})
+			break
+		}
+	}
+	return response, nil
+}
+
+// NewChange reads the change a stream record describes.
+//
+// Parameters:
+// - record: The stream record.
+//
+// Returns:
+// - The change.
+// - An error if the operation is unknown or an image can't be read as a user, or ErrNotUser.
+func NewChange(record events.DynamoDBEventRecord) (Change, error) {
+	change := Change{
+		Operation:      record.EventName,
+		SequenceNumber: record.Change.SequenceNumber,
+		Time:           record.Change.ApproximateCreationDateTime.UTC().Format(time.RFC3339),
+	}
+	switch record.EventName {
+	case OperationInsert, OperationModify, OperationRemove:
+	default:
+		return change, fmt.Errorf("unknown operation %q", record.EventName)
+	}
+
+	// Other entities sharing a single table are skipped
+	keys, err := toAttributeValues(record.Change.Keys)
+	if err != nil {
+		return change, fmt.Errorf("keys: %w", err)
+	}
+	if !user.IsUserKey(keys) {
+		return change, ErrNotUser
+	}
+
+	if change.Old, err = imageUser(record.Change.OldImage); err != nil {
+		return change, fmt.Errorf("old image: %w", err)
+	}
+	if change.New, err = imageUser(record.Change.NewImage); err != nil {
+		return change, fmt.Errorf("new image: %w", err)
+	}
+
+	// Without images (a KEYS_ONLY stream) the email is still in the key
+	if change.Email = user.KeyEmail(keys); change.Email == "" {
+		return change, fmt.Errorf("record has no email key")
+	}
+	return change, nil
+}
+
+// imageUser reads a stream image as a user.
+//
+// Returns:
+// - The user, or nil if the image is empty.
+// - An error if the image can't be converted.
+func imageUser(image map[string]events.DynamoDBAttributeValue) (*user.User, error) {
+	if len(image) == 0 {
+		return nil, nil
+	}
+	item, err := toAttributeValues(image)
+	if err != nil {
+		return nil, err
+	}
+	u := new(user.User)
+	if err := user.UnmarshalItem(item, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
@@ -0,0 +1,154 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/user"
+	"github.com/aws/aws-lambda-go/events"
+	"testing"
+	"time"
+)
+
+// record builds a stream record for the user with the given email and last names before
+// and after the change; an empty name leaves that image out.
+func record(operation string, sequence string, email string, oldName string, newName string) events.DynamoDBEventRecord {
+	image := func(lastName string) map[string]events.DynamoDBAttributeValue {
+		if lastName == "" {
+			return nil
+		}
+		return map[string]events.DynamoDBAttributeValue{
+			"email":     events.NewStringAttribute(email),
+			"firstname": events.NewStringAttribute("Ada"),
+			"lastname":  events.NewStringAttribute(lastName),
+			"version":   events.NewNumberAttribute("2"),
+			"verified":  events.NewBooleanAttribute(true),
+			"tags": events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{
+				"team": events.NewStringAttribute("core"),
+			}),
+		}
+	}
+	return events.DynamoDBEventRecord{
+		EventID:   "event-" + sequence,
+		EventName: operation,
+		Change: events.DynamoDBStreamRecord{
+			ApproximateCreationDateTime: events.SecondsEpochTime{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+			Keys:                        map[string]events.DynamoDBAttributeValue{"email": events.NewStringAttribute(email)},
+			OldImage:                    image(oldName),
+			NewImage:                    image(newName),
+			SequenceNumber:              sequence,
+		},
+	}
+}
+
+func TestNewChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		record  events.DynamoDBEventRecord
+		wantOld string
+		wantNew string
+		wantErr bool
+	}{
+		{name: "insert", record: record(OperationInsert, "1", "ada@example.com", "", "Lovelace"), wantNew: "Lovelace"},
+		{name: "modify", record: record(OperationModify, "2", "ada@example.com", "Lovelace", "King"),
+			wantOld: "Lovelace", wantNew: "King"},
+		{name: "remove", record: record(OperationRemove, "3", "ada@example.com", "King", ""), wantOld: "King"},
+		{name: "keys only", record: record(OperationRemove, "4", "ada@example.com", "", "")},
+		{name: "unknown operation", record: record("TRUNCATE", "5", "ada@example.com", "", ""), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, err := NewChange(tt.record)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewChange() = %+v, want an error", change)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewChange() error = %v", err)
+			}
+			if change.Operation != tt.record.EventName || change.Email != "ada@example.com" ||
+				change.SequenceNumber != tt.record.Change.SequenceNumber || change.Time != "2024-05-01T12:00:00Z" {
+				t.Errorf("NewChange() = %+v, want the record's operation, email, sequence number and time", change)
+			}
+			for _, image := range []struct {
+				side string
+				got  string
+				want string
+			}{{"old", lastName(change.Old), tt.wantOld}, {"new", lastName(change.New), tt.wantNew}} {
+				if image.got != image.want {
+					t.Errorf("%s lastname = %q, want %q", image.side, image.got, image.want)
+				}
+			}
+			if change.New != nil && (change.New.Version != 2 || !change.New.Verified || change.New.Tags["team"] != "core") {
+				t.Errorf("new image = %+v, want every attribute converted", change.New)
+			}
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	insert := record(OperationInsert, "1", "ada@example.com", "", "Lovelace")
+	modify := record(OperationModify, "2", "ada@example.com", "Lovelace", "King")
+	remove := record(OperationRemove, "3", "ada@example.com", "King", "")
+	malformed := record(OperationModify, "2", "ada@example.com", "Lovelace", "King")
+	malformed.Change.NewImage["version"] = events.NewStringAttribute("two")
+	tests := []struct {
+		name        string
+		records     []events.DynamoDBEventRecord
+		failSend    string // Sequence number the sink fails to send
+		wantSent    []string
+		wantFailure string
+	}{
+		{name: "all sent in order", records: []events.DynamoDBEventRecord{insert, modify, remove},
+			wantSent: []string{OperationInsert, OperationModify, OperationRemove}},
+		{name: "malformed record", records: []events.DynamoDBEventRecord{insert, malformed, remove},
+			wantSent: []string{OperationInsert}, wantFailure: "2"},
+		{name: "sink failure", records: []events.DynamoDBEventRecord{insert, modify, remove}, failSend: "2",
+			wantSent: []string{OperationInsert}, wantFailure: "2"},
+		{name: "empty batch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+			h := &Handler{Sink: SinkFunc(func(change Change) error {
+				if change.SequenceNumber == tt.failSend {
+					return errors.New("sink unavailable")
+				}
+				sent = append(sent, change.Operation)
+				return nil
+			})}
+
+			response, err := h.Handle(context.Background(), events.DynamoDBEvent{Records: tt.records})
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if len(sent) != len(tt.wantSent) {
+				t.Fatalf("sent %v, want %v", sent, tt.wantSent)
+			}
+			for i := range sent {
+				if sent[i] != tt.wantSent[i] {
+					t.Errorf("sent %v, want %v", sent, tt.wantSent)
+				}
+			}
+			failures := response.BatchItemFailures
+			if tt.wantFailure == "" {
+				if len(failures) != 0 {
+					t.Errorf("BatchItemFailures = %v, want none", failures)
+				}
+				return
+			}
+			if len(failures) != 1 || failures[0].ItemIdentifier != tt.wantFailure {
+				t.Errorf("BatchItemFailures = %v, want only %s", failures, tt.wantFailure)
+			}
+		})
+	}
+}
+
+// lastName returns the last name of an image, or an empty string if there is none.
+func lastName(image *user.User) string {
+	if image == nil {
+		return ""
+	}
+	return image.LastName
+}
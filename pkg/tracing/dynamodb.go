@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// contextClient binds a context to the DynamoDB calls made without one
+type contextClient struct {
+	dynamodbiface.DynamoDBAPI
+	ctx context.Context
+}
+
+// WithContext wraps a DynamoDB client so calls made through the context-less methods
+// (GetItem, PutItem, ...) run with ctx. This carries the invocation's trace segment to
+// the instrumented client without threading a context through every function.
+//
+// Parameters:
+// - ctx: The context to bind, usually the one returned by Begin.
+// - client: The DynamoDB client to wrap.
+//
+// Returns:
+// - The wrapping client.
+func WithContext(ctx context.Context, client dynamodbiface.DynamoDBAPI) dynamodbiface.DynamoDBAPI {
+	return &contextClient{DynamoDBAPI: client, ctx: ctx}
+}
+
+// GetItem runs dynamodb.GetItem with the bound context.
+func (c *contextClient) GetItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return c.DynamoDBAPI.GetItemWithContext(c.ctx, in)
+}
+
+// PutItem runs dynamodb.PutItem with the bound context.
+func (c *contextClient) PutItem(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return c.DynamoDBAPI.PutItemWithContext(c.ctx, in)
+}
+
+// UpdateItem runs dynamodb.UpdateItem with the bound context.
+func (c *contextClient) UpdateItem(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	return c.DynamoDBAPI.UpdateItemWithContext(c.ctx, in)
+}
+
+// DeleteItem runs dynamodb.DeleteItem with the bound context.
+func (c *contextClient) DeleteItem(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	return c.DynamoDBAPI.DeleteItemWithContext(c.ctx, in)
+}
+
+// Scan runs dynamodb.Scan with the bound context.
+func (c *contextClient) Scan(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	return c.DynamoDBAPI.ScanWithContext(c.ctx, in)
+}
+
+// Query runs dynamodb.Query with the bound context.
+func (c *contextClient) Query(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	return c.DynamoDBAPI.QueryWithContext(c.ctx, in)
+}
+
+// BatchGetItem runs dynamodb.BatchGetItem with the bound context.
+func (c *contextClient) BatchGetItem(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	return c.DynamoDBAPI.BatchGetItemWithContext(c.ctx, in)
+}
+
+// BatchWriteItem runs dynamodb.BatchWriteItem with the bound context.
+func (c *contextClient) BatchWriteItem(in *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	return c.DynamoDBAPI.BatchWriteItemWithContext(c.ctx, in)
+}
+
+// TransactWriteItems runs dynamodb.TransactWriteItems with the bound context.
+func (c *contextClient) TransactWriteItems(in *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return c.DynamoDBAPI.TransactWriteItemsWithContext(c.ctx, in)
+}
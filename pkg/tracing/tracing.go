@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-xray-sdk-go/strategy/ctxmissing"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"os"
+)
+
+// Enabled reports whether X-Ray tracing is turned on via ENABLE_XRAY=true.
+func Enabled() bool {
+	return os.Getenv("ENABLE_XRAY") == "true"
+}
+
+// Configure prepares the X-Ray SDK and instruments an AWS service client so each of
+// its calls is recorded as a subsegment. It does nothing unless tracing is enabled.
+// Missing trace contexts are logged instead of panicking, so the same binary works
+// outside Lambda (local runs and tests).
+//
+// Parameters:
+// - c: The AWS service client to instrument (e.g. the DynamoDB client's Client).
+func Configure(c *client.Client) {
+	if !Enabled() {
+		return
+	}
+	xray.Configure(xray.Config{ContextMissingStrategy: ctxmissing.NewDefaultLogErrorStrategy()})
+	xray.AWS(c)
+}
+
+// hasTraceContext reports whether ctx carries a segment or a Lambda trace header
+// that a subsegment can be attached to.
+func hasTraceContext(ctx context.Context) bool {
+	return xray.GetSegment(ctx) != nil || ctx.Value(xray.LambdaTraceHeaderKey) != nil
+}
+
+// Segment is a started subsegment, or a no-op when tracing is disabled or unavailable
+type Segment struct {
+	seg *xray.Segment
+}
+
+// Begin starts a subsegment named name under the trace in ctx.
+//
+// Parameters:
+// - ctx: The invocation context.
+// - name: The subsegment name.
+//
+// Returns:
+// - The context carrying the subsegment, to pass to downstream calls.
+// - The subsegment, whose methods are safe to call even when nothing was started.
+func Begin(ctx context.Context, name string) (context.Context, *Segment) {
+	if !Enabled() || !hasTraceContext(ctx) {
+		return ctx, &Segment{}
+	}
+	ctx, seg := xray.BeginSubsegment(ctx, name)
+	return ctx, &Segment{seg: seg}
+}
+
+// Annotate adds an indexed annotation to the subsegment.
+func (s *Segment) Annotate(key string, value interface{}) {
+	if s.seg != nil {
+		s.seg.AddAnnotation(key, value)
+	}
+}
+
+// Close ends the subsegment, recording err if it is not nil.
+func (s *Segment) Close(err error) {
+	if s.seg != nil {
+		s.seg.Close(err)
+	}
+}
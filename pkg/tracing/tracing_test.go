@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"testing"
+)
+
+// TestBeginOutsideLambda checks tracing never panics without a Lambda trace context, as in
+// local runs and tests, whether or not it is enabled.
+func TestBeginOutsideLambda(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled string
+	}{
+		{name: "disabled"},
+		{name: "enabled", enabled: "true"},
+		{name: "not true", enabled: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENABLE_XRAY", tt.enabled)
+			ctx, seg := Begin(context.Background(), "handler")
+			if ctx == nil || seg == nil {
+				t.Fatalf("Begin() = %v, %v; want a context and a segment", ctx, seg)
+			}
+			if seg.seg != nil {
+				t.Error("Begin() started a subsegment without a trace context")
+			}
+			seg.Annotate("status", 200)
+			seg.Close(errors.New("failed"))
+		})
+	}
+}
+
+func TestWithContextPassesCallsThrough(t *testing.T) {
+	t.Setenv("ENABLE_XRAY", "true")
+	fake := mocks.NewFakeDynamo()
+	fake.OnGetItem(func(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+		return &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{"email": {S: in.Key["email"].S}}}, nil
+	})
+	fake.OnPutItem(func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+		return nil, mocks.ThrottlingError()
+	})
+	client := WithContext(context.Background(), fake)
+
+	out, err := client.GetItem(&dynamodb.GetItemInput{TableName: aws.String("users"),
+		Key: map[string]*dynamodb.AttributeValue{"email": {S: aws.String("ada@example.com")}}})
+	if err != nil || aws.StringValue(out.Item["email"].S) != "ada@example.com" {
+		t.Errorf("GetItem() = %v, %v; want the fake's item", out, err)
+	}
+	if _, err := client.PutItem(&dynamodb.PutItemInput{TableName: aws.String("users")}); err == nil {
+		t.Error("PutItem() error = nil, want the fake's error")
+	}
+	if calls := fake.Calls(); len(calls) != 2 {
+		t.Errorf("calls = %v, want GetItem and PutItem", calls)
+	}
+}
@@ -0,0 +1,80 @@
+package user
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxAddressFieldLength is the maximum number of characters of each address field
+const maxAddressFieldLength = 200
+
+// Error messages for user addresses
+var (
+	ErrorInvalidAddressField = "address fields must be at most 200 characters without control characters"
+	ErrorInvalidCountry      = "address.country must be an ISO 3166-1 alpha-2 code such as \"US\""
+	ErrorMissingPostalCode   = "address.postalCode is required when address.country is set"
+)
+
+// Address is a user's mailing address, stored as a DynamoDB map attribute
+type Address struct {
+	Line1      string `json:"line1,omitempty" xml:"line1,omitempty"`           // Street address
+	Line2      string `json:"line2,omitempty" xml:"line2,omitempty"`           // Apartment, suite or unit
+	City       string `json:"city,omitempty" xml:"city,omitempty"`             // City or locality
+	State      string `json:"state,omitempty" xml:"state,omitempty"`           // State, province or region
+	PostalCode string `json:"postalCode,omitempty" xml:"postalCode,omitempty"` // Postal or ZIP code
+	Country    string `json:"country,omitempty" xml:"country,omitempty"`       // ISO 3166-1 alpha-2 country code
+}
+
+// Validate checks the fields of the address. Every field is optional, but a country must
+// be a valid code and comes with a postal code.
+//
+// Returns:
+//   - A validation error naming the invalid field (e.g. "address.country"), a validation
+//     error wrapping the ValidationErrors of every invalid field if there are several, or nil.
+func (a *Address) Validate() error {
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"line1", a.Line1}, {"line2", a.Line2}, {"city", a.City},
+		{"state", a.State}, {"postalCode", a.PostalCode}, {"country", a.Country},
+	}
+	var invalid ValidationErrors
+	for _, field := range fields {
+		if !isAddressFieldValid(field.value) {
+			invalid.add(newFieldError(ErrValidation, ErrorInvalidAddressField, "address."+field.name, nil))
+		}
+	}
+
+	switch {
+	case a.Country == "":
+	case !validators.IsCountryCodeValid(a.Country):
+		invalid.add(newFieldError(ErrValidation, ErrorInvalidCountry, "address.country", nil))
+	case strings.TrimSpace(a.PostalCode) == "":
+		invalid.add(newFieldError(ErrValidation, ErrorMissingPostalCode, "address.postalCode", nil))
+	}
+	return invalid.err()
+}
+
+// sanitize trims, whitespace-collapses and NFC-normalizes every field of the address.
+func (a *Address) sanitize() {
+	for _, field := range []*string{&a.Line1, &a.Line2, &a.City, &a.State, &a.PostalCode, &a.Country} {
+		*field = validators.SanitizeString(*field)
+	}
+}
+
+// isAddressFieldValid reports whether an address field is valid UTF-8 of at most
+// maxAddressFieldLength characters without control characters. Empty fields are valid.
+func isAddressFieldValid(value string) bool {
+	if !utf8.ValidString(value) || utf8.RuneCountInString(value) > maxAddressFieldLength {
+		return false
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+package user
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAddressValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		address     Address
+		wantField   string
+		wantMessage string
+	}{
+		{name: "empty"},
+		{name: "full", address: Address{Line1: "12 St James's Square", Line2: "Flat 3", City: "London",
+			State: "Greater London", PostalCode: "SW1Y 4JH", Country: "GB"}},
+		{name: "city only", address: Address{City: "London"}},
+		{name: "unknown country", address: Address{PostalCode: "12345", Country: "XX"}, wantField: "address.country",
+			wantMessage: ErrorInvalidCountry},
+		{name: "lower-case country", address: Address{PostalCode: "12345", Country: "us"}, wantField: "address.country",
+			wantMessage: ErrorInvalidCountry},
+		{name: "country without postal code", address: Address{Country: "US"}, wantField: "address.postalCode",
+			wantMessage: ErrorMissingPostalCode},
+		{name: "blank postal code", address: Address{PostalCode: "  ", Country: "US"}, wantField: "address.postalCode",
+			wantMessage: ErrorMissingPostalCode},
+		{name: "field too long", address: Address{City: strings.Repeat("a", maxAddressFieldLength+1)},
+			wantField: "address.city", wantMessage: ErrorInvalidAddressField},
+		{name: "control character", address: Address{Line1: "12 St James's\nSquare"}, wantField: "address.line1",
+			wantMessage: ErrorInvalidAddressField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.address.Validate()
+			if tt.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			var userErr *Error
+			if !errors.As(err, &userErr) || !errors.Is(err, ErrValidation) || userErr.Field != tt.wantField ||
+				userErr.Message != tt.wantMessage {
+				t.Errorf("Validate() error = %v, want %q on %s", err, tt.wantMessage, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestAddressRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  *Address
+		wantKeys []string // Keys of the stored map, or nil for no address attribute
+	}{
+		{name: "full", address: &Address{Line1: "12 St James's Square", Line2: "Flat 3", City: "London",
+			State: "Greater London", PostalCode: "SW1Y 4JH", Country: "GB"},
+			wantKeys: []string{"city", "country", "line1", "line2", "postalCode", "state"}},
+		{name: "partial", address: &Address{City: "London", PostalCode: "SW1Y 4JH", Country: "GB"},
+			wantKeys: []string{"city", "country", "postalCode"}},
+		{name: "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := storedAda()
+			stored.Address = tt.address
+			repo, table := newFakeTable(t, stored)
+
+			attribute, ok := table.items[stored.Email]["address"]
+			if tt.wantKeys == nil {
+				if ok {
+					t.Errorf("address attribute = %v, want none", attribute)
+				}
+			} else {
+				var keys []string
+				for key, value := range attribute.M {
+					if value.S == nil {
+						t.Errorf("address.%s = %v, want a string", key, value)
+					}
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				if !reflect.DeepEqual(keys, tt.wantKeys) {
+					t.Errorf("address map keys = %v, want %v", keys, tt.wantKeys)
+				}
+			}
+
+			got, err := repo.Get(stored.Email, ReadOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Address, tt.address) {
+				t.Errorf("Address = %+v, want %+v", got.Address, tt.address)
+			}
+		})
+	}
+}
+
+func TestPatchAddressOnDynamo(t *testing.T) {
+	tests := []struct {
+		name           string
+		stored         *Address
+		patch          string
+		wantExpression string
+		wantNames      map[string]string // Names of the patched placeholders
+		wantValues     map[string]string // String values written, if any
+	}{
+		{name: "one field", stored: &Address{Line1: "12 St James's Square", Line2: "Flat 3", City: "London"},
+			patch:          `{"address": {"city": "Marylebone"}}`,
+			wantExpression: "SET #updatedAt = :now, #version = :next, #address.#p0 = :p0",
+			wantNames:      map[string]string{"#address": "address", "#p0": "city"},
+			wantValues:     map[string]string{":p0": "Marylebone"}},
+		{name: "clears a field", stored: &Address{Line1: "12 St James's Square", Line2: "Flat 3", City: "London"},
+			patch:          `{"address": {"line2": null}}`,
+			wantExpression: "SET #updatedAt = :now, #version = :next REMOVE #address.#p0",
+			wantNames:      map[string]string{"#address": "address", "#p0": "line2"}},
+		{name: "adds a country", stored: &Address{Line1: "12 St James's Square", Line2: "Flat 3", City: "London"},
+			patch:          `{"address": {"postalCode": "SW1Y 4JH", "country": "GB"}}`,
+			wantExpression: "SET #updatedAt = :now, #version = :next, #address.#p0 = :p0, #address.#p1 = :p1",
+			wantNames:      map[string]string{"#address": "address", "#p0": "country", "#p1": "postalCode"},
+			wantValues:     map[string]string{":p0": "GB", ":p1": "SW1Y 4JH"}},
+		{name: "sets and clears fields", stored: &Address{Line1: "12 St James's Square", Line2: "Flat 3", City: "London"},
+			patch:          `{"address": {"line1": "1 Piccadilly", "line2": null}}`,
+			wantExpression: "SET #updatedAt = :now, #version = :next, #address.#p0 = :p0 REMOVE #address.#p1",
+			wantNames:      map[string]string{"#address": "address", "#p0": "line1", "#p1": "line2"},
+			wantValues:     map[string]string{":p0": "1 Piccadilly"}},
+		{name: "first address", patch: `{"address": {"city": "London"}}`,
+			wantExpression: "SET #updatedAt = :now, #version = :next, #p0 = :p0",
+			wantNames:      map[string]string{"#p0": "address"}},
+		{name: "last field cleared", stored: &Address{City: "London"}, patch: `{"address": {"city": null}}`,
+			wantExpression: "SET #updatedAt = :now, #version = :next, #p0 = :p0",
+			wantNames:      map[string]string{"#p0": "address"}},
+		{name: "clears the address", stored: &Address{City: "London"}, patch: `{"address": null}`,
+			wantExpression: "SET #updatedAt = :now, #version = :next REMOVE #p0",
+			wantNames:      map[string]string{"#p0": "address"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := storedAda()
+			stored.Address = tt.stored
+			repo, table := newFakeTable(t, stored)
+			table.fake.OnUpdateItem(func(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+			if _, _, err := PatchUser(stored.Email, tt.patch, 0, UpdateOptions{}, repo); err != nil {
+				t.Fatalf("PatchUser() error = %v", err)
+			}
+			inputs := table.fake.Inputs("UpdateItem")
+			if len(inputs) != 1 {
+				t.Fatalf("UpdateItem calls = %d, want 1", len(inputs))
+			}
+			in := inputs[0].(*dynamodb.UpdateItemInput)
+			if got := aws.StringValue(in.UpdateExpression); got != tt.wantExpression {
+				t.Errorf("UpdateExpression = %q, want %q", got, tt.wantExpression)
+			}
+			for placeholder, name := range tt.wantNames {
+				if got := aws.StringValue(in.ExpressionAttributeNames[placeholder]); got != name {
+					t.Errorf("name %s = %q, want %q", placeholder, got, name)
+				}
+			}
+			for placeholder, value := range tt.wantValues {
+				if got := in.ExpressionAttributeValues[placeholder]; got == nil || aws.StringValue(got.S) != value {
+					t.Errorf("value %s = %v, want %q", placeholder, got, value)
+				}
+			}
+		})
+	}
+}
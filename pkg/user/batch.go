@@ -0,0 +1,149 @@
+package user
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"time"
+)
+
+// Error messages for batch operations
+var (
+	ErrorCouldNotBatchWrite = "couldn't write the batch to dynamodb"
+	ErrorBatchUnprocessed   = "dynamodb left the item unprocessed after retries"
+)
+
+// DynamoDB limits on the number of items per batch request
+const (
+	batchWriteSize = 25
+	batchGetSize   = 100
+)
+
+// Retry bounds for items DynamoDB leaves unprocessed (e.g. under throttling)
+const (
+	batchMaxAttempts  = 5
+	batchRetryBackoff = 50 * time.Millisecond
+)
+
+// backoff waits before the given retry attempt, doubling the delay each time.
+func backoff(attempt int) {
+	time.Sleep(batchRetryBackoff << uint(attempt))
+}
+
+// GetMany retrieves the users stored under the given emails with BatchGetItem, in chunks
+// of 100 keys, retrying unprocessed keys with backoff. Emails without a user are skipped.
+func (r *DynamoRepository) GetMany(emails []string, opts ReadOptions) ([]User, error) {
+	var users []User
+	for start := 0; start < len(emails); start += batchGetSize {
+		end := start + batchGetSize
+		if end > len(emails) {
+			end = len(emails)
+		}
+
+		keys := &dynamodb.KeysAndAttributes{ConsistentRead: aws.Bool(opts.ConsistentRead)}
+		for _, email := range emails[start:end] {
+			keys.Keys = append(keys.Keys, emailKey(email))
+		}
+		if len(opts.Fields) > 0 {
+			keys.ProjectionExpression, keys.ExpressionAttributeNames = projection(opts.Fields)
+		}
+
+		requestItems := map[string]*dynamodb.KeysAndAttributes{r.TableName: keys}
+		for attempt := 0; len(requestItems) > 0; attempt++ {
+			if attempt == batchMaxAttempts {
+				return nil, newError(ErrStorage, ErrorBatchUnprocessed, nil)
+			}
+			if attempt > 0 {
+				backoff(attempt)
+			}
+
+			result, err := r.DynaClient.BatchGetItem(&dynamodb.BatchGetItemInput{
+				RequestItems:           requestItems,
+				ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+			})
+			if err != nil {
+				return nil, newError(ErrStorage, ErrorFailedToFetchRecord, err)
+			}
+
+			var chunk []User
+			if err := unmarshalUsers(result.Responses[r.TableName], &chunk); err != nil {
+				return nil, err
+			}
+			users = append(users, chunk...)
+			requestItems = result.UnprocessedKeys
+		}
+	}
+	return users, nil
+}
+
+// PutMany writes the users with BatchWriteItem, in chunks of 25, retrying unprocessed
+// items with backoff. Existing users are overwritten.
+func (r *DynamoRepository) PutMany(users []User) map[string]error {
+	requests := make([]*dynamodb.WriteRequest, 0, len(users))
+	failed := map[string]error{}
+	for i := range users {
+		item, err := marshalUser(&users[i])
+		if err != nil {
+			failed[users[i].Email] = err
+			continue
+		}
+		requests = append(requests, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+	}
+	r.batchWrite(requests, failed)
+	return failed
+}
+
+// DeleteMany deletes the users stored under the given emails with BatchWriteItem, in chunks
+// of 25, retrying unprocessed items with backoff. Missing users are not an error.
+func (r *DynamoRepository) DeleteMany(emails []string) map[string]error {
+	requests := make([]*dynamodb.WriteRequest, len(emails))
+	for i, email := range emails {
+		requests[i] = &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: emailKey(email)}}
+	}
+	failed := map[string]error{}
+	r.batchWrite(requests, failed)
+	return failed
+}
+
+// batchWrite sends write requests in chunks, recording the email of every request that
+// couldn't be written in failed.
+func (r *DynamoRepository) batchWrite(requests []*dynamodb.WriteRequest, failed map[string]error) {
+	for start := 0; start < len(requests); start += batchWriteSize {
+		end := start + batchWriteSize
+		if end > len(requests) {
+			end = len(requests)
+		}
+
+		pending := requests[start:end]
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt == batchMaxAttempts {
+				for _, request := range pending {
+					failed[writeRequestEmail(request)] = newError(ErrStorage, ErrorBatchUnprocessed, nil)
+				}
+				break
+			}
+			if attempt > 0 {
+				backoff(attempt)
+			}
+
+			result, err := r.DynaClient.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+				RequestItems:           map[string][]*dynamodb.WriteRequest{r.TableName: pending},
+				ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+			})
+			if err != nil {
+				for _, request := range pending {
+					failed[writeRequestEmail(request)] = newError(ErrStorage, ErrorCouldNotBatchWrite, err)
+				}
+				break
+			}
+			pending = result.UnprocessedItems[r.TableName]
+		}
+	}
+}
+
+// writeRequestEmail returns the email a put or delete request targets.
+func writeRequestEmail(request *dynamodb.WriteRequest) string {
+	if request.PutRequest != nil {
+		return KeyEmail(request.PutRequest.Item)
+	}
+	return KeyEmail(request.DeleteRequest.Key)
+}
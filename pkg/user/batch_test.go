@@ -0,0 +1,93 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+func TestFetchUsersByEmail(t *testing.T) {
+	many := make([]string, 150)
+	for i := range many {
+		many[i] = fmt.Sprintf("user%03d@example.com", i)
+	}
+	tests := []struct {
+		name        string
+		stored      []string
+		emails      []string
+		unprocessed int // Batches answered with every key unprocessed before the keys are served
+		wantUsers   []string
+		wantMissing []string
+		wantCalls   int
+		wantErr     error
+	}{
+		{name: "all found", stored: []string{"ada@example.com", "alan@example.com"},
+			emails: []string{"alan@example.com", "ada@example.com"}, wantUsers: []string{"alan@example.com", "ada@example.com"},
+			wantMissing: []string{}, wantCalls: 1},
+		{name: "partial misses", stored: []string{"ada@example.com", "grace@example.com"},
+			emails:      []string{"bob@example.com", "grace@example.com", "alan@example.com", "Ada@Example.com"},
+			wantUsers:   []string{"grace@example.com", "ada@example.com"},
+			wantMissing: []string{"bob@example.com", "alan@example.com"}, wantCalls: 1},
+		{name: "duplicates", stored: []string{"ada@example.com"}, emails: []string{"ada@example.com", "ADA@example.com"},
+			wantUsers: []string{"ada@example.com"}, wantMissing: []string{}, wantCalls: 1},
+		{name: "unprocessed keys retried", stored: []string{"ada@example.com"},
+			emails: []string{"ada@example.com", "bob@example.com"}, unprocessed: 2,
+			wantUsers: []string{"ada@example.com"}, wantMissing: []string{"bob@example.com"}, wantCalls: 3},
+		{name: "unprocessed keys given up", stored: []string{"ada@example.com"}, emails: []string{"ada@example.com"},
+			unprocessed: batchMaxAttempts, wantCalls: batchMaxAttempts, wantErr: ErrStorage},
+		{name: "chunked", stored: many[:10], emails: many, wantUsers: many[:10], wantMissing: many[10:], wantCalls: 2},
+		{name: "invalid email", stored: []string{"ada@example.com"}, emails: []string{"ada@example.com", "not-an-email"},
+			wantErr: ErrValidation},
+		{name: "too many", emails: make([]string, MaxBatchGet+1), wantErr: ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []User
+			for _, email := range tt.stored {
+				users = append(users, User{Email: email})
+			}
+			repo, table := newFakeTable(t, users...)
+			unprocessed := tt.unprocessed
+			table.fake.OnBatchGetItem(func(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+				if len(in.RequestItems["users"].Keys) > batchGetSize {
+					t.Errorf("BatchGetItem sent %d keys, want at most %d", len(in.RequestItems["users"].Keys), batchGetSize)
+				}
+				if unprocessed > 0 {
+					unprocessed--
+					return &dynamodb.BatchGetItemOutput{UnprocessedKeys: in.RequestItems}, nil
+				}
+				out := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]*dynamodb.AttributeValue{}}
+				for _, key := range in.RequestItems["users"].Keys {
+					if item := table.items[KeyEmail(key)]; item != nil {
+						out.Responses["users"] = append(out.Responses["users"], item)
+					}
+				}
+				return out, nil
+			})
+
+			result, err := FetchUsersByEmail(tt.emails, ReadOptions{}, repo)
+			if calls := len(table.fake.Inputs("BatchGetItem")); calls != tt.wantCalls {
+				t.Errorf("BatchGetItem called %d times, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FetchUsersByEmail() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchUsersByEmail() error = %v", err)
+			}
+			var got []string
+			for _, u := range result.Users {
+				got = append(got, u.Email)
+			}
+			if !reflect.DeepEqual(got, tt.wantUsers) || !reflect.DeepEqual(result.Missing, tt.wantMissing) {
+				t.Errorf("FetchUsersByEmail() = users %v, missing %v; want %v, %v", got, result.Missing, tt.wantUsers,
+					tt.wantMissing)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"strconv"
+	"time"
+)
+
+// Limits on the number of users a single batch operation handles
+const (
+	MaxBatchCreate = 500
+	MaxBatchGet    = 500
+)
+
+// Error messages for batch operations
+var (
+	ErrorEmptyBatch       = "batch contains no users"
+	ErrorBatchTooLarge    = "too many users in one batch; the limit is " + strconv.Itoa(MaxBatchCreate)
+	ErrorDuplicateInBatch = "email appears more than once in the batch"
+	ErrorTooManyEmails    = "too many emails in one request; the limit is " + strconv.Itoa(MaxBatchGet)
+)
+
+// Outcomes of the items of a batch operation
+const (
+	BatchCreated = "created"
+	BatchSkipped = "skipped"
+	BatchFailed  = "failed"
+)
+
+// BatchResult is the outcome of one item of a batch operation
+type BatchResult struct {
+	Index  int          `json:"index"`            // Position of the item in the request, from 0
+	Email  string       `json:"email"`            // Email of the item
+	Status string       `json:"status"`           // BatchCreated, BatchSkipped or BatchFailed
+	Error  string       `json:"error,omitempty"`  // Why the item was skipped or failed
+	Field  string       `json:"field,omitempty"`  // Offending field, for validation failures
+	Fields []FieldError `json:"fields,omitempty"` // Every invalid field, for items with several
+	User   *User        `json:"-"`                // The stored user, for items that were written
+}
+
+// failedResult builds the result of an item that failed with err.
+func failedResult(email string, err error) BatchResult {
+	result := BatchResult{Email: email, Status: BatchFailed, Error: ErrorInvalidUserData}
+	var userErr *Error
+	if errors.As(err, &userErr) {
+		result.Error, result.Field = userErr.Message, userErr.Field
+	}
+	var invalid ValidationErrors
+	if errors.As(err, &invalid) {
+		result.Fields = invalid.Fields()
+	}
+	return result
+}
+
+// itemEmail returns the normalized email of a batch item that couldn't be decoded, or an
+// empty string, so its result can still name it.
+func itemEmail(item json.RawMessage) string {
+	var named struct {
+		Email string `json:"email"`
+	}
+	_ = json.Unmarshal(item, &named)
+	return NormalizeEmail(named.Email)
+}
+
+// numberResults sets the index of each result to its position in the request.
+func numberResults(results []BatchResult) {
+	for i := range results {
+		results[i].Index = i
+	}
+}
+
+// CreateUsers creates the users in a JSON array, reporting the outcome of each.
+//
+// Every user is decoded and prepared like a single create: checked against the create
+// schema, validated, made active unless it sets a status, given the role the caller may
+// set, and issued a verification token, returned on the user of its result. Users whose
+// email already exists (or repeats an earlier item) are skipped; the others are written in
+// batches. Since batch writes can't be conditional, a user created concurrently between
+// the existence check and the write is overwritten.
+//
+// Parameters:
+// - body: The JSON array of users.
+// - opts: Options tuning the create of every user.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - The outcome of each user, in request order.
+// - An error if the body is invalid or too large, or the existence check fails.
+func CreateUsers(body string, opts CreateOptions, repo Repository) ([]BatchResult, error) {
+	var items []json.RawMessage
+	if err := DecodeJSON(body, &items); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, newError(ErrValidation, ErrorEmptyBatch, nil)
+	}
+	if len(items) > MaxBatchCreate {
+		return nil, newError(ErrTooLarge, ErrorBatchTooLarge, nil)
+	}
+
+	// Decode and prepare every user, skipping repeated emails
+	users := make([]User, len(items))
+	results := make([]BatchResult, len(items))
+	tokens := make([]string, len(items))
+	seen := map[string]bool{}
+	var candidates []string
+	for i, item := range items {
+		_, password, err := decodeUser(string(item), createSchema, &users[i])
+		if err != nil {
+			results[i] = failedResult(itemEmail(item), err)
+			continue
+		}
+		token, err := prepareCreate(&users[i], password, opts)
+		email := users[i].Email
+		if err != nil {
+			results[i] = failedResult(email, err)
+			continue
+		}
+		tokens[i] = token
+		if seen[email] {
+			results[i] = BatchResult{Email: email, Status: BatchSkipped, Error: ErrorDuplicateInBatch}
+			continue
+		}
+		seen[email] = true
+		candidates = append(candidates, email)
+	}
+
+	// Skip the users that already exist
+	existing, err := repo.GetMany(candidates, ReadOptions{ConsistentRead: true, Fields: []string{"email"}})
+	if err != nil {
+		return nil, err
+	}
+	exists := map[string]bool{}
+	for _, u := range existing {
+		exists[u.Email] = true
+	}
+
+	now := timestamp()
+	var toCreate []User
+	var indexes []int
+	for i := range users {
+		if results[i].Status != "" {
+			continue
+		}
+		if exists[users[i].Email] {
+			results[i] = BatchResult{Email: users[i].Email, Status: BatchSkipped, Error: ErrorUserAlreadyExists}
+			continue
+		}
+		users[i].CreatedAt, users[i].UpdatedAt, users[i].Version, users[i].DeletedAt = now, now, 1, ""
+		toCreate = append(toCreate, users[i])
+		indexes = append(indexes, i)
+	}
+
+	failed := repo.PutMany(toCreate)
+	for _, i := range indexes {
+		if err, ok := failed[users[i].Email]; ok {
+			results[i] = failedResult(users[i].Email, err)
+			continue
+		}
+		users[i].VerifyToken = tokens[i]
+		results[i] = BatchResult{Email: users[i].Email, Status: BatchCreated, User: &users[i]}
+	}
+	numberResults(results)
+	return results, nil
+}
+
+// BatchGetResult is the result of fetching several users by email
+type BatchGetResult struct {
+	Users   []User   `json:"users"`   // The users found, in request order
+	Missing []string `json:"missing"` // The requested emails without a user, in request order
+}
+
+// FetchUsersByEmail retrieves several users by email in as few requests as possible.
+// Emails are normalized and validated before anything is read; repeated emails are
+// returned once.
+//
+// Parameters:
+// - emails: The emails of the users to fetch.
+// - opts: Options tuning the read.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - The users found and the emails missing, both in request order.
+// - A validation error naming the first invalid email, or an error if the read fails.
+func FetchUsersByEmail(emails []string, opts ReadOptions, repo Repository) (*BatchGetResult, error) {
+	if len(emails) > MaxBatchGet {
+		return nil, newError(ErrTooLarge, ErrorTooManyEmails, nil)
+	}
+
+	var keys []string
+	seen := map[string]bool{}
+	for _, email := range emails {
+		key := NormalizeEmail(email)
+		if !validators.IsEmailValid(key) {
+			return nil, newFieldError(ErrValidation, ErrorInvalidEmail+": "+email, "emails", nil)
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	// The email is needed to match the users back to the request, and the deleted flag
+	// and expiry to hide soft-deleted and expired users
+	if len(opts.Fields) > 0 {
+		opts.Fields = WithFields(opts.Fields, "email", "deletedAt", "expiresAt")
+	}
+	found, err := repo.GetMany(keys, opts)
+	if err != nil {
+		return nil, err
+	}
+	byEmail := make(map[string]User, len(found))
+	now := time.Now()
+	for _, u := range found {
+		if (u.DeletedAt == "" || opts.IncludeDeleted) && !u.ExpiresAt.Expired(now) {
+			byEmail[u.Email] = u
+		}
+	}
+
+	result := &BatchGetResult{Users: []User{}, Missing: []string{}}
+	for _, key := range keys {
+		if u, ok := byEmail[key]; ok {
+			result.Users = append(result.Users, u)
+		} else {
+			result.Missing = append(result.Missing, key)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package user
+
+import (
+	"testing"
+)
+
+func TestCreateUsersPreparesLikeCreate(t *testing.T) {
+	tests := []struct {
+		name       string
+		extra      string
+		opts       CreateOptions
+		wantStatus string
+		wantRole   string
+	}{
+		{name: "defaults", wantStatus: StatusActive, wantRole: RoleUser},
+		{name: "role ignored for users", extra: `, "role": "admin"`, wantStatus: StatusActive, wantRole: RoleUser},
+		{name: "role set by admins", extra: `, "role": "admin"`, opts: CreateOptions{AllowRoleChange: true},
+			wantStatus: StatusActive, wantRole: RoleAdmin},
+		{name: "status kept", extra: `, "status": "inactive"`, wantStatus: StatusInactive, wantRole: RoleUser},
+		{name: "suspension forbidden", extra: `, "status": "suspended"`},
+		{name: "role change rejected", extra: `, "role": "admin"`, opts: CreateOptions{RejectRoleChange: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := `{"email": "Ada@Example.com", "firstname": "Ada", "lastname": "Lovelace"` + tt.extra + `}`
+			single, singleErr := CreateUserFromJSON(item, tt.opts, NewMemoryRepository())
+			results, err := CreateUsers("["+item+"]", tt.opts, NewMemoryRepository())
+			if err != nil {
+				t.Fatalf("CreateUsers() error = %v", err)
+			}
+			result := results[0]
+
+			if tt.wantStatus == "" {
+				if singleErr == nil || result.Status != BatchFailed {
+					t.Fatalf("create error = %v, batch status = %q; want both to fail", singleErr, result.Status)
+				}
+				if result.Error != singleErr.(*Error).Message {
+					t.Errorf("batch error = %q, want the create's %q", result.Error, singleErr.(*Error).Message)
+				}
+				return
+			}
+			if singleErr != nil || result.Status != BatchCreated {
+				t.Fatalf("create error = %v, batch status = %q (%s); want both to succeed", singleErr, result.Status,
+					result.Error)
+			}
+			for _, u := range []*User{single, result.User} {
+				if u.Email != "ada@example.com" || u.Status != tt.wantStatus || u.Role != tt.wantRole {
+					t.Errorf("user %q has status %q and role %q, want %q and %q", u.Email, u.Status, u.Role, tt.wantStatus,
+						tt.wantRole)
+				}
+				if u.Verified || u.VerifyToken == "" || u.VerifyTokenHash == "" || u.VerifyTokenExpiresAt == "" {
+					t.Errorf("user %q isn't awaiting verification with a token", u.Email)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateUsersAppliesDomainPolicies(t *testing.T) {
+	t.Setenv("ALLOWED_EMAIL_DOMAINS", "example.com")
+	body := `[{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"},
+		{"email": "alan@elsewhere.org", "firstname": "Alan", "lastname": "Turing"}]`
+	results, err := CreateUsers(body, CreateOptions{}, NewMemoryRepository())
+	if err != nil {
+		t.Fatalf("CreateUsers() error = %v", err)
+	}
+	if results[0].Status != BatchCreated || results[1].Status != BatchFailed || results[1].Error != ErrorDomainNotAllowed {
+		t.Errorf("results = %+v, want the second user rejected by the domain policy", results)
+	}
+}
@@ -0,0 +1,146 @@
+package user
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"os"
+	"strings"
+	"time"
+)
+
+// Error messages for creation time ranges
+var (
+	ErrorInvalidCreatedAfter  = "createdAfter must be an RFC3339 time such as 2024-01-01T00:00:00Z"
+	ErrorInvalidCreatedBefore = "createdBefore must be an RFC3339 time such as 2024-01-01T00:00:00Z"
+	ErrorInvalidCreatedRange  = "createdAfter must not be later than createdBefore"
+)
+
+// createdIndex returns the name of the global secondary index partitioned by entityType and
+// sorted by createdAt (CREATED_INDEX), or an empty string if there is none.
+func createdIndex() string {
+	return os.Getenv("CREATED_INDEX")
+}
+
+// ParseCreatedRange validates the bounds of a creation time range.
+//
+// Parameters:
+// - after: The earliest creation time as RFC3339, or an empty string for no lower bound.
+// - before: The latest creation time as RFC3339, or an empty string for no upper bound.
+//
+// Returns:
+// - The bounds, in UTC as createdAt is stored, or empty strings where unset.
+// - A validation error if a bound isn't RFC3339 or after is later than before.
+func ParseCreatedRange(after string, before string) (string, string, error) {
+	var err error
+	if after, err = parseCreatedBound(after); err != nil {
+		return "", "", newFieldError(ErrValidation, ErrorInvalidCreatedAfter, "createdAfter", nil)
+	}
+	if before, err = parseCreatedBound(before); err != nil {
+		return "", "", newFieldError(ErrValidation, ErrorInvalidCreatedBefore, "createdBefore", nil)
+	}
+	if after != "" && before != "" && after > before {
+		return "", "", newFieldError(ErrValidation, ErrorInvalidCreatedRange, "createdAfter", nil)
+	}
+	return after, before, nil
+}
+
+// parseCreatedBound parses one bound of a creation time range.
+func parseCreatedBound(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return "", err
+	}
+	return parsed.UTC().Format(time.RFC3339), nil
+}
+
+// createdCondition builds the condition selecting the filter's creation time range, inclusive
+// of both bounds, adding its names and values. It serves both as a scan filter and as the
+// sort key condition of the creation index.
+func createdCondition(f Filter, names map[string]*string, values map[string]*dynamodb.AttributeValue) string {
+	names["#createdAt"] = aws.String("createdAt")
+	if f.CreatedAfter != "" {
+		values[":createdAfter"] = &dynamodb.AttributeValue{S: aws.String(f.CreatedAfter)}
+	}
+	if f.CreatedBefore != "" {
+		values[":createdBefore"] = &dynamodb.AttributeValue{S: aws.String(f.CreatedBefore)}
+	}
+	switch {
+	case f.CreatedAfter != "" && f.CreatedBefore != "":
+		return "#createdAt BETWEEN :createdAfter AND :createdBefore"
+	case f.CreatedAfter != "":
+		return "#createdAt >= :createdAfter"
+	default:
+		return "#createdAt <= :createdBefore"
+	}
+}
+
+// usesCreatedIndex reports whether a list can be served by querying the creation index:
+// the index must be configured and the filter must select on the creation time. Other
+// filters are applied to the query results.
+func usesCreatedIndex(filter Filter) bool {
+	return createdIndex() != "" && filter.HasCreatedRange()
+}
+
+// warnCreatedScan logs that a list on a creation time range reads the whole table, because
+// CREATED_INDEX isn't set.
+func warnCreatedScan(filter Filter) {
+	if createdIndex() == "" && filter.HasCreatedRange() {
+		logging.Default.Warn("filtering a creation time range without CREATED_INDEX reads every user", logging.Fields{
+			"createdAfter":  filter.CreatedAfter,
+			"createdBefore": filter.CreatedBefore,
+		})
+	}
+}
+
+// queryByCreated reads the users created in the filter's range from the creation index,
+// newest first, paging until the range is exhausted or maxItems users were read.
+//
+// Parameters:
+// - opts: Options tuning the read; consistent reads are rejected because GSIs don't support them.
+// - maxItems: The maximum number of items to return.
+//
+// Returns:
+// - The items, newest first.
+// - Whether items were left unread because of maxItems.
+// - An error if the query fails or a consistent read was requested.
+func (r *DynamoRepository) queryByCreated(opts ReadOptions, maxItems int) (
+	[]map[string]*dynamodb.AttributeValue, bool, error) {
+	if opts.ConsistentRead {
+		return nil, false, newFieldError(ErrValidation, ErrorConsistentReadOnIndex, "consistent", nil)
+	}
+
+	// The range is the key condition; the other filters apply to what it reads
+	rest := opts.Filter
+	rest.CreatedAfter, rest.CreatedBefore = "", ""
+	filter, names, values := rest.expression()
+	if names == nil {
+		names, values = map[string]*string{}, map[string]*dynamodb.AttributeValue{}
+	}
+	names["#entityType"] = aws.String(EntityTypeAttribute)
+	values[":entityType"] = &dynamodb.AttributeValue{S: aws.String(userEntityType())}
+	keyCondition := "#entityType = :entityType AND " + createdCondition(opts.Filter, names, values)
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.TableName),
+		IndexName:              aws.String(createdIndex()),
+		KeyConditionExpression: aws.String(keyCondition),
+		ScanIndexForward:       aws.Bool(false),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+	if len(opts.Fields) > 0 {
+		var projected map[string]*string
+		input.ProjectionExpression, projected = projection(opts.Fields)
+		for placeholder, name := range projected {
+			names[placeholder] = name
+		}
+	}
+	input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues = visibleOnly(opts,
+		filter, names, values)
+
+	return r.query(input, maxItems)
+}
@@ -0,0 +1,33 @@
+package user
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// CSVColumns are the columns of a user export, in order
+var CSVColumns = []string{"email", "firstname", "lastname", "createdAt"}
+
+// WriteCSV writes users as RFC 4180 CSV with a header row of CSVColumns.
+// Values containing commas, quotes or line breaks are quoted.
+//
+// Parameters:
+// - w: Where the CSV is written.
+// - users: The users to write, in order.
+//
+// Returns:
+// - An error if writing fails.
+func WriteCSV(w io.Writer, users []User) error {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+	if err := writer.Write(CSVColumns); err != nil {
+		return err
+	}
+	for _, u := range users {
+		if err := writer.Write([]string{u.Email, u.FirstName, u.LastName, u.CreatedAt}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
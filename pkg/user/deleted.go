@@ -0,0 +1,120 @@
+package user
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error messages for purges of soft-deleted users
+var (
+	ErrorMissingOlderThan = "olderThan is required, e.g. 30d"
+	ErrorInvalidOlderThan = "olderThan must be a number of days or hours such as 30d or 12h, or an RFC3339 time"
+)
+
+// ParseCutoff resolves an age such as "30d" or "12h", or an RFC3339 time, into the time
+// before which records count as older.
+//
+// Parameters:
+// - olderThan: The age, as a positive whole number of days (d) or hours (h), or an RFC3339 time.
+// - now: The time ages are counted back from.
+//
+// Returns:
+// - The cutoff, in UTC.
+// - A validation error if olderThan is missing or malformed.
+func ParseCutoff(olderThan string, now time.Time) (time.Time, error) {
+	olderThan = strings.TrimSpace(olderThan)
+	if olderThan == "" {
+		return time.Time{}, newFieldError(ErrValidation, ErrorMissingOlderThan, "olderThan", nil)
+	}
+	if cutoff, err := time.Parse(time.RFC3339, olderThan); err == nil {
+		return cutoff.UTC(), nil
+	}
+
+	var unit time.Duration
+	switch olderThan[len(olderThan)-1] {
+	case 'd':
+		unit = 24 * time.Hour
+	case 'h':
+		unit = time.Hour
+	}
+	count, err := strconv.Atoi(olderThan[:len(olderThan)-1])
+	if unit == 0 || err != nil || count < 1 || count > maxAgeUnits(unit) {
+		return time.Time{}, newFieldError(ErrValidation, ErrorInvalidOlderThan, "olderThan", err)
+	}
+	return now.Add(-time.Duration(count) * unit).UTC(), nil
+}
+
+// maxAgeUnits bounds the count of an age so it can't overflow a time.Duration: 100 years.
+func maxAgeUnits(unit time.Duration) int {
+	return int(100 * 365 * 24 * time.Hour / unit)
+}
+
+// ListDeleted reads the soft-deleted users only, up to opts.MaxItems.
+//
+// Parameters:
+// - opts: Options tuning the read; the filter is kept and narrowed to deleted users.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - The page of users, flagged as truncated if the limit was hit.
+// - An error if the users cannot be fetched.
+func ListDeleted(opts ReadOptions, repo Repository) (*Page, error) {
+	opts.Filter.Deleted = true
+	opts.IncludeDeleted = true
+	return repo.List(opts)
+}
+
+// PurgeDeleted removes for good the users soft-deleted before a cutoff. Nothing is removed
+// when more users match than MAX_BULK_DELETE allows.
+//
+// Parameters:
+// - olderThan: How long ago the users must have been deleted, e.g. "30d", "12h" or an RFC3339 time.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The removed emails, ordered by email, and any that couldn't be removed.
+//   - A validation error for a malformed olderThan, ErrTooLarge if too many users match,
+//     or an error if the users could not be listed.
+func PurgeDeleted(olderThan string, repo Repository) (*BulkDeleteResult, error) {
+	cutoff, err := ParseCutoff(olderThan, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	limit := maxBulkDelete()
+	page, err := ListDeleted(ReadOptions{
+		ConsistentRead: true,
+		Fields:         []string{"email", "deletedAt"},
+		MaxItems:       limit,
+		Filter:         Filter{DeletedBefore: cutoff.Format(time.RFC3339)},
+	}, repo)
+	if err != nil {
+		return nil, err
+	}
+	if page.Truncated {
+		return nil, newError(ErrTooLarge, ErrorTooManyToDelete+strconv.Itoa(limit), nil)
+	}
+
+	emails := make([]string, len(page.Users))
+	for i := range page.Users {
+		emails[i] = page.Users[i].Email
+	}
+	sort.Strings(emails)
+
+	result := &BulkDeleteResult{Matched: len(emails), Emails: []string{}}
+	if len(emails) == 0 {
+		return result, nil
+	}
+	failed := repo.DeleteMany(emails)
+	for _, email := range emails {
+		if _, ok := failed[email]; ok {
+			result.Failed = append(result.Failed, email)
+		} else {
+			result.Emails = append(result.Emails, email)
+		}
+	}
+	result.Deleted = len(result.Emails)
+	return result, nil
+}
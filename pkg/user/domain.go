@@ -0,0 +1,49 @@
+package user
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"os"
+	"strings"
+)
+
+// Error messages for email domain policies
+var (
+	ErrorDisposableEmail   = "disposable email addresses are not accepted"
+	ErrorDomainRejectsMail = "email domain does not accept mail"
+	ErrorDomainNotAllowed  = "email domain is not allowed; this deployment only accepts addresses at its allowed domains"
+)
+
+// checkEmailDomain applies the deployment's domain policies (ALLOWED_EMAIL_DOMAINS and
+// BLOCK_DISPOSABLE_EMAILS) to an email a user is created with or moves to. Users already
+// stored under an out-of-policy email can still be read, updated and deleted.
+//
+// Parameters:
+// - email: The normalized, valid email.
+//
+// Returns:
+// - A validation error naming the policy the email breaks, or nil.
+func checkEmailDomain(email string) error {
+	allowed := os.Getenv("ALLOWED_EMAIL_DOMAINS")
+	if allowed != "" && !validators.IsDomainAllowed(email, strings.Split(allowed, ",")) {
+		return newFieldError(ErrValidation, ErrorDomainNotAllowed, "email", nil)
+	}
+	if os.Getenv("BLOCK_DISPOSABLE_EMAILS") == "true" && validators.IsDisposableEmail(email) {
+		return newFieldError(ErrValidation, ErrorDisposableEmail, "email", nil)
+	}
+	return nil
+}
+
+// checkMailDomain rejects an email whose domain has no mail servers when EMAIL_MX_CHECK is
+// set. It takes a DNS lookup, cached per domain, so it is left out of imports.
+//
+// Parameters:
+// - email: The normalized, valid email.
+//
+// Returns:
+// - A validation error if the domain surely doesn't accept mail, or nil.
+func checkMailDomain(email string) error {
+	if os.Getenv("EMAIL_MX_CHECK") == "true" && !validators.AcceptsMail(email) {
+		return newFieldError(ErrValidation, ErrorDomainRejectsMail, "email", nil)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"net"
+	"testing"
+)
+
+// mxResolver answers every MX lookup with mx and err, and finds no address.
+type mxResolver struct {
+	mx  []*net.MX
+	err error
+}
+
+func (r mxResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return r.mx, r.err
+}
+
+func (r mxResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestCreateUserChecksMailDomain(t *testing.T) {
+	tests := []struct {
+		name        string
+		check       string
+		resolver    mxResolver
+		email       string
+		wantMessage string
+	}{
+		{name: "accepts mail", check: "true", resolver: mxResolver{mx: []*net.MX{{Host: "mx.example.com."}}},
+			email: "ada@mail-ok.example"},
+		{name: "no mail servers", check: "true",
+			resolver: mxResolver{err: &net.DNSError{Err: "no such host", IsNotFound: true}},
+			email:    "ada@no-mail.example", wantMessage: ErrorDomainRejectsMail},
+		{name: "null mx", check: "true", resolver: mxResolver{mx: []*net.MX{{Host: "."}}}, email: "ada@null-mx.example",
+			wantMessage: ErrorDomainRejectsMail},
+		{name: "dns failure fails open", check: "true",
+			resolver: mxResolver{err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}},
+			email:    "ada@servfail.example"},
+		{name: "check disabled", resolver: mxResolver{mx: []*net.MX{{Host: "."}}}, email: "ada@unchecked.example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("EMAIL_MX_CHECK", tt.check)
+			defer func(resolver validators.Resolver) { validators.MailResolver = resolver }(validators.MailResolver)
+			validators.MailResolver = tt.resolver
+
+			_, err := CreateUserFromJSON(`{"email": "`+tt.email+`", "firstname": "Ada", "lastname": "Lovelace"}`,
+				CreateOptions{}, NewMemoryRepository())
+			if tt.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("CreateUserFromJSON() error = %v", err)
+				}
+				return
+			}
+			var userErr *Error
+			if !errors.As(err, &userErr) || userErr.Message != tt.wantMessage || userErr.Field != "email" {
+				t.Errorf("CreateUserFromJSON() error = %v, want %q on the email", err, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestAllowedEmailDomains(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed string
+		email   string
+		wantErr bool
+	}{
+		{name: "no allowlist", email: "ada@gmail.com"},
+		{name: "exact", allowed: "example.com", email: "ada@Example.com"},
+		{name: "wildcard", allowed: "example.com,*.corp.example.com", email: "ada@eu.corp.example.com"},
+		{name: "outside", allowed: "example.com,*.corp.example.com", email: "ada@gmail.com", wantErr: true},
+		{name: "near miss", allowed: "*.corp.example.com", email: "ada@evilcorp.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOWED_EMAIL_DOMAINS", tt.allowed)
+			_, createErr := CreateUserFromJSON(`{"email": "`+tt.email+`", "firstname": "Ada", "lastname": "Lovelace"}`,
+				CreateOptions{}, NewMemoryRepository())
+
+			repo := NewMemoryRepository()
+			if err := repo.Create(&User{Email: "grace@outside.example", FirstName: "Grace", LastName: "Hopper",
+				Version: 1}); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+			_, _, changeErr := ChangeEmail("grace@outside.example", `{"email": "`+tt.email+`"}`, 0, UpdateOptions{}, repo)
+
+			for operation, err := range map[string]error{"create": createErr, "change-email": changeErr} {
+				if !tt.wantErr {
+					if err != nil {
+						t.Errorf("%s error = %v", operation, err)
+					}
+					continue
+				}
+				var userErr *Error
+				if !errors.As(err, &userErr) || userErr.Message != ErrorDomainNotAllowed || !errors.Is(err, ErrValidation) {
+					t.Errorf("%s error = %v, want %q", operation, err, ErrorDomainNotAllowed)
+				}
+			}
+		})
+	}
+}
+
+func TestOutOfPolicyUsersStayReachable(t *testing.T) {
+	t.Setenv("ALLOWED_EMAIL_DOMAINS", "example.com")
+	repo := NewMemoryRepository()
+	if err := repo.Create(&User{Email: "grace@outside.example", FirstName: "Grace", LastName: "Hopper", Version: 1}); err != nil {
+		t.Fatalf("seeding: %v", err)
+	}
+
+	if _, err := FetchUser("grace@outside.example", ReadOptions{}, repo); err != nil {
+		t.Errorf("FetchUser() error = %v, want the existing user", err)
+	}
+	if err := DeleteUser("grace@outside.example", false, repo); err != nil {
+		t.Errorf("DeleteUser() error = %v, want the existing user deleted", err)
+	}
+}
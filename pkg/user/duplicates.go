@@ -0,0 +1,107 @@
+package user
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Defaults of duplicate detection, unless DUPLICATE_THRESHOLD and MAX_DUPLICATE_SCAN say otherwise
+const (
+	defaultDuplicateThreshold = 0.8
+	defaultMaxDuplicateScan   = 1000
+)
+
+// ErrorMissingDuplicateName is returned when a duplicate lookup lacks either name
+var ErrorMissingDuplicateName = "firstname and lastname are required"
+
+// DuplicateMatch is a user whose name resembles the one looked up
+type DuplicateMatch struct {
+	Email     string  `json:"email"`     // Email of the user
+	FirstName string  `json:"firstname"` // First name of the user
+	LastName  string  `json:"lastname"`  // Last name of the user
+	Score     float64 `json:"score"`     // Similarity of the full names, from 0 to 1
+}
+
+// DuplicateResult is the outcome of a duplicate lookup
+type DuplicateResult struct {
+	Matches   []DuplicateMatch `json:"matches"`   // Matches, most similar first
+	Truncated bool             `json:"truncated"` // Whether users were left unread because of MAX_DUPLICATE_SCAN
+}
+
+// duplicateThreshold returns the lowest score reported as a match (DUPLICATE_THRESHOLD, 0.8 by default).
+func duplicateThreshold() float64 {
+	threshold, err := strconv.ParseFloat(os.Getenv("DUPLICATE_THRESHOLD"), 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		return defaultDuplicateThreshold
+	}
+	return threshold
+}
+
+// maxDuplicateScan returns the most users a duplicate lookup reads (MAX_DUPLICATE_SCAN, 1000 by default).
+func maxDuplicateScan() int {
+	limit, err := strconv.Atoi(os.Getenv("MAX_DUPLICATE_SCAN"))
+	if err != nil || limit < 1 {
+		return defaultMaxDuplicateScan
+	}
+	return limit
+}
+
+// FindDuplicates looks for users whose full name resembles the given one, scored by
+// validators.NameSimilarity on "firstname lastname", so accents, case and small typos
+// don't hide a match. Every user is a candidate, so the table is read rather than the
+// lastname index, which would miss a misspelled last name; at most MAX_DUPLICATE_SCAN
+// users are read.
+//
+// Parameters:
+// - firstName: The first name of the person.
+// - lastName: The last name of the person.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The users scoring at least DUPLICATE_THRESHOLD, most similar first, and whether the
+//     read stopped at MAX_DUPLICATE_SCAN.
+//   - A validation error if either name is missing, or an error if the users could not be
+//     listed.
+func FindDuplicates(firstName string, lastName string, repo Repository) (*DuplicateResult, error) {
+	if strings.TrimSpace(firstName) == "" {
+		return nil, newFieldError(ErrValidation, ErrorMissingDuplicateName, "firstname", nil)
+	}
+	if strings.TrimSpace(lastName) == "" {
+		return nil, newFieldError(ErrValidation, ErrorMissingDuplicateName, "lastname", nil)
+	}
+
+	page, err := repo.List(ReadOptions{
+		Fields:   []string{"email", "firstname", "lastname"},
+		MaxItems: maxDuplicateScan(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name := firstName + " " + lastName
+	threshold := duplicateThreshold()
+	result := &DuplicateResult{Matches: []DuplicateMatch{}, Truncated: page.Truncated}
+	for _, u := range page.Users {
+		score := validators.NameSimilarity(name, u.FirstName+" "+u.LastName)
+		if score < threshold {
+			continue
+		}
+		result.Matches = append(result.Matches, DuplicateMatch{
+			Email:     u.Email,
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Score:     math.Round(score*1000) / 1000,
+		})
+	}
+	sort.SliceStable(result.Matches, func(i, j int) bool {
+		if result.Matches[i].Score != result.Matches[j].Score {
+			return result.Matches[i].Score > result.Matches[j].Score
+		}
+		return result.Matches[i].Email < result.Matches[j].Email
+	})
+	return result, nil
+}
@@ -0,0 +1,163 @@
+package user
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Error messages for email changes
+var (
+	ErrorEmailImmutable   = "email can't be changed by an update; use POST /users/{email}/change-email"
+	ErrorEmailUnchanged   = "new email is the user's current email"
+	ErrorCouldNotMoveItem = "couldn't move the user to the new email"
+	ErrorMissingNewEmail  = "body must name the new email"
+)
+
+// EmailChangeRequest is the body of an email change
+type EmailChangeRequest struct {
+	Email string `json:"email"` // The new email
+}
+
+// checkEmailUnchanged rejects an update body naming another email than the targeted user.
+//
+// Parameters:
+// - target: The email of the targeted user.
+// - body: The email in the update body, if any.
+//
+// Returns:
+// - A validation error if both are set and differ once normalized, or nil.
+func checkEmailUnchanged(target string, body string) error {
+	if target != "" && body != "" && NormalizeEmail(target) != NormalizeEmail(body) {
+		return newFieldError(ErrValidation, ErrorEmailImmutable, "email", nil)
+	}
+	return nil
+}
+
+// ChangeEmail moves a user to a new email. The email is the partition key, so the user is
+// written under the new key and removed from the old one in a single transaction: either
+// both happen or neither does. The creation time is kept, the version bumped, and the
+// user has to verify the new email.
+//
+// Parameters:
+// - email: The current email of the user.
+// - body: The JSON body naming the new email, e.g. {"email": "new@example.com"}.
+// - expectedVersion: The version the client last saw, or 0 to use the currently stored version.
+// - opts: Options tuning the write; only administrators may change suspended users.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The user under its new email.
+//   - The user as it was before the change.
+//   - A validation error for a missing or invalid new email, an ErrNotFound error if the
+//     user doesn't exist, an ErrConflict error if the new email is taken, an
+//     ErrPreconditionFailed error if the version doesn't match, or an error if the write fails.
+func ChangeEmail(email string, body string, expectedVersion int, opts UpdateOptions, repo Repository) (*User, *User, error) {
+	var req EmailChangeRequest
+	if err := DecodeJSON(body, &req); err != nil {
+		return nil, nil, err
+	}
+	if req.Email == "" {
+		return nil, nil, newFieldError(ErrValidation, ErrorMissingNewEmail, "email", nil)
+	}
+	newEmail := NormalizeEmail(req.Email)
+
+	current, err := FetchUser(email, ReadOptions{ConsistentRead: true}, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if newEmail == current.Email {
+		return nil, nil, newFieldError(ErrValidation, ErrorEmailUnchanged, "email", nil)
+	}
+	if err := checkStatusChange(current.CurrentStatus(), current.CurrentStatus(), opts.AllowSuspended); err != nil {
+		return nil, nil, err
+	}
+	if expectedVersion == 0 {
+		expectedVersion = current.Version
+	} else if expectedVersion != current.Version {
+		return nil, nil, newError(ErrPreconditionFailed, ErrorVersionMismatch, nil)
+	}
+
+	// The new email has yet to be verified
+	moved := *current
+	moved.Email = newEmail
+	moved.UpdatedAt = timestamp()
+	moved.Version = expectedVersion + 1
+	clearVerification(&moved)
+	if err := moved.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := checkEmailDomain(moved.Email); err != nil {
+		return nil, nil, err
+	}
+	if err := checkMailDomain(moved.Email); err != nil {
+		return nil, nil, err
+	}
+
+	if err := repo.Move(current.Email, &moved, expectedVersion); err != nil {
+		return nil, nil, err
+	}
+	return &moved, current, nil
+}
+
+// Move writes u under its email and removes the user stored under exactly oldEmail in one
+// TransactWriteItems. The put fails if the new email is taken, and the delete if the old
+// user's version is no longer expectedVersion; a failure of either cancels both.
+func (r *DynamoRepository) Move(oldEmail string, u *User, expectedVersion int) error {
+	item, err := marshalUser(u)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{
+				TableName:                aws.String(r.TableName),
+				Item:                     item,
+				ConditionExpression:      aws.String("attribute_not_exists(#key)"),
+				ExpressionAttributeNames: withKeyName(nil),
+			}},
+			{Delete: &dynamodb.Delete{
+				TableName:                 aws.String(r.TableName),
+				Key:                       emailKey(oldEmail),
+				ConditionExpression:       aws.String("attribute_exists(#key) AND (" + versionCondition(expectedVersion) + ")"),
+				ExpressionAttributeNames:  withKeyName(map[string]*string{"#version": aws.String("version")}),
+				ExpressionAttributeValues: versionValues(expectedVersion),
+			}},
+		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+
+	_, err = r.DynaClient.TransactWriteItems(input)
+	var canceled *dynamodb.TransactionCanceledException
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &canceled) && len(canceled.CancellationReasons) == 2:
+		// Reasons are listed in the order of the transaction's items
+		if reason := canceled.CancellationReasons[0]; reason != nil && aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+			return newError(ErrConflict, ErrorUserAlreadyExists, err)
+		}
+		if reason := canceled.CancellationReasons[1]; reason != nil && aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+			return newError(ErrPreconditionFailed, ErrorVersionMismatch, err)
+		}
+	}
+	return newError(ErrStorage, ErrorCouldNotMoveItem, err)
+}
+
+// Move stores u under its email and removes the user stored under oldEmail, atomically.
+func (r *MemoryRepository) Move(oldEmail string, u *User, expectedVersion int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, taken := r.users[u.Email]; taken {
+		return newError(ErrConflict, ErrorUserAlreadyExists, nil)
+	}
+	current, ok := r.users[oldEmail]
+	if !ok || current.Version != expectedVersion {
+		return newError(ErrPreconditionFailed, ErrorVersionMismatch, nil)
+	}
+	delete(r.users, oldEmail)
+	r.users[u.Email] = *u
+	return nil
+}
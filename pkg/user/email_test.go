@@ -0,0 +1,141 @@
+package user
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+// transactOn makes the fake table run TransactWriteItems like DynamoDB: every condition is
+// checked before any write, and one failing cancels the whole transaction. inject, if set,
+// runs first and may fail the call or change the table, like a concurrent writer.
+func transactOn(table *fakeTable, inject func() error) {
+	table.fake.OnTransactWriteItems(func(in *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+		if inject != nil {
+			if err := inject(); err != nil {
+				return nil, err
+			}
+		}
+		reasons := make([]*dynamodb.CancellationReason, len(in.TransactItems))
+		canceled := false
+		for i, write := range in.TransactItems {
+			reasons[i] = &dynamodb.CancellationReason{Code: aws.String("None")}
+			var holds bool
+			switch {
+			case write.Put != nil:
+				holds = table.holds(KeyEmail(write.Put.Item), write.Put.ConditionExpression, write.Put.ExpressionAttributeValues)
+			case write.Delete != nil:
+				holds = table.holds(KeyEmail(write.Delete.Key), write.Delete.ConditionExpression,
+					write.Delete.ExpressionAttributeValues)
+			}
+			if !holds {
+				reasons[i].Code = aws.String("ConditionalCheckFailed")
+				canceled = true
+			}
+		}
+		if canceled {
+			return nil, &dynamodb.TransactionCanceledException{CancellationReasons: reasons}
+		}
+		for _, write := range in.TransactItems {
+			switch {
+			case write.Put != nil:
+				table.items[KeyEmail(write.Put.Item)] = write.Put.Item
+			case write.Delete != nil:
+				delete(table.items, KeyEmail(write.Delete.Key))
+			}
+		}
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	})
+}
+
+func TestChangeEmail(t *testing.T) {
+	bob := User{Email: "bob@example.com", FirstName: "Bob", LastName: "Barker", Version: 1}
+	tests := []struct {
+		name    string
+		body    string
+		version int
+		inject  func(table *fakeTable) error
+		wantErr error
+		wantOld bool // Whether a record remains under the old email
+		wantNew bool // Whether a record exists under the new email
+	}{
+		{name: "moved", body: `{"email": "augusta@example.com"}`, wantNew: true},
+		{name: "moved at the expected version", body: `{"email": "augusta@example.com"}`, version: 2, wantNew: true},
+		{name: "normalized", body: `{"email": "Augusta@Example.com"}`, wantNew: true},
+		{name: "new email taken", body: `{"email": "bob@example.com"}`, wantErr: ErrConflict, wantOld: true},
+		{name: "stale version", body: `{"email": "augusta@example.com"}`, version: 1, wantErr: ErrPreconditionFailed,
+			wantOld: true},
+		{name: "updated concurrently", body: `{"email": "augusta@example.com"}`,
+			inject: func(table *fakeTable) error {
+				table.items["ada@example.com"]["version"] = &dynamodb.AttributeValue{N: aws.String("3")}
+				return nil
+			}, wantErr: ErrPreconditionFailed, wantOld: true},
+		{name: "new email taken concurrently", body: `{"email": "augusta@example.com"}`,
+			inject: func(table *fakeTable) error {
+				table.items["augusta@example.com"] = map[string]*dynamodb.AttributeValue{
+					"email": {S: aws.String("augusta@example.com")}, "version": {N: aws.String("1")}}
+				return nil
+			}, wantErr: ErrConflict, wantOld: true, wantNew: true},
+		{name: "deleted concurrently", body: `{"email": "augusta@example.com"}`,
+			inject: func(table *fakeTable) error {
+				delete(table.items, "ada@example.com")
+				return nil
+			}, wantErr: ErrPreconditionFailed},
+		{name: "transaction throttled", body: `{"email": "augusta@example.com"}`,
+			inject: func(table *fakeTable) error { return mocks.ThrottlingError() }, wantErr: ErrStorage, wantOld: true},
+		{name: "same email", body: `{"email": "ADA@example.com"}`, wantErr: ErrValidation, wantOld: true},
+		{name: "invalid email", body: `{"email": "not-an-email"}`, wantErr: ErrValidation, wantOld: true},
+		{name: "missing email", body: `{}`, wantErr: ErrValidation, wantOld: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ada := storedAda()
+			repo, table := newFakeTable(t, ada, bob)
+			transactOn(table, func() error {
+				if tt.inject == nil {
+					return nil
+				}
+				return tt.inject(table)
+			})
+			before := table.snapshot()
+
+			moved, previous, err := ChangeEmail(ada.Email, tt.body, tt.version, UpdateOptions{}, repo)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChangeEmail() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// The transaction writes both records or neither, so only a concurrent writer can
+			// leave records under both emails or under neither
+			_, hasOld := table.items[ada.Email]
+			_, hasNew := table.items["augusta@example.com"]
+			if hasOld != tt.wantOld || hasNew != tt.wantNew {
+				t.Fatalf("records under the old email %v and the new one %v, want %v and %v", hasOld, hasNew,
+					tt.wantOld, tt.wantNew)
+			}
+			if !reflect.DeepEqual(table.items[bob.Email], before[bob.Email]) {
+				t.Errorf("%s = %v, want it untouched", bob.Email, table.items[bob.Email])
+			}
+			if tt.wantErr != nil {
+				if tt.inject == nil && !reflect.DeepEqual(table.items, before) {
+					t.Errorf("table = %v, want it unchanged", table.items)
+				}
+				return
+			}
+
+			if previous.Email != ada.Email || moved.Email != "augusta@example.com" {
+				t.Errorf("moved %s to %s, want ada@example.com to augusta@example.com", previous.Email, moved.Email)
+			}
+			stored, err := repo.Get("augusta@example.com", ReadOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if stored.CreatedAt != ada.CreatedAt || stored.Version != ada.Version+1 || stored.Verified ||
+				stored.FirstName != ada.FirstName {
+				t.Errorf("stored %+v, want the moved record at version %d with its creation time", *stored, ada.Version+1)
+			}
+		})
+	}
+}
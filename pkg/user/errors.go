@@ -0,0 +1,146 @@
+package user
+
+import (
+	"errors"
+	"strings"
+)
+
+// Sentinel errors classifying why a user operation failed.
+// Use errors.Is to test an error returned by this package against them.
+var (
+	ErrValidation         = errors.New("validation error")
+	ErrNotFound           = errors.New("not found")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrConflict           = errors.New("conflict")
+	ErrForbidden          = errors.New("forbidden")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrGone               = errors.New("gone")
+	ErrRateLimited        = errors.New("too many requests")
+	ErrUnprocessable      = errors.New("unprocessable request")
+	ErrTooLarge           = errors.New("request too large")
+	ErrStorage            = errors.New("storage error")
+	ErrInternal           = errors.New("internal error")
+)
+
+// Error is the error type returned by the user operations.
+// It carries a client-safe message, the sentinel kind of the failure and,
+// when available, the underlying cause (e.g. the AWS SDK error).
+type Error struct {
+	Kind    error  // One of the sentinel errors above
+	Message string // Client-facing message, safe to return in API responses
+	Field   string // JSON name of the offending field, if the error concerns one
+	Err     error  // Underlying cause, if any
+}
+
+// Error returns the message followed by the underlying cause, if any.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying cause so errors.As can reach the AWS error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel kind of this error.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// newError builds an *Error of the given kind.
+//
+// Parameters:
+// - kind: The sentinel error classifying the failure.
+// - message: The client-facing message.
+// - err: The underlying cause, or nil.
+//
+// Returns:
+// - The constructed error.
+func newError(kind error, message string, err error) error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// newFieldError builds an *Error of the given kind concerning a single field.
+//
+// Parameters:
+// - kind: The sentinel error classifying the failure.
+// - message: The client-facing message.
+// - field: The JSON name of the offending field.
+// - err: The underlying cause, or nil.
+//
+// Returns:
+// - The constructed error.
+func newFieldError(kind error, message string, field string, err error) error {
+	return &Error{Kind: kind, Message: message, Field: field, Err: err}
+}
+
+// ErrorValidationFailed is the message of a request with several invalid fields, each of
+// which is described by the ValidationErrors it wraps
+var ErrorValidationFailed = "request has invalid fields"
+
+// FieldError describes one invalid field of a request
+type FieldError struct {
+	Field   string `json:"field" xml:"field"`                         // JSON name of the offending field
+	Pointer string `json:"pointer,omitempty" xml:"pointer,omitempty"` // JSON pointer of the field in the body, e.g. "/address/city"
+	Message string `json:"message" xml:"message"`                     // Why the field is invalid
+}
+
+// ValidationErrors collects the validation errors of every invalid field of a request,
+// so clients learn about all of them in one round trip rather than one at a time.
+// Use errors.As to reach it from an error returned by this package.
+type ValidationErrors []*Error
+
+// Error returns the messages of the collected errors.
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, err := range v {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Fields describes each invalid field, in the order the fields were checked.
+func (v ValidationErrors) Fields() []FieldError {
+	fields := make([]FieldError, len(v))
+	for i, err := range v {
+		fields[i] = err.FieldError()
+	}
+	return fields
+}
+
+// FieldError describes the field an error concerns.
+func (e *Error) FieldError() FieldError {
+	return FieldError{Field: e.Field, Pointer: fieldPointer(e.Field), Message: e.Message}
+}
+
+// add collects a validation error, flattening the errors of a nested check. Nil errors
+// are ignored.
+func (v *ValidationErrors) add(err error) {
+	var nested ValidationErrors
+	var userErr *Error
+	switch {
+	case err == nil:
+	case errors.As(err, &nested):
+		*v = append(*v, nested...)
+	case errors.As(err, &userErr):
+		*v = append(*v, userErr)
+	default:
+		*v = append(*v, &Error{Kind: ErrValidation, Message: ErrorInvalidUserData, Err: err})
+	}
+}
+
+// err returns the collected errors as one error: nil if there are none, the error itself
+// if there is one, and a validation error wrapping them all otherwise.
+func (v ValidationErrors) err() error {
+	switch len(v) {
+	case 0:
+		return nil
+	case 1:
+		return v[0]
+	default:
+		return newError(ErrValidation, ErrorValidationFailed, v)
+	}
+}
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"testing"
+)
+
+// TestErrorsKeepTheAWSError checks the AWS error behind a failed operation can still be
+// recovered through the wrapping, along with the kind of the failure.
+func TestErrorsKeepTheAWSError(t *testing.T) {
+	const body = `{"email": "bob@example.com", "firstname": "Bob", "lastname": "Smith"}`
+	tests := []struct {
+		name      string
+		fail      func(fake *mocks.FakeDynamo)
+		operation func(repo Repository) error
+		wantKind  error
+		wantCode  string
+	}{
+		{name: "get throttled", fail: failGet(mocks.ThrottlingError()),
+			operation: func(repo Repository) error {
+				_, err := FetchUser("ada@example.com", ReadOptions{}, repo)
+				return err
+			},
+			wantKind: ErrStorage, wantCode: dynamodb.ErrCodeProvisionedThroughputExceededException},
+		{name: "table missing", fail: failGet(mocks.ResourceNotFoundError()),
+			operation: func(repo Repository) error {
+				_, err := FetchUser("ada@example.com", ReadOptions{}, repo)
+				return err
+			},
+			wantKind: ErrStorage, wantCode: dynamodb.ErrCodeResourceNotFoundException},
+		{name: "create raced", fail: failPut(mocks.ConditionalCheckFailedError()),
+			operation: func(repo Repository) error {
+				return repo.Create(&User{Email: "bob@example.com", FirstName: "Bob", LastName: "Smith"})
+			},
+			wantKind: ErrConflict, wantCode: dynamodb.ErrCodeConditionalCheckFailedException},
+		{name: "create throttled", fail: failPut(mocks.ThrottlingError()),
+			operation: func(repo Repository) error {
+				_, err := CreateUserFromJSON(body, CreateOptions{}, repo)
+				return err
+			},
+			wantKind: ErrStorage, wantCode: dynamodb.ErrCodeProvisionedThroughputExceededException},
+		{name: "stale update", fail: failPut(mocks.ConditionalCheckFailedError()),
+			operation: func(repo Repository) error {
+				u := storedAda()
+				return repo.Update(&u, 2)
+			},
+			wantKind: ErrPreconditionFailed, wantCode: dynamodb.ErrCodeConditionalCheckFailedException},
+		{name: "delete throttled", fail: failDelete(mocks.ThrottlingError()),
+			operation: func(repo Repository) error {
+				return DeleteUser("ada@example.com", true, repo)
+			},
+			wantKind: ErrStorage, wantCode: dynamodb.ErrCodeProvisionedThroughputExceededException},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, table := newFakeTable(t, storedAda())
+			tt.fail(table.fake)
+
+			err := tt.operation(repo)
+			if !errors.Is(err, tt.wantKind) {
+				t.Fatalf("error = %v, want %v", err, tt.wantKind)
+			}
+			var aerr awserr.Error
+			if !errors.As(err, &aerr) {
+				t.Fatalf("error = %v, want it to wrap the AWS error", err)
+			}
+			if aerr.Code() != tt.wantCode {
+				t.Errorf("AWS error code = %q, want %q", aerr.Code(), tt.wantCode)
+			}
+			var userErr *Error
+			if !errors.As(err, &userErr) || userErr.Message == "" {
+				t.Errorf("error = %v, want an *Error with a client-facing message", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package user
+
+import "github.com/Vansh3140/golang-serverless/pkg/audit"
+
+// DataExport is everything stored about one user, as handed out for data-access requests
+type DataExport struct {
+	ExportedAt string        `json:"exportedAt"`      // RFC3339 time the export was produced
+	User       User          `json:"user"`            // The stored record, including its timestamps and version
+	Audit      []audit.Entry `json:"audit,omitempty"` // The user's audit entries, newest first, when auditing is on
+}
+
+// ExportUser gathers everything stored about a user.
+// The record is read consistently so the export reflects the latest write, and
+// soft-deleted users are included since their data is still stored.
+//
+// Parameters:
+// - email: The email of the user to export.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - The export document.
+// - An error if the user does not exist or cannot be fetched.
+func ExportUser(email string, repo Repository) (*DataExport, error) {
+	u, err := FetchUser(email, ReadOptions{ConsistentRead: true, IncludeDeleted: true}, repo)
+	if err != nil {
+		return nil, err
+	}
+	return &DataExport{ExportedAt: timestamp(), User: *u}, nil
+}
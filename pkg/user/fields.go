@@ -0,0 +1,92 @@
+package user
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ErrorUnknownFields is returned when a fields selection names an attribute users don't have
+var ErrorUnknownFields = "unknown fields; valid fields are: " + strings.Join(Fields, ", ")
+
+// Fields lists the JSON names of the User fields, which are also their attribute names
+var Fields = []string{"email", "firstname", "lastname", "createdAt", "updatedAt", "version", "deletedAt", "expiresAt", "address", "tags", "status", "verified", "role"}
+
+// ParseFields parses a comma-separated fields selection such as "email,firstname".
+//
+// Parameters:
+// - raw: The selection; blank entries are ignored and duplicates removed.
+//
+// Returns:
+// - The selected fields, or nil if raw selects none.
+// - A validation error if a field is unknown.
+func ParseFields(raw string) ([]string, error) {
+	var fields []string
+	seen := map[string]bool{}
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		if !isField(field) {
+			return nil, newFieldError(ErrValidation, ErrorUnknownFields, "fields", nil)
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// WithFields returns a copy of fields with the extra fields added unless already present.
+// DynamoDB rejects projections naming an attribute twice.
+//
+// Parameters:
+// - fields: The selected fields.
+// - extra: The fields to add.
+//
+// Returns:
+// - The combined selection.
+func WithFields(fields []string, extra ...string) []string {
+	combined := append([]string{}, fields...)
+	for _, field := range extra {
+		present := false
+		for _, existing := range combined {
+			present = present || existing == field
+		}
+		if !present {
+			combined = append(combined, field)
+		}
+	}
+	return combined
+}
+
+// isField reports whether name is one of Fields.
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the user as a JSON object holding only the given fields.
+//
+// Parameters:
+// - fields: The fields to keep, as returned by ParseFields.
+//
+// Returns:
+// - The selected fields keyed by their JSON names.
+func (u *User) Select(fields []string) map[string]interface{} {
+	encoded, _ := json.Marshal(u)
+	var all map[string]interface{}
+	_ = json.Unmarshal(encoded, &all)
+
+	// Fields the user doesn't have, such as an unset deletedAt, stay absent rather than null
+	selected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected
+}
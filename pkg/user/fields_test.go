@@ -0,0 +1,97 @@
+package user
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"reflect"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", raw: ""},
+		{name: "blank entries", raw: " , ,"},
+		{name: "one", raw: "email", want: []string{"email"}},
+		{name: "several in order", raw: "lastname, email,version", want: []string{"lastname", "email", "version"}},
+		{name: "duplicates", raw: "email,email", want: []string{"email"}},
+		{name: "unknown", raw: "email,password", wantErr: true},
+		{name: "case matters", raw: "Email", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFields(tt.raw)
+			if tt.wantErr {
+				var userErr *Error
+				if !errors.Is(err, ErrValidation) || !errors.As(err, &userErr) || userErr.Message != ErrorUnknownFields {
+					t.Fatalf("ParseFields(%q) error = %v, want %q", tt.raw, err, ErrorUnknownFields)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFields(%q) = %v, %v; want %v", tt.raw, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjection(t *testing.T) {
+	tests := []struct {
+		name           string
+		keyAttribute   string
+		fields         []string
+		wantExpression string
+		wantNames      map[string]string
+	}{
+		{name: "email", fields: []string{"email"}, wantExpression: "#f0",
+			wantNames: map[string]string{"#f0": "email"}},
+		{name: "reserved words", fields: []string{"email", "status", "role"}, wantExpression: "#f0, #f1, #f2",
+			wantNames: map[string]string{"#f0": "email", "#f1": "status", "#f2": "role"}},
+		{name: "names read the data key", fields: []string{"email", "firstname"}, wantExpression: "#f0, #f1, #f2",
+			wantNames: map[string]string{"#f0": "email", "#f1": "firstname", "#f2": piiKeyAttribute}},
+		{name: "custom key attribute", keyAttribute: "pk", fields: []string{"email", "version"},
+			wantExpression: "#f0, #f1", wantNames: map[string]string{"#f0": "pk", "#f1": "version"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KEY_ATTRIBUTE", tt.keyAttribute)
+			expression, names := projection(tt.fields)
+			if aws.StringValue(expression) != tt.wantExpression {
+				t.Errorf("ProjectionExpression = %q, want %q", aws.StringValue(expression), tt.wantExpression)
+			}
+			got := make(map[string]string, len(names))
+			for placeholder, name := range names {
+				got[placeholder] = aws.StringValue(name)
+			}
+			if !reflect.DeepEqual(got, tt.wantNames) {
+				t.Errorf("ExpressionAttributeNames = %v, want %v", got, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	u := storedAda()
+	tests := []struct {
+		name   string
+		fields []string
+		want   map[string]interface{}
+	}{
+		{name: "email", fields: []string{"email"}, want: map[string]interface{}{"email": "ada@example.com"}},
+		{name: "several", fields: []string{"email", "lastname", "version"},
+			want: map[string]interface{}{"email": "ada@example.com", "lastname": "Lovelace", "version": float64(2)}},
+		{name: "unset fields stay absent", fields: []string{"email", "deletedAt"},
+			want: map[string]interface{}{"email": "ada@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := u.Select(tt.fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select(%v) = %v, want %v", tt.fields, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package user
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"strings"
+)
+
+// Filter selects the users a list or count includes. Empty fields don't filter.
+type Filter struct {
+	FirstName string // Exact first name
+	LastName  string // Exact last name
+	Query     string // Substring of the first or last name
+	Domain    string // Email domain, e.g. "example.com"
+	Tags      Tags   // Exact values of tags, all of which must match
+	Status    string // Account status; users without one are active
+
+	Deleted       bool   // Only soft-deleted users; the read must include deleted users
+	DeletedBefore string // Only users soft-deleted before this RFC3339 time
+
+	CreatedAfter  string // Only users created at or after this RFC3339 time
+	CreatedBefore string // Only users created at or before this RFC3339 time
+}
+
+// IsEmpty reports whether the filter selects every user.
+func (f Filter) IsEmpty() bool {
+	return f.FirstName == "" && f.LastName == "" && f.Query == "" && f.Domain == "" && len(f.Tags) == 0 &&
+		f.Status == "" && !f.Deleted && f.DeletedBefore == "" && !f.HasCreatedRange()
+}
+
+// HasCreatedRange reports whether the filter selects on the creation time.
+func (f Filter) HasCreatedRange() bool {
+	return f.CreatedAfter != "" || f.CreatedBefore != ""
+}
+
+// Matches reports whether the user passes the filter.
+func (f Filter) Matches(u *User) bool {
+	if f.FirstName != "" && u.FirstName != f.FirstName {
+		return false
+	}
+	if f.LastName != "" && u.LastName != f.LastName {
+		return false
+	}
+	if f.Query != "" && !strings.Contains(u.FirstName, f.Query) && !strings.Contains(u.LastName, f.Query) {
+		return false
+	}
+	if f.Domain != "" && !strings.HasSuffix(strings.ToLower(u.Email), "@"+strings.ToLower(f.Domain)) {
+		return false
+	}
+	if f.Status != "" && u.CurrentStatus() != f.Status {
+		return false
+	}
+	if f.Deleted && u.DeletedAt == "" {
+		return false
+	}
+	// RFC3339 UTC timestamps sort chronologically
+	if f.DeletedBefore != "" && (u.DeletedAt == "" || u.DeletedAt >= f.DeletedBefore) {
+		return false
+	}
+	if f.CreatedAfter != "" && u.CreatedAt < f.CreatedAfter {
+		return false
+	}
+	if f.CreatedBefore != "" && (u.CreatedAt == "" || u.CreatedAt > f.CreatedBefore) {
+		return false
+	}
+	for key, value := range f.Tags {
+		if tag, ok := u.Tags[key]; !ok || tag != value {
+			return false
+		}
+	}
+	return true
+}
+
+// expression builds the scan FilterExpression applying the filter, combining its
+// conditions with AND.
+//
+// Returns:
+// - The filter expression, or nil if the filter is empty.
+// - The ExpressionAttributeNames used by the expression.
+// - The ExpressionAttributeValues used by the expression.
+func (f Filter) expression() (*string, map[string]*string, map[string]*dynamodb.AttributeValue) {
+	if f.IsEmpty() {
+		return nil, nil, nil
+	}
+
+	var conditions []string
+	names := map[string]*string{}
+	values := map[string]*dynamodb.AttributeValue{}
+	if f.FirstName != "" {
+		conditions = append(conditions, "#firstname = :firstname")
+		names["#firstname"] = aws.String("firstname")
+		values[":firstname"] = &dynamodb.AttributeValue{S: aws.String(f.FirstName)}
+	}
+	if f.LastName != "" {
+		conditions = append(conditions, "#lastname = :lastname")
+		names["#lastname"] = aws.String("lastname")
+		values[":lastname"] = &dynamodb.AttributeValue{S: aws.String(f.LastName)}
+	}
+	if f.Query != "" {
+		conditions = append(conditions, "(contains(#firstname, :q) OR contains(#lastname, :q))")
+		names["#firstname"] = aws.String("firstname")
+		names["#lastname"] = aws.String("lastname")
+		values[":q"] = &dynamodb.AttributeValue{S: aws.String(f.Query)}
+	}
+	if f.Domain != "" {
+		// DynamoDB has no ends_with, so this also matches e.g. "@example.com.au";
+		// callers needing an exact domain check Matches on the results
+		conditions = append(conditions, "contains(#email, :domain)")
+		names["#email"] = aws.String(attributeName("email"))
+		values[":domain"] = &dynamodb.AttributeValue{S: aws.String("@" + strings.ToLower(f.Domain))}
+	}
+	if f.Status != "" {
+		condition := "#status = :status"
+		if f.Status == StatusActive {
+			condition = "(attribute_not_exists(#status) OR #status = :status)"
+		}
+		conditions = append(conditions, condition)
+		names["#status"] = aws.String("status")
+		values[":status"] = &dynamodb.AttributeValue{S: aws.String(f.Status)}
+	}
+	if f.Deleted {
+		conditions = append(conditions, "attribute_exists(#deletedAt)")
+		names["#deletedAt"] = aws.String("deletedAt")
+	}
+	if f.DeletedBefore != "" {
+		conditions = append(conditions, "#deletedAt < :deletedBefore")
+		names["#deletedAt"] = aws.String("deletedAt")
+		values[":deletedBefore"] = &dynamodb.AttributeValue{S: aws.String(f.DeletedBefore)}
+	}
+	if f.HasCreatedRange() {
+		conditions = append(conditions, createdCondition(f, names, values))
+	}
+	conditions = append(conditions, tagsExpression(f.Tags, names, values)...)
+	return aws.String(strings.Join(conditions, " AND ")), names, values
+}
+
+// applyToScan adds the filter's expression to a scan input, keeping any attribute
+// names the input already has (e.g. from a projection).
+func (f Filter) applyToScan(input *dynamodb.ScanInput) {
+	expression, names, values := f.expression()
+	if expression == nil {
+		return
+	}
+	input.FilterExpression = expression
+	input.ExpressionAttributeValues = values
+	if input.ExpressionAttributeNames == nil {
+		input.ExpressionAttributeNames = map[string]*string{}
+	}
+	for placeholder, name := range names {
+		input.ExpressionAttributeNames[placeholder] = name
+	}
+}
@@ -0,0 +1,89 @@
+package user
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"testing"
+)
+
+func TestFilterExpression(t *testing.T) {
+	tests := []struct {
+		name           string
+		filter         Filter
+		wantExpression string
+		wantValues     map[string]string
+	}{
+		{name: "empty", filter: Filter{}},
+		{name: "firstname", filter: Filter{FirstName: "Ada"}, wantExpression: "#firstname = :firstname",
+			wantValues: map[string]string{":firstname": "Ada"}},
+		{name: "lastname", filter: Filter{LastName: "Lovelace"}, wantExpression: "#lastname = :lastname",
+			wantValues: map[string]string{":lastname": "Lovelace"}},
+		{name: "query", filter: Filter{Query: "ove"},
+			wantExpression: "(contains(#firstname, :q) OR contains(#lastname, :q))",
+			wantValues:     map[string]string{":q": "ove"}},
+		{name: "names combined", filter: Filter{FirstName: "Ada", LastName: "Lovelace"},
+			wantExpression: "#firstname = :firstname AND #lastname = :lastname",
+			wantValues:     map[string]string{":firstname": "Ada", ":lastname": "Lovelace"}},
+		{name: "all name filters", filter: Filter{FirstName: "Ada", LastName: "Lovelace", Query: "ove"},
+			wantExpression: "#firstname = :firstname AND #lastname = :lastname AND " +
+				"(contains(#firstname, :q) OR contains(#lastname, :q))",
+			wantValues: map[string]string{":firstname": "Ada", ":lastname": "Lovelace", ":q": "ove"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, names, values := tt.filter.expression()
+			if aws.StringValue(expression) != tt.wantExpression {
+				t.Errorf("FilterExpression = %q, want %q", aws.StringValue(expression), tt.wantExpression)
+			}
+			if len(values) != len(tt.wantValues) {
+				t.Errorf("ExpressionAttributeValues = %v, want %v", values, tt.wantValues)
+			}
+			for placeholder, want := range tt.wantValues {
+				if got := aws.StringValue(values[placeholder].S); got != want {
+					t.Errorf("%s = %q, want %q", placeholder, got, want)
+				}
+			}
+			for placeholder, name := range names {
+				if "#"+aws.StringValue(name) != placeholder {
+					t.Errorf("placeholder %s names %q", placeholder, aws.StringValue(name))
+				}
+			}
+		})
+	}
+}
+
+func TestFetchUsersFilterExpression(t *testing.T) {
+	// visible wraps a filter in the conditions hiding deleted and expired users
+	visible := func(filter string) string {
+		if filter != "" {
+			filter = "(" + filter + ") AND "
+		}
+		return "(" + filter + "attribute_not_exists(#deletedAt)) AND (attribute_not_exists(#expiresAt) OR #expiresAt > :now)"
+	}
+	tests := []struct {
+		name           string
+		filter         Filter
+		wantExpression string
+	}{
+		{name: "unfiltered", wantExpression: visible("")},
+		{name: "firstname", filter: Filter{FirstName: "Ada"}, wantExpression: visible("#firstname = :firstname")},
+		{name: "lastname and query", filter: Filter{LastName: "Lovelace", Query: "Ad"},
+			wantExpression: visible("#lastname = :lastname AND (contains(#firstname, :q) OR contains(#lastname, :q))")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := mocks.NewFakeDynamo()
+			if _, err := FetchUsers(ReadOptions{Filter: tt.filter}, NewDynamoRepository("users", fake)); err != nil {
+				t.Fatalf("FetchUsers() error = %v", err)
+			}
+			input := fake.Inputs("Scan")[0].(*dynamodb.ScanInput)
+			if got := aws.StringValue(input.FilterExpression); got != tt.wantExpression {
+				t.Errorf("FilterExpression = %q, want %q", got, tt.wantExpression)
+			}
+			if input.ExpressionAttributeValues[":now"] == nil {
+				t.Errorf("ExpressionAttributeValues = %v, want the current time", input.ExpressionAttributeValues)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strconv"
+	"time"
+)
+
+// IdempotencyTTL is how long an Idempotency-Key is remembered after the create it guarded
+const IdempotencyTTL = 24 * time.Hour
+
+// Error messages for idempotent creates
+var (
+	ErrorFailedToFetchIdempotencyKey = "failed to fetch idempotency key from dynamodb"
+	ErrorFailedToClaimIdempotencyKey = "failed to claim idempotency key in dynamodb"
+	ErrorIdempotencyKeyReused        = "idempotency key was already used with a different payload"
+	ErrorIdempotencyKeyInProgress    = "a request with this idempotency key is still in progress"
+)
+
+// idempotencyRecord remembers which user a create request with a given key produced.
+// The idempotency table uses idempotencyKey as partition key and expiresAt as TTL attribute.
+// The email is empty while the create it guards is in progress.
+type idempotencyRecord struct {
+	Key         string `json:"idempotencyKey"`  // Client-supplied Idempotency-Key header
+	RequestHash string `json:"requestHash"`     // SHA-256 of the request body
+	Email       string `json:"email,omitempty"` // Email of the user that was created
+	ExpiresAt   int64  `json:"expiresAt"`       // Epoch seconds after which DynamoDB TTL removes the record
+}
+
+// CreateUserIdempotent creates a user at most once per idempotency key.
+//
+// The key is claimed with a conditional put before the user is created, so of two
+// concurrent requests with the same key only one creates the user. Replaying a key with
+// the same payload returns the user created by the first request instead of a conflict,
+// so clients can safely retry after a timeout. Replaying a key with a different payload
+// is rejected, and so is a replay while the first request is still creating the user.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest containing the user data.
+// - key: The client-supplied idempotency key.
+// - idempotencyTable: The name of the DynamoDB table storing idempotency keys.
+// - opts: Options tuning the create.
+// - repo: The repository storing the users.
+// - dynaClient: The DynamoDB client interface, used for the idempotency table.
+//
+// Returns:
+//   - A pointer to the created (or previously created) User struct.
+//   - An error if creation fails, an ErrUnprocessable error if the key was used with a
+//     different payload, or an ErrConflict error if the key's first request is in progress.
+func CreateUserIdempotent(req events.APIGatewayProxyRequest, key string, idempotencyTable string, opts CreateOptions,
+	repo Repository, dynaClient dynamodbiface.DynamoDBAPI) (*User, error) {
+	record := &idempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash(req.Body),
+		ExpiresAt:   time.Now().Add(IdempotencyTTL).Unix(),
+	}
+
+	claimed, err := claimIdempotencyKey(record, idempotencyTable, dynaClient)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		// Replay the original result if this key was seen before
+		return replayIdempotencyKey(record, idempotencyTable, repo, dynaClient)
+	}
+
+	newUser, err := CreateUserWithOptions(req, opts, repo)
+	if err != nil {
+		// Nothing was created, so release the key for the client to retry with
+		if releaseErr := releaseIdempotencyKey(record, idempotencyTable, dynaClient); releaseErr != nil {
+			logging.Default.Error("failed to release idempotency key", logging.Fields{
+				"requestId": req.RequestContext.RequestID,
+				"error":     releaseErr,
+			})
+		}
+		return nil, err
+	}
+
+	// The user already exists at this point, so failing to finish the record
+	// must not turn the create into an error
+	record.Email = newUser.Email
+	if err := finishIdempotencyRecord(record, idempotencyTable, dynaClient); err != nil {
+		logging.Default.Error("failed to store idempotency key", logging.Fields{
+			"requestId": req.RequestContext.RequestID,
+			"email":     logging.Email(newUser.Email),
+			"error":     err,
+		})
+	}
+
+	return newUser, nil
+}
+
+// replayIdempotencyKey returns the user created under a key another request claimed.
+//
+// Returns:
+//   - The user the first request created.
+//   - An ErrUnprocessable error if the payloads differ, an ErrConflict error if the first
+//     request hasn't created the user yet, or an error if a lookup fails.
+func replayIdempotencyKey(record *idempotencyRecord, idempotencyTable string, repo Repository,
+	dynaClient dynamodbiface.DynamoDBAPI) (*User, error) {
+	stored, err := fetchIdempotencyRecord(record.Key, idempotencyTable, dynaClient)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		// The claim expired or was released between the put and the read
+		return nil, newError(ErrConflict, ErrorIdempotencyKeyInProgress, nil)
+	}
+	if stored.RequestHash != record.RequestHash {
+		return nil, newError(ErrUnprocessable, ErrorIdempotencyKeyReused, nil)
+	}
+	if stored.Email == "" {
+		return nil, newError(ErrConflict, ErrorIdempotencyKeyInProgress, nil)
+	}
+	// The user may have been created moments ago, so don't risk a stale read
+	return FetchUser(stored.Email, ReadOptions{ConsistentRead: true}, repo)
+}
+
+// fetchIdempotencyRecord looks up an unexpired idempotency record.
+// Expired records are ignored because DynamoDB TTL deletion can lag behind.
+//
+// Returns:
+// - The record, or nil if the key is unknown or expired.
+// - An error if the lookup fails.
+func fetchIdempotencyRecord(key string, idempotencyTable string,
+	dynaClient dynamodbiface.DynamoDBAPI) (*idempotencyRecord, error) {
+	input := &dynamodb.GetItemInput{
+		Key:                    idempotencyKey(key),
+		TableName:              aws.String(idempotencyTable),
+		ConsistentRead:         aws.Bool(true),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+
+	result, err := dynaClient.GetItem(input)
+	if err != nil {
+		return nil, newError(ErrStorage, ErrorFailedToFetchIdempotencyKey, err)
+	}
+	if len(result.Item) == 0 {
+		return nil, nil
+	}
+
+	record := new(idempotencyRecord)
+	if err := dynamodbattribute.UnmarshalMap(result.Item, record); err != nil {
+		return nil, newError(ErrInternal, ErrorFailedToUnmarshalRecord, err)
+	}
+	if record.ExpiresAt <= time.Now().Unix() {
+		return nil, nil
+	}
+	return record, nil
+}
+
+// claimIdempotencyKey stores a record without an email, unless an unexpired record for
+// the key exists. Expired records may still be stored, as DynamoDB TTL deletion can lag
+// behind, and are claimed over.
+//
+// Returns:
+// - true if the key was claimed, false if another request holds it.
+// - An error if the put fails.
+func claimIdempotencyKey(record *idempotencyRecord, idempotencyTable string,
+	dynaClient dynamodbiface.DynamoDBAPI) (bool, error) {
+	item, err := dynamodbattribute.MarshalMap(record)
+	if err != nil {
+		return false, newError(ErrInternal, ErrorCouldNotMarshalItem, err)
+	}
+
+	_, err = dynaClient.PutItem(&dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String(idempotencyTable),
+		ConditionExpression: aws.String("attribute_not_exists(idempotencyKey) OR expiresAt <= :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))},
+		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return false, nil
+		}
+		return false, newError(ErrStorage, ErrorFailedToClaimIdempotencyKey, err)
+	}
+	return true, nil
+}
+
+// finishIdempotencyRecord records the email of the user created under a claimed key.
+func finishIdempotencyRecord(record *idempotencyRecord, idempotencyTable string,
+	dynaClient dynamodbiface.DynamoDBAPI) error {
+	_, err := dynaClient.UpdateItem(&dynamodb.UpdateItemInput{
+		Key:                 idempotencyKey(record.Key),
+		TableName:           aws.String(idempotencyTable),
+		UpdateExpression:    aws.String("SET email = :email"),
+		ConditionExpression: aws.String("requestHash = :hash"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":email": {S: aws.String(record.Email)},
+			":hash":  {S: aws.String(record.RequestHash)},
+		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	return err
+}
+
+// releaseIdempotencyKey deletes a claim whose create failed, if it is still this request's.
+func releaseIdempotencyKey(record *idempotencyRecord, idempotencyTable string,
+	dynaClient dynamodbiface.DynamoDBAPI) error {
+	_, err := dynaClient.DeleteItem(&dynamodb.DeleteItemInput{
+		Key:                 idempotencyKey(record.Key),
+		TableName:           aws.String(idempotencyTable),
+		ConditionExpression: aws.String("requestHash = :hash AND attribute_not_exists(email)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":hash": {S: aws.String(record.RequestHash)},
+		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if isConditionalCheckFailed(err) {
+		return nil
+	}
+	return err
+}
+
+// requestHash returns the SHA-256 of a request body, hex encoded.
+func requestHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyKey returns the key of an idempotency record.
+func idempotencyKey(key string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{"idempotencyKey": {S: aws.String(key)}}
+}
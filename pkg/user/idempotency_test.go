@@ -0,0 +1,156 @@
+package user
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"testing"
+	"time"
+)
+
+// newIdempotencyTable returns a FakeDynamo keeping idempotency records in a map, applying
+// the conditions CreateUserIdempotent writes with like a table would.
+func newIdempotencyTable(t *testing.T, records ...idempotencyRecord) (*mocks.FakeDynamo, map[string]*idempotencyRecord) {
+	t.Helper()
+	table := map[string]*idempotencyRecord{}
+	for i := range records {
+		table[records[i].Key] = &records[i]
+	}
+	fake := mocks.NewFakeDynamo()
+	fake.OnGetItem(func(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+		record := table[aws.StringValue(in.Key["idempotencyKey"].S)]
+		if record == nil {
+			return &dynamodb.GetItemOutput{}, nil
+		}
+		item, err := dynamodbattribute.MarshalMap(record)
+		return &dynamodb.GetItemOutput{Item: item}, err
+	})
+	fake.OnPutItem(func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+		record := new(idempotencyRecord)
+		if err := dynamodbattribute.UnmarshalMap(in.Item, record); err != nil {
+			return nil, err
+		}
+		if stored := table[record.Key]; stored != nil && stored.ExpiresAt > time.Now().Unix() {
+			return nil, mocks.ConditionalCheckFailedError()
+		}
+		table[record.Key] = record
+		return &dynamodb.PutItemOutput{}, nil
+	})
+	fake.OnUpdateItem(func(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+		record := table[aws.StringValue(in.Key["idempotencyKey"].S)]
+		if record == nil || record.RequestHash != aws.StringValue(in.ExpressionAttributeValues[":hash"].S) {
+			return nil, mocks.ConditionalCheckFailedError()
+		}
+		record.Email = aws.StringValue(in.ExpressionAttributeValues[":email"].S)
+		return &dynamodb.UpdateItemOutput{}, nil
+	})
+	fake.OnDeleteItem(func(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+		key := aws.StringValue(in.Key["idempotencyKey"].S)
+		if record := table[key]; record == nil || record.Email != "" {
+			return nil, mocks.ConditionalCheckFailedError()
+		}
+		delete(table, key)
+		return &dynamodb.DeleteItemOutput{}, nil
+	})
+	return fake, table
+}
+
+func TestCreateUserIdempotent(t *testing.T) {
+	const body = `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`
+	sameHash := requestHash(body)
+	later := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name       string
+		stored     []idempotencyRecord
+		existing   bool
+		body       string
+		throttled  bool
+		wantErr    error
+		wantUser   bool
+		wantRecord bool
+	}{
+		{name: "first request", body: body, wantUser: true, wantRecord: true},
+		{name: "replay", stored: []idempotencyRecord{{Key: "k", RequestHash: sameHash, Email: "ada@example.com", ExpiresAt: later}},
+			existing: true, body: body, wantUser: true, wantRecord: true},
+		{name: "different payload", stored: []idempotencyRecord{{Key: "k", RequestHash: "other", Email: "ada@example.com", ExpiresAt: later}},
+			existing: true, body: body, wantErr: ErrUnprocessable, wantUser: true, wantRecord: true},
+		{name: "first request in progress", stored: []idempotencyRecord{{Key: "k", RequestHash: sameHash, ExpiresAt: later}},
+			body: body, wantErr: ErrConflict, wantRecord: true},
+		{name: "expired key", stored: []idempotencyRecord{{Key: "k", RequestHash: "other", Email: "bob@example.com", ExpiresAt: 1}},
+			body: body, wantUser: true, wantRecord: true},
+		{name: "failed create releases the key", body: `{"email": "ada@example.com"}`, wantErr: ErrValidation},
+		{name: "claim throttled", body: body, throttled: true, wantErr: ErrStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMemoryRepository()
+			if tt.existing {
+				if err := repo.Create(&User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 1}); err != nil {
+					t.Fatalf("seeding: %v", err)
+				}
+			}
+			fake, table := newIdempotencyTable(t, tt.stored...)
+			if tt.throttled {
+				fake.OnPutItem(func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+					return nil, mocks.ThrottlingError()
+				})
+			}
+
+			req := events.APIGatewayProxyRequest{Body: tt.body}
+			created, err := CreateUserIdempotent(req, "k", "idempotency", CreateOptions{}, repo, fake)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateUserIdempotent() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && created.Email != "ada@example.com" {
+				t.Errorf("user = %q, want ada@example.com", created.Email)
+			}
+			if _, err := repo.Get("ada@example.com", ReadOptions{}); (err == nil) != tt.wantUser {
+				t.Errorf("stored user error = %v, want stored %v", err, tt.wantUser)
+			}
+			record := table["k"]
+			if (record != nil) != tt.wantRecord {
+				t.Fatalf("record = %+v, want stored %v", record, tt.wantRecord)
+			}
+			if tt.wantErr == nil && record.Email != "ada@example.com" {
+				t.Errorf("record email = %q, want the created user's", record.Email)
+			}
+		})
+	}
+}
+
+func TestCreateUserIdempotentClaimsFirst(t *testing.T) {
+	const body = `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`
+	repo := NewMemoryRepository()
+	fake, table := newIdempotencyTable(t)
+
+	// Two requests with one key: the second finds the first's claim before any user exists
+	var replayErr error
+	fake.OnPutItem(func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+		if _, err := repo.Get("ada@example.com", ReadOptions{}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("user stored before the key was claimed: %v", err)
+		}
+		if len(table) == 0 {
+			record := new(idempotencyRecord)
+			if err := dynamodbattribute.UnmarshalMap(in.Item, record); err != nil {
+				return nil, err
+			}
+			table[record.Key] = record
+			_, replayErr = CreateUserIdempotent(events.APIGatewayProxyRequest{Body: body}, "k", "idempotency",
+				CreateOptions{}, repo, fake)
+			return &dynamodb.PutItemOutput{}, nil
+		}
+		return nil, mocks.ConditionalCheckFailedError()
+	})
+
+	if _, err := CreateUserIdempotent(events.APIGatewayProxyRequest{Body: body}, "k", "idempotency",
+		CreateOptions{}, repo, fake); err != nil {
+		t.Fatalf("CreateUserIdempotent() error = %v", err)
+	}
+	if !errors.Is(replayErr, ErrConflict) {
+		t.Errorf("concurrent request error = %v, want ErrConflict", replayErr)
+	}
+}
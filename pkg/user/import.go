@@ -0,0 +1,213 @@
+package user
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MaxImportRows is the most users a single CSV import accepts
+const MaxImportRows = 1000
+
+// Ways an import treats emails that already exist
+const (
+	ImportSkip      = "skip"
+	ImportOverwrite = "overwrite"
+)
+
+// BatchUpdated is the outcome of an imported row that replaced an existing user
+const BatchUpdated = "updated"
+
+// Error messages for CSV imports
+var (
+	ErrorEmptyImport       = "CSV contains no users"
+	ErrorImportTooLarge    = "too many rows in one import; the limit is " + strconv.Itoa(MaxImportRows)
+	ErrorMissingCSVColumn  = "CSV header is missing the column"
+	ErrorDuplicateColumn   = "CSV header repeats the column"
+	ErrorMalformedCSV      = "malformed CSV"
+	ErrorInvalidOnConflict = "onConflict must be skip or overwrite"
+)
+
+// ImportResult is the outcome of one row of a CSV import
+type ImportResult struct {
+	Line int `json:"line"` // Line of the row in the CSV, counting the header as line 1
+	BatchResult
+}
+
+// ImportCSV creates users from a CSV document, reporting the outcome of each row.
+//
+// The header row must name the email, firstname and lastname columns, in any order;
+// other columns (e.g. createdAt from an export) are ignored. Every row is validated like
+// a single create. Existing emails are skipped or, with ImportOverwrite, replaced while
+// keeping their creation time. Rows are written in batches, which can't be conditional.
+//
+// Parameters:
+// - body: The CSV document.
+// - onConflict: ImportSkip or ImportOverwrite.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The outcome of each row, in document order.
+//   - A validation error naming the line of malformed CSV, ErrTooLarge for too many rows,
+//     or an error if the existence check fails.
+func ImportCSV(body string, onConflict string, repo Repository) ([]ImportResult, error) {
+	if onConflict != ImportSkip && onConflict != ImportOverwrite {
+		return nil, newFieldError(ErrValidation, ErrorInvalidOnConflict, "onConflict", nil)
+	}
+
+	users, lines, err := parseCSV(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate every row, skipping repeated emails
+	results := make([]ImportResult, len(users))
+	seen := map[string]bool{}
+	var candidates []string
+	for i := range users {
+		results[i].Line = lines[i]
+		users[i].Email = NormalizeEmail(users[i].Email)
+		email := users[i].Email
+		if err := users[i].Validate(); err != nil {
+			results[i].BatchResult = failedResult(email, err)
+			continue
+		}
+		if seen[email] {
+			results[i].BatchResult = BatchResult{Email: email, Status: BatchSkipped, Error: ErrorDuplicateInBatch}
+			continue
+		}
+		seen[email] = true
+		candidates = append(candidates, email)
+	}
+
+	existing, err := repo.GetMany(candidates, ReadOptions{ConsistentRead: true})
+	if err != nil {
+		return nil, err
+	}
+	byEmail := make(map[string]User, len(existing))
+	for _, u := range existing {
+		byEmail[u.Email] = u
+	}
+
+	now := timestamp()
+	var toWrite []User
+	var indexes []int
+	for i := range users {
+		if results[i].Status != "" {
+			continue
+		}
+		current, exists := byEmail[users[i].Email]
+		switch {
+		case exists && onConflict == ImportSkip:
+			results[i].BatchResult = BatchResult{Email: users[i].Email, Status: BatchSkipped, Error: ErrorUserAlreadyExists}
+			continue
+		case exists:
+			users[i].CreatedAt, users[i].UpdatedAt, users[i].Version = current.CreatedAt, now, current.Version+1
+		default:
+			// Only new users have to meet the domain policies
+			if err := checkEmailDomain(users[i].Email); err != nil {
+				results[i].BatchResult = failedResult(users[i].Email, err)
+				continue
+			}
+			users[i].CreatedAt, users[i].UpdatedAt, users[i].Version = now, now, 1
+		}
+		toWrite = append(toWrite, users[i])
+		indexes = append(indexes, i)
+	}
+
+	failed := repo.PutMany(toWrite)
+	for _, i := range indexes {
+		email := users[i].Email
+		switch err, ok := failed[email]; {
+		case ok:
+			results[i].BatchResult = failedResult(email, err)
+		case byEmail[email].Email != "":
+			results[i].BatchResult = BatchResult{Email: email, Status: BatchUpdated, User: &users[i]}
+		default:
+			results[i].BatchResult = BatchResult{Email: email, Status: BatchCreated, User: &users[i]}
+		}
+	}
+	for i := range results {
+		results[i].Index = i
+	}
+	return results, nil
+}
+
+// parseCSV reads the users from a CSV document with a header row.
+//
+// Returns:
+// - The users, in document order.
+// - The line of each user, counting the header as line 1.
+// - A validation error naming the offending line if the document is malformed.
+func parseCSV(body string) ([]User, []int, error) {
+	reader := csv.NewReader(strings.NewReader(body))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, newError(ErrValidation, ErrorEmptyImport, nil)
+	}
+	if err != nil {
+		return nil, nil, malformedCSV(err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok {
+			return nil, nil, newError(ErrValidation, ErrorDuplicateColumn+" "+name, nil)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"email", "firstname", "lastname"} {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, newError(ErrValidation, ErrorMissingCSVColumn+" "+name, nil)
+		}
+	}
+
+	var users []User
+	var lines []int
+	line := 2
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, malformedCSV(err)
+		}
+		if len(users) == MaxImportRows {
+			return nil, nil, newError(ErrTooLarge, ErrorImportTooLarge, nil)
+		}
+		u := User{
+			Email:     record[columns["email"]],
+			FirstName: record[columns["firstname"]],
+			LastName:  record[columns["lastname"]],
+		}
+		u.sanitize()
+		users = append(users, u)
+		lines = append(lines, line)
+
+		// A row spans more than one line when a quoted value contains line breaks
+		line++
+		for _, value := range record {
+			line += strings.Count(value, "\n")
+		}
+	}
+	if len(users) == 0 {
+		return nil, nil, newError(ErrValidation, ErrorEmptyImport, nil)
+	}
+	return users, lines, nil
+}
+
+// malformedCSV builds the validation error for a CSV parse failure, naming its line.
+func malformedCSV(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return newError(ErrValidation, fmt.Sprintf("%s on line %d: %v", ErrorMalformedCSV, parseErr.Line, parseErr.Err), err)
+	}
+	return newError(ErrValidation, ErrorMalformedCSV, err)
+}
@@ -0,0 +1,123 @@
+package user
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"os"
+)
+
+// lastNameIndex returns the name of the global secondary index partitioned by lastname
+// (LASTNAME_INDEX), or an empty string if there is none.
+func lastNameIndex() string {
+	return os.Getenv("LASTNAME_INDEX")
+}
+
+// usesLastNameIndex reports whether a list can be served by querying the lastname index:
+// the index must be configured and the filter must select on lastname only.
+func usesLastNameIndex(filter Filter) bool {
+	return lastNameIndex() != "" && filter.LastName != "" && filter.FirstName == "" && filter.Query == "" &&
+		filter.Domain == "" && len(filter.Tags) == 0 && filter.Status == "" &&
+		!filter.Deleted && filter.DeletedBefore == "" && !filter.HasCreatedRange()
+}
+
+// queryByLastName reads the users with the given last name from the lastname index,
+// paging until the index is exhausted or maxItems users were read.
+//
+// Parameters:
+// - opts: Options tuning the read; consistent reads are rejected because GSIs don't support them.
+// - maxItems: The maximum number of items to return.
+//
+// Returns:
+// - The items in index order.
+// - Whether items were left unread because of maxItems.
+// - An error if the query fails or a consistent read was requested.
+func (r *DynamoRepository) queryByLastName(opts ReadOptions, maxItems int) (
+	[]map[string]*dynamodb.AttributeValue, bool, error) {
+	if opts.ConsistentRead {
+		return nil, false, newFieldError(ErrValidation, ErrorConsistentReadOnIndex, "consistent", nil)
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.TableName),
+		IndexName:              aws.String(lastNameIndex()),
+		KeyConditionExpression: aws.String("#lastname = :lastname"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":lastname": {S: aws.String(opts.Filter.LastName)},
+		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+	if len(opts.Fields) > 0 {
+		input.ProjectionExpression, input.ExpressionAttributeNames = projection(opts.Fields)
+	} else {
+		input.ExpressionAttributeNames = map[string]*string{}
+	}
+	input.ExpressionAttributeNames["#lastname"] = aws.String("lastname")
+	input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues = visibleOnly(opts,
+		nil, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+
+	return r.query(input, maxItems)
+}
+
+// entityQuery turns the scan of a list or count into a query of the entity index for the
+// user profiles, keeping the scan's projection, filter and Select. It serves lists and
+// counts in single-table mode, where a scan would read every other entity too.
+//
+// Parameters:
+// - scan: The scan input; it is not modified.
+//
+// Returns:
+// - The query input.
+// - A validation error if the scan is consistent, since GSIs don't support consistent reads.
+func entityQuery(scan *dynamodb.ScanInput) (*dynamodb.QueryInput, error) {
+	if aws.BoolValue(scan.ConsistentRead) {
+		return nil, newFieldError(ErrValidation, ErrorConsistentReadOnIndex, "consistent", nil)
+	}
+
+	names := map[string]*string{"#entityType": aws.String(EntityTypeAttribute)}
+	for placeholder, name := range scan.ExpressionAttributeNames {
+		names[placeholder] = name
+	}
+	values := map[string]*dynamodb.AttributeValue{":entityType": {S: aws.String(userEntityType())}}
+	for placeholder, value := range scan.ExpressionAttributeValues {
+		values[placeholder] = value
+	}
+	return &dynamodb.QueryInput{
+		TableName:                 scan.TableName,
+		IndexName:                 aws.String(EntityIndex()),
+		KeyConditionExpression:    aws.String("#entityType = :entityType"),
+		FilterExpression:          scan.FilterExpression,
+		ProjectionExpression:      scan.ProjectionExpression,
+		Select:                    scan.Select,
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ReturnConsumedCapacity:    scan.ReturnConsumedCapacity,
+	}, nil
+}
+
+// query pages through a query until it is exhausted or maxItems items were read.
+//
+// Returns:
+// - The items in index order.
+// - Whether items were left unread because of maxItems.
+// - An error if the query fails.
+func (r *DynamoRepository) query(input *dynamodb.QueryInput, maxItems int) (
+	[]map[string]*dynamodb.AttributeValue, bool, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	for {
+		result, err := r.DynaClient.Query(input)
+		if err != nil {
+			return nil, false, newError(ErrStorage, ErrorFailedToFetchRecord, err)
+		}
+		items = append(items, result.Items...)
+		if len(items) > maxItems {
+			return items[:maxItems], true, nil
+		}
+		if len(result.LastEvaluatedKey) == 0 {
+			return items, false, nil
+		}
+		if len(items) == maxItems {
+			return items, true, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
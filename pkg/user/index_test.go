@@ -0,0 +1,85 @@
+package user
+
+import (
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"strconv"
+	"testing"
+)
+
+func TestFetchUsersQueriesTheLastNameIndex(t *testing.T) {
+	tests := []struct {
+		name      string
+		index     string
+		filter    Filter
+		wantQuery bool
+	}{
+		{name: "no index", filter: Filter{LastName: "Lovelace"}},
+		{name: "index", index: "lastname-index", filter: Filter{LastName: "Lovelace"}, wantQuery: true},
+		{name: "index, other filters", index: "lastname-index", filter: Filter{LastName: "Lovelace", FirstName: "Ada"}},
+		{name: "index, no lastname", index: "lastname-index", filter: Filter{FirstName: "Ada"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LASTNAME_INDEX", tt.index)
+			fake := mocks.NewFakeDynamo()
+			// Both read operations serve three pages of one user
+			fake.OnQuery(func(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+				items, next := lastNamePage(in.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{Items: items, LastEvaluatedKey: next}, nil
+			})
+			fake.OnScan(func(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+				items, next := lastNamePage(in.ExclusiveStartKey)
+				return &dynamodb.ScanOutput{Items: items, LastEvaluatedKey: next}, nil
+			})
+
+			page, err := FetchUsers(ReadOptions{Filter: tt.filter}, NewDynamoRepository("users", fake))
+			if err != nil {
+				t.Fatalf("FetchUsers() error = %v", err)
+			}
+			if len(page.Users) != 3 {
+				t.Errorf("FetchUsers() returned %d users, want all 3 pages", len(page.Users))
+			}
+
+			queries, scans := fake.Inputs("Query"), fake.Inputs("Scan")
+			if !tt.wantQuery {
+				if len(queries) != 0 || len(scans) != 3 {
+					t.Errorf("%d queries and %d scans, want 3 scans", len(queries), len(scans))
+				}
+				return
+			}
+			if len(queries) != 3 || len(scans) != 0 {
+				t.Fatalf("%d queries and %d scans, want 3 queries", len(queries), len(scans))
+			}
+			in := queries[0].(*dynamodb.QueryInput)
+			if aws.StringValue(in.IndexName) != tt.index || aws.StringValue(in.TableName) != "users" {
+				t.Errorf("query of table %q, index %q; want users, %q", aws.StringValue(in.TableName),
+					aws.StringValue(in.IndexName), tt.index)
+			}
+			if aws.StringValue(in.KeyConditionExpression) != "#lastname = :lastname" ||
+				aws.StringValue(in.ExpressionAttributeValues[":lastname"].S) != tt.filter.LastName {
+				t.Errorf("KeyConditionExpression = %q with %v, want the last name", aws.StringValue(in.KeyConditionExpression),
+					in.ExpressionAttributeValues[":lastname"])
+			}
+		})
+	}
+}
+
+// lastNamePage returns the page of a three-page read starting at a key, and the key of the
+// next page.
+func lastNamePage(start map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue,
+	map[string]*dynamodb.AttributeValue) {
+	page := 0
+	if start != nil {
+		page, _ = strconv.Atoi(aws.StringValue(start["page"].N))
+	}
+	items := []map[string]*dynamodb.AttributeValue{{
+		"email":    {S: aws.String("user" + strconv.Itoa(page) + "@example.com")},
+		"lastname": {S: aws.String("Lovelace")},
+	}}
+	if page == 2 {
+		return items, nil
+	}
+	return items, map[string]*dynamodb.AttributeValue{"page": {N: aws.String(strconv.Itoa(page + 1))}}
+}
@@ -0,0 +1,200 @@
+package user
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"os"
+	"strings"
+)
+
+// DefaultKeyAttribute is the partition key attribute of the users table unless KEY_ATTRIBUTE
+// is set
+const DefaultKeyAttribute = "email"
+
+// Attributes of the items in single-table mode
+const (
+	PartitionKeyAttribute = "PK"         // Partition key, e.g. "USER#ada@example.com"
+	SortKeyAttribute      = "SK"         // Sort key, e.g. "PROFILE"
+	EntityTypeAttribute   = "entityType" // Kind of entity an item holds, partitioning the entity index
+)
+
+// Defaults of the single-table layout, overridden by USER_KEY_PREFIX, USER_SORT_KEY,
+// USER_ENTITY_TYPE and ENTITY_INDEX
+const (
+	DefaultUserKeyPrefix  = "USER#"
+	DefaultUserSortKey    = "PROFILE"
+	DefaultUserEntityType = "USER"
+	DefaultEntityIndex    = "entityType-index"
+)
+
+// keyPlaceholder stands for the key attribute in condition expressions, so any name can be
+// configured, even a reserved word
+const keyPlaceholder = "#key"
+
+// SingleTable reports whether users share their table with other entities (SINGLE_TABLE=true),
+// stored under PK=USER#<email> and SK=PROFILE rather than keyed by the email alone.
+func SingleTable() bool {
+	return os.Getenv("SINGLE_TABLE") == "true"
+}
+
+// userKeyPrefix returns the prefix of the partition keys of users in single-table mode.
+func userKeyPrefix() string {
+	if prefix := os.Getenv("USER_KEY_PREFIX"); prefix != "" {
+		return prefix
+	}
+	return DefaultUserKeyPrefix
+}
+
+// userSortKey returns the sort key of user profiles in single-table mode.
+func userSortKey() string {
+	if sortKey := os.Getenv("USER_SORT_KEY"); sortKey != "" {
+		return sortKey
+	}
+	return DefaultUserSortKey
+}
+
+// userEntityType returns the entityType of user profiles in single-table mode.
+func userEntityType() string {
+	if entityType := os.Getenv("USER_ENTITY_TYPE"); entityType != "" {
+		return entityType
+	}
+	return DefaultUserEntityType
+}
+
+// EntityIndex returns the name of the global secondary index partitioned by entityType,
+// which lists users in single-table mode (ENTITY_INDEX, DefaultEntityIndex if unset).
+func EntityIndex() string {
+	if index := os.Getenv("ENTITY_INDEX"); index != "" {
+		return index
+	}
+	return DefaultEntityIndex
+}
+
+// KeyAttribute returns the name of the table's partition key attribute: PK in single-table
+// mode, and otherwise the attribute holding the email of each user (KEY_ATTRIBUTE,
+// DefaultKeyAttribute if unset). The JSON field stays "email" whatever the attribute is called.
+func KeyAttribute() string {
+	if SingleTable() {
+		return PartitionKeyAttribute
+	}
+	if name := os.Getenv("KEY_ATTRIBUTE"); name != "" {
+		return name
+	}
+	return DefaultKeyAttribute
+}
+
+// attributeName returns the attribute a field of Fields is stored under: the key attribute
+// for the email, unless single-table items keep it in "email", and the field's own name for
+// the others.
+func attributeName(field string) string {
+	if field == "email" && !SingleTable() {
+		return KeyAttribute()
+	}
+	return field
+}
+
+// PartitionKey returns the single-table partition key of the user with the email, e.g.
+// "USER#ada@example.com", which other items about the user can share.
+func PartitionKey(email string) string {
+	return userKeyPrefix() + email
+}
+
+// emailKey returns the DynamoDB key of the user stored under the email.
+func emailKey(email string) map[string]*dynamodb.AttributeValue {
+	if SingleTable() {
+		return map[string]*dynamodb.AttributeValue{
+			PartitionKeyAttribute: {S: aws.String(PartitionKey(email))},
+			SortKeyAttribute:      {S: aws.String(userSortKey())},
+		}
+	}
+	return map[string]*dynamodb.AttributeValue{KeyAttribute(): {S: aws.String(email)}}
+}
+
+// IsUserKey reports whether the key of an item, e.g. from a stream record, is the key of a
+// user rather than of another entity sharing the table.
+func IsUserKey(key map[string]*dynamodb.AttributeValue) bool {
+	if !SingleTable() {
+		return true
+	}
+	partition, sort := key[PartitionKeyAttribute], key[SortKeyAttribute]
+	return partition != nil && sort != nil && strings.HasPrefix(aws.StringValue(partition.S), userKeyPrefix()) &&
+		aws.StringValue(sort.S) == userSortKey()
+}
+
+// KeyEmail returns the email of the user an item key identifies.
+//
+// Parameters:
+// - key: The key, or a whole item.
+//
+// Returns:
+// - The email, or an empty string if the key doesn't hold one.
+func KeyEmail(key map[string]*dynamodb.AttributeValue) string {
+	value, ok := key[KeyAttribute()]
+	if !ok {
+		return ""
+	}
+	if SingleTable() {
+		return strings.TrimPrefix(aws.StringValue(value.S), userKeyPrefix())
+	}
+	return aws.StringValue(value.S)
+}
+
+// withKeyName adds keyPlaceholder to the ExpressionAttributeNames of a condition.
+//
+// Parameters:
+// - names: The names the expression already uses, or nil.
+//
+// Returns:
+// - The names, including keyPlaceholder.
+func withKeyName(names map[string]*string) map[string]*string {
+	if names == nil {
+		names = map[string]*string{}
+	}
+	names[keyPlaceholder] = aws.String(KeyAttribute())
+	return names
+}
+
+// toTableKeys stores the email of a marshaled user under the table's keys, in place: in the
+// key attribute, or in single-table mode in PK and SK next to "email", with the entityType.
+// Outside single-table mode, items get the entityType too when CREATED_INDEX is set.
+func toTableKeys(item map[string]*dynamodb.AttributeValue) {
+	email, ok := item["email"]
+	if !ok {
+		return
+	}
+	if SingleTable() {
+		for name, value := range emailKey(aws.StringValue(email.S)) {
+			item[name] = value
+		}
+		item[EntityTypeAttribute] = &dynamodb.AttributeValue{S: aws.String(userEntityType())}
+		return
+	}
+	if key := KeyAttribute(); key != "email" {
+		delete(item, "email")
+		item[key] = email
+	}
+	// The creation index is partitioned by entityType, which only single-table items have otherwise
+	if createdIndex() != "" {
+		item[EntityTypeAttribute] = &dynamodb.AttributeValue{S: aws.String(userEntityType())}
+	}
+}
+
+// fromTableKeys undoes toTableKeys on an item read from the table, in place, so it
+// unmarshals to a user.
+func fromTableKeys(item map[string]*dynamodb.AttributeValue) {
+	if SingleTable() {
+		if _, ok := item["email"]; !ok && item[PartitionKeyAttribute] != nil {
+			item["email"] = &dynamodb.AttributeValue{S: aws.String(KeyEmail(item))}
+		}
+		delete(item, PartitionKeyAttribute)
+		delete(item, SortKeyAttribute)
+		delete(item, EntityTypeAttribute)
+		return
+	}
+	delete(item, EntityTypeAttribute)
+	key := KeyAttribute()
+	if email, ok := item[key]; ok && key != "email" {
+		delete(item, key)
+		item["email"] = email
+	}
+}
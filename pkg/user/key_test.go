@@ -0,0 +1,101 @@
+package user
+
+import (
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"testing"
+)
+
+func TestCRUDWithKeyAttribute(t *testing.T) {
+	tests := []struct {
+		name         string
+		keyAttribute string
+		wantKey      string
+	}{
+		{name: "default", wantKey: "email"},
+		{name: "pk", keyAttribute: "pk", wantKey: "pk"},
+		{name: "reserved word", keyAttribute: "name", wantKey: "name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KEY_ATTRIBUTE", tt.keyAttribute)
+			repo, table := newFakeTable(t)
+
+			// checkStored asserts Ada is stored under the key attribute alone, with the last name
+			checkStored := func(step string, wantLastName string) {
+				t.Helper()
+				item := table.items["ada@example.com"]
+				if item == nil {
+					t.Fatalf("%s: nothing stored under %s=ada@example.com", step, tt.wantKey)
+				}
+				if aws.StringValue(item[tt.wantKey].S) != "ada@example.com" {
+					t.Errorf("%s: stored %s = %v, want the email", step, tt.wantKey, item[tt.wantKey])
+				}
+				if _, ok := item["email"]; ok && tt.wantKey != "email" {
+					t.Errorf("%s: stored item has an email attribute besides %s", step, tt.wantKey)
+				}
+				if aws.StringValue(item["lastname"].S) != wantLastName {
+					t.Errorf("%s: stored lastname = %v, want %q", step, item["lastname"], wantLastName)
+				}
+			}
+
+			created, err := CreateUserFromJSON(`{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`,
+				CreateOptions{}, repo)
+			if err != nil {
+				t.Fatalf("CreateUserFromJSON() error = %v", err)
+			}
+			if created.Email != "ada@example.com" {
+				t.Errorf("created email = %q, want ada@example.com", created.Email)
+			}
+			checkStored("create", "Lovelace")
+			if _, err := CreateUserFromJSON(`{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`,
+				CreateOptions{}, repo); !errors.Is(err, ErrConflict) {
+				t.Errorf("second CreateUserFromJSON() error = %v, want %v", err, ErrConflict)
+			}
+
+			got, err := FetchUser("ada@example.com", ReadOptions{}, repo)
+			if err != nil || got.Email != "ada@example.com" || got.LastName != "Lovelace" {
+				t.Fatalf("FetchUser() = %+v, %v; want Ada Lovelace", got, err)
+			}
+
+			if _, _, err := UpdateUser(events.APIGatewayProxyRequest{Body: `{"firstname": "Ada", "lastname": "King"}`},
+				"ada@example.com", 0, repo); err != nil {
+				t.Fatalf("UpdateUser() error = %v", err)
+			}
+			checkStored("update", "King")
+
+			if err := DeleteUser("ada@example.com", true, repo); err != nil {
+				t.Fatalf("DeleteUser() error = %v", err)
+			}
+			if len(table.items) != 0 {
+				t.Errorf("items after the delete = %v, want none", table.items)
+			}
+			if _, err := FetchUser("ada@example.com", ReadOptions{}, repo); !errors.Is(err, ErrNotFound) {
+				t.Errorf("FetchUser() after the delete error = %v, want %v", err, ErrNotFound)
+			}
+
+			// Every key sent and every condition on the key names the configured attribute
+			for _, call := range table.fake.Calls() {
+				var key map[string]*dynamodb.AttributeValue
+				var names map[string]*string
+				switch in := call.Input.(type) {
+				case *dynamodb.GetItemInput:
+					key = in.Key
+				case *dynamodb.DeleteItemInput:
+					key, names = in.Key, in.ExpressionAttributeNames
+				case *dynamodb.PutItemInput:
+					names = in.ExpressionAttributeNames
+				}
+				if key != nil && (len(key) != 1 || key[tt.wantKey] == nil) {
+					t.Errorf("%s key = %v, want only %s", call.Operation, key, tt.wantKey)
+				}
+				if name, ok := names[keyPlaceholder]; ok && aws.StringValue(name) != tt.wantKey {
+					t.Errorf("%s condition names %s = %q, want %q", call.Operation, keyPlaceholder,
+						aws.StringValue(name), tt.wantKey)
+				}
+			}
+		})
+	}
+}
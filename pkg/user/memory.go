@@ -0,0 +1,141 @@
+package user
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryRepository is a Repository keeping users in memory, for tests and local development.
+// It is safe for concurrent use.
+type MemoryRepository struct {
+	mu    sync.RWMutex
+	users map[string]User
+}
+
+// NewMemoryRepository creates an empty in-memory Repository.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{users: map[string]User{}}
+}
+
+// Get returns a copy of the user stored under the email. Reads are always consistent.
+func (r *MemoryRepository) Get(email string, _ ReadOptions) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[email]
+	if !ok {
+		return nil, newError(ErrNotFound, ErrorUserDoesNotExist, nil)
+	}
+	return &u, nil
+}
+
+// List returns copies of the users passing the filter ordered by email, up to opts.MaxItems.
+func (r *MemoryRepository) List(opts ReadOptions) (*Page, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	items := make([]User, 0, len(r.users))
+	for _, u := range r.users {
+		if opts.Filter.Matches(&u) && (opts.IncludeDeleted || u.DeletedAt == "") && !u.ExpiresAt.Expired(now) {
+			items = append(items, u)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Email < items[j].Email })
+
+	page := &Page{Users: items}
+	if opts.MaxItems > 0 && len(items) > opts.MaxItems {
+		page.Users, page.Truncated = items[:opts.MaxItems], true
+	}
+	return page, nil
+}
+
+// Count returns the number of users passing the filter.
+func (r *MemoryRepository) Count(opts ReadOptions) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var count int64
+	for _, u := range r.users {
+		if opts.Filter.Matches(&u) && (opts.IncludeDeleted || u.DeletedAt == "") && !u.ExpiresAt.Expired(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Create stores a new user unless the email is taken.
+func (r *MemoryRepository) Create(u *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[u.Email]; ok {
+		return newError(ErrConflict, ErrorUserAlreadyExists, nil)
+	}
+	r.users[u.Email] = *u
+	return nil
+}
+
+// Update replaces a stored user if its version is still expectedVersion.
+func (r *MemoryRepository) Update(u *User, expectedVersion int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Like the conditional put, a missing user fails the version check
+	current, ok := r.users[u.Email]
+	if !ok || current.Version != expectedVersion {
+		return newError(ErrPreconditionFailed, ErrorVersionMismatch, nil)
+	}
+	r.users[u.Email] = *u
+	return nil
+}
+
+// Delete removes the user stored under the email.
+func (r *MemoryRepository) Delete(email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[email]; !ok {
+		return newError(ErrNotFound, ErrorUserDoesNotExist, nil)
+	}
+	delete(r.users, email)
+	return nil
+}
+
+// GetMany returns copies of the users stored under the emails, skipping missing ones.
+func (r *MemoryRepository) GetMany(emails []string, _ ReadOptions) ([]User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []User
+	for _, email := range emails {
+		if u, ok := r.users[email]; ok {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+// PutMany stores the users, overwriting existing ones.
+func (r *MemoryRepository) PutMany(users []User) map[string]error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range users {
+		r.users[u.Email] = u
+	}
+	return map[string]error{}
+}
+
+// DeleteMany removes the users stored under the emails.
+func (r *MemoryRepository) DeleteMany(emails []string) map[string]error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, email := range emails {
+		delete(r.users, email)
+	}
+	return map[string]error{}
+}
@@ -0,0 +1,58 @@
+package user
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestMemoryRepositoryIsSafeForConcurrentUse(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(repo *MemoryRepository, i int) error
+		want  int // Writes expected to succeed
+	}{
+		{name: "racing creates", write: func(repo *MemoryRepository, _ int) error {
+			return repo.Create(&User{Email: "ada@example.com", Version: 1})
+		}, want: 1},
+		{name: "racing updates of one version", write: func(repo *MemoryRepository, _ int) error {
+			return repo.Update(&User{Email: "bob@example.com", Version: 2}, 1)
+		}, want: 1},
+		{name: "distinct creates", write: func(repo *MemoryRepository, i int) error {
+			return repo.Create(&User{Email: string(rune('a'+i)) + "@example.com", Version: 1})
+		}, want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMemoryRepository()
+			if err := repo.Create(&User{Email: "bob@example.com", Version: 1}); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+
+			var wg sync.WaitGroup
+			errs := make([]error, 20)
+			for i := range errs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs[i] = tt.write(repo, i)
+					_, _ = repo.List(ReadOptions{})
+				}(i)
+			}
+			wg.Wait()
+
+			succeeded := 0
+			for _, err := range errs {
+				switch {
+				case err == nil:
+					succeeded++
+				case !errors.Is(err, ErrConflict) && !errors.Is(err, ErrPreconditionFailed):
+					t.Errorf("write error = %v, want a conflict or a failed precondition", err)
+				}
+			}
+			if succeeded != tt.want {
+				t.Errorf("%d writes succeeded, want %d", succeeded, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package user
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"strconv"
+)
+
+// Error messages for merges
+var (
+	ErrorMissingMergeEmails = "body must name the primary and duplicate emails"
+	ErrorMergeIntoSelf      = "a user can't be merged into itself"
+	ErrorCouldNotMerge      = "couldn't merge the users"
+)
+
+// MergeRequest is the body of a merge
+type MergeRequest struct {
+	Primary   string `json:"primary"`   // Email of the user that is kept
+	Duplicate string `json:"duplicate"` // Email of the user merged into it and removed
+}
+
+// MergeUsers merges a duplicate account into a primary one. The primary keeps its own
+// fields and takes the duplicate's where its own are empty, the union of both tag sets,
+// its own tag winning a conflicting key, and the earlier creation time. The merged primary
+// is written and the duplicate removed in a single transaction: either both happen or
+// neither does.
+//
+// Parameters:
+// - body: The JSON body naming both users, e.g. {"primary": "a@x.com", "duplicate": "b@x.com"}.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The merged primary.
+//   - The primary and the duplicate as they were before the merge.
+//   - A validation error for a missing email or a merge of a user into itself, an
+//     ErrNotFound error if either user doesn't exist, an ErrPreconditionFailed error if
+//     either changed meanwhile, or an error if the write fails.
+func MergeUsers(body string, repo Repository) (*User, *User, *User, error) {
+	var req MergeRequest
+	if err := DecodeJSON(body, &req); err != nil {
+		return nil, nil, nil, err
+	}
+	if req.Primary == "" {
+		return nil, nil, nil, newFieldError(ErrValidation, ErrorMissingMergeEmails, "primary", nil)
+	}
+	if req.Duplicate == "" {
+		return nil, nil, nil, newFieldError(ErrValidation, ErrorMissingMergeEmails, "duplicate", nil)
+	}
+	if NormalizeEmail(req.Primary) == NormalizeEmail(req.Duplicate) {
+		return nil, nil, nil, newFieldError(ErrValidation, ErrorMergeIntoSelf, "duplicate", nil)
+	}
+
+	primary, err := FetchUser(req.Primary, ReadOptions{ConsistentRead: true}, repo)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	duplicate, err := FetchUser(req.Duplicate, ReadOptions{ConsistentRead: true}, repo)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	merged := mergeFields(primary, duplicate)
+	merged.UpdatedAt = timestamp()
+	merged.Version = primary.Version + 1
+	if err := merged.Validate(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := repo.Merge(merged, primary.Version, duplicate.Email, duplicate.Version); err != nil {
+		return nil, nil, nil, err
+	}
+	return merged, primary, duplicate, nil
+}
+
+// mergeFields returns a copy of primary filled in from duplicate. Only profile fields are
+// taken; the credentials, verification, status and role of the primary are kept, since
+// they belong to its own email. In particular the duplicate's password is never carried
+// over, so it can't be used to sign in as the primary.
+func mergeFields(primary *User, duplicate *User) *User {
+	merged := *primary
+	if merged.FirstName == "" {
+		merged.FirstName = duplicate.FirstName
+	}
+	if merged.LastName == "" {
+		merged.LastName = duplicate.LastName
+	}
+	if merged.Address == nil && duplicate.Address != nil {
+		address := *duplicate.Address
+		merged.Address = &address
+	}
+	// RFC3339 UTC timestamps sort chronologically
+	if duplicate.CreatedAt != "" && (merged.CreatedAt == "" || duplicate.CreatedAt < merged.CreatedAt) {
+		merged.CreatedAt = duplicate.CreatedAt
+	}
+
+	if len(primary.Tags) > 0 || len(duplicate.Tags) > 0 {
+		merged.Tags = make(Tags, len(primary.Tags)+len(duplicate.Tags))
+		for key, value := range duplicate.Tags {
+			merged.Tags[key] = value
+		}
+		for key, value := range primary.Tags {
+			merged.Tags[key] = value
+		}
+	}
+	return &merged
+}
+
+// Merge writes u and removes the user stored under exactly duplicateEmail in one
+// TransactWriteItems. Each write is conditioned on its user still existing at the version
+// read; a failure of either cancels both.
+func (r *DynamoRepository) Merge(u *User, expectedVersion int, duplicateEmail string, duplicateVersion int) error {
+	item, err := marshalUser(u)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{
+				TableName:                 aws.String(r.TableName),
+				Item:                      item,
+				ConditionExpression:       aws.String("attribute_exists(#key) AND (" + versionCondition(expectedVersion) + ")"),
+				ExpressionAttributeNames:  withKeyName(map[string]*string{"#version": aws.String("version")}),
+				ExpressionAttributeValues: versionValues(expectedVersion),
+			}},
+			{Delete: &dynamodb.Delete{
+				TableName:                 aws.String(r.TableName),
+				Key:                       emailKey(duplicateEmail),
+				ConditionExpression:       aws.String("attribute_exists(#key) AND (" + versionCondition(duplicateVersion) + ")"),
+				ExpressionAttributeNames:  withKeyName(map[string]*string{"#version": aws.String("version")}),
+				ExpressionAttributeValues: versionValues(duplicateVersion),
+			}},
+		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+
+	_, err = r.DynaClient.TransactWriteItems(input)
+	var canceled *dynamodb.TransactionCanceledException
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &canceled):
+		for _, reason := range canceled.CancellationReasons {
+			if reason != nil && aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+				return newError(ErrPreconditionFailed, ErrorVersionMismatch, err)
+			}
+		}
+	}
+	return newError(ErrStorage, ErrorCouldNotMerge, err)
+}
+
+// Merge stores u and removes the user stored under duplicateEmail, atomically.
+func (r *MemoryRepository) Merge(u *User, expectedVersion int, duplicateEmail string, duplicateVersion int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.users[u.Email]
+	if !ok || current.Version != expectedVersion {
+		return newError(ErrPreconditionFailed, ErrorVersionMismatch, nil)
+	}
+	duplicate, ok := r.users[duplicateEmail]
+	if !ok || duplicate.Version != duplicateVersion {
+		return newError(ErrPreconditionFailed, ErrorVersionMismatch, nil)
+	}
+	delete(r.users, duplicateEmail)
+	r.users[u.Email] = *u
+	return nil
+}
+
+// versionCondition returns the condition that an item is still at a version. Records
+// created before versioning have no version attribute and count as version 0.
+func versionCondition(version int) string {
+	if version == 0 {
+		return "attribute_not_exists(#version) OR #version = :expected"
+	}
+	return "#version = :expected"
+}
+
+// versionValues returns the attribute values of versionCondition.
+func versionValues(version int) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{":expected": {N: aws.String(strconv.Itoa(version))}}
+}
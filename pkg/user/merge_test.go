@@ -0,0 +1,140 @@
+package user
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+// mergeUsers are the primary and duplicate of the merge tests
+func mergeUsers() (User, User) {
+	primary := User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 3,
+		CreatedAt: "2021-01-01T00:00:00Z", Tags: Tags{"plan": "pro"}, Status: StatusActive, Role: RoleUser}
+	duplicate := User{Email: "ada.lovelace@example.com", FirstName: "Augusta", LastName: "King", Version: 5,
+		CreatedAt: "2020-01-01T00:00:00Z", Address: &Address{City: "London"}, Tags: Tags{"plan": "free", "ref": "ads"},
+		PasswordHash: "duplicate-hash", Verified: true, Status: StatusActive, Role: RoleAdmin}
+	return primary, duplicate
+}
+
+func TestMergeFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		primary  func(u *User)
+		wantHash string
+	}{
+		{name: "primary without a password", primary: func(u *User) {}, wantHash: ""},
+		{name: "primary with a password", primary: func(u *User) { u.PasswordHash = "primary-hash" },
+			wantHash: "primary-hash"},
+		{name: "primary without names", primary: func(u *User) { u.FirstName, u.LastName = "", "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, duplicate := mergeUsers()
+			tt.primary(&primary)
+			merged := mergeFields(&primary, &duplicate)
+
+			if merged.PasswordHash != tt.wantHash {
+				t.Errorf("PasswordHash = %q, want %q", merged.PasswordHash, tt.wantHash)
+			}
+			if merged.Verified || merged.Role != RoleUser {
+				t.Errorf("verified %v and role %q, want the primary's", merged.Verified, merged.Role)
+			}
+			wantFirst := primary.FirstName
+			if wantFirst == "" {
+				wantFirst = duplicate.FirstName
+			}
+			if merged.FirstName != wantFirst || merged.Address == nil || merged.Address.City != "London" {
+				t.Errorf("profile = %q, %+v; want %q and the duplicate's address", merged.FirstName, merged.Address, wantFirst)
+			}
+			if want := (Tags{"plan": "pro", "ref": "ads"}); !reflect.DeepEqual(merged.Tags, want) {
+				t.Errorf("Tags = %v, want %v", merged.Tags, want)
+			}
+			if merged.CreatedAt != duplicate.CreatedAt {
+				t.Errorf("CreatedAt = %q, want the earlier %q", merged.CreatedAt, duplicate.CreatedAt)
+			}
+		})
+	}
+}
+
+func TestMergeUsers(t *testing.T) {
+	canceled := func(reasons ...string) error {
+		err := &dynamodb.TransactionCanceledException{}
+		for _, code := range reasons {
+			err.CancellationReasons = append(err.CancellationReasons, &dynamodb.CancellationReason{Code: aws.String(code)})
+		}
+		return err
+	}
+	tests := []struct {
+		name     string
+		transact error
+		wantErr  error
+	}{
+		{name: "merged"},
+		{name: "primary changed", transact: canceled("ConditionalCheckFailed", "None"), wantErr: ErrPreconditionFailed},
+		{name: "duplicate changed", transact: canceled("None", "ConditionalCheckFailed"), wantErr: ErrPreconditionFailed},
+		{name: "conflicting transaction", transact: canceled("TransactionConflict", "None"), wantErr: ErrStorage},
+		{name: "throttled", transact: mocks.ThrottlingError(), wantErr: ErrStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, duplicate := mergeUsers()
+			repo, table := newFakeTable(t, primary, duplicate)
+			if tt.transact != nil {
+				table.fake.OnTransactWriteItems(func(in *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+					return nil, tt.transact
+				})
+			}
+			before := table.snapshot()
+
+			body := `{"primary": "ada@example.com", "duplicate": "ada.lovelace@example.com"}`
+			merged, _, _, err := MergeUsers(body, repo)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MergeUsers() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				// A canceled transaction writes nothing: neither record changed
+				if !reflect.DeepEqual(table.items, before) {
+					t.Errorf("items = %v, want them unchanged", table.items)
+				}
+				return
+			}
+
+			if _, ok := table.items[duplicate.Email]; ok {
+				t.Error("the duplicate is still stored")
+			}
+			stored, err := repo.Get(primary.Email, ReadOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if stored.PasswordHash != "" || stored.Version != primary.Version+1 || stored.Address == nil {
+				t.Errorf("stored primary = %+v, want the merged %+v without the duplicate's password", stored, merged)
+			}
+		})
+	}
+}
+
+func TestMemoryMergeKeepsBothOnConflict(t *testing.T) {
+	primary, duplicate := mergeUsers()
+	repo := NewMemoryRepository()
+	for _, u := range []User{primary, duplicate} {
+		u := u
+		if err := repo.Create(&u); err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+	}
+	merged := mergeFields(&primary, &duplicate)
+	merged.Version = primary.Version + 1
+
+	// The duplicate was written since it was read
+	if err := repo.Merge(merged, primary.Version, duplicate.Email, duplicate.Version-1); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("Merge() error = %v, want ErrPreconditionFailed", err)
+	}
+	for _, want := range []User{primary, duplicate} {
+		if got, err := repo.Get(want.Email, ReadOptions{}); err != nil || !reflect.DeepEqual(*got, want) {
+			t.Errorf("stored %s = %+v, %v; want it unchanged", want.Email, got, err)
+		}
+	}
+}
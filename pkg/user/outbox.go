@@ -0,0 +1,89 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/outbox"
+	"github.com/Vansh3140/golang-serverless/pkg/store"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// putUser writes a user if the condition holds, through the store or, when the outbox is
+// enabled, in one TransactWriteItems with the outbox entry of the write.
+//
+// Parameters:
+// - u: The user to write.
+// - condition: The condition of the write.
+// - action: The outbox action of the write, "created" or "updated".
+//
+// Returns:
+// - A marshaling error, an ErrConditionFailed error, or the error of the write.
+func (r *DynamoRepository) putUser(u *User, condition *store.Condition, action string) error {
+	if !outbox.Enabled() {
+		return r.store().Put(u, condition)
+	}
+	item, err := r.store().Marshal(u)
+	if err != nil {
+		return err
+	}
+	// The outbox holds the record as clients see it, without hashes or encrypted names
+	record, err := json.Marshal(u)
+	if err != nil {
+		return fmt.Errorf("%w: %v", store.ErrMarshal, err)
+	}
+	write := &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+		TableName:                 aws.String(r.TableName),
+		Item:                      item,
+		ConditionExpression:       aws.String(condition.Expression),
+		ExpressionAttributeNames:  condition.Names,
+		ExpressionAttributeValues: condition.Values,
+	}}
+	return r.writeWithOutbox(write, outbox.NewEntry(action, u.Email, u.Version, record, r.RequestID, u.UpdatedAt))
+}
+
+// deleteUser removes the user stored under exactly the given email if the condition holds,
+// through the store or, when the outbox is enabled, in one TransactWriteItems with the
+// outbox entry of the delete.
+//
+// Returns:
+// - An ErrConditionFailed error, or the error of the delete.
+func (r *DynamoRepository) deleteUser(email string, condition *store.Condition) error {
+	if !outbox.Enabled() {
+		return r.store().Delete(email, condition)
+	}
+	write := &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+		TableName:                 aws.String(r.TableName),
+		Key:                       r.store().ItemKey(email),
+		ConditionExpression:       aws.String(condition.Expression),
+		ExpressionAttributeNames:  condition.Names,
+		ExpressionAttributeValues: condition.Values,
+	}}
+	return r.writeWithOutbox(write, outbox.NewEntry("deleted", email, 0, nil, r.RequestID, timestamp()))
+}
+
+// writeWithOutbox makes a write and records its outbox entry in one TransactWriteItems, so
+// the entry exists if and only if the write happened.
+//
+// Returns:
+// - An ErrConditionFailed error if the write's condition failed, or the error of the transaction.
+func (r *DynamoRepository) writeWithOutbox(write *dynamodb.TransactWriteItem, entry *outbox.Entry) error {
+	put, err := outbox.Put(entry)
+	if err != nil {
+		return fmt.Errorf("%w: %v", store.ErrMarshal, err)
+	}
+	_, err = r.DynaClient.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems:          []*dynamodb.TransactWriteItem{write, put},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+
+	// Reasons are listed in the order of the transaction's items
+	var canceled *dynamodb.TransactionCanceledException
+	if errors.As(err, &canceled) && len(canceled.CancellationReasons) > 0 {
+		if reason := canceled.CancellationReasons[0]; reason != nil && aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+			return store.ConditionFailed(err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,242 @@
+package user
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Limits on passwords. bcrypt only reads the first 72 bytes, so longer passwords are rejected.
+const (
+	MinPasswordLength = 8
+	MaxPasswordBytes  = 72
+)
+
+// Error messages for passwords
+var (
+	ErrorPasswordTooShort     = "password must be at least 8 characters"
+	ErrorPasswordTooLong      = "password must be at most 72 bytes"
+	ErrorPasswordIsEmail      = "password must not be the email"
+	ErrorPasswordNotUpdatable = "password can't be changed by an update; use POST /users/{email}/password"
+	ErrorMissingPasswords     = "body must carry currentPassword and newPassword"
+	ErrorMissingCredentials   = "body must carry email and password"
+	ErrorInvalidCredentials   = "invalid email or password"
+	ErrorCouldNotHashPassword = "couldn't hash the password"
+)
+
+// dummyHash is compared against when there is no stored hash, so a missing user takes as
+// long to reject as a wrong password. It is built on first use to keep cold starts fast.
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// PasswordChangeRequest is the body of a password change
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"currentPassword"` // The password stored now
+	NewPassword     string `json:"newPassword"`     // The password to store
+}
+
+// Credentials is the body of a login
+type Credentials struct {
+	Email    string `json:"email"`    // The email of the user
+	Password string `json:"password"` // The user's password
+}
+
+// bcryptCost returns the bcrypt cost of new hashes (BCRYPT_COST, 10 by default).
+func bcryptCost() int {
+	cost, err := strconv.Atoi(os.Getenv("BCRYPT_COST"))
+	if err != nil || cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return bcrypt.DefaultCost
+	}
+	return cost
+}
+
+// validatePassword checks a new password against the policy: at least MinPasswordLength
+// characters, at most MaxPasswordBytes bytes, and not the user's email.
+func validatePassword(password string, email string, field string) error {
+	switch {
+	case len([]rune(password)) < MinPasswordLength:
+		return newFieldError(ErrValidation, ErrorPasswordTooShort, field, nil)
+	case len(password) > MaxPasswordBytes:
+		return newFieldError(ErrValidation, ErrorPasswordTooLong, field, nil)
+	case strings.EqualFold(strings.TrimSpace(password), strings.TrimSpace(email)):
+		return newFieldError(ErrValidation, ErrorPasswordIsEmail, field, nil)
+	}
+	return nil
+}
+
+// checkPassword reports whether password matches a stored bcrypt hash. Without a stored
+// hash it still runs a comparison, against dummyHash, before failing.
+func checkPassword(hash string, password string) bool {
+	if hash == "" {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcryptCost())
+		})
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// hashPassword validates a new password and hashes it with bcrypt.
+//
+// Parameters:
+// - password: The new password.
+// - email: The email of the user, which the password must differ from.
+// - field: The request field carrying the password, named in validation errors.
+//
+// Returns:
+// - The bcrypt hash.
+// - A validation error if the password breaks the policy, or an error if hashing fails.
+func hashPassword(password string, email string, field string) (string, error) {
+	if err := validatePassword(password, email, field); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost())
+	if err != nil {
+		return "", newError(ErrInternal, ErrorCouldNotHashPassword, err)
+	}
+	return string(hash), nil
+}
+
+// ChangePassword replaces a user's password after checking the current one. A missing
+// user, a user without a password and a wrong current password fail alike, so callers
+// can't tell them apart.
+//
+// Parameters:
+// - email: The email of the user.
+// - body: The JSON body, {"currentPassword": "...", "newPassword": "..."}.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The updated user.
+//   - A validation error for a malformed body or a new password breaking the policy, an
+//     ErrUnauthorized error if the user or current password doesn't match, or an error if
+//     the write fails.
+func ChangePassword(email string, body string, repo Repository) (*User, error) {
+	var req PasswordChangeRequest
+	if err := DecodeJSON(body, &req); err != nil {
+		return nil, err
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return nil, newError(ErrValidation, ErrorMissingPasswords, nil)
+	}
+
+	current, err := Authenticate(email, req.CurrentPassword, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	newHash, err := hashPassword(req.NewPassword, current.Email, "newPassword")
+	if err != nil {
+		return nil, err
+	}
+	return repo.SetPassword(current.Email, newHash, timestamp(), current.Version)
+}
+
+// ParseCredentials strictly decodes the body of a login.
+//
+// Parameters:
+// - body: The JSON body, {"email": "...", "password": "..."}.
+//
+// Returns:
+// - The credentials.
+// - A validation error for a malformed body or a missing email or password.
+func ParseCredentials(body string) (*Credentials, error) {
+	creds := new(Credentials)
+	if err := DecodeJSON(body, creds); err != nil {
+		return nil, err
+	}
+	if creds.Email == "" || creds.Password == "" {
+		return nil, newError(ErrValidation, ErrorMissingCredentials, nil)
+	}
+	return creds, nil
+}
+
+// Authenticate checks a user's password. A missing user, a user without a password, an
+// inactive or suspended user and a wrong password fail alike, so callers can't tell them
+// apart, and take about as long.
+//
+// Parameters:
+// - email: The email of the user.
+// - password: The password to check.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - The user.
+// - An ErrUnauthorized error if the credentials don't match, or an error if the read fails.
+func Authenticate(email string, password string, repo Repository) (*User, error) {
+	current, err := FetchUser(email, ReadOptions{ConsistentRead: true}, repo)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	storedHash := ""
+	if err == nil {
+		storedHash = current.PasswordHash
+	}
+	// Check the password even without a user, so a missing user takes as long to reject
+	if !checkPassword(storedHash, password) || err != nil || current.CurrentStatus() != StatusActive {
+		return nil, newError(ErrUnauthorized, ErrorInvalidCredentials, nil)
+	}
+	return current, nil
+}
+
+// SetPassword stores a new password hash for the user stored under exactly the given
+// email with an UpdateItem, if its version is still expectedVersion, bumping the version.
+func (r *DynamoRepository) SetPassword(email string, hash string, updatedAt string, expectedVersion int) (*User, error) {
+	input := &dynamodb.UpdateItemInput{
+		Key:       emailKey(email),
+		TableName: aws.String(r.TableName),
+		UpdateExpression: aws.String("SET #passwordHash = :hash, #updatedAt = :now, " +
+			"#version = if_not_exists(#version, :zero) + :one"),
+		ConditionExpression: aws.String("attribute_exists(#key) AND (" + versionCondition(expectedVersion) + ")"),
+		ExpressionAttributeNames: withKeyName(map[string]*string{
+			"#passwordHash": aws.String("passwordHash"),
+			"#updatedAt":    aws.String("updatedAt"),
+			"#version":      aws.String("version"),
+		}),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":hash":     {S: aws.String(hash)},
+			":now":      {S: aws.String(updatedAt)},
+			":expected": {N: aws.String(strconv.Itoa(expectedVersion))},
+			":zero":     {N: aws.String("0")},
+			":one":      {N: aws.String("1")},
+		},
+		ReturnValues:           aws.String(dynamodb.ReturnValueAllNew),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+
+	result, err := r.DynaClient.UpdateItem(input)
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, newError(ErrPreconditionFailed, ErrorVersionMismatch, err)
+		}
+		return nil, newError(ErrStorage, ErrorCouldNotDynamoPutItem, err)
+	}
+
+	updated := new(User)
+	if err := UnmarshalItem(result.Attributes, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// SetPassword stores a new password hash for the user stored under the email.
+func (r *MemoryRepository) SetPassword(email string, hash string, updatedAt string, expectedVersion int) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[email]
+	if !ok || u.Version != expectedVersion {
+		return nil, newError(ErrPreconditionFailed, ErrorVersionMismatch, nil)
+	}
+	u.PasswordHash, u.UpdatedAt, u.Version = hash, updatedAt, u.Version+1
+	r.users[email] = u
+	return &u, nil
+}
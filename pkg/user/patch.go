@@ -0,0 +1,294 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/outbox"
+	"github.com/Vansh3140/golang-serverless/pkg/store"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPatchAttempts is how many times a patch without an expected version is applied
+// before a concurrent write wins, so racing patches of different fields both land
+const maxPatchAttempts = 3
+
+// serverFields are the fields a patch can't set; like on an update, they are ignored
+var serverFields = []string{"createdAt", "updatedAt", "deletedAt", "version", "verified"}
+
+// PatchUser partially updates a user with a JSON merge patch (RFC 7396): the fields the
+// body names are set, those it sets to null are cleared, and objects such as the address
+// are patched field by field. The rest of the user is left as stored. The patch is checked
+// against the patch schema, the patched user is validated like an update, and only the
+// fields the patch names are written.
+//
+// Without an expected version, the patch is applied to the latest stored user and retried
+// if another write lands in between; with one, a stale version fails.
+//
+// Parameters:
+// - email: The email of the user to patch.
+// - body: The merge patch, e.g. {"lastname": "Doe", "address": {"line2": null}}.
+// - expectedVersion: The version the client last saw, or 0 to patch the latest one.
+// - opts: Options tuning the update.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The patched user.
+//   - The user as it was before the patch.
+//   - A validation error for a malformed patch or an invalid patched user, an ErrNotFound
+//     error if the user doesn't exist, an ErrPreconditionFailed error if the version doesn't
+//     match, or an error if the write fails.
+func PatchUser(email string, body string, expectedVersion int, opts UpdateOptions, repo Repository) (*User, *User, error) {
+	var patch map[string]interface{}
+	if err := DecodeJSON(body, &patch); err != nil {
+		return nil, nil, err
+	}
+	if _, ok := patch["password"]; ok {
+		return nil, nil, newFieldError(ErrValidation, ErrorPasswordNotUpdatable, "password", nil)
+	}
+	if err := validateDocument(patchSchema, patch); err != nil {
+		return nil, nil, err
+	}
+	if given, ok := patch["email"].(string); ok {
+		// The email is the key, so the patch can't move the user to another one
+		if err := checkEmailUnchanged(email, given); err != nil {
+			return nil, nil, err
+		}
+	}
+	delete(patch, "email")
+	for _, field := range serverFields {
+		delete(patch, field)
+	}
+
+	for attempt := 1; ; attempt++ {
+		currUser, err := FetchUser(email, ReadOptions{ConsistentRead: true}, repo)
+		if err != nil {
+			return nil, nil, err
+		}
+		if expectedVersion != 0 && expectedVersion != currUser.Version {
+			return nil, nil, newError(ErrPreconditionFailed, ErrorVersionMismatch, nil)
+		}
+
+		patched, err := applyPatch(currUser, patch, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		patched.Version = currUser.Version + 1
+
+		err = repo.Patch(patched, patchedFields(patch, currUser, patched), currUser.Version)
+		if errors.Is(err, ErrPreconditionFailed) && expectedVersion == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return patched, currUser, nil
+	}
+}
+
+// applyPatch merges a patch into a copy of the stored user and checks the result like the
+// body of an update.
+func applyPatch(currUser *User, patch map[string]interface{}, opts UpdateOptions) (*User, error) {
+	stored, err := json.Marshal(currUser)
+	if err != nil {
+		return nil, newError(ErrValidation, ErrorInvalidUserData, err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(stored, &document); err != nil {
+		return nil, newError(ErrValidation, ErrorInvalidUserData, err)
+	}
+	// A new time to live replaces the stored expiry
+	if _, ok := patch["ttlDays"]; ok {
+		delete(document, "expiresAt")
+	}
+	merged, err := json.Marshal(mergePatch(document, patch))
+	if err != nil {
+		return nil, newError(ErrValidation, ErrorInvalidUserData, err)
+	}
+
+	var patched User
+	expirySet, _, err := decodeUser(string(merged), updateSchema, &patched)
+	if err != nil {
+		return nil, err
+	}
+	if err := patched.Validate(); err != nil {
+		return nil, err
+	}
+	if err := prepareUpdate(&patched, currUser, expirySet, opts); err != nil {
+		return nil, err
+	}
+	return &patched, nil
+}
+
+// mergePatch applies a JSON merge patch to a document, in place (RFC 7396).
+func mergePatch(document map[string]interface{}, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		switch value := value.(type) {
+		case nil:
+			delete(document, key)
+		case map[string]interface{}:
+			target, _ := document[key].(map[string]interface{})
+			if target == nil {
+				target = map[string]interface{}{}
+			}
+			document[key] = mergePatch(target, value)
+		default:
+			document[key] = value
+		}
+	}
+	return document
+}
+
+// patchedFields returns the stored fields a patch writes, in sorted order. ttlDays sets
+// the expiry. Tags the patch adds or removes are written one by one, as "tags.<key>",
+// when the stored user has tags and some are left; otherwise the map is written whole.
+// Address fields are written the same way, as "address.<field>".
+func patchedFields(patch map[string]interface{}, currUser *User, patched *User) []string {
+	fields := make([]string, 0, len(patch))
+	for field, value := range patch {
+		keys, isObject := value.(map[string]interface{})
+		switch {
+		case field == "ttlDays":
+			field = "expiresAt"
+		case field == "tags" && isObject && len(currUser.Tags) > 0 && len(patched.Tags) > 0:
+			for key := range keys {
+				fields = append(fields, tagField(key))
+			}
+			continue
+		case field == "address" && isObject && currUser.Address != nil && patched.Address != nil &&
+			*patched.Address != (Address{}):
+			for key := range keys {
+				fields = append(fields, addressField(key))
+			}
+			continue
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// tagField returns the field of Patch writing a single tag.
+func tagField(key string) string {
+	return "tags." + key
+}
+
+// addressField returns the field of Patch writing a single address field.
+func addressField(key string) string {
+	return "address." + key
+}
+
+// patchAttributes returns the attributes written for patched fields. The names are
+// encrypted under one data key, so patching either writes both and the key.
+func patchAttributes(fields []string) []string {
+	attributes := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field == "firstname" || field == "lastname" {
+			fields = WithFields(fields, "firstname", "lastname", piiKeyAttribute)
+			break
+		}
+	}
+	for _, field := range fields {
+		attributes = append(attributes, attributeName(field))
+	}
+	sort.Strings(attributes)
+	return attributes
+}
+
+// Patch writes the given fields of u with an UpdateItem, setting those u has and removing
+// the others, if the stored user is still at expectedVersion. A "tags.<key>" or
+// "address.<field>" field sets or removes that key of the stored map, leaving the other
+// keys alone. The patch is recorded in the outbox when OUTBOX_TABLE_NAME is set.
+func (r *DynamoRepository) Patch(u *User, fields []string, expectedVersion int) error {
+	item, err := r.store().Marshal(u)
+	if err != nil {
+		return storeError(err, ErrorCouldNotMarshalItem)
+	}
+
+	names := withKeyName(map[string]*string{"#updatedAt": aws.String("updatedAt"), "#version": aws.String("version")})
+	values := versionValues(expectedVersion)
+	values[":now"] = &dynamodb.AttributeValue{S: aws.String(u.UpdatedAt)}
+	values[":next"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(u.Version))}
+	set := []string{"#updatedAt = :now", "#version = :next"}
+	var remove []string
+	for i, attribute := range patchAttributes(fields) {
+		placeholder := "#p" + strconv.Itoa(i)
+		value := item[attribute]
+		if parent, key, ok := strings.Cut(attribute, "."); ok {
+			// A single tag or address field is a document path into its map
+			names["#"+parent] = aws.String(parent)
+			names[placeholder] = aws.String(key)
+			placeholder = "#" + parent + "." + placeholder
+			value = nil
+			if stored := item[parent]; stored != nil {
+				value = stored.M[key]
+			}
+		} else {
+			names[placeholder] = aws.String(attribute)
+		}
+		if !isNullValue(value) {
+			values[":p"+strconv.Itoa(i)] = value
+			set = append(set, placeholder+" = :p"+strconv.Itoa(i))
+		} else {
+			remove = append(remove, placeholder)
+		}
+	}
+
+	expression := "SET " + strings.Join(set, ", ")
+	if len(remove) > 0 {
+		expression += " REMOVE " + strings.Join(remove, ", ")
+	}
+	condition := "attribute_exists(#key) AND (" + versionCondition(expectedVersion) + ")"
+
+	if outbox.Enabled() {
+		record, err := json.Marshal(u)
+		if err != nil {
+			return newError(ErrStorage, ErrorCouldNotMarshalItem, err)
+		}
+		write := &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+			TableName:                 aws.String(r.TableName),
+			Key:                       emailKey(u.Email),
+			UpdateExpression:          aws.String(expression),
+			ConditionExpression:       aws.String(condition),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		}}
+		err = r.writeWithOutbox(write, outbox.NewEntry("updated", u.Email, u.Version, record, r.RequestID, u.UpdatedAt))
+		if errors.Is(err, store.ErrConditionFailed) {
+			return newError(ErrPreconditionFailed, ErrorVersionMismatch, err)
+		}
+		return storeError(err, ErrorCouldNotDynamoPutItem)
+	}
+
+	_, err = r.DynaClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String(r.TableName),
+		Key:                       emailKey(u.Email),
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ReturnConsumedCapacity:    aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return newError(ErrPreconditionFailed, ErrorVersionMismatch, err)
+		}
+		return newError(ErrStorage, ErrorCouldNotDynamoPutItem, err)
+	}
+	return nil
+}
+
+// Patch stores u if the stored user is still at expectedVersion. The whole user is
+// stored; only the Dynamo repository needs to know the fields.
+func (r *MemoryRepository) Patch(u *User, fields []string, expectedVersion int) error {
+	return r.Update(u, expectedVersion)
+}
+
+// isNullValue reports whether an attribute value is the NULL the marshaler writes for
+// empty values.
+func isNullValue(value *dynamodb.AttributeValue) bool {
+	return value == nil || aws.BoolValue(value.NULL)
+}
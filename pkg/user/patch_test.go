@@ -0,0 +1,345 @@
+package user
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// seedUser stores a user in a fresh in-memory repository, as it would be after a create
+// and an update.
+func seedUser(t *testing.T) (*MemoryRepository, User) {
+	t.Helper()
+	stored := User{
+		Email:        "ada@example.com",
+		FirstName:    "Ada",
+		LastName:     "Lovelace",
+		CreatedAt:    "2020-01-01T00:00:00Z",
+		UpdatedAt:    "2020-01-02T00:00:00Z",
+		Version:      2,
+		Address:      &Address{Line1: "12 St James's Square", Line2: "Flat 3", City: "London"},
+		Tags:         Tags{"plan": "pro"},
+		Status:       StatusActive,
+		Verified:     true,
+		Role:         RoleUser,
+		PasswordHash: "hash",
+	}
+	repo := NewMemoryRepository()
+	if err := repo.Create(&stored); err != nil {
+		t.Fatalf("seeding: %v", err)
+	}
+	return repo, stored
+}
+
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		body     string
+		version  int
+		wantErr  error
+		wantFunc func(t *testing.T, got *User)
+	}{
+		{name: "sets a field", body: `{"lastname": "King"}`, wantFunc: func(t *testing.T, got *User) {
+			if got.FirstName != "Ada" || got.LastName != "King" {
+				t.Errorf("names = %q %q, want Ada King", got.FirstName, got.LastName)
+			}
+			if got.Address == nil || got.Address.Line1 == "" || got.Tags["plan"] != "pro" {
+				t.Errorf("untouched fields changed: address %+v, tags %v", got.Address, got.Tags)
+			}
+		}},
+		{name: "patches the address field by field", body: `{"address": {"line2": null, "city": "Marylebone"}}`,
+			wantFunc: func(t *testing.T, got *User) {
+				want := &Address{Line1: "12 St James's Square", City: "Marylebone"}
+				if !reflect.DeepEqual(got.Address, want) {
+					t.Errorf("Address = %+v, want %+v", got.Address, want)
+				}
+			}},
+		{name: "clears a field", body: `{"tags": null}`, wantFunc: func(t *testing.T, got *User) {
+			if len(got.Tags) != 0 {
+				t.Errorf("Tags = %v, want none", got.Tags)
+			}
+		}},
+		{name: "keeps credentials and server fields", body: `{"version": 9, "verified": false, "createdAt": "x"}`,
+			wantFunc: func(t *testing.T, got *User) {
+				if got.Version != 3 || !got.Verified || got.CreatedAt != "2020-01-01T00:00:00Z" || got.PasswordHash != "hash" {
+					t.Errorf("got version %d, verified %v, createdAt %q, hash %q", got.Version, got.Verified, got.CreatedAt,
+						got.PasswordHash)
+				}
+			}},
+		{name: "sets a time to live", body: `{"ttlDays": 30}`, wantFunc: func(t *testing.T, got *User) {
+			if got.ExpiresAt == 0 {
+				t.Error("ExpiresAt = 0, want an expiry")
+			}
+		}},
+		{name: "same email", body: `{"email": "ada@example.com", "firstname": "Augusta"}`},
+		{name: "matching version", body: `{"firstname": "Augusta"}`, version: 2},
+		{name: "stale version", body: `{"firstname": "Augusta"}`, version: 1, wantErr: ErrPreconditionFailed},
+		{name: "other email", body: `{"email": "bob@example.com"}`, wantErr: ErrValidation},
+		{name: "email of the wrong type", body: `{"email": 1}`, wantErr: ErrValidation},
+		{name: "password", body: `{"password": "s3cret-passw0rd"}`, wantErr: ErrValidation},
+		{name: "unknown field", body: `{"nickname": "Ada"}`, wantErr: ErrValidation},
+		{name: "clears a required field", body: `{"firstname": null}`, wantErr: ErrValidation},
+		{name: "invalid name", body: `{"lastname": "` + strings.Repeat("a", 1000) + `"}`, wantErr: ErrValidation},
+		{name: "empty body", body: ``, wantErr: ErrValidation},
+		{name: "missing user", email: "bob@example.com", body: `{"lastname": "King"}`, wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, stored := seedUser(t)
+			email := tt.email
+			if email == "" {
+				email = stored.Email
+			}
+
+			got, previous, err := PatchUser(email, tt.body, tt.version, UpdateOptions{}, repo)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PatchUser() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if current, _ := repo.Get(stored.Email, ReadOptions{}); !reflect.DeepEqual(*current, stored) {
+					t.Errorf("stored user = %+v, want it unchanged", *current)
+				}
+				return
+			}
+			if previous.Version != stored.Version || got.Version != stored.Version+1 {
+				t.Errorf("versions = %d -> %d, want %d -> %d", previous.Version, got.Version, stored.Version, stored.Version+1)
+			}
+			if current, _ := repo.Get(stored.Email, ReadOptions{}); !reflect.DeepEqual(current, got) {
+				t.Errorf("stored user = %+v, want %+v", current, got)
+			}
+			if tt.wantFunc != nil {
+				tt.wantFunc(t, got)
+			}
+		})
+	}
+}
+
+// racingRepository fails the first writes with a version mismatch, like a table another
+// request writes to between the read and the write.
+type racingRepository struct {
+	*MemoryRepository
+	conflicts int
+}
+
+func (r *racingRepository) Patch(u *User, fields []string, expectedVersion int) error {
+	if r.conflicts > 0 {
+		r.conflicts--
+		return newError(ErrPreconditionFailed, ErrorVersionMismatch, nil)
+	}
+	return r.MemoryRepository.Patch(u, fields, expectedVersion)
+}
+
+func TestPatchUserRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   int
+		conflicts int
+		wantErr   error
+	}{
+		{name: "retried without a version", conflicts: maxPatchAttempts - 1},
+		{name: "gives up", conflicts: maxPatchAttempts, wantErr: ErrPreconditionFailed},
+		{name: "not retried with a version", version: 2, conflicts: 1, wantErr: ErrPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory, _ := seedUser(t)
+			repo := &racingRepository{MemoryRepository: memory, conflicts: tt.conflicts}
+			_, _, err := PatchUser("ada@example.com", `{"lastname": "King"}`, tt.version, UpdateOptions{}, repo)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("PatchUser() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document map[string]interface{}
+		patch    map[string]interface{}
+		want     map[string]interface{}
+	}{
+		{name: "sets", document: map[string]interface{}{"a": "1"}, patch: map[string]interface{}{"b": "2"},
+			want: map[string]interface{}{"a": "1", "b": "2"}},
+		{name: "replaces", document: map[string]interface{}{"a": "1"}, patch: map[string]interface{}{"a": "2"},
+			want: map[string]interface{}{"a": "2"}},
+		{name: "removes", document: map[string]interface{}{"a": "1", "b": "2"}, patch: map[string]interface{}{"a": nil},
+			want: map[string]interface{}{"b": "2"}},
+		{name: "merges objects", document: map[string]interface{}{"o": map[string]interface{}{"a": "1", "b": "2"}},
+			patch: map[string]interface{}{"o": map[string]interface{}{"a": nil, "c": "3"}},
+			want:  map[string]interface{}{"o": map[string]interface{}{"b": "2", "c": "3"}}},
+		{name: "creates objects", document: map[string]interface{}{"o": "1"},
+			patch: map[string]interface{}{"o": map[string]interface{}{"a": "1"}},
+			want:  map[string]interface{}{"o": map[string]interface{}{"a": "1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePatch(tt.document, tt.patch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDynamoRepositoryPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		fields      []string
+		updateErr   error
+		wantSet     []string
+		wantRemoved []string
+		wantErr     error
+	}{
+		{name: "sets a field", fields: []string{"lastname"}, wantSet: []string{"firstname", "lastname"},
+			wantRemoved: []string{piiKeyAttribute}},
+		{name: "removes an empty field", fields: []string{"address", "tags"}, wantSet: []string{"tags"},
+			wantRemoved: []string{"address"}},
+		{name: "writes single tags", fields: []string{"tags.plan", "tags.trial"}, wantSet: []string{"tags.plan"},
+			wantRemoved: []string{"tags.trial"}},
+		{name: "version mismatch", fields: []string{"tags"}, updateErr: mocks.ConditionalCheckFailedError(),
+			wantErr: ErrPreconditionFailed},
+		{name: "throttled", fields: []string{"tags"}, updateErr: mocks.ThrottlingError(), wantErr: ErrStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := mocks.NewFakeDynamo()
+			fake.OnUpdateItem(func(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+				return &dynamodb.UpdateItemOutput{}, tt.updateErr
+			})
+			repo := NewDynamoRepository("users", fake)
+			u := &User{Email: "ada@example.com", FirstName: "Ada", LastName: "King", UpdatedAt: "2020-01-03T00:00:00Z",
+				Version: 3, Tags: Tags{"plan": "pro"}}
+
+			err := repo.Patch(u, tt.fields, 2)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Patch() error = %v, want %v", err, tt.wantErr)
+			}
+			inputs := fake.Inputs("UpdateItem")
+			if len(inputs) != 1 {
+				t.Fatalf("UpdateItem calls = %d, want 1", len(inputs))
+			}
+			in := inputs[0].(*dynamodb.UpdateItemInput)
+			if !strings.Contains(aws.StringValue(in.ConditionExpression), "#version = :expected") ||
+				aws.StringValue(in.ExpressionAttributeValues[":expected"].N) != "2" {
+				t.Errorf("condition = %q, want the expected version", aws.StringValue(in.ConditionExpression))
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			set, removed := updatedAttributes(in)
+			if !reflect.DeepEqual(set, tt.wantSet) || !reflect.DeepEqual(removed, tt.wantRemoved) {
+				t.Errorf("set %v and removed %v, want %v and %v", set, removed, tt.wantSet, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestPatchedFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		stored  Tags
+		patch   string
+		patched Tags
+		want    []string
+	}{
+		{name: "fields", stored: Tags{"plan": "pro"}, patch: `{"lastname": "King", "ttlDays": 3}`,
+			patched: Tags{"plan": "pro"}, want: []string{"expiresAt", "lastname"}},
+		{name: "single tags", stored: Tags{"plan": "pro", "trial": "yes"},
+			patch:   `{"tags": {"source": "ads", "trial": null}}`,
+			patched: Tags{"plan": "pro", "source": "ads"}, want: []string{"tags.source", "tags.trial"}},
+		{name: "first tags", patch: `{"tags": {"source": "ads"}}`, patched: Tags{"source": "ads"},
+			want: []string{"tags"}},
+		{name: "last tag removed", stored: Tags{"plan": "pro"}, patch: `{"tags": {"plan": null}}`, want: []string{"tags"}},
+		{name: "tags cleared", stored: Tags{"plan": "pro"}, patch: `{"tags": null}`, want: []string{"tags"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch map[string]interface{}
+			if err := DecodeJSON(tt.patch, &patch); err != nil {
+				t.Fatalf("decoding the patch: %v", err)
+			}
+			got := patchedFields(patch, &User{Tags: tt.stored}, &User{Tags: tt.patched})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("patchedFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchUserTags(t *testing.T) {
+	full := Tags{}
+	for i := 0; i < MaxTags; i++ {
+		full["t"+strconv.Itoa(i)] = "x"
+	}
+	tests := []struct {
+		name     string
+		stored   Tags
+		patch    string
+		wantTags Tags
+		wantErr  error
+	}{
+		{name: "adds and removes", stored: Tags{"plan": "pro", "trial": "yes"},
+			patch:    `{"tags": {"source": "ads", "trial": null}}`,
+			wantTags: Tags{"plan": "pro", "source": "ads"}},
+		{name: "replaces at the limit", stored: full, patch: `{"tags": {"t0": null, "extra": "x"}}`},
+		{name: "over the limit", stored: full, patch: `{"tags": {"extra": "x"}}`, wantErr: ErrValidation},
+		{name: "reserved key", stored: Tags{"plan": "pro"}, patch: `{"tags": {"email": "x"}}`, wantErr: ErrValidation},
+		{name: "value too long", stored: Tags{"plan": "pro"}, patch: `{"tags": {"plan": "` + strings.Repeat("x", 300) + `"}}`,
+			wantErr: ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMemoryRepository()
+			if err := repo.Create(&User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 1,
+				Tags: tt.stored}); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+
+			got, _, err := PatchUser("ada@example.com", tt.patch, 0, UpdateOptions{}, repo)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PatchUser() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantTags != nil && !reflect.DeepEqual(got.Tags, tt.wantTags) {
+				t.Errorf("Tags = %v, want %v", got.Tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+// updatedAttributes returns the patched attributes an UpdateItem sets and removes, leaving
+// out the timestamp and version every patch sets. Single tags and address fields are named
+// "tags.<key>" and "address.<field>".
+func updatedAttributes(in *dynamodb.UpdateItemInput) ([]string, []string) {
+	var set, removed []string
+	expression := aws.StringValue(in.UpdateExpression)
+	for placeholder, name := range in.ExpressionAttributeNames {
+		if !strings.HasPrefix(placeholder, "#p") {
+			continue
+		}
+		attribute := aws.StringValue(name)
+		switch {
+		case strings.Contains(expression, "#tags."+placeholder):
+			placeholder = "#tags." + placeholder
+			attribute = tagField(attribute)
+		case strings.Contains(expression, "#address."+placeholder):
+			placeholder = "#address." + placeholder
+			attribute = addressField(attribute)
+		}
+		if strings.Contains(expression, placeholder+" = ") {
+			set = append(set, attribute)
+		} else {
+			removed = append(removed, attribute)
+		}
+	}
+	sort.Strings(set)
+	sort.Strings(removed)
+	return set, removed
+}
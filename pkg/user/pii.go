@@ -0,0 +1,208 @@
+package user
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"os"
+	"sync"
+	"time"
+)
+
+// Error messages for encrypted names
+var (
+	ErrorEncryptedFieldFilter = "cannot filter on encrypted field"
+	ErrorCouldNotEncryptNames = "couldn't encrypt the user's names"
+	ErrorCouldNotDecryptNames = "couldn't decrypt the user's names"
+)
+
+// piiKeyAttribute holds the KMS-wrapped data key of an item whose names are encrypted.
+// Items without it were written before encryption was enabled and hold plain names.
+const piiKeyAttribute = "piiKey"
+
+// encryptedFields are the attributes encrypted when PII_KMS_KEY_ARN is set
+var encryptedFields = []string{"firstname", "lastname"}
+
+// dataKeyLifetime is how long a container encrypts with the same data key, so writes don't
+// each cost a GenerateDataKey call
+const dataKeyLifetime = 5 * time.Minute
+
+// maxCachedDataKeys is how many unwrapped data keys are cached at once for decryption
+const maxCachedDataKeys = 1000
+
+// KeyService generates and unwraps the data keys encrypting names. *kms.KMS implements it.
+type KeyService interface {
+	GenerateDataKey(input *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error)
+}
+
+// Keys is the key service names are encrypted with; main sets it to a KMS client, and tests
+// can replace it with a stub.
+var Keys KeyService
+
+// dataKeys caches the data key current writes are encrypted with, and the unwrapped keys
+// of the items read, keyed by their wrapped form.
+var dataKeys = struct {
+	sync.Mutex
+	plaintext []byte
+	wrapped   []byte
+	expires   time.Time
+	unwrapped map[string][]byte
+}{unwrapped: map[string][]byte{}}
+
+// piiKeyARN returns the KMS key names are encrypted under (PII_KMS_KEY_ARN), or an empty
+// string if names are stored in plain text.
+func piiKeyARN() string {
+	return os.Getenv("PII_KMS_KEY_ARN")
+}
+
+// checkEncryptedFilter rejects filters that DynamoDB would have to evaluate on encrypted
+// names, which can't match ciphertext.
+//
+// Parameters:
+// - filter: The filter of a list or count.
+//
+// Returns:
+// - A validation error naming the filtered field, or nil.
+func checkEncryptedFilter(filter Filter) error {
+	if piiKeyARN() == "" {
+		return nil
+	}
+	switch {
+	case filter.FirstName != "":
+		return newFieldError(ErrValidation, ErrorEncryptedFieldFilter, "firstname", nil)
+	case filter.LastName != "":
+		return newFieldError(ErrValidation, ErrorEncryptedFieldFilter, "lastname", nil)
+	case filter.Query != "":
+		return newFieldError(ErrValidation, ErrorEncryptedFieldFilter, "q", nil)
+	}
+	return nil
+}
+
+// encryptNames replaces the names of an item with their ciphertext, nonce first, in place,
+// and stores the wrapped data key they were encrypted with in the item.
+func encryptNames(item map[string]*dynamodb.AttributeValue) error {
+	plaintext, wrapped, err := currentDataKey()
+	if err != nil {
+		return newError(ErrStorage, ErrorCouldNotEncryptNames, err)
+	}
+	aead, err := newAEAD(plaintext)
+	if err != nil {
+		return newError(ErrInternal, ErrorCouldNotEncryptNames, err)
+	}
+	for _, field := range encryptedFields {
+		value, ok := item[field]
+		if !ok || value.S == nil {
+			continue
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return newError(ErrInternal, ErrorCouldNotEncryptNames, err)
+		}
+		// The field name is authenticated, so a first name can't be swapped for a last name
+		sealed := aead.Seal(nonce, nonce, []byte(*value.S), []byte(field))
+		item[field] = &dynamodb.AttributeValue{B: sealed}
+	}
+	item[piiKeyAttribute] = &dynamodb.AttributeValue{B: wrapped}
+	return nil
+}
+
+// decryptNames replaces the encrypted names of an item with their plain text, in place.
+// Items without a wrapped data key are left as they are.
+func decryptNames(item map[string]*dynamodb.AttributeValue) error {
+	wrapped, ok := item[piiKeyAttribute]
+	if !ok || len(wrapped.B) == 0 {
+		return nil
+	}
+	plaintext, err := unwrapDataKey(wrapped.B)
+	if err != nil {
+		return newError(ErrStorage, ErrorCouldNotDecryptNames, err)
+	}
+	aead, err := newAEAD(plaintext)
+	if err != nil {
+		return newError(ErrInternal, ErrorCouldNotDecryptNames, err)
+	}
+	for _, field := range encryptedFields {
+		value, ok := item[field]
+		if !ok || value.B == nil {
+			continue
+		}
+		if len(value.B) < aead.NonceSize() {
+			return newError(ErrInternal, ErrorCouldNotDecryptNames, errors.New("ciphertext too short"))
+		}
+		nonce, sealed := value.B[:aead.NonceSize()], value.B[aead.NonceSize():]
+		opened, err := aead.Open(nil, nonce, sealed, []byte(field))
+		if err != nil {
+			return newError(ErrInternal, ErrorCouldNotDecryptNames, err)
+		}
+		item[field] = &dynamodb.AttributeValue{S: aws.String(string(opened))}
+	}
+	delete(item, piiKeyAttribute)
+	return nil
+}
+
+// currentDataKey returns the data key writes are encrypted with, generating a new one
+// under PII_KMS_KEY_ARN once the current one is dataKeyLifetime old.
+//
+// Returns:
+// - The plaintext data key.
+// - The data key wrapped by KMS, to store alongside the encrypted names.
+// - An error if no key service is set or KMS fails.
+func currentDataKey() ([]byte, []byte, error) {
+	dataKeys.Lock()
+	defer dataKeys.Unlock()
+	if dataKeys.plaintext != nil && time.Now().Before(dataKeys.expires) {
+		return dataKeys.plaintext, dataKeys.wrapped, nil
+	}
+	if Keys == nil {
+		return nil, nil, errors.New("no key service configured")
+	}
+
+	result, err := Keys.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(piiKeyARN()),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	dataKeys.plaintext, dataKeys.wrapped = result.Plaintext, result.CiphertextBlob
+	dataKeys.expires = time.Now().Add(dataKeyLifetime)
+	dataKeys.unwrapped[string(result.CiphertextBlob)] = result.Plaintext
+	return dataKeys.plaintext, dataKeys.wrapped, nil
+}
+
+// unwrapDataKey returns the plaintext of a wrapped data key, asking KMS only for keys not
+// seen by this container yet.
+func unwrapDataKey(wrapped []byte) ([]byte, error) {
+	dataKeys.Lock()
+	defer dataKeys.Unlock()
+	if plaintext, ok := dataKeys.unwrapped[string(wrapped)]; ok {
+		return plaintext, nil
+	}
+	if Keys == nil {
+		return nil, errors.New("no key service configured")
+	}
+
+	result, err := Keys.Decrypt(&kms.DecryptInput{CiphertextBlob: wrapped})
+	if err != nil {
+		return nil, err
+	}
+	if len(dataKeys.unwrapped) >= maxCachedDataKeys {
+		dataKeys.unwrapped = map[string][]byte{}
+	}
+	dataKeys.unwrapped[string(wrapped)] = result.Plaintext
+	return result.Plaintext, nil
+}
+
+// newAEAD returns the AES-GCM cipher of a data key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,183 @@
+package user
+
+import (
+	"bytes"
+	"errors"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"testing"
+)
+
+// stubKeys hands out one data key, wrapped as "wrapped", counting the calls to KMS
+type stubKeys struct {
+	err       error
+	generated int
+	decrypted int
+}
+
+// stubDataKey is the plaintext data key of stubKeys
+var stubDataKey = bytes.Repeat([]byte{7}, 32)
+
+func (k *stubKeys) GenerateDataKey(input *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	k.generated++
+	if k.err != nil {
+		return nil, k.err
+	}
+	return &kms.GenerateDataKeyOutput{Plaintext: stubDataKey, CiphertextBlob: []byte("wrapped")}, nil
+}
+
+func (k *stubKeys) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	k.decrypted++
+	if k.err != nil || string(input.CiphertextBlob) != "wrapped" {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return &kms.DecryptOutput{Plaintext: stubDataKey}, nil
+}
+
+// useKeys makes keys the key service of the test, with no data keys cached.
+func useKeys(t *testing.T, keys KeyService) {
+	t.Helper()
+	forgetDataKeys()
+	previous := Keys
+	Keys = keys
+	t.Cleanup(func() {
+		Keys = previous
+		forgetDataKeys()
+	})
+}
+
+// forgetDataKeys empties the data key cache, as in a new container.
+func forgetDataKeys() {
+	dataKeys.Lock()
+	defer dataKeys.Unlock()
+	dataKeys.plaintext, dataKeys.wrapped = nil, nil
+	dataKeys.unwrapped = map[string][]byte{}
+}
+
+func TestEncryptedNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		keyARN        string
+		coldRead      bool // Read with an empty data key cache, as another container would
+		wantEncrypted bool
+		wantDecrypts  int
+	}{
+		{name: "plain", wantEncrypted: false},
+		{name: "encrypted", keyARN: "arn:aws:kms:us-east-1:123456789012:key/pii", wantEncrypted: true},
+		{name: "encrypted, cold read", keyARN: "arn:aws:kms:us-east-1:123456789012:key/pii", coldRead: true,
+			wantEncrypted: true, wantDecrypts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &stubKeys{}
+			useKeys(t, keys)
+			t.Setenv("PII_KMS_KEY_ARN", tt.keyARN)
+			repo, table := newFakeTable(t)
+
+			if err := repo.Create(&User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 1}); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			item := table.items["ada@example.com"]
+			for _, field := range encryptedFields {
+				stored := item[field]
+				if encrypted := stored.B != nil && stored.S == nil; encrypted != tt.wantEncrypted {
+					t.Errorf("stored %s = %v, want encrypted: %v", field, stored, tt.wantEncrypted)
+				}
+				if bytes.Contains(stored.B, []byte("Ada")) || bytes.Contains(stored.B, []byte("Lovelace")) {
+					t.Errorf("stored %s = %q holds the plain name", field, stored.B)
+				}
+			}
+			if _, wrapped := item[piiKeyAttribute]; wrapped != tt.wantEncrypted {
+				t.Errorf("stored %s present: %v, want %v", piiKeyAttribute, wrapped, tt.wantEncrypted)
+			}
+			if email := KeyEmail(item); email != "ada@example.com" {
+				t.Errorf("stored key = %q, want the plain email", email)
+			}
+
+			if tt.coldRead {
+				forgetDataKeys()
+			}
+			got, err := FetchUser("ada@example.com", ReadOptions{}, repo)
+			if err != nil {
+				t.Fatalf("FetchUser() error = %v", err)
+			}
+			if got.FirstName != "Ada" || got.LastName != "Lovelace" {
+				t.Errorf("FetchUser() names = %q %q, want Ada Lovelace", got.FirstName, got.LastName)
+			}
+			if keys.decrypted != tt.wantDecrypts {
+				t.Errorf("Decrypt called %d times, want %d", keys.decrypted, tt.wantDecrypts)
+			}
+		})
+	}
+}
+
+func TestEncryptedNamesReadsPlainItems(t *testing.T) {
+	keys := &stubKeys{}
+	useKeys(t, keys)
+	// Ada was written before encryption was enabled
+	repo, table := newFakeTable(t, storedAda())
+	t.Setenv("PII_KMS_KEY_ARN", "arn:aws:kms:us-east-1:123456789012:key/pii")
+	if err := repo.Create(&User{Email: "grace@example.com", FirstName: "Grace", LastName: "Hopper", Version: 1}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	for email, want := range map[string]string{"ada@example.com": "Ada Lovelace", "grace@example.com": "Grace Hopper"} {
+		got, err := FetchUser(email, ReadOptions{}, repo)
+		if err != nil {
+			t.Fatalf("FetchUser(%q) error = %v", email, err)
+		}
+		if name := got.FirstName + " " + got.LastName; name != want {
+			t.Errorf("FetchUser(%q) names = %q, want %q", email, name, want)
+		}
+	}
+	if _, wrapped := table.items["ada@example.com"][piiKeyAttribute]; wrapped {
+		t.Errorf("reading the plain item rewrote it")
+	}
+}
+
+func TestEncryptedNamesKeyServiceFailure(t *testing.T) {
+	useKeys(t, &stubKeys{err: errors.New("access denied")})
+	t.Setenv("PII_KMS_KEY_ARN", "arn:aws:kms:us-east-1:123456789012:key/pii")
+	repo, table := newFakeTable(t)
+
+	err := repo.Create(&User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 1})
+	var userErr *Error
+	if !errors.As(err, &userErr) || userErr.Message != ErrorCouldNotEncryptNames || !errors.Is(err, ErrStorage) {
+		t.Errorf("Create() error = %v, want %q", err, ErrorCouldNotEncryptNames)
+	}
+	if len(table.items) != 0 {
+		t.Errorf("table = %v, want nothing written", table.items)
+	}
+}
+
+func TestEncryptedFieldFilters(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    Filter
+		wantField string
+	}{
+		{name: "first name", filter: Filter{FirstName: "Ada"}, wantField: "firstname"},
+		{name: "last name", filter: Filter{LastName: "Lovelace"}, wantField: "lastname"},
+		{name: "query", filter: Filter{Query: "ove"}, wantField: "q"},
+		{name: "domain", filter: Filter{Domain: "example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useKeys(t, &stubKeys{})
+			t.Setenv("PII_KMS_KEY_ARN", "arn:aws:kms:us-east-1:123456789012:key/pii")
+			repo, _ := newFakeTable(t)
+
+			_, err := FetchUsers(ReadOptions{Filter: tt.filter}, repo)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Errorf("FetchUsers() error = %v", err)
+				}
+				return
+			}
+			var userErr *Error
+			if !errors.As(err, &userErr) || userErr.Message != ErrorEncryptedFieldFilter || userErr.Field != tt.wantField ||
+				!errors.Is(err, ErrValidation) {
+				t.Errorf("FetchUsers() error = %v, want %q on %s", err, ErrorEncryptedFieldFilter, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package user
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxBulkDelete is how many users a bulk delete may remove unless MAX_BULK_DELETE says otherwise
+const defaultMaxBulkDelete = 1000
+
+// Error messages for bulk deletes
+var (
+	ErrorInvalidDomain   = "domain must be a domain name such as example.com"
+	ErrorTooManyToDelete = "too many users match; the bulk delete limit is "
+)
+
+// BulkDeleteResult is the outcome of deleting the users matching a filter
+type BulkDeleteResult struct {
+	DryRun  bool     `json:"dryRun"`           // Whether the users were only listed, not deleted
+	Matched int      `json:"matched"`          // Number of users matching the filter
+	Deleted int      `json:"deleted"`          // Number of users deleted
+	Emails  []string `json:"emails"`           // Emails that were (or in a dry run would be) deleted
+	Failed  []string `json:"failed,omitempty"` // Emails that could not be deleted
+}
+
+// maxBulkDelete returns the most users a bulk delete may remove (MAX_BULK_DELETE, 1000 by default).
+func maxBulkDelete() int {
+	limit, err := strconv.Atoi(os.Getenv("MAX_BULK_DELETE"))
+	if err != nil || limit < 1 {
+		return defaultMaxBulkDelete
+	}
+	return limit
+}
+
+// DeleteUsersByDomain deletes every user whose email belongs to the given domain.
+// Nothing is deleted when more users match than MAX_BULK_DELETE allows. Users are always
+// removed for good, including soft-deleted ones.
+//
+// Parameters:
+// - domain: The email domain, e.g. "example.com".
+// - dryRun: Whether to only report the users that would be deleted.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The matched and deleted emails, ordered by email.
+//   - A validation error for a malformed domain, ErrTooLarge if too many users match,
+//     or an error if the users could not be listed.
+func DeleteUsersByDomain(domain string, dryRun bool, repo Repository) (*BulkDeleteResult, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if domain == "" || strings.ContainsAny(domain, "@ ,") || !strings.Contains(domain, ".") {
+		return nil, newFieldError(ErrValidation, ErrorInvalidDomain, "domain", nil)
+	}
+
+	limit := maxBulkDelete()
+	filter := Filter{Domain: domain}
+	page, err := repo.List(ReadOptions{
+		ConsistentRead: true,
+		Fields:         []string{"email"},
+		MaxItems:       limit,
+		Filter:         filter,
+		IncludeDeleted: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if page.Truncated {
+		return nil, newError(ErrTooLarge, ErrorTooManyToDelete+strconv.Itoa(limit), nil)
+	}
+
+	// The storage-side filter is a substring match, so keep exact domain matches only
+	emails := []string{}
+	for i := range page.Users {
+		if filter.Matches(&page.Users[i]) {
+			emails = append(emails, page.Users[i].Email)
+		}
+	}
+	sort.Strings(emails)
+
+	result := &BulkDeleteResult{DryRun: dryRun, Matched: len(emails), Emails: emails}
+	if dryRun || len(emails) == 0 {
+		return result, nil
+	}
+
+	failed := repo.DeleteMany(emails)
+	result.Emails = []string{}
+	for _, email := range emails {
+		if _, ok := failed[email]; ok {
+			result.Failed = append(result.Failed, email)
+		} else {
+			result.Emails = append(result.Emails, email)
+		}
+	}
+	result.Deleted = len(result.Emails)
+	return result, nil
+}
@@ -0,0 +1,340 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/store"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrorConsistentReadOnIndex is returned when a strongly consistent read targets a global
+// secondary index, which DynamoDB only serves with eventually consistent reads
+var ErrorConsistentReadOnIndex = "consistent reads are not supported on global secondary indexes"
+
+// ReadOptions tune how users are read
+type ReadOptions struct {
+	ConsistentRead bool     // Use a strongly consistent read (twice the read capacity) instead of an eventually consistent one
+	Fields         []string // Attributes to read (see Fields), or nil for all of them
+	MaxItems       int      // Maximum number of users a list returns, or 0 for no limit
+	Filter         Filter   // Users a list or count includes
+	IncludeDeleted bool     // Also return soft-deleted users
+}
+
+// DefaultReadOptions returns the options used when the client didn't ask for any.
+// Reads are strongly consistent if CONSISTENT_READS is set to "true", and lists stop
+// after MAX_LIST_ITEMS users (1000 by default).
+func DefaultReadOptions() ReadOptions {
+	return ReadOptions{
+		ConsistentRead: os.Getenv("CONSISTENT_READS") == "true",
+		MaxItems:       maxListItems(),
+	}
+}
+
+// Page is the result of listing users
+type Page struct {
+	Users     []User // The users read
+	Truncated bool   // Whether more users exist beyond ReadOptions.MaxItems
+}
+
+// Repository stores users by email.
+// Emails are used exactly as given; normalization is up to the callers.
+type Repository interface {
+	// Get returns the user stored under the email, or an ErrNotFound error.
+	Get(email string, opts ReadOptions) (*User, error)
+	// List returns the stored users, up to opts.MaxItems.
+	List(opts ReadOptions) (*Page, error)
+	// Search returns the users whose email starts with prefix, up to limit, resuming after
+	// the page the cursor ends, or returns a validation error for a malformed cursor.
+	Search(prefix string, limit int, cursor string) (*SearchPage, error)
+	// Count returns the number of stored users.
+	Count(opts ReadOptions) (int64, error)
+	// Create stores a new user, or returns an ErrConflict error if the email is taken.
+	Create(u *User) error
+	// Update replaces a stored user if its version is still expectedVersion, or returns an
+	// ErrPreconditionFailed error. Users stored before versioning count as version 0.
+	Update(u *User, expectedVersion int) error
+	// Patch writes the given fields of u, as named in its JSON, if the stored user's version
+	// is still expectedVersion, or returns an ErrPreconditionFailed error. Fields u leaves
+	// empty are removed, and "tags.<key>" writes a single tag.
+	Patch(u *User, fields []string, expectedVersion int) error
+	// Delete removes the user stored under the email, or returns an ErrNotFound error.
+	Delete(email string) error
+
+	// GetMany returns the users stored under the emails, skipping emails without a user.
+	GetMany(emails []string, opts ReadOptions) ([]User, error)
+	// PutMany stores the users, overwriting existing ones, and returns the failures by email.
+	PutMany(users []User) map[string]error
+	// DeleteMany removes the users stored under the emails and returns the failures by email.
+	DeleteMany(emails []string) map[string]error
+
+	// SoftDelete flags the user stored under the email as deleted at deletedAt, or returns
+	// an ErrNotFound error if there is no such user or it is already deleted.
+	SoftDelete(email string, deletedAt string) error
+	// Restore clears the deleted flag of the user stored under the email, or returns an
+	// ErrConflict error if it isn't deleted.
+	Restore(email string, restoredAt string) (*User, error)
+	// SetStatus sets the status of the user stored under the email, or returns an
+	// ErrConflict error if there is no such user or it already has the status.
+	SetStatus(email string, status string, updatedAt string) (*User, error)
+	// Move stores u under its email and removes the user stored under oldEmail atomically,
+	// or returns an ErrConflict error if the new email is taken or an ErrPreconditionFailed
+	// error if the old user's version isn't expectedVersion.
+	Move(oldEmail string, u *User, expectedVersion int) error
+	// Merge stores u and removes the user stored under duplicateEmail atomically, or returns
+	// an ErrPreconditionFailed error if either user is gone or no longer at the given version.
+	Merge(u *User, expectedVersion int, duplicateEmail string, duplicateVersion int) error
+	// SetPassword stores a new password hash for the user stored under the email if its
+	// version is still expectedVersion, or returns an ErrPreconditionFailed error.
+	SetPassword(email string, hash string, updatedAt string, expectedVersion int) (*User, error)
+	// MarkVerified sets verified on the user stored under the email and removes its token if
+	// the stored token is still tokenHash and unexpired at now, or returns an ErrGone error.
+	MarkVerified(email string, tokenHash string, now string) (*User, error)
+	// SetVerifyToken stores the verification token fields of u if the stored token hash is
+	// still previousHash, or returns an ErrRateLimited error.
+	SetVerifyToken(u *User, previousHash string) error
+}
+
+// DynamoRepository is the Repository backed by a DynamoDB table keyed by the email, under
+// the KEY_ATTRIBUTE attribute
+type DynamoRepository struct {
+	TableName  string                    // The name of the DynamoDB table
+	DynaClient dynamodbiface.DynamoDBAPI // The DynamoDB client interface
+	Context    context.Context           // Parent of the contexts of cancellable calls; context.Background() if nil
+	RequestID  string                    // ID of the request the writes are made for, recorded in outbox entries
+}
+
+// NewDynamoRepository creates a Repository storing users in a DynamoDB table.
+//
+// Parameters:
+// - tableName: The name of the DynamoDB table.
+// - dynaClient: The DynamoDB client interface.
+//
+// Returns:
+// - The DynamoDB-backed repository.
+func NewDynamoRepository(tableName string, dynaClient dynamodbiface.DynamoDBAPI) *DynamoRepository {
+	return &DynamoRepository{TableName: tableName, DynaClient: dynaClient}
+}
+
+// Get retrieves the user stored under exactly the given email.
+func (r *DynamoRepository) Get(email string, opts ReadOptions) (*User, error) {
+	read := store.ReadOptions{ConsistentRead: opts.ConsistentRead}
+	if len(opts.Fields) > 0 {
+		read.Projection, read.Names = projection(opts.Fields)
+	}
+
+	u, err := r.store().Get(email, read)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, newError(ErrNotFound, ErrorUserDoesNotExist, nil)
+	}
+	if err != nil {
+		return nil, storeError(err, ErrorFailedToFetchRecord)
+	}
+	return u, nil
+}
+
+// List retrieves the users with a table scan, paging until the table is exhausted or
+// opts.MaxItems users were read. The filter is applied server-side by DynamoDB, and
+// expired users, as well as soft-deleted ones unless opts.IncludeDeleted is set, are skipped.
+// Lists filtered on lastname alone query the LASTNAME_INDEX index instead, when configured,
+// and lists filtered on a creation time range the CREATED_INDEX index, newest first. With
+// SCAN_SEGMENTS above 1, the segments are scanned in parallel.
+// In single-table mode, the entity index is queried for the user profiles instead.
+func (r *DynamoRepository) List(opts ReadOptions) (*Page, error) {
+	if err := checkEncryptedFilter(opts.Filter); err != nil {
+		return nil, err
+	}
+	input := &dynamodb.ScanInput{
+		TableName:              aws.String(r.TableName),
+		ConsistentRead:         aws.Bool(opts.ConsistentRead),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+	if len(opts.Fields) > 0 {
+		input.ProjectionExpression, input.ExpressionAttributeNames = projection(opts.Fields)
+	}
+	opts.Filter.applyToScan(input)
+	input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues = visibleOnly(opts,
+		input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+
+	maxItems := opts.MaxItems
+	if maxItems <= 0 {
+		maxItems = math.MaxInt32
+	}
+
+	// Query the creation or lastname index when it can serve the filter, or the entity
+	// index in single-table mode, otherwise scan the table
+	var scanned []map[string]*dynamodb.AttributeValue
+	var truncated bool
+	var err error
+	warnCreatedScan(opts.Filter)
+	switch {
+	case usesCreatedIndex(opts.Filter):
+		scanned, truncated, err = r.queryByCreated(opts, maxItems)
+		if err != nil {
+			return nil, err
+		}
+	case usesLastNameIndex(opts.Filter):
+		scanned, truncated, err = r.queryByLastName(opts, maxItems)
+		if err != nil {
+			return nil, err
+		}
+	case SingleTable():
+		query, err := entityQuery(input)
+		if err != nil {
+			return nil, err
+		}
+		if scanned, truncated, err = r.query(query, maxItems); err != nil {
+			return nil, err
+		}
+	default:
+		scanned, truncated, err = r.store().ScanItems(input, scanSegments(), maxItems)
+		if err != nil {
+			return nil, newError(ErrStorage, ErrorFailedToFetchRecord, err)
+		}
+	}
+
+	// Unmarshal the result into a slice of User structs
+	page := &Page{Users: []User{}, Truncated: truncated}
+	if err := unmarshalUsers(scanned, &page.Users); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Count counts the users with a Select COUNT scan, which reads every item but returns
+// none, paging until the table is exhausted. In single-table mode the entity index is
+// queried instead.
+func (r *DynamoRepository) Count(opts ReadOptions) (int64, error) {
+	if err := checkEncryptedFilter(opts.Filter); err != nil {
+		return 0, err
+	}
+	input := &dynamodb.ScanInput{
+		TableName:              aws.String(r.TableName),
+		ConsistentRead:         aws.Bool(opts.ConsistentRead),
+		Select:                 aws.String(dynamodb.SelectCount),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+	opts.Filter.applyToScan(input)
+	input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues = visibleOnly(opts,
+		input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+
+	if SingleTable() {
+		return r.countEntities(input)
+	}
+
+	var count int64
+	for {
+		result, err := r.DynaClient.Scan(input)
+		if err != nil {
+			return 0, newError(ErrStorage, ErrorFailedToFetchRecord, err)
+		}
+		count += aws.Int64Value(result.Count)
+		if len(result.LastEvaluatedKey) == 0 {
+			return count, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// countEntities counts the users of a Select COUNT scan with a query of the entity index
+// instead, for single-table mode.
+func (r *DynamoRepository) countEntities(scan *dynamodb.ScanInput) (int64, error) {
+	input, err := entityQuery(scan)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for {
+		result, err := r.DynaClient.Query(input)
+		if err != nil {
+			return 0, newError(ErrStorage, ErrorFailedToFetchRecord, err)
+		}
+		count += aws.Int64Value(result.Count)
+		if len(result.LastEvaluatedKey) == 0 {
+			return count, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// Create inserts a new user, guarding against a concurrent create of the same email. The
+// creation is recorded in the outbox when OUTBOX_TABLE_NAME is set.
+func (r *DynamoRepository) Create(u *User) error {
+	err := r.putUser(u, &store.Condition{
+		Expression: "attribute_not_exists(#key)",
+		Names:      withKeyName(nil),
+	}, "created")
+	if errors.Is(err, store.ErrConditionFailed) {
+		return newError(ErrConflict, ErrorUserAlreadyExists, err)
+	}
+	return storeError(err, ErrorCouldNotDynamoPutItem)
+}
+
+// Update replaces a user only if it still exists and nobody else wrote it since
+// expectedVersion was read. The update is recorded in the outbox when OUTBOX_TABLE_NAME
+// is set.
+func (r *DynamoRepository) Update(u *User, expectedVersion int) error {
+	err := r.putUser(u, &store.Condition{
+		Expression: "attribute_exists(#key) AND (" + versionCondition(expectedVersion) + ")",
+		Names:      withKeyName(map[string]*string{"#version": aws.String("version")}),
+		Values:     versionValues(expectedVersion),
+	}, "updated")
+	if errors.Is(err, store.ErrConditionFailed) {
+		return newError(ErrPreconditionFailed, ErrorVersionMismatch, err)
+	}
+	return storeError(err, ErrorCouldNotDynamoPutItem)
+}
+
+// Delete removes the user stored under exactly the given email. The deletion is recorded in
+// the outbox when OUTBOX_TABLE_NAME is set.
+func (r *DynamoRepository) Delete(email string) error {
+	// Fail if there is nothing to delete
+	err := r.deleteUser(email, &store.Condition{
+		Expression: "attribute_exists(#key)",
+		Names:      withKeyName(nil),
+	})
+	if errors.Is(err, store.ErrConditionFailed) {
+		return newError(ErrNotFound, ErrorUserDoesNotExist, err)
+	}
+	return storeError(err, ErrorCouldNotDeleteItem)
+}
+
+// projection builds a ProjectionExpression reading the given attributes.
+// Every name goes through a placeholder so reserved words are safe. Reading either name
+// also reads the wrapped data key they may be encrypted with.
+//
+// Parameters:
+// - fields: The attribute names to read.
+//
+// Returns:
+// - The projection expression, e.g. "#f0, #f1".
+// - The ExpressionAttributeNames mapping the placeholders to the attribute names.
+func projection(fields []string) (*string, map[string]*string) {
+	for _, field := range fields {
+		if field == "firstname" || field == "lastname" {
+			fields = WithFields(fields, piiKeyAttribute)
+			break
+		}
+	}
+	placeholders := make([]string, len(fields))
+	names := make(map[string]*string, len(fields))
+	for i, field := range fields {
+		placeholders[i] = "#f" + strconv.Itoa(i)
+		names[placeholders[i]] = aws.String(attributeName(field))
+	}
+	return aws.String(strings.Join(placeholders, ", ")), names
+}
+
+// isConditionalCheckFailed reports whether err is a DynamoDB conditional check failure.
+func isConditionalCheckFailed(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
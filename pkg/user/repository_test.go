@@ -0,0 +1,412 @@
+package user
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"strings"
+	"testing"
+)
+
+// fakeTable is a users table backed by a FakeDynamo, keeping the items it is sent by email
+type fakeTable struct {
+	fake  *mocks.FakeDynamo
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+// newFakeTable returns a DynamoRepository over a fakeTable holding the given users. Reads,
+// puts, deletes and transactions are served from the items, checking the existence and
+// version conditions the repository writes with; other calls are up to the test.
+func newFakeTable(t *testing.T, users ...User) (*DynamoRepository, *fakeTable) {
+	t.Helper()
+	table := &fakeTable{fake: mocks.NewFakeDynamo(), items: map[string]map[string]*dynamodb.AttributeValue{}}
+	for i := range users {
+		item, err := marshalUser(&users[i])
+		if err != nil {
+			t.Fatalf("seeding: %v", err)
+		}
+		table.items[users[i].Email] = item
+	}
+	table.fake.OnGetItem(func(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+		return &dynamodb.GetItemOutput{Item: table.items[KeyEmail(in.Key)]}, nil
+	})
+	table.fake.OnPutItem(func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+		email := KeyEmail(in.Item)
+		if !table.holds(email, in.ConditionExpression, in.ExpressionAttributeValues) {
+			return nil, mocks.ConditionalCheckFailedError()
+		}
+		table.items[email] = in.Item
+		return &dynamodb.PutItemOutput{}, nil
+	})
+	table.fake.OnDeleteItem(func(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+		email := KeyEmail(in.Key)
+		if !table.holds(email, in.ConditionExpression, in.ExpressionAttributeValues) {
+			return nil, mocks.ConditionalCheckFailedError()
+		}
+		delete(table.items, email)
+		return &dynamodb.DeleteItemOutput{}, nil
+	})
+	table.fake.OnTransactWriteItems(func(in *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+		for _, write := range in.TransactItems {
+			switch {
+			case write.Put != nil:
+				table.items[KeyEmail(write.Put.Item)] = write.Put.Item
+			case write.Delete != nil:
+				delete(table.items, KeyEmail(write.Delete.Key))
+			}
+		}
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	})
+	return NewDynamoRepository("users", table.fake), table
+}
+
+// holds reports whether a condition of the repository holds for the item stored under an
+// email. Only the conditions it writes are understood: on the key's existence, and on the
+// version, where an item without one is at version 0.
+func (f *fakeTable) holds(email string, condition *string, values map[string]*dynamodb.AttributeValue) bool {
+	item, exists := f.items[email]
+	expression := aws.StringValue(condition)
+	switch {
+	case strings.Contains(expression, "attribute_not_exists(#key)") && exists:
+		return false
+	case strings.Contains(expression, "attribute_exists(#key)") && !exists:
+		return false
+	case strings.Contains(expression, "#version = :expected"):
+		version := "0"
+		if item["version"] != nil {
+			version = aws.StringValue(item["version"].N)
+		}
+		return version == aws.StringValue(values[":expected"].N)
+	}
+	return true
+}
+
+// snapshot copies the items of the table, to compare them after a failed write.
+func (f *fakeTable) snapshot() map[string]map[string]*dynamodb.AttributeValue {
+	items := make(map[string]map[string]*dynamodb.AttributeValue, len(f.items))
+	for email, item := range f.items {
+		items[email] = item
+	}
+	return items
+}
+
+// storedAda is the user the repository tests start from
+func storedAda() User {
+	return User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 2,
+		CreatedAt: "2021-01-01T00:00:00Z", UpdatedAt: "2021-01-02T00:00:00Z", Status: StatusActive, Role: RoleUser}
+}
+
+func TestDynamoRepositoryGet(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		opts    ReadOptions
+		getErr  error
+		wantErr error
+	}{
+		{name: "found", email: "ada@example.com"},
+		{name: "consistent projection", email: "ada@example.com", opts: ReadOptions{ConsistentRead: true, Fields: []string{"email", "version"}}},
+		{name: "missing", email: "bob@example.com", wantErr: ErrNotFound},
+		{name: "throttled", email: "ada@example.com", getErr: mocks.ThrottlingError(), wantErr: ErrStorage},
+		{name: "table missing", email: "ada@example.com", getErr: mocks.ResourceNotFoundError(), wantErr: ErrStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, table := newFakeTable(t, storedAda())
+			if tt.getErr != nil {
+				table.fake.OnGetItem(func(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+					return nil, tt.getErr
+				})
+			}
+
+			got, err := repo.Get(tt.email, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get() error = %v, want %v", err, tt.wantErr)
+			}
+			input := table.fake.Inputs("GetItem")[0].(*dynamodb.GetItemInput)
+			if aws.StringValue(input.TableName) != "users" || aws.BoolValue(input.ConsistentRead) != tt.opts.ConsistentRead {
+				t.Errorf("GetItem table %q, consistent %v; want users, %v", aws.StringValue(input.TableName),
+					aws.BoolValue(input.ConsistentRead), tt.opts.ConsistentRead)
+			}
+			if (input.ProjectionExpression != nil) != (len(tt.opts.Fields) > 0) {
+				t.Errorf("ProjectionExpression = %v, want one for fields %v", aws.StringValue(input.ProjectionExpression), tt.opts.Fields)
+			}
+			if tt.wantErr == nil && (got.Email != tt.email || got.Version != 2) {
+				t.Errorf("Get() = %+v, want the stored user", got)
+			}
+		})
+	}
+}
+
+func TestDynamoRepositoryCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		putErr  error
+		wantErr error
+	}{
+		{name: "new", email: "bob@example.com"},
+		{name: "taken", email: "ada@example.com", wantErr: ErrConflict},
+		{name: "throttled", email: "bob@example.com", putErr: mocks.ThrottlingError(), wantErr: ErrStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, table := newFakeTable(t, storedAda())
+			if tt.putErr != nil {
+				table.fake.OnPutItem(func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+					return nil, tt.putErr
+				})
+			}
+			before := table.snapshot()
+
+			err := repo.Create(&User{Email: tt.email, FirstName: "Bob", LastName: "Smith", Version: 1})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(table.items) != len(before) || table.items["ada@example.com"]["firstname"] != before["ada@example.com"]["firstname"] {
+					t.Errorf("items = %v, want them unchanged", table.items)
+				}
+				return
+			}
+			if got, err := repo.Get(tt.email, ReadOptions{}); err != nil || got.FirstName != "Bob" {
+				t.Errorf("Get() = %+v, %v; want the created user", got, err)
+			}
+		})
+	}
+}
+
+func TestDynamoRepositoryUpdate(t *testing.T) {
+	legacy := storedAda()
+	legacy.Version = 0
+	tests := []struct {
+		name            string
+		stored          User
+		missing         bool // The user isn't stored
+		expectedVersion int
+		putErr          error
+		wantErr         error
+	}{
+		{name: "current version", stored: storedAda(), expectedVersion: 2},
+		{name: "stale version", stored: storedAda(), expectedVersion: 1, wantErr: ErrPreconditionFailed},
+		{name: "unversioned record", stored: legacy, expectedVersion: 0},
+		{name: "missing user", stored: legacy, missing: true, expectedVersion: 0, wantErr: ErrPreconditionFailed},
+		{name: "throttled", stored: storedAda(), expectedVersion: 2, putErr: mocks.ThrottlingError(), wantErr: ErrStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seeded []User
+			if !tt.missing {
+				seeded = append(seeded, tt.stored)
+			}
+			repo, table := newFakeTable(t, seeded...)
+			if tt.putErr != nil {
+				table.fake.OnPutItem(func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+					return nil, tt.putErr
+				})
+			}
+
+			updated := tt.stored
+			updated.LastName, updated.Version = "King", tt.expectedVersion+1
+			err := repo.Update(&updated, tt.expectedVersion)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update() error = %v, want %v", err, tt.wantErr)
+			}
+			got, err := repo.Get(tt.stored.Email, ReadOptions{})
+			if tt.missing {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("Get() = %+v, %v; want the user still missing", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			wantLastName := "King"
+			if tt.wantErr != nil {
+				wantLastName = "Lovelace"
+			}
+			if got.LastName != wantLastName {
+				t.Errorf("stored lastname = %q, want %q", got.LastName, wantLastName)
+			}
+		})
+	}
+}
+
+func TestDynamoRepositoryDelete(t *testing.T) {
+	tests := []struct {
+		name      string
+		email     string
+		deleteErr error
+		wantErr   error
+	}{
+		{name: "stored", email: "ada@example.com"},
+		{name: "missing", email: "bob@example.com", wantErr: ErrNotFound},
+		{name: "throttled", email: "ada@example.com", deleteErr: mocks.ThrottlingError(), wantErr: ErrStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, table := newFakeTable(t, storedAda())
+			if tt.deleteErr != nil {
+				table.fake.OnDeleteItem(func(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+					return nil, tt.deleteErr
+				})
+			}
+
+			err := repo.Delete(tt.email)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Delete() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, stored := table.items["ada@example.com"]; stored != (tt.wantErr != nil) {
+				t.Errorf("user stored = %v after Delete() error %v", stored, err)
+			}
+		})
+	}
+}
+
+// TestUserOperationsOnDynamo runs the four user operations over a FakeDynamo, failing each
+// DynamoDB call they make in turn.
+func TestUserOperationsOnDynamo(t *testing.T) {
+	const body = `{"email": "ada@example.com", "firstname": "Ada", "lastname": "King"}`
+	operations := map[string]func(repo Repository) error{
+		"fetch": func(repo Repository) error {
+			_, err := FetchUser("Ada@Example.com", ReadOptions{}, repo)
+			return err
+		},
+		"create": func(repo Repository) error {
+			_, err := CreateUserFromJSON(strings.Replace(body, "ada@", "bob@", 1), CreateOptions{}, repo)
+			return err
+		},
+		"update": func(repo Repository) error {
+			_, _, err := UpdateUser(events.APIGatewayProxyRequest{Body: body}, "ada@example.com", 0, repo)
+			return err
+		},
+		"delete": func(repo Repository) error {
+			return DeleteUser("ada@example.com", true, repo)
+		},
+	}
+	tests := []struct {
+		name      string
+		operation string
+		fail      func(fake *mocks.FakeDynamo)
+		wantErr   error
+	}{
+		{name: "fetch", operation: "fetch"},
+		{name: "fetch throttled", operation: "fetch", fail: failGet(mocks.ThrottlingError()), wantErr: ErrStorage},
+		{name: "create", operation: "create"},
+		{name: "create lookup throttled", operation: "create", fail: failGet(mocks.ThrottlingError()), wantErr: ErrStorage},
+		{name: "create raced", operation: "create", fail: failPut(mocks.ConditionalCheckFailedError()), wantErr: ErrConflict},
+		{name: "create throttled", operation: "create", fail: failPut(mocks.ThrottlingError()), wantErr: ErrStorage},
+		{name: "update", operation: "update"},
+		{name: "update lookup throttled", operation: "update", fail: failGet(mocks.ThrottlingError()), wantErr: ErrStorage},
+		{name: "update raced", operation: "update", fail: failPut(mocks.ConditionalCheckFailedError()), wantErr: ErrPreconditionFailed},
+		{name: "update throttled", operation: "update", fail: failPut(mocks.ThrottlingError()), wantErr: ErrStorage},
+		{name: "delete", operation: "delete"},
+		{name: "delete raced", operation: "delete", fail: failDelete(mocks.ConditionalCheckFailedError()), wantErr: ErrNotFound},
+		{name: "delete throttled", operation: "delete", fail: failDelete(mocks.ThrottlingError()), wantErr: ErrStorage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, table := newFakeTable(t, storedAda())
+			if tt.fail != nil {
+				tt.fail(table.fake)
+			}
+			if err := operations[tt.operation](repo); !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s error = %v, want %v", tt.operation, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// failGet, failPut and failDelete make every call of one operation fail with err.
+func failGet(err error) func(fake *mocks.FakeDynamo) {
+	return func(fake *mocks.FakeDynamo) {
+		fake.OnGetItem(func(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) { return nil, err })
+	}
+}
+
+func failPut(err error) func(fake *mocks.FakeDynamo) {
+	return func(fake *mocks.FakeDynamo) {
+		fake.OnPutItem(func(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) { return nil, err })
+	}
+}
+
+func failDelete(err error) func(fake *mocks.FakeDynamo) {
+	return func(fake *mocks.FakeDynamo) {
+		fake.OnDeleteItem(func(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) { return nil, err })
+	}
+}
+
+func TestDynamoRepositoryReturnsConsumedCapacity(t *testing.T) {
+	tests := []struct {
+		name          string
+		outbox        string
+		index         string
+		call          func(repo *DynamoRepository) error
+		wantOperation string
+	}{
+		{name: "get", call: func(repo *DynamoRepository) error {
+			_, err := repo.Get("ada@example.com", ReadOptions{})
+			return err
+		}, wantOperation: "GetItem"},
+		{name: "list", call: func(repo *DynamoRepository) error {
+			_, err := repo.List(ReadOptions{})
+			return err
+		}, wantOperation: "Scan"},
+		{name: "list by last name", index: "lastname-index", call: func(repo *DynamoRepository) error {
+			_, err := repo.List(ReadOptions{Filter: Filter{LastName: "Lovelace"}})
+			return err
+		}, wantOperation: "Query"},
+		{name: "create", call: func(repo *DynamoRepository) error {
+			return repo.Create(&User{Email: "bob@example.com", FirstName: "Bob", LastName: "Barker", Version: 1})
+		}, wantOperation: "PutItem"},
+		{name: "update", call: func(repo *DynamoRepository) error {
+			u := storedAda()
+			u.LastName, u.Version = "King", 3
+			return repo.Update(&u, 2)
+		}, wantOperation: "PutItem"},
+		{name: "delete", call: func(repo *DynamoRepository) error {
+			return repo.Delete("ada@example.com")
+		}, wantOperation: "DeleteItem"},
+		{name: "create with outbox", outbox: "outbox", call: func(repo *DynamoRepository) error {
+			return repo.Create(&User{Email: "bob@example.com", FirstName: "Bob", LastName: "Barker", Version: 1})
+		}, wantOperation: "TransactWriteItems"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OUTBOX_TABLE_NAME", tt.outbox)
+			t.Setenv("LASTNAME_INDEX", tt.index)
+			repo, table := newFakeTable(t, storedAda())
+
+			if err := tt.call(repo); err != nil {
+				t.Fatalf("%s error = %v", tt.name, err)
+			}
+			calls := table.fake.Calls()
+			if len(calls) == 0 || calls[len(calls)-1].Operation != tt.wantOperation {
+				t.Fatalf("calls = %v, want a %s", calls, tt.wantOperation)
+			}
+			for _, call := range calls {
+				var consumed *string
+				switch in := call.Input.(type) {
+				case *dynamodb.GetItemInput:
+					consumed = in.ReturnConsumedCapacity
+				case *dynamodb.PutItemInput:
+					consumed = in.ReturnConsumedCapacity
+				case *dynamodb.DeleteItemInput:
+					consumed = in.ReturnConsumedCapacity
+				case *dynamodb.ScanInput:
+					consumed = in.ReturnConsumedCapacity
+				case *dynamodb.QueryInput:
+					consumed = in.ReturnConsumedCapacity
+				case *dynamodb.TransactWriteItemsInput:
+					consumed = in.ReturnConsumedCapacity
+				}
+				if aws.StringValue(consumed) != dynamodb.ReturnConsumedCapacityTotal {
+					t.Errorf("%s ReturnConsumedCapacity = %q, want TOTAL", call.Operation, aws.StringValue(consumed))
+				}
+			}
+		})
+	}
+}
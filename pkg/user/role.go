@@ -0,0 +1,60 @@
+package user
+
+// Roles of a user. Users stored before roles existed are plain users.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Error messages for user roles
+var (
+	ErrorInvalidRole         = "role must be user or admin"
+	ErrorRoleChangeForbidden = "only an administrator can change a user's role"
+)
+
+// ParseRole checks a role given by a client.
+//
+// Parameters:
+// - raw: The role, or an empty string for none.
+//
+// Returns:
+// - A validation error if the role is set but unknown.
+func ParseRole(raw string) error {
+	if raw != "" && raw != RoleUser && raw != RoleAdmin {
+		return newFieldError(ErrValidation, ErrorInvalidRole, "role", nil)
+	}
+	return nil
+}
+
+// CurrentRole returns the role of the user, which is user if none is stored.
+func (u *User) CurrentRole() string {
+	if u.Role == "" {
+		return RoleUser
+	}
+	return u.Role
+}
+
+// resolveRole decides the role a write stores when it asks for requested over current.
+// Callers that may not change roles keep the current one, or fail if reject is set.
+//
+// Parameters:
+// - current: The stored role, or RoleUser for a new user.
+// - requested: The role in the body, or an empty string to keep the current one.
+// - allowed: Whether the caller may change roles, as administrators may.
+// - reject: Whether a forbidden change fails instead of being ignored.
+//
+// Returns:
+// - The role to store.
+// - An ErrForbidden error if the change is forbidden and reject is set.
+func resolveRole(current string, requested string, allowed bool, reject bool) (string, error) {
+	if requested == "" || requested == current {
+		return current, nil
+	}
+	if allowed {
+		return requested, nil
+	}
+	if reject {
+		return "", newFieldError(ErrForbidden, ErrorRoleChangeForbidden, "role", nil)
+	}
+	return current, nil
+}
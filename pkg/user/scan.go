@@ -0,0 +1,27 @@
+package user
+
+import (
+	"os"
+	"strconv"
+)
+
+// defaultMaxListItems is how many users a list returns when MAX_LIST_ITEMS is unset
+const defaultMaxListItems = 1000
+
+// scanSegments returns how many segments FetchUsers scans in parallel (SCAN_SEGMENTS, default 1).
+func scanSegments() int {
+	segments, err := strconv.Atoi(os.Getenv("SCAN_SEGMENTS"))
+	if err != nil || segments < 1 {
+		return 1
+	}
+	return segments
+}
+
+// maxListItems returns how many users a list returns at most (MAX_LIST_ITEMS, default 1000).
+func maxListItems() int {
+	maxItems, err := strconv.Atoi(os.Getenv("MAX_LIST_ITEMS"))
+	if err != nil || maxItems < 1 {
+		return defaultMaxListItems
+	}
+	return maxItems
+}
@@ -0,0 +1,151 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// segmentedTable returns a DynamoRepository over a FakeDynamo whose scan serves every
+// segment in pages of pageSize users, pages per segment, after a delay per page. The
+// returned function reports how many times each segment was started.
+func segmentedTable(pages int, pageSize int, delay time.Duration, fail map[int64]error) (*DynamoRepository,
+	func() map[int64]int) {
+	var mu sync.Mutex
+	started := map[int64]int{}
+	fake := mocks.NewFakeDynamo()
+	fake.OnScan(func(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+		segment := aws.Int64Value(in.Segment)
+		page := 0
+		if in.ExclusiveStartKey != nil {
+			page, _ = strconv.Atoi(aws.StringValue(in.ExclusiveStartKey["page"].N))
+		} else {
+			mu.Lock()
+			started[segment]++
+			mu.Unlock()
+		}
+		time.Sleep(delay)
+		if err := fail[segment]; err != nil {
+			return nil, err
+		}
+
+		out := &dynamodb.ScanOutput{}
+		for i := 0; i < pageSize; i++ {
+			email := fmt.Sprintf("user-%d-%d-%d@example.com", segment, page, i)
+			out.Items = append(out.Items, map[string]*dynamodb.AttributeValue{"email": {S: aws.String(email)}})
+		}
+		if page+1 < pages {
+			out.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{"page": {N: aws.String(strconv.Itoa(page + 1))}}
+		}
+		return out, nil
+	})
+	return NewDynamoRepository("users", fake), func() map[int64]int {
+		mu.Lock()
+		defer mu.Unlock()
+		return started
+	}
+}
+
+func TestFetchUsersScansEverySegmentOnce(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments string
+		want     int
+	}{
+		{name: "unset", segments: "", want: 1},
+		{name: "invalid", segments: "many", want: 1},
+		{name: "one", segments: "1", want: 1},
+		{name: "four", segments: "4", want: 4},
+		{name: "sixteen", segments: "16", want: 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SCAN_SEGMENTS", tt.segments)
+			repo, started := segmentedTable(3, 2, 0, nil)
+
+			page, err := FetchUsers(ReadOptions{}, repo)
+			if err != nil {
+				t.Fatalf("FetchUsers() error = %v", err)
+			}
+			if len(started()) != tt.want {
+				t.Errorf("scanned segments %v, want %d", started(), tt.want)
+			}
+			for segment, times := range started() {
+				if segment < 0 || segment >= int64(tt.want) || times != 1 {
+					t.Errorf("segment %d scanned %d times, want once", segment, times)
+				}
+			}
+			if len(page.Users) != tt.want*3*2 || page.Truncated {
+				t.Errorf("FetchUsers() returned %d users (truncated %v), want %d", len(page.Users), page.Truncated,
+					tt.want*3*2)
+			}
+		})
+	}
+}
+
+func TestFetchUsersSegmentFailure(t *testing.T) {
+	t.Setenv("SCAN_SEGMENTS", "4")
+	repo, _ := segmentedTable(3, 2, 0, map[int64]error{2: mocks.ThrottlingError()})
+
+	page, err := FetchUsers(ReadOptions{}, repo)
+	if !errors.Is(err, ErrStorage) || page != nil {
+		t.Errorf("FetchUsers() = %v, %v; want a storage error", page, err)
+	}
+}
+
+func BenchmarkFetchUsers(b *testing.B) {
+	for _, segments := range []int{1, 4, 16} {
+		b.Run("segments="+strconv.Itoa(segments), func(b *testing.B) {
+			b.Setenv("SCAN_SEGMENTS", strconv.Itoa(segments))
+			// The table holds the same 960 users whatever the segmentation
+			repo, _ := segmentedTable(960/(8*segments), 8, time.Millisecond, nil)
+			for i := 0; i < b.N; i++ {
+				if _, err := FetchUsers(ReadOptions{}, repo); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchUsersPaging(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxItems      string
+		want          int
+		wantTruncated bool
+	}{
+		{name: "all pages", want: 6},
+		{name: "cap above the table", maxItems: "10", want: 6},
+		{name: "cap at the table size", maxItems: "6", want: 6},
+		{name: "cap on a page boundary", maxItems: "2", want: 2, wantTruncated: true},
+		{name: "cap within a page", maxItems: "3", want: 3, wantTruncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_LIST_ITEMS", tt.maxItems)
+			repo, _ := segmentedTable(3, 2, 0, nil)
+
+			page, err := FetchUsers(DefaultReadOptions(), repo)
+			if err != nil {
+				t.Fatalf("FetchUsers() error = %v", err)
+			}
+			if len(page.Users) != tt.want || page.Truncated != tt.wantTruncated {
+				t.Fatalf("FetchUsers() returned %d users (truncated %v), want %d (truncated %v)", len(page.Users),
+					page.Truncated, tt.want, tt.wantTruncated)
+			}
+			// The pages are concatenated in the order they were read
+			for i, u := range page.Users {
+				if want := fmt.Sprintf("user-0-%d-%d@example.com", i/2, i%2); u.Email != want {
+					t.Errorf("user %d = %q, want %q", i, u.Email, want)
+				}
+			}
+		})
+	}
+}
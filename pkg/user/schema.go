@@ -0,0 +1,101 @@
+package user
+
+import (
+	"embed"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/schema"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"io/fs"
+	"strings"
+)
+
+// Names of the JSON Schemas of user documents
+const (
+	createSchema = "create.json" // Body of a create
+	updateSchema = "update.json" // Body of an update
+	patchSchema  = "patch.json"  // Body of a patch
+	userSchema   = "user.json"   // Fields every stored user must have valid
+)
+
+// ErrorMissingField is the message of a required field a body leaves out
+var ErrorMissingField = "field is required"
+
+// schemaFiles holds the JSON Schemas of user documents, named after the document
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// schemas is compiled once, at cold start. The schemas ship with the binary, so a
+// malformed one is a build error and panics.
+var schemas = compileSchemas()
+
+// compileSchemas compiles the embedded schemas with the formats they use: "email", checked
+// like any email, and "name", checked like a first or last name. Both are sanitized first,
+// as the decoded user will be.
+func compileSchemas() *schema.Registry {
+	files, err := fs.Sub(schemaFiles, "schemas")
+	if err != nil {
+		panic("user: invalid schemas: " + err.Error())
+	}
+	registry, err := schema.Compile(files, map[string]schema.Format{
+		"email": func(value string) error {
+			return validators.ValidateEmail(validators.SanitizeName(value))
+		},
+		"name": func(value string) error {
+			if !validators.IsNameValid(validators.SanitizeName(value)) {
+				return errors.New(ErrorInvalidUserData)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		panic("user: invalid schemas: " + err.Error())
+	}
+	return registry
+}
+
+// validateDocument checks a decoded JSON document against a schema, reporting every
+// violation as a field error.
+//
+// Parameters:
+// - name: The name of the schema, e.g. createSchema.
+// - document: The document, as decoded by encoding/json into an interface{}.
+//
+// Returns:
+//   - A validation error naming the invalid field, a validation error wrapping the
+//     ValidationErrors of every invalid field if there are several, or nil if the document
+//     is valid.
+func validateDocument(name string, document interface{}) error {
+	var invalid ValidationErrors
+	reported := map[FieldError]bool{}
+	for _, violation := range schemas.Schema(name).Validate(document) {
+		message := violation.Message
+		switch violation.Keyword {
+		case "type":
+			message = ErrorInvalidFieldType
+		case "additionalProperties", "false":
+			message = ErrorUnknownField
+		case "required":
+			message = ErrorMissingField
+		}
+		// A field breaking several keywords with one message, such as a name that is both
+		// empty and malformed, is reported once
+		field := strings.Join(schema.Tokens(violation.Pointer), ".")
+		if reported[FieldError{Field: field, Message: message}] {
+			continue
+		}
+		reported[FieldError{Field: field, Message: message}] = true
+		invalid.add(newFieldError(ErrValidation, message, field, nil))
+	}
+	return invalid.err()
+}
+
+// fieldPointer returns the JSON pointer of a field named like a FieldError, e.g.
+// "/address/city" for "address.city". Only the first dot separates names, since tag keys
+// may hold dots.
+func fieldPointer(field string) string {
+	if field == "" {
+		return ""
+	}
+	return schema.Pointer(strings.SplitN(field, ".", 2)...)
+}
@@ -0,0 +1,146 @@
+package user
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// invalidBodies are user bodies every kind of write rejects, with the field and message
+// of the error
+var invalidBodies = []struct {
+	name      string
+	body      string
+	wantField string
+	wantMsg   string
+}{
+	{name: "unknown field", body: `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace", "nickname": "A"}`,
+		wantField: "nickname", wantMsg: ErrorUnknownField},
+	{name: "unknown address field", body: `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace", "address": {"zip": "1"}}`,
+		wantField: "address.zip", wantMsg: ErrorUnknownField},
+	{name: "wrong type", body: `{"email": "ada@example.com", "firstname": "Ada", "lastname": 7}`,
+		wantField: "lastname", wantMsg: ErrorInvalidFieldType},
+	{name: "empty name", body: `{"email": "ada@example.com", "firstname": "", "lastname": "Lovelace"}`,
+		wantField: "firstname", wantMsg: "invalid firstname"},
+	{name: "name too long", body: `{"email": "ada@example.com", "firstname": "Ada", "lastname": "` + strings.Repeat("a", 101) + `"}`,
+		wantField: "lastname", wantMsg: "invalid lastname"},
+	{name: "negative ttl", body: `{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace", "ttlDays": -1}`,
+		wantField: "ttlDays", wantMsg: "ttlDays must not be negative"},
+}
+
+func TestCreateRejectsLikeBatch(t *testing.T) {
+	tests := append(invalidBodies, []struct {
+		name      string
+		body      string
+		wantField string
+		wantMsg   string
+	}{
+		{name: "missing email", body: `{"firstname": "Ada", "lastname": "Lovelace"}`, wantField: "email",
+			wantMsg: ErrorMissingField},
+		{name: "missing name", body: `{"email": "ada@example.com", "firstname": "Ada"}`, wantField: "lastname",
+			wantMsg: ErrorMissingField},
+		{name: "invalid email", body: `{"email": "ada@", "firstname": "Ada", "lastname": "Lovelace"}`,
+			wantField: "email"},
+	}...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, singleErr := CreateUserFromJSON(tt.body, CreateOptions{}, NewMemoryRepository())
+			if !errors.Is(singleErr, ErrValidation) || !hasFieldError(singleErr, tt.wantField, tt.wantMsg) {
+				t.Fatalf("CreateUserFromJSON() error = %v, want %q on %q", singleErr, tt.wantMsg, tt.wantField)
+			}
+			single := singleErr.(*Error)
+
+			valid := `{"email": "alan@example.com", "firstname": "Alan", "lastname": "Turing"}`
+			results, err := CreateUsers("["+valid+", "+tt.body+"]", CreateOptions{}, NewMemoryRepository())
+			if err != nil {
+				t.Fatalf("CreateUsers() error = %v", err)
+			}
+			got := results[1]
+			if results[0].Status != BatchCreated || got.Status != BatchFailed {
+				t.Fatalf("statuses = %q, %q; want created, failed", results[0].Status, got.Status)
+			}
+			if got.Error != single.Message || got.Field != single.Field || !reflect.DeepEqual(got.Fields, fieldErrors(singleErr)) {
+				t.Errorf("batch item = %q on %q %v, want the create's %v", got.Error, got.Field, got.Fields, singleErr)
+			}
+		})
+	}
+}
+
+func TestPatchRejectsLikeUpdate(t *testing.T) {
+	for _, tt := range invalidBodies {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, stored := seedUser(t)
+			_, _, err := PatchUser(stored.Email, tt.body, 0, UpdateOptions{}, repo)
+			if !errors.Is(err, ErrValidation) || !hasFieldError(err, tt.wantField, tt.wantMsg) {
+				t.Errorf("PatchUser() error = %v, want %q on %q", err, tt.wantMsg, tt.wantField)
+			}
+			if current, _ := repo.Get(stored.Email, ReadOptions{}); !reflect.DeepEqual(*current, stored) {
+				t.Errorf("stored user = %+v, want it unchanged", *current)
+			}
+		})
+	}
+}
+
+func TestPatchSchema(t *testing.T) {
+	tests := []struct {
+		name      string
+		patch     string
+		wantField string
+		wantMsg   string
+	}{
+		{name: "no fields", patch: `{}`},
+		{name: "removes optional fields", patch: `{"address": null, "tags": null, "ttlDays": null, "status": null}`},
+		{name: "removes a name", patch: `{"firstname": null}`, wantField: "firstname", wantMsg: ErrorInvalidFieldType},
+		{name: "removes the email", patch: `{"email": null}`, wantField: "email", wantMsg: ErrorInvalidFieldType},
+		{name: "unknown address field", patch: `{"address": {"zip": "1"}}`, wantField: "address.zip",
+			wantMsg: ErrorUnknownField},
+		{name: "tag of the wrong type", patch: `{"tags": {"plan": 1}}`, wantField: "tags.plan",
+			wantMsg: ErrorInvalidFieldType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch map[string]interface{}
+			if err := DecodeJSON(tt.patch, &patch); err != nil {
+				t.Fatalf("decoding the patch: %v", err)
+			}
+			err := validateDocument(patchSchema, patch)
+			if tt.wantMsg == "" {
+				if err != nil {
+					t.Errorf("validateDocument() error = %v, want nil", err)
+				}
+				return
+			}
+			if !hasFieldError(err, tt.wantField, tt.wantMsg) {
+				t.Errorf("validateDocument() error = %v, want %q on %q", err, tt.wantMsg, tt.wantField)
+			}
+		})
+	}
+}
+
+// fieldErrors returns the fields a validation error reports, like a batch result does:
+// every invalid field for an error wrapping ValidationErrors, and none for an error about
+// a single field.
+func fieldErrors(err error) []FieldError {
+	var invalid ValidationErrors
+	if errors.As(err, &invalid) {
+		return invalid.Fields()
+	}
+	return nil
+}
+
+// hasFieldError reports whether a validation error concerns field, with the message if
+// one is given.
+func hasFieldError(err error, field string, message string) bool {
+	reported := fieldErrors(err)
+	var userErr *Error
+	if len(reported) == 0 && errors.As(err, &userErr) {
+		reported = []FieldError{userErr.FieldError()}
+	}
+	for _, fieldErr := range reported {
+		if fieldErr.Field == field && (message == "" || fieldErr.Message == message) {
+			return true
+		}
+	}
+	return false
+}
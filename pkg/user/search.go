@@ -0,0 +1,221 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"strings"
+	"unicode/utf8"
+)
+
+// MinSearchPrefix is the shortest email prefix a search accepts, so a search can't dump the table
+const MinSearchPrefix = 2
+
+// Error messages for searches
+var (
+	ErrorPrefixTooShort        = "prefix must be at least 2 characters"
+	ErrorInvalidSearchCursor   = "invalid cursor"
+	ErrorInvalidSearchPageSize = "limit must be a positive number"
+)
+
+// SearchPage is a page of the users an email search found
+type SearchPage struct {
+	Users      []User // The users found, in email order in single-table mode and in table order otherwise
+	NextCursor string // Cursor of the next page, or empty on the last one
+}
+
+// SearchUsers finds the users whose email starts with a prefix, for type-ahead. The prefix
+// is normalized like an email and matched literally.
+//
+// Parameters:
+// - prefix: The start of the emails, at least MinSearchPrefix characters.
+// - limit: The most users to return.
+// - cursor: The NextCursor of the previous page, or an empty string for the first one.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - The page of users found.
+// - A validation error for a short prefix or a malformed cursor, or an error if the read fails.
+func SearchUsers(prefix string, limit int, cursor string, repo Repository) (*SearchPage, error) {
+	prefix = NormalizeEmail(prefix)
+	if utf8.RuneCountInString(prefix) < MinSearchPrefix {
+		return nil, newFieldError(ErrValidation, ErrorPrefixTooShort, "prefix", nil)
+	}
+	if limit < 1 {
+		return nil, newFieldError(ErrValidation, ErrorInvalidSearchPageSize, "limit", nil)
+	}
+	return repo.Search(prefix, limit, cursor)
+}
+
+// Search reads the users whose email starts with prefix, paging until limit users were
+// found or the table is exhausted. The table is scanned with a begins_with filter on the
+// key; in single-table mode the entity index, sorted by PK, is queried with a begins_with
+// key condition instead. The prefix is passed as an expression value, so it is always
+// matched literally.
+func (r *DynamoRepository) Search(prefix string, limit int, cursor string) (*SearchPage, error) {
+	startKey, err := decodeSearchCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var read func(startKey map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue,
+		map[string]*dynamodb.AttributeValue, error)
+	if SingleTable() {
+		input := &dynamodb.QueryInput{
+			TableName:              aws.String(r.TableName),
+			IndexName:              aws.String(EntityIndex()),
+			KeyConditionExpression: aws.String("#entityType = :entityType AND begins_with(#pk, :prefix)"),
+			ExpressionAttributeNames: map[string]*string{
+				"#entityType": aws.String(EntityTypeAttribute),
+				"#pk":         aws.String(PartitionKeyAttribute),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":entityType": {S: aws.String(userEntityType())},
+				":prefix":     {S: aws.String(PartitionKey(prefix))},
+			},
+			ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+		}
+		input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues = visibleOnly(
+			ReadOptions{}, nil, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+		read = func(startKey map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue,
+			map[string]*dynamodb.AttributeValue, error) {
+			input.ExclusiveStartKey = startKey
+			out, err := r.DynaClient.Query(input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return out.Items, out.LastEvaluatedKey, nil
+		}
+	} else {
+		input := &dynamodb.ScanInput{
+			TableName:                 aws.String(r.TableName),
+			FilterExpression:          aws.String("begins_with(#key, :prefix)"),
+			ExpressionAttributeNames:  withKeyName(nil),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":prefix": {S: aws.String(prefix)}},
+			ReturnConsumedCapacity:    aws.String(dynamodb.ReturnConsumedCapacityTotal),
+		}
+		input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues = visibleOnly(
+			ReadOptions{}, input.FilterExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+		read = func(startKey map[string]*dynamodb.AttributeValue) ([]map[string]*dynamodb.AttributeValue,
+			map[string]*dynamodb.AttributeValue, error) {
+			input.ExclusiveStartKey = startKey
+			out, err := r.DynaClient.Scan(input)
+			if err != nil {
+				return nil, nil, err
+			}
+			return out.Items, out.LastEvaluatedKey, nil
+		}
+	}
+
+	// Pages may hold more matches than are left to return, so the cursor resumes after the
+	// last item returned rather than after the page
+	var found []map[string]*dynamodb.AttributeValue
+	var nextCursor string
+	for {
+		items, lastKey, err := read(startKey)
+		if err != nil {
+			return nil, newError(ErrStorage, ErrorFailedToFetchRecord, err)
+		}
+		if left := limit - len(found); len(items) >= left {
+			found = append(found, items[:left]...)
+			if len(items) > left || len(lastKey) > 0 {
+				nextCursor = encodeSearchCursor(searchKey(found[len(found)-1]))
+			}
+			break
+		}
+		found = append(found, items...)
+		if len(lastKey) == 0 {
+			break
+		}
+		startKey = lastKey
+	}
+
+	page := &SearchPage{Users: []User{}, NextCursor: nextCursor}
+	if err := unmarshalUsers(found, &page.Users); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Search returns copies of the users whose email starts with prefix, in email order. The
+// cursor is the last email returned.
+func (r *MemoryRepository) Search(prefix string, limit int, cursor string) (*SearchPage, error) {
+	after, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, newFieldError(ErrValidation, ErrorInvalidSearchCursor, "cursor", err)
+	}
+
+	page, err := r.List(ReadOptions{})
+	if err != nil {
+		return nil, err
+	}
+	result := &SearchPage{Users: []User{}}
+	for _, u := range page.Users {
+		if !strings.HasPrefix(u.Email, prefix) || u.Email <= string(after) {
+			continue
+		}
+		if len(result.Users) == limit {
+			last := result.Users[len(result.Users)-1].Email
+			result.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(last))
+			break
+		}
+		result.Users = append(result.Users, u)
+	}
+	return result, nil
+}
+
+// searchKeyAttributes returns the attributes a search resumes from: the key of the table,
+// and in single-table mode the key of the entity index too.
+func searchKeyAttributes() []string {
+	if SingleTable() {
+		return []string{EntityTypeAttribute, PartitionKeyAttribute, SortKeyAttribute}
+	}
+	return []string{KeyAttribute()}
+}
+
+// searchKey returns the ExclusiveStartKey resuming a search after an item.
+func searchKey(item map[string]*dynamodb.AttributeValue) map[string]string {
+	key := map[string]string{}
+	for _, name := range searchKeyAttributes() {
+		if value, ok := item[name]; ok {
+			key[name] = aws.StringValue(value.S)
+		}
+	}
+	return key
+}
+
+// encodeSearchCursor encodes the key a search resumes from as an opaque cursor.
+func encodeSearchCursor(key map[string]string) string {
+	encoded, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// decodeSearchCursor decodes a cursor made by encodeSearchCursor.
+//
+// Returns:
+// - The ExclusiveStartKey, or nil for an empty cursor.
+// - A validation error if the cursor is malformed or doesn't hold the expected key.
+func decodeSearchCursor(cursor string) (map[string]*dynamodb.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	var key map[string]string
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(decoded, &key)
+	}
+	if err != nil || len(key) != len(searchKeyAttributes()) {
+		return nil, newFieldError(ErrValidation, ErrorInvalidSearchCursor, "cursor", err)
+	}
+
+	startKey := make(map[string]*dynamodb.AttributeValue, len(key))
+	for _, name := range searchKeyAttributes() {
+		value, ok := key[name]
+		if !ok || value == "" {
+			return nil, newFieldError(ErrValidation, ErrorInvalidSearchCursor, "cursor", nil)
+		}
+		startKey[name] = &dynamodb.AttributeValue{S: aws.String(value)}
+	}
+	return startKey, nil
+}
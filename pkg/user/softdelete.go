@@ -0,0 +1,182 @@
+package user
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"os"
+	"time"
+)
+
+// Error messages for soft-deleted users
+var (
+	ErrorUserNotDeleted = "user is not deleted"
+	ErrorUserDeleted    = "user exists but is deleted; restore it, or create it with onDeletedConflict=overwrite"
+)
+
+// SoftDeleteEnabled reports whether deletes only flag users with deletedAt (SOFT_DELETE=true).
+func SoftDeleteEnabled() bool {
+	return os.Getenv("SOFT_DELETE") == "true"
+}
+
+// withoutDeleted adds a condition excluding soft-deleted users to a FilterExpression.
+//
+// Parameters:
+// - expression: The existing filter expression, or nil.
+// - names: The existing ExpressionAttributeNames, or nil.
+//
+// Returns:
+// - The combined filter expression.
+// - The attribute names, including the one for deletedAt.
+func withoutDeleted(expression *string, names map[string]*string) (*string, map[string]*string) {
+	if names == nil {
+		names = map[string]*string{}
+	}
+	names["#deletedAt"] = aws.String("deletedAt")
+	condition := "attribute_not_exists(#deletedAt)"
+	if expression != nil {
+		condition = "(" + *expression + ") AND " + condition
+	}
+	return aws.String(condition), names
+}
+
+// RestoreUser clears the deletedAt flag of a soft-deleted user.
+//
+// Parameters:
+// - email: The email of the user to restore.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The restored user.
+//   - An ErrNotFound error if the user doesn't exist, an ErrConflict error if it isn't
+//     deleted, or an error if the write fails.
+func RestoreUser(email string, repo Repository) (*User, error) {
+	current, err := FetchUser(email, ReadOptions{ConsistentRead: true, IncludeDeleted: true}, repo)
+	if err != nil {
+		return nil, err
+	}
+	if current.DeletedAt == "" {
+		return nil, newError(ErrConflict, ErrorUserNotDeleted, nil)
+	}
+	return repo.Restore(current.Email, timestamp())
+}
+
+// createOverDeleted replaces a soft-deleted user with a new one under the same email.
+// It fails with ErrorUserDeleted unless overwrite is set. Expired users awaiting TTL
+// deletion are always replaced, since reads no longer return them.
+func createOverDeleted(newUser *User, overwrite bool, repo Repository) error {
+	current, err := repo.Get(newUser.Email, ReadOptions{ConsistentRead: true})
+	if errors.Is(err, ErrNotFound) {
+		// Hard-deleted in the meantime
+		return repo.Create(newUser)
+	}
+	if err != nil {
+		return err
+	}
+	expired := current.ExpiresAt.Expired(time.Now())
+	if current.DeletedAt == "" && !expired {
+		return newError(ErrConflict, ErrorUserAlreadyExists, nil)
+	}
+	if !overwrite && !expired {
+		return newError(ErrConflict, ErrorUserDeleted, nil)
+	}
+
+	newUser.Version = current.Version + 1
+	return repo.Update(newUser, current.Version)
+}
+
+// SoftDelete flags the user stored under exactly the given email as deleted with an
+// UpdateItem, bumping its version. Users that are missing or already deleted are not found.
+func (r *DynamoRepository) SoftDelete(email string, deletedAt string) error {
+	input := &dynamodb.UpdateItemInput{
+		Key:                 emailKey(email),
+		TableName:           aws.String(r.TableName),
+		UpdateExpression:    aws.String("SET #deletedAt = :now, #updatedAt = :now, #version = if_not_exists(#version, :zero) + :one"),
+		ConditionExpression: aws.String("attribute_exists(#key) AND attribute_not_exists(#deletedAt)"),
+		ExpressionAttributeNames: withKeyName(map[string]*string{
+			"#deletedAt": aws.String("deletedAt"),
+			"#updatedAt": aws.String("updatedAt"),
+			"#version":   aws.String("version"),
+		}),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now":  {S: aws.String(deletedAt)},
+			":zero": {N: aws.String("0")},
+			":one":  {N: aws.String("1")},
+		},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+
+	_, err := r.DynaClient.UpdateItem(input)
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return newError(ErrNotFound, ErrorUserDoesNotExist, err)
+		}
+		return newError(ErrStorage, ErrorCouldNotDeleteItem, err)
+	}
+	return nil
+}
+
+// Restore clears the deletedAt flag of the user stored under exactly the given email,
+// bumping its version.
+func (r *DynamoRepository) Restore(email string, restoredAt string) (*User, error) {
+	input := &dynamodb.UpdateItemInput{
+		Key:                 emailKey(email),
+		TableName:           aws.String(r.TableName),
+		UpdateExpression:    aws.String("REMOVE #deletedAt SET #updatedAt = :now, #version = if_not_exists(#version, :zero) + :one"),
+		ConditionExpression: aws.String("attribute_exists(#deletedAt)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#deletedAt": aws.String("deletedAt"),
+			"#updatedAt": aws.String("updatedAt"),
+			"#version":   aws.String("version"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now":  {S: aws.String(restoredAt)},
+			":zero": {N: aws.String("0")},
+			":one":  {N: aws.String("1")},
+		},
+		ReturnValues:           aws.String(dynamodb.ReturnValueAllNew),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+
+	result, err := r.DynaClient.UpdateItem(input)
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, newError(ErrConflict, ErrorUserNotDeleted, err)
+		}
+		return nil, newError(ErrStorage, ErrorCouldNotDynamoPutItem, err)
+	}
+
+	restored := new(User)
+	if err := UnmarshalItem(result.Attributes, restored); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// SoftDelete flags the user stored under the email as deleted.
+func (r *MemoryRepository) SoftDelete(email string, deletedAt string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[email]
+	if !ok || u.DeletedAt != "" {
+		return newError(ErrNotFound, ErrorUserDoesNotExist, nil)
+	}
+	u.DeletedAt, u.UpdatedAt, u.Version = deletedAt, deletedAt, u.Version+1
+	r.users[email] = u
+	return nil
+}
+
+// Restore clears the deletedAt flag of the user stored under the email.
+func (r *MemoryRepository) Restore(email string, restoredAt string) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[email]
+	if !ok || u.DeletedAt == "" {
+		return nil, newError(ErrConflict, ErrorUserNotDeleted, nil)
+	}
+	u.DeletedAt, u.UpdatedAt, u.Version = "", restoredAt, u.Version+1
+	r.users[email] = u
+	return &u, nil
+}
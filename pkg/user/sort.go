@@ -0,0 +1,79 @@
+package user
+
+import (
+	"sort"
+	"strings"
+)
+
+// SortFields lists the fields users can be sorted by
+var SortFields = []string{"lastname", "firstname", "email", "createdAt"}
+
+// Error messages for invalid sort parameters
+var (
+	ErrorInvalidSort  = "sort must be one of: " + strings.Join(SortFields, ", ")
+	ErrorInvalidOrder = "order must be asc or desc"
+)
+
+// ParseSort validates a sort field and order.
+//
+// Parameters:
+// - field: One of SortFields, or an empty string for no sorting.
+// - order: "asc", "desc", or an empty string for ascending.
+//
+// Returns:
+// - Whether the order is descending.
+// - A validation error naming the invalid parameter.
+func ParseSort(field string, order string) (bool, error) {
+	valid := field == ""
+	for _, sortField := range SortFields {
+		if field == sortField {
+			valid = true
+		}
+	}
+	if !valid {
+		return false, newFieldError(ErrValidation, ErrorInvalidSort, "sort", nil)
+	}
+
+	switch order {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	default:
+		return false, newFieldError(ErrValidation, ErrorInvalidOrder, "order", nil)
+	}
+}
+
+// SortUsers sorts users in place by one of SortFields.
+// Names and emails compare case-insensitively, and the sort is stable so users with
+// equal values keep their relative order.
+//
+// Parameters:
+// - users: The users to sort.
+// - field: The field to sort by, as validated by ParseSort; an empty field leaves users as is.
+// - desc: Whether to sort in descending order.
+func SortUsers(users []User, field string, desc bool) {
+	if field == "" {
+		return
+	}
+
+	key := func(u *User) string {
+		switch field {
+		case "firstname":
+			return strings.ToLower(u.FirstName)
+		case "lastname":
+			return strings.ToLower(u.LastName)
+		case "email":
+			return strings.ToLower(u.Email)
+		default:
+			// RFC3339 UTC timestamps sort lexically
+			return u.CreatedAt
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		if desc {
+			return key(&users[i]) > key(&users[j])
+		}
+		return key(&users[i]) < key(&users[j])
+	})
+}
@@ -0,0 +1,82 @@
+package user
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name      string
+		field     string
+		order     string
+		wantDesc  bool
+		wantError string
+	}{
+		{name: "none"},
+		{name: "lastname", field: "lastname"},
+		{name: "ascending", field: "createdAt", order: "asc"},
+		{name: "descending", field: "email", order: "desc", wantDesc: true},
+		{name: "unknown field", field: "password", wantError: ErrorInvalidSort},
+		{name: "field case matters", field: "LastName", wantError: ErrorInvalidSort},
+		{name: "unknown order", field: "email", order: "up", wantError: ErrorInvalidOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc, err := ParseSort(tt.field, tt.order)
+			if tt.wantError != "" {
+				var userErr *Error
+				if !errors.Is(err, ErrValidation) || !errors.As(err, &userErr) || userErr.Message != tt.wantError {
+					t.Fatalf("ParseSort(%q, %q) error = %v, want %q", tt.field, tt.order, err, tt.wantError)
+				}
+				return
+			}
+			if err != nil || desc != tt.wantDesc {
+				t.Errorf("ParseSort(%q, %q) = %v, %v; want %v", tt.field, tt.order, desc, err, tt.wantDesc)
+			}
+		})
+	}
+}
+
+func TestSortUsers(t *testing.T) {
+	users := []User{
+		{Email: "grace@example.com", FirstName: "Grace", LastName: "hopper", CreatedAt: "2021-03-01T00:00:00Z"},
+		{Email: "Ada@example.com", FirstName: "ada", LastName: "Lovelace", CreatedAt: "2021-01-01T00:00:00Z"},
+		{Email: "alan@example.com", FirstName: "Alan", LastName: "Turing", CreatedAt: "2021-02-01T00:00:00Z"},
+		{Email: "byron@example.com", FirstName: "Ada", LastName: "Byron", CreatedAt: "2021-01-01T00:00:00Z"},
+	}
+	tests := []struct {
+		name  string
+		field string
+		desc  bool
+		want  []string
+	}{
+		{name: "unsorted", want: []string{"grace@example.com", "Ada@example.com", "alan@example.com", "byron@example.com"}},
+		{name: "lastname ignores case", field: "lastname",
+			want: []string{"byron@example.com", "grace@example.com", "Ada@example.com", "alan@example.com"}},
+		{name: "email ignores case", field: "email",
+			want: []string{"Ada@example.com", "alan@example.com", "byron@example.com", "grace@example.com"}},
+		{name: "firstname ties keep their order", field: "firstname",
+			want: []string{"Ada@example.com", "byron@example.com", "alan@example.com", "grace@example.com"}},
+		{name: "firstname descending", field: "firstname", desc: true,
+			want: []string{"grace@example.com", "alan@example.com", "Ada@example.com", "byron@example.com"}},
+		{name: "createdAt ties keep their order", field: "createdAt",
+			want: []string{"Ada@example.com", "byron@example.com", "alan@example.com", "grace@example.com"}},
+		{name: "createdAt descending", field: "createdAt", desc: true,
+			want: []string{"grace@example.com", "alan@example.com", "Ada@example.com", "byron@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted := append([]User(nil), users...)
+			SortUsers(sorted, tt.field, tt.desc)
+			var got []string
+			for _, u := range sorted {
+				got = append(got, u.Email)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortUsers(%q, desc %v) = %v, want %v", tt.field, tt.desc, got, tt.want)
+			}
+		})
+	}
+}
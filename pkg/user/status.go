@@ -0,0 +1,155 @@
+package user
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Statuses of a user account. Users stored before statuses existed are active.
+const (
+	StatusActive    = "active"
+	StatusInactive  = "inactive"
+	StatusSuspended = "suspended"
+)
+
+// Error messages for user statuses
+var (
+	ErrorInvalidStatus   = "status must be active, inactive or suspended"
+	ErrorStatusUnchanged = "user already has that status"
+	ErrorUserSuspended   = "user is suspended; only an administrator can change it"
+)
+
+// UpdateOptions tune how a user is updated
+type UpdateOptions struct {
+	AllowSuspended   bool // Let the write change a suspended user or suspend one, as administrators may
+	AllowRoleChange  bool // Let the write change the role, as administrators may
+	RejectRoleChange bool // Fail with ErrForbidden, instead of keeping the role, when it may not be changed
+}
+
+// isStatus reports whether status is one of the user statuses.
+func isStatus(status string) bool {
+	return status == StatusActive || status == StatusInactive || status == StatusSuspended
+}
+
+// ParseStatus checks a status given by a client, e.g. as a list filter.
+//
+// Parameters:
+// - raw: The status, or an empty string for none.
+//
+// Returns:
+// - A validation error if the status is set but unknown.
+func ParseStatus(raw string) error {
+	if raw != "" && !isStatus(raw) {
+		return newFieldError(ErrValidation, ErrorInvalidStatus, "status", nil)
+	}
+	return nil
+}
+
+// CurrentStatus returns the status of the user, which is active if none is stored.
+func (u *User) CurrentStatus() string {
+	if u.Status == "" {
+		return StatusActive
+	}
+	return u.Status
+}
+
+// checkStatusChange rejects writes touching a suspended user, or suspending one, unless
+// allowSuspended is set.
+func checkStatusChange(current string, next string, allowSuspended bool) error {
+	if !allowSuspended && (current == StatusSuspended || next == StatusSuspended && next != current) {
+		return newFieldError(ErrForbidden, ErrorUserSuspended, "status", nil)
+	}
+	return nil
+}
+
+// SetStatus moves a user to another status, e.g. to deactivate it.
+//
+// Parameters:
+// - email: The email of the user.
+// - status: The status to set.
+// - opts: Options tuning the write; only administrators may change suspended users.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The updated user.
+//   - A validation error for an unknown status, an ErrNotFound error if the user doesn't
+//     exist, an ErrForbidden error for a suspended user, an ErrConflict error if the user
+//     already has the status, or an error if the write fails.
+func SetStatus(email string, status string, opts UpdateOptions, repo Repository) (*User, error) {
+	if !isStatus(status) {
+		return nil, newFieldError(ErrValidation, ErrorInvalidStatus, "status", nil)
+	}
+	current, err := FetchUser(email, ReadOptions{ConsistentRead: true}, repo)
+	if err != nil {
+		return nil, err
+	}
+	if current.CurrentStatus() == status {
+		return nil, newError(ErrConflict, ErrorStatusUnchanged, nil)
+	}
+	if err := checkStatusChange(current.CurrentStatus(), status, opts.AllowSuspended); err != nil {
+		return nil, err
+	}
+	return repo.SetStatus(current.Email, status, timestamp())
+}
+
+// statusCondition is the condition of a write moving a user to status: the user exists
+// and doesn't have the status yet, counting users without one as active.
+func statusCondition(status string) string {
+	if status == StatusActive {
+		return "attribute_exists(#key) AND attribute_exists(#status) AND #status <> :status"
+	}
+	return "attribute_exists(#key) AND (attribute_not_exists(#status) OR #status <> :status)"
+}
+
+// SetStatus sets the status of the user stored under exactly the given email with an
+// UpdateItem, bumping its version. Users that are missing or already have the status
+// conflict.
+func (r *DynamoRepository) SetStatus(email string, status string, updatedAt string) (*User, error) {
+	input := &dynamodb.UpdateItemInput{
+		Key:                 emailKey(email),
+		TableName:           aws.String(r.TableName),
+		UpdateExpression:    aws.String("SET #status = :status, #updatedAt = :now, #version = if_not_exists(#version, :zero) + :one"),
+		ConditionExpression: aws.String(statusCondition(status)),
+		ExpressionAttributeNames: withKeyName(map[string]*string{
+			"#status":    aws.String("status"),
+			"#updatedAt": aws.String("updatedAt"),
+			"#version":   aws.String("version"),
+		}),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":status": {S: aws.String(status)},
+			":now":    {S: aws.String(updatedAt)},
+			":zero":   {N: aws.String("0")},
+			":one":    {N: aws.String("1")},
+		},
+		ReturnValues:           aws.String(dynamodb.ReturnValueAllNew),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+
+	result, err := r.DynaClient.UpdateItem(input)
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, newError(ErrConflict, ErrorStatusUnchanged, err)
+		}
+		return nil, newError(ErrStorage, ErrorCouldNotDynamoPutItem, err)
+	}
+
+	updated := new(User)
+	if err := UnmarshalItem(result.Attributes, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// SetStatus sets the status of the user stored under the email.
+func (r *MemoryRepository) SetStatus(email string, status string, updatedAt string) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[email]
+	if !ok || u.CurrentStatus() == status {
+		return nil, newError(ErrConflict, ErrorStatusUnchanged, nil)
+	}
+	u.Status, u.UpdatedAt, u.Version = status, updatedAt, u.Version+1
+	r.users[email] = u
+	return &u, nil
+}
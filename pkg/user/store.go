@@ -0,0 +1,124 @@
+package user
+
+import (
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/store"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// newUserStore returns the generic store users are read and written with, which maps the
+// email to the table's keys and encrypts names when PII_KMS_KEY_ARN is set.
+//
+// Parameters:
+// - tableName: The name of the DynamoDB table.
+// - dynaClient: The DynamoDB client interface, or nil to only marshal and unmarshal users.
+//
+// Returns:
+// - The store.
+func newUserStore(tableName string, dynaClient dynamodbiface.DynamoDBAPI) *store.Store[User] {
+	return &store.Store[User]{
+		Client:       dynaClient,
+		TableName:    tableName,
+		KeyAttribute: KeyAttribute(),
+		Key:          func(u *User) string { return u.Email },
+		KeyOf:        emailKey,
+		Encode:       encodeItem,
+		Decode:       decodeItem,
+	}
+}
+
+// store returns the generic store of the repository's table.
+func (r *DynamoRepository) store() *store.Store[User] {
+	s := newUserStore(r.TableName, r.DynaClient)
+	s.Context = r.Context
+	return s
+}
+
+// encodeItem adapts a marshaled user to the table, in place: the email goes to the table's
+// keys, and the names are encrypted when PII_KMS_KEY_ARN is set.
+func encodeItem(item map[string]*dynamodb.AttributeValue) error {
+	toTableKeys(item)
+	if piiKeyARN() == "" {
+		return nil
+	}
+	return encryptNames(item)
+}
+
+// decodeItem undoes encodeItem on an item read from the table, in place. Names are
+// decrypted if they were stored encrypted, whether or not PII_KMS_KEY_ARN is still set.
+func decodeItem(item map[string]*dynamodb.AttributeValue) error {
+	fromTableKeys(item)
+	return decryptNames(item)
+}
+
+// storeError converts an error of the generic store to an Error of this package.
+//
+// Parameters:
+// - err: The error of the store, or nil.
+// - message: The message of a failed DynamoDB call.
+//
+// Returns:
+// - The Error, or nil if err is nil. Errors of the hooks are returned as they are.
+func storeError(err error, message string) error {
+	var userErr *Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &userErr):
+		return err
+	case errors.Is(err, store.ErrMarshal):
+		return newError(ErrInternal, ErrorCouldNotMarshalItem, err)
+	case errors.Is(err, store.ErrUnmarshal):
+		return newError(ErrInternal, ErrorFailedToUnmarshalRecord, err)
+	}
+	return newError(ErrStorage, message, err)
+}
+
+// marshalUser converts a user to a DynamoDB item, like the repository's store writes it.
+//
+// Parameters:
+// - u: The user to store.
+//
+// Returns:
+// - The item.
+// - An error if the user can't be marshaled or its names encrypted.
+func marshalUser(u *User) (map[string]*dynamodb.AttributeValue, error) {
+	item, err := newUserStore("", nil).Marshal(u)
+	return item, storeError(err, ErrorCouldNotMarshalItem)
+}
+
+// UnmarshalItem converts an item of the users table, or a stream image of one, to a user,
+// decrypting its names if they were stored encrypted.
+//
+// Parameters:
+// - item: The item read.
+// - u: The user to fill.
+//
+// Returns:
+// - An error if the item can't be decrypted or unmarshaled.
+func UnmarshalItem(item map[string]*dynamodb.AttributeValue, u *User) error {
+	decoded, err := newUserStore("", nil).Unmarshal(item)
+	if err != nil {
+		return storeError(err, ErrorFailedToUnmarshalRecord)
+	}
+	*u = *decoded
+	return nil
+}
+
+// unmarshalUsers converts DynamoDB items to users, like UnmarshalItem.
+//
+// Parameters:
+// - items: The items read.
+// - users: The slice to fill.
+//
+// Returns:
+// - An error if an item can't be decrypted or unmarshaled.
+func unmarshalUsers(items []map[string]*dynamodb.AttributeValue, users *[]User) error {
+	decoded, err := newUserStore("", nil).UnmarshalAll(items)
+	if err != nil {
+		return storeError(err, ErrorFailedToUnmarshalRecord)
+	}
+	*users = decoded
+	return nil
+}
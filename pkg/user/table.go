@@ -0,0 +1,133 @@
+package user
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"time"
+)
+
+// Error messages for table provisioning failures
+var (
+	ErrorCouldNotDescribeTable = "couldn't describe the table"
+	ErrorCouldNotCreateTable   = "couldn't create the table"
+	ErrorTableNotActive        = "table did not become active in time"
+)
+
+// Polling bounds while waiting for a new table to become ACTIVE
+const (
+	tableActivePollInterval = 2 * time.Second
+	tableActiveMaxPolls     = 30
+)
+
+// EnsureTable creates the users table if it doesn't exist yet, with the KEY_ATTRIBUTE
+// attribute ("email" by default) as its string partition key and on-demand billing, and
+// waits until it is ACTIVE. In single-table mode, the table is keyed by PK and SK and gets
+// the entity index.
+//
+// Parameters:
+// - ctx: Context bounding the whole operation.
+// - tableName: DynamoDB table name to ensure.
+// - dynaClient: DynamoDB client interface.
+//
+// Returns:
+// - true if the table was created, false if it already existed.
+// - An error if the table couldn't be described, created, or didn't become active in time.
+func EnsureTable(ctx context.Context, tableName string, dynaClient dynamodbiface.DynamoDBAPI) (bool, error) {
+	_, err := dynaClient.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err == nil {
+		return false, nil
+	}
+	if !isResourceNotFound(err) {
+		return false, newError(ErrStorage, ErrorCouldNotDescribeTable, err)
+	}
+
+	_, err = dynaClient.CreateTableWithContext(ctx, createTableInput(tableName))
+	// Another container may have created it concurrently; wait for it all the same
+	if err != nil && !isResourceInUse(err) {
+		return false, newError(ErrStorage, ErrorCouldNotCreateTable, err)
+	}
+
+	return true, waitForActiveTable(ctx, tableName, dynaClient)
+}
+
+// createTableInput describes the users table to create.
+func createTableInput(tableName string) *dynamodb.CreateTableInput {
+	if !SingleTable() {
+		return &dynamodb.CreateTableInput{
+			TableName: aws.String(tableName),
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{{
+				AttributeName: aws.String(KeyAttribute()),
+				AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+			}},
+			KeySchema: []*dynamodb.KeySchemaElement{{
+				AttributeName: aws.String(KeyAttribute()),
+				KeyType:       aws.String(dynamodb.KeyTypeHash),
+			}},
+			BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		}
+	}
+
+	var definitions []*dynamodb.AttributeDefinition
+	for _, name := range []string{PartitionKeyAttribute, SortKeyAttribute, EntityTypeAttribute} {
+		definitions = append(definitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(name),
+			AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+		})
+	}
+	return &dynamodb.CreateTableInput{
+		TableName:            aws.String(tableName),
+		AttributeDefinitions: definitions,
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(PartitionKeyAttribute), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String(SortKeyAttribute), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{{
+			IndexName: aws.String(EntityIndex()),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String(EntityTypeAttribute), KeyType: aws.String(dynamodb.KeyTypeHash)},
+				{AttributeName: aws.String(PartitionKeyAttribute), KeyType: aws.String(dynamodb.KeyTypeRange)},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+		}},
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+	}
+}
+
+// waitForActiveTable polls the table status until it is ACTIVE, up to tableActiveMaxPolls times.
+func waitForActiveTable(ctx context.Context, tableName string, dynaClient dynamodbiface.DynamoDBAPI) error {
+	for poll := 0; poll < tableActiveMaxPolls; poll++ {
+		out, err := dynaClient.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		if err != nil && !isResourceNotFound(err) {
+			return newError(ErrStorage, ErrorCouldNotDescribeTable, err)
+		}
+		if err == nil && out.Table != nil && aws.StringValue(out.Table.TableStatus) == dynamodb.TableStatusActive {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return newError(ErrStorage, ErrorTableNotActive, ctx.Err())
+		case <-time.After(tableActivePollInterval):
+		}
+	}
+	return newError(ErrStorage, ErrorTableNotActive, nil)
+}
+
+// isResourceNotFound reports whether err is DynamoDB's ResourceNotFoundException.
+func isResourceNotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException
+}
+
+// isResourceInUse reports whether err is DynamoDB's ResourceInUseException.
+func isResourceInUse(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == dynamodb.ErrCodeResourceInUseException
+}
@@ -0,0 +1,88 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"testing"
+)
+
+func TestEnsureTable(t *testing.T) {
+	active := &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		TableStatus: aws.String(dynamodb.TableStatusActive),
+	}}
+	tests := []struct {
+		name        string
+		describe    []error // Errors of the successive DescribeTable calls; nil finds the table ACTIVE
+		create      error
+		wantCreated bool
+		wantCreate  bool
+		wantMessage string
+	}{
+		{name: "already exists", describe: []error{nil}},
+		{name: "created", describe: []error{mocks.ResourceNotFoundError(), nil}, wantCreated: true, wantCreate: true},
+		{name: "created concurrently", describe: []error{mocks.ResourceNotFoundError(), nil},
+			create:      awserr.New(dynamodb.ErrCodeResourceInUseException, "table is being created", nil),
+			wantCreated: true, wantCreate: true},
+		{name: "creation fails", describe: []error{mocks.ResourceNotFoundError()}, create: mocks.ThrottlingError(),
+			wantCreate: true, wantMessage: ErrorCouldNotCreateTable},
+		{name: "describe fails", describe: []error{mocks.ThrottlingError()}, wantMessage: ErrorCouldNotDescribeTable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := mocks.NewFakeDynamo()
+			describes := 0
+			fake.OnDescribeTable(func(in *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+				if describes >= len(tt.describe) {
+					t.Fatalf("unexpected DescribeTable call %d", describes+1)
+				}
+				err := tt.describe[describes]
+				describes++
+				if err != nil {
+					return nil, err
+				}
+				return active, nil
+			})
+			fake.OnCreateTable(func(in *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+				return &dynamodb.CreateTableOutput{}, tt.create
+			})
+
+			created, err := EnsureTable(context.Background(), "users", fake)
+			if tt.wantMessage != "" {
+				var userErr *Error
+				if !errors.As(err, &userErr) || userErr.Message != tt.wantMessage || !errors.Is(err, ErrStorage) {
+					t.Fatalf("EnsureTable() error = %v, want a storage error %q", err, tt.wantMessage)
+				}
+			} else if err != nil {
+				t.Fatalf("EnsureTable() error = %v", err)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("EnsureTable() created = %v, want %v", created, tt.wantCreated)
+			}
+
+			inputs := fake.Inputs("CreateTable")
+			wantCalls := 0
+			if tt.wantCreate {
+				wantCalls = 1
+			}
+			if len(inputs) != wantCalls {
+				t.Fatalf("CreateTable called %d times, want %d", len(inputs), wantCalls)
+			}
+			if wantCalls == 0 {
+				return
+			}
+			in := inputs[0].(*dynamodb.CreateTableInput)
+			if aws.StringValue(in.TableName) != "users" ||
+				aws.StringValue(in.BillingMode) != dynamodb.BillingModePayPerRequest ||
+				len(in.KeySchema) != 1 || aws.StringValue(in.KeySchema[0].AttributeName) != "email" ||
+				aws.StringValue(in.KeySchema[0].KeyType) != dynamodb.KeyTypeHash ||
+				len(in.AttributeDefinitions) != 1 ||
+				aws.StringValue(in.AttributeDefinitions[0].AttributeType) != dynamodb.ScalarAttributeTypeS {
+				t.Errorf("CreateTable input = %v, want an on-demand table keyed by the email string", in)
+			}
+		})
+	}
+}
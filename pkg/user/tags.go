@@ -0,0 +1,139 @@
+package user
+
+import (
+	"encoding/xml"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits on the tags of a user
+const (
+	MaxTags          = 20  // Keys per user
+	MaxTagKeyLength  = 64  // Characters per key
+	MaxTagValueBytes = 256 // Bytes per value
+)
+
+// Error messages for user tags
+var (
+	ErrorTooManyTags      = "a user can have at most 20 tags"
+	ErrorInvalidTagKey    = "tag keys must be 1 to 64 letters, digits, '_', '-', '.' or ':'"
+	ErrorReservedTagKey   = "tag keys can't be the name of a user field"
+	ErrorTagValueTooLong  = "tag values must be at most 256 bytes"
+	ErrorInvalidTagFilter = "tag filters must name a key, e.g. tag.plan=pro"
+)
+
+// Tags is free-form metadata of a user, such as a plan tier or referral source, stored
+// as a DynamoDB map attribute
+type Tags map[string]string
+
+// Validate checks the number of tags and each key and value. Keys are checked in sorted
+// order so the reported keys don't depend on map iteration.
+//
+// Returns:
+//   - A validation error naming the offending tag (e.g. "tags.plan"), a validation error
+//     wrapping the ValidationErrors of every offending tag if there are several, or nil.
+func (t Tags) Validate() error {
+	if len(t) > MaxTags {
+		return newFieldError(ErrValidation, ErrorTooManyTags, "tags", nil)
+	}
+	var invalid ValidationErrors
+	for _, key := range t.keys() {
+		if err := validateTagKey(key); err != nil {
+			invalid.add(err)
+			continue
+		}
+		if len(t[key]) > MaxTagValueBytes {
+			invalid.add(newFieldError(ErrValidation, ErrorTagValueTooLong, "tags."+key, nil))
+		}
+	}
+	return invalid.err()
+}
+
+// sanitize trims, whitespace-collapses and NFC-normalizes every tag value.
+func (t Tags) sanitize() {
+	for key, value := range t {
+		t[key] = validators.SanitizeString(value)
+	}
+}
+
+// keys returns the tag keys in sorted order.
+func (t Tags) keys() []string {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateTagKey checks the characters and length of a tag key, and that it doesn't
+// shadow a user field, compared case-insensitively.
+func validateTagKey(key string) error {
+	if key == "" || len(key) > MaxTagKeyLength {
+		return newFieldError(ErrValidation, ErrorInvalidTagKey, "tags."+key, nil)
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.:", r)) {
+			return newFieldError(ErrValidation, ErrorInvalidTagKey, "tags."+key, nil)
+		}
+	}
+	for _, field := range Fields {
+		if strings.EqualFold(key, field) {
+			return newFieldError(ErrValidation, ErrorReservedTagKey, "tags."+key, nil)
+		}
+	}
+	return nil
+}
+
+// MarshalXML encodes the tags as one element per key, in sorted order.
+func (t Tags) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, key := range t.keys() {
+		if err := encoder.EncodeElement(t[key], xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// UnmarshalXML decodes one element per key.
+func (t *Tags) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	tags := Tags{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err := decoder.DecodeElement(&value, &element); err != nil {
+				return err
+			}
+			tags[element.Name.Local] = value
+		case xml.EndElement:
+			*t = tags
+			return nil
+		}
+	}
+}
+
+// tagsExpression builds the conditions of a FilterExpression matching users whose tags
+// hold the given values, adding the placeholders it uses to names and values.
+func tagsExpression(tags Tags, names map[string]*string, values map[string]*dynamodb.AttributeValue) []string {
+	var conditions []string
+	for i, key := range tags.keys() {
+		placeholder := strconv.Itoa(i)
+		conditions = append(conditions, "#tags.#tag"+placeholder+" = :tag"+placeholder)
+		names["#tags"] = aws.String("tags")
+		names["#tag"+placeholder] = aws.String(key)
+		values[":tag"+placeholder] = &dynamodb.AttributeValue{S: aws.String(tags[key])}
+	}
+	return conditions
+}
@@ -0,0 +1,67 @@
+package user
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTagsValidate(t *testing.T) {
+	tooMany := Tags{}
+	for i := 0; i <= MaxTags; i++ {
+		tooMany["t"+strconv.Itoa(i)] = "x"
+	}
+	tests := []struct {
+		name      string
+		tags      Tags
+		wantField string
+		wantMsg   string
+	}{
+		{name: "none"},
+		{name: "valid", tags: Tags{"plan": "pro", "ref:source": "ads", "a_b-c.d": ""}},
+		{name: "too many", tags: tooMany, wantField: "tags", wantMsg: ErrorTooManyTags},
+		{name: "invalid key", tags: Tags{"plan tier": "pro"}, wantField: "tags.plan tier", wantMsg: ErrorInvalidTagKey},
+		{name: "key too long", tags: Tags{strings.Repeat("k", MaxTagKeyLength+1): "x"},
+			wantField: "tags." + strings.Repeat("k", MaxTagKeyLength+1), wantMsg: ErrorInvalidTagKey},
+		{name: "reserved key", tags: Tags{"Email": "x"}, wantField: "tags.Email", wantMsg: ErrorReservedTagKey},
+		{name: "value too long", tags: Tags{"plan": strings.Repeat("x", MaxTagValueBytes+1)}, wantField: "tags.plan",
+			wantMsg: ErrorTagValueTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tags.Validate()
+			if tt.wantMsg == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			var userErr *Error
+			if !errors.As(err, &userErr) || !errors.Is(err, ErrValidation) {
+				t.Fatalf("Validate() error = %v, want a validation error", err)
+			}
+			if userErr.Field != tt.wantField || userErr.Message != tt.wantMsg {
+				t.Errorf("Validate() = %q on %q, want %q on %q", userErr.Message, userErr.Field, tt.wantMsg, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestTagsExpression(t *testing.T) {
+	names := map[string]*string{}
+	values := map[string]*dynamodb.AttributeValue{}
+	conditions := tagsExpression(Tags{"source": "ads", "plan": "pro"}, names, values)
+
+	want := []string{"#tags.#tag0 = :tag0", "#tags.#tag1 = :tag1"}
+	if strings.Join(conditions, " AND ") != strings.Join(want, " AND ") {
+		t.Fatalf("conditions = %v, want %v", conditions, want)
+	}
+	// Keys are sorted, so plan comes first
+	if aws.StringValue(names["#tag0"]) != "plan" || aws.StringValue(values[":tag0"].S) != "pro" ||
+		aws.StringValue(names["#tags"]) != "tags" {
+		t.Errorf("names %v and values %v don't match plan=pro first", names, values)
+	}
+}
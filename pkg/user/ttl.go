@@ -0,0 +1,161 @@
+package user
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultMaxTTLDays is how far ahead an expiry may be set unless MAX_TTL_DAYS says otherwise
+const defaultMaxTTLDays = 365
+
+// Error messages for user expiry
+var (
+	ErrorInvalidExpiresAt = "expiresAt must be an RFC3339 timestamp"
+	ErrorExpiryInPast     = "expiresAt must be in the future"
+	ErrorExpiryTooFar     = "expiresAt is further ahead than the maximum TTL"
+	ErrorInvalidTTLDays   = "ttlDays must not be negative"
+	ErrorTTLAndExpiresAt  = "ttlDays and expiresAt can't be combined"
+)
+
+// Expiry is the time a user expires, stored as epoch seconds so DynamoDB TTL can delete
+// the item and serialized in JSON as an RFC3339 timestamp. Zero means no expiry.
+type Expiry int64
+
+// MarshalJSON encodes the expiry as an RFC3339 timestamp.
+func (e Expiry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Unix(int64(e), 0).UTC().Format(time.RFC3339))
+}
+
+// UnmarshalJSON decodes an RFC3339 timestamp, or epoch seconds.
+func (e *Expiry) UnmarshalJSON(data []byte) error {
+	var seconds int64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*e = Expiry(seconds)
+		return nil
+	}
+
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return newFieldError(ErrValidation, ErrorInvalidExpiresAt, "expiresAt", err)
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return newFieldError(ErrValidation, ErrorInvalidExpiresAt, "expiresAt", err)
+	}
+	*e = Expiry(parsed.Unix())
+	return nil
+}
+
+// MarshalXML encodes the expiry as an RFC3339 timestamp, like its JSON.
+func (e Expiry) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	return encoder.EncodeElement(time.Unix(int64(e), 0).UTC().Format(time.RFC3339), start)
+}
+
+// UnmarshalXML decodes an RFC3339 timestamp, or epoch seconds.
+func (e *Expiry) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	var raw string
+	if err := decoder.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		*e = Expiry(seconds)
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return newFieldError(ErrValidation, ErrorInvalidExpiresAt, "expiresAt", err)
+	}
+	*e = Expiry(parsed.Unix())
+	return nil
+}
+
+// Expired reports whether the expiry has passed at now. An expiry equal to now has passed.
+func (e Expiry) Expired(now time.Time) bool {
+	return e != 0 && int64(e) <= now.Unix()
+}
+
+// maxTTL returns how far ahead an expiry may be set (MAX_TTL_DAYS, 365 days by default).
+func maxTTL() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("MAX_TTL_DAYS"))
+	if err != nil || days < 1 {
+		days = defaultMaxTTLDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// validateExpiry checks that an expiry, if set, is in the future and within the maximum TTL.
+func validateExpiry(e Expiry, now time.Time) error {
+	switch {
+	case e == 0:
+		return nil
+	case e.Expired(now):
+		return newFieldError(ErrValidation, ErrorExpiryInPast, "expiresAt", nil)
+	case int64(e) > now.Add(maxTTL()).Unix():
+		return newFieldError(ErrValidation, ErrorExpiryTooFar, "expiresAt", nil)
+	}
+	return nil
+}
+
+// UserRequest is the body of a create or update, which may set the expiry relative to now
+// and, on create, a password
+type UserRequest struct {
+	User
+	TTLDays  *int    `json:"ttlDays"`  // Days until the user expires; 0 removes the expiry
+	Password *string `json:"password"` // Plain-text password, hashed before it is stored
+}
+
+// expiry resolves the expiry a request asks for.
+//
+// Returns:
+// - The expiry, or zero if none.
+// - Whether the request set (or cleared) the expiry at all.
+// - A validation error if ttlDays is negative or combined with expiresAt.
+func (r *UserRequest) expiry(now time.Time) (Expiry, bool, error) {
+	if r.TTLDays == nil {
+		return r.ExpiresAt, r.ExpiresAt != 0, nil
+	}
+	if r.ExpiresAt != 0 {
+		return 0, false, newFieldError(ErrValidation, ErrorTTLAndExpiresAt, "ttlDays", nil)
+	}
+	if *r.TTLDays < 0 {
+		return 0, false, newFieldError(ErrValidation, ErrorInvalidTTLDays, "ttlDays", nil)
+	}
+	if *r.TTLDays == 0 {
+		return 0, true, nil
+	}
+	return Expiry(now.Add(time.Duration(*r.TTLDays) * 24 * time.Hour).Unix()), true, nil
+}
+
+// visibleOnly adds the conditions leaving out the users reads never return to a
+// FilterExpression: expired users, whose TTL deletion can lag up to 48 hours, and
+// soft-deleted users unless opts.IncludeDeleted is set.
+//
+// Parameters:
+// - opts: The options of the read.
+// - expression: The existing filter expression, or nil.
+// - names: The existing ExpressionAttributeNames, or nil.
+// - values: The existing ExpressionAttributeValues, or nil.
+//
+// Returns:
+// - The combined filter expression, attribute names and attribute values.
+func visibleOnly(opts ReadOptions, expression *string, names map[string]*string,
+	values map[string]*dynamodb.AttributeValue) (*string, map[string]*string, map[string]*dynamodb.AttributeValue) {
+	if !opts.IncludeDeleted {
+		expression, names = withoutDeleted(expression, names)
+	}
+	if values == nil {
+		values = map[string]*dynamodb.AttributeValue{}
+	}
+	names["#expiresAt"] = aws.String("expiresAt")
+	values[":now"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))}
+	condition := "(attribute_not_exists(#expiresAt) OR #expiresAt > :now)"
+	if expression != nil {
+		condition = "(" + *expression + ") AND " + condition
+	}
+	return aws.String(condition), names, values
+}
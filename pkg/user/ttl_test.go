@@ -0,0 +1,137 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExpiryExpired(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		expiry Expiry
+		want   bool
+	}{
+		{name: "no expiry", expiry: 0},
+		{name: "a second ago", expiry: Expiry(now.Unix() - 1), want: true},
+		{name: "now", expiry: Expiry(now.Unix()), want: true},
+		{name: "in a second", expiry: Expiry(now.Unix() + 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.expiry.Expired(now); got != tt.want {
+				t.Errorf("Expired() = %v, want %v", got, tt.want)
+			}
+			// Sub-second precision doesn't move the boundary
+			if got := tt.expiry.Expired(now.Add(999 * time.Millisecond)); got != tt.want {
+				t.Errorf("Expired() within the second = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateExpiry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		maxDays    string
+		expiry     Expiry
+		wantErrMsg string
+	}{
+		{name: "no expiry"},
+		{name: "now", expiry: Expiry(now.Unix()), wantErrMsg: ErrorExpiryInPast},
+		{name: "past", expiry: Expiry(now.Add(-time.Hour).Unix()), wantErrMsg: ErrorExpiryInPast},
+		{name: "in a second", expiry: Expiry(now.Unix() + 1)},
+		{name: "at the default maximum", expiry: Expiry(now.Add(365 * 24 * time.Hour).Unix())},
+		{name: "past the default maximum", expiry: Expiry(now.Add(365*24*time.Hour).Unix() + 1),
+			wantErrMsg: ErrorExpiryTooFar},
+		{name: "past a configured maximum", maxDays: "7", expiry: Expiry(now.Add(8 * 24 * time.Hour).Unix()),
+			wantErrMsg: ErrorExpiryTooFar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_TTL_DAYS", tt.maxDays)
+			err := validateExpiry(tt.expiry, now)
+			if tt.wantErrMsg == "" {
+				if err != nil {
+					t.Errorf("validateExpiry() error = %v", err)
+				}
+				return
+			}
+			var userErr *Error
+			if !errors.As(err, &userErr) || userErr.Message != tt.wantErrMsg || !errors.Is(err, ErrValidation) {
+				t.Errorf("validateExpiry() error = %v, want %q", err, tt.wantErrMsg)
+			}
+		})
+	}
+}
+
+func TestExpiryJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    Expiry
+		wantErr bool
+	}{
+		{name: "RFC3339", json: `"2024-05-01T12:00:00Z"`, want: 1714564800},
+		{name: "offset", json: `"2024-05-01T14:00:00+02:00"`, want: 1714564800},
+		{name: "epoch seconds", json: `1714564800`, want: 1714564800},
+		{name: "malformed", json: `"tomorrow"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Expiry
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("Unmarshal(%s) = %d, %v; want %d", tt.json, got, err, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+			// GET surfaces the expiry as RFC3339 in UTC
+			if encoded, _ := json.Marshal(got); string(encoded) != `"2024-05-01T12:00:00Z"` {
+				t.Errorf("Marshal() = %s, want the RFC3339 timestamp", encoded)
+			}
+		})
+	}
+}
+
+func TestExpiredUsersAreHidden(t *testing.T) {
+	tests := []struct {
+		name        string
+		expires     bool
+		expiresIn   time.Duration
+		wantVisible bool
+	}{
+		{name: "no expiry", wantVisible: true},
+		{name: "expiring now", expires: true},
+		{name: "expired", expires: true, expiresIn: -time.Hour},
+		{name: "expiring later", expires: true, expiresIn: time.Hour, wantVisible: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMemoryRepository()
+			u := User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace"}
+			if tt.expires {
+				u.ExpiresAt = Expiry(time.Now().Add(tt.expiresIn).Unix())
+			}
+			if err := repo.Create(&u); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+
+			_, err := FetchUser("ada@example.com", ReadOptions{}, repo)
+			if tt.wantVisible != (err == nil) || (err != nil && !errors.Is(err, ErrNotFound)) {
+				t.Errorf("FetchUser() error = %v, want visible %v", err, tt.wantVisible)
+			}
+			page, err := FetchUsers(ReadOptions{}, repo)
+			if err != nil {
+				t.Fatalf("FetchUsers() error = %v", err)
+			}
+			if tt.wantVisible != (len(page.Users) == 1) {
+				t.Errorf("FetchUsers() = %d users, want visible %v", len(page.Users), tt.wantVisible)
+			}
+		})
+	}
+}
@@ -5,10 +5,11 @@ import (
 	"errors"
 	"github.com/Vansh3140/golang-serverless/pkg/validators"
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Error messages for common issues
@@ -17,205 +18,570 @@ var (
 	ErrorFailedToUnmarshalRecord = "failed to unmarshal record fetched from dynamodb"
 	ErrorInvalidUserData         = "invalid user data"
 	ErrorInvalidEmail            = "invalid email"
+	ErrorInvalidFirstName        = "invalid firstname"
+	ErrorInvalidLastName         = "invalid lastname"
 	ErrorCouldNotMarshalItem     = "couldn't marshal the item"
 	ErrorCouldNotDeleteItem      = "couldn't delete the item"
 	ErrorCouldNotDynamoPutItem   = "could not dynamo put item"
 	ErrorUserAlreadyExists       = "user already exists"
 	ErrorUserDoesNotExist        = "user doesn't exist"
+	ErrorVersionMismatch         = "user was modified by another request"
+	ErrorEmptyBody               = "empty request body"
+	ErrorMalformedJSON           = "malformed JSON body"
+	ErrorUnknownField            = "unknown field"
+	ErrorInvalidFieldType        = "invalid field type"
 )
 
 // User represents a user entity in the system
 type User struct {
-	Email     string `json:"email"`     // User's email address
-	FirstName string `json:"firstname"` // User's first name
-	LastName  string `json:"lastname"`  // User's last name
+	Email     string   `json:"email" xml:"email"`                             // User's email address
+	FirstName string   `json:"firstname" xml:"firstname"`                     // User's first name
+	LastName  string   `json:"lastname" xml:"lastname"`                       // User's last name
+	CreatedAt string   `json:"createdAt" xml:"createdAt"`                     // RFC3339 time the user was created
+	UpdatedAt string   `json:"updatedAt" xml:"updatedAt"`                     // RFC3339 time the user was last modified
+	Version   int      `json:"version" xml:"version"`                         // Incremented on every write for optimistic locking
+	DeletedAt string   `json:"deletedAt,omitempty" xml:"deletedAt,omitempty"` // RFC3339 time the user was soft-deleted, if it was
+	ExpiresAt Expiry   `json:"expiresAt,omitempty" xml:"expiresAt,omitempty"` // Time the user expires, if it does; DynamoDB TTL attribute
+	Address   *Address `json:"address,omitempty" xml:"address,omitempty"`     // Mailing address, if given
+	Tags      Tags     `json:"tags,omitempty" xml:"tags,omitempty"`           // Free-form metadata, e.g. a plan tier
+	Status    string   `json:"status,omitempty" xml:"status,omitempty"`       // Account status; active if empty
+	Verified  bool     `json:"verified" xml:"verified"`                       // Whether the user proved it owns the email
+	Role      string   `json:"role,omitempty" xml:"role,omitempty"`           // RoleUser or RoleAdmin; user if empty
+
+	// bcrypt hash of the password, if one was set; never sent to clients
+	PasswordHash string `json:"-" xml:"-" dynamodbav:"passwordHash,omitempty"`
+
+	// Pending email verification, never sent to clients: the hash and RFC3339 expiry of the
+	// token, and the RFC3339 times tokens were sent, for rate limiting
+	VerifyTokenHash      string   `json:"-" xml:"-" dynamodbav:"verifyTokenHash,omitempty"`
+	VerifyTokenExpiresAt string   `json:"-" xml:"-" dynamodbav:"verifyTokenExpiresAt,omitempty"`
+	VerifySentAt         []string `json:"-" xml:"-" dynamodbav:"verifySentAt,omitempty"`
+
+	// Plain-text verification token, set only on the user returned by a create or resend
+	VerifyToken string `json:"-" xml:"-" dynamodbav:"-"`
 }
 
-// FetchUser retrieves a user by email from DynamoDB.
+// Validate checks the client-supplied fields of the user. Every field is checked, so all
+// the invalid ones are reported at once.
+//
+// Returns:
+//   - A validation error naming the invalid field, a validation error wrapping the
+//     ValidationErrors of every invalid field if there are several, or nil if the user is valid.
+func (u *User) Validate() error {
+	var invalid ValidationErrors
+	// The email and names are checked by the user schema, which names the reason an email
+	// is invalid, e.g. a malformed domain, rather than a bare "invalid email"
+	invalid.add(validateDocument(userSchema, map[string]interface{}{
+		"email": u.Email, "firstname": u.FirstName, "lastname": u.LastName,
+	}))
+	if u.Address != nil {
+		invalid.add(u.Address.Validate())
+	}
+	invalid.add(u.Tags.Validate())
+	invalid.add(ParseStatus(u.Status))
+	invalid.add(ParseRole(u.Role))
+	invalid.add(validateExpiry(u.ExpiresAt, time.Now()))
+	return invalid.err()
+}
+
+// sanitize cleans up the client-supplied strings of the user before they are validated and
+// stored: names and the email lose invisible characters, and every string is trimmed,
+// whitespace-collapsed and NFC-normalized.
+func (u *User) sanitize() {
+	u.Email = validators.SanitizeName(u.Email)
+	u.FirstName = validators.SanitizeName(u.FirstName)
+	u.LastName = validators.SanitizeName(u.LastName)
+	u.Status = validators.SanitizeString(u.Status)
+	u.Role = validators.SanitizeString(u.Role)
+	if u.Address != nil {
+		u.Address.sanitize()
+	}
+	u.Tags.sanitize()
+}
+
+// timestamp returns the current UTC time formatted as an RFC3339 string.
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// decodeUser strictly decodes a JSON request body into a User.
+// The body is first validated against a JSON Schema, so every field that is missing, of
+// the wrong type or unknown (e.g. the typo "firstName") is reported at once. The decoded
+// strings are sanitized.
+// The expiry may be given as an "expiresAt" timestamp or as "ttlDays" from now.
 //
 // Parameters:
-// - email: The email of the user to fetch.
-// - tableName: The name of the DynamoDB table.
-// - dynaClient: The DynamoDB client interface.
+// - body: The raw request body.
+// - schemaName: The schema the body must satisfy, e.g. createSchema.
+// - u: The User to decode into.
 //
 // Returns:
-// - A pointer to the User struct containing user details.
-// - An error if the user cannot be fetched or unmarshaled.
-func FetchUser(email string, tableName string, dynaClient dynamodbiface.DynamoDBAPI) (*User, error) {
-	input := &dynamodb.GetItemInput{
-		Key: map[string]*dynamodb.AttributeValue{
-			"email": {
-				S: aws.String(email),
-			},
-		},
-		TableName: aws.String(tableName),
-	}
-
-	// Fetch the item from DynamoDB
-	result, err := dynaClient.GetItem(input)
-	if err != nil {
-		return nil, errors.New(ErrorFailedToFetchRecord)
+// - Whether the body set (or cleared) the expiry.
+// - The plain-text password in the body, or nil if there is none.
+// - A validation error describing the problem (naming the field where possible), or nil.
+func decodeUser(body string, schemaName string, u *User) (bool, *string, error) {
+	var document interface{}
+	if err := DecodeJSON(body, &document); err != nil {
+		return false, nil, err
+	}
+	if err := validateDocument(schemaName, document); err != nil {
+		return false, nil, err
 	}
 
-	// Unmarshal the result into a User struct
-	item := new(User)
-	err = dynamodbattribute.UnmarshalMap(result.Item, item)
+	var req UserRequest
+	if err := DecodeJSON(body, &req); err != nil {
+		return false, nil, err
+	}
+	expiresAt, expirySet, err := req.expiry(time.Now())
 	if err != nil {
-		return nil, errors.New(ErrorFailedToUnmarshalRecord)
+		return false, nil, err
+	}
+	*u = req.User
+	u.ExpiresAt = expiresAt
+	u.sanitize()
+	return expirySet, req.Password, nil
+}
+
+// DecodeJSON strictly decodes a JSON request body into v, rejecting unknown fields, with
+// the validation errors of a user body.
+func DecodeJSON(body string, v interface{}) error {
+	if len(strings.TrimSpace(body)) == 0 {
+		return newError(ErrValidation, ErrorEmptyBody, nil)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+
+	// Anything after the JSON object makes the body malformed
+	if err == nil && decoder.More() {
+		return newError(ErrValidation, ErrorMalformedJSON, nil)
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var userErr *Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &userErr):
+		// Fields decoding themselves report their own validation errors
+		return err
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return newError(ErrValidation, ErrorMalformedJSON, err)
+	case errors.As(err, &typeErr):
+		return newFieldError(ErrValidation, ErrorInvalidFieldType, typeErr.Field, err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return newFieldError(ErrValidation, ErrorUnknownField, field, err)
+	default:
+		return newError(ErrValidation, ErrorInvalidUserData, err)
 	}
-	return item, nil
 }
 
-// FetchUsers retrieves all users from DynamoDB.
+// NormalizeEmail returns the key under which a user's email is stored.
+// Emails are lowercased and trimmed unless NORMALIZE_EMAILS is set to "false".
+func NormalizeEmail(email string) string {
+	if os.Getenv("NORMALIZE_EMAILS") == "false" {
+		return email
+	}
+	return validators.NormalizeEmail(email)
+}
+
+// shouldRetryRawEmail reports whether a lookup that missed on the normalized email
+// should be retried with the email exactly as supplied. This is enabled with
+// EMAIL_LOOKUP_FALLBACK=true to reach records stored before normalization.
+func shouldRetryRawEmail(err error, key string, email string) bool {
+	return errors.Is(err, ErrNotFound) && key != email && os.Getenv("EMAIL_LOOKUP_FALLBACK") == "true"
+}
+
+// FetchUser retrieves a user by email.
+// The email is normalized before the lookup. Expired users are not found, and neither
+// are soft-deleted users unless opts.IncludeDeleted is set.
 //
 // Parameters:
-// - tableName: The name of the DynamoDB table.
-// - dynaClient: The DynamoDB client interface.
+// - email: The email of the user to fetch.
+// - opts: Options tuning the read.
+// - repo: The repository storing the users.
 //
 // Returns:
-// - A pointer to a slice of User structs.
-// - An error if the users cannot be fetched or unmarshaled.
-func FetchUsers(tableName string, dynaClient dynamodbiface.DynamoDBAPI) (*[]User, error) {
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(tableName),
+// - A pointer to the User struct containing user details.
+// - An error if the user does not exist or cannot be fetched or unmarshaled.
+func FetchUser(email string, opts ReadOptions, repo Repository) (*User, error) {
+	// The deleted flag and expiry are needed to hide soft-deleted and expired users
+	if len(opts.Fields) > 0 {
+		opts.Fields = WithFields(opts.Fields, "deletedAt", "expiresAt")
 	}
 
-	// Scan the table for all items
-	result, err := dynaClient.Scan(input)
-	if err != nil {
-		return nil, errors.New(ErrorFailedToFetchRecord)
+	key := NormalizeEmail(email)
+	item, err := repo.Get(key, opts)
+	if shouldRetryRawEmail(err, key, email) {
+		item, err = repo.Get(email, opts)
 	}
-
-	// Unmarshal the result into a slice of User structs
-	items := new([]User)
-	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, items)
-	if err != nil {
-		return nil, errors.New(ErrorFailedToUnmarshalRecord)
+	// DynamoDB TTL deletion lags up to 48 hours, so expired users are hidden until then
+	if err == nil && ((item.DeletedAt != "" && !opts.IncludeDeleted) || item.ExpiresAt.Expired(time.Now())) {
+		return nil, newError(ErrNotFound, ErrorUserDoesNotExist, nil)
 	}
+	return item, err
+}
+
+// FetchUsers retrieves all users, up to opts.MaxItems.
+//
+// Parameters:
+// - opts: Options tuning the read.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - The page of users, flagged as truncated if the limit was hit.
+// - An error if the users cannot be fetched or unmarshaled.
+func FetchUsers(opts ReadOptions, repo Repository) (*Page, error) {
+	return repo.List(opts)
+}
+
+// FetchUsersByLastName retrieves the users with the given last name, up to opts.MaxItems.
+// When LASTNAME_INDEX names a global secondary index partitioned by lastname, the index is
+// queried; otherwise the table is scanned with a filter.
+//
+// Parameters:
+// - lastName: The exact last name to look up.
+// - opts: Options tuning the read; any other filter is replaced.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - The page of users, flagged as truncated if the limit was hit.
+// - An error if the users cannot be fetched, or if a consistent read targets the index.
+func FetchUsersByLastName(lastName string, opts ReadOptions, repo Repository) (*Page, error) {
+	opts.Filter = Filter{LastName: lastName}
+	return repo.List(opts)
+}
 
-	return items, nil
+// CountUsers counts the users without reading them.
+//
+// Parameters:
+// - opts: Options tuning the read.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - The number of users.
+// - An error if the users cannot be counted.
+func CountUsers(opts ReadOptions, repo Repository) (int64, error) {
+	return repo.Count(opts)
 }
 
-// CreateUser creates a new user in DynamoDB.
+// CreateOptions tune how a user is created
+type CreateOptions struct {
+	OverwriteDeleted bool // Replace a soft-deleted user with the same email instead of failing with a conflict
+	AllowSuspended   bool // Let the user be created suspended, as administrators may
+	AllowRoleChange  bool // Let the user be created with any role, as administrators may
+	RejectRoleChange bool // Fail with ErrForbidden, instead of creating a plain user, when a role may not be set
+}
+
+// CreateUser creates a new user from the request body.
 //
 // Parameters:
 // - req: APIGatewayProxyRequest containing the user data.
-// - tableName: The name of the DynamoDB table.
-// - dynaClient: The DynamoDB client interface.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - A pointer to the newly created User struct.
+// - An error if user creation fails.
+func CreateUser(req events.APIGatewayProxyRequest, repo Repository) (*User, error) {
+	return CreateUserWithOptions(req, CreateOptions{}, repo)
+}
+
+// CreateUserWithOptions creates a new user from the request body.
+// An email held by a soft-deleted user conflicts unless opts.OverwriteDeleted is set.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest containing the user data.
+// - opts: Options tuning the create.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - A pointer to the newly created User struct.
+// - An error if user creation fails.
+func CreateUserWithOptions(req events.APIGatewayProxyRequest, opts CreateOptions, repo Repository) (*User, error) {
+	return CreateUserFromJSON(req.Body, opts, repo)
+}
+
+// CreateUserFromJSON creates a new user from a JSON document, such as a request body or
+// a queue message.
+// An email held by a soft-deleted user conflicts unless opts.OverwriteDeleted is set.
+//
+// Parameters:
+// - body: The user as JSON.
+// - opts: Options tuning the create.
+// - repo: The repository storing the users.
 //
 // Returns:
 // - A pointer to the newly created User struct.
 // - An error if user creation fails.
-func CreateUser(req events.APIGatewayProxyRequest, tableName string, dynaClient dynamodbiface.DynamoDBAPI) (*User, error) {
+func CreateUserFromJSON(body string, opts CreateOptions, repo Repository) (*User, error) {
 	var newUser User
 
-	// Unmarshal the request body into a User struct
-	if err := json.Unmarshal([]byte(req.Body), &newUser); err != nil {
-		return nil, errors.New(ErrorInvalidUserData)
+	// Decode the JSON into a User struct
+	_, password, err := decodeUser(body, createSchema, &newUser)
+	if err != nil {
+		return nil, err
 	}
-
-	// Validate the user's email
-	if !validators.IsEmailValid(newUser.Email) {
-		return nil, errors.New(ErrorInvalidEmail)
+	token, err := prepareCreate(&newUser, password, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if the user already exists
-	currUser, _ := FetchUser(newUser.Email, tableName, dynaClient)
-	if currUser != nil && len(currUser.Email) != 0 {
-		return nil, errors.New(ErrorUserAlreadyExists)
+	_, err = FetchUser(newUser.Email, DefaultReadOptions(), repo)
+	if err == nil {
+		return nil, newError(ErrConflict, ErrorUserAlreadyExists, nil)
 	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	// Set the timestamps server-side, ignoring any values supplied by the client
+	newUser.CreatedAt = timestamp()
+	newUser.UpdatedAt = newUser.CreatedAt
+	newUser.Version = 1
+	newUser.DeletedAt = ""
 
-	// Marshal the new user into a DynamoDB item
-	result, err := dynamodbattribute.MarshalMap(newUser)
+	// Insert the new user, guarding against a concurrent create
+	err = repo.Create(&newUser)
+	if errors.Is(err, ErrConflict) {
+		// The email may be held by a soft-deleted user
+		err = createOverDeleted(&newUser, opts.OverwriteDeleted, repo)
+	}
 	if err != nil {
-		return nil, errors.New(ErrorCouldNotMarshalItem)
+		return nil, err
+	}
+
+	newUser.VerifyToken = token
+	return &newUser, nil
+}
+
+// prepareCreate checks a decoded new user and fills in what the server decides, as every
+// create does: the user's fields, password and email domain are validated, the status
+// defaults to active, the role is resolved, the password hashed, and a verification
+// token issued. The timestamps are left to the write.
+//
+// Parameters:
+// - newUser: The decoded user, whose email is normalized in place.
+// - password: The plain-text password of the body, or nil.
+// - opts: Options tuning the create.
+//
+// Returns:
+// - The plain-text verification token, which isn't stored.
+// - A validation error, or an ErrForbidden error for a status or role the caller can't set.
+func prepareCreate(newUser *User, password *string, opts CreateOptions) (string, error) {
+	newUser.Email = NormalizeEmail(newUser.Email)
+
+	// Validate the user's fields and password, and that the email's domain is accepted
+	var invalid ValidationErrors
+	invalid.add(newUser.Validate())
+	if password != nil {
+		invalid.add(validatePassword(*password, newUser.Email, "password"))
+	}
+	if err := invalid.err(); err != nil {
+		return "", err
+	}
+	if err := checkEmailDomain(newUser.Email); err != nil {
+		return "", err
+	}
+	if err := checkMailDomain(newUser.Email); err != nil {
+		return "", err
 	}
 
-	// Insert the new user into the DynamoDB table
-	input := &dynamodb.PutItemInput{
-		Item:      result,
-		TableName: aws.String(tableName),
+	// New users are active unless created otherwise
+	if newUser.Status == "" {
+		newUser.Status = StatusActive
+	}
+	if err := checkStatusChange(StatusActive, newUser.Status, opts.AllowSuspended); err != nil {
+		return "", err
 	}
 
-	_, putErr := dynaClient.PutItem(input)
-	if putErr != nil {
-		return nil, errors.New(ErrorCouldNotDynamoPutItem)
+	// New users are plain users unless an administrator creates them otherwise
+	var err error
+	if newUser.Role, err = resolveRole(RoleUser, newUser.Role, opts.AllowRoleChange, opts.RejectRoleChange); err != nil {
+		return "", err
 	}
 
-	return &newUser, nil
+	// Only the hash of the password is stored
+	if password != nil {
+		if newUser.PasswordHash, err = hashPassword(*password, newUser.Email, "password"); err != nil {
+			return "", err
+		}
+	}
+
+	// New users have to verify their email with the token sent to it
+	newUser.Verified = false
+	if err := issueVerifyToken(newUser, time.Now()); err != nil {
+		return "", err
+	}
+	token := newUser.VerifyToken
+	newUser.VerifyToken = ""
+	return token, nil
+}
+
+// UpdateUser updates an existing user from the request body.
+//
+// Parameters:
+// - req: APIGatewayProxyRequest containing the updated user data.
+// - email: The email of the user to update, or an empty string to take it from the request body.
+// - expectedVersion: The version the client last saw, or 0 to use the currently stored version.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - A pointer to the updated User struct.
+// - A pointer to the user as it was before the update.
+// - An error if the update fails or the version does not match.
+func UpdateUser(req events.APIGatewayProxyRequest, email string, expectedVersion int, repo Repository) (*User, *User, error) {
+	return UpdateUserWithOptions(req, email, expectedVersion, UpdateOptions{}, repo)
 }
 
-// UpdateUser updates an existing user in DynamoDB.
+// UpdateUserWithOptions updates an existing user from the request body.
+//
+// The write only succeeds if the stored version still matches the expected one,
+// so concurrent updates cannot silently overwrite each other. The stored status is kept
+// unless the body sets one, and suspended users can't be changed unless opts.AllowSuspended is set.
+// The role only changes if opts.AllowRoleChange is set.
 //
 // Parameters:
 // - req: APIGatewayProxyRequest containing the updated user data.
-// - tableName: The name of the DynamoDB table.
-// - dynaClient: The DynamoDB client interface.
+// - email: The email of the user to update, or an empty string to take it from the request body.
+// - expectedVersion: The version the client last saw, or 0 to use the currently stored version.
+// - opts: Options tuning the update.
+// - repo: The repository storing the users.
 //
 // Returns:
 // - A pointer to the updated User struct.
-// - An error if the update fails.
-func UpdateUser(req events.APIGatewayProxyRequest, tableName string, dynaClient dynamodbiface.DynamoDBAPI) (*User, error) {
+// - A pointer to the user as it was before the update.
+// - An error if the update fails or the version does not match.
+func UpdateUserWithOptions(req events.APIGatewayProxyRequest, email string, expectedVersion int, opts UpdateOptions,
+	repo Repository) (*User, *User, error) {
 	var newUser User
 
-	// Unmarshal the request body into a User struct
-	if err := json.Unmarshal([]byte(req.Body), &newUser); err != nil {
-		return nil, errors.New(ErrorInvalidEmail)
+	// Decode the request body into a User struct
+	expirySet, password, err := decodeUser(req.Body, updateSchema, &newUser)
+	if err != nil {
+		return nil, nil, err
+	}
+	if password != nil {
+		return nil, nil, newFieldError(ErrValidation, ErrorPasswordNotUpdatable, "password", nil)
 	}
 
-	// Check if the user exists
-	currUser, _ := FetchUser(newUser.Email, tableName, dynaClient)
-	if currUser != nil && len(currUser.Email) == 0 {
-		return nil, errors.New(ErrorUserDoesNotExist)
+	// The email is the key, so the body can't move the user to another one
+	if err := checkEmailUnchanged(email, newUser.Email); err != nil {
+		return nil, nil, err
+	}
+	if len(email) > 0 {
+		newUser.Email = email
 	}
+	rawEmail := newUser.Email
+	newUser.Email = NormalizeEmail(newUser.Email)
 
-	// Marshal the updated user into a DynamoDB item
-	result, err := dynamodbattribute.MarshalMap(newUser)
+	// Validate the user's email and names
+	if err := newUser.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	// Check if the user exists
+	currUser, err := FetchUser(rawEmail, DefaultReadOptions(), repo)
 	if err != nil {
-		return nil, errors.New(ErrorCouldNotMarshalItem)
+		return nil, nil, err
 	}
 
-	// Update the user in the DynamoDB table
-	input := &dynamodb.PutItemInput{
-		Item:      result,
-		TableName: aws.String(tableName),
+	if err := prepareUpdate(&newUser, currUser, expirySet, opts); err != nil {
+		return nil, nil, err
 	}
 
-	_, err = dynaClient.PutItem(input)
-	if err != nil {
-		return nil, errors.New(ErrorCouldNotDynamoPutItem)
+	// Fall back to the stored version when the client didn't supply one
+	if expectedVersion == 0 {
+		expectedVersion = currUser.Version
+	} else if expectedVersion != currUser.Version {
+		return nil, nil, newError(ErrPreconditionFailed, ErrorVersionMismatch, nil)
 	}
+	newUser.Version = expectedVersion + 1
 
-	return &newUser, nil
+	// Update the user only if nobody else wrote it in the meantime
+	if err := repo.Update(&newUser, expectedVersion); err != nil {
+		return nil, nil, err
+	}
+
+	return &newUser, currUser, nil
 }
 
-// DeleteUser deletes a user from DynamoDB by email.
+// prepareUpdate completes a user decoded from a client with what clients can't change
+// through an update: it keeps the stored key, creation time, credentials and verification,
+// refreshes the modification time, and checks the status and role changes.
 //
 // Parameters:
-// - req: APIGatewayProxyRequest containing the user's email in query parameters.
-// - tableName: The name of the DynamoDB table.
-// - dynaClient: The DynamoDB client interface.
+// - newUser: The user as the client sent it, completed in place.
+// - currUser: The user as stored.
+// - expirySet: Whether the client set or cleared the expiry; the stored one is kept otherwise.
+// - opts: Options tuning the update.
 //
 // Returns:
-// - An error if the user could not be deleted.
-func DeleteUser(req events.APIGatewayProxyRequest, tableName string, dynaClient dynamodbiface.DynamoDBAPI) error {
-	email := req.QueryStringParameters["email"]
-
-	// Prepare the delete item input
-	input := &dynamodb.DeleteItemInput{
-		Key: map[string]*dynamodb.AttributeValue{
-			"email": {
-				S: aws.String(email),
-			},
-		},
-		TableName: aws.String(tableName),
-	}
-
-	// Delete the item from DynamoDB
-	_, err := dynaClient.DeleteItem(input)
-	if err != nil {
-		return errors.New(ErrorCouldNotDeleteItem)
+// - An ErrForbidden error for a status or role change the caller may not make, or nil.
+func prepareUpdate(newUser *User, currUser *User, expirySet bool, opts UpdateOptions) error {
+	// Write back under the stored key, which may be a pre-normalization email
+	newUser.Email = currUser.Email
+
+	// Keep the original creation time and refresh the modification time,
+	// ignoring any values supplied by the client
+	newUser.CreatedAt = currUser.CreatedAt
+	newUser.UpdatedAt = timestamp()
+	newUser.DeletedAt = ""
+
+	// Keep the stored expiry unless the client set or cleared it
+	if !expirySet {
+		newUser.ExpiresAt = currUser.ExpiresAt
 	}
 
+	// The password only changes through ChangePassword, and verification through VerifyEmail
+	newUser.PasswordHash = currUser.PasswordHash
+	newUser.Verified = currUser.Verified
+	newUser.VerifyTokenHash, newUser.VerifyTokenExpiresAt = currUser.VerifyTokenHash, currUser.VerifyTokenExpiresAt
+	newUser.VerifySentAt = currUser.VerifySentAt
+
+	// Keep the stored status unless the client set one
+	if newUser.Status == "" {
+		newUser.Status = currUser.Status
+	}
+	if err := checkStatusChange(currUser.CurrentStatus(), newUser.CurrentStatus(), opts.AllowSuspended); err != nil {
+		return err
+	}
+
+	// Keep the stored role unless an administrator changes it
+	role, err := resolveRole(currUser.CurrentRole(), newUser.Role, opts.AllowRoleChange, opts.RejectRoleChange)
+	if err != nil {
+		return err
+	}
+	newUser.Role = role
 	return nil
 }
+
+// DeleteUser deletes a user by email.
+// The email is normalized before the lookup. With SOFT_DELETE=true the user is only
+// flagged with deletedAt unless hard is set.
+//
+// Parameters:
+// - email: The email of the user to delete.
+// - hard: Whether to remove the user even when soft deletes are enabled.
+// - repo: The repository storing the users.
+//
+// Returns:
+// - An error if the user does not exist or could not be deleted.
+func DeleteUser(email string, hard bool, repo Repository) error {
+	remove := repo.Delete
+	if SoftDeleteEnabled() && !hard {
+		deletedAt := timestamp()
+		remove = func(email string) error { return repo.SoftDelete(email, deletedAt) }
+	}
+
+	key := NormalizeEmail(email)
+	err := remove(key)
+	if shouldRetryRawEmail(err, key, email) {
+		return remove(email)
+	}
+	return err
+}
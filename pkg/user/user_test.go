@@ -0,0 +1,148 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Vansh3140/golang-serverless/pkg/validators"
+	"github.com/aws/aws-lambda-go/events"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimestampsAreMonotonic(t *testing.T) {
+	repo := NewMemoryRepository()
+	created, err := CreateUserFromJSON(`{"email": "ada@example.com", "firstname": "Ada", "lastname": "Lovelace"}`,
+		CreateOptions{}, repo)
+	if err != nil {
+		t.Fatalf("CreateUserFromJSON() error = %v", err)
+	}
+	if created.CreatedAt != created.UpdatedAt || created.Version != 1 {
+		t.Fatalf("created at %q, updated at %q, version %d; want equal times and version 1", created.CreatedAt,
+			created.UpdatedAt, created.Version)
+	}
+
+	steps := []struct {
+		name  string
+		write func() (*User, error)
+	}{
+		{name: "update", write: func() (*User, error) {
+			req := events.APIGatewayProxyRequest{Body: `{"firstname": "Ada", "lastname": "King", "createdAt": "1999-01-01T00:00:00Z"}`}
+			u, _, err := UpdateUserWithOptions(req, "ada@example.com", 0, UpdateOptions{}, repo)
+			return u, err
+		}},
+		{name: "patch", write: func() (*User, error) {
+			u, _, err := PatchUser("ada@example.com", `{"lastname": "Byron", "updatedAt": "1999-01-01T00:00:00Z"}`, 0,
+				UpdateOptions{}, repo)
+			return u, err
+		}},
+		{name: "status", write: func() (*User, error) {
+			return SetStatus("ada@example.com", StatusInactive, UpdateOptions{}, repo)
+		}},
+	}
+	previous := created
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			got, err := step.write()
+			if err != nil {
+				t.Fatalf("%s error = %v", step.name, err)
+			}
+			if got.CreatedAt != created.CreatedAt {
+				t.Errorf("CreatedAt = %q, want %q kept", got.CreatedAt, created.CreatedAt)
+			}
+			if !notBefore(t, got.UpdatedAt, previous.UpdatedAt) {
+				t.Errorf("UpdatedAt = %q, before the previous %q", got.UpdatedAt, previous.UpdatedAt)
+			}
+			if got.Version <= previous.Version {
+				t.Errorf("Version = %d, want more than %d", got.Version, previous.Version)
+			}
+			previous = got
+		})
+	}
+}
+
+func TestUpdateKeepsLaterTimestamps(t *testing.T) {
+	repo, stored := seedUser(t)
+	req := events.APIGatewayProxyRequest{Body: `{"firstname": "Ada", "lastname": "King"}`}
+	got, _, err := UpdateUserWithOptions(req, stored.Email, stored.Version, UpdateOptions{}, repo)
+	if err != nil {
+		t.Fatalf("UpdateUserWithOptions() error = %v", err)
+	}
+	if got.CreatedAt != stored.CreatedAt || got.UpdatedAt == stored.UpdatedAt || !notBefore(t, got.UpdatedAt, stored.UpdatedAt) {
+		t.Errorf("created at %q, updated at %q; want %q kept and a later update", got.CreatedAt, got.UpdatedAt,
+			stored.CreatedAt)
+	}
+
+	// A stale write leaves the stored timestamps alone
+	_, _, err = UpdateUserWithOptions(req, stored.Email, stored.Version, UpdateOptions{}, repo)
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("stale update error = %v, want ErrPreconditionFailed", err)
+	}
+	if current, _ := repo.Get(stored.Email, ReadOptions{}); current.UpdatedAt != got.UpdatedAt {
+		t.Errorf("UpdatedAt = %q after a stale update, want %q", current.UpdatedAt, got.UpdatedAt)
+	}
+}
+
+// notBefore reports whether the RFC3339 time a is at or after b.
+func notBefore(t *testing.T, a string, b string) bool {
+	t.Helper()
+	at, err := time.Parse(time.RFC3339, a)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", a, err)
+	}
+	bt, err := time.Parse(time.RFC3339, b)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", b, err)
+	}
+	return !at.Before(bt)
+}
+
+func TestNamesAreValidated(t *testing.T) {
+	long := strings.Repeat("a", validators.MaxNameLength+1)
+	tests := []struct {
+		name          string
+		firstname     string
+		lastname      string
+		wantFirstname string
+		wantField     string
+	}{
+		{name: "unicode", firstname: "Zoë", lastname: "李", wantFirstname: "Zoë"},
+		{name: "padded", firstname: "  Ada  ", lastname: "Lovelace", wantFirstname: "Ada"},
+		{name: "empty firstname", firstname: "", lastname: "Lovelace", wantField: "firstname"},
+		{name: "blank lastname", firstname: "Ada", lastname: "   ", wantField: "lastname"},
+		{name: "long firstname", firstname: long, lastname: "Lovelace", wantField: "firstname"},
+		{name: "long lastname", firstname: "Ada", lastname: long, wantField: "lastname"},
+		{name: "control character", firstname: "Ada\u0007", lastname: "Lovelace", wantFirstname: "Ada"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := json.Marshal(map[string]string{"firstname": tt.firstname, "lastname": tt.lastname})
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := `{"email": "ada@example.com", ` + string(names[1:])
+
+			repo := NewMemoryRepository()
+			created, createErr := CreateUserFromJSON(body, CreateOptions{}, repo)
+			if createErr != nil {
+				// Update a valid user instead
+				if err := repo.Create(&User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 1}); err != nil {
+					t.Fatalf("seeding: %v", err)
+				}
+			}
+			updated, _, updateErr := UpdateUser(events.APIGatewayProxyRequest{Body: body}, "ada@example.com", 0, repo)
+
+			for operation, err := range map[string]error{"create": createErr, "update": updateErr} {
+				if tt.wantField == "" && err != nil {
+					t.Errorf("%s error = %v", operation, err)
+				}
+				if tt.wantField != "" && !hasFieldError(err, tt.wantField, "") {
+					t.Errorf("%s error = %v, want one for %s", operation, err, tt.wantField)
+				}
+			}
+			if tt.wantField == "" && (created.FirstName != tt.wantFirstname || updated.FirstName != tt.wantFirstname) {
+				t.Errorf("firstname = %q created, %q updated; want %q", created.FirstName, updated.FirstName, tt.wantFirstname)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"time"
+)
+
+// Limits of the email verification flow
+const (
+	verifyTokenBytes = 32             // Random bytes per token
+	VerifyTokenTTL   = 24 * time.Hour // How long a token can be used
+	MaxVerifySends   = 3              // Tokens sent per email within verifySendWindow
+	verifySendWindow = time.Hour
+)
+
+// Error messages for email verification
+var (
+	ErrorMissingVerifyToken        = "body must carry the verification token"
+	ErrorInvalidVerifyToken        = "verification token is invalid"
+	ErrorVerifyTokenExpired        = "verification token has expired; request a new one"
+	ErrorAlreadyVerified           = "email is already verified"
+	ErrorTooManyVerifySends        = "at most 3 verification tokens can be sent per hour"
+	ErrorCouldNotCreateVerifyToken = "couldn't create a verification token"
+)
+
+// VerifyRequest is the body of an email verification
+type VerifyRequest struct {
+	Token string `json:"token"` // The token sent to the email
+}
+
+// hashVerifyToken returns the hex SHA-256 of a token, as stored on the user. Tokens are
+// random enough that a fast hash suffices.
+func hashVerifyToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueVerifyToken generates a verification token for u, storing its hash, expiry and
+// send time on u and the token itself in u.VerifyToken.
+//
+// Parameters:
+// - u: The user to verify.
+// - now: The current time.
+//
+// Returns:
+// - An error if no random token could be generated.
+func issueVerifyToken(u *User, now time.Time) error {
+	raw := make([]byte, verifyTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return newError(ErrInternal, ErrorCouldNotCreateVerifyToken, err)
+	}
+	u.VerifyToken = base64.RawURLEncoding.EncodeToString(raw)
+	u.VerifyTokenHash = hashVerifyToken(u.VerifyToken)
+	u.VerifyTokenExpiresAt = now.Add(VerifyTokenTTL).UTC().Format(time.RFC3339)
+	u.VerifySentAt = append(recentSends(u.VerifySentAt, now), now.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// recentSends returns the send times within verifySendWindow of now.
+func recentSends(sentAt []string, now time.Time) []string {
+	var recent []string
+	for _, sent := range sentAt {
+		if t, err := time.Parse(time.RFC3339, sent); err == nil && now.Sub(t) < verifySendWindow {
+			recent = append(recent, sent)
+		}
+	}
+	return recent
+}
+
+// clearVerification marks u as unverified and drops any pending token, e.g. when it moves
+// to a new email.
+func clearVerification(u *User) {
+	u.Verified = false
+	u.VerifyTokenHash, u.VerifyTokenExpiresAt, u.VerifySentAt = "", "", nil
+}
+
+// VerifyEmail checks the token sent to a user's email and marks the user verified.
+//
+// Parameters:
+// - email: The email of the user.
+// - body: The JSON body, {"token": "..."}.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The verified user.
+//   - A validation error for a missing or expired token, an ErrGone error for a wrong one,
+//     an ErrNotFound error if the user doesn't exist, an ErrConflict error if it is already
+//     verified, or an error if the write fails.
+func VerifyEmail(email string, body string, repo Repository) (*User, error) {
+	var req VerifyRequest
+	if err := DecodeJSON(body, &req); err != nil {
+		return nil, err
+	}
+	if req.Token == "" {
+		return nil, newFieldError(ErrValidation, ErrorMissingVerifyToken, "token", nil)
+	}
+
+	current, err := FetchUser(email, ReadOptions{ConsistentRead: true}, repo)
+	if err != nil {
+		return nil, err
+	}
+	if current.Verified {
+		return nil, newError(ErrConflict, ErrorAlreadyVerified, nil)
+	}
+	hash := hashVerifyToken(req.Token)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(current.VerifyTokenHash)) != 1 {
+		return nil, newFieldError(ErrGone, ErrorInvalidVerifyToken, "token", nil)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	if current.VerifyTokenExpiresAt <= now {
+		return nil, newFieldError(ErrValidation, ErrorVerifyTokenExpired, "token", nil)
+	}
+	return repo.MarkVerified(current.Email, hash, now)
+}
+
+// ResendVerification replaces a user's verification token with a new one, at most
+// MaxVerifySends times per hour.
+//
+// Parameters:
+// - email: The email of the user.
+// - repo: The repository storing the users.
+//
+// Returns:
+//   - The user, carrying the new token in VerifyToken.
+//   - An ErrNotFound error if the user doesn't exist, an ErrConflict error if it is already
+//     verified, an ErrRateLimited error past the limit, or an error if the write fails.
+func ResendVerification(email string, repo Repository) (*User, error) {
+	current, err := FetchUser(email, ReadOptions{ConsistentRead: true}, repo)
+	if err != nil {
+		return nil, err
+	}
+	if current.Verified {
+		return nil, newError(ErrConflict, ErrorAlreadyVerified, nil)
+	}
+	now := time.Now()
+	if len(recentSends(current.VerifySentAt, now)) >= MaxVerifySends {
+		return nil, newError(ErrRateLimited, ErrorTooManyVerifySends, nil)
+	}
+
+	previousHash := current.VerifyTokenHash
+	if err := issueVerifyToken(current, now); err != nil {
+		return nil, err
+	}
+	if err := repo.SetVerifyToken(current, previousHash); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// MarkVerified sets verified on the user stored under exactly the given email and removes
+// its token with an UpdateItem, bumping its version. The write is conditioned on the token
+// hash and expiry, so a token replaced or expired since it was checked is rejected.
+func (r *DynamoRepository) MarkVerified(email string, tokenHash string, now string) (*User, error) {
+	input := &dynamodb.UpdateItemInput{
+		Key:       emailKey(email),
+		TableName: aws.String(r.TableName),
+		UpdateExpression: aws.String("SET #verified = :true, #updatedAt = :now, #version = if_not_exists(#version, :zero) + :one " +
+			"REMOVE #verifyTokenHash, #verifyTokenExpiresAt, #verifySentAt"),
+		ConditionExpression: aws.String("attribute_exists(#key) AND #verifyTokenHash = :hash AND #verifyTokenExpiresAt > :now"),
+		ExpressionAttributeNames: withKeyName(map[string]*string{
+			"#verified":             aws.String("verified"),
+			"#updatedAt":            aws.String("updatedAt"),
+			"#version":              aws.String("version"),
+			"#verifyTokenHash":      aws.String("verifyTokenHash"),
+			"#verifyTokenExpiresAt": aws.String("verifyTokenExpiresAt"),
+			"#verifySentAt":         aws.String("verifySentAt"),
+		}),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":true": {BOOL: aws.Bool(true)},
+			":hash": {S: aws.String(tokenHash)},
+			":now":  {S: aws.String(now)},
+			":zero": {N: aws.String("0")},
+			":one":  {N: aws.String("1")},
+		},
+		ReturnValues:           aws.String(dynamodb.ReturnValueAllNew),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+
+	result, err := r.DynaClient.UpdateItem(input)
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, newFieldError(ErrGone, ErrorInvalidVerifyToken, "token", err)
+		}
+		return nil, newError(ErrStorage, ErrorCouldNotDynamoPutItem, err)
+	}
+
+	updated := new(User)
+	if err := UnmarshalItem(result.Attributes, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// SetVerifyToken stores the verification token hash, expiry and send times of u with an
+// UpdateItem, if the stored token hash is still previousHash. Concurrent resends therefore
+// can't both pass the rate limit.
+func (r *DynamoRepository) SetVerifyToken(u *User, previousHash string) error {
+	sentAt, err := dynamodbattribute.Marshal(u.VerifySentAt)
+	if err != nil {
+		return newError(ErrInternal, ErrorCouldNotMarshalItem, err)
+	}
+	condition := "#verifyTokenHash = :previous"
+	values := map[string]*dynamodb.AttributeValue{
+		":hash":      {S: aws.String(u.VerifyTokenHash)},
+		":expiresAt": {S: aws.String(u.VerifyTokenExpiresAt)},
+		":sentAt":    sentAt,
+	}
+	if previousHash == "" {
+		condition = "attribute_not_exists(#verifyTokenHash)"
+	} else {
+		values[":previous"] = &dynamodb.AttributeValue{S: aws.String(previousHash)}
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		Key:                 emailKey(u.Email),
+		TableName:           aws.String(r.TableName),
+		UpdateExpression:    aws.String("SET #verifyTokenHash = :hash, #verifyTokenExpiresAt = :expiresAt, #verifySentAt = :sentAt"),
+		ConditionExpression: aws.String("attribute_exists(#key) AND " + condition),
+		ExpressionAttributeNames: withKeyName(map[string]*string{
+			"#verifyTokenHash":      aws.String("verifyTokenHash"),
+			"#verifyTokenExpiresAt": aws.String("verifyTokenExpiresAt"),
+			"#verifySentAt":         aws.String("verifySentAt"),
+		}),
+		ExpressionAttributeValues: values,
+		ReturnConsumedCapacity:    aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}
+
+	if _, err := r.DynaClient.UpdateItem(input); err != nil {
+		if isConditionalCheckFailed(err) {
+			return newError(ErrRateLimited, ErrorTooManyVerifySends, err)
+		}
+		return newError(ErrStorage, ErrorCouldNotDynamoPutItem, err)
+	}
+	return nil
+}
+
+// MarkVerified sets verified on the user stored under the email if its token is still
+// tokenHash and unexpired at now.
+func (r *MemoryRepository) MarkVerified(email string, tokenHash string, now string) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[email]
+	if !ok || u.VerifyTokenHash != tokenHash || u.VerifyTokenExpiresAt <= now {
+		return nil, newFieldError(ErrGone, ErrorInvalidVerifyToken, "token", nil)
+	}
+	clearVerification(&u)
+	u.Verified, u.UpdatedAt, u.Version = true, now, u.Version+1
+	r.users[email] = u
+	return &u, nil
+}
+
+// SetVerifyToken stores the verification token of u if the stored one is still previousHash.
+func (r *MemoryRepository) SetVerifyToken(u *User, previousHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.Email]
+	if !ok || stored.VerifyTokenHash != previousHash {
+		return newError(ErrRateLimited, ErrorTooManyVerifySends, nil)
+	}
+	stored.VerifyTokenHash, stored.VerifyTokenExpiresAt, stored.VerifySentAt = u.VerifyTokenHash, u.VerifyTokenExpiresAt, u.VerifySentAt
+	r.users[u.Email] = stored
+	return nil
+}
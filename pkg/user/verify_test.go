@@ -0,0 +1,61 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     func(token string) string
+		expired  bool
+		verified bool
+		wantErr  error
+		wantMsg  string
+	}{
+		{name: "verified", body: func(token string) string { return `{"token": "` + token + `"}` }},
+		{name: "missing token", body: func(string) string { return `{}` }, wantErr: ErrValidation,
+			wantMsg: ErrorMissingVerifyToken},
+		{name: "wrong token", body: func(string) string { return `{"token": "not-the-token"}` }, wantErr: ErrGone,
+			wantMsg: ErrorInvalidVerifyToken},
+		{name: "expired token", body: func(token string) string { return `{"token": "` + token + `"}` }, expired: true,
+			wantErr: ErrValidation, wantMsg: ErrorVerifyTokenExpired},
+		{name: "wrong and expired token", body: func(string) string { return `{"token": "not-the-token"}` },
+			expired: true, wantErr: ErrGone, wantMsg: ErrorInvalidVerifyToken},
+		{name: "already verified", body: func(token string) string { return `{"token": "` + token + `"}` },
+			verified: true, wantErr: ErrConflict, wantMsg: ErrorAlreadyVerified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{Email: "ada@example.com", FirstName: "Ada", LastName: "Lovelace", Version: 1,
+				Verified: tt.verified}
+			if err := issueVerifyToken(u, time.Now()); err != nil {
+				t.Fatalf("issueVerifyToken() error = %v", err)
+			}
+			token := u.VerifyToken
+			if tt.expired {
+				u.VerifyTokenExpiresAt = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+			}
+			repo := NewMemoryRepository()
+			if err := repo.Create(u); err != nil {
+				t.Fatalf("seeding: %v", err)
+			}
+
+			got, err := VerifyEmail("ada@example.com", tt.body(token), repo)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || err.Error() != tt.wantMsg {
+					t.Fatalf("VerifyEmail() error = %v, want %v %q", err, tt.wantErr, tt.wantMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyEmail() error = %v", err)
+			}
+			if !got.Verified || got.VerifyTokenHash != "" || got.Version != 2 {
+				t.Errorf("VerifyEmail() = %+v, want it verified without a token at version 2", got)
+			}
+		})
+	}
+}
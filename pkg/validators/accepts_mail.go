@@ -0,0 +1,109 @@
+package validators
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/net/idna"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mailLookupTimeout bounds the DNS lookups of AcceptsMail, so a slow resolver can't hold up
+// the request
+const mailLookupTimeout = 500 * time.Millisecond
+
+// maxCachedMailDomains is how many domains are cached at once, so signups from many
+// domains can't exhaust memory
+const maxCachedMailDomains = 10000
+
+// Resolver looks up the DNS records AcceptsMail needs. *net.Resolver implements it.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// MailResolver is the resolver used by AcceptsMail; tests can replace it with a stub.
+var MailResolver Resolver = net.DefaultResolver
+
+// mailDomains caches, for the container lifetime, whether each domain accepts mail.
+// Only definite answers are cached, never lookups that failed open.
+var mailDomains = struct {
+	sync.RWMutex
+	accepts map[string]bool
+}{accepts: map[string]bool{}}
+
+// AcceptsMail checks whether the domain of an email address can receive mail.
+//
+// A domain accepts mail if it has MX records, or if it has none but resolves to an address,
+// which mail servers then deliver to. A "null MX" (a single MX record for ".") declares that
+// the domain accepts no mail. Lookups that time out or fail for any other reason than the
+// records not existing count as accepting mail, so a DNS outage doesn't block signups.
+//
+// Parameters:
+// - email: The email address to check.
+//
+// Returns:
+// - A boolean indicating whether the domain accepts mail (true) or surely doesn't (false).
+func AcceptsMail(email string) bool {
+	domain := normalizeDomain(email[strings.LastIndex(email, "@")+1:])
+	// DNS knows internationalized domains by their punycode form
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		domain = ascii
+	}
+
+	mailDomains.RLock()
+	accepts, cached := mailDomains.accepts[domain]
+	mailDomains.RUnlock()
+	if cached {
+		return accepts
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mailLookupTimeout)
+	defer cancel()
+	accepts, definite := lookupMailDomain(ctx, domain)
+	if definite {
+		mailDomains.Lock()
+		if len(mailDomains.accepts) >= maxCachedMailDomains {
+			mailDomains.accepts = map[string]bool{}
+		}
+		mailDomains.accepts[domain] = accepts
+		mailDomains.Unlock()
+	}
+	return accepts
+}
+
+// lookupMailDomain looks up the MX records of a domain, then its addresses if it has none.
+//
+// Returns:
+// - Whether the domain accepts mail.
+// - Whether the answer is definite, rather than a failed lookup counted as accepting mail.
+func lookupMailDomain(ctx context.Context, domain string) (bool, bool) {
+	records, err := MailResolver.LookupMX(ctx, domain)
+	switch {
+	case err == nil && len(records) == 1 && strings.Trim(records[0].Host, ".") == "":
+		return false, true
+	case err == nil && len(records) > 0:
+		return true, true
+	case err != nil && !isNotFound(err):
+		return true, false
+	}
+
+	hosts, err := MailResolver.LookupHost(ctx, domain)
+	switch {
+	case err == nil:
+		return len(hosts) > 0, true
+	case isNotFound(err):
+		return false, true
+	default:
+		return true, false
+	}
+}
+
+// isNotFound reports whether a lookup failed because the records don't exist, as opposed
+// to a timeout or a server failure.
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,90 @@
+package validators
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// stubResolver answers lookups from fixed records, counting them. A nil answer with no
+// error is a name that doesn't exist.
+type stubResolver struct {
+	mx      []*net.MX
+	mxErr   error
+	hosts   []string
+	hostErr error
+	block   bool // Wait for the lookup's deadline instead of answering
+	lookups int
+}
+
+func (r *stubResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	r.lookups++
+	if r.block {
+		<-ctx.Done()
+		return nil, &net.DNSError{Err: ctx.Err().Error(), Name: name, IsTimeout: true}
+	}
+	if r.mx == nil && r.mxErr == nil {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return r.mx, r.mxErr
+}
+
+func (r *stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.lookups++
+	if r.hosts == nil && r.hostErr == nil {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return r.hosts, r.hostErr
+}
+
+func TestAcceptsMail(t *testing.T) {
+	servfail := &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}
+	tests := []struct {
+		name        string
+		resolver    *stubResolver
+		want        bool
+		wantCached  bool
+		wantLookups int
+	}{
+		{name: "mx", resolver: &stubResolver{mx: []*net.MX{{Host: "mx.example.com.", Pref: 10}}}, want: true,
+			wantCached: true, wantLookups: 1},
+		{name: "null mx", resolver: &stubResolver{mx: []*net.MX{{Host: ".", Pref: 0}}}, wantCached: true, wantLookups: 1},
+		{name: "address only", resolver: &stubResolver{hosts: []string{"192.0.2.1"}}, want: true, wantCached: true,
+			wantLookups: 2},
+		{name: "neither", resolver: &stubResolver{}, wantCached: true, wantLookups: 2},
+		{name: "mx timeout fails open", resolver: &stubResolver{block: true}, want: true, wantLookups: 1},
+		{name: "mx servfail fails open", resolver: &stubResolver{mxErr: servfail}, want: true, wantLookups: 1},
+		{name: "address servfail fails open", resolver: &stubResolver{hostErr: servfail}, want: true, wantLookups: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(resolver Resolver) { MailResolver = resolver }(MailResolver)
+			MailResolver = tt.resolver
+			mailDomains.accepts = map[string]bool{}
+
+			start := time.Now()
+			if got := AcceptsMail("ada@Example.com"); got != tt.want {
+				t.Errorf("AcceptsMail() = %v, want %v", got, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > 2*mailLookupTimeout {
+				t.Errorf("AcceptsMail() took %v, want at most the %v timeout", elapsed, mailLookupTimeout)
+			}
+			if tt.resolver.lookups != tt.wantLookups {
+				t.Errorf("lookups = %d, want %d", tt.resolver.lookups, tt.wantLookups)
+			}
+
+			// Definite answers are cached for the domain; failed lookups are retried
+			if got := AcceptsMail("grace@example.com"); got != tt.want {
+				t.Errorf("second AcceptsMail() = %v, want %v", got, tt.want)
+			}
+			wantLookups := tt.wantLookups
+			if !tt.wantCached {
+				wantLookups *= 2
+			}
+			if tt.resolver.lookups != wantLookups {
+				t.Errorf("lookups after a second call = %d, want %d", tt.resolver.lookups, wantLookups)
+			}
+		})
+	}
+}
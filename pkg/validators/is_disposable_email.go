@@ -0,0 +1,126 @@
+package validators
+
+import (
+	_ "embed" // Embeds the default blocklist
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultDisposableDomains is the built-in list of disposable email domains
+//
+//go:embed disposable_domains.txt
+var defaultDisposableDomains string
+
+// maxDisposableListBytes bounds the list downloaded from DISPOSABLE_DOMAINS_URL
+const maxDisposableListBytes = 8 << 20
+
+// disposableListClient downloads the blocklist with a short timeout so a slow host can't
+// stall a cold start
+var disposableListClient = &http.Client{Timeout: 3 * time.Second}
+
+// disposableDomains is the set of blocked domains, loaded on first use unless
+// LoadDisposableDomains loaded it at cold start
+var disposableDomains = struct {
+	sync.RWMutex
+	domains map[string]bool
+}{}
+
+// LoadDisposableDomains loads the disposable email domains blocked by IsDisposableEmail,
+// and is meant to be called once at cold start.
+//
+// The list downloaded from DISPOSABLE_DOMAINS_URL, if set, replaces the built-in one, and
+// the comma-separated domains of EXTRA_BLOCKED_DOMAINS are added to it. Lists hold one
+// domain per line; blank lines and lines starting with # are ignored.
+//
+// Returns:
+// - An error if the list can't be downloaded, in which case the built-in one is used.
+func LoadDisposableDomains() error {
+	list := defaultDisposableDomains
+	var err error
+	if url := os.Getenv("DISPOSABLE_DOMAINS_URL"); url != "" {
+		var downloaded string
+		if downloaded, err = downloadDomainList(url); err == nil {
+			list = downloaded
+		}
+	}
+
+	domains := map[string]bool{}
+	for _, line := range strings.Split(list, "\n") {
+		if domain := normalizeDomain(line); domain != "" && !strings.HasPrefix(domain, "#") {
+			domains[domain] = true
+		}
+	}
+	for _, extra := range strings.Split(os.Getenv("EXTRA_BLOCKED_DOMAINS"), ",") {
+		if domain := normalizeDomain(extra); domain != "" {
+			domains[domain] = true
+		}
+	}
+
+	disposableDomains.Lock()
+	disposableDomains.domains = domains
+	disposableDomains.Unlock()
+	return err
+}
+
+// downloadDomainList fetches a domain list from a URL.
+func downloadDomainList(url string) (string, error) {
+	resp, err := disposableListClient.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download disposable domains: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download disposable domains: status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDisposableListBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read disposable domains: %w", err)
+	}
+	return string(body), nil
+}
+
+// normalizeDomain lowercases a domain and trims surrounding whitespace and dots.
+func normalizeDomain(domain string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+// IsDisposableEmail checks whether an email address belongs to a disposable email service.
+//
+// The domain and each of its parent domains are looked up in the blocklist, so
+// "foo.mailinator.com" is blocked when "mailinator.com" is listed. The list is loaded on
+// first use if LoadDisposableDomains wasn't called.
+//
+// Parameters:
+// - email: The email address to check.
+//
+// Returns:
+// - A boolean indicating whether the domain is blocked (true) or not (false).
+func IsDisposableEmail(email string) bool {
+	disposableDomains.RLock()
+	domains := disposableDomains.domains
+	disposableDomains.RUnlock()
+	if domains == nil {
+		LoadDisposableDomains()
+		disposableDomains.RLock()
+		domains = disposableDomains.domains
+		disposableDomains.RUnlock()
+	}
+
+	domain := normalizeDomain(email[strings.LastIndex(email, "@")+1:])
+	for domain != "" {
+		if domains[domain] {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
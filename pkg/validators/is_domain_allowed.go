@@ -0,0 +1,30 @@
+package validators
+
+import "strings"
+
+// IsDomainAllowed checks an email address against a domain allowlist.
+//
+// Patterns are compared case-insensitively. A pattern such as "example.com" matches that
+// domain only, while "*.corp.example.com" matches its subdomains at any depth, e.g.
+// "eu.corp.example.com", but neither "corp.example.com" itself nor "evilcorp.example.com".
+//
+// Parameters:
+// - email: The email address to check.
+// - patterns: The allowed domains and wildcard patterns.
+//
+// Returns:
+// - A boolean indicating whether the email's domain matches a pattern (true) or not (false).
+func IsDomainAllowed(email string, patterns []string) bool {
+	domain := normalizeDomain(email[strings.LastIndex(email, "@")+1:])
+	for _, pattern := range patterns {
+		pattern = normalizeDomain(pattern)
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(domain, pattern[1:]) && len(domain) > len(pattern)-1 {
+				return true
+			}
+		} else if pattern != "" && domain == pattern {
+			return true
+		}
+	}
+	return false
+}
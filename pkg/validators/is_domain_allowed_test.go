@@ -0,0 +1,43 @@
+package validators
+
+import (
+	"testing"
+)
+
+func TestIsDomainAllowed(t *testing.T) {
+	patterns := []string{"example.com", "*.corp.example.org", " Partner.Example.NET "}
+	tests := []struct {
+		name  string
+		email string
+		want  bool
+	}{
+		{name: "exact", email: "ada@example.com", want: true},
+		{name: "exact in another case", email: "ada@EXAMPLE.Com", want: true},
+		{name: "exact with a trailing dot", email: "ada@example.com.", want: true},
+		{name: "padded pattern", email: "ada@partner.example.net", want: true},
+		{name: "subdomain of an exact pattern", email: "ada@mail.example.com"},
+		{name: "wildcard", email: "ada@eu.corp.example.org", want: true},
+		{name: "wildcard at depth", email: "ada@a.b.corp.example.org", want: true},
+		{name: "wildcard in another case", email: "ada@EU.Corp.Example.org", want: true},
+		{name: "wildcard apex", email: "ada@corp.example.org"},
+		{name: "wildcard near miss", email: "ada@evilcorp.example.org"},
+		{name: "suffix near miss", email: "ada@notexample.com"},
+		{name: "prefix near miss", email: "ada@example.com.evil.net"},
+		{name: "other top-level domain", email: "ada@example.co"},
+		{name: "other domain", email: "ada@gmail.com"},
+		{name: "allowed domain in the local part", email: "example.com@gmail.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDomainAllowed(tt.email, patterns); got != tt.want {
+				t.Errorf("IsDomainAllowed(%q) = %v, want %v", tt.email, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("empty patterns", func(t *testing.T) {
+		if IsDomainAllowed("ada@example.com", []string{"", " "}) {
+			t.Error("IsDomainAllowed() = true with only empty patterns, want false")
+		}
+	})
+}
@@ -1,28 +1,192 @@
 package validators
 
-import "regexp"
+import (
+	"errors"
+	"golang.org/x/net/idna"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
-// IsEmailValid validates an email address.
+// Reasons ValidateEmail rejects an email address for
+var (
+	ErrEmailTooLong   = errors.New("email is too long; it may have 64 characters before the @ and 254 in all")
+	ErrEmailTooShort  = errors.New("email is too short")
+	ErrEmailBadFormat = errors.New("email must look like name@domain")
+	ErrEmailBadDomain = errors.New("email domain must be dot-separated labels of letters, digits and hyphens")
+)
+
+// Regular expressions validating the two halves of an email address, compiled once.
+var (
+	rxEmailLocal  = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+$")
+	rxEmailDomain = regexp.MustCompile("^[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?" +
+		"(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+)
+
+// ValidateEmail validates an email address and explains why it is invalid.
 //
-// This function checks if the given email address adheres to a standard email format
-// using a regular expression. It also validates the email's length to ensure it falls
-// within acceptable bounds.
+// This function checks the length of the email address, then that it is a local part of
+// at most 64 allowed characters, an @, and a domain of dot-separated labels. With
+// ALLOW_IDN_EMAIL=true, addresses with non-ASCII characters are checked by
+// ValidateInternationalEmail instead.
 //
 // Parameters:
 // - email: The email address to validate.
 //
 // Returns:
-// - A boolean indicating whether the email address is valid (true) or invalid (false).
-func IsEmailValid(email string) bool {
-	// Regular expression to validate an email address format.
-	var rxEmail = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]{1,64}@[a-zA-Z0-9]" +
-		"(?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9]" +
-		"(?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+//   - nil if the email is valid, or ErrEmailTooShort, ErrEmailTooLong, ErrEmailBadFormat or
+//     ErrEmailBadDomain.
+func ValidateEmail(email string) error {
+	if allowIDNEmail() && !isASCII(email) {
+		return ValidateInternationalEmail(email)
+	}
+	if len(email) < 3 {
+		return ErrEmailTooShort
+	}
+	if len(email) > 254 {
+		return ErrEmailTooLong
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return ErrEmailBadFormat
+	}
+	local, domain := email[:at], email[at+1:]
+	if len(local) > 64 {
+		return ErrEmailTooLong
+	}
+	if !rxEmailLocal.MatchString(local) {
+		return ErrEmailBadFormat
+	}
+	if !rxEmailDomain.MatchString(domain) {
+		return ErrEmailBadDomain
+	}
 
-	// Validate email length and check if it matches the regex pattern.
-	if len(email) < 3 || len(email) > 254 || !rxEmail.MatchString(email) {
-		return false // Invalid email
+	return nil // Valid email
+}
+
+// ValidateInternationalEmail validates an internationalized email address (RFC 6531) and
+// explains why it is invalid.
+//
+// The local part may hold letters, marks and digits of any script on top of the ASCII
+// characters ValidateEmail allows, but no symbols such as emoji. The domain may be an IDN;
+// it is converted to its ASCII (punycode) form first, which must then be a valid domain.
+// The length limits apply to the punycode form, counting the local part in UTF-8 bytes.
+//
+// Parameters:
+// - email: The email address to validate.
+//
+// Returns:
+//   - nil if the email is valid, or ErrEmailTooShort, ErrEmailTooLong, ErrEmailBadFormat or
+//     ErrEmailBadDomain.
+func ValidateInternationalEmail(email string) error {
+	if !utf8.ValidString(email) {
+		return ErrEmailBadFormat
+	}
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		if len(email) < 3 {
+			return ErrEmailTooShort
+		}
+		return ErrEmailBadFormat
 	}
+	local, domain := email[:at], email[at+1:]
 
-	return true // Valid email
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err != nil || !rxEmailDomain.MatchString(asciiDomain) || !isDomainTextValid(domain) {
+		return ErrEmailBadDomain
+	}
+	length := len(local) + 1 + len(asciiDomain)
+	if length < 3 {
+		return ErrEmailTooShort
+	}
+	if length > 254 || len(local) > 64 {
+		return ErrEmailTooLong
+	}
+	for _, r := range local {
+		if r < utf8.RuneSelf {
+			if !rxEmailLocal.MatchString(string(r)) {
+				return ErrEmailBadFormat
+			}
+		} else if !unicode.In(r, unicode.L, unicode.M, unicode.N) {
+			return ErrEmailBadFormat
+		}
+	}
+
+	return nil // Valid email
+}
+
+// cjkScripts are the scripts East Asian names mix in a single word
+var cjkScripts = map[string]bool{"Han": true, "Hiragana": true, "Katakana": true, "Hangul": true, "Bopomofo": true}
+
+// isDomainTextValid reports whether the labels of a Unicode domain hold letters, marks,
+// digits and hyphens only, rejecting symbols such as emoji that IDNA lookups tolerate, and
+// whether each label sticks to one script, so e.g. a Cyrillic "а" can't pose as a Latin
+// "a". East Asian scripts may be mixed with each other and with Latin.
+func isDomainTextValid(domain string) bool {
+	for _, label := range strings.Split(domain, ".") {
+		scripts := map[string]bool{}
+		for _, r := range label {
+			if r == '-' || unicode.IsDigit(r) || unicode.Is(unicode.M, r) {
+				continue
+			}
+			if !unicode.IsLetter(r) {
+				return false
+			}
+			scripts[scriptOf(r)] = true
+		}
+		if len(scripts) < 2 {
+			continue
+		}
+		for script := range scripts {
+			if !cjkScripts[script] && script != "Latin" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// scriptOf returns the name of the Unicode script of a letter.
+func scriptOf(r rune) string {
+	if r < utf8.RuneSelf {
+		return "Latin"
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// allowIDNEmail reports whether internationalized email addresses are accepted
+// (ALLOW_IDN_EMAIL=true).
+func allowIDNEmail() bool {
+	return os.Getenv("ALLOW_IDN_EMAIL") == "true"
+}
+
+// isASCII reports whether s holds ASCII characters only.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEmailValid validates an email address.
+//
+// This function reports whether ValidateEmail accepts the email address.
+//
+// Parameters:
+// - email: The email address to validate.
+//
+// Returns:
+// - A boolean indicating whether the email address is valid (true) or invalid (false).
+func IsEmailValid(email string) bool {
+	return ValidateEmail(email) == nil
 }
@@ -0,0 +1,93 @@
+package validators
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		idn   string
+		want  error
+	}{
+		{name: "valid", email: "ada@example.com"},
+		{name: "plus", email: "ada+news@example.com"},
+		{name: "subdomain", email: "ada@mail.example.co.uk"},
+		{name: "shortest", email: "a@b"},
+		{name: "symbols in the local part", email: "o'brien!#$%&*=?^_`{|}~@example.com"},
+		{name: "empty", email: "", want: ErrEmailTooShort},
+		{name: "two characters", email: "a@", want: ErrEmailTooShort},
+		{name: "too long", email: strings.Repeat("a", 64) + "@" + strings.Repeat("b", 186) + ".com", want: ErrEmailTooLong},
+		{name: "longest", email: strings.Repeat("a", 64) + "@" + strings.Repeat("b", 61) + "." + strings.Repeat("c", 61) +
+			"." + strings.Repeat("d", 61) + ".com"},
+		{name: "local part too long", email: strings.Repeat("a", 65) + "@example.com", want: ErrEmailTooLong},
+		{name: "no at", email: "ada.example.com", want: ErrEmailBadFormat},
+		{name: "nothing before the at", email: "@example.com", want: ErrEmailBadFormat},
+		{name: "nothing after the at", email: "ada@", want: ErrEmailBadFormat},
+		{name: "space in the local part", email: "ada lovelace@example.com", want: ErrEmailBadFormat},
+		{name: "two ats", email: "ada@home@example.com", want: ErrEmailBadFormat},
+		{name: "quoted local part", email: `"ada"@example.com`, want: ErrEmailBadFormat},
+		{name: "underscore in the domain", email: "ada@exa_mple.com", want: ErrEmailBadDomain},
+		{name: "leading hyphen in the domain", email: "ada@-example.com", want: ErrEmailBadDomain},
+		{name: "empty label", email: "ada@example..com", want: ErrEmailBadDomain},
+		{name: "trailing dot", email: "ada@example.com.", want: ErrEmailBadDomain},
+		{name: "label too long", email: "ada@" + strings.Repeat("a", 64) + ".com", want: ErrEmailBadDomain},
+		{name: "unicode without IDN", email: "josé@example.com", want: ErrEmailBadFormat},
+		{name: "unicode local part", email: "josé@example.com", idn: "true"},
+		{name: "unicode domain", email: "ada@bücher.example", idn: "true"},
+		{name: "emoji local part", email: "ada😀@example.com", idn: "true", want: ErrEmailBadFormat},
+		{name: "emoji domain", email: "ada@i❤.example", idn: "true", want: ErrEmailBadDomain},
+		{name: "mixed-script domain", email: "ada@ex\u0430mple.com", idn: "true", want: ErrEmailBadDomain},
+		{name: "unicode without an at", email: "josé", idn: "true", want: ErrEmailBadFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOW_IDN_EMAIL", tt.idn)
+			if err := ValidateEmail(tt.email); err != tt.want {
+				t.Errorf("ValidateEmail(%q) = %v, want %v", tt.email, err, tt.want)
+			}
+			if valid := IsEmailValid(tt.email); valid != (tt.want == nil) {
+				t.Errorf("IsEmailValid(%q) = %v, want %v", tt.email, valid, tt.want == nil)
+			}
+		})
+	}
+}
+
+func TestValidateInternationalEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		want  error
+	}{
+		{name: "chinese and japanese", email: "用户@例え.jp"},
+		{name: "cyrillic", email: "ада@пример.рф"},
+		{name: "ascii", email: "ada@example.com"},
+		{name: "combining mark", email: "josé@example.com"},
+		{name: "punycode domain", email: "ada@xn--bcher-kva.example"},
+		{name: "han and latin label", email: "ada@例example.jp"},
+		{name: "han and hiragana label", email: "ada@例え.jp"},
+		{name: "latin and cyrillic label", email: "ada@ex\u0430mple.com", want: ErrEmailBadDomain},
+		{name: "cyrillic and greek label", email: "ada@\u0430\u03b1.example", want: ErrEmailBadDomain},
+		{name: "emoji local part", email: "ada\U0001f600@example.com", want: ErrEmailBadFormat},
+		{name: "emoji domain", email: "ada@❤.example", want: ErrEmailBadDomain},
+		{name: "symbol local part", email: "ada™@example.com", want: ErrEmailBadFormat},
+		{name: "space", email: "用 户@example.com", want: ErrEmailBadFormat},
+		{name: "invalid UTF-8", email: "ada\xff@example.com", want: ErrEmailBadFormat},
+		// 21 three-byte characters are 63 bytes; 22 are 66, over the 64 allowed
+		{name: "local part at the byte limit", email: strings.Repeat("用", 21) + "@example.com"},
+		{name: "local part over the byte limit", email: strings.Repeat("用", 22) + "@example.com", want: ErrEmailTooLong},
+		// The punycode of each label is longer than the label, and the limits apply to it
+		{name: "domain over the limit in punycode", email: "ada@" + strings.Repeat(strings.Repeat("ü", 20)+".", 12) + "example",
+			want: ErrEmailTooLong},
+		{name: "label over the limit in punycode", email: "ada@" + strings.Repeat("ü", 60) + ".example", want: ErrEmailBadDomain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateInternationalEmail(tt.email); err != tt.want {
+				t.Errorf("ValidateInternationalEmail(%q) = %v, want %v", tt.email, err, tt.want)
+			}
+		})
+	}
+}
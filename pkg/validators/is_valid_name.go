@@ -0,0 +1,42 @@
+package validators
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the maximum number of characters allowed in a first or last name.
+const MaxNameLength = 100
+
+// IsNameValid validates a first or last name.
+//
+// This function checks that the name is non-empty valid UTF-8, carries no leading or
+// trailing whitespace, is at most MaxNameLength characters long and contains no control
+// characters. Letters from any script are accepted.
+//
+// Parameters:
+// - name: The name to validate.
+//
+// Returns:
+// - A boolean indicating whether the name is valid (true) or invalid (false).
+func IsNameValid(name string) bool {
+	// Reject empty, padded or malformed names
+	if len(name) == 0 || name != strings.TrimSpace(name) || !utf8.ValidString(name) {
+		return false
+	}
+
+	// Length is counted in characters, not bytes, so non-Latin names aren't penalized
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return false
+	}
+
+	// Reject control characters such as newlines and tabs
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,40 @@
+package validators
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsNameValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "ascii", input: "Ada", want: true},
+		{name: "accented", input: "Zoë", want: true},
+		{name: "apostrophe and hyphen", input: "O'Brien-Smith", want: true},
+		{name: "cyrillic", input: "Анна", want: true},
+		{name: "han", input: "李小龍", want: true},
+		{name: "arabic", input: "فاطمة", want: true},
+		{name: "inner space", input: "Mary Ann", want: true},
+		{name: "empty", input: "", want: false},
+		{name: "only spaces", input: "   ", want: false},
+		{name: "leading space", input: " Ada", want: false},
+		{name: "trailing space", input: "Ada ", want: false},
+		{name: "trailing newline", input: "Ada\n", want: false},
+		{name: "tab inside", input: "A\tda", want: false},
+		{name: "invalid utf-8", input: "Ad\xffa", want: false},
+		{name: "at the limit", input: strings.Repeat("a", MaxNameLength), want: true},
+		{name: "over the limit", input: strings.Repeat("a", MaxNameLength+1), want: false},
+		{name: "multibyte at the limit", input: strings.Repeat("é", MaxNameLength), want: true},
+		{name: "multibyte over the limit", input: strings.Repeat("é", MaxNameLength+1), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNameValid(tt.input); got != tt.want {
+				t.Errorf("IsNameValid(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
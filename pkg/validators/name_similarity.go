@@ -0,0 +1,85 @@
+package validators
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// FoldName reduces a name to the form names are compared in.
+//
+// The name is decomposed so accents can be dropped ("José" becomes "jose"), lowercased,
+// and its runs of whitespace are collapsed to a single space.
+//
+// Parameters:
+// - name: The name to fold.
+//
+// Returns:
+// - The folded name.
+func FoldName(name string) string {
+	folded := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, norm.NFD.String(name))
+	return strings.Join(strings.Fields(folded), " ")
+}
+
+// NameSimilarity scores how alike two names are.
+//
+// Both names are folded with FoldName, then compared by their Levenshtein distance in
+// characters, normalized by the length of the longer one: 1 means the same name, 0 that
+// no character lines up.
+//
+// Parameters:
+// - a: The first name.
+// - b: The second name.
+//
+// Returns:
+// - The similarity, between 0 and 1.
+func NameSimilarity(a string, b string) float64 {
+	ra, rb := []rune(FoldName(a)), []rune(FoldName(b))
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein counts the insertions, deletions and substitutions turning a into b, keeping
+// a single row of the distance matrix.
+func levenshtein(a []rune, b []rune) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			above := row[j]
+			row[j] = min3(row[j]+1, row[j-1]+1, diagonal+cost)
+			diagonal = above
+		}
+	}
+	return row[len(b)]
+}
+
+// min3 returns the smallest of three ints.
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,64 @@
+package validators
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFoldName(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "plain", value: "Ada Lovelace", want: "ada lovelace"},
+		{name: "upper case", value: "ADA LOVELACE", want: "ada lovelace"},
+		{name: "composed accents", value: "José Muñoz", want: "jose munoz"},
+		{name: "decomposed accents", value: "Jose\u0301 Mun\u0303oz", want: "jose munoz"},
+		{name: "umlaut", value: "Zoë Brück", want: "zoe bruck"},
+		{name: "whitespace runs", value: "  Ada \t Lovelace \n", want: "ada lovelace"},
+		{name: "non-latin script", value: "Ада", want: "ада"},
+		{name: "empty", value: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FoldName(tt.value); got != tt.want {
+				t.Errorf("FoldName(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want float64
+	}{
+		{name: "same", a: "Ada Lovelace", b: "Ada Lovelace", want: 1},
+		{name: "case differences", a: "ada lovelace", b: "ADA LOVELACE", want: 1},
+		{name: "accents dropped", a: "José Muñoz", b: "Jose Munoz", want: 1},
+		{name: "composed and decomposed", a: "Jos\u00e9", b: "Jose\u0301", want: 1},
+		{name: "extra whitespace", a: " Ada  Lovelace", b: "Ada Lovelace", want: 1},
+		{name: "one substitution", a: "Ada Lovelace", b: "Ada Lovelase", want: 1 - 1.0/12},
+		{name: "one insertion", a: "Ada Lovelace", b: "Ada Lovelacee", want: 1 - 1.0/13},
+		{name: "accent and typo", a: "Renée Dupont", b: "renee dupond", want: 1 - 1.0/12},
+		// Lengths count characters, not bytes
+		{name: "non-latin script", a: "Ада", b: "Адя", want: 1 - 1.0/3},
+		{name: "nothing in common", a: "abc", b: "xyz", want: 0},
+		{name: "one empty", a: "Ada", b: "", want: 0},
+		{name: "both empty", a: "", b: "", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NameSimilarity(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("NameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if reversed := NameSimilarity(tt.b, tt.a); math.Abs(reversed-got) > 1e-9 {
+				t.Errorf("NameSimilarity(%q, %q) = %v, want it symmetric with %v", tt.b, tt.a, reversed, got)
+			}
+		})
+	}
+}
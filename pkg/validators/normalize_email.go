@@ -0,0 +1,34 @@
+package validators
+
+import (
+	"golang.org/x/net/idna"
+	"strings"
+)
+
+// NormalizeEmail converts an email address to its canonical form.
+//
+// Email addresses are compared case-insensitively in practice, so the address is
+// trimmed of surrounding whitespace and lowercased before it is used as a key. With
+// ALLOW_IDN_EMAIL=true, an internationalized domain is also mapped to its canonical
+// Unicode form, so e.g. full-width characters compare equal to their usual form.
+//
+// Parameters:
+// - email: The email address to normalize.
+//
+// Returns:
+// - The normalized email address.
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !allowIDNEmail() || isASCII(email) {
+		return email
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	// Domains IDNA rejects are left as they are, for validation to report
+	if domain, err := idna.Lookup.ToUnicode(email[at+1:]); err == nil {
+		email = email[:at+1] + domain
+	}
+	return email
+}
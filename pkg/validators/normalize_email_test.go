@@ -0,0 +1,30 @@
+package validators
+
+import (
+	"testing"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		idn   string
+		want  string
+	}{
+		{name: "lowercase", email: "ada@example.com", want: "ada@example.com"},
+		{name: "mixed case", email: "Ada.Lovelace@Example.COM", want: "ada.lovelace@example.com"},
+		{name: "padded", email: "  ada@example.com\t", want: "ada@example.com"},
+		{name: "plus kept", email: "Ada+News@example.com", want: "ada+news@example.com"},
+		{name: "no domain", email: "Ada", want: "ada"},
+		{name: "full-width domain", email: "ada@ｅｘａｍｐｌｅ.com", idn: "true", want: "ada@example.com"},
+		{name: "full-width domain without IDN", email: "ada@ｅｘａｍｐｌｅ.com", want: "ada@ｅｘａｍｐｌｅ.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOW_IDN_EMAIL", tt.idn)
+			if got := NormalizeEmail(tt.email); got != tt.want {
+				t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.email, got, tt.want)
+			}
+		})
+	}
+}
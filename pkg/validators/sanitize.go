@@ -0,0 +1,51 @@
+package validators
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// zeroWidth holds the invisible characters that pasted text commonly carries
+var zeroWidth = map[rune]bool{
+	'\u200b': true, // Zero width space
+	'\u200c': true, // Zero width non-joiner
+	'\u200d': true, // Zero width joiner
+	'\u2060': true, // Word joiner
+	'\ufeff': true, // Zero width no-break space (byte order mark)
+}
+
+// SanitizeString cleans up a free-text value sent by a client.
+//
+// The value is NFC-normalized, so composed and decomposed accents compare equal, trimmed
+// of Unicode whitespace, and every internal run of whitespace, including line breaks and
+// tabs, is collapsed to a single space.
+//
+// Parameters:
+// - s: The value to sanitize.
+//
+// Returns:
+// - The sanitized value.
+func SanitizeString(s string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(s)), " ")
+}
+
+// SanitizeName cleans up a name or email sent by a client.
+//
+// On top of what SanitizeString does, control characters and zero-width characters are
+// removed, since they are invisible and only make lookups fail or render badly.
+//
+// Parameters:
+// - s: The value to sanitize.
+//
+// Returns:
+// - The sanitized value.
+func SanitizeName(s string) string {
+	return SanitizeString(strings.Map(func(r rune) rune {
+		// Whitespace controls such as newlines are kept for SanitizeString to collapse
+		if zeroWidth[r] || (unicode.IsControl(r) && !unicode.IsSpace(r)) {
+			return -1
+		}
+		return r
+	}, s))
+}
@@ -0,0 +1,65 @@
+package validators
+
+import (
+	"testing"
+)
+
+func TestSanitizeString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "clean", value: "Ada Lovelace", want: "Ada Lovelace"},
+		{name: "empty", value: "", want: ""},
+		{name: "only whitespace", value: " \t\n ", want: ""},
+		{name: "trailing spaces", value: "Ada  ", want: "Ada"},
+		{name: "internal runs", value: "Ada \t\n  Lovelace", want: "Ada Lovelace"},
+		{name: "no-break space", value: "\u00a0Ada\u00a0\u00a0Lovelace\u00a0", want: "Ada Lovelace"},
+		{name: "ideographic space", value: "\u3000Ada\u3000", want: "Ada"},
+		{name: "line separator", value: "Ada\u2028Lovelace", want: "Ada Lovelace"},
+		{name: "decomposed accent", value: "Jose\u0301", want: "Jos\u00e9"},
+		{name: "composed accent", value: "Jos\u00e9", want: "Jos\u00e9"},
+		{name: "hangul jamo", value: "\u1100\u1161", want: "\uac00"},
+		{name: "zero width kept", value: "Ada\u200bLovelace", want: "Ada\u200bLovelace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeString(tt.value); got != tt.want {
+				t.Errorf("SanitizeString(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "clean", value: "Ada", want: "Ada"},
+		{name: "zero width space", value: "Ada\u200b", want: "Ada"},
+		{name: "zero width joiner inside", value: "Lo\u200dvelace", want: "Lovelace"},
+		{name: "zero width non-joiner", value: "\u200cAda", want: "Ada"},
+		{name: "word joiner", value: "Ada\u2060Lovelace", want: "AdaLovelace"},
+		{name: "byte order mark", value: "\ufeffAda", want: "Ada"},
+		{name: "null byte", value: "Ada\x00", want: "Ada"},
+		{name: "escape sequence", value: "\x1b[31mAda", want: "[31mAda"},
+		{name: "delete character", value: "Ad\x7fa", want: "Ada"},
+		{name: "C1 control", value: "Ada\u0085Lovelace", want: "Ada Lovelace"},
+		{name: "newline collapsed", value: "Ada\nLovelace\r\n", want: "Ada Lovelace"},
+		{name: "tab collapsed", value: "Ada\t\tLovelace", want: "Ada Lovelace"},
+		{name: "zero width between spaces", value: "Ada \u200b Lovelace", want: "Ada Lovelace"},
+		{name: "decomposed accent with padding", value: " Zoe\u0308 ", want: "Zo\u00eb"},
+		{name: "email", value: " ada@example.com\u200b\n", want: "ada@example.com"},
+		{name: "only invisible", value: "\u200b\u200d\ufeff", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeName(tt.value); got != tt.want {
+				t.Errorf("SanitizeName(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Vansh3140/golang-serverless/pkg/events"
+	"github.com/Vansh3140/golang-serverless/pkg/logging"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the body under WEBHOOK_SECRET
+const SignatureHeader = "X-Signature"
+
+// Limits on the delivery of a callback
+const (
+	attemptTimeout = 3 * time.Second
+	maxRetries     = 2
+	retryDelay     = 100 * time.Millisecond
+)
+
+// budgetMargin is the time left to the invocation to return its response after the callbacks
+const budgetMargin = 250 * time.Millisecond
+
+// deliveryConcurrency bounds the callbacks sent at the same time
+const deliveryConcurrency = 8
+
+// maxDrainBytes bounds the response body read so the connection can be reused
+const maxDrainBytes = 4 << 10
+
+// callbackTypes are the events sent to the webhook; verification requests carry a token and
+// stay internal
+var callbackTypes = map[string]bool{
+	events.UserCreated: true,
+	events.UserUpdated: true,
+	events.UserDeleted: true,
+}
+
+// Doer sends HTTP requests. *http.Client implements it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client sends the callbacks; tests can replace it with a stub. Each attempt is bounded by
+// its request's context, so the client needs no timeout of its own.
+var Client Doer = &http.Client{}
+
+// pending holds the events of the requests being served until the response is ready,
+// keyed by request ID
+var pending = struct {
+	sync.Mutex
+	events map[string][]events.Event
+}{events: map[string][]events.Event{}}
+
+// URL returns the endpoint callbacks are posted to (WEBHOOK_URL), or an empty string when
+// webhooks are off.
+func URL() string {
+	return os.Getenv("WEBHOOK_URL")
+}
+
+// Enabled reports whether user mutations are posted to a webhook.
+func Enabled() bool {
+	return URL() != ""
+}
+
+// Enqueue holds the events of a request until Flush delivers them, once the request has
+// been served. Only user creates, updates and deletes are kept.
+//
+// Parameters:
+// - requestID: The ID of the request that made the changes.
+// - published: The events published for the changes.
+func Enqueue(requestID string, published []events.Event) {
+	if !Enabled() {
+		return
+	}
+	pending.Lock()
+	defer pending.Unlock()
+	for _, event := range published {
+		if callbackTypes[event.Type] {
+			pending.events[requestID] = append(pending.events[requestID], event)
+		}
+	}
+}
+
+// Flush delivers the events held for a request, like DeliverAll.
+func Flush(ctx context.Context, requestID string) {
+	pending.Lock()
+	held := pending.events[requestID]
+	delete(pending.events, requestID)
+	pending.Unlock()
+
+	DeliverAll(ctx, held)
+}
+
+// DeliverAll posts events to WEBHOOK_URL concurrently, retrying each one up to twice on a
+// 5xx response or a timeout. Deliveries stop short of the context's deadline, the end of
+// the Lambda invocation, so they never make it time out. Events that still failed are
+// logged and published with the default publisher as WebhookFailed events.
+//
+// Parameters:
+// - ctx: The invocation context.
+// - published: The events to deliver.
+func DeliverAll(ctx context.Context, published []events.Event) {
+	if !Enabled() || len(published) == 0 {
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-budgetMargin))
+		defer cancel()
+	}
+
+	var (
+		mu          sync.Mutex
+		deadLetters []events.Event
+		wg          sync.WaitGroup
+	)
+	slots := make(chan struct{}, deliveryConcurrency)
+	for _, event := range published {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(event events.Event) {
+			defer func() { <-slots; wg.Done() }()
+			delivery := deliver(ctx, event)
+			if delivery == nil {
+				return
+			}
+			logging.Default.Error("failed to deliver webhook", logging.Fields{
+				"requestId": event.RequestID,
+				"type":      event.Type,
+				"email":     logging.Email(event.Email),
+				"status":    delivery.Status,
+				"attempts":  delivery.Attempts,
+				"error":     delivery.Error,
+			})
+			deadLetter := event
+			deadLetter.Type, deadLetter.Delivery = events.WebhookFailed, delivery
+			mu.Lock()
+			deadLetters = append(deadLetters, deadLetter)
+			mu.Unlock()
+		}(event)
+	}
+	wg.Wait()
+
+	for _, failure := range events.PublishAll(events.Default, deadLetters) {
+		logging.Default.Error("failed to publish event", logging.Fields{
+			"requestId": failure.Event.RequestID,
+			"type":      failure.Event.Type,
+			"email":     logging.Email(failure.Event.Email),
+			"error":     failure.Err,
+		})
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of a body under a secret, as sent in X-Signature.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts one event, retrying on a 5xx response or a timeout while the context allows.
+//
+// Parameters:
+// - ctx: The context bounding every attempt.
+// - event: The event to post.
+//
+// Returns:
+// - The failed delivery, or nil if the webhook answered 2xx.
+func deliver(ctx context.Context, event events.Event) *events.Delivery {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return &events.Delivery{Type: event.Type, Error: err.Error()}
+	}
+
+	delivery := &events.Delivery{Type: event.Type}
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return delivery
+			case <-time.After(time.Duration(attempt) * retryDelay):
+			}
+		}
+		delivery.Attempts++
+		status, err := post(ctx, body)
+		delivery.Status = status
+		if err == nil {
+			return nil
+		}
+		delivery.Error = err.Error()
+		if !retryable(status, err) || ctx.Err() != nil {
+			return delivery
+		}
+	}
+	return delivery
+}
+
+// post makes one attempt at posting a body to WEBHOOK_URL, signed with WEBHOOK_SECRET if set.
+//
+// Returns:
+// - The status of the response, or 0 if there was none.
+// - An error if the request failed or the status isn't 2xx.
+func post(ctx context.Context, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, URL(), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret := os.Getenv("WEBHOOK_SECRET"); secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed attempt is worth repeating: a 5xx response or a timeout.
+func retryable(status int, err error) bool {
+	if status >= 500 {
+		return true
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"context"
+	"github.com/Vansh3140/golang-serverless/pkg/events"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubDoer answers the attempts with the scripted statuses in order, the last one
+// repeating; a 0 status fails the attempt with a timeout. It keeps the requests it gets.
+type stubDoer struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (d *stubDoer) Do(req *http.Request) (*http.Response, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	d.requests = append(d.requests, req)
+	d.bodies = append(d.bodies, string(body))
+
+	status := d.statuses[len(d.statuses)-1]
+	if len(d.requests) <= len(d.statuses) {
+		status = d.statuses[len(d.requests)-1]
+	}
+	if status == 0 {
+		return nil, context.DeadlineExceeded
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+// recordingPublisher keeps the events it publishes
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []events.Event
+}
+
+func (p *recordingPublisher) Publish(event events.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, event)
+	return nil
+}
+
+// useStubs makes doer send the callbacks and publisher receive the dead letters of the test.
+func useStubs(t *testing.T, doer Doer, publisher events.Publisher) {
+	t.Helper()
+	previousClient, previousPublisher := Client, events.Default
+	Client, events.Default = doer, publisher
+	t.Cleanup(func() { Client, events.Default = previousClient, previousPublisher })
+}
+
+func TestDeliverAll(t *testing.T) {
+	tests := []struct {
+		name         string
+		secret       string
+		statuses     []int
+		wantAttempts int
+		wantFailure  bool
+		wantStatus   int
+	}{
+		{name: "delivered", secret: "s3cret", statuses: []int{http.StatusNoContent}, wantAttempts: 1},
+		{name: "unsigned", statuses: []int{http.StatusOK}, wantAttempts: 1},
+		{name: "retried after a 5xx", secret: "s3cret", statuses: []int{http.StatusBadGateway, http.StatusOK},
+			wantAttempts: 2},
+		{name: "retried after a timeout", secret: "s3cret", statuses: []int{0, http.StatusOK}, wantAttempts: 2},
+		{name: "5xx after the retries", secret: "s3cret", statuses: []int{http.StatusServiceUnavailable},
+			wantAttempts: 3, wantFailure: true, wantStatus: http.StatusServiceUnavailable},
+		{name: "timeouts after the retries", statuses: []int{0}, wantAttempts: 3, wantFailure: true},
+		{name: "4xx not retried", secret: "s3cret", statuses: []int{http.StatusBadRequest}, wantAttempts: 1,
+			wantFailure: true, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WEBHOOK_URL", "https://partner.example/hooks/users")
+			t.Setenv("WEBHOOK_SECRET", tt.secret)
+			doer, publisher := &stubDoer{statuses: tt.statuses}, &recordingPublisher{}
+			useStubs(t, doer, publisher)
+
+			event := events.Event{Type: events.UserUpdated, Email: "ada@example.com", RequestID: "req-599"}
+			DeliverAll(context.Background(), []events.Event{event})
+
+			if len(doer.requests) != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", len(doer.requests), tt.wantAttempts)
+			}
+			for i, req := range doer.requests {
+				if req.Method != http.MethodPost || req.URL.String() != "https://partner.example/hooks/users" ||
+					req.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("attempt %d = %s %s (%s)", i, req.Method, req.URL, req.Header.Get("Content-Type"))
+				}
+				signature, signed := req.Header[SignatureHeader]
+				switch {
+				case tt.secret == "" && signed:
+					t.Errorf("attempt %d signed without a secret: %v", i, signature)
+				case tt.secret != "" && req.Header.Get(SignatureHeader) != Sign(tt.secret, []byte(doer.bodies[i])):
+					t.Errorf("attempt %d %s = %q, want the HMAC of its body", i, SignatureHeader, req.Header.Get(SignatureHeader))
+				}
+				if !strings.Contains(doer.bodies[i], `"requestId":"req-599"`) {
+					t.Errorf("attempt %d body = %s, want the event", i, doer.bodies[i])
+				}
+			}
+
+			if !tt.wantFailure {
+				if len(publisher.published) != 0 {
+					t.Errorf("dead letters = %+v, want none", publisher.published)
+				}
+				return
+			}
+			if len(publisher.published) != 1 {
+				t.Fatalf("dead letters = %+v, want one", publisher.published)
+			}
+			deadLetter := publisher.published[0]
+			if deadLetter.Type != events.WebhookFailed || deadLetter.Email != "ada@example.com" || deadLetter.Delivery == nil ||
+				deadLetter.Delivery.Type != events.UserUpdated || deadLetter.Delivery.Attempts != tt.wantAttempts ||
+				deadLetter.Delivery.Status != tt.wantStatus {
+				t.Errorf("dead letter = %+v (delivery %+v), want the failed %s after %d attempts, status %d", deadLetter,
+					deadLetter.Delivery, events.UserUpdated, tt.wantAttempts, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestDeliverAllStaysWithinTheBudget(t *testing.T) {
+	t.Setenv("WEBHOOK_URL", "https://partner.example/hooks/users")
+	doer, publisher := &stubDoer{statuses: []int{http.StatusInternalServerError}}, &recordingPublisher{}
+	useStubs(t, doer, publisher)
+
+	// With 400ms left, 150ms remain for the callbacks: one retry fits, the second doesn't
+	ctx, cancel := context.WithTimeout(context.Background(), budgetMargin+150*time.Millisecond)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	DeliverAll(ctx, []events.Event{{Type: events.UserCreated, Email: "ada@example.com"}})
+
+	if left := time.Until(deadline); left < budgetMargin/2 {
+		t.Errorf("DeliverAll() returned %v before the deadline, want about %v", left, budgetMargin)
+	}
+	if len(doer.requests) != 2 {
+		t.Errorf("attempts = %d, want 2 within the budget", len(doer.requests))
+	}
+	if len(publisher.published) != 1 || publisher.published[0].Type != events.WebhookFailed {
+		t.Errorf("dead letters = %+v, want the failed delivery", publisher.published)
+	}
+}
+
+func TestFlushOnlyDeliversUserMutations(t *testing.T) {
+	t.Setenv("WEBHOOK_URL", "https://partner.example/hooks/users")
+	doer := &stubDoer{statuses: []int{http.StatusOK}}
+	useStubs(t, doer, &recordingPublisher{})
+
+	Enqueue("req-1", []events.Event{
+		{Type: events.UserCreated, Email: "ada@example.com"},
+		{Type: events.UserVerificationRequested, Email: "ada@example.com"},
+	})
+	Enqueue("req-2", []events.Event{{Type: events.UserDeleted, Email: "grace@example.com"}})
+	Flush(context.Background(), "req-1")
+
+	if len(doer.bodies) != 1 || !strings.Contains(doer.bodies[0], events.UserCreated) {
+		t.Errorf("delivered %v, want only the creation of request req-1", doer.bodies)
+	}
+	Flush(context.Background(), "req-1")
+	if len(doer.bodies) != 1 {
+		t.Errorf("second Flush() delivered %v again", doer.bodies[1:])
+	}
+	Flush(context.Background(), "req-2")
+	if len(doer.bodies) != 2 {
+		t.Errorf("delivered %d events, want the deletion of request req-2 too", len(doer.bodies))
+	}
+}
+
+func TestDeliverAllDisabled(t *testing.T) {
+	t.Setenv("WEBHOOK_URL", "")
+	doer := &stubDoer{statuses: []int{http.StatusOK}}
+	useStubs(t, doer, &recordingPublisher{})
+
+	Enqueue("req-1", []events.Event{{Type: events.UserCreated, Email: "ada@example.com"}})
+	Flush(context.Background(), "req-1")
+	DeliverAll(context.Background(), []events.Event{{Type: events.UserCreated, Email: "ada@example.com"}})
+	if len(doer.requests) != 0 {
+		t.Errorf("attempts = %d, want none without WEBHOOK_URL", len(doer.requests))
+	}
+}